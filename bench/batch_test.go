@@ -0,0 +1,43 @@
+package bench
+
+import (
+	"testing"
+
+	"github.com/lxwagn/using-go-with-c-libraries/pkg/mylib"
+)
+
+// BenchmarkCounterAdd compares n CounterAdd calls with the same n queued
+// in a mylib.Batch.
+func BenchmarkCounterAdd(b *testing.B) {
+	b.Run("PerItem/100", perItem(100))
+	b.Run("Batch/100", batched(100))
+}
+
+// perItem makes n CounterAdd calls: n cgo crossings per op.
+func perItem(n int) func(b *testing.B) {
+	return func(b *testing.B) {
+		b.RunParallel(func(pb *testing.PB) {
+			for pb.Next() {
+				for i := 0; i < n; i++ {
+					mylib.CounterAdd(1)
+				}
+			}
+		})
+	}
+}
+
+// batched queues the same n calls in a mylib.Batch: one crossing per op.
+func batched(n int) func(b *testing.B) {
+	return func(b *testing.B) {
+		b.RunParallel(func(pb *testing.PB) {
+			var batch mylib.Batch
+			defer batch.Close()
+			for pb.Next() {
+				for i := 0; i < n; i++ {
+					batch.CounterAdd(1)
+				}
+				batch.Flush()
+			}
+		})
+	}
+}
@@ -0,0 +1,99 @@
+// Package bench measures the cost of crossing between Go and C, so the
+// overheads this repository talks about have numbers attached and
+// regressions show up.
+//
+// The benchmarks are in the package's tests; go test -cpu runs them
+// under several GOMAXPROCS settings:
+//
+//	go test -run '^$' -bench . -cpu 1,2,4,8 ./bench
+//
+// Each one uses b.RunParallel, which runs the loop on GOMAXPROCS
+// goroutines at once: a per-op time that stays flat as GOMAXPROCS grows
+// means the calls scale, one that grows means they contend. A _test.go
+// file cannot use cgo, so the calls into C they measure are the small
+// functions here.
+package bench
+
+/*
+
+#include <stdlib.h>
+#include <string.h>
+
+struct benchPair {
+	long long a;
+	double b;
+	char tag[32];
+};
+
+static void benchEmpty(void) {}
+
+static size_t benchStrlen(const char *s) {
+	return strlen(s);
+}
+
+static long long benchTakeStruct(struct benchPair p) {
+	return p.a;
+}
+
+static struct benchPair benchMakeStruct(long long a) {
+	struct benchPair p;
+
+	memset(&p, 0, sizeof p);
+	p.a = a;
+	p.b = (double)a;
+	return p;
+}
+
+// Defined in callback_export.go.
+extern int benchGoCallback(int value);
+
+static int benchCallback(int value) {
+	return benchGoCallback(value);
+}
+
+*/
+import "C"
+
+import "unsafe"
+
+//go:noinline
+func goEmpty() {}
+
+// emptyCgo is the fixed price of a cgo call: switching to the system
+// stack and telling the scheduler the goroutine is in C.
+func emptyCgo() {
+	C.benchEmpty()
+}
+
+// strlenCString adds copying s to C memory and freeing it, as every
+// wrapper taking a string does. The copy is made with C's malloc, so it
+// does not show up as a Go allocation.
+func strlenCString(s string) {
+	cs := C.CString(s)
+	C.benchStrlen(cs)
+	C.free(unsafe.Pointer(cs))
+}
+
+// A pair is the 48-byte struct passed and returned by value.
+type pair = C.struct_benchPair
+
+func newPair(a int64) pair {
+	return pair{a: C.longlong(a)}
+}
+
+// takeStruct passes p by value, which copies it for the call.
+func takeStruct(p pair) {
+	C.benchTakeStruct(p)
+}
+
+// makeStruct returns the same struct by value from C.
+func makeStruct(a int64) pair {
+	return C.benchMakeStruct(C.longlong(a))
+}
+
+// callbackRoundTrip calls into C, which calls straight back into Go. The
+// callback costs far more than the call, because the C thread has to be
+// reattached to the Go runtime.
+func callbackRoundTrip(v int) int {
+	return int(C.benchCallback(C.int(v)))
+}
@@ -0,0 +1,78 @@
+package bench
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/lxwagn/using-go-with-c-libraries/pkg/mylib"
+)
+
+// BenchmarkGoCall is the baseline the others compare against: an
+// ordinary Go function call that the compiler may not inline.
+func BenchmarkGoCall(b *testing.B) {
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			goEmpty()
+		}
+	})
+}
+
+func BenchmarkEmptyCgoCall(b *testing.B) {
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			emptyCgo()
+		}
+	})
+}
+
+func BenchmarkStringArg(b *testing.B) {
+	b.Run("16", stringArg(16))
+	b.Run("1024", stringArg(1024))
+}
+
+func stringArg(n int) func(b *testing.B) {
+	s := strings.Repeat("x", n)
+	return func(b *testing.B) {
+		b.SetBytes(int64(n))
+		b.RunParallel(func(pb *testing.PB) {
+			for pb.Next() {
+				strlenCString(s)
+			}
+		})
+	}
+}
+
+func BenchmarkStructArg(b *testing.B) {
+	p := newPair(1)
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			takeStruct(p)
+		}
+	})
+}
+
+func BenchmarkStructResult(b *testing.B) {
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			makeStruct(1)
+		}
+	})
+}
+
+func BenchmarkCallbackRoundTrip(b *testing.B) {
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			callbackRoundTrip(1)
+		}
+	})
+}
+
+// BenchmarkMylibCounterAdd is a real wrapper: a cgo call made under
+// pkg/mylib's call guard, which serializes callers.
+func BenchmarkMylibCounterAdd(b *testing.B) {
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			mylib.CounterAdd(1)
+		}
+	})
+}
@@ -0,0 +1,11 @@
+package bench
+
+// The preamble of a file with //export may only declare C functions, so
+// the C side of the callback benchmark lives in bench.go.
+
+import "C"
+
+//export benchGoCallback
+func benchGoCallback(value C.int) C.int {
+	return value + 1
+}
@@ -0,0 +1,18 @@
+package bench
+
+/*
+
+#include <unistd.h>
+
+static void benchBlock(int us) {
+	usleep(us);
+}
+
+*/
+import "C"
+
+// blockC blocks in C for us microseconds, holding its thread, for
+// BenchmarkBlockingCall.
+func blockC(us int) {
+	C.benchBlock(C.int(us))
+}
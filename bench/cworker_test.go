@@ -0,0 +1,68 @@
+package bench
+
+import (
+	"sync/atomic"
+	"testing"
+
+	"github.com/lxwagn/using-go-with-c-libraries/pkg/cworker"
+	"github.com/lxwagn/using-go-with-c-libraries/pkg/mylib"
+)
+
+// The cworker benchmarks compare calling C directly with funnelling the
+// calls through a cworker.Pool of four threads, under load: sixteen
+// goroutines per GOMAXPROCS calling at once. A blocking call holds a
+// thread for 100µs, and the threads-in-C metric is the most calls that
+// were in C at one time, each holding a thread. Direct calls take as many
+// threads as there are callers; the pool never more than four, at the
+// price of the callers queueing for them. Fewer than four can be in C
+// when GOMAXPROCS is small: a locked goroutine runs only on its own
+// thread, so each worker waits for the scheduler to hand it a P.
+
+func BenchmarkBlockingCall(b *testing.B) {
+	b.Run("Direct", blockingCall(0))
+	b.Run("Pool4", blockingCall(4))
+}
+
+// BenchmarkCounterAddPool is MylibCounterAdd through the pool: the added
+// cost of handing a short call to another thread and waiting for it.
+func BenchmarkCounterAddPool(b *testing.B) {
+	p := cworker.New(4, 64)
+	defer p.Close()
+	b.SetParallelism(16)
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			p.Submit(func() { mylib.CounterAdd(1) })
+		}
+	})
+}
+
+// blockingCall calls C directly if workers is 0, and otherwise through a
+// pool of that many threads.
+func blockingCall(workers int) func(b *testing.B) {
+	return func(b *testing.B) {
+		var p *cworker.Pool
+		if workers > 0 {
+			p = cworker.New(workers, 64)
+			defer p.Close()
+		}
+		var inC, peak atomic.Int64
+		call := func() {
+			n := inC.Add(1)
+			for m := peak.Load(); n > m && !peak.CompareAndSwap(m, n); m = peak.Load() {
+			}
+			blockC(100)
+			inC.Add(-1)
+		}
+		b.SetParallelism(16)
+		b.RunParallel(func(pb *testing.PB) {
+			for pb.Next() {
+				if p == nil {
+					call()
+				} else {
+					p.Submit(call)
+				}
+			}
+		})
+		b.ReportMetric(float64(peak.Load()), "threads-in-C")
+	}
+}
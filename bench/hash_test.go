@@ -0,0 +1,48 @@
+package bench
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"hash"
+	"testing"
+
+	"github.com/lxwagn/using-go-with-c-libraries/examples/osslhash"
+)
+
+// BenchmarkHash computes SHA-256 with OpenSSL, through examples/osslhash,
+// and with crypto/sha256. Each iteration creates a hash, so small inputs
+// show the cost of an EVP_MD_CTX and its cleanup against a Go value on
+// the heap.
+func BenchmarkHash(b *testing.B) {
+	for _, impl := range []struct {
+		name string
+		new  func() hash.Hash
+	}{
+		{"SHA256/OpenSSL", osslhash.NewSHA256},
+		{"SHA256/Go", sha256.New},
+	} {
+		b.Run(impl.name, func(b *testing.B) {
+			for _, size := range []int{64, 8 << 10, 1 << 20} {
+				name := fmt.Sprintf("%dB", size)
+				if size >= 1<<10 {
+					name = fmt.Sprintf("%dKiB", size>>10)
+				}
+				b.Run(name, hashSum(make([]byte, size), impl.new))
+			}
+		})
+	}
+}
+
+func hashSum(data []byte, newHash func() hash.Hash) func(b *testing.B) {
+	return func(b *testing.B) {
+		b.SetBytes(int64(len(data)))
+		b.RunParallel(func(pb *testing.PB) {
+			sum := make([]byte, 0, sha256.Size)
+			for pb.Next() {
+				h := newHash()
+				h.Write(data)
+				sum = h.Sum(sum[:0])
+			}
+		})
+	}
+}
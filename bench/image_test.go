@@ -0,0 +1,61 @@
+package bench
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/png"
+	"testing"
+
+	"github.com/lxwagn/using-go-with-c-libraries/examples/imagec"
+)
+
+// BenchmarkImage decodes a 1024×1024 PNG three ways: with libpng into C
+// memory, which the garbage collector never sees, with libpng into Go
+// memory, and with image/png.
+func BenchmarkImage(b *testing.B) {
+	data := imageInput(1024, 1024)
+	b.Run("Decode/C/CMemory", imageDecode(data, func(data []byte) error {
+		m, err := imagec.Decode(data)
+		if err == nil {
+			m.Free()
+		}
+		return err
+	}))
+	b.Run("Decode/C/GoMemory", imageDecode(data, func(data []byte) error {
+		_, err := imagec.DecodeNRGBA(data)
+		return err
+	}))
+	b.Run("Decode/Go", imageDecode(data, func(data []byte) error {
+		_, err := png.Decode(bytes.NewReader(data))
+		return err
+	}))
+}
+
+func imageDecode(data []byte, decode func([]byte) error) func(b *testing.B) {
+	return func(b *testing.B) {
+		b.SetBytes(int64(len(data)))
+		b.RunParallel(func(pb *testing.PB) {
+			for pb.Next() {
+				if err := decode(data); err != nil {
+					b.Error(err)
+					return
+				}
+			}
+		})
+	}
+}
+
+// imageInput returns a w×h PNG of gradients, which compresses like a
+// photograph more than like a flat image.
+func imageInput(w, h int) []byte {
+	m := image.NewNRGBA(image.Rect(0, 0, w, h))
+	for y := range h {
+		for x := range w {
+			m.SetNRGBA(x, y, color.NRGBA{uint8(x), uint8(y), uint8(x*y>>4 + x), 255})
+		}
+	}
+	var buf bytes.Buffer
+	png.Encode(&buf, m)
+	return buf.Bytes()
+}
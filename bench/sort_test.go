@@ -0,0 +1,61 @@
+package bench
+
+import (
+	"cmp"
+	"fmt"
+	"math/rand/v2"
+	"slices"
+	"sort"
+	"testing"
+
+	"github.com/lxwagn/using-go-with-c-libraries/examples/qsort"
+	"github.com/lxwagn/using-go-with-c-libraries/pkg/mylib"
+)
+
+// BenchmarkSort sorts the same random int64s in Go, with slices.SortFunc
+// and sort.Slice, and in C, with qsort and a C comparison and then with
+// Go comparisons called back from qsort and from mySort. The gap between
+// the C comparison and the Go ones is the price of the callbacks, roughly
+// n log n of them per sort. The C sorts with Go comparisons run one at a
+// time, so they do not scale with GOMAXPROCS.
+func BenchmarkSort(b *testing.B) {
+	for _, sorter := range []struct {
+		name string
+		sort func([]int64)
+	}{
+		{"SlicesSortFunc", func(s []int64) { slices.SortFunc(s, cmp.Compare[int64]) }},
+		{"SortSlice", func(s []int64) { sort.Slice(s, func(i, j int) bool { return s[i] < s[j] }) }},
+		{"QsortC", qsort.Int64s},
+		{"QsortGo", func(s []int64) { qsort.Slice(s, cmp.Compare[int64]) }},
+		{"MylibSort", func(s []int64) { mylib.Sort(s, cmp.Compare[int64]) }},
+	} {
+		b.Run(sorter.name, func(b *testing.B) {
+			for _, n := range []int{100, 10000} {
+				b.Run(fmt.Sprint(n), sortBench(sortInput(n), sorter.sort))
+			}
+		})
+	}
+}
+
+func sortInput(n int) []int64 {
+	r := rand.New(rand.NewPCG(1, 2))
+	s := make([]int64, n)
+	for i := range s {
+		s[i] = r.Int64()
+	}
+	return s
+}
+
+// sortBench sorts a fresh copy of data each op; the copy is part of the
+// time, the same for every sort.
+func sortBench(data []int64, sortFn func([]int64)) func(b *testing.B) {
+	return func(b *testing.B) {
+		b.RunParallel(func(pb *testing.PB) {
+			s := make([]int64, len(data))
+			for pb.Next() {
+				copy(s, data)
+				sortFn(s)
+			}
+		})
+	}
+}
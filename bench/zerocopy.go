@@ -0,0 +1,69 @@
+package bench
+
+/*
+
+#include <stdlib.h>
+#include <string.h>
+
+// The same FNV-1a hash as myHash, so that the work in C is the same for
+// every way of passing the bytes.
+static unsigned int benchHash(const char *s, size_t n) {
+	unsigned int h = 2166136261u;
+	size_t i;
+
+	for (i = 0; i < n; i++) {
+		h ^= (unsigned char)s[i];
+		h *= 16777619u;
+	}
+	return h;
+}
+
+static unsigned int benchHashCString(const char *s) {
+	return benchHash(s, strlen(s));
+}
+
+static unsigned int benchHashGoString(_GoString_ s) {
+	return benchHash(_GoStringPtr(s), _GoStringLen(s));
+}
+
+*/
+import "C"
+
+import (
+	"unsafe"
+
+	"github.com/lxwagn/using-go-with-c-libraries/pkg/cmem"
+)
+
+// The ways BenchmarkPassString and BenchmarkPassBytes pass their bytes to
+// benchHash. A _test.go file cannot use cgo, so they are here.
+
+// hashCString copies s into C memory, as every wrapper taking a string
+// with C.CString does.
+func hashCString(s string) {
+	cs := C.CString(s)
+	C.benchHashCString(cs)
+	C.free(unsafe.Pointer(cs))
+}
+
+// hashGoString passes s in place as a _GoString_.
+func hashGoString(s string) {
+	C.benchHashGoString(s)
+}
+
+// hashBorrowString passes s in place through cmem.BorrowString.
+func hashBorrowString(s string) {
+	C.benchHash((*C.char)(cmem.BorrowString(s)), C.size_t(len(s)))
+}
+
+// hashCBytes copies b into C memory with C.CBytes.
+func hashCBytes(b []byte) {
+	p := C.CBytes(b)
+	C.benchHash((*C.char)(p), C.size_t(len(b)))
+	C.free(p)
+}
+
+// hashBorrowSlice passes b in place through cmem.BorrowSlice.
+func hashBorrowSlice(b []byte) {
+	C.benchHash((*C.char)(cmem.BorrowSlice(b)), C.size_t(len(b)))
+}
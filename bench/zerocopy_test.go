@@ -0,0 +1,61 @@
+package bench
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+// The zero-copy benchmarks pass the same bytes to the same C hash
+// function by copying them into C memory, as C.CString and C.CBytes do,
+// and in place, through a _GoString_ parameter or a pointer from
+// cmem.BorrowString and cmem.BorrowSlice. The copy's cost, a malloc, a
+// memcpy and a free, grows with the size; passing in place costs the
+// same as an empty call whatever the size.
+var passSizes = []int{16, 1024, 64 << 10}
+
+func BenchmarkPassString(b *testing.B) {
+	for _, way := range []struct {
+		name string
+		pass func(string)
+	}{
+		{"CString", hashCString},
+		{"GoString", hashGoString},
+		{"Borrow", hashBorrowString},
+	} {
+		b.Run(way.name, func(b *testing.B) {
+			for _, n := range passSizes {
+				s := strings.Repeat("x", n)
+				b.Run(fmt.Sprint(n), passBench(n, func() { way.pass(s) }))
+			}
+		})
+	}
+}
+
+func BenchmarkPassBytes(b *testing.B) {
+	for _, way := range []struct {
+		name string
+		pass func([]byte)
+	}{
+		{"CBytes", hashCBytes},
+		{"Borrow", hashBorrowSlice},
+	} {
+		b.Run(way.name, func(b *testing.B) {
+			for _, n := range passSizes {
+				data := []byte(strings.Repeat("x", n))
+				b.Run(fmt.Sprint(n), passBench(n, func() { way.pass(data) }))
+			}
+		})
+	}
+}
+
+func passBench(n int, call func()) func(b *testing.B) {
+	return func(b *testing.B) {
+		b.SetBytes(int64(n))
+		b.RunParallel(func(pb *testing.PB) {
+			for pb.Next() {
+				call()
+			}
+		})
+	}
+}
@@ -0,0 +1,88 @@
+package bench
+
+import (
+	"bytes"
+	"compress/flate"
+	"compress/zlib"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"testing"
+
+	"github.com/lxwagn/using-go-with-c-libraries/examples/zlibc"
+)
+
+// BenchmarkZlib compresses and decompresses the same data with the C
+// library, through examples/zlibc, and with compress/zlib, which is
+// compress/flate plus a header and checksum. Small inputs show the fixed
+// cost of the C path, a z_stream and its buffers in C memory, against
+// Go's; large ones show the speed of each compressor.
+func BenchmarkZlib(b *testing.B) {
+	sizes := []int{1 << 10, 1 << 20}
+	for _, size := range sizes {
+		data := zlibInput(size)
+		for _, level := range []int{flate.BestSpeed, flate.DefaultCompression} {
+			name := fmt.Sprintf("%dKiB/Level%d", size>>10, level)
+			b.Run("Compress/C/"+name, zlibCompress(data, func(w io.Writer) io.WriteCloser {
+				z, _ := zlibc.NewWriterLevel(w, level)
+				return z
+			}))
+			b.Run("Compress/Go/"+name, zlibCompress(data, func(w io.Writer) io.WriteCloser {
+				z, _ := zlib.NewWriterLevel(w, level)
+				return z
+			}))
+		}
+	}
+	for _, size := range sizes {
+		var buf bytes.Buffer
+		w := zlib.NewWriter(&buf)
+		w.Write(zlibInput(size))
+		w.Close()
+		name := fmt.Sprintf("%dKiB", size>>10)
+		b.Run("Decompress/C/"+name, zlibDecompress(buf.Bytes(), size, func(r io.Reader) (io.ReadCloser, error) {
+			return zlibc.NewReader(r)
+		}))
+		b.Run("Decompress/Go/"+name, zlibDecompress(buf.Bytes(), size, zlib.NewReader))
+	}
+}
+
+func zlibCompress(data []byte, newWriter func(io.Writer) io.WriteCloser) func(b *testing.B) {
+	return func(b *testing.B) {
+		b.SetBytes(int64(len(data)))
+		b.RunParallel(func(pb *testing.PB) {
+			for pb.Next() {
+				w := newWriter(io.Discard)
+				w.Write(data)
+				w.Close()
+			}
+		})
+	}
+}
+
+func zlibDecompress(stream []byte, size int, newReader func(io.Reader) (io.ReadCloser, error)) func(b *testing.B) {
+	return func(b *testing.B) {
+		b.SetBytes(int64(size))
+		b.RunParallel(func(pb *testing.PB) {
+			for pb.Next() {
+				r, err := newReader(bytes.NewReader(stream))
+				if err != nil {
+					b.Error(err)
+					return
+				}
+				io.Copy(io.Discard, r)
+				r.Close()
+			}
+		})
+	}
+}
+
+// zlibInput returns n bytes of text-like data.
+func zlibInput(n int) []byte {
+	words := []string{"cgo ", "zlib ", "stream ", "buffer ", "deflate ", "inflate ", "\n"}
+	r := rand.New(rand.NewPCG(uint64(n), 7))
+	var b bytes.Buffer
+	for b.Len() < n {
+		b.WriteString(words[r.IntN(len(words))])
+	}
+	return b.Bytes()[:n]
+}
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+var (
+	ifdefRE = regexp.MustCompile(`^#\s*(ifdef|ifndef)\s+([A-Za-z_]\w*)\s*$`)
+	elseRE  = regexp.MustCompile(`^#\s*else\b`)
+)
+
+// preprocess keeps the lines of src that a compiler defining the macros
+// in defined, and no others, would see: the branches of #ifdef, #ifndef
+// and #else that are taken, without the directives themselves. Other
+// conditionals, such as #if, need an expression evaluator cbindgen does
+// not have, and are an error.
+func preprocess(src string, defined map[string]bool) (string, error) {
+	var out strings.Builder
+	// taking[i] says whether the lines inside the i+1st open conditional
+	// are kept; a nested block is kept only if every enclosing one is.
+	var taking []bool
+	skipping := func() bool {
+		for _, t := range taking {
+			if !t {
+				return true
+			}
+		}
+		return false
+	}
+	for i, line := range strings.Split(src, "\n") {
+		trimmed := strings.TrimSpace(line)
+		switch {
+		case ifdefRE.MatchString(trimmed):
+			m := ifdefRE.FindStringSubmatch(trimmed)
+			taking = append(taking, defined[m[2]] == (m[1] == "ifdef"))
+		case conditionRE.MatchString(trimmed):
+			return "", fmt.Errorf("line %d: cannot evaluate %s", i+1, trimmed)
+		case elseRE.MatchString(trimmed):
+			if len(taking) == 0 {
+				return "", fmt.Errorf("line %d: #else without #if", i+1)
+			}
+			taking[len(taking)-1] = !taking[len(taking)-1]
+		case endifRE.MatchString(trimmed):
+			if len(taking) == 0 {
+				return "", fmt.Errorf("line %d: unbalanced #endif", i+1)
+			}
+			taking = taking[:len(taking)-1]
+		case !skipping():
+			out.WriteString(line)
+			out.WriteByte('\n')
+		}
+	}
+	if len(taking) > 0 {
+		return "", fmt.Errorf("unterminated #if")
+	}
+	return out.String(), nil
+}
+
+// generateDef writes a module-definition file exporting every function
+// the header declares, for linking the DLL with MSVC (link /DEF:) or for
+// making an import library from it (lib /DEF: or dlltool -d). MSVC
+// exports only what a .def file or __declspec(dllexport) names, where
+// MinGW's linker exports every function by default; with the .def file
+// both DLLs export the same names.
+func generateDef(h *header, cfg config) ([]byte, error) {
+	g := &generator{cfg: cfg, h: h}
+	g.printf("; Generated by cbindgen from %s; DO NOT EDIT.\n", cfg.header)
+	g.printf("LIBRARY %s\n", strings.TrimSuffix(cfg.header, ".h"))
+	g.printf("EXPORTS\n")
+	for _, f := range h.funcs {
+		g.printf("\t%s\n", f.name)
+	}
+	// Variables are exported as DATA, which gives them no thunk in the
+	// import library; importers reach them through __imp_ pointers.
+	for _, v := range h.vars {
+		g.printf("\t%s DATA\n", v)
+	}
+	return g.buf.Bytes(), nil
+}
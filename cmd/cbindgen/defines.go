@@ -0,0 +1,66 @@
+package main
+
+import (
+	"fmt"
+	"go/format"
+	"strings"
+)
+
+// generateDefines writes a plain Go file, with no cgo, holding a constant
+// per #define with an integer or string value, named like enum constants:
+// MYLIB_RING_HEADER becomes RingHeader with -trim-define MYLIB_. Macros
+// with no such value, function-like ones among them, are listed in a
+// comment and left to hand-written code.
+func generateDefines(h *header, cfg config) ([]byte, error) {
+	g := &generator{cfg: cfg, h: h}
+
+	g.printf("// Code generated by cbindgen from %s; DO NOT EDIT.\n\n", cfg.header)
+	if cfg.build != "" {
+		g.printf("//go:build %s\n\n", cfg.build)
+	}
+	g.printf("package %s\n\n", cfg.pkg)
+
+	var defines []define
+	for _, d := range h.defines {
+		if !d.enum {
+			defines = append(defines, d)
+		}
+	}
+	if len(defines) > 0 {
+		g.printf("// The #defines of %s with a literal value.\n", cfg.header)
+		g.printf("const (\n")
+		for _, d := range defines {
+			g.printf("%s = %s // %s\n", g.defineName(d.name), d.value, d.name)
+		}
+		g.printf(")\n\n")
+	}
+
+	if len(h.macros) > 0 {
+		g.printf("// Macros of %s with no constant value:\n//\n", cfg.header)
+		for _, m := range h.macros {
+			if m.fn {
+				g.printf("//\t%s(%s)\n", m.name, strings.Join(m.params, ", "))
+			} else {
+				g.printf("//\t%s\n", m.name)
+			}
+		}
+	}
+
+	out, err := format.Source(g.buf.Bytes())
+	if err != nil {
+		return nil, fmt.Errorf("formatting output: %v\n%s", err, g.buf.Bytes())
+	}
+	return out, nil
+}
+
+// defineName turns the C name of a #define into a Go one: MYLIB_OP_LOOKUP
+// becomes OpLookup.
+func (g *generator) defineName(c string) string {
+	var b strings.Builder
+	for _, w := range strings.Split(strings.ToLower(strings.TrimPrefix(c, g.cfg.trimDefine)), "_") {
+		if w != "" {
+			b.WriteString(exported(w))
+		}
+	}
+	return b.String()
+}
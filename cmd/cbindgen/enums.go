@@ -0,0 +1,75 @@
+package main
+
+import (
+	"fmt"
+	"go/format"
+	"strings"
+)
+
+// generateEnums writes a plain Go file, with no cgo, holding a named type
+// per C enum: its constants, a String method giving their C names and an
+// IsValid method. A constant's Go name is the type name followed by its
+// label, "not found" giving StatusNotFound for enum myStatus, or by its C
+// name without the -trim-define prefix if it has no label.
+func generateEnums(h *header, cfg config) ([]byte, error) {
+	g := &generator{cfg: cfg, h: h}
+
+	g.printf("// Code generated by cbindgen from %s; DO NOT EDIT.\n\n", cfg.header)
+	if cfg.build != "" {
+		g.printf("//go:build %s\n\n", cfg.build)
+	}
+	g.printf("package %s\n\n", cfg.pkg)
+	if len(h.enums) > 0 {
+		g.printf("import \"strconv\"\n\n")
+	}
+
+	for _, e := range h.enums {
+		typ := g.funcName(e.name)
+		recv := strings.ToLower(typ[:1])
+		names := make([]string, len(e.consts))
+		for i, c := range e.consts {
+			names[i] = typ + g.constName(c)
+		}
+
+		g.printf("// %s mirrors enum %s.\n", typ, e.name)
+		g.printf("type %s int32\n\n", typ)
+		g.printf("const (\n")
+		for i, c := range e.consts {
+			g.printf("%s %s = %d // %s\n", names[i], typ, c.value, c.name)
+		}
+		g.printf(")\n\n")
+
+		g.printf("// IsValid reports whether %s is one of the constants of enum %s.\n", recv, e.name)
+		g.printf("func (%s %s) IsValid() bool {\n", recv, typ)
+		g.printf("switch %s {\ncase %s:\nreturn true\n}\nreturn false\n}\n\n", recv, strings.Join(names, ", "))
+
+		g.printf("// String returns the C name of %s.\n", recv)
+		g.printf("func (%s %s) String() string {\n", recv, typ)
+		g.printf("switch %s {\n", recv)
+		for i, c := range e.consts {
+			g.printf("case %s:\nreturn %q\n", names[i], c.name)
+		}
+		g.printf("}\nreturn \"%s(\" + strconv.Itoa(int(%s)) + \")\"\n}\n\n", e.name, recv)
+	}
+
+	out, err := format.Source(g.buf.Bytes())
+	if err != nil {
+		return nil, fmt.Errorf("formatting output: %v\n%s", err, g.buf.Bytes())
+	}
+	return out, nil
+}
+
+// constName turns a constant's label, or failing that its C name, into
+// the part of its Go name after the type: "not found" and
+// MYLIB_OP_LOOKUP become NotFound and OpLookup.
+func (g *generator) constName(c enumConst) string {
+	words := strings.Fields(c.label)
+	if len(words) == 0 {
+		return g.defineName(c.name)
+	}
+	var b strings.Builder
+	for _, w := range words {
+		b.WriteString(exported(w))
+	}
+	return b.String()
+}
@@ -0,0 +1,287 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"go/format"
+	"strings"
+)
+
+type config struct {
+	pkg        string
+	build      string // build constraint for the output, if any
+	header     string // header name as written in the #include
+	pkgConfig  string // pkg-config module to link, if any
+	trimFunc   string // prefix dropped from function names
+	trimDefine string // prefix dropped from #define names
+}
+
+// scalar describes how a C scalar type is spelled in Go and in cgo.
+type scalar struct {
+	goType string
+	cgo    string
+}
+
+var scalars = map[string]scalar{
+	"char":               {"byte", "C.char"},
+	"unsigned char":      {"byte", "C.uchar"},
+	"short":              {"int16", "C.short"},
+	"unsigned short":     {"uint16", "C.ushort"},
+	"int":                {"int32", "C.int"},
+	"unsigned int":       {"uint32", "C.uint"},
+	"long":               {"int64", "C.long"},
+	"unsigned long":      {"uint64", "C.ulong"},
+	"long long":          {"int64", "C.longlong"},
+	"unsigned long long": {"uint64", "C.ulonglong"},
+	"size_t":             {"uint", "C.size_t"},
+	"float":              {"float32", "C.float"},
+	"double":             {"float64", "C.double"},
+}
+
+type generator struct {
+	cfg     config
+	h       *header
+	structs map[string]bool // struct tags with a Go mirror
+	opaque  map[string]bool
+	buf     bytes.Buffer
+}
+
+func generate(h *header, cfg config) ([]byte, error) {
+	g := &generator{
+		cfg:     cfg,
+		h:       h,
+		structs: make(map[string]bool),
+		opaque:  make(map[string]bool),
+	}
+	for _, s := range h.structs {
+		if s.skip == "" {
+			g.structs[s.name] = true
+		}
+	}
+	for _, o := range h.opaque {
+		g.opaque[o] = true
+	}
+
+	g.printf("// Code generated by cbindgen from %s; DO NOT EDIT.\n\n", cfg.header)
+	if cfg.build != "" {
+		g.printf("//go:build %s\n\n", cfg.build)
+	}
+	g.printf("package %s\n\n", cfg.pkg)
+	g.printf("/*\n")
+	if cfg.pkgConfig != "" {
+		g.printf("#cgo pkg-config: %s\n", cfg.pkgConfig)
+	}
+	g.printf("#include %q\n#include <stdlib.h>\n*/\nimport \"C\"\n\n", cfg.header)
+	head := g.buf.Len()
+
+	if len(h.defines) > 0 {
+		g.printf("const (\n")
+		for _, d := range h.defines {
+			g.printf("%s = C.%s\n", strings.TrimPrefix(d.name, cfg.trimDefine), d.name)
+		}
+		g.printf(")\n\n")
+	}
+
+	for _, s := range h.structs {
+		if err := g.mirror(s); err != nil {
+			return nil, err
+		}
+	}
+	for _, o := range h.opaque {
+		g.printf("// %s is the opaque C type %s.\n", g.typeName(o), o)
+		g.printf("type %s C.%s\n\n", g.typeName(o), o)
+	}
+	for _, f := range h.funcs {
+		g.function(f)
+	}
+	g.layoutChecks()
+
+	src := g.buf.Bytes()
+	var imports []byte
+	for _, pkg := range []string{"fmt", "unsafe"} {
+		if bytes.Contains(src[head:], []byte(pkg+".")) {
+			imports = fmt.Appendf(imports, "import %q\n\n", pkg)
+		}
+	}
+	src = append(src[:head:head], append(imports, src[head:]...)...)
+	out, err := format.Source(src)
+	if err != nil {
+		return nil, fmt.Errorf("formatting output: %v\n%s", err, src)
+	}
+	return out, nil
+}
+
+func (g *generator) printf(format string, args ...any) {
+	fmt.Fprintf(&g.buf, format, args...)
+}
+
+// funcName turns a C function name into an exported Go one: with -trim
+// my, myBufferNew becomes BufferNew.
+func (g *generator) funcName(c string) string {
+	s := strings.TrimPrefix(c, g.cfg.trimFunc)
+	if s == "" {
+		s = c
+	}
+	return exported(s)
+}
+
+// typeName names the Go counterpart of a C struct tag or typedef. Type
+// names keep their prefix so that myStruct stays MyStruct.
+func (g *generator) typeName(c string) string {
+	return exported(c)
+}
+
+func exported(s string) string {
+	return strings.ToUpper(s[:1]) + s[1:]
+}
+
+// mirror emits a Go struct with the same layout as the C struct s. Pointer
+// fields become *byte or unsafe.Pointer, so a mirror can be converted to
+// and from the C type without copying.
+func (g *generator) mirror(s *cStruct) error {
+	if s.skip != "" {
+		g.printf("// struct %s: skipped, %s.\n\n", s.name, s.skip)
+		return nil
+	}
+	g.printf("// %s mirrors struct %s.\n", g.typeName(s.name), s.name)
+	g.printf("type %s struct {\n", g.typeName(s.name))
+	for _, f := range s.fields {
+		t, ok := g.fieldType(f.typ)
+		if !ok {
+			return fmt.Errorf("struct %s: field %s has unsupported type %s", s.name, f.name, f.typ)
+		}
+		g.printf("%s %s\n", exported(f.name), t)
+	}
+	g.printf("}\n\n")
+	return nil
+}
+
+func (g *generator) fieldType(t cType) (string, bool) {
+	switch {
+	case t.ptr == 0:
+		if sc, ok := scalars[t.base]; ok {
+			return sc.goType, true
+		}
+		if tag, ok := strings.CutPrefix(t.base, "struct "); ok && g.structs[tag] {
+			return g.typeName(tag), true
+		}
+		return "", false
+	case t.ptr == 1 && t.base == "char":
+		return "*byte", true
+	default:
+		return "unsafe.Pointer", true
+	}
+}
+
+// A conv says how one parameter or result crosses between Go and C.
+type conv struct {
+	goType string
+	pre    string // statements run before the call
+	arg    string // expression passed to C
+	ret    string // expression converting the C result r, for results
+}
+
+func (g *generator) paramConv(p param) (conv, bool) {
+	n, t := p.name, p.typ
+	if t.ptr == 0 {
+		if sc, ok := scalars[t.base]; ok {
+			return conv{goType: sc.goType, arg: fmt.Sprintf("%s(%s)", sc.cgo, n)}, true
+		}
+		if tag, ok := strings.CutPrefix(t.base, "struct "); ok && g.structs[tag] {
+			return conv{
+				goType: g.typeName(tag),
+				arg:    fmt.Sprintf("*(*C.struct_%s)(unsafe.Pointer(&%s))", tag, n),
+			}, true
+		}
+		return conv{}, false
+	}
+	if t.ptr != 1 {
+		return conv{}, false
+	}
+	switch {
+	case t.base == "char":
+		return conv{
+			goType: "string",
+			pre:    fmt.Sprintf("c%s := C.CString(%s)\ndefer C.free(unsafe.Pointer(c%s))\n", n, n, n),
+			arg:    "c" + n,
+		}, true
+	case t.base == "void":
+		return conv{goType: "unsafe.Pointer", arg: n}, true
+	case g.opaque[t.base]:
+		return conv{goType: "*" + g.typeName(t.base), arg: fmt.Sprintf("(*C.%s)(%s)", t.base, n)}, true
+	}
+	if sc, ok := scalars[t.base]; ok {
+		return conv{goType: "*" + sc.goType, arg: fmt.Sprintf("(*%s)(unsafe.Pointer(%s))", sc.cgo, n)}, true
+	}
+	if tag, ok := strings.CutPrefix(t.base, "struct "); ok && g.structs[tag] {
+		return conv{
+			goType: "*" + g.typeName(tag),
+			arg:    fmt.Sprintf("(*C.struct_%s)(unsafe.Pointer(%s))", tag, n),
+		}, true
+	}
+	return conv{}, false
+}
+
+func (g *generator) resultConv(t cType) (conv, bool) {
+	if t.ptr == 0 {
+		if sc, ok := scalars[t.base]; ok {
+			return conv{goType: sc.goType, ret: fmt.Sprintf("%s(r)", sc.goType)}, true
+		}
+		if tag, ok := strings.CutPrefix(t.base, "struct "); ok && g.structs[tag] {
+			return conv{goType: g.typeName(tag), ret: fmt.Sprintf("*(*%s)(unsafe.Pointer(&r))", g.typeName(tag))}, true
+		}
+		return conv{}, false
+	}
+	if t.ptr != 1 {
+		return conv{}, false
+	}
+	switch {
+	case t.base == "char":
+		return conv{goType: "string", ret: "C.GoString(r)"}, true
+	case t.base == "void":
+		return conv{goType: "unsafe.Pointer", ret: "r"}, true
+	case g.opaque[t.base]:
+		return conv{goType: "*" + g.typeName(t.base), ret: fmt.Sprintf("(*%s)(r)", g.typeName(t.base))}, true
+	}
+	return conv{}, false
+}
+
+// function emits a wrapper for f, or a comment saying why it has none.
+func (g *generator) function(f *cFunc) {
+	var params, pre, args []string
+	for _, p := range f.params {
+		if p.typ.base == "..." {
+			g.printf("// %s: skipped, variadic.\n\n", f.name)
+			return
+		}
+		c, ok := g.paramConv(p)
+		if !ok {
+			g.printf("// %s: skipped, parameter %s has unsupported type %s.\n\n", f.name, p.name, p.typ)
+			return
+		}
+		params = append(params, p.name+" "+c.goType)
+		pre = append(pre, c.pre)
+		args = append(args, c.arg)
+	}
+
+	void := f.ret.ptr == 0 && f.ret.base == "void"
+	var res conv
+	if !void {
+		var ok bool
+		if res, ok = g.resultConv(f.ret); !ok {
+			g.printf("// %s: skipped, result has unsupported type %s.\n\n", f.name, f.ret)
+			return
+		}
+	}
+
+	g.printf("// %s calls %s.\n", g.funcName(f.name), f.name)
+	g.printf("func %s(%s) %s {\n", g.funcName(f.name), strings.Join(params, ", "), res.goType)
+	g.printf("%s", strings.Join(pre, ""))
+	call := fmt.Sprintf("C.%s(%s)", f.name, strings.Join(args, ", "))
+	if void {
+		g.printf("%s\n", call)
+	} else {
+		g.printf("r := %s\nreturn %s\n", call, res.ret)
+	}
+	g.printf("}\n\n")
+}
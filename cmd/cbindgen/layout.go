@@ -0,0 +1,107 @@
+package main
+
+import (
+	"go/token"
+	"strings"
+)
+
+// generateLayout writes a C header of static assertions, one per field of
+// every struct that generate mirrors, that the C compiler lays the struct
+// out naturally: each field at the first offset its alignment allows
+// after the one before, and the whole padded only to its own alignment.
+// That is the layout a Go struct with the same fields gets, and what a
+// #pragma pack or -fpack-struct in the library's build would break. The
+// library's source includes the header, so such a build fails to compile
+// instead of producing a library the mirrors no longer fit.
+func generateLayout(h *header, cfg config) ([]byte, error) {
+	g := &generator{cfg: cfg, h: h}
+	guard := strings.ToUpper(strings.NewReplacer(".", "_", "-", "_").Replace(strings.TrimSuffix(cfg.header, ".h"))) + "_LAYOUT_H"
+	prefix := strings.ToUpper(strings.TrimSuffix(cfg.trimDefine, "_"))
+	if prefix == "" {
+		prefix = "CBINDGEN"
+	}
+	assert, alignof, alignUp := prefix+"_LAYOUT_ASSERT", prefix+"_ALIGNOF", prefix+"_ALIGN_UP"
+
+	g.printf("/* Code generated by cbindgen from %s; DO NOT EDIT. */\n\n", cfg.header)
+	g.printf("#ifndef %s\n#define %s\n\n#include <stddef.h>\n\n", guard, guard)
+	g.printf("/*\n * Compilers without C11 get a negative array size instead, and MSVC's\n * __alignof or the offset of a member after a char for _Alignof; the\n * latter is packed along with the structs, so only C11 catches\n * -fpack-struct.\n */\n")
+	g.printf("#if defined(__STDC_VERSION__) && __STDC_VERSION__ >= 201112L\n")
+	g.printf("#define %s(cond, msg) _Static_assert(cond, msg)\n", assert)
+	g.printf("#define %s(t) _Alignof(t)\n", alignof)
+	g.printf("#else\n")
+	g.printf("#define %s_CAT1(a, b) a##b\n", assert)
+	g.printf("#define %s_CAT(a, b) %s_CAT1(a, b)\n", assert, assert)
+	g.printf("#define %s(cond, msg) typedef char %s_CAT(layoutAssert, __LINE__)[(cond) ? 1 : -1]\n", assert, assert)
+	g.printf("#ifdef _MSC_VER\n#define %s(t) __alignof(t)\n#else\n#define %s(t) offsetof(struct { char c; t x; }, x)\n#endif\n", alignof, alignof)
+	g.printf("#endif\n\n")
+	g.printf("#define %s(n, a) (((n) + (a) - 1) / (a) * (a))\n", alignUp)
+
+	for _, s := range h.structs {
+		if s.skip != "" || len(s.fields) == 0 {
+			continue
+		}
+		st := "struct " + s.name
+		end := func(f param) string {
+			return "offsetof(" + st + ", " + f.name + ") + sizeof(((" + st + " *)0)->" + f.name + ")"
+		}
+		g.printf("\n")
+		for i, f := range s.fields {
+			want := "0"
+			if i > 0 {
+				want = alignUp + "(" + end(s.fields[i-1]) + ", " + alignof + "(" + f.typ.String() + "))"
+			}
+			g.printf("%s(offsetof(%s, %s) == %s,\n\t\"%s: padding before %s\");\n", assert, st, f.name, want, st, f.name)
+		}
+		last := s.fields[len(s.fields)-1]
+		g.printf("%s(sizeof(%s) == %s(%s, %s(%s)),\n\t\"%s: padding at the end\");\n", assert, st, alignUp, end(last), alignof, st, st)
+	}
+	g.printf("\n#endif\n")
+	return g.buf.Bytes(), nil
+}
+
+// layoutChecks writes, into a cgo file that has the mirrors, an init
+// function comparing the size, alignment and field offsets of each with
+// those cgo found for its C struct. A mirror can only drift from its
+// struct when the header changes and the file is not regenerated, or
+// when the header's layout depends on the target; either way, init fails
+// naming the first difference instead of letting C and Go disagree
+// about where a field lives.
+func (g *generator) layoutChecks() {
+	var checks []string
+	for _, s := range g.h.structs {
+		if s.skip != "" {
+			continue
+		}
+		goT, cT := g.typeName(s.name)+"{}", "C.struct_"+s.name+"{}"
+		st := "struct " + s.name
+		checks = append(checks,
+			`{"sizeof(`+st+`)", unsafe.Sizeof(`+goT+`), C.sizeof_struct_`+s.name+`}`,
+			`{"_Alignof(`+st+`)", unsafe.Alignof(`+goT+`), unsafe.Alignof(`+cT+`)}`)
+		for _, f := range s.fields {
+			checks = append(checks, `{"offsetof(`+st+`, `+f.name+`)", unsafe.Offsetof(`+goT+`.`+exported(f.name)+`), unsafe.Offsetof(`+cT+`.`+cgoField(f.name)+`)}`)
+		}
+	}
+	if len(checks) == 0 {
+		return
+	}
+	g.printf("// The mirrors above must have the layout of their C structs as cgo\n")
+	g.printf("// compiled %s for this target; init fails at the first difference.\n", g.cfg.header)
+	g.printf("func init() {\n")
+	g.printf("for _, l := range []struct {\nname string\ngoVal, cVal uintptr\n}{\n")
+	for _, c := range checks {
+		g.printf("%s,\n", c)
+	}
+	g.printf("} {\n")
+	g.printf("if l.goVal != l.cVal {\n")
+	g.printf("panic(fmt.Sprintf(\"%s: %%s is %%d in C but %%d in the Go mirror; regenerate with cbindgen\", l.name, l.cVal, l.goVal))\n", g.cfg.pkg)
+	g.printf("}\n}\n}\n\n")
+}
+
+// cgoField returns the name cgo gives the C struct field name in Go: a Go
+// keyword gets a leading underscore.
+func cgoField(name string) string {
+	if token.IsKeyword(name) {
+		return "_" + name
+	}
+	return name
+}
@@ -0,0 +1,111 @@
+// Command cbindgen generates Go bindings from a C header.
+//
+// It reads the integer #defines, structs and function prototypes of a
+// simple library header and writes a cgo file with one Go constant per
+// define, a layout-compatible Go struct per C struct and a thin wrapper
+// per function. Functions whose parameters it cannot map, such as
+// function pointers, are listed in a comment and left to hand-written
+// code. It is meant to be run through go:generate:
+//
+//	//go:generate go run ../../../cmd/cbindgen -header ../../../src/mylib.h -pkg raw -o raw.go
+//
+// With -enums it writes only the header's enums instead, as Go types with
+// String and IsValid methods in a file that does not need cgo. -defines
+// does the same for the #defines, writing their values as Go constants.
+//
+// With -def it writes a Windows module-definition file listing the
+// header's functions as the DLL's exports instead. Each -D names a macro
+// the compiler defines, such as _WIN32 and _MSC_VER for MSVC, and makes
+// cbindgen evaluate the header's #ifdef and #ifndef blocks the way that
+// compiler would rather than drop them:
+//
+//	go run ./cmd/cbindgen -header src/mylib.h -def -D _WIN32 -D _MSC_VER -o src/mylib.def
+//
+// The Go structs a cgo file mirrors C structs with are checked against
+// them by an init function in the same file. With -layout cbindgen
+// writes a C header for the library's source to include instead, whose
+// static assertions stop the library compiling if its structs are not
+// laid out the way those Go structs are:
+//
+//	go run ./cmd/cbindgen -header src/mylib.h -layout -trim-define MYLIB_ -o src/mylib_layout.h
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+func main() {
+	var (
+		headerPath = flag.String("header", "", "C header to read")
+		out        = flag.String("o", "", "output file (default stdout)")
+		enums      = flag.Bool("enums", false, "write only the enums, as plain Go types")
+		defines    = flag.Bool("defines", false, "write only the #defines, as plain Go constants")
+		def        = flag.Bool("def", false, "write a Windows .def file exporting the functions")
+		layout     = flag.Bool("layout", false, "write a C header asserting the mirrored structs' layout")
+		macros     = make(map[string]bool)
+		cfg        config
+	)
+	flag.Func("D", "evaluate #ifdef blocks as if `macro` were defined (repeatable)", func(name string) error {
+		macros[name] = true
+		return nil
+	})
+	flag.StringVar(&cfg.pkg, "pkg", "main", "package name of the generated file")
+	flag.StringVar(&cfg.build, "build", "", "build constraint for the generated file")
+	flag.StringVar(&cfg.pkgConfig, "pkg-config", "", "pkg-config module to link against")
+	flag.StringVar(&cfg.trimFunc, "trim", "", "prefix to drop from C function names")
+	flag.StringVar(&cfg.trimDefine, "trim-define", "", "prefix to drop from #define names")
+	flag.Parse()
+
+	if *headerPath == "" {
+		fmt.Fprintln(os.Stderr, "cbindgen: -header is required")
+		flag.Usage()
+		os.Exit(2)
+	}
+	cfg.header = filepath.Base(*headerPath)
+
+	src, err := os.ReadFile(*headerPath)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "cbindgen:", err)
+		os.Exit(1)
+	}
+	text := string(src)
+	if len(macros) > 0 {
+		if text, err = preprocess(text, macros); err != nil {
+			fmt.Fprintf(os.Stderr, "cbindgen: %s: %v\n", *headerPath, err)
+			os.Exit(1)
+		}
+	}
+	h, err := parseHeader(text)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "cbindgen: %s: %v\n", *headerPath, err)
+		os.Exit(1)
+	}
+	gen := generate
+	switch {
+	case *enums:
+		gen = generateEnums
+	case *defines:
+		gen = generateDefines
+	case *def:
+		gen = generateDef
+	case *layout:
+		gen = generateLayout
+	}
+	code, err := gen(h, cfg)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "cbindgen:", err)
+		os.Exit(1)
+	}
+
+	if *out == "" {
+		os.Stdout.Write(code)
+		return
+	}
+	if err := os.WriteFile(*out, code, 0o644); err != nil {
+		fmt.Fprintln(os.Stderr, "cbindgen:", err)
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,341 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// A header is what cbindgen understands of a C header file.
+type header struct {
+	defines  []define
+	macros   []macro // #defines without a literal value
+	enums    []*cEnum
+	structs  []*cStruct
+	opaque   []string // typedef struct x x; without a definition
+	funcPtrs map[string]bool
+	funcs    []*cFunc
+	vars     []string // extern variables
+}
+
+type define struct {
+	name  string
+	value string // an integer or string literal
+	enum  bool   // an enum constant rather than a #define
+}
+
+// A macro is a #define cbindgen cannot turn into a constant: a
+// function-like macro, or one whose value is an expression.
+type macro struct {
+	name   string
+	params []string // nil unless function is set
+	fn     bool
+}
+
+// A cEnum is an enum definition. Its constants are also recorded as
+// defines.
+type cEnum struct {
+	name   string
+	consts []enumConst
+}
+
+type enumConst struct {
+	name  string
+	value int64
+	label string // the comment after the constant, if any
+}
+
+type cStruct struct {
+	name   string
+	fields []param
+	skip   string // why the struct cannot be mirrored, if it cannot
+}
+
+type cFunc struct {
+	name   string
+	ret    cType
+	params []param
+}
+
+type param struct {
+	name string
+	typ  cType
+}
+
+// A cType is a C type reduced to what the generator needs: the base type
+// name with qualifiers dropped, and the level of indirection.
+type cType struct {
+	base     string // e.g. "int", "unsigned char", "struct myPoint", "myBuffer"
+	ptr      int
+	constant bool
+}
+
+func (t cType) String() string {
+	s := t.base + strings.Repeat("*", t.ptr)
+	if t.constant {
+		s = "const " + s
+	}
+	return s
+}
+
+var (
+	commentRE   = regexp.MustCompile(`(?s)/\*.*?\*/|//[^\n]*`)
+	defineRE    = regexp.MustCompile(`^#\s*define\s+([A-Za-z_]\w*)\s+(-?(?:0[xX][0-9a-fA-F]+|\d+)|"(?:[^"\\]|\\.)*")\s*$`)
+	macroRE     = regexp.MustCompile(`^#\s*define\s+([A-Za-z_]\w*)(\(([^)]*)\))?\s+\S`)
+	structRE    = regexp.MustCompile(`(?s)^struct\s+(\w+)\s*\{(.*)\}$`)
+	enumRE      = regexp.MustCompile(`(?s)^enum\s+(\w+)\s*\{(.*)\}$`)
+	enumConstRE = regexp.MustCompile(`^([A-Za-z_]\w*)\s*(?:=\s*(-?(?:0[xX][0-9a-fA-F]+|\d+)))?$`)
+	labelRE     = regexp.MustCompile(`(?m)^\s*([A-Za-z_]\w*)\s*(?:=[^,/]*)?,?\s*/\*\s*(.*?)\s*\*/\s*$`)
+	opaqueRE    = regexp.MustCompile(`^typedef\s+struct\s+(\w+)\s+(\w+)$`)
+	funcPtrRE   = regexp.MustCompile(`^typedef\s+.+\(\s*\*\s*(\w+)\s*\)\s*\(.*\)$`)
+	externRE    = regexp.MustCompile(`^extern\s+[^(]+?\b(\w+)$`)
+	funcRE      = regexp.MustCompile(`(?s)^(.+?)\b(\w+)\s*\((.*)\)$`)
+	spaceRE     = regexp.MustCompile(`\s+`)
+	callConvRE  = regexp.MustCompile(`^(__stdcall|__cdecl|WINAPI|APIENTRY|CALLBACK|[A-Z][A-Z0-9_]*_(STDCALL|CDECL))$`)
+	conditionRE = regexp.MustCompile(`^#\s*(if|ifdef|ifndef)\b`)
+	endifRE     = regexp.MustCompile(`^#\s*endif\b`)
+)
+
+// parseHeader parses the declarations in src. It handles the subset of C
+// found in a simple library header: integer and string #defines, enums with integer
+// values, struct definitions, opaque struct typedefs, function pointer
+// typedefs, function prototypes and extern variables. Structs with
+// unions, bitfields or arrays are recorded but not parsed. Code inside #if blocks is platform-specific and
+// is skipped, as is anything else it does not recognize.
+func parseHeader(src string) (*header, error) {
+	h := &header{funcPtrs: make(map[string]bool)}
+
+	// A comment on the same line as an enum constant labels it; gather
+	// those before the comments go.
+	labels := make(map[string]string)
+	for _, m := range labelRE.FindAllStringSubmatch(src, -1) {
+		labels[m[1]] = m[2]
+	}
+	src = commentRE.ReplaceAllString(src, " ")
+
+	// Preprocessor lines first: collect defines, drop conditional blocks
+	// and includes.
+	var body strings.Builder
+	depth := 0
+	for _, line := range strings.Split(src, "\n") {
+		trimmed := strings.TrimSpace(line)
+		switch {
+		case conditionRE.MatchString(trimmed):
+			depth++
+			continue
+		case endifRE.MatchString(trimmed):
+			if depth == 0 {
+				return nil, fmt.Errorf("unbalanced #endif")
+			}
+			depth--
+			continue
+		case depth > 0:
+			continue
+		case strings.HasPrefix(trimmed, "#"):
+			if m := defineRE.FindStringSubmatch(trimmed); m != nil {
+				h.defines = append(h.defines, define{name: m[1], value: m[2]})
+			} else if m := macroRE.FindStringSubmatch(trimmed); m != nil {
+				h.macros = append(h.macros, parseMacro(m))
+			}
+			continue
+		}
+		body.WriteString(line)
+		body.WriteByte('\n')
+	}
+	if depth != 0 {
+		return nil, fmt.Errorf("unterminated #if")
+	}
+
+	for _, decl := range splitDecls(body.String()) {
+		decl = strings.TrimSpace(spaceRE.ReplaceAllString(decl, " "))
+		if decl == "" {
+			continue
+		}
+
+		if m := structRE.FindStringSubmatch(decl); m != nil {
+			s := &cStruct{name: m[1]}
+			h.structs = append(h.structs, s)
+			// A Go struct cannot reproduce the layout of unions,
+			// bitfields or member arrays reliably.
+			switch {
+			case strings.Contains(m[2], "union"):
+				s.skip = "has a union member"
+				continue
+			case strings.Contains(m[2], ":"):
+				s.skip = "has bitfields"
+				continue
+			case strings.Contains(m[2], "["):
+				s.skip = "has an array member"
+				continue
+			}
+			for _, f := range strings.Split(m[2], ";") {
+				if f = strings.TrimSpace(f); f == "" {
+					continue
+				}
+				p, err := parseParam(f)
+				if err != nil {
+					return nil, fmt.Errorf("struct %s: %v", s.name, err)
+				}
+				s.fields = append(s.fields, p)
+			}
+			continue
+		}
+		if m := enumRE.FindStringSubmatch(decl); m != nil {
+			e, err := parseEnum(m[1], m[2], labels)
+			if err != nil {
+				return nil, err
+			}
+			h.enums = append(h.enums, e)
+			for _, c := range e.consts {
+				h.defines = append(h.defines, define{name: c.name, value: strconv.FormatInt(c.value, 10), enum: true})
+			}
+			continue
+		}
+		if m := opaqueRE.FindStringSubmatch(decl); m != nil {
+			h.opaque = append(h.opaque, m[2])
+			continue
+		}
+		if m := funcPtrRE.FindStringSubmatch(decl); m != nil {
+			h.funcPtrs[m[1]] = true
+			continue
+		}
+		if strings.HasPrefix(decl, "typedef") {
+			continue
+		}
+		if m := externRE.FindStringSubmatch(decl); m != nil {
+			h.vars = append(h.vars, m[1])
+			continue
+		}
+		if m := funcRE.FindStringSubmatch(decl); m != nil {
+			f := &cFunc{name: m[2], ret: parseType(m[1])}
+			args := strings.TrimSpace(m[3])
+			if args != "" && args != "void" {
+				for i, a := range strings.Split(args, ",") {
+					p, err := parseParam(a)
+					if err != nil {
+						return nil, fmt.Errorf("%s: %v", f.name, err)
+					}
+					if p.name == "" {
+						p.name = fmt.Sprintf("arg%d", i)
+					}
+					f.params = append(f.params, p)
+				}
+			}
+			h.funcs = append(h.funcs, f)
+		}
+	}
+	return h, nil
+}
+
+// parseMacro turns a match of macroRE into a macro.
+func parseMacro(m []string) macro {
+	mc := macro{name: m[1], fn: m[2] != ""}
+	for _, p := range strings.Split(m[3], ",") {
+		if p = strings.TrimSpace(p); p != "" {
+			mc.params = append(mc.params, p)
+		}
+	}
+	return mc
+}
+
+// parseEnum parses the body of enum name. A constant without a value is
+// one more than the one before it, as in C.
+func parseEnum(name, body string, labels map[string]string) (*cEnum, error) {
+	e := &cEnum{name: name}
+	next := int64(0)
+	for _, c := range strings.Split(body, ",") {
+		if c = strings.TrimSpace(c); c == "" {
+			continue
+		}
+		m := enumConstRE.FindStringSubmatch(c)
+		if m == nil {
+			return nil, fmt.Errorf("enum %s: unsupported constant %q", name, c)
+		}
+		if m[2] != "" {
+			v, err := strconv.ParseInt(m[2], 0, 64)
+			if err != nil {
+				return nil, fmt.Errorf("enum %s: %v", name, err)
+			}
+			next = v
+		}
+		e.consts = append(e.consts, enumConst{m[1], next, labels[m[1]]})
+		next++
+	}
+	return e, nil
+}
+
+// splitDecls splits src at semicolons outside braces and parentheses.
+func splitDecls(src string) []string {
+	var decls []string
+	depth, start := 0, 0
+	for i, c := range src {
+		switch c {
+		case '{', '(':
+			depth++
+		case '}', ')':
+			depth--
+		case ';':
+			if depth == 0 {
+				decls = append(decls, src[start:i])
+				start = i + 1
+			}
+		}
+	}
+	return append(decls, src[start:])
+}
+
+// parseParam parses a declaration such as "const char *s" or "int n".
+// A lone type, as in an unnamed prototype parameter, has no name.
+func parseParam(s string) (param, error) {
+	s = strings.TrimSpace(s)
+	if strings.ContainsAny(s, "[(") {
+		return param{}, fmt.Errorf("unsupported declaration %q", s)
+	}
+
+	i := strings.LastIndexAny(s, " *")
+	if i < 0 {
+		return param{typ: parseType(s)}, nil
+	}
+	name := s[i+1:]
+	if isTypeWord(name) {
+		return param{typ: parseType(s)}, nil
+	}
+	return param{name: name, typ: parseType(s[:i+1])}, nil
+}
+
+// isTypeWord reports whether w can only be part of a type, which tells a
+// parameter name from the last word of an unnamed parameter's type.
+func isTypeWord(w string) bool {
+	switch w {
+	case "int", "char", "short", "long", "unsigned", "signed", "double", "float", "void", "size_t":
+		return true
+	}
+	return false
+}
+
+func parseType(s string) cType {
+	var t cType
+	t.ptr = strings.Count(s, "*")
+	var words []string
+	for _, w := range strings.Fields(strings.ReplaceAll(s, "*", " ")) {
+		switch w {
+		case "const":
+			t.constant = true
+		case "extern", "static", "inline", "volatile", "signed":
+		default:
+			// A calling convention, such as __stdcall, is for the
+			// C compiler: cgo calls through the header's declaration
+			// and the generated code needs none.
+			if callConvRE.MatchString(w) {
+				continue
+			}
+			words = append(words, w)
+		}
+	}
+	t.base = strings.Join(words, " ")
+	if t.base == "unsigned" {
+		t.base = "unsigned int"
+	}
+	return t
+}
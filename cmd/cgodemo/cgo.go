@@ -0,0 +1,123 @@
+//go:build !nocgo && !windows
+
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"runtime"
+	"strings"
+	"time"
+
+	"github.com/lxwagn/using-go-with-c-libraries/pkg/cmem"
+	"github.com/lxwagn/using-go-with-c-libraries/pkg/mylib"
+)
+
+var leakTargets string
+
+func init() {
+	register(&scenario{
+		name:    "async",
+		summary: "let C threads fill pinned Go buffers after the call returns",
+		n:       100,
+		run:     runAsync,
+	})
+	register(&scenario{
+		name:    "leakcheck",
+		summary: "run other scenarios and check that they leave no C memory behind",
+		n:       100,
+		flags: func(fs *flag.FlagSet) {
+			fs.StringVar(&leakTargets, "scenarios", "strings,structs,callbacks,sessions,async", "comma-separated `names` of the scenarios to run")
+		},
+		run: runLeakcheck,
+	})
+}
+
+func runAsync(c *config) error {
+	const size = 4 << 10
+	want := make([]byte, size)
+	mylib.Fill(want, 7)
+	sum := mylib.Checksum(want)
+
+	ops := make([]*mylib.FillOp, c.n)
+	for i := range ops {
+		op, err := mylib.FillAsync(make([]byte, size), 7, time.Duration(i%10)*100*time.Microsecond)
+		if err != nil {
+			return err
+		}
+		ops[i] = op
+	}
+	c.logf("%d fills started", len(ops))
+	for i, op := range ops {
+		got, err := op.Wait()
+		if err != nil {
+			return err
+		}
+		if got != sum {
+			return fmt.Errorf("fill %d: checksum %#x, want %#x", i, got, sum)
+		}
+	}
+	c.logf("%d fills done, each with checksum %#x", len(ops), sum)
+	return nil
+}
+
+// live is what leakcheck compares before and after.
+type live struct {
+	cmem    uint64 // allocations through pkg/cmem
+	library int64  // allocations inside the C library
+	buffers int
+}
+
+func readLive() (live, error) {
+	// Some C objects are freed by cleanups, which run after a collection.
+	runtime.GC()
+	runtime.GC()
+	n, err := mylib.Allocations()
+	return live{cmem.ReadStats().Live(), n, mylib.LiveBuffers()}, err
+}
+
+func runLeakcheck(c *config) error {
+	var targets []*scenario
+	for _, name := range strings.Split(leakTargets, ",") {
+		s := lookup(strings.TrimSpace(name))
+		if s == nil || s.name == "leakcheck" {
+			return fmt.Errorf("-scenarios: cannot check %q", name)
+		}
+		targets = append(targets, s)
+	}
+
+	quiet := &config{n: 1, out: io.Discard}
+	// A first run sets up what the scenarios keep for the life of the
+	// program, which is not a leak.
+	for _, s := range targets {
+		if err := s.run(quiet); err != nil {
+			return fmt.Errorf("%s: %w", s.name, err)
+		}
+	}
+	cmem.EnableStats()
+	before, err := readLive()
+	if err != nil {
+		return err
+	}
+	for i := 0; i < c.n; i++ {
+		for _, s := range targets {
+			if err := s.run(quiet); err != nil {
+				return fmt.Errorf("%s: %w", s.name, err)
+			}
+		}
+	}
+	after, err := readLive()
+	if err != nil {
+		return err
+	}
+	c.logf("allocations live before: %+v, after: %+v", before, after)
+	if after != before {
+		if cmem.Tracking {
+			cmem.DumpLeaks(os.Stderr)
+		}
+		return fmt.Errorf("live C allocations went from %+v to %+v; build with -tags cmemdbg to see where the pkg/cmem ones were made", before, after)
+	}
+	return nil
+}
@@ -0,0 +1,191 @@
+// Command cgodemo runs one interop scenario at a time against pkg/mylib,
+// so that each can be tried, timed and compared without editing code:
+//
+//	cgodemo list                  # the scenarios
+//	cgodemo structs -n 100000     # one of them, 100000 times
+//	cgodemo callbacks -v          # with a line per step
+//	cgodemo all                   # every scenario, once each
+//
+// Every scenario takes -n, the number of iterations, -v, and -trace, which
+// writes an execution trace for go tool trace with each call into C as a
+// task named after its wrapper (see mylib.TraceMode). It prints one line
+// with the time taken when it is done, and fails with a non-zero exit
+// status if the library returned something other than expected.
+//
+// The strings, structs, callbacks, sessions and cancel scenarios use only
+// what every build of pkg/mylib has, so they run the same with cgo, with
+// -tags nocgo and on Windows; async and leakcheck need cgo (see
+// cgo.go). cmd/demo stays the single smoke test the build modes in the
+// Makefile are checked with.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"runtime/trace"
+	"slices"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/lxwagn/using-go-with-c-libraries/pkg/mylib"
+)
+
+// A scenario drives one part of the library.
+type scenario struct {
+	name    string
+	summary string
+	n       int // the default for -n
+	// flags adds the scenario's own flags, if it has any.
+	flags func(fs *flag.FlagSet)
+	run   func(c *config) error
+}
+
+// A config is what a scenario is run with.
+type config struct {
+	n       int
+	verbose bool
+	trace   string
+	out     io.Writer
+}
+
+// logf prints a line when -v is set.
+func (c *config) logf(format string, args ...any) {
+	if c.verbose {
+		fmt.Fprintf(c.out, "  "+format+"\n", args...)
+	}
+}
+
+var scenarios []*scenario
+
+func register(s *scenario) {
+	scenarios = append(scenarios, s)
+}
+
+func lookup(name string) *scenario {
+	for _, s := range scenarios {
+		if s.name == name {
+			return s
+		}
+	}
+	return nil
+}
+
+func main() {
+	log.SetFlags(0)
+	log.SetPrefix("cgodemo: ")
+	slices.SortFunc(scenarios, func(a, b *scenario) int { return strings.Compare(a.name, b.name) })
+
+	if len(os.Args) < 2 || os.Args[1] == "list" || os.Args[1] == "help" || os.Args[1] == "-h" {
+		usage(os.Stdout)
+		return
+	}
+
+	name, args := os.Args[1], os.Args[2:]
+	if name == "all" {
+		fs := flag.NewFlagSet("all", flag.ExitOnError)
+		verbose := fs.Bool("v", false, "log each step")
+		fs.Parse(args)
+		failed := false
+		for _, s := range scenarios {
+			c := &config{out: os.Stdout}
+			scenarioFlags(s, c).Parse(nil)
+			c.n, c.verbose = 1, *verbose
+			if err := runScenario(s, c); err != nil {
+				log.Print(err)
+				failed = true
+			}
+		}
+		if failed {
+			os.Exit(1)
+		}
+		return
+	}
+
+	s := lookup(name)
+	if s == nil {
+		fmt.Fprintf(os.Stderr, "cgodemo: no scenario %q\n\n", name)
+		usage(os.Stderr)
+		os.Exit(2)
+	}
+	c := &config{out: os.Stdout}
+	fs := scenarioFlags(s, c)
+	fs.Parse(args)
+	if fs.NArg() > 0 || c.n < 1 {
+		fs.Usage()
+		os.Exit(2)
+	}
+	if err := runScenario(s, c); err != nil {
+		log.Fatal(err)
+	}
+}
+
+// scenarioFlags returns the flags of s, which set c. Defining them sets
+// the scenario's own to their defaults.
+func scenarioFlags(s *scenario, c *config) *flag.FlagSet {
+	fs := flag.NewFlagSet(s.name, flag.ExitOnError)
+	fs.IntVar(&c.n, "n", s.n, "run `count` iterations")
+	fs.BoolVar(&c.verbose, "v", false, "log each step")
+	fs.StringVar(&c.trace, "trace", "", "write an execution trace with a task per C call to `file`")
+	if s.flags != nil {
+		s.flags(fs)
+	}
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "usage: cgodemo %s [flags]\n\n%s.\n\n", s.name, s.summary)
+		fs.PrintDefaults()
+	}
+	return fs
+}
+
+// runScenario runs s and reports how long it took.
+func runScenario(s *scenario, c *config) error {
+	if c.trace != "" {
+		stop, err := startTrace(c.trace)
+		if err != nil {
+			return err
+		}
+		defer stop()
+	}
+	start := time.Now()
+	if err := s.run(c); err != nil {
+		return fmt.Errorf("%s: %w", s.name, err)
+	}
+	d := time.Since(start)
+	fmt.Fprintf(c.out, "%-10s %d iteration(s) in %v (%v each)\n", s.name, c.n, d.Round(time.Microsecond), (d / time.Duration(c.n)).Round(time.Nanosecond))
+	return nil
+}
+
+// startTrace starts writing an execution trace to file, with every call
+// into C a task and a region, and returns the function that stops it.
+func startTrace(file string) (stop func(), err error) {
+	f, err := os.Create(file)
+	if err != nil {
+		return nil, err
+	}
+	if err := trace.Start(f); err != nil {
+		f.Close()
+		return nil, err
+	}
+	prev := mylib.SetTraceMode(mylib.TraceTasks)
+	return func() {
+		mylib.SetTraceMode(prev)
+		trace.Stop()
+		if err := f.Close(); err != nil {
+			log.Print(err)
+		}
+	}, nil
+}
+
+func usage(w io.Writer) {
+	fmt.Fprintln(w, "usage: cgodemo scenario [-n count] [-v] [flags]")
+	fmt.Fprintln(w, "       cgodemo all [-v]")
+	fmt.Fprintln(w, "\nscenarios:")
+	tw := tabwriter.NewWriter(w, 0, 8, 2, ' ', 0)
+	for _, s := range scenarios {
+		fmt.Fprintf(tw, "  %s\t%s\n", s.name, s.summary)
+	}
+	tw.Flush()
+}
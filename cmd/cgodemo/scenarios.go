@@ -0,0 +1,180 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/lxwagn/using-go-with-c-libraries/pkg/mylib"
+)
+
+func init() {
+	register(&scenario{
+		name:    "strings",
+		summary: "pass text to C and back, as UTF-8 and as wchar_t",
+		n:       1000,
+		run:     runStrings,
+	})
+	register(&scenario{
+		name:    "structs",
+		summary: "return structs by value and translate an array of them in place",
+		n:       100000,
+		run:     runStructs,
+	})
+	register(&scenario{
+		name:    "callbacks",
+		summary: "call Go from C, through a callback, opaque userdata and a C function pointer",
+		n:       1000,
+		run:     runCallbacks,
+	})
+	register(&scenario{
+		name:    "sessions",
+		summary: "use independent C objects behind opaque pointers",
+		n:       1000,
+		run:     runSessions,
+	})
+	register(&scenario{
+		name:    "cancel",
+		summary: "stop a long C call by cancelling its context",
+		n:       10,
+		run:     runCancel,
+	})
+}
+
+// wideSamples include characters outside the Basic Multilingual Plane,
+// which are surrogate pairs where wchar_t is 2 bytes wide.
+var wideSamples = []string{"hello", "héllo wörld", "日本語", "a😀b"}
+
+func runStrings(c *config) error {
+	if c.verbose {
+		if err := mylib.Print("Hello from a C library function"); err != nil {
+			return err
+		}
+	}
+	for i := 0; i < c.n; i++ {
+		for _, s := range wideSamples {
+			r, err := mylib.WideReverse(s)
+			if err != nil {
+				return err
+			}
+			back, err := mylib.WideReverse(r)
+			if err != nil {
+				return err
+			}
+			if back != s {
+				return fmt.Errorf("WideReverse twice turned %q into %q", s, back)
+			}
+			if i == 0 {
+				c.logf("%q reversed in C: %q", s, r)
+			}
+		}
+	}
+	if c.verbose {
+		if _, err := mylib.Logf("%d samples, longest %-6s|, %5.1f%% done", len(wideSamples), "héllo wörld", 100.0); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func runStructs(c *config) error {
+	pts := []mylib.Point{{X: 1, Y: 2, Weight: 0.5}, {X: 3, Y: 4, Weight: 1}}
+	for i := 0; i < c.n; i++ {
+		s, err := mylib.MakeStruct(i, "made in C")
+		if err != nil {
+			return err
+		}
+		if s, err = mylib.ScaleStruct(s, 2); err != nil {
+			return err
+		}
+		if s.A != 2*i || s.B != "made in C" {
+			return fmt.Errorf("MakeStruct(%d) scaled by 2 = %+v", i, s)
+		}
+		mylib.TranslatePoints(pts, 1, -1)
+	}
+	if want := 1 + c.n; int(pts[0].X) != want || int(pts[1].Y) != 4-c.n {
+		return fmt.Errorf("points after %d translations: %+v", c.n, pts)
+	}
+	c.logf("points after %d translations: %+v", c.n, pts)
+	return nil
+}
+
+func runCallbacks(c *config) error {
+	for i := 0; i < c.n; i++ {
+		total := mylib.CallN(4, func(j int) int {
+			if i == 0 {
+				c.logf("Go callback %d called from C", j)
+			}
+			return j
+		})
+		if total != 0+1+2+3 {
+			return fmt.Errorf("CallN(4) summed the callbacks to %d, want 6", total)
+		}
+	}
+
+	counts, err := mylib.CountWords("hello from go hello from c")
+	if err != nil {
+		return err
+	}
+	if counts["hello"] != 2 || counts["go"] != 1 {
+		return fmt.Errorf("CountWords: %v", counts)
+	}
+	c.logf("words counted through opaque userdata: %v", counts)
+
+	r, err := mylib.GetReducer("sum")
+	if err != nil {
+		return err
+	}
+	got := r.Reduce([]int32{1, 2, 3, 4})
+	if got != 10 {
+		return fmt.Errorf("sum reducer: %d, want 10", got)
+	}
+	c.logf("1+2+3+4 through a C function pointer: %d", got)
+	return nil
+}
+
+func runSessions(c *config) error {
+	names := []string{"apples", "pears"}
+	sessions := make([]*mylib.Session, len(names))
+	for i, name := range names {
+		s, err := mylib.NewSession(name, int64(c.n)*int64(len(name)))
+		if err != nil {
+			return err
+		}
+		defer s.Close()
+		sessions[i] = s
+	}
+	for i := 0; i < c.n; i++ {
+		for _, s := range sessions {
+			if _, err := s.Add(int64(len(s.Name()))); err != nil {
+				return err
+			}
+		}
+	}
+	for _, s := range sessions {
+		total, calls, err := s.Stats()
+		if err != nil {
+			return err
+		}
+		if calls != c.n || total != int64(c.n*len(s.Name())) {
+			return fmt.Errorf("session %s: total %d after %d calls", s.Name(), total, calls)
+		}
+		c.logf("session %s: total %d after %d calls", s.Name(), total, calls)
+	}
+	return nil
+}
+
+func runCancel(c *config) error {
+	for i := 0; i < c.n; i++ {
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+		start := time.Now()
+		_, err := mylib.Crunch(ctx, 1<<62)
+		cancel()
+		if !errors.Is(err, context.DeadlineExceeded) {
+			return fmt.Errorf("Crunch with a 10ms deadline: %v", err)
+		}
+		c.logf("C call stopped %v after it started", time.Since(start).Round(time.Millisecond))
+	}
+	return nil
+}
@@ -0,0 +1,327 @@
+// Command covmerge merges Go and C coverage into one report. It reads
+// any number of, told apart by their contents:
+//
+//   - Go profiles, as written by go test -coverprofile or go tool covdata
+//     textfmt;
+//   - gcov's JSON, from gcov --json-format --stdout, for a library built
+//     with gcc --coverage;
+//   - lcov tracefiles, such as llvm-cov export -format=lcov writes for a
+//     library built with clang's -fprofile-instr-generate
+//     -fcoverage-mapping.
+//
+// It writes the lines of every file, with the number of times each ran,
+// as an lcov tracefile to -o, which genhtml and most editors can show,
+// and prints how many of each file's lines ran, and the totals for Go and
+// C. Paths are made relative to the current directory, and Go import
+// paths relative to the module in go.mod there, so the same source file
+// named by two inputs is counted once. Counts for the same line add up.
+//
+//	go run ./cmd/covmerge -o cover/merged.info cover/go.txt cover/c.json
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// A profile holds, for each file, the count of each of its lines.
+type profile map[string]map[int]int64
+
+func (p profile) add(file string, line int, n int64) {
+	if p[file] == nil {
+		p[file] = make(map[int]int64)
+	}
+	p[file][line] += n
+}
+
+func main() {
+	var (
+		out    = flag.String("o", "", "write the merged lcov tracefile to `file`")
+		module = flag.String("module", "", "strip the module `path` from Go files (default: from go.mod)")
+	)
+	flag.Usage = func() {
+		fmt.Fprintln(os.Stderr, "usage: covmerge [-o file] [-module path] profile...")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	if flag.NArg() == 0 {
+		flag.Usage()
+		os.Exit(2)
+	}
+	if *module == "" {
+		*module = modulePath("go.mod")
+	}
+
+	p := make(profile)
+	for _, name := range flag.Args() {
+		if err := read(p, name, *module); err != nil {
+			fmt.Fprintf(os.Stderr, "covmerge: %s: %v\n", name, err)
+			os.Exit(1)
+		}
+	}
+	if *out != "" {
+		var b bytes.Buffer
+		writeLcov(&b, p)
+		if err := os.WriteFile(*out, b.Bytes(), 0o644); err != nil {
+			fmt.Fprintln(os.Stderr, "covmerge:", err)
+			os.Exit(1)
+		}
+	}
+	summarize(os.Stdout, p)
+}
+
+// read adds the profile in file name to p, whichever kind it is.
+func read(p profile, name, module string) error {
+	data, err := os.ReadFile(name)
+	if err != nil {
+		return err
+	}
+	trimmed := bytes.TrimSpace(data)
+	switch {
+	case bytes.HasPrefix(trimmed, []byte("mode:")):
+		return readGo(p, data, module)
+	case bytes.HasPrefix(trimmed, []byte("{")):
+		return readGcov(p, data)
+	case bytes.HasPrefix(trimmed, []byte("TN:")), bytes.HasPrefix(trimmed, []byte("SF:")):
+		return readLcov(p, data)
+	}
+	return fmt.Errorf("not a Go profile, gcov JSON or lcov tracefile")
+}
+
+// readGo reads a Go profile, whose lines after the first are
+//
+//	import/path/file.go:startLine.startCol,endLine.endCol statements count
+//
+// A line counts as run as often as the block covering it that ran most,
+// since a line can hold the end of one block and the start of another;
+// counts of the same block in different runs add up.
+func readGo(p profile, data []byte, module string) error {
+	blocks := make(map[string]int64)
+	var order []string
+	sc := bufio.NewScanner(bytes.NewReader(data))
+	for sc.Scan() {
+		line := sc.Text()
+		if line == "" || strings.HasPrefix(line, "mode:") {
+			continue
+		}
+		i := strings.LastIndexByte(line, ' ')
+		if i < 0 {
+			return fmt.Errorf("bad line %q", line)
+		}
+		n, err := strconv.ParseInt(line[i+1:], 10, 64)
+		if err != nil {
+			return fmt.Errorf("bad line %q", line)
+		}
+		j := strings.LastIndexByte(line[:i], ' ')
+		if j < 0 {
+			return fmt.Errorf("bad line %q", line)
+		}
+		block := line[:j]
+		if _, ok := blocks[block]; !ok {
+			order = append(order, block)
+		}
+		blocks[block] += n
+	}
+	if err := sc.Err(); err != nil {
+		return err
+	}
+
+	lines := make(profile)
+	for _, block := range order {
+		colon := strings.LastIndexByte(block, ':')
+		var start, end, col int
+		if colon < 0 {
+			return fmt.Errorf("bad block %q", block)
+		}
+		if _, err := fmt.Sscanf(block[colon+1:], "%d.%d,%d.%d", &start, &col, &end, &col); err != nil {
+			return fmt.Errorf("bad block %q", block)
+		}
+		file := goFile(block[:colon], module)
+		if lines[file] == nil {
+			lines[file] = make(map[int]int64)
+		}
+		for l := start; l <= end; l++ {
+			if n, ok := lines[file][l]; !ok || blocks[block] > n {
+				lines[file][l] = blocks[block]
+			}
+		}
+	}
+	for file, counts := range lines {
+		for l, n := range counts {
+			p.add(file, l, n)
+		}
+	}
+	return nil
+}
+
+// goFile returns the path of a Go file named by its import path, relative
+// to the module if it is in it.
+func goFile(name, module string) string {
+	if module != "" {
+		if rest, ok := strings.CutPrefix(name, module+"/"); ok {
+			return rest
+		}
+	}
+	return relative(name)
+}
+
+// readGcov reads gcov's JSON, one document for each source file given to
+// gcov, named relative to the directory gcov ran in.
+func readGcov(p profile, data []byte) error {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	for {
+		var doc struct {
+			Dir   string `json:"current_working_directory"`
+			Files []struct {
+				File  string `json:"file"`
+				Lines []struct {
+					Line  int   `json:"line_number"`
+					Count int64 `json:"count"`
+				} `json:"lines"`
+			} `json:"files"`
+		}
+		if err := dec.Decode(&doc); err == io.EOF {
+			return nil
+		} else if err != nil {
+			return err
+		}
+		for _, f := range doc.Files {
+			file := f.File
+			if !filepath.IsAbs(file) {
+				file = filepath.Join(doc.Dir, file)
+			}
+			file = relative(file)
+			for _, l := range f.Lines {
+				p.add(file, l.Line, l.Count)
+			}
+		}
+	}
+}
+
+// readLcov reads the SF and DA records of an lcov tracefile.
+func readLcov(p profile, data []byte) error {
+	var file string
+	sc := bufio.NewScanner(bytes.NewReader(data))
+	for sc.Scan() {
+		line := strings.TrimSpace(sc.Text())
+		switch {
+		case strings.HasPrefix(line, "SF:"):
+			file = relative(line[len("SF:"):])
+		case strings.HasPrefix(line, "DA:"):
+			f := strings.Split(line[len("DA:"):], ",")
+			if len(f) < 2 || file == "" {
+				return fmt.Errorf("bad record %q", line)
+			}
+			l, err1 := strconv.Atoi(f[0])
+			n, err2 := strconv.ParseInt(f[1], 10, 64)
+			if err1 != nil || err2 != nil {
+				return fmt.Errorf("bad record %q", line)
+			}
+			p.add(file, l, n)
+		case line == "end_of_record":
+			file = ""
+		}
+	}
+	return sc.Err()
+}
+
+// relative returns name relative to the current directory if it is
+// within it, with slashes.
+func relative(name string) string {
+	if filepath.IsAbs(name) {
+		if wd, err := os.Getwd(); err == nil {
+			if rel, err := filepath.Rel(wd, name); err == nil && !strings.HasPrefix(rel, "..") {
+				name = rel
+			}
+		}
+	}
+	return filepath.ToSlash(filepath.Clean(name))
+}
+
+// modulePath returns the module path declared in the go.mod file name,
+// or "" if there is none.
+func modulePath(name string) string {
+	data, err := os.ReadFile(name)
+	if err != nil {
+		return ""
+	}
+	for line := range strings.Lines(string(data)) {
+		if rest, ok := strings.CutPrefix(strings.TrimSpace(line), "module "); ok {
+			return strings.Trim(strings.TrimSpace(rest), `"`)
+		}
+	}
+	return ""
+}
+
+func files(p profile) []string {
+	names := make([]string, 0, len(p))
+	for name := range p {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	return names
+}
+
+func writeLcov(w io.Writer, p profile) {
+	for _, file := range files(p) {
+		fmt.Fprintf(w, "SF:%s\n", file)
+		lines := make([]int, 0, len(p[file]))
+		for l := range p[file] {
+			lines = append(lines, l)
+		}
+		slices.Sort(lines)
+		hit := 0
+		for _, l := range lines {
+			n := p[file][l]
+			if n > 0 {
+				hit++
+			}
+			fmt.Fprintf(w, "DA:%d,%d\n", l, n)
+		}
+		fmt.Fprintf(w, "LF:%d\nLH:%d\nend_of_record\n", len(lines), hit)
+	}
+}
+
+// summarize prints the lines run out of those that could be, by file and
+// in total for each language.
+func summarize(w io.Writer, p profile) {
+	type total struct{ hit, all int }
+	var langs [2]total // Go, C
+	for _, file := range files(p) {
+		var t total
+		for _, n := range p[file] {
+			t.all++
+			if n > 0 {
+				t.hit++
+			}
+		}
+		lang := 1
+		if strings.HasSuffix(file, ".go") {
+			lang = 0
+		}
+		langs[lang].hit += t.hit
+		langs[lang].all += t.all
+		fmt.Fprintf(w, "%-50s %5d/%-5d %5.1f%%\n", file, t.hit, t.all, percent(t.hit, t.all))
+	}
+	for i, name := range []string{"total Go", "total C"} {
+		if t := langs[i]; t.all > 0 {
+			fmt.Fprintf(w, "%-50s %5d/%-5d %5.1f%%\n", name, t.hit, t.all, percent(t.hit, t.all))
+		}
+	}
+}
+
+func percent(hit, all int) float64 {
+	if all == 0 {
+		return 0
+	}
+	return 100 * float64(hit) / float64(all)
+}
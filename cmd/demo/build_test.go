@@ -0,0 +1,134 @@
+//go:build cgo && linux
+
+package main
+
+import (
+	"debug/elf"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// makeLib runs src/Makefile with args, as the top-level Makefile's
+// targets do before building the demo, skipping the test in -short mode
+// or without make.
+func makeLib(t *testing.T, args ...string) {
+	t.Helper()
+	if testing.Short() {
+		t.Skip("builds the library")
+	}
+	if _, err := exec.LookPath("make"); err != nil {
+		t.Skip(err)
+	}
+	cmd := exec.Command("make", append([]string{"-C", filepath.Join("..", "..", "src")}, args...)...)
+	if out, err := cmd.CombinedOutput(); err != nil {
+		t.Fatalf("make %s: %v\n%s", strings.Join(args, " "), err, out)
+	}
+}
+
+// build builds the demo with tags into a temporary directory, with the
+// variables in env replacing those of the same name in the environment,
+// and returns the binary's path. It skips the test in -short mode.
+func build(t *testing.T, tags string, env ...string) string {
+	t.Helper()
+	if testing.Short() {
+		t.Skip("builds a binary")
+	}
+	gotool, err := exec.LookPath("go")
+	if err != nil {
+		t.Skip(err)
+	}
+	bin := filepath.Join(t.TempDir(), "demo")
+	cmd := exec.Command(gotool, "build", "-tags", tags, "-o", bin, ".")
+	cmd.Env = os.Environ()
+	for _, kv := range env {
+		name, _, _ := strings.Cut(kv, "=")
+		cmd.Env = slices.DeleteFunc(cmd.Env, func(e string) bool { return strings.HasPrefix(e, name+"=") })
+	}
+	cmd.Env = append(cmd.Env, env...)
+	if out, err := cmd.CombinedOutput(); err != nil {
+		t.Fatalf("go build -tags %s: %v\n%s", tags, err, out)
+	}
+	return bin
+}
+
+// importedLibraries returns the shared libraries the ELF file at path
+// needs at run time.
+func importedLibraries(t *testing.T, path string) []string {
+	t.Helper()
+	f, err := elf.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	libs, err := f.ImportedLibraries()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return libs
+}
+
+// runDemo runs the binary and checks that it prints the library's greeting.
+func runDemo(t *testing.T, name string, args ...string) {
+	t.Helper()
+	out, err := exec.Command(name, args...).CombinedOutput()
+	if err != nil {
+		t.Fatalf("%v\n%s", err, out)
+	}
+	if !strings.Contains(string(out), "Hello") {
+		t.Errorf("output has no greeting:\n%s", out)
+	}
+}
+
+// TestStatic builds the demo as make static does, against libmylib.a and
+// a static libc, and checks that it needs no shared library at all.
+func TestStatic(t *testing.T) {
+	makeLib(t, "static")
+	bin := build(t, "static")
+	if libs := importedLibraries(t, bin); len(libs) > 0 {
+		t.Errorf("the static demo needs shared libraries %q", libs)
+	}
+	runDemo(t, bin)
+}
+
+// TestSource builds the demo as make source does, compiling the copy of
+// the library in pkg/mylib/csrc without pkg-config, and checks that the
+// copy is up to date and the binary does not need libmylib.so.
+func TestSource(t *testing.T) {
+	if testing.Short() {
+		t.Skip("builds a binary")
+	}
+	vendorc := exec.Command("go", "run", "../../cmd/vendorc", "-check", "-o", "csrc",
+		"../../src/mylib.c", "../../src/mylib.h", "../../src/mylib_layout.h")
+	vendorc.Dir = filepath.Join("..", "..", "pkg", "mylib")
+	if out, err := vendorc.CombinedOutput(); err != nil {
+		t.Errorf("pkg/mylib/csrc is out of date with src (go generate ./pkg/mylib): %v\n%s", err, out)
+	}
+
+	bin := build(t, "mylib_source", "PKG_CONFIG_PATH=")
+	for _, lib := range importedLibraries(t, bin) {
+		if strings.HasPrefix(lib, "libmylib") {
+			t.Errorf("the mylib_source demo needs %s", lib)
+		}
+	}
+	runDemo(t, bin)
+}
+
+// TestMusl builds the demo as make musl does, against musl rather than
+// glibc, and runs it with cmd/scratchrun in an empty root. It needs
+// musl-gcc, or the compiler named by $MUSL_CC.
+func TestMusl(t *testing.T) {
+	cc := os.Getenv("MUSL_CC")
+	if cc == "" {
+		cc = "musl-gcc"
+	}
+	if _, err := exec.LookPath(cc); err != nil {
+		t.Skip(err)
+	}
+	makeLib(t, "static", "CC="+cc, "OUT=../lib/musl")
+	bin := build(t, "static,musl,netgo,osusergo", "CGO_ENABLED=1", "CC="+cc)
+	runDemo(t, "go", "run", "../scratchrun", bin)
+}
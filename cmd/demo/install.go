@@ -0,0 +1,299 @@
+package main
+
+import (
+	"debug/elf"
+	"debug/macho"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"strings"
+
+	"github.com/lxwagn/using-go-with-c-libraries/pkg/libpath"
+)
+
+// "demo install" copies the running program and the library it uses into
+// PREFIX/bin and PREFIX/lib, so that the copy runs from any directory and
+// the tree can be moved as a whole.
+//
+// A program linked against libmylib finds it through its rpath. The one
+// pkg-config gives names the directory the library was built in, which
+// the copy must not depend on, so the installed program must have
+// $ORIGIN/../lib instead: the dynamic linker replaces $ORIGIN with the
+// program's own directory. On macOS, @loader_path/../lib does the same,
+// and the program must refer to the library as @rpath/libmylib.dylib.
+// Building with -tags mylib_origin gives the Linux rpath (see
+// pkg/mylib/link_origin.go). Otherwise install rewrites the copy with
+// patchelf or install_name_tool when one is on PATH, and fails when
+// neither is.
+//
+// The nocgo and Windows builds load the library themselves and already
+// look in ../lib next to the executable (see pkg/libpath), so they need
+// no rewriting.
+
+// originRpath is the rpath that finds PREFIX/lib from PREFIX/bin.
+func originRpath() string {
+	if runtime.GOOS == "darwin" {
+		return "@loader_path/../lib"
+	}
+	return "$ORIGIN/../lib"
+}
+
+// A binary is what install needs to know about an executable: the rpath
+// entries it has, and the libmylib files it is linked against, by the
+// names it refers to them with.
+type binary struct {
+	rpaths []string
+	libs   []string
+}
+
+func install(args []string) error {
+	fs := flag.NewFlagSet("install", flag.ExitOnError)
+	prefix := fs.String("prefix", "dist", "install under `dir`")
+	libDir := fs.String("lib", "", "take the library from `dir` instead of where the program finds it")
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "usage: demo install [-prefix dir] [-lib dir]\n")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+	if fs.NArg() > 0 {
+		fs.Usage()
+		os.Exit(2)
+	}
+
+	exe, err := os.Executable()
+	if err != nil {
+		return err
+	}
+	if exe, err = filepath.EvalSymlinks(exe); err != nil {
+		return err
+	}
+	bin, err := readBinary(exe)
+	if err != nil {
+		return err
+	}
+
+	// A program that is not linked against the library loads it by the
+	// name pkg/mylib uses.
+	linked := len(bin.libs) > 0
+	if !linked {
+		name := "libmylib.so"
+		if runtime.GOOS == "windows" {
+			name = "mylib.dll"
+		}
+		bin.libs = []string{name}
+	}
+
+	var dirs []string
+	if *libDir != "" {
+		dirs = []string{*libDir}
+	} else {
+		if linked {
+			dirs = expandRpaths(bin.rpaths, filepath.Dir(exe))
+		}
+		dirs = append(dirs, fileDirs(libpath.Dirs())...)
+	}
+
+	binDir := filepath.Join(*prefix, "bin")
+	destLib := filepath.Join(*prefix, "lib")
+	for _, dir := range []string{binDir, destLib} {
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			return err
+		}
+	}
+
+	var installed []string
+	for _, name := range bin.libs {
+		base := filepath.Base(name)
+		src, err := libpath.Search(base, dirs, func(path string) error {
+			_, err := os.Stat(path)
+			return err
+		})
+		if err != nil {
+			return fmt.Errorf("install: %w", err)
+		}
+		dst := filepath.Join(destLib, base)
+		if err := copyFile(dst, src, 0o755); err != nil {
+			return err
+		}
+		installed = append(installed, dst)
+	}
+
+	dst := filepath.Join(binDir, filepath.Base(exe))
+	if err := copyFile(dst, exe, 0o755); err != nil {
+		return err
+	}
+	if linked {
+		if err := relocate(dst, bin, installed); err != nil {
+			os.Remove(dst)
+			return err
+		}
+	}
+
+	fmt.Printf("installed %s\n", dst)
+	for _, lib := range installed {
+		fmt.Printf("installed %s\n", lib)
+	}
+	return nil
+}
+
+// readBinary reads the rpath and the libmylib dependencies of the
+// executable at path. Formats other than ELF and Mach-O, such as Windows
+// PE, have neither.
+func readBinary(path string) (binary, error) {
+	var b binary
+	isMylib := func(name string) bool {
+		return strings.HasPrefix(filepath.Base(name), "libmylib.")
+	}
+
+	if f, err := elf.Open(path); err == nil {
+		defer f.Close()
+		for _, tag := range []elf.DynTag{elf.DT_RUNPATH, elf.DT_RPATH} {
+			vals, err := f.DynString(tag)
+			if err != nil {
+				return b, err
+			}
+			for _, v := range vals {
+				b.rpaths = append(b.rpaths, filepath.SplitList(v)...)
+			}
+		}
+		libs, err := f.ImportedLibraries()
+		if err != nil {
+			return b, err
+		}
+		b.libs = slices.DeleteFunc(libs, func(s string) bool { return !isMylib(s) })
+		return b, nil
+	}
+
+	if f, err := macho.Open(path); err == nil {
+		defer f.Close()
+		for _, l := range f.Loads {
+			if r, ok := l.(*macho.Rpath); ok {
+				b.rpaths = append(b.rpaths, r.Path)
+			}
+		}
+		libs, err := f.ImportedLibraries()
+		if err != nil {
+			return b, err
+		}
+		b.libs = slices.DeleteFunc(libs, func(s string) bool { return !isMylib(s) })
+		return b, nil
+	}
+	return b, nil
+}
+
+// expandRpaths returns the directories rpaths name for a program in dir.
+func expandRpaths(rpaths []string, dir string) []string {
+	var dirs []string
+	for _, r := range rpaths {
+		for _, v := range []string{"$ORIGIN", "${ORIGIN}", "@loader_path", "@executable_path"} {
+			r = strings.ReplaceAll(r, v, dir)
+		}
+		if !strings.Contains(r, "$") && !strings.HasPrefix(r, "@") {
+			dirs = append(dirs, r)
+		}
+	}
+	return dirs
+}
+
+// fileDirs replaces the dynamic linker's search path in dirs, the empty
+// string, with the directories of LD_LIBRARY_PATH or DYLD_LIBRARY_PATH,
+// the part of it where a file can be looked for by name.
+func fileDirs(dirs []string) []string {
+	env := "LD_LIBRARY_PATH"
+	switch runtime.GOOS {
+	case "darwin":
+		env = "DYLD_LIBRARY_PATH"
+	case "windows":
+		env = "PATH"
+	}
+	var out []string
+	for _, dir := range dirs {
+		if dir != "" {
+			out = append(out, dir)
+			continue
+		}
+		for _, d := range filepath.SplitList(os.Getenv(env)) {
+			if d != "" {
+				out = append(out, d)
+			}
+		}
+	}
+	return out
+}
+
+// relocate makes the installed program at path find the installed
+// libraries relative to itself, rewriting it if its rpath does not.
+func relocate(path string, bin binary, libs []string) error {
+	want := originRpath()
+	if runtime.GOOS != "darwin" {
+		if slices.Equal(bin.rpaths, []string{want}) {
+			return nil
+		}
+		if _, err := exec.LookPath("patchelf"); err != nil {
+			return fmt.Errorf("install: %s has rpath %q rather than %s, and patchelf is not installed to change it; build it with -tags mylib_origin",
+				path, strings.Join(bin.rpaths, ":"), want)
+		}
+		return run("patchelf", "--set-rpath", want, path)
+	}
+
+	if _, err := exec.LookPath("install_name_tool"); err != nil {
+		return fmt.Errorf("install: install_name_tool is needed to relocate %s", path)
+	}
+	if !slices.Contains(bin.rpaths, want) {
+		if err := run("install_name_tool", "-add_rpath", want, path); err != nil {
+			return err
+		}
+	}
+	for i, name := range bin.libs {
+		rel := "@rpath/" + filepath.Base(name)
+		if name == rel {
+			continue
+		}
+		if err := run("install_name_tool", "-change", name, rel, path); err != nil {
+			return err
+		}
+		if err := run("install_name_tool", "-id", rel, libs[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func run(name string, args ...string) error {
+	out, err := exec.Command(name, args...).CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("install: %s: %v\n%s", name, err, out)
+	}
+	return nil
+}
+
+// copyFile copies src to dst with the given mode, replacing dst by
+// renaming over it, so that a running copy of an older dst is not
+// disturbed.
+func copyFile(dst, src string, mode os.FileMode) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	tmp, err := os.CreateTemp(filepath.Dir(dst), ".install-*")
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(tmp, in)
+	err = errors.Join(err, tmp.Chmod(mode), tmp.Close())
+	if err == nil {
+		err = os.Rename(tmp.Name(), dst)
+	}
+	if err != nil {
+		os.Remove(tmp.Name())
+	}
+	return err
+}
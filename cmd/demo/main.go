@@ -0,0 +1,95 @@
+// Command demo prints a greeting from a C library function and from
+// inline C, using the pkg/mylib binding.
+//
+// With -profile it then runs a CPU-bound workload for the given time,
+// and with -pprof it serves net/http/pprof, to look at time spent in C
+// (see profile.go).
+//
+// "demo install -prefix dir" copies the program and libmylib into dir/bin
+// and dir/lib, set up to run from anywhere (see install.go).
+//
+// cmd/cgodemo runs each of these parts on its own, repeatedly and timed.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/lxwagn/using-go-with-c-libraries/pkg/mylib"
+)
+
+func main() {
+	if len(os.Args) > 1 && os.Args[1] == "install" {
+		if err := install(os.Args[2:]); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+
+	pprofAddr := flag.String("pprof", "", "serve net/http/pprof on `addr`")
+	profile := flag.Duration("profile", 0, "run the profiling workload for `duration`")
+	flag.Parse()
+
+	if *pprofAddr != "" {
+		servePprof(*pprofAddr)
+	}
+
+	fmt.Println("-------------------------------")
+
+	// C Library
+	if err := mylib.Print("Hello from a C library function"); err != nil {
+		log.Fatal(err)
+	}
+
+	// Optional parts of the library, found at run time
+	fmt.Println(mylib.Features())
+
+	// Inline C
+	mylib.PrintInline()
+
+	// Go callback invoked from C
+	total := mylib.CallN(4, func(i int) int {
+		fmt.Printf("Hello from a Go callback (%d)\n", i)
+		return i
+	})
+	fmt.Println("Sum returned to Go:", total)
+
+	// Go struct passed through C as opaque userdata
+	counts, err := mylib.CountWords("hello from go hello from c")
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Println("Word counts:", counts)
+
+	// Two independent stateful C objects behind opaque pointers
+	for _, name := range []string{"apples", "pears"} {
+		s, err := mylib.NewSession(name, 100)
+		if err != nil {
+			log.Fatal(err)
+		}
+		s.Add(int64(len(name)))
+		total, calls, _ := s.Stats()
+		fmt.Printf("Session %s: total %d after %d call(s)\n", s.Name(), total, calls)
+		s.Close()
+	}
+
+	// C function pointer obtained at run time
+	sum, err := mylib.GetReducer("sum")
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Println("Reduced in C through a function pointer:", sum.Reduce([]int32{1, 2, 3, 4}))
+
+	// Variadic C function called through fixed-arity shims
+	if _, err := mylib.Logf("%d words, longest %-6s|, %5.1f%% done", len(counts), "hello", 99.5); err != nil {
+		log.Fatal(err)
+	}
+
+	fmt.Println("-------------------------------")
+
+	if *profile > 0 {
+		profileWorkload(*profile)
+	}
+}
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"hash/adler32"
+	"log"
+	"net/http"
+	_ "net/http/pprof"
+	"time"
+
+	// Names the C frames in CPU profiles on linux/amd64 and arm64.
+	_ "github.com/lxwagn/using-go-with-c-libraries/pkg/ctraceback"
+	"github.com/lxwagn/using-go-with-c-libraries/pkg/mylib"
+)
+
+// servePprof serves net/http/pprof's handlers on addr in the background.
+func servePprof(addr string) {
+	go func() {
+		log.Printf("pprof: serving on http://%s/debug/pprof/", addr)
+		log.Print(http.ListenAndServe(addr, nil))
+	}()
+}
+
+// profileWorkload keeps the CPU busy for d with work split between C and
+// Go, so that a profile shows where the time inside C goes:
+//
+//	go run ./cmd/demo -pprof localhost:6060 -profile 30s &
+//	go tool pprof -top 'http://localhost:6060/debug/pprof/profile?seconds=20'
+//
+// About half the samples land in myCrunch, a pure C loop, and most of
+// the rest in myChecksum, against a few percent in hash/adler32, which
+// computes the same Adler-32 over the same buffer in Go. With
+// pkg/ctraceback the C functions appear by name below the _Cfunc_
+// wrappers; without it all of their samples are charged to
+// runtime.cgocall.
+//
+// perf profiles the Go side as it is, since Go keeps frame pointers. To
+// walk the stack through libmylib, build it with frame pointers and
+// debug symbols (cd src; make profile):
+//
+//	perf record -g bin/demo -profile 10s
+//	perf report
+func profileWorkload(d time.Duration) {
+	buf := make([]byte, 4<<20)
+	mylib.Fill(buf, 7)
+
+	var calls, sum int64
+	deadline := time.Now().Add(d)
+	for time.Now().Before(deadline) {
+		n, err := mylib.Crunch(context.Background(), 2_000_000)
+		if err != nil {
+			log.Fatal(err)
+		}
+		c := mylib.Checksum(buf)
+		g := adler32.Checksum(buf)
+		if c != g {
+			log.Fatalf("myChecksum = %#x, adler32.Checksum = %#x", c, g)
+		}
+		sum += n & 0xff
+		calls++
+	}
+	fmt.Printf("Profiled workload: %d rounds in %v (%d)\n", calls, d, sum)
+}
@@ -0,0 +1,92 @@
+/*
+ * A C program with Go linked in statically. Build libgoarchive.a first;
+ * see ../main.go or the goarchive target of the top-level Makefile.
+ *
+ * Each step checks one rule of sharing a process with the Go runtime and
+ * exits non-zero if it does not hold, so the program doubles as a test.
+ */
+#include <signal.h>
+#include <stdio.h>
+#include <stdlib.h>
+#include <string.h>
+#include <time.h>
+#include <unistd.h>
+
+#include "libgoarchive.h"
+
+static volatile sig_atomic_t gotUsr2;
+
+static void onUsr2(int sig) {
+	(void)sig;
+	gotUsr2 = 1;
+}
+
+static void fail(const char *msg) {
+	fprintf(stderr, "FAIL: %s\n", msg);
+	exit(1);
+}
+
+/* Signals handled by Go are delivered on a Go goroutine, asynchronously
+ * to raise/kill returning, so give them a moment to arrive. */
+static int waitCount(int sig, int want) {
+	struct timespec ts = {0, 10 * 1000 * 1000};
+	int i;
+
+	for (i = 0; i < 100; i++) {
+		if (GoSignalCount(sig) >= want)
+			return 1;
+		nanosleep(&ts, NULL);
+	}
+	return 0;
+}
+
+int main(void) {
+	struct sigaction sa;
+	sigset_t set;
+
+	/* 1. The runtime was started by a constructor before main; the first
+	 * exported call waits for it to be ready. */
+	if (GoRuntimeReady() != 1)
+		fail("Go runtime not ready");
+	if (GoMultiply(6, 7) != 42)
+		fail("GoMultiply");
+	printf("ok   runtime initialized, GoMultiply(6, 7) = 42\n");
+
+	/* 2. Go only receives asynchronous signals it asked for. */
+	GoWatchSignal(SIGUSR1);
+	raise(SIGUSR1);
+	if (!waitCount(SIGUSR1, 1))
+		fail("SIGUSR1 not delivered to Go");
+	printf("ok   SIGUSR1 delivered to os/signal\n");
+
+	/* 3. C may still handle signals Go does not watch. The handler must
+	 * use SA_ONSTACK: it can run on a Go thread, whose stack is too small
+	 * for C, and Go gives each thread an alternate signal stack. */
+	memset(&sa, 0, sizeof(sa));
+	sa.sa_handler = onUsr2;
+	sa.sa_flags = SA_ONSTACK | SA_RESTART;
+	sigemptyset(&sa.sa_mask);
+	if (sigaction(SIGUSR2, &sa, NULL) != 0)
+		fail("sigaction");
+	raise(SIGUSR2);
+	if (!gotUsr2)
+		fail("SIGUSR2 not delivered to the C handler");
+	printf("ok   SIGUSR2 delivered to the C handler\n");
+
+	/* 4. The runtime's threads were created before this point and keep
+	 * the signal mask they started with. Blocking SIGUSR1 here only
+	 * affects the main thread, so a signal sent to the process still
+	 * reaches Go through one of them. Block signals before the runtime
+	 * starts (for example in a constructor that runs earlier) to keep
+	 * them away from Go entirely. */
+	sigemptyset(&set);
+	sigaddset(&set, SIGUSR1);
+	if (sigprocmask(SIG_BLOCK, &set, NULL) != 0)
+		fail("sigprocmask");
+	kill(getpid(), SIGUSR1);
+	if (!waitCount(SIGUSR1, 2))
+		fail("SIGUSR1 blocked in main thread did not reach Go");
+	printf("ok   SIGUSR1 reached Go while blocked in the main thread\n");
+
+	return 0;
+}
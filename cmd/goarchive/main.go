@@ -0,0 +1,87 @@
+// Command goarchive is built as a static C library:
+//
+//	go build -buildmode=c-archive -o lib/libgoarchive.a ./cmd/goarchive
+//
+// The archive contains the whole Go runtime. Linking it into a C program
+// (host/host.c) adds a constructor that starts the runtime when the
+// program is loaded, before C's main runs; every exported function waits
+// for that start-up to finish before running.
+//
+// The functions below let the host check how the Go runtime and the C
+// program share signals, which is the part of embedding Go that most
+// often goes wrong.
+package main
+
+import "C"
+
+import (
+	"os"
+	"os/signal"
+	"sync"
+	"sync/atomic"
+	"syscall"
+)
+
+// GoRuntimeReady returns 1. Calling it from C blocks until the Go runtime
+// is initialized, so it is a cheap way for the host to make sure of that.
+//
+//export GoRuntimeReady
+func GoRuntimeReady() C.int {
+	return 1
+}
+
+// GoMultiply returns a * b.
+//
+//export GoMultiply
+func GoMultiply(a, b C.int) C.int {
+	return a * b
+}
+
+var (
+	watchMu sync.Mutex
+	counts  = map[syscall.Signal]*atomic.Int32{}
+)
+
+// GoWatchSignal has Go handle sig with os/signal, counting deliveries.
+// In a c-archive the Go runtime installs handlers only for the
+// synchronous signals (SIGSEGV, SIGBUS, SIGFPE ...) by itself; any other
+// signal reaches Go only after signal.Notify asks for it.
+//
+//export GoWatchSignal
+func GoWatchSignal(sig C.int) {
+	s := syscall.Signal(sig)
+
+	watchMu.Lock()
+	defer watchMu.Unlock()
+	if counts[s] != nil {
+		return
+	}
+
+	n := new(atomic.Int32)
+	counts[s] = n
+
+	ch := make(chan os.Signal, 16)
+	signal.Notify(ch, s)
+	go func() {
+		for range ch {
+			n.Add(1)
+		}
+	}()
+}
+
+// GoSignalCount returns how many times sig has been delivered to Go since
+// GoWatchSignal(sig).
+//
+//export GoSignalCount
+func GoSignalCount(sig C.int) C.int {
+	watchMu.Lock()
+	n := counts[syscall.Signal(sig)]
+	watchMu.Unlock()
+	if n == nil {
+		return 0
+	}
+	return C.int(n.Load())
+}
+
+// main is required by -buildmode=c-archive but never runs.
+func main() {}
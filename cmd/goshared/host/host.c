@@ -0,0 +1,23 @@
+/*
+ * A C program calling into Go. Build libgoshared.so first; see
+ * ../main.go or the goshared target of the top-level Makefile.
+ */
+#include <stdio.h>
+
+#include "libgoshared.h"
+
+int main(void) {
+	int nums[] = {1, 2, 3, 4, 5};
+	char *greeting, *upper;
+
+	printf("GoAdd(2, 3) = %d\n", GoAdd(2, 3));
+	printf("GoSum(1..5) = %ld\n", GoSum(nums, 5));
+
+	greeting = GoGreet("C");
+	upper = GoUpper(greeting);
+	printf("%s\n%s\n", greeting, upper);
+	GoFree(upper);
+	GoFree(greeting);
+
+	return 0;
+}
@@ -0,0 +1,64 @@
+// Command goshared is built as a C shared library rather than a program:
+//
+//	go build -buildmode=c-shared -o lib/libgoshared.so ./cmd/goshared
+//
+// This is the reverse of pkg/mylib: the functions marked //export below
+// are called from C. The go tool writes their prototypes to
+// lib/libgoshared.h, which host/host.c includes.
+package main
+
+/*
+#include <stdlib.h>
+*/
+import "C"
+
+import (
+	"strings"
+	"unsafe"
+)
+
+// GoAdd returns a + b.
+//
+//export GoAdd
+func GoAdd(a, b C.int) C.int {
+	return a + b
+}
+
+// GoSum returns the sum of the n ints at p. The memory belongs to the C
+// caller and is only read for the duration of the call.
+//
+//export GoSum
+func GoSum(p *C.int, n C.int) C.long {
+	var sum C.long
+	for _, v := range unsafe.Slice(p, int(n)) {
+		sum += C.long(v)
+	}
+	return sum
+}
+
+// GoGreet returns a greeting for name in memory from C's malloc. The C
+// caller owns the result and must release it with GoFree (or free).
+//
+//export GoGreet
+func GoGreet(name *C.char) *C.char {
+	return C.CString("Hello from Go, " + C.GoString(name))
+}
+
+// GoUpper returns an upper-cased copy of s, allocated like GoGreet's
+// result.
+//
+//export GoUpper
+func GoUpper(s *C.char) *C.char {
+	return C.CString(strings.ToUpper(C.GoString(s)))
+}
+
+// GoFree releases a string returned by one of the functions above.
+//
+//export GoFree
+func GoFree(p *C.char) {
+	C.free(unsafe.Pointer(p))
+}
+
+// main is required by -buildmode=c-shared but never runs; the Go runtime
+// is started when the library is loaded.
+func main() {}
@@ -0,0 +1,124 @@
+//go:build linux
+
+// Command scratchrun runs a program in an empty root directory, the way a
+// container built FROM scratch would: the program is the only file there,
+// with no libc, no dynamic linker, no /etc and no /proc. It proves a
+// binary meant for such an image really is self-contained.
+//
+//	go run ./cmd/scratchrun bin/demo-musl [arg...]
+//
+// Before running it, scratchrun checks the ELF file itself and fails if
+// it names a dynamic linker (PT_INTERP) or needs any shared library
+// (DT_NEEDED). The program then runs chrooted into a fresh temporary
+// directory holding only its copy, with an empty environment. Unless
+// scratchrun is run as root, that happens in a new user namespace, where
+// the calling user is root and may chroot. scratchrun exits with the
+// program's status.
+package main
+
+import (
+	"debug/elf"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"syscall"
+)
+
+func main() {
+	log.SetFlags(0)
+	log.SetPrefix("scratchrun: ")
+	flag.Usage = func() {
+		fmt.Fprintln(os.Stderr, "usage: scratchrun program [arg...]")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	if flag.NArg() == 0 {
+		flag.Usage()
+		os.Exit(2)
+	}
+	prog := flag.Arg(0)
+
+	if err := checkStatic(prog); err != nil {
+		log.Fatal(err)
+	}
+
+	root, err := os.MkdirTemp("", "scratch-")
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer os.RemoveAll(root)
+	name := "/" + filepath.Base(prog)
+	if err := copyFile(filepath.Join(root, name), prog); err != nil {
+		log.Fatal(err)
+	}
+
+	cmd := exec.Command(name, flag.Args()[1:]...)
+	cmd.Path = name
+	cmd.Dir = "/"
+	cmd.Env = []string{}
+	cmd.Stdin, cmd.Stdout, cmd.Stderr = os.Stdin, os.Stdout, os.Stderr
+	cmd.SysProcAttr = &syscall.SysProcAttr{Chroot: root}
+	if os.Getuid() != 0 {
+		cmd.SysProcAttr.Cloneflags = syscall.CLONE_NEWUSER | syscall.CLONE_NEWNS
+		cmd.SysProcAttr.UidMappings = []syscall.SysProcIDMap{{ContainerID: 0, HostID: os.Getuid(), Size: 1}}
+		cmd.SysProcAttr.GidMappings = []syscall.SysProcIDMap{{ContainerID: 0, HostID: os.Getgid(), Size: 1}}
+	}
+
+	err = cmd.Run()
+	var exit *exec.ExitError
+	if errors.As(err, &exit) {
+		os.RemoveAll(root)
+		os.Exit(exit.ExitCode())
+	}
+	if err != nil {
+		log.Fatal(err)
+	}
+}
+
+// checkStatic returns an error if the ELF file at path needs anything at
+// run time beyond the kernel.
+func checkStatic(path string) error {
+	f, err := elf.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	for _, p := range f.Progs {
+		if p.Type == elf.PT_INTERP {
+			interp, _ := io.ReadAll(p.Open())
+			return fmt.Errorf("%s is dynamically linked: needs the dynamic linker %s", path, string(interp[:len(interp)-min(len(interp), 1)]))
+		}
+	}
+	libs, err := f.ImportedLibraries()
+	if err != nil {
+		return err
+	}
+	if len(libs) > 0 {
+		return fmt.Errorf("%s needs shared libraries %q", path, libs)
+	}
+	return nil
+}
+
+func copyFile(dst, src string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	out, err := os.OpenFile(dst, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o755)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
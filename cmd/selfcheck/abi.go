@@ -0,0 +1,89 @@
+//go:build linux && !nocgo
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/lxwagn/using-go-with-c-libraries/pkg/mylib"
+	mylib1 "github.com/lxwagn/using-go-with-c-libraries/pkg/mylib/v1"
+	mylib2 "github.com/lxwagn/using-go-with-c-libraries/pkg/mylib/v2"
+)
+
+// openBoth opens 1.x and 2.0 side by side. 2.0 is built only by make v2
+// in src; without it the checks are skipped.
+func openBoth() (*mylib1.Library, *mylib2.Library, error) {
+	l2, err := mylib2.Open("")
+	if err != nil {
+		logf("%v; build it with make -C src v2; skipped", err)
+		return nil, nil, nil
+	}
+	l1, err := mylib1.Open("")
+	if err != nil {
+		l2.Close()
+		return nil, nil, err
+	}
+	return l1, l2, nil
+}
+
+func init() {
+	register("abi/side-by-side", func() error {
+		l1, l2, err := openBoth()
+		if l1 == nil {
+			return err
+		}
+		defer l1.Close()
+		defer l2.Close()
+
+		if v1, v2 := l1.Version(), l2.Version(); v1/10000 != 1 || v2/10000 != 2 {
+			return fmt.Errorf("versions %d and %d", v1, v2)
+		}
+		if _, err := l1.Lookup("tera"); !errors.Is(err, mylib1.ErrNotFound) {
+			return fmt.Errorf("1.x Lookup(tera) = %v, want ErrNotFound", err)
+		}
+		if v, err := l2.Lookup("tera"); err != nil || v != 1e12 {
+			return fmt.Errorf("2.0 Lookup(tera) = %d, %v; want 1e12", v, err)
+		}
+
+		b := []byte("hello, world")
+		sum := l1.Checksum(b)
+		if got := l2.Checksum(l2.Checksum(1, b[:5]), b[5:]); got != sum {
+			return fmt.Errorf("2.0 checksum in two parts %#x, 1.x checksum %#x", got, sum)
+		}
+
+		// dlopen found the 1.x library already linked in, through
+		// pkg/mylib, and 2.0 has a counter of its own.
+		before := mylib.CounterAdd(0)
+		l1.CounterAdd(3)
+		c2 := l2.CounterAdd(0)
+		l2.CounterAdd(5)
+		if got := mylib.CounterAdd(0); got != before+3 {
+			return fmt.Errorf("linked counter %d after 1.x added 3 to %d", got, before)
+		}
+		if got := l2.CounterAdd(0); got != c2+5 {
+			return fmt.Errorf("2.0 counter %d after adding 5 to %d", got, c2)
+		}
+		l1.CounterAdd(-3)
+		return nil
+	})
+
+	// Each binding refuses the other's library rather than calling its
+	// functions with the wrong signatures.
+	register("abi/mismatch", func() error {
+		if _, err := mylib2.Open(mylib2.DefaultName); err != nil {
+			logf("%v; skipped", err)
+			return nil
+		}
+		_, err := mylib2.Open(mylib1.DefaultName)
+		if err == nil || !strings.Contains(err.Error(), mylib2.SymbolVersion) {
+			return fmt.Errorf("2.0 binding on 1.x: %v, want a missing %s symbol", err, mylib2.SymbolVersion)
+		}
+		_, err = mylib1.Open(mylib2.DefaultName)
+		if err == nil || !strings.Contains(err.Error(), "not 1.x") {
+			return fmt.Errorf("1.x binding on 2.0: %v", err)
+		}
+		return nil
+	})
+}
@@ -0,0 +1,85 @@
+//go:build !nocgo && !windows
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/lxwagn/using-go-with-c-libraries/pkg/cmem"
+	"github.com/lxwagn/using-go-with-c-libraries/pkg/mylib"
+)
+
+func init() {
+	// The allocator can only change while the library has nothing
+	// allocated, which earlier checks make hard to promise, so the
+	// switching happens in a fresh process.
+	registerChild("allocators", func() {
+		if err := switchAllocators(); err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+	})
+
+	register("allocator/switch", func() error {
+		out, err := runChild("allocators")
+		switch {
+		case errors.Is(err, errSurvived):
+			return nil
+		case err == nil:
+			return errors.New(strings.TrimSpace(out))
+		}
+		return err
+	})
+}
+
+func switchAllocators() error {
+	if n, err := mylib.Allocations(); err != nil || n != 0 {
+		return fmt.Errorf("%d allocations live at start (%v)", n, err)
+	}
+
+	if err := mylib.SetAllocator(mylib.CountingAllocator); err != nil {
+		return err
+	}
+	before := mylib.AllocatorStats()
+	b, err := mylib.NewBuffer()
+	if err != nil {
+		return err
+	}
+	b.Append("hello")
+	during := mylib.AllocatorStats()
+	if err := mylib.SetAllocator(mylib.GoAllocator); !errors.Is(err, mylib.ErrInvalid) {
+		b.Close()
+		return fmt.Errorf("SetAllocator with a buffer live = %v, want ErrInvalid", err)
+	}
+	b.Close()
+	after := mylib.AllocatorStats()
+	if d := during.Live() - before.Live(); d != 2 || during.LiveBytes <= before.LiveBytes {
+		return fmt.Errorf("counting allocator: %d allocations and %d bytes for a buffer", d, during.LiveBytes-before.LiveBytes)
+	}
+	if after.LiveBytes != before.LiveBytes || after.Frees-before.Frees != 2 {
+		return fmt.Errorf("counting allocator after Close: %+v, before: %+v", after, before)
+	}
+
+	cmem.EnableStats()
+	if err := mylib.SetAllocator(mylib.GoAllocator); err != nil {
+		return err
+	}
+	cbefore := cmem.ReadStats()
+	s, err := mylib.NewSession("allocated in Go", 100)
+	if err != nil {
+		return err
+	}
+	cduring := cmem.ReadStats()
+	s.Close()
+	cafter := cmem.ReadStats()
+	if d := cduring.Live() - cbefore.Live(); d != 2 {
+		return fmt.Errorf("Go allocator: %d allocations in cmem for a session, want 2", d)
+	}
+	if cafter.Live() != cbefore.Live() || cafter.LiveBytes != cbefore.LiveBytes {
+		return fmt.Errorf("Go allocator after Close: %+v, before: %+v", cafter, cbefore)
+	}
+	return mylib.SetAllocator(mylib.SystemAllocator)
+}
@@ -0,0 +1,66 @@
+//go:build !nocgo && !windows
+
+package main
+
+/*
+
+// countArgs counts the strings in a NULL-terminated array, as C code
+// handed an argv must.
+static int countArgs(char **argv) {
+	int n = 0;
+
+	while (argv[n] != NULL)
+		n++;
+	return n;
+}
+
+*/
+import "C"
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+
+	"github.com/lxwagn/using-go-with-c-libraries/pkg/cmem"
+	"github.com/lxwagn/using-go-with-c-libraries/pkg/mylib"
+)
+
+func init() {
+	// The array ends in a NULL that C can find, empty strings included,
+	// and converts back to the same strings.
+	register("argv/roundtrip", func() error {
+		for _, ss := range [][]string{nil, {""}, {"prog", "", "two words", "ünï"}} {
+			p := cmem.CStrings(ss)
+			n := int(C.countArgs((**C.char)(p)))
+			back := cmem.GoStrings(p)
+			backN := cmem.GoStringsN(p, n)
+			cmem.Free(p)
+			if n != len(ss) || !slices.Equal(back, ss) || !slices.Equal(backN, ss) {
+				return fmt.Errorf("%q came back as %d strings, %q and %q", ss, n, back, backN)
+			}
+		}
+		return nil
+	})
+
+	register("argv/parse", func() error {
+		argv := []string{"prog", "-v", "-n", "-42", "--name=cgo", "--", "-v", "file"}
+		a, err := mylib.ParseArgs(argv)
+		if err != nil {
+			return err
+		}
+		want := mylib.Args{Verbose: true, Count: -42, Name: "cgo", Rest: []string{"-v", "file"}}
+		if a.Verbose != want.Verbose || a.Count != want.Count || a.Name != want.Name || !slices.Equal(a.Rest, want.Rest) {
+			return fmt.Errorf("ParseArgs(%q) = %+v, want %+v", argv, a, want)
+		}
+		if a, err := mylib.ParseArgs([]string{"prog"}); err != nil || len(a.Rest) != 0 {
+			return fmt.Errorf("ParseArgs of no arguments = %+v, %v", a, err)
+		}
+		for _, bad := range [][]string{{"prog", "-x"}, {"prog", "-n"}, {"prog", "-n", "12abc"}, {}} {
+			if _, err := mylib.ParseArgs(bad); !errors.Is(err, mylib.ErrInvalid) {
+				return fmt.Errorf("ParseArgs(%q): %v, want ErrInvalid", bad, err)
+			}
+		}
+		return nil
+	})
+}
@@ -0,0 +1,125 @@
+//go:build !nocgo && !windows
+
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"math/rand/v2"
+	"runtime"
+	"sync"
+	"sync/atomic"
+	"time"
+	"weak"
+
+	"github.com/lxwagn/using-go-with-c-libraries/pkg/mylib"
+)
+
+func init() {
+	register("async/fill", func() error {
+		buf := make([]byte, 4096)
+		op, err := mylib.FillAsync(buf, 7, time.Millisecond)
+		if err != nil {
+			return err
+		}
+		sum, err := op.Wait()
+		if err != nil {
+			return err
+		}
+		want := make([]byte, len(buf))
+		mylib.Fill(want, 7)
+		if !bytes.Equal(buf, want) || sum != mylib.Checksum(want) {
+			return fmt.Errorf("async fill differs from Fill, or checksum %#x", sum)
+		}
+		return nil
+	})
+
+	// Dozens of buffers are written by C while the collector runs flat
+	// out and the heap churns. Until the fills are under way the check
+	// holds the buffers only through weak pointers, so the pins alone
+	// keep them alive: a buffer freed or reused under C would show up as
+	// a nil weak pointer, wrong bytes or a wrong checksum.
+	register("async/gc-stress", func() error {
+		var stop atomic.Bool
+		var wg sync.WaitGroup
+		for range 4 {
+			wg.Go(func() {
+				var keep [][]byte
+				for !stop.Load() {
+					keep = append(keep, make([]byte, 1+rand.IntN(8192)))
+					if len(keep) > 256 {
+						keep = keep[:0]
+					}
+					runtime.GC()
+				}
+			})
+		}
+		defer func() {
+			stop.Store(true)
+			wg.Wait()
+		}()
+
+		type job struct {
+			op   *mylib.FillOp
+			buf  weak.Pointer[[64 << 10]byte]
+			n    int
+			seed byte
+		}
+		jobs := make([]job, 64)
+		for i := range jobs {
+			buf := new([64 << 10]byte)
+			// Deliberately dirty, so that a buffer the fill never
+			// reached cannot pass.
+			for j := range buf {
+				buf[j] = 0xa5
+			}
+			n := 1 + rand.IntN(len(buf))
+			op, err := mylib.FillAsync(buf[:n], byte(i), time.Duration(20+rand.IntN(20))*time.Millisecond)
+			if err != nil {
+				return err
+			}
+			jobs[i] = job{op, weak.Make(buf), n, byte(i)}
+		}
+		time.Sleep(10 * time.Millisecond)
+
+		// Take strong references to every buffer at once, while most
+		// fills are still pending, then check them.
+		bufs := make([]*[64 << 10]byte, len(jobs))
+		for i, j := range jobs {
+			bufs[i] = j.buf.Value()
+			if bufs[i] == nil {
+				select {
+				case <-j.op.Done():
+					// Unpinned and then collected, legitimately.
+				default:
+					return fmt.Errorf("buffer %d collected while C was still to fill it", i)
+				}
+			}
+		}
+		verified := 0
+		for i, j := range jobs {
+			sum, err := j.op.Wait()
+			if err != nil {
+				return err
+			}
+			buf := bufs[i]
+			if buf == nil {
+				continue
+			}
+			want := make([]byte, len(buf))
+			for k := range want {
+				want[k] = 0xa5
+			}
+			mylib.Fill(want[:j.n], j.seed)
+			if !bytes.Equal(buf[:], want) || sum != mylib.Checksum(want[:j.n]) {
+				return fmt.Errorf("fill %d of %d bytes came back wrong", i, j.n)
+			}
+			verified++
+		}
+		logf("%d of %d fills verified", verified, len(jobs))
+		if verified == 0 {
+			return fmt.Errorf("every buffer was collected before it could be checked")
+		}
+		return nil
+	})
+}
@@ -0,0 +1,162 @@
+//go:build !nocgo && !windows
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"runtime"
+	"time"
+
+	"github.com/lxwagn/using-go-with-c-libraries/examples/audio"
+)
+
+func init() {
+	register("audio/loopback", func() error {
+		// At 48 kHz, 256 frames is a period of 5.3ms, and the rings
+		// hold 16 periods.
+		st, err := audio.Open(audio.Config{SampleRate: 48000, Channels: 2, FramesPerBuffer: 256, Loopback: true})
+		if err != nil {
+			return err
+		}
+		defer st.Close()
+
+		// A counting signal: after the device's silence before the
+		// first samples arrive, every sample read back must be the
+		// next one, or some were dropped or padded.
+		const total = 48000 // half a second of stereo, in whole chunks
+		next := float32(1)
+		chunk := func() []float32 {
+			p := make([]float32, 1000)
+			for i := range p {
+				p[i] = next
+				next++
+			}
+			return p
+		}
+		if _, err := st.Write(chunk()); err != nil {
+			return err
+		}
+		if err := st.Start(); err != nil {
+			return err
+		}
+		logf("device thread real-time: %v", st.Stats().Realtime)
+		errc := make(chan error, 1)
+		go func() {
+			for next < total {
+				if _, err := st.Write(chunk()); err != nil {
+					errc <- err
+					return
+				}
+			}
+			errc <- nil
+		}()
+
+		buf := make([]float32, 512)
+		want := float32(0)
+		for want < total {
+			n, err := st.Read(buf)
+			if err != nil {
+				return err
+			}
+			for _, v := range buf[:n] {
+				switch {
+				case want == total:
+					// Silence again once the writer is done.
+				case want == 0 && v == 0:
+				case v == want+1:
+					want = v
+				default:
+					return fmt.Errorf("read %v after %v", v, want)
+				}
+			}
+		}
+		if err := <-errc; err != nil {
+			return err
+		}
+		s := st.Stats()
+		logf("%+v", s)
+		if s.Overruns != 0 {
+			return fmt.Errorf("%d overruns", s.Overruns)
+		}
+		return nil
+	})
+
+	register("audio/tone", func() error {
+		st, err := audio.Open(audio.Config{SampleRate: 8000, Channels: 1, FramesPerBuffer: 80})
+		if err != nil {
+			return err
+		}
+		defer st.Close()
+		if err := st.Start(); err != nil {
+			return err
+		}
+		// Nothing is written, so every period is an underrun.
+		buf := make([]float32, 800)
+		if _, err := st.Read(buf); err != nil {
+			return err
+		}
+		var peak float32
+		for _, v := range buf {
+			peak = max(peak, v, -v)
+		}
+		if peak < 0.45 || peak > 0.5 {
+			return fmt.Errorf("tone peak %v, want 0.5", peak)
+		}
+		if err := st.Stop(); err != nil {
+			return err
+		}
+		s := st.Stats()
+		if s.Callbacks < 10 || s.Underruns != s.Callbacks {
+			return fmt.Errorf("%+v, want an underrun per callback", s)
+		}
+
+		// Stopped, the ring runs dry and Read says so instead of
+		// waiting forever.
+		for {
+			if _, err := st.Read(buf); err != nil {
+				if !errors.Is(err, audio.ErrStopped) {
+					return err
+				}
+				break
+			}
+		}
+		st.Close()
+		if _, err := st.Write(buf); !errors.Is(err, audio.ErrClosed) {
+			return fmt.Errorf("Write after Close: %v", err)
+		}
+		return nil
+	})
+
+	register("audio/gc-pauses", func() error {
+		// The device thread never enters Go, so garbage collections,
+		// with their stop-the-world phases, do not hold up its
+		// periods.
+		st, err := audio.Open(audio.Config{SampleRate: 48000, Channels: 1, FramesPerBuffer: 48})
+		if err != nil {
+			return err
+		}
+		defer st.Close()
+		if err := st.Start(); err != nil {
+			return err
+		}
+		start := time.Now()
+		before := st.Stats().Callbacks
+		var keep [][]byte
+		for time.Since(start) < 200*time.Millisecond {
+			for range 100 {
+				keep = append(keep, make([]byte, 64<<10))
+			}
+			keep = keep[:0]
+			runtime.GC()
+		}
+		elapsed := time.Since(start)
+		got := st.Stats().Callbacks - before
+		want := uint64(elapsed / time.Millisecond) // a period is 1ms
+		logf("%d callbacks in %v with GC running, want about %d", got, elapsed, want)
+		if got < want/2 {
+			return fmt.Errorf("%d callbacks in %v, want about %d", got, elapsed, want)
+		}
+		return nil
+	})
+}
@@ -0,0 +1,119 @@
+//go:build !nocgo && !windows
+
+package main
+
+/*
+
+static void takePointer(void *p) {}
+
+*/
+import "C"
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"unsafe"
+
+	"github.com/lxwagn/using-go-with-c-libraries/pkg/cgocheck"
+	"github.com/lxwagn/using-go-with-c-libraries/pkg/cmem"
+)
+
+type node struct {
+	next *node
+	v    int
+}
+
+type cRef struct {
+	s *C.char
+	n int
+}
+
+// A pointerCase is a value and the pointer to it that is passed to C.
+type pointerCase struct {
+	name string
+	arg  any
+	ptr  unsafe.Pointer
+}
+
+func pointerCases() []pointerCase {
+	x := new(int64)
+	n := &node{v: 1}
+	linked := &node{next: &node{}}
+	ints := make([]int64, 4)
+	nils := make([]*int64, 4)
+	ptrs := []*int64{new(int64)}
+	strs := []string{strings.Repeat("x", 3)}
+	ref := &cRef{s: (*C.char)(cmem.CString("c memory")), n: 8}
+
+	return []pointerCase{
+		{"scalar", x, unsafe.Pointer(x)},
+		{"nil-next", n, unsafe.Pointer(n)},
+		{"linked", linked, unsafe.Pointer(linked)},
+		{"ints", ints, unsafe.Pointer(&ints[0])},
+		{"nil-ptrs", nils, unsafe.Pointer(&nils[0])},
+		{"ptrs", ptrs, unsafe.Pointer(&ptrs[0])},
+		{"strings", strs, unsafe.Pointer(&strs[0])},
+		{"c-pointer", ref, unsafe.Pointer(ref)},
+	}
+}
+
+// runtimeAccepts passes p to C and reports whether the runtime's
+// cgocheck=1 test let it through. That test panics in the call.
+func runtimeAccepts(p unsafe.Pointer) (ok bool, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			msg := fmt.Sprint(r)
+			if !strings.Contains(msg, "Go pointer to unpinned Go pointer") {
+				err = fmt.Errorf("unexpected panic: %s", msg)
+			}
+		}
+	}()
+	C.takePointer(p)
+	return true, nil
+}
+
+func init() {
+	// Check must reject exactly what the runtime rejects, for the cases
+	// where it can tell.
+	register("cgocheck/agrees-with-runtime", func() error {
+		for _, c := range pointerCases() {
+			checked := cgocheck.Check(c.arg)
+			ok, err := runtimeAccepts(c.ptr)
+			if err != nil {
+				return fmt.Errorf("%s: %v", c.name, err)
+			}
+			if ok != (checked == nil) {
+				return fmt.Errorf("%s: runtime accepts: %v, Check: %v", c.name, ok, checked)
+			}
+			if checked != nil {
+				logf("%s: %v", c.name, checked)
+			}
+		}
+		return nil
+	})
+
+	register("cgocheck/safepass-stops-the-call", func() error {
+		linked := &node{next: &node{}}
+		called := false
+		err := cgocheck.SafePass(func() {
+			called = true
+			C.takePointer(unsafe.Pointer(linked))
+		}, linked)
+		var cerr *cgocheck.Error
+		if !errors.As(err, &cerr) || cerr.Path != "next" {
+			return fmt.Errorf("SafePass: got %v, want an error about next", err)
+		}
+		if called {
+			return errors.New("SafePass called f with a bad argument")
+		}
+		return nil
+	})
+
+	register("cgocheck/map-rejected", func() error {
+		if err := cgocheck.Check(map[string]int{}); err == nil {
+			return errors.New("Check accepted a map")
+		}
+		return nil
+	})
+}
@@ -0,0 +1,47 @@
+//go:build !nocgo && !windows
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"unsafe"
+
+	"github.com/lxwagn/using-go-with-c-libraries/pkg/cmem"
+)
+
+// With GOEXPERIMENT=cgocheck2, the runtime also checks every pointer
+// store Go code makes, and kills the program when a Go pointer is written
+// to C memory, where the garbage collector cannot see it:
+//
+//	GOEXPERIMENT=cgocheck2 go run ./cmd/selfcheck -run cgocheck2
+
+func init() {
+	registerChild("store-go-pointer-in-c", func() {
+		slot := (*unsafe.Pointer)(cmem.Malloc(int(unsafe.Sizeof(unsafe.Pointer(nil)))))
+		*slot = unsafe.Pointer(new(int))
+	})
+
+	register("cgocheck2/store-go-pointer-in-c", func() error {
+		out, err := runChild("store-go-pointer-in-c")
+		if !cgocheck2Enabled {
+			if errors.Is(err, errSurvived) {
+				logf("not built with GOEXPERIMENT=cgocheck2; store went unnoticed")
+				return nil
+			}
+			return fmt.Errorf("child failed without cgocheck2: %v\n%s", err, out)
+		}
+		if errors.Is(err, errSurvived) {
+			return errors.New("store went undetected")
+		}
+		if err != nil {
+			return err
+		}
+		if !strings.Contains(out, "Go pointer") {
+			return fmt.Errorf("unexpected failure:\n%s", out)
+		}
+		logf("%s", strings.SplitN(strings.TrimSpace(out), "\n", 2)[0])
+		return nil
+	})
+}
@@ -0,0 +1,5 @@
+//go:build !goexperiment.cgocheck2
+
+package main
+
+const cgocheck2Enabled = false
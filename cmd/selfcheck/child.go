@@ -0,0 +1,65 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+)
+
+// Some checks provoke a sanitizer or the runtime into killing the
+// process. They commit the fault in a copy of selfcheck started with
+// childEnv naming a function registered with registerChild, and inspect
+// its output.
+const childEnv = "SELFCHECK_CHILD"
+
+var children = map[string]func(){}
+
+func registerChild(name string, f func()) {
+	children[name] = f
+}
+
+// runAsChild runs the child function named by childEnv, if it is set,
+// and exits. It returns if the variable is not set.
+func runAsChild() {
+	name := os.Getenv(childEnv)
+	if name == "" {
+		return
+	}
+	f, ok := children[name]
+	if !ok {
+		fmt.Fprintf(os.Stderr, "selfcheck: unknown %s=%s\n", childEnv, name)
+		os.Exit(2)
+	}
+	f()
+	flushCoverage()
+	os.Exit(0)
+}
+
+// errSurvived is returned by runChild if the child exited normally.
+var errSurvived = errors.New("child process survived")
+
+// runChild runs the child function name in a new process and returns its
+// combined output. The error is errSurvived if the process exited with
+// status 0, and nil if it failed as intended.
+func runChild(name string) (string, error) {
+	exe, err := os.Executable()
+	if err != nil {
+		return "", err
+	}
+	var out bytes.Buffer
+	cmd := exec.Command(exe)
+	cmd.Env = append(os.Environ(), childEnv+"="+name)
+	cmd.Stdout = &out
+	cmd.Stderr = &out
+	err = cmd.Run()
+	var exit *exec.ExitError
+	switch {
+	case err == nil:
+		return out.String(), errSurvived
+	case errors.As(err, &exit):
+		return out.String(), nil
+	}
+	return out.String(), err
+}
@@ -0,0 +1,141 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"math"
+	"os"
+	"path/filepath"
+	"reflect"
+
+	"github.com/lxwagn/using-go-with-c-libraries/pkg/mylib"
+	"github.com/lxwagn/using-go-with-c-libraries/pkg/mylib/mock"
+)
+
+// sameError reports whether a and b would be handled alike by code that
+// checks errors as pkg/mylib documents: by status, by sentinel, or as a
+// missing file. Their messages may differ, as the errno text does between
+// systems.
+func sameError(a, b error) bool {
+	if (a == nil) != (b == nil) {
+		return false
+	}
+	sa, oka := mylib.StatusOf(a)
+	sb, okb := mylib.StatusOf(b)
+	if sa != sb || oka != okb {
+		return false
+	}
+	for _, target := range []error{mylib.ErrNUL, mylib.ErrClosed, fs.ErrNotExist, context.Canceled} {
+		if errors.Is(a, target) != errors.Is(b, target) {
+			return false
+		}
+	}
+	return true
+}
+
+func init() {
+	// The mock is only useful while it behaves like the library, so the
+	// same calls go to both and must agree.
+	register("client/mock", func() error {
+		real, fake := mylib.Native(), mock.New()
+
+		file := filepath.Join(os.TempDir(), "selfcheck-client")
+		if err := os.WriteFile(file, make([]byte, 1234), 0o644); err != nil {
+			return err
+		}
+		defer os.Remove(file)
+		fake.SetFileSize(file, 1234)
+
+		canceled, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		calls := []struct {
+			name string
+			f    func(c mylib.Client) (any, error)
+		}{
+			{"Lookup(two)", func(c mylib.Client) (any, error) { return c.Lookup("two") }},
+			{"Lookup(four)", func(c mylib.Client) (any, error) { return c.Lookup("four") }},
+			{"Lookup(NUL)", func(c mylib.Client) (any, error) { return c.Lookup("t\x00wo") }},
+			{"FileSize(file)", func(c mylib.Client) (any, error) { return c.FileSize(file) }},
+			{"FileSize(missing)", func(c mylib.Client) (any, error) { return c.FileSize(file + ".missing") }},
+			{"MakeStruct", func(c mylib.Client) (any, error) { return c.MakeStruct(-5, "five") }},
+			{"MakeStruct(big)", func(c mylib.Client) (any, error) { return c.MakeStruct(math.MaxInt32+1, "") }},
+			{"ScaleStruct(overflow)", func(c mylib.Client) (any, error) {
+				return c.ScaleStruct(mylib.MyStruct{A: math.MaxInt32, B: "x"}, 2)
+			}},
+			{"TranslatePoints", func(c mylib.Client) (any, error) {
+				pts := []mylib.Point{{X: 1, Y: 2, Weight: 3}}
+				c.TranslatePoints(pts, -2, 5)
+				return pts, nil
+			}},
+			{"Fill+Checksum", func(c mylib.Client) (any, error) {
+				b := make([]byte, 300)
+				c.Fill(b, 250)
+				return c.Checksum(b), nil
+			}},
+			{"CountWords", func(c mylib.Client) (any, error) { return c.CountWords("a b  a c ") }},
+			{"WideReverse", func(c mylib.Client) (any, error) { return c.WideReverse("a😀bé") }},
+			{"Crunch", func(c mylib.Client) (any, error) { return c.Crunch(context.Background(), 100000) }},
+			{"Crunch(canceled)", func(c mylib.Client) (any, error) { return c.Crunch(canceled, 100000) }},
+			{"Crunch(negative)", func(c mylib.Client) (any, error) { return c.Crunch(context.Background(), -1) }},
+			{"NewSession(negative)", func(c mylib.Client) (any, error) {
+				s, err := c.NewSession("s", -1)
+				return s == nil, err
+			}},
+			{"Session", func(c mylib.Client) (any, error) {
+				s, err := c.NewSession("s", 10)
+				if err != nil {
+					return nil, err
+				}
+				var results []any
+				for _, d := range []int64{4, 5, 2, -11, -19} {
+					t, err := s.Add(d)
+					results = append(results, t, sameError(err, mylib.ErrRange))
+				}
+				total, n, err := s.Stats()
+				results = append(results, total, n, err)
+				results = append(results, s.Reset(), s.Close())
+				_, err = s.Add(1)
+				return results, err
+			}},
+		}
+		for _, call := range calls {
+			rv, rerr := call.f(real)
+			fv, ferr := call.f(fake)
+			if !sameError(rerr, ferr) {
+				return fmt.Errorf("%s: library returned error %v, mock %v", call.name, rerr, ferr)
+			}
+			if rerr == nil && !reflect.DeepEqual(rv, fv) {
+				return fmt.Errorf("%s: library returned %v, mock %v", call.name, rv, fv)
+			}
+		}
+		return nil
+	})
+
+	register("client/inject", func() error {
+		c := mock.New()
+		c.FailOnce("Lookup", mylib.ErrNoMemory)
+		if _, err := c.Lookup("one"); !errors.Is(err, mylib.ErrNoMemory) {
+			return fmt.Errorf("Lookup after FailOnce: %v, want ErrNoMemory", err)
+		}
+		if v, err := c.Lookup("one"); v != 1 || err != nil {
+			return fmt.Errorf("second Lookup: %d, %v, want 1", v, err)
+		}
+		c.FailOn("Session.Add", mylib.ErrRange)
+		s, err := c.NewSession("s", 10)
+		if err != nil {
+			return err
+		}
+		for range 2 {
+			if _, err := s.Add(1); !errors.Is(err, mylib.ErrRange) {
+				return fmt.Errorf("Session.Add after FailOn: %v", err)
+			}
+		}
+		if n := c.Calls("Session.Add"); n != 2 {
+			return fmt.Errorf("Calls(Session.Add) = %d, want 2", n)
+		}
+		return nil
+	})
+}
@@ -0,0 +1,97 @@
+//go:build cmemdbg && !nocgo && !windows
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"unsafe"
+
+	"github.com/lxwagn/using-go-with-c-libraries/pkg/cmem"
+	"github.com/lxwagn/using-go-with-c-libraries/pkg/mylib"
+)
+
+// These checks need allocation tracking:
+//
+//	go run -tags cmemdbg ./cmd/selfcheck -run cmem
+
+func init() {
+	register("cmem/no-leaks", func() error {
+		return withLeakCheck(func() error {
+			s, err := mylib.NewSession("leaks", 10)
+			if err != nil {
+				return err
+			}
+			if _, err := s.Add(1); err != nil {
+				return err
+			}
+			s.Close()
+
+			if _, err := mylib.Lookup("two"); err != nil {
+				return err
+			}
+			if _, err := mylib.CountWords("one two two"); err != nil {
+				return err
+			}
+
+			var b mylib.Batch
+			b.CounterAdd(1)
+			b.Lookup("three")
+			_, err = b.Flush()
+			b.Close()
+			return err
+		})
+	})
+
+	register("cmem/leak-is-reported", func() error {
+		var leaked unsafe.Pointer
+		err := withLeakCheck(func() error {
+			leaked = cmem.CString("leaked")
+			return nil
+		})
+		cmem.Free(leaked)
+		if err == nil {
+			return errors.New("leak not reported")
+		}
+		if !strings.Contains(err.Error(), "cmd/selfcheck/cmem.go:") {
+			return fmt.Errorf("leak reported without its call site: %v", err)
+		}
+		logf("%v", err)
+		return nil
+	})
+}
+
+// withLeakCheck runs f under cmem.CheckLeaks and returns f's error or the
+// leaks CheckLeaks found.
+func withLeakCheck(f func() error) error {
+	var t leakTB
+	cmem.CheckLeaks(&t)
+	err := f()
+	for i := len(t.cleanups) - 1; i >= 0; i-- {
+		t.cleanups[i]()
+	}
+	if err != nil {
+		return err
+	}
+	if len(t.errs) > 0 {
+		return errors.New(strings.Join(t.errs, "; "))
+	}
+	return nil
+}
+
+// leakTB implements cmem.TB for code outside a test.
+type leakTB struct {
+	cleanups []func()
+	errs     []string
+}
+
+func (t *leakTB) Helper() {}
+
+func (t *leakTB) Cleanup(f func()) {
+	t.cleanups = append(t.cleanups, f)
+}
+
+func (t *leakTB) Errorf(format string, args ...any) {
+	t.errs = append(t.errs, fmt.Sprintf(format, args...))
+}
@@ -0,0 +1,83 @@
+//go:build !nocgo && !windows
+
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"expvar"
+	"fmt"
+	"net/http/httptest"
+	"strings"
+
+	"github.com/lxwagn/using-go-with-c-libraries/pkg/cmem"
+	"github.com/lxwagn/using-go-with-c-libraries/pkg/cmetrics"
+)
+
+func init() {
+	register("cmetrics/stats", func() error {
+		cmem.EnableStats()
+		before := cmem.ReadStats()
+		p := cmem.Malloc(100)
+		q := cmem.Calloc(10, 20)
+		mid := cmem.ReadStats()
+		cmem.Free(p)
+		cmem.Free(q)
+		after := cmem.ReadStats()
+
+		if d := mid.LiveBytes - before.LiveBytes; d != 300 {
+			return fmt.Errorf("live bytes grew by %d for 300 bytes allocated", d)
+		}
+		if mid.PeakBytes < mid.LiveBytes {
+			return fmt.Errorf("peak %d below live %d", mid.PeakBytes, mid.LiveBytes)
+		}
+		if after.LiveBytes != before.LiveBytes || after.Live() != before.Live() {
+			return fmt.Errorf("after freeing: %+v, before: %+v", after, before)
+		}
+		if after.Allocs-before.Allocs != 2 || after.Frees-before.Frees != 2 {
+			return fmt.Errorf("two allocations freed: %+v, before: %+v", after, before)
+		}
+		return nil
+	})
+
+	register("cmetrics/expvar", func() error {
+		cmetrics.Publish("cmem")
+		a := cmem.NewArena(0)
+		a.Alloc(1)
+		var s cmem.Stats
+		err := json.Unmarshal([]byte(expvar.Get("cmem").String()), &s)
+		a.Free()
+		if err != nil {
+			return err
+		}
+		if s.LiveBytes < cmem.DefaultBlockSize {
+			return fmt.Errorf("expvar shows %d live bytes with an arena block allocated", s.LiveBytes)
+		}
+		return nil
+	})
+
+	register("cmetrics/prometheus", func() error {
+		rec := httptest.NewRecorder()
+		cmetrics.Handler().ServeHTTP(rec, httptest.NewRequest("GET", "/metrics", nil))
+		s := cmem.ReadStats()
+		body := rec.Body.String()
+		for _, want := range []string{
+			"# TYPE cmem_live_bytes gauge\n",
+			fmt.Sprintf("cmem_live_bytes %d\n", s.LiveBytes),
+			"# TYPE cmem_allocs_total counter\n",
+			fmt.Sprintf("cmem_allocs_total %d\n", s.Allocs),
+		} {
+			if !strings.Contains(body, want) {
+				return fmt.Errorf("no %q in\n%s", strings.TrimSpace(want), body)
+			}
+		}
+		var buf bytes.Buffer
+		if err := cmetrics.WritePrometheus(&buf); err != nil {
+			return err
+		}
+		if n := strings.Count(buf.String(), "# TYPE "); n != 5 {
+			return fmt.Errorf("%d metrics written, want 5", n)
+		}
+		return nil
+	})
+}
@@ -0,0 +1,116 @@
+//go:build !nocgo && !windows
+
+package main
+
+/*
+
+#include <stddef.h>
+
+*/
+import "C"
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"math/bits"
+
+	"github.com/lxwagn/using-go-with-c-libraries/pkg/cnum"
+	"github.com/lxwagn/using-go-with-c-libraries/pkg/mylib"
+)
+
+// panics reports whether f panics with an error matching cnum.ErrRange.
+func panics(f func()) (ok bool) {
+	defer func() {
+		err, _ := recover().(error)
+		ok = errors.Is(err, cnum.ErrRange)
+	}()
+	f()
+	return false
+}
+
+func init() {
+	// pkg/cnum assumes the C type sizes rather than asking a compiler.
+	register("cnum/sizes", func() error {
+		for _, c := range []struct {
+			typ     string
+			c, want int
+		}{
+			{"int", C.sizeof_int, 32},
+			{"unsigned int", C.sizeof_uint, 32},
+			{"long", C.sizeof_long, cnum.LongBits},
+			{"long long", C.sizeof_longlong, 64},
+			{"size_t", C.sizeof_size_t, bits.UintSize},
+		} {
+			if c.c*8 != c.want {
+				return fmt.Errorf("C %s is %d bits, pkg/cnum assumes %d", c.typ, c.c*8, c.want)
+			}
+		}
+		return nil
+	})
+
+	register("cnum/convert", func() error {
+		for _, c := range []struct {
+			name string
+			err  error
+		}{
+			{"ToCInt(MaxInt32)", second(cnum.ToCInt(math.MaxInt32))},
+			{"ToCInt(MinInt32)", second(cnum.ToCInt(math.MinInt32))},
+			{"ToCUint(MaxUint32)", second(cnum.ToCUint(uint64(math.MaxUint32)))},
+			{"ToCSizeT(0)", second(cnum.ToCSizeT(0))},
+			{"FromCInt[int8](-128)", second(cnum.FromCInt[int8](-128))},
+		} {
+			if c.err != nil {
+				return fmt.Errorf("%s: %v", c.name, c.err)
+			}
+		}
+		for _, c := range []struct {
+			name string
+			err  error
+		}{
+			{"ToCInt(MaxInt32+1)", second(cnum.ToCInt(int64(math.MaxInt32) + 1))},
+			{"ToCInt(MinInt32-1)", second(cnum.ToCInt(int64(math.MinInt32) - 1))},
+			{"ToCUint(-1)", second(cnum.ToCUint(-1))},
+			{"ToCSizeT(-1)", second(cnum.ToCSizeT(-1))},
+			{"ToCLongLong(MaxUint64)", second(cnum.ToCLongLong(uint64(math.MaxUint64)))},
+			{"FromCInt[uint8](256)", second(cnum.FromCInt[uint8](256))},
+			{"FromCSizeT[int](MaxUint)", second(cnum.FromCSizeT[int](math.MaxUint))},
+		} {
+			if !errors.Is(c.err, cnum.ErrRange) {
+				return fmt.Errorf("%s: %v, want a range error", c.name, c.err)
+			}
+		}
+		return nil
+	})
+
+	// The wrappers refuse what the C types cannot hold instead of passing
+	// it on truncated.
+	register("cnum/wrappers", func() error {
+		big := math.MaxInt32 + 1
+		_, err := mylib.MakeStruct(big, "x")
+		if !errors.Is(err, mylib.ErrInvalid) || !errors.Is(err, cnum.ErrRange) {
+			return fmt.Errorf("MakeStruct(%d) = %v, want ErrInvalid and cnum.ErrRange", big, err)
+		}
+		logf("MakeStruct(%d): %v", big, err)
+
+		// 1<<32 would be 0 truncated, leaving the counter as it was.
+		before := mylib.CounterAdd(0)
+		if !panics(func() { mylib.CounterAdd(1 << 32) }) {
+			return fmt.Errorf("CounterAdd(1<<32) did not panic with a range error")
+		}
+		if got := mylib.CounterAdd(0); got != before {
+			return fmt.Errorf("counter %d after the refused call, was %d", got, before)
+		}
+
+		pts := []mylib.Point{{X: 1, Y: 2}}
+		if !panics(func() { mylib.TranslatePoints(pts, 0, -1<<32) }) || pts[0].Y != 2 {
+			return fmt.Errorf("TranslatePoints by -1<<32 did not panic, or moved the point to %v", pts[0])
+		}
+		return nil
+	})
+}
+
+// second returns the error of a two-result call.
+func second[T any](_ T, err error) error {
+	return err
+}
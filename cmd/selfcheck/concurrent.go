@@ -0,0 +1,78 @@
+//go:build !nocgo && !windows
+
+package main
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/lxwagn/using-go-with-c-libraries/pkg/mylib"
+)
+
+// These checks call into the C library from many goroutines at once. On
+// their own they only catch lost updates; run them under -race to check
+// the Go side of the wrapper, or in tsan mode (see tsan.go) to check the C
+// side too.
+
+const (
+	concurrentGoroutines = 8
+	concurrentCalls      = 1000
+)
+
+func init() {
+	// myCounterAdd is not thread-safe; the package's call guard is all
+	// that keeps these from racing.
+	register("concurrent/counter", func() error {
+		before := mylib.CounterAdd(0)
+		hammer(func() { mylib.CounterAdd(1) })
+		after := mylib.CounterAdd(0)
+		mylib.CounterAdd(before - after)
+		if want := before + concurrentGoroutines*concurrentCalls; after != want {
+			return fmt.Errorf("counter went from %d to %d, want %d", before, after, want)
+		}
+		return nil
+	})
+
+	register("concurrent/session", func() error {
+		s, err := mylib.NewSession("concurrent", 1<<20)
+		if err != nil {
+			return err
+		}
+		defer s.Close()
+
+		var mu sync.Mutex
+		var firstErr error
+		hammer(func() {
+			if _, err := s.Add(1); err != nil {
+				mu.Lock()
+				firstErr = err
+				mu.Unlock()
+			}
+		})
+		if firstErr != nil {
+			return firstErr
+		}
+		total, calls, err := s.Stats()
+		if err != nil {
+			return err
+		}
+		if want := concurrentGoroutines * concurrentCalls; total != int64(want) || calls != want {
+			return fmt.Errorf("Stats() = %d, %d, want %d, %d", total, calls, want, want)
+		}
+		return nil
+	})
+}
+
+// hammer runs f concurrentCalls times in each of concurrentGoroutines
+// goroutines.
+func hammer(f func()) {
+	var wg sync.WaitGroup
+	for range concurrentGoroutines {
+		wg.Go(func() {
+			for range concurrentCalls {
+				f()
+			}
+		})
+	}
+	wg.Wait()
+}
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/lxwagn/using-go-with-c-libraries/pkg/mylib"
+)
+
+func init() {
+	// The setters write the C globals that myLogf and myBufferAppend
+	// read; Logf printing nothing shows that C saw the write.
+	register("config/globals", func() error {
+		start, err := mylib.ReadConfig()
+		if err != nil {
+			return err
+		}
+		logf("config: %+v", start)
+		if start.LogLevel != mylib.LogLevelInfo || start.BufferSize != 16 {
+			return fmt.Errorf("initial config %+v, want the library's defaults", start)
+		}
+		defer mylib.SetLogLevel(start.LogLevel)
+		defer mylib.SetBufferSize(start.BufferSize)
+
+		if err := mylib.SetLogLevel(mylib.LogLevelOff); err != nil {
+			return err
+		}
+		if err := mylib.SetBufferSize(256); err != nil {
+			return err
+		}
+		c, err := mylib.ReadConfig()
+		if err != nil {
+			return err
+		}
+		if want := (mylib.Config{LogLevel: mylib.LogLevelOff, BufferSize: 256}); c != want {
+			return fmt.Errorf("config %+v after setting, want %+v", c, want)
+		}
+		if n, err := mylib.Logf("not %s", "printed"); n != 0 || err != nil {
+			return fmt.Errorf("Logf at %v = %d, %v; want 0, nil", c.LogLevel, n, err)
+		}
+
+		b, err := mylib.NewBuffer()
+		if err != nil {
+			return err
+		}
+		defer b.Close()
+		for range 3 {
+			if err := b.Append("more than sixteen bytes "); err != nil {
+				return err
+			}
+		}
+		if n := b.Len(); n != 3*24 {
+			return fmt.Errorf("buffer holds %d bytes, want %d", n, 3*24)
+		}
+
+		if err := mylib.SetLogLevel(mylib.LogLevelDebug + 1); !errors.Is(err, mylib.ErrInvalid) {
+			return fmt.Errorf("SetLogLevel(%v) = %v, want ErrInvalid", mylib.LogLevelDebug+1, err)
+		}
+		if err := mylib.SetBufferSize(0); !errors.Is(err, mylib.ErrInvalid) {
+			return fmt.Errorf("SetBufferSize(0) = %v, want ErrInvalid", err)
+		}
+		return nil
+	})
+}
@@ -0,0 +1,18 @@
+//go:build !nocgo && !windows
+
+package main
+
+import (
+	"errors"
+	"log"
+
+	"github.com/lxwagn/using-go-with-c-libraries/pkg/mylib"
+)
+
+// flushCoverage writes the C library's line counts, if it was built for
+// coverage, before the process exits; see make coverage.
+func flushCoverage() {
+	if _, err := mylib.FlushCoverage(); err != nil && !errors.Is(err, errors.ErrUnsupported) {
+		log.Printf("selfcheck: %v", err)
+	}
+}
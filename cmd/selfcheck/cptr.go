@@ -0,0 +1,57 @@
+//go:build !nocgo && !windows
+
+package main
+
+import (
+	"fmt"
+
+	"github.com/lxwagn/using-go-with-c-libraries/pkg/cptr"
+	"github.com/lxwagn/using-go-with-c-libraries/pkg/mylib/raw"
+)
+
+func init() {
+	// C sees the elements at the addresses Index computes, and Slice sees
+	// what C wrote, with no copies either way.
+	register("cptr/array", func() error {
+		a := cptr.AllocArray[raw.MyPoint](8)
+		defer a.Free()
+
+		for i := range a.Len() {
+			a.Index(i).Set(raw.MyPoint{X: int32(i), Y: int32(10 * i), Weight: float64(i) / 2})
+		}
+		raw.TranslatePoints(a.P(), int32(a.Len()), 3, -2)
+		for i, pt := range a.Slice() {
+			want := raw.MyPoint{X: int32(i) + 3, Y: int32(10*i) - 2, Weight: float64(i) / 2}
+			if pt != want {
+				return fmt.Errorf("point %d = %+v, want %+v", i, pt, want)
+			}
+		}
+		return nil
+	})
+
+	register("cptr/out-param", func() error {
+		p := cptr.Alloc[int32]()
+		defer p.Free()
+
+		if rc := raw.Lookup("two", p.P()); rc != raw.OK {
+			return fmt.Errorf("myLookup returned %d", rc)
+		}
+		if v := p.Get(); v != 2 {
+			return fmt.Errorf("value = %d, want 2", v)
+		}
+		return nil
+	})
+
+	register("cptr/bounds", func() (err error) {
+		a := cptr.AllocArray[int64](4)
+		defer a.Free()
+
+		defer func() {
+			if recover() == nil {
+				err = fmt.Errorf("Index(4) on a 4-element array did not panic")
+			}
+		}()
+		a.Index(4)
+		return nil
+	})
+}
@@ -0,0 +1,195 @@
+//go:build !nocgo && !windows
+
+package main
+
+/*
+#include <stdio.h>
+#include <stdlib.h>
+
+// Stand-ins for a library's diagnostics: stderr is unbuffered, and the
+// unfinished line stays in stdout's buffer until someone flushes it.
+static void warn(const char *s) { fprintf(stderr, "warning: %s\n", s); }
+static void printPartial(const char *s) { fputs(s, stdout); }
+*/
+import "C"
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"strings"
+	"unsafe"
+
+	"github.com/lxwagn/using-go-with-c-libraries/pkg/cstdio"
+	"github.com/lxwagn/using-go-with-c-libraries/pkg/handles"
+	"github.com/lxwagn/using-go-with-c-libraries/pkg/mylib"
+)
+
+func init() {
+	register("cstdio/writer", func() error {
+		var buf bytes.Buffer
+		c, err := cstdio.Start(cstdio.Stdout, &buf)
+		if err != nil {
+			return err
+		}
+		perr := mylib.Print("hello from C")
+		cs := C.CString("no newline")
+		C.printPartial(cs)
+		C.free(unsafe.Pointer(cs))
+		if err := c.Stop(); err != nil {
+			return err
+		}
+		if perr != nil {
+			return perr
+		}
+		if want := "hello from C\nno newline"; buf.String() != want {
+			return fmt.Errorf("captured %q, want %q", buf.String(), want)
+		}
+		return nil
+	})
+
+	register("cstdio/slog", func() error {
+		var buf bytes.Buffer
+		l := slog.New(slog.NewTextHandler(&buf, &slog.HandlerOptions{
+			ReplaceAttr: func(_ []string, a slog.Attr) slog.Attr {
+				if a.Key == slog.TimeKey {
+					return slog.Attr{}
+				}
+				return a
+			},
+		}))
+		c, err := cstdio.StartLog(cstdio.Stdout, l, slog.LevelWarn)
+		if err != nil {
+			return err
+		}
+		_, lerr := mylib.Logf("%d widgets", 3)
+		if err := c.Stop(); err != nil {
+			return err
+		}
+		if lerr != nil {
+			return lerr
+		}
+		want := `level=WARN msg="mylib: 3 widgets" stream=stdout` + "\n"
+		if buf.String() != want {
+			return fmt.Errorf("logged %q, want %q", buf.String(), want)
+		}
+		return nil
+	})
+
+	// One capture per stream at a time; once stopped, the stream can be
+	// captured again, and a second Stop is harmless.
+	register("cstdio/stderr", func() error {
+		var first, second strings.Builder
+		c, err := cstdio.Start(cstdio.Stderr, &first)
+		if err != nil {
+			return err
+		}
+		if _, err := cstdio.Start(cstdio.Stderr, &second); !errors.Is(err, cstdio.ErrBusy) {
+			c.Stop()
+			return fmt.Errorf("second Start = %v, want ErrBusy", err)
+		}
+		cs := C.CString("disk almost full")
+		C.warn(cs)
+		C.free(unsafe.Pointer(cs))
+		if err := c.Stop(); err != nil {
+			return err
+		}
+		if err := c.Stop(); err != nil {
+			return fmt.Errorf("second Stop: %v", err)
+		}
+		if want := "warning: disk almost full\n"; first.String() != want {
+			return fmt.Errorf("captured %q, want %q", first.String(), want)
+		}
+
+		c, err = cstdio.Start(cstdio.Stderr, &second)
+		if err != nil {
+			return fmt.Errorf("Start after Stop: %v", err)
+		}
+		return c.Stop()
+	})
+	// A report bigger than stdio's buffer crosses into Go in several
+	// writes, and arrives whole and in order. The handle behind the FILE
+	// is gone once it is closed.
+	register("cstdio/file-writer", func() error {
+		values := make([]int64, 2000)
+		var want strings.Builder
+		want.WriteString("big\n")
+		var total int64
+		for i := range values {
+			values[i] = int64(i * i)
+			total += values[i]
+			fmt.Fprintf(&want, "%d: %d\n", i, values[i])
+		}
+		fmt.Fprintf(&want, "total: %d\n", total)
+
+		live := handles.Live()
+		w := &countingWriter{}
+		n, err := mylib.WriteReport(w, "big", values)
+		if err != nil {
+			return err
+		}
+		if got := w.buf.String(); got != want.String() || n != int64(len(got)) {
+			return fmt.Errorf("report of %d bytes, %d reported, does not match the %d expected", len(got), n, want.Len())
+		}
+		logf("%d bytes in %d writes", n, w.writes)
+		if w.writes < 2 {
+			return fmt.Errorf("%d bytes arrived in one write; expected stdio to flush its buffer along the way", n)
+		}
+		if got := handles.Live(); got != live {
+			return fmt.Errorf("%d handles live after the report, %d before", got, live)
+		}
+		return nil
+	})
+
+	// The io.Writer's own error comes back from the C call that failed.
+	register("cstdio/file-error", func() error {
+		errFull := errors.New("disk full")
+		_, err := mylib.WriteReport(failingWriter{errFull}, "doomed", []int64{1, 2, 3})
+		if !errors.Is(err, errFull) {
+			return fmt.Errorf("WriteReport to a failing writer: %v, want %v", err, errFull)
+		}
+		return nil
+	})
+
+	// A FILE * from C reads to io.EOF, and closes once.
+	register("cstdio/file-reader", func() error {
+		r, err := mylib.OpenReport("small", []int64{-5, 7})
+		if err != nil {
+			return err
+		}
+		got, err := io.ReadAll(r)
+		if err != nil {
+			r.Close()
+			return err
+		}
+		if want := "small\n0: -5\n1: 7\ntotal: 2\n"; string(got) != want {
+			r.Close()
+			return fmt.Errorf("read %q, want %q", got, want)
+		}
+		if err := r.Close(); err != nil {
+			return err
+		}
+		if err := r.Close(); !errors.Is(err, cstdio.ErrClosed) {
+			return fmt.Errorf("second Close = %v, want ErrClosed", err)
+		}
+		return nil
+	})
+}
+
+type countingWriter struct {
+	buf    bytes.Buffer
+	writes int
+}
+
+func (w *countingWriter) Write(p []byte) (int, error) {
+	w.writes++
+	return w.buf.Write(p)
+}
+
+type failingWriter struct{ err error }
+
+func (w failingWriter) Write(p []byte) (int, error) {
+	return 0, w.err
+}
@@ -0,0 +1,28 @@
+//go:build !nocgo && !windows
+
+package main
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/lxwagn/using-go-with-c-libraries/pkg/mylib/ctest"
+)
+
+// The C library's own unit tests, one check each.
+func init() {
+	for _, c := range ctest.Cases() {
+		register("ctest/"+c.Name, func() error {
+			var f failures
+			c.Run(&f)
+			return errors.Join(f...)
+		})
+	}
+}
+
+// failures is a ctest.Reporter that keeps what it is told.
+type failures []error
+
+func (f *failures) Errorf(format string, args ...any) {
+	*f = append(*f, fmt.Errorf(format, args...))
+}
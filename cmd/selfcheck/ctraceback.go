@@ -0,0 +1,39 @@
+//go:build linux && (amd64 || arm64) && !nocgo
+
+package main
+
+import (
+	"fmt"
+	"regexp"
+
+	_ "github.com/lxwagn/using-go-with-c-libraries/pkg/ctraceback"
+	"github.com/lxwagn/using-go-with-c-libraries/pkg/mylib/raw"
+)
+
+// cFrame matches the traceback entry ctraceback makes for myChecksum.
+var cFrame = regexp.MustCompile(`(?m)^myChecksum\n\t\S*libmylib\.so`)
+
+func init() {
+	// myChecksum does not check its buffer, so a nil one with a non-zero
+	// length faults inside the C library.
+	registerChild("fault-in-c", func() {
+		raw.Checksum(nil, 16)
+	})
+
+	register("ctraceback/fault-names-c-function", func() error {
+		if tsanEnabled {
+			// ThreadSanitizer catches the SIGSEGV and prints its own
+			// report before the runtime sees it.
+			logf("the fault goes to ThreadSanitizer; skipped")
+			return nil
+		}
+		out, err := runChild("fault-in-c")
+		if err != nil {
+			return err
+		}
+		if !cFrame.MatchString(out) {
+			return fmt.Errorf("no myChecksum frame in the traceback:\n%s", out)
+		}
+		return nil
+	})
+}
@@ -0,0 +1,264 @@
+//go:build curl && !nocgo && !windows
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"time"
+
+	"github.com/lxwagn/using-go-with-c-libraries/examples/curl"
+)
+
+func init() {
+	register("curl/headers", func() error {
+		logf("%s", curl.Version())
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.URL.Path == "/redirect" {
+				http.Redirect(w, r, "/", http.StatusFound)
+				return
+			}
+			w.Header()["X-Method"] = []string{r.Method}
+			w.Header()["X-Got"] = r.Header["X-Multi"]
+			w.Header().Set("X-Empty-Seen", fmt.Sprint(r.Header["X-Empty"] != nil))
+			w.Header().Set("X-Accept", r.Header.Get("Accept"))
+			w.WriteHeader(http.StatusCreated)
+			io.WriteString(w, "hello")
+		}))
+		defer srv.Close()
+		client := &http.Client{Transport: &curl.Transport{}}
+		defer client.CloseIdleConnections()
+
+		req, _ := http.NewRequest("GET", srv.URL+"/redirect", nil)
+		req.Header["X-Multi"] = []string{"one", "two, three"}
+		req.Header["X-Empty"] = []string{""}
+		resp, err := client.Do(req)
+		if err != nil {
+			return err
+		}
+		b, err := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if err != nil {
+			return err
+		}
+		if resp.StatusCode != 201 || resp.Status != "201 Created" || resp.ProtoMajor != 1 {
+			return fmt.Errorf("status %q %s", resp.Status, resp.Proto)
+		}
+		if string(b) != "hello" || resp.ContentLength != 5 {
+			return fmt.Errorf("body %q, ContentLength %d", b, resp.ContentLength)
+		}
+		if got := resp.Header.Values("X-Got"); strings.Join(got, "|") != "one|two, three" {
+			return fmt.Errorf("X-Got = %q", got)
+		}
+		// The redirect was followed by the client, headers and all,
+		// and libcurl's own Accept was left out.
+		if resp.Request.URL.Path != "/" || resp.Header.Get("X-Empty-Seen") != "true" || resp.Header.Get("X-Accept") != "" {
+			return fmt.Errorf("request %s, header %v", resp.Request.URL, resp.Header)
+		}
+
+		req, _ = http.NewRequest("HEAD", srv.URL, nil)
+		resp, err = client.Do(req)
+		if err != nil {
+			return err
+		}
+		resp.Body.Close()
+		if resp.Header.Get("X-Method") != "HEAD" || resp.Body != http.NoBody {
+			return fmt.Errorf("HEAD: %v", resp.Header)
+		}
+		return nil
+	})
+
+	register("curl/upload", func() error {
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			h := sha256.New()
+			n, _ := io.Copy(h, r.Body)
+			fmt.Fprintf(w, "%s %d %d %x %v", r.Method, r.ContentLength, n, h.Sum(nil), r.TransferEncoding)
+		}))
+		defer srv.Close()
+		client := &http.Client{Transport: &curl.Transport{}}
+		defer client.CloseIdleConnections()
+
+		data := bytes.Repeat([]byte("0123456789abcdef"), 1<<16)
+		sum := sha256.Sum256(data)
+		for _, tc := range []struct {
+			method string
+			body   func() io.Reader
+			want   string
+		}{
+			{"PUT", func() io.Reader { return bytes.NewReader(data) }, fmt.Sprintf("PUT %d %d %x []", len(data), len(data), sum)},
+			// An io.Pipe has no known length, so the body goes chunked.
+			{"POST", func() io.Reader {
+				pr, pw := io.Pipe()
+				go func() {
+					for i := 0; i < len(data); i += 1000 {
+						pw.Write(data[i:min(i+1000, len(data))])
+					}
+					pw.Close()
+				}()
+				return pr
+			}, fmt.Sprintf("POST -1 %d %x [chunked]", len(data), sum)},
+			{"POST", func() io.Reader { return nil }, fmt.Sprintf("POST 0 0 %x []", sha256.Sum256(nil))},
+			{"DELETE", func() io.Reader { return nil }, fmt.Sprintf("DELETE 0 0 %x []", sha256.Sum256(nil))},
+		} {
+			req, err := http.NewRequest(tc.method, srv.URL, tc.body())
+			if err != nil {
+				return err
+			}
+			resp, err := client.Do(req)
+			if err != nil {
+				return fmt.Errorf("%s: %v", tc.method, err)
+			}
+			b, _ := io.ReadAll(resp.Body)
+			resp.Body.Close()
+			if string(b) != tc.want {
+				return fmt.Errorf("server saw %q, want %q", b, tc.want)
+			}
+		}
+
+		// A failing body fails the request with its error.
+		errBody := errors.New("body failed")
+		req, _ := http.NewRequest("POST", srv.URL, io.MultiReader(strings.NewReader("partial"), errReader{errBody}))
+		if _, err := client.Do(req); !errors.Is(err, errBody) {
+			return fmt.Errorf("failing body: %v", err)
+		}
+		return nil
+	})
+
+	register("curl/streaming", func() error {
+		release := make(chan struct{})
+		gone := make(chan struct{})
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.URL.Path == "/endless" {
+				defer close(gone)
+				chunk := bytes.Repeat([]byte("x"), 32<<10)
+				for {
+					if _, err := w.Write(chunk); err != nil {
+						return
+					}
+				}
+			}
+			io.WriteString(w, "first")
+			w.(http.Flusher).Flush()
+			<-release
+			io.WriteString(w, " second")
+		}))
+		defer srv.Close()
+		client := &http.Client{Transport: &curl.Transport{}}
+		defer client.CloseIdleConnections()
+
+		// The response arrives while the server is still holding back
+		// the rest of the body.
+		resp, err := client.Get(srv.URL)
+		if err != nil {
+			close(release)
+			return err
+		}
+		buf := make([]byte, 5)
+		_, err = io.ReadFull(resp.Body, buf)
+		close(release)
+		if err != nil || string(buf) != "first" {
+			return fmt.Errorf("first chunk %q, %v", buf, err)
+		}
+		rest, err := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if err != nil || string(rest) != " second" {
+			return fmt.Errorf("rest %q, %v", rest, err)
+		}
+
+		// Closing the body early stops a transfer that would never end.
+		resp, err = client.Get(srv.URL + "/endless")
+		if err != nil {
+			return err
+		}
+		if _, err := io.CopyN(io.Discard, resp.Body, 1<<20); err != nil {
+			return err
+		}
+		resp.Body.Close()
+		select {
+		case <-gone:
+		case <-time.After(5 * time.Second):
+			return errors.New("transfer went on after Body.Close")
+		}
+		return nil
+	})
+
+	register("curl/timeouts", func() error {
+		stall := make(chan struct{})
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			select {
+			case <-stall:
+			case <-r.Context().Done():
+			}
+		}))
+		defer srv.Close()
+		defer close(stall)
+
+		client := &http.Client{Transport: &curl.Transport{Timeout: 100 * time.Millisecond}}
+		defer client.CloseIdleConnections()
+		start := time.Now()
+		if _, err := client.Get(srv.URL); !errors.Is(err, curl.ErrTimeout) {
+			return fmt.Errorf("Transport.Timeout: %v", err)
+		}
+		logf("timed out after %v", time.Since(start))
+
+		client.Transport = &curl.Transport{}
+		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+		defer cancel()
+		req, _ := http.NewRequestWithContext(ctx, "GET", srv.URL, nil)
+		if _, err := client.Do(req); !errors.Is(err, context.DeadlineExceeded) {
+			return fmt.Errorf("context deadline: %v", err)
+		}
+
+		ctx, cancel = context.WithCancel(context.Background())
+		time.AfterFunc(100*time.Millisecond, cancel)
+		req, _ = http.NewRequestWithContext(ctx, "GET", srv.URL, nil)
+		start = time.Now()
+		if _, err := client.Do(req); !errors.Is(err, context.Canceled) {
+			return fmt.Errorf("cancel: %v", err)
+		}
+		if d := time.Since(start); d > time.Second {
+			return fmt.Errorf("cancel took %v", d)
+		}
+		return nil
+	})
+
+	register("curl/errors", func() error {
+		ln, err := net.Listen("tcp", "127.0.0.1:0")
+		if err != nil {
+			return err
+		}
+		addr := ln.Addr().String()
+		ln.Close()
+
+		client := &http.Client{Transport: &curl.Transport{}}
+		defer client.CloseIdleConnections()
+		_, err = client.Get("http://" + addr)
+		var e *curl.Error
+		if !errors.Is(err, curl.ErrCouldntConnect) || !errors.As(err, &e) {
+			return fmt.Errorf("closed port: %v", err)
+		}
+		logf("%v (code %d)", err, e.Code)
+
+		if _, err := client.Get("ftp://" + addr); !errors.Is(err, curl.ErrUnsupportedProtocol) {
+			return fmt.Errorf("ftp: %v", err)
+		}
+		req, _ := http.NewRequest("GET", "http://"+addr, nil)
+		req.Header["X-Bad"] = []string{"a\r\nX-Injected: yes"}
+		if _, err := client.Do(req); err == nil || !strings.Contains(err.Error(), "invalid header") {
+			return fmt.Errorf("header with CRLF: %v", err)
+		}
+		return nil
+	})
+}
+
+type errReader struct{ err error }
+
+func (r errReader) Read([]byte) (int, error) { return 0, r.err }
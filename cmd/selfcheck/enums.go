@@ -0,0 +1,48 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/lxwagn/using-go-with-c-libraries/pkg/mylib"
+)
+
+func init() {
+	register("enums/status", func() error {
+		_, err := mylib.Lookup("missing")
+		switch st, ok := mylib.StatusOf(err); {
+		case !ok:
+			return fmt.Errorf("StatusOf(%v) found no status", err)
+		case st != mylib.StatusNotFound:
+			return fmt.Errorf("StatusOf(%v) = %v, want %v", err, st, mylib.StatusNotFound)
+		}
+
+		if st, _ := mylib.StatusOf(nil); st != mylib.StatusOK {
+			return fmt.Errorf("StatusOf(nil) = %v, want %v", st, mylib.StatusOK)
+		}
+		if _, ok := mylib.StatusOf(fmt.Errorf("unrelated")); ok {
+			return fmt.Errorf("StatusOf found a status in an unrelated error")
+		}
+		return nil
+	})
+
+	register("enums/string", func() error {
+		for _, c := range []struct {
+			st    mylib.Status
+			name  string
+			valid bool
+		}{
+			{mylib.StatusOK, "MYLIB_OK", true},
+			{mylib.StatusInvalid, "MYLIB_EINVAL", true},
+			{mylib.StatusNoMemory, "MYLIB_ENOMEM", true},
+			{mylib.Status(99), "myStatus(99)", false},
+		} {
+			if s := c.st.String(); s != c.name {
+				return fmt.Errorf("Status(%d).String() = %q, want %q", int32(c.st), s, c.name)
+			}
+			if v := c.st.IsValid(); v != c.valid {
+				return fmt.Errorf("Status(%d).IsValid() = %v, want %v", int32(c.st), v, c.valid)
+			}
+		}
+		return nil
+	})
+}
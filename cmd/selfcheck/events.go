@@ -0,0 +1,126 @@
+//go:build !nocgo && !windows
+
+package main
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/lxwagn/using-go-with-c-libraries/pkg/handles"
+	"github.com/lxwagn/using-go-with-c-libraries/pkg/mylib"
+)
+
+const eventCount = 1000
+
+func init() {
+	// Every event arrives, in order, however small the buffer.
+	register("events/block", func() error {
+		s, err := mylib.Subscribe(eventCount, 0, mylib.EventBuffer(4))
+		if err != nil {
+			return err
+		}
+		defer s.Close()
+
+		var next int64
+		for ev := range s.Events() {
+			if ev.Seq != next || ev.Value != int(ev.Seq%100) || ev.Coalesced != 0 {
+				return fmt.Errorf("got %+v, want seq %d", ev, next)
+			}
+			next++
+		}
+		if next != eventCount {
+			return fmt.Errorf("got %d events, want %d", next, eventCount)
+		}
+		return nil
+	})
+
+	// With nobody receiving, the first four events fill the buffer and the
+	// rest are dropped.
+	register("events/drop", func() error {
+		s, err := mylib.Subscribe(eventCount, 0, mylib.EventBuffer(4), mylib.OnFull(mylib.Drop))
+		if err != nil {
+			return err
+		}
+		defer s.Close()
+
+		if err := waitFor(func() bool { return s.Dropped() == eventCount-4 }); err != nil {
+			return fmt.Errorf("dropped %d events, want %d", s.Dropped(), eventCount-4)
+		}
+		var next int64
+		for ev := range s.Events() {
+			if ev.Seq != next {
+				return fmt.Errorf("got seq %d, want %d", ev.Seq, next)
+			}
+			next++
+		}
+		if next != 4 {
+			return fmt.Errorf("got %d events, want 4", next)
+		}
+		return nil
+	})
+
+	// A slow receiver gets fewer events, each standing for the ones it
+	// replaced, and always the last one.
+	register("events/coalesce", func() error {
+		s, err := mylib.Subscribe(eventCount, 0, mylib.EventBuffer(4), mylib.OnFull(mylib.Coalesce))
+		if err != nil {
+			return err
+		}
+		defer s.Close()
+
+		received, total := 0, 0
+		last := int64(-1)
+		for ev := range s.Events() {
+			if ev.Seq <= last {
+				return fmt.Errorf("got seq %d after %d", ev.Seq, last)
+			}
+			last = ev.Seq
+			received++
+			total += 1 + ev.Coalesced
+			time.Sleep(100 * time.Microsecond)
+		}
+		if total != eventCount || last != eventCount-1 {
+			return fmt.Errorf("%d events standing for %d, the last seq %d; want %d ending in %d",
+				received, total, last, eventCount, eventCount-1)
+		}
+		logf("%d events received for %d emitted", received, total)
+		return s.Close()
+	})
+
+	// An endless emitter, stopped while its thread may be blocked on a
+	// full channel.
+	register("events/close", func() error {
+		liveBefore := handles.Live()
+		s, err := mylib.Subscribe(0, time.Millisecond, mylib.EventBuffer(1))
+		if err != nil {
+			return err
+		}
+		for range 5 {
+			<-s.Events()
+		}
+		if err := s.Close(); err != nil {
+			return err
+		}
+		for range s.Events() {
+		}
+		if err := s.Close(); err != nil {
+			return fmt.Errorf("second Close: %v", err)
+		}
+		if live := handles.Live(); live != liveBefore {
+			return fmt.Errorf("%d handles outstanding, want %d", live, liveBefore)
+		}
+		return nil
+	})
+}
+
+// waitFor polls cond for up to ten seconds and fails if it never holds.
+func waitFor(cond func() bool) error {
+	deadline := time.Now().Add(10 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			return fmt.Errorf("timed out")
+		}
+		time.Sleep(time.Millisecond)
+	}
+	return nil
+}
@@ -0,0 +1,159 @@
+//go:build !nocgo && !windows
+
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"os"
+	"time"
+
+	"github.com/lxwagn/using-go-with-c-libraries/pkg/mylib"
+)
+
+func init() {
+	// A megabyte through the C echo thread, written and read at once so
+	// that neither side can get far ahead of the socket buffers.
+	register("fd/echo", func() error {
+		// The first pollable descriptor opens the poller's own, so count
+		// from after a pipe has come and gone.
+		if r, w, err := os.Pipe(); err == nil {
+			r.Close()
+			w.Close()
+		}
+		before := countFDs()
+		c, err := mylib.EchoConn()
+		if err != nil {
+			return err
+		}
+		want := make([]byte, 1<<20)
+		rand.NewChaCha8([32]byte{}).Read(want)
+
+		werr := make(chan error, 1)
+		go func() {
+			_, err := c.Write(want)
+			werr <- err
+		}()
+		got := make([]byte, len(want))
+		if _, err := io.ReadFull(c, got); err != nil {
+			c.Close()
+			return err
+		}
+		if err := <-werr; err != nil {
+			c.Close()
+			return err
+		}
+		if !bytes.Equal(got, want) {
+			c.Close()
+			return errors.New("echoed bytes differ")
+		}
+		if err := c.Close(); err != nil {
+			return err
+		}
+		// The connection's descriptor was the only one on the Go side,
+		// and the C thread closes its end once it reads end of file.
+		deadline := time.Now().Add(time.Second)
+		for before >= 0 && countFDs() != before {
+			if time.Now().After(deadline) {
+				return fmt.Errorf("%d descriptors open after Close, want %d", countFDs(), before)
+			}
+			time.Sleep(time.Millisecond)
+		}
+		return nil
+	})
+
+	// Deadlines work only on descriptors the poller manages, so this is
+	// what shows that the adopted socket is non-blocking.
+	register("fd/echo-deadline", func() error {
+		c, err := mylib.EchoConn()
+		if err != nil {
+			return err
+		}
+		defer c.Close()
+		c.SetReadDeadline(time.Now().Add(20 * time.Millisecond))
+		if _, err := c.Read(make([]byte, 1)); !errors.Is(err, os.ErrDeadlineExceeded) {
+			return fmt.Errorf("Read with nothing sent: %v, want a deadline error", err)
+		}
+		return nil
+	})
+
+	register("fd/notify", func() error {
+		f, err := mylib.NotifyFile()
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+
+		// Closing a second duplicate does not close the library's pipe.
+		g, err := mylib.NotifyFile()
+		if err != nil {
+			return err
+		}
+		g.Close()
+
+		for _, msg := range []string{"first", "second"} {
+			if err := mylib.Notify(msg); err != nil {
+				return err
+			}
+		}
+		mylib.NotifyClose()
+
+		f.SetReadDeadline(time.Now().Add(time.Second))
+		lines, err := io.ReadAll(f)
+		if err != nil {
+			return fmt.Errorf("reading after NotifyClose: %v, want end of file", err)
+		}
+		if string(lines) != "first\nsecond\n" {
+			return fmt.Errorf("read %q", lines)
+		}
+		if err := mylib.Notify("a\nb"); !errors.Is(err, mylib.ErrInvalid) {
+			return fmt.Errorf("Notify with a newline: %v, want ErrInvalid", err)
+		}
+		return nil
+	})
+
+	register("fd/notify-full", func() error {
+		defer mylib.NotifyClose()
+		f, err := mylib.NotifyFile()
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+
+		var sent int
+		for {
+			err := mylib.Notify("filling the pipe")
+			if errors.Is(err, mylib.ErrRange) {
+				break
+			}
+			if err != nil {
+				return err
+			}
+			if sent++; sent > 1<<20 {
+				return errors.New("pipe never filled")
+			}
+		}
+		sc := bufio.NewScanner(f)
+		for range sent {
+			if !sc.Scan() {
+				return fmt.Errorf("scan: %v", sc.Err())
+			}
+		}
+		logf("pipe filled after %d lines", sent)
+		return mylib.Notify("room again")
+	})
+}
+
+// countFDs returns the number of descriptors open in the process, or -1
+// where /proc does not list them.
+func countFDs() int {
+	ents, err := os.ReadDir("/proc/self/fd")
+	if err != nil {
+		return -1
+	}
+	return len(ents)
+}
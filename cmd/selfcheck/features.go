@@ -0,0 +1,47 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"runtime"
+
+	"github.com/lxwagn/using-go-with-c-libraries/pkg/features"
+	"github.com/lxwagn/using-go-with-c-libraries/pkg/mylib"
+)
+
+func init() {
+	// The library built from this tree has everything the bindings know
+	// about, apart from what its platform leaves out.
+	register("features/current", func() error {
+		set := mylib.Features()
+		if _, ok := set.Version(); !ok {
+			return fmt.Errorf("no version reported: %v", set)
+		}
+		for _, f := range features.All() {
+			if (f == features.Threads || f == features.Rings || f == features.Descriptors || f == features.Events) && runtime.GOOS == "windows" {
+				continue
+			}
+			if !set.Has(f) {
+				return fmt.Errorf("missing %v: %v", f, set)
+			}
+		}
+		return nil
+	})
+
+	register("features/unsupported", func() error {
+		var old features.Set
+		err := old.Require(features.WideStrings)
+		if !errors.Is(err, errors.ErrUnsupported) {
+			return fmt.Errorf("Require on an empty set = %v, want ErrUnsupported", err)
+		}
+		if _, ok := old.Version(); ok {
+			return fmt.Errorf("empty set reports a version")
+		}
+
+		v := features.DecodeVersion(10203)
+		if v != (features.Version{Major: 1, Minor: 2, Patch: 3}) || !v.AtLeast(features.Version{Major: 1, Minor: 1}) {
+			return fmt.Errorf("DecodeVersion(10203) = %v", v)
+		}
+		return nil
+	})
+}
@@ -0,0 +1,177 @@
+//go:build !nocgo && !windows
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+
+	"github.com/lxwagn/using-go-with-c-libraries/pkg/cmem"
+	"github.com/lxwagn/using-go-with-c-libraries/pkg/cplugin"
+	"github.com/lxwagn/using-go-with-c-libraries/pkg/ffi"
+	"github.com/lxwagn/using-go-with-c-libraries/pkg/mylib"
+)
+
+func init() {
+	register("ffi/libc", func() error {
+		strlen, err := ffiFunc("strlen", ffi.Ulong, ffi.Pointer)
+		if err != nil {
+			return err
+		}
+		if n, err := strlen("hello, ffi"); err != nil || n != uint(10) {
+			return fmt.Errorf("strlen = %v, %v; want 10", n, err)
+		}
+
+		strtod, err := ffiFunc("strtod", ffi.Double, ffi.Pointer, ffi.Pointer)
+		if err != nil {
+			return err
+		}
+		if d, err := strtod("2.5e3", nil); err != nil || d != 2500.0 {
+			return fmt.Errorf("strtod = %v, %v; want 2500", d, err)
+		}
+
+		labs, err := ffiFunc("labs", ffi.Long, ffi.Long)
+		if err != nil {
+			return err
+		}
+		if n, err := labs(-1 << 40); err != nil || n != 1<<40 {
+			return fmt.Errorf("labs = %v, %v; want %d", n, err, 1<<40)
+		}
+		return nil
+	})
+
+	register("ffi/variadic", func() error {
+		fn, err := ffi.Lookup("snprintf")
+		if err != nil {
+			return err
+		}
+		sig, err := ffi.NewVariadic(ffi.Int, []ffi.Type{ffi.Pointer, ffi.Ulong, ffi.Pointer}, ffi.Int, ffi.Double, ffi.Pointer)
+		if err != nil {
+			return err
+		}
+		if got, want := sig.String(), "int (void *, unsigned long, void *, ...)"; got != want {
+			return fmt.Errorf("String = %q, want %q", got, want)
+		}
+		buf := cmem.Malloc(64)
+		defer cmem.Free(buf)
+		n, err := sig.Call(fn, buf, 64, "%d %.2f %s", 42, 3.14159, "end")
+		if err != nil {
+			return err
+		}
+		const want = "42 3.14 end"
+		if got := string(cmem.Copy(buf, len(want))); got != want || n != int32(len(want)) {
+			return fmt.Errorf("snprintf wrote %q and returned %v, want %q and %d", got, n, want, len(want))
+		}
+		return nil
+	})
+
+	// The library's own function, found by name, acts on the same
+	// counter as the cgo binding.
+	register("ffi/mylib", func() error {
+		add, err := ffiFunc("myCounterAdd", ffi.Int, ffi.Int)
+		if err != nil {
+			return err
+		}
+		before := mylib.CounterAdd(0)
+		got, err := add(5)
+		if err != nil {
+			return err
+		}
+		if got != int32(before+5) || mylib.CounterAdd(0) != before+5 {
+			return fmt.Errorf("myCounterAdd(5) = %v after %d", got, before)
+		}
+		mylib.CounterAdd(-5)
+		return nil
+	})
+
+	// A function a plugin exports beyond the plugin ABI.
+	register("ffi/plugin", func() error {
+		p, err := cplugin.Load(filepath.Join(*pluginDir, "upper"+cplugin.Ext))
+		if err != nil {
+			return err
+		}
+		defer p.Close()
+		fn, err := p.Symbol("upperCount")
+		if err != nil {
+			return err
+		}
+		sig, err := ffi.NewSignature(ffi.Int, ffi.Pointer, ffi.Int)
+		if err != nil {
+			return err
+		}
+		for _, tc := range []struct {
+			limit int
+			want  int32
+		}{{100, 9}, {3, 3}} {
+			n, err := sig.Call(fn, "Hello, world", tc.limit)
+			if err != nil {
+				return err
+			}
+			if n != tc.want {
+				return fmt.Errorf("upperCount(limit %d) = %v, want %d", tc.limit, n, tc.want)
+			}
+		}
+		if _, err := p.Symbol("noSuchFunction"); err == nil {
+			return errors.New("Symbol of a missing name succeeded")
+		}
+		return nil
+	})
+
+	register("ffi/bad-args", func() error {
+		fn, err := ffi.Lookup("abs")
+		if err != nil {
+			return err
+		}
+		sig, err := ffi.NewSignature(ffi.Int, ffi.Int)
+		if err != nil {
+			return err
+		}
+		narrow, err := ffi.NewSignature(ffi.Void, ffi.Int8, ffi.Uint)
+		if err != nil {
+			return err
+		}
+		for _, tc := range []struct {
+			sig  *ffi.Signature
+			args []any
+		}{
+			{sig, nil},
+			{sig, []any{1, 2}},
+			{sig, []any{"1"}},
+			{sig, []any{1.5}},
+			{sig, []any{int64(1) << 40}},
+			{narrow, []any{300, uint(1)}},
+			{narrow, []any{1, -1}},
+		} {
+			// None of these reach the function, so narrow's bogus
+			// signature for abs does no harm.
+			if _, err := tc.sig.Call(fn, tc.args...); !errors.Is(err, ffi.ErrArgs) {
+				return fmt.Errorf("%v called with %v: %v, want ErrArgs", tc.sig, tc.args, err)
+			}
+		}
+		if _, err := ffi.NewSignature(ffi.Int, ffi.Void); err == nil {
+			return errors.New("NewSignature with a void parameter succeeded")
+		}
+		if _, err := ffi.Lookup("noSuchFunction"); err == nil {
+			return errors.New("Lookup of a missing name succeeded")
+		}
+		return nil
+	})
+}
+
+// ffiFunc looks name up and prepares a call to it with the given
+// signature.
+func ffiFunc(name string, ret ffi.Type, args ...ffi.Type) (func(...any) (any, error), error) {
+	fn, err := ffi.Lookup(name)
+	if err != nil {
+		return nil, err
+	}
+	sig, err := ffi.NewSignature(ret, args...)
+	if err != nil {
+		return nil, err
+	}
+	logf("%s: %v", name, sig)
+	return func(args ...any) (any, error) {
+		return sig.Call(fn, args...)
+	}, nil
+}
@@ -0,0 +1,165 @@
+//go:build !nocgo && !windows
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"runtime"
+	"sync"
+
+	"github.com/lxwagn/using-go-with-c-libraries/examples/gpu"
+)
+
+func init() {
+	register("gpu/saxpy", func() error {
+		name, err := gpu.DeviceName()
+		if err != nil {
+			return err
+		}
+		logf("device %s", name)
+		const streams, n = 4, 1 << 16
+		var wg sync.WaitGroup
+		errs := make([]error, streams)
+		for i := range streams {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				errs[i] = saxpy(float32(i+1), n)
+			}()
+		}
+		wg.Wait()
+		// The Go slices were pinned for the copies; a collection now
+		// panics on any pin left behind.
+		runtime.GC()
+		return errors.Join(errs...)
+	})
+
+	register("gpu/callbacks", func() error {
+		s, err := gpu.NewStream()
+		if err != nil {
+			return err
+		}
+		defer s.Close()
+		const n = 1000
+		done := make([]<-chan error, n)
+		for i := range done {
+			done[i] = s.Done()
+		}
+		// Each callback runs after those queued before it, so once the
+		// last channel is ready all the others are.
+		if err := <-done[n-1]; err != nil {
+			return err
+		}
+		for i, ch := range done[:n-1] {
+			select {
+			case err := <-ch:
+				if err != nil {
+					return fmt.Errorf("callback %d: %w", i, err)
+				}
+			default:
+				return fmt.Errorf("callback %d ran after callback %d", i, n-1)
+			}
+		}
+		if _, ok := <-done[0]; ok {
+			return errors.New("Done channel not closed")
+		}
+		return nil
+	})
+
+	register("gpu/fault", func() error {
+		s, err := gpu.NewStream()
+		if err != nil {
+			return err
+		}
+		defer s.Close()
+		dx, err := gpu.Malloc(10)
+		if err != nil {
+			return err
+		}
+		defer dx.Free()
+		dy, err := gpu.Malloc(100)
+		if err != nil {
+			return err
+		}
+		defer dy.Free()
+		y := make([]float32, 100)
+		for i := range y {
+			y[i] = 1
+		}
+		// The kernel reads past the end of x: queuing it succeeds,
+		// running it fails the stream.
+		if err := s.Saxpy(2, dx, dy); err != nil {
+			return fmt.Errorf("queuing the kernel: %w", err)
+		}
+		if err := s.CopyToHost(y, dy); err != nil {
+			return err
+		}
+		if err := <-s.Done(); !errors.Is(err, gpu.ErrIllegalAddress) {
+			return fmt.Errorf("Done: %v, want %v", err, gpu.ErrIllegalAddress)
+		}
+		if err := s.Synchronize(); !errors.Is(err, gpu.ErrIllegalAddress) {
+			return fmt.Errorf("Synchronize: %v, want %v", err, gpu.ErrIllegalAddress)
+		}
+		if y[0] != 1 {
+			return fmt.Errorf("copy after the fault ran: y[0] = %v", y[0])
+		}
+		if err := s.Close(); err != nil && !errors.Is(err, gpu.ErrIllegalAddress) {
+			return err
+		}
+		if err := s.Synchronize(); !errors.Is(err, gpu.ErrClosed) {
+			return fmt.Errorf("Synchronize after Close: %v, want %v", err, gpu.ErrClosed)
+		}
+		runtime.GC()
+		return nil
+	})
+}
+
+// saxpy computes y = a*x + y for n values on a stream of its own, with x
+// in a HostBuffer and y in a Go slice, and checks the result.
+func saxpy(a float32, n int) error {
+	s, err := gpu.NewStream()
+	if err != nil {
+		return err
+	}
+	defer s.Close()
+	x, err := gpu.NewHostBuffer(n)
+	if err != nil {
+		return err
+	}
+	defer x.Free()
+	y := make([]float32, n)
+	for i := range n {
+		x.Data()[i] = float32(i)
+		y[i] = 1
+	}
+	dx, err := gpu.Malloc(n)
+	if err != nil {
+		return err
+	}
+	defer dx.Free()
+	dy, err := gpu.Malloc(n)
+	if err != nil {
+		return err
+	}
+	defer dy.Free()
+	for _, err := range []error{
+		s.CopyToDevice(dx, x.Data()),
+		s.CopyToDevice(dy, y),
+		s.Saxpy(a, dx, dy),
+		s.CopyToHost(y, dy),
+	} {
+		if err != nil {
+			return err
+		}
+	}
+	if err := <-s.Done(); err != nil {
+		return err
+	}
+	for i, v := range y {
+		if want := a*float32(i) + 1; v != want {
+			return fmt.Errorf("a=%v: y[%d] = %v, want %v", a, i, v, want)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,138 @@
+//go:build !nocgo && !windows
+
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"image/color/palette"
+	"image/png"
+
+	"github.com/lxwagn/using-go-with-c-libraries/examples/imagec"
+)
+
+func init() {
+	register("imagec/decode", func() error {
+		for name, src := range testImages(61, 37) {
+			var buf bytes.Buffer
+			if err := png.Encode(&buf, src); err != nil {
+				return err
+			}
+			data := buf.Bytes()
+			// image/png's decoding, converted to the NRGBA every
+			// imagec result is.
+			want := toNRGBA(src)
+
+			cfg, err := imagec.DecodeConfig(data)
+			if err != nil {
+				return fmt.Errorf("%s: %v", name, err)
+			}
+			if cfg.Width != 61 || cfg.Height != 37 {
+				return fmt.Errorf("%s: config %dx%d", name, cfg.Width, cfg.Height)
+			}
+
+			img, err := imagec.Decode(data)
+			if err != nil {
+				return fmt.Errorf("%s: %v", name, err)
+			}
+			if err := sameNRGBA(img.NRGBA, want); err != nil {
+				img.Free()
+				return fmt.Errorf("%s: Decode: %v", name, err)
+			}
+			c := img.Copy()
+			img.Free()
+			img.Free()
+			if img.Pix != nil {
+				return fmt.Errorf("%s: Pix not cleared by Free", name)
+			}
+			if err := sameNRGBA(c, want); err != nil {
+				return fmt.Errorf("%s: Copy after Free: %v", name, err)
+			}
+
+			g, err := imagec.DecodeNRGBA(data)
+			if err != nil {
+				return fmt.Errorf("%s: %v", name, err)
+			}
+			if err := sameNRGBA(g, want); err != nil {
+				return fmt.Errorf("%s: DecodeNRGBA: %v", name, err)
+			}
+		}
+		return nil
+	})
+
+	register("imagec/errors", func() error {
+		if _, err := imagec.Decode([]byte("GIF89a")); !errors.Is(err, imagec.ErrFormat) {
+			return fmt.Errorf("not a PNG: %v", err)
+		}
+		var buf bytes.Buffer
+		png.Encode(&buf, testImages(100, 100)["nrgba"])
+		data := buf.Bytes()
+		// Truncated inside the image data: the header reads, the
+		// pixels fail.
+		if _, err := imagec.DecodeConfig(data[:len(data)-50]); err != nil {
+			return fmt.Errorf("truncated header: %v", err)
+		}
+		_, err := imagec.DecodeNRGBA(data[:len(data)/2])
+		if err == nil {
+			return errors.New("truncated PNG decoded")
+		}
+		logf("truncated: %v", err)
+		// A flipped byte in the image data breaks its checksum.
+		bad := bytes.Clone(data)
+		bad[len(bad)/2] ^= 0xff
+		if img, err := imagec.Decode(bad); err == nil {
+			img.Free()
+			return errors.New("corrupt PNG decoded")
+		}
+		return nil
+	})
+}
+
+// testImages returns w×h images of the color types PNG encodes
+// differently.
+func testImages(w, h int) map[string]image.Image {
+	r := image.Rect(0, 0, w, h)
+	nrgba := image.NewNRGBA(r)
+	gray := image.NewGray(r)
+	pal := image.NewPaletted(r, append(color.Palette{color.NRGBA{10, 20, 30, 0}, color.NRGBA{200, 100, 50, 128}}, palette.Plan9[:50]...))
+	for y := range h {
+		for x := range w {
+			nrgba.SetNRGBA(x, y, color.NRGBA{uint8(x * 4), uint8(y * 7), uint8(x ^ y), uint8(255 - x - y)})
+			gray.SetGray(x, y, color.Gray{uint8(x*y + 3)})
+			pal.SetColorIndex(x, y, uint8((x+y)%len(pal.Palette)))
+		}
+	}
+	return map[string]image.Image{"nrgba": nrgba, "gray": gray, "paletted": pal}
+}
+
+func toNRGBA(src image.Image) *image.NRGBA {
+	if m, ok := src.(*image.NRGBA); ok {
+		return m
+	}
+	m := image.NewNRGBA(src.Bounds())
+	for y := src.Bounds().Min.Y; y < src.Bounds().Max.Y; y++ {
+		for x := src.Bounds().Min.X; x < src.Bounds().Max.X; x++ {
+			m.Set(x, y, color.NRGBAModel.Convert(src.At(x, y)))
+		}
+	}
+	return m
+}
+
+func sameNRGBA(got, want *image.NRGBA) error {
+	if got.Rect != want.Rect {
+		return fmt.Errorf("bounds %v, want %v", got.Rect, want.Rect)
+	}
+	for y := want.Rect.Min.Y; y < want.Rect.Max.Y; y++ {
+		for x := want.Rect.Min.X; x < want.Rect.Max.X; x++ {
+			g, w := got.NRGBAAt(x, y), want.NRGBAAt(x, y)
+			// Fully transparent pixels may come out with any color.
+			if g != w && !(g.A == 0 && w.A == 0) {
+				return fmt.Errorf("pixel (%d, %d) = %v, want %v", x, y, g, w)
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,95 @@
+//go:build !nocgo && !windows
+
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/lxwagn/using-go-with-c-libraries/pkg/isolate"
+	"github.com/lxwagn/using-go-with-c-libraries/pkg/mylib"
+)
+
+func init() {
+	isolate.Register("selfcheck.sleep", func(d time.Duration) (bool, error) {
+		time.Sleep(d)
+		return true, nil
+	})
+	// A copy of selfcheck started as a worker serves calls from here on
+	// and never runs the checks.
+	isolate.Main()
+
+	// The assert in myMean kills the worker, not selfcheck, and the next
+	// call gets a new worker.
+	register("isolate/assert", func() error {
+		w := isolate.NewWorker()
+		defer w.Close()
+		ctx := context.Background()
+
+		if m, err := mylib.IsolatedMean(ctx, w, []int32{1, 2, 3, 6}); err != nil || m != 3 {
+			return fmt.Errorf("IsolatedMean = %v, %v, want 3", m, err)
+		}
+
+		_, err := mylib.IsolatedMean(ctx, w, nil)
+		var crash *isolate.CrashError
+		if !errors.As(err, &crash) {
+			return fmt.Errorf("IsolatedMean(nil): %v, want a CrashError", err)
+		}
+		logf("%v", err)
+		status, ok := crash.State.Sys().(syscall.WaitStatus)
+		if !ok || !status.Signaled() || status.Signal() != syscall.SIGABRT {
+			return fmt.Errorf("worker ended with %v, want SIGABRT", crash.State)
+		}
+		if !strings.Contains(crash.Stderr, "Assertion") || !strings.Contains(crash.Stderr, "myMean") {
+			return fmt.Errorf("stderr %q does not show the assertion", crash.Stderr)
+		}
+
+		if m, err := mylib.IsolatedMean(ctx, w, []int32{-4, 4}); err != nil || m != 0 {
+			return fmt.Errorf("IsolatedMean after the crash = %v, %v, want 0", m, err)
+		}
+		return nil
+	})
+
+	// A call that does not return in time is ended by killing the worker.
+	register("isolate/timeout", func() error {
+		w := isolate.NewWorker()
+		defer w.Close()
+
+		ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+		defer cancel()
+		start := time.Now()
+		_, err := isolate.Call[time.Duration, bool](ctx, w, "selfcheck.sleep", time.Minute)
+		if !errors.Is(err, context.DeadlineExceeded) {
+			return fmt.Errorf("Call: %v, want DeadlineExceeded", err)
+		}
+		if d := time.Since(start); d > 10*time.Second {
+			return fmt.Errorf("Call returned after %v", d)
+		}
+
+		if ok, err := isolate.Call[time.Duration, bool](context.Background(), w, "selfcheck.sleep", 0); err != nil || !ok {
+			return fmt.Errorf("Call after the timeout = %v, %v", ok, err)
+		}
+		return nil
+	})
+
+	// Errors from the worker come back as text, and a Worker is unusable
+	// once closed.
+	register("isolate/errors", func() error {
+		w := isolate.NewWorker()
+		var remote *isolate.RemoteError
+		if _, err := isolate.Call[int, int](context.Background(), w, "no.such.function", 0); !errors.As(err, &remote) {
+			return fmt.Errorf("Call of an unknown function: %v, want a RemoteError", err)
+		}
+		if err := w.Close(); err != nil {
+			return err
+		}
+		if _, err := mylib.IsolatedMean(context.Background(), w, []int32{1}); !errors.Is(err, isolate.ErrClosed) {
+			return fmt.Errorf("IsolatedMean after Close: %v, want ErrClosed", err)
+		}
+		return nil
+	})
+}
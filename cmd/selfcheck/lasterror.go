@@ -0,0 +1,45 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"runtime"
+	"sync"
+	"syscall"
+
+	"github.com/lxwagn/using-go-with-c-libraries/pkg/mylib"
+)
+
+func init() {
+	// Two kinds of failure, with different errno values, from many
+	// goroutines at once. An errno read on the wrong thread, or after
+	// another call, would pair a failure with the other's error.
+	register("lasterror/per-call", func() error {
+		missing := "/nonexistent/selfcheck"
+		dir := os.TempDir()
+
+		var wg sync.WaitGroup
+		errs := make(chan error, 32)
+		for i := range 32 {
+			wg.Go(func() {
+				for range 500 {
+					path, want := missing, fs.ErrNotExist
+					if i%2 == 1 {
+						path, want = dir, error(syscall.EINVAL)
+					}
+					_, err := mylib.FileSize(path)
+					if !errors.Is(err, want) {
+						errs <- fmt.Errorf("FileSize(%q) = %v, want %v", path, err, want)
+						return
+					}
+					runtime.Gosched()
+				}
+			})
+		}
+		wg.Wait()
+		close(errs)
+		return <-errs
+	})
+}
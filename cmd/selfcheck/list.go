@@ -0,0 +1,83 @@
+//go:build !nocgo && !windows
+
+package main
+
+import (
+	"fmt"
+	"slices"
+
+	"github.com/lxwagn/using-go-with-c-libraries/pkg/mylib"
+)
+
+func init() {
+	register("list/collect", func() error {
+		l, err := mylib.SplitWords("  the quick  brown fox ")
+		if err != nil {
+			return err
+		}
+		got := l.CollectSlice()
+		want := []mylib.Word{
+			{Text: "the", Offset: 2},
+			{Text: "quick", Offset: 6},
+			{Text: "brown", Offset: 13},
+			{Text: "fox", Offset: 19},
+		}
+		if !slices.Equal(got, want) {
+			return fmt.Errorf("CollectSlice = %v, want %v", got, want)
+		}
+		if n := countWords(l); n != 0 {
+			return fmt.Errorf("%d words left after CollectSlice, want 0", n)
+		}
+		return nil
+	})
+
+	register("list/empty", func() error {
+		l, err := mylib.SplitWords("   ")
+		if err != nil {
+			return err
+		}
+		defer l.Free()
+		if n := countWords(l); n != 0 {
+			return fmt.Errorf("got %d words, want 0", n)
+		}
+		return nil
+	})
+
+	// Stopping early must leave the list intact for another walk, and
+	// freeing it from inside the loop must end the loop instead of
+	// reading freed nodes.
+	register("list/early-exit", func() error {
+		l, err := mylib.SplitWords("a b c d e")
+		if err != nil {
+			return err
+		}
+		defer l.Free()
+
+		for w := range l.All() {
+			if w.Text == "b" {
+				break
+			}
+		}
+		if n := countWords(l); n != 5 {
+			return fmt.Errorf("got %d words after break, want 5", n)
+		}
+
+		var seen []string
+		for w := range l.All() {
+			seen = append(seen, w.Text)
+			l.Free()
+		}
+		if !slices.Equal(seen, []string{"a"}) {
+			return fmt.Errorf("loop freeing the list saw %q, want [a]", seen)
+		}
+		return nil
+	})
+}
+
+func countWords(l *mylib.WordList) int {
+	n := 0
+	for range l.All() {
+		n++
+	}
+	return n
+}
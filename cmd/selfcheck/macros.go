@@ -0,0 +1,43 @@
+//go:build !nocgo && !windows
+
+package main
+
+import (
+	"fmt"
+
+	"github.com/lxwagn/using-go-with-c-libraries/pkg/features"
+	"github.com/lxwagn/using-go-with-c-libraries/pkg/mylib"
+)
+
+func init() {
+	// The generated constants and the macro wrappers describe the same
+	// header, and the library built from it.
+	register("macros/version", func() error {
+		n := mylib.VersionNumber(mylib.VersionMajor, mylib.VersionMinor, mylib.VersionPatch)
+		if n != mylib.HeaderVersion {
+			return fmt.Errorf("VersionNumber of the defines = %d, HeaderVersion = %d", n, mylib.HeaderVersion)
+		}
+		if s := features.DecodeVersion(n).String(); s != mylib.VersionString {
+			return fmt.Errorf("version %s, VersionString %q", s, mylib.VersionString)
+		}
+		if v, ok := mylib.Features().Version(); !ok || v != features.DecodeVersion(n) {
+			return fmt.Errorf("library version %v, header version %s", v, mylib.VersionString)
+		}
+		return nil
+	})
+
+	register("macros/functions", func() error {
+		for kind, want := range map[int]bool{mylib.ValueInt: true, mylib.ValueReal: true, mylib.ValueText: false} {
+			if got := mylib.ValueIsNumber(kind); got != want {
+				return fmt.Errorf("ValueIsNumber(%d) = %v", kind, got)
+			}
+		}
+		if got := mylib.RingSize(4096); got != mylib.RingHeader+4096 {
+			return fmt.Errorf("RingSize(4096) = %d", got)
+		}
+		if got := mylib.RingMessageSize(10); got != 14 {
+			return fmt.Errorf("RingMessageSize(10) = %d, want 14", got)
+		}
+		return nil
+	})
+}
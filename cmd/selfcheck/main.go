@@ -0,0 +1,74 @@
+// Command selfcheck runs end-to-end checks of the module's packages that
+// go test cannot easily make: crashes in child processes, sanitizer and
+// cgocheck reports, and the like. Each check drives the real C library
+// and reports ok or FAIL; the exit status is non-zero if any check fails.
+//
+//	go run ./cmd/selfcheck [-run regexp] [-v]
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"regexp"
+	"time"
+)
+
+// A check returns nil if the behavior it checks is as expected.
+type check struct {
+	name string
+	run  func() error
+}
+
+// checks is filled in by the init functions of the other files, one file
+// per area.
+var checks []check
+
+func register(name string, run func() error) {
+	checks = append(checks, check{name, run})
+}
+
+var verbose = flag.Bool("v", false, "log progress from checks")
+
+var pluginDir = flag.String("plugins", "lib/plugins", "load the sample plugins from `dir` (cd src/plugins; make)")
+
+// logf logs from a check when -v is given.
+func logf(format string, args ...any) {
+	if *verbose {
+		log.Printf(format, args...)
+	}
+}
+
+func main() {
+	runAsChild()
+
+	run := flag.String("run", ".", "run only checks matching `regexp`")
+	flag.Parse()
+
+	re, err := regexp.Compile(*run)
+	if err != nil {
+		log.Fatalf("selfcheck: -run: %v", err)
+	}
+
+	failed := 0
+	for _, c := range checks {
+		if !re.MatchString(c.name) {
+			continue
+		}
+		start := time.Now()
+		err := c.run()
+		d := time.Since(start).Round(time.Millisecond)
+		if err != nil {
+			fmt.Printf("FAIL %s (%v): %v\n", c.name, d, err)
+			failed++
+			continue
+		}
+		fmt.Printf("ok   %s (%v)\n", c.name, d)
+	}
+	flushCoverage()
+	if failed > 0 {
+		fmt.Printf("%d check(s) failed\n", failed)
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,81 @@
+//go:build !nocgo && !windows
+
+package main
+
+import (
+	"fmt"
+	"math"
+	"runtime"
+	"runtime/debug"
+
+	"github.com/lxwagn/using-go-with-c-libraries/pkg/cmem"
+)
+
+// garbage keeps the allocations below from being optimized away.
+var garbage []byte
+
+// allocGarbage allocates n MiB of Go memory that is garbage at once, and
+// returns the number of collections that ran meanwhile.
+func allocGarbage(n int) uint32 {
+	var ms runtime.MemStats
+	runtime.ReadMemStats(&ms)
+	before := ms.NumGC
+	for range n {
+		garbage = make([]byte, 1<<20)
+	}
+	garbage = nil
+	runtime.ReadMemStats(&ms)
+	return ms.NumGC - before
+}
+
+func init() {
+	// The Go limit is what C leaves of the total, and with the collector
+	// otherwise off, C filling most of the total is what makes it run.
+	register("cmem/memory-limit", func() error {
+		orig := debug.SetMemoryLimit(-1)
+		defer debug.SetGCPercent(debug.SetGCPercent(-1))
+		const total = 256 << 20
+		if prev := cmem.SetTotalMemoryLimit(total); prev != math.MaxInt64 {
+			cmem.SetTotalMemoryLimit(math.MaxInt64)
+			return fmt.Errorf("first SetTotalMemoryLimit returned %d, want none", prev)
+		}
+		defer cmem.SetTotalMemoryLimit(math.MaxInt64)
+
+		check := func(what string) error {
+			live := cmem.ReadStats().LiveBytes
+			want := max(int64(total)-live, total/16)
+			if got := debug.SetMemoryLimit(-1); got < want-max(total/64, 1<<20) || got > want+max(total/64, 1<<20) {
+				return fmt.Errorf("%s: Go limit %d with %d bytes live in C, want about %d", what, got, live, want)
+			}
+			return nil
+		}
+		if err := check("at start"); err != nil {
+			return err
+		}
+		runtime.GC()
+		idle := allocGarbage(64)
+
+		p := cmem.Malloc(240 << 20)
+		if err := check("with 240MiB in C"); err != nil {
+			cmem.Free(p)
+			return err
+		}
+		busy := allocGarbage(64)
+		cmem.Free(p)
+		logf("collections for 64MiB of garbage: %d with C empty, %d with 240MiB in C", idle, busy)
+		if busy == 0 {
+			return fmt.Errorf("no collection for 64MiB of garbage with the Go limit at %d", total/16)
+		}
+		if err := check("after freeing"); err != nil {
+			return err
+		}
+
+		if prev := cmem.SetTotalMemoryLimit(math.MaxInt64); prev != total {
+			return fmt.Errorf("SetTotalMemoryLimit returned %d, want %d", prev, total)
+		}
+		if got := debug.SetMemoryLimit(-1); got != orig {
+			return fmt.Errorf("Go limit %d after sharing stopped, want %d as before", got, orig)
+		}
+		return nil
+	})
+}
@@ -0,0 +1,91 @@
+//go:build !nocgo && !windows
+
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"hash/fnv"
+
+	"github.com/lxwagn/using-go-with-c-libraries/pkg/mylib"
+)
+
+func fnv32a(b []byte) uint32 {
+	h := fnv.New32a()
+	h.Write(b)
+	return h.Sum32()
+}
+
+func init() {
+	// A message filled in from Go through its payload view is read by C
+	// to the last byte and no further: the payload starts at offset 6,
+	// inside the padding of the header.
+	register("message/fill", func() error {
+		for _, n := range []int{0, 1, 2, 3, 1000} {
+			m, err := mylib.NewMessage(7, n)
+			if err != nil {
+				return err
+			}
+			p := m.Payload()
+			for i := range p {
+				p[i] = byte(i*7 + 1)
+			}
+			if err := checkMessage(m, 7, p); err != nil {
+				m.Free()
+				return fmt.Errorf("%d-byte payload: %v", n, err)
+			}
+			m.Free()
+		}
+		return nil
+	})
+
+	// A message the library allocates is read through the length in its
+	// header and freed with myFree.
+	register("message/from-library", func() error {
+		before, err := mylib.Allocations()
+		if err != nil {
+			return err
+		}
+		payload := []byte("variable-length payload")
+		m, err := mylib.CopyMessage(3, payload)
+		if err != nil {
+			return err
+		}
+		if err := checkMessage(m, 3, payload); err != nil {
+			m.Free()
+			return err
+		}
+		m.Free()
+		if n, _ := mylib.Allocations(); n != before {
+			return fmt.Errorf("%d library allocations live after Free, want %d", n, before)
+		}
+		if p := m.Payload(); p != nil {
+			return fmt.Errorf("Payload after Free = %q, want nil", p)
+		}
+		if _, err := m.Checksum(); !errors.Is(err, mylib.ErrClosed) {
+			return fmt.Errorf("Checksum after Free: %v, want ErrClosed", err)
+		}
+		return nil
+	})
+}
+
+func checkMessage(m *mylib.Message, typ uint16, payload []byte) error {
+	if got := m.Type(); got != typ {
+		return fmt.Errorf("type %d, want %d", got, typ)
+	}
+	if got := m.Payload(); !bytes.Equal(got, payload) {
+		return fmt.Errorf("payload %q, want %q", got, payload)
+	}
+	if got, want := len(m.Bytes()), mylib.MessageSize(len(payload)); got != want || want != 6+len(payload) {
+		return fmt.Errorf("message of %d bytes, want %d with 6 of header", got, want)
+	}
+	sum, err := m.Checksum()
+	if err != nil {
+		return err
+	}
+	if want := fnv32a(payload); sum != want {
+		return fmt.Errorf("myMessageChecksum = %#x, want %#x", sum, want)
+	}
+	return nil
+}
@@ -0,0 +1,65 @@
+//go:build darwin && !nocgo
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/lxwagn/using-go-with-c-libraries/examples/objc"
+)
+
+func init() {
+	register("objc/nsstring", func() error {
+		for _, s := range []string{"", "hello", "Grüße, 世界 🐹"} {
+			ns, err := objc.NewString(s)
+			if err != nil {
+				return err
+			}
+			if got := ns.String(); got != s {
+				return fmt.Errorf("round trip of %q gave %q", s, got)
+			}
+		}
+		ns, _ := objc.NewString("🐹")
+		// One rune outside the BMP is two UTF-16 code units.
+		if n := ns.Len(); n != 2 {
+			return fmt.Errorf("Len = %d, want 2", n)
+		}
+		if _, err := objc.NewString("\xff"); !errors.Is(err, objc.ErrInvalidUTF8) {
+			return fmt.Errorf("invalid UTF-8: %v", err)
+		}
+
+		i, _ := objc.NewString("istanbul")
+		if got := i.Upper("tr_TR").String(); got != "İSTANBUL" {
+			return fmt.Errorf("Turkish upper case = %q", got)
+		}
+		a, _ := objc.NewString("file9.txt")
+		b, _ := objc.NewString("file10.txt")
+		if objc.Compare(a, b) != -1 {
+			return errors.New("file9.txt does not sort before file10.txt")
+		}
+		return nil
+	})
+
+	register("objc/foundation", func() error {
+		logf("%s on %s", objc.OSVersion(), objc.HostName())
+		if v := objc.OSVersion(); !strings.HasPrefix(v, "Version ") {
+			return fmt.Errorf("OSVersion = %q", v)
+		}
+		if got := objc.FormatNumber(1234567.5, "de_DE"); got != "1.234.567,5" {
+			return fmt.Errorf("de_DE number = %q", got)
+		}
+		ran := false
+		objc.WithAutoreleasePool(func() {
+			for range 1000 {
+				objc.HostName()
+			}
+			ran = true
+		})
+		if !ran {
+			return errors.New("WithAutoreleasePool did not run its function")
+		}
+		return nil
+	})
+}
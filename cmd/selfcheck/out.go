@@ -0,0 +1,74 @@
+//go:build !nocgo && !windows
+
+package main
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/lxwagn/using-go-with-c-libraries/pkg/cptr"
+	"github.com/lxwagn/using-go-with-c-libraries/pkg/mylib"
+)
+
+func init() {
+	// Receive hands back what was stored in the slot, or the zero value
+	// if the call failed after storing something.
+	register("out/receive", func() error {
+		v, err := cptr.Receive(func(out *int32) error {
+			*out = 7
+			return nil
+		})
+		if err != nil || v != 7 {
+			return fmt.Errorf("Receive = %d, %v, want 7", v, err)
+		}
+		errBoom := errors.New("boom")
+		v, err = cptr.Receive(func(out *int32) error {
+			*out = 9
+			return errBoom
+		})
+		if !errors.Is(err, errBoom) || v != 0 {
+			return fmt.Errorf("Receive after a failure = %d, %v, want 0 and the error", v, err)
+		}
+		return nil
+	})
+
+	// Both strings come back through char ** parameters, and are freed
+	// once copied, on failure as well as success.
+	register("out/parse", func() error {
+		before, err := mylib.Allocations()
+		if err != nil {
+			return err
+		}
+		for _, c := range []struct{ line, key, value string }{
+			{"name=cgo", "name", "cgo"},
+			{"  spaced key\t =  a = b  ", "spaced key", "a = b"},
+			{"empty=", "empty", ""},
+		} {
+			k, v, err := mylib.ParseKeyValue(c.line)
+			if err != nil || k != c.key || v != c.value {
+				return fmt.Errorf("ParseKeyValue(%q) = %q, %q, %v", c.line, k, v, err)
+			}
+		}
+		for _, line := range []string{"no equals sign", " = value"} {
+			if _, _, err := mylib.ParseKeyValue(line); !errors.Is(err, mylib.ErrInvalid) {
+				return fmt.Errorf("ParseKeyValue(%q): %v, want ErrInvalid", line, err)
+			}
+		}
+		if after, _ := mylib.Allocations(); after != before {
+			return fmt.Errorf("%d library allocations live afterwards, %d before", after, before)
+		}
+		return nil
+	})
+
+	register("out/buffer", func() error {
+		b, err := mylib.NewBufferFrom("from an out-parameter")
+		if err != nil {
+			return err
+		}
+		defer b.Close()
+		if got := b.String(); got != "from an out-parameter" {
+			return fmt.Errorf("buffer holds %q", got)
+		}
+		return nil
+	})
+}
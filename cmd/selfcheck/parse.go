@@ -0,0 +1,59 @@
+//go:build !nocgo && !windows
+
+package main
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/lxwagn/using-go-with-c-libraries/pkg/mylib"
+)
+
+func init() {
+	register("parse/records", func() error {
+		for record, want := range map[string]int64{
+			"answer=42":               42,
+			"zero=0":                  0,
+			"max=9223372036854775807": 1<<63 - 1,
+		} {
+			got, err := mylib.ParseRecord(record)
+			if err != nil || got != want {
+				return fmt.Errorf("ParseRecord(%q) = %d, %v, want %d", record, got, err, want)
+			}
+		}
+		return nil
+	})
+
+	// Each of these makes myParseRecord longjmp out of the middle of its
+	// loop, back into the shim.
+	register("parse/longjmp", func() error {
+		for record, want := range map[string]string{
+			"answer":                  "missing '='",
+			"=1":                      "empty key",
+			"answer=":                 "empty value",
+			"answer=4x":               "value is not a number",
+			"max=9223372036854775808": "value out of range",
+		} {
+			_, err := mylib.ParseRecord(record)
+			var perr *mylib.ParseError
+			if !errors.As(err, &perr) || perr.Msg != want || !errors.Is(err, mylib.ErrInvalid) {
+				return fmt.Errorf("ParseRecord(%q) = %v, want a ParseError %q", record, err, want)
+			}
+		}
+		return nil
+	})
+
+	// Many jumps, each followed by a call that returns, in case a jump
+	// leaves the thread or the library in a bad state.
+	register("parse/repeated", func() error {
+		for i := range 10000 {
+			if _, err := mylib.ParseRecord("n=x"); err == nil {
+				return fmt.Errorf("jump %d: no error", i)
+			}
+			if v, err := mylib.ParseRecord(fmt.Sprintf("n=%d", i)); err != nil || v != int64(i) {
+				return fmt.Errorf("after %d jumps: ParseRecord = %d, %v", i, v, err)
+			}
+		}
+		return nil
+	})
+}
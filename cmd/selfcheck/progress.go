@@ -0,0 +1,123 @@
+//go:build !nocgo && !windows
+
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/lxwagn/using-go-with-c-libraries/pkg/handles"
+	"github.com/lxwagn/using-go-with-c-libraries/pkg/mylib"
+)
+
+const progressIterations = 1 << 20
+
+func init() {
+	// myCrunchProgress reports 1024 times over the run; at the default
+	// step of 1% about a hundred of those reach Go, in order, ending at
+	// 100, and the result is Crunch's.
+	register("progress/func", func() error {
+		var reports []float64
+		got, err := mylib.CrunchProgress(context.Background(), progressIterations, func(percent float64) {
+			reports = append(reports, percent)
+		})
+		if err != nil {
+			return err
+		}
+		want, err := mylib.Crunch(context.Background(), progressIterations)
+		if err != nil {
+			return err
+		}
+		if got != want {
+			return fmt.Errorf("CrunchProgress = %d, Crunch = %d", got, want)
+		}
+		if len(reports) < 50 || len(reports) > 102 {
+			return fmt.Errorf("%d reports at a 1%% step", len(reports))
+		}
+		for i := 1; i < len(reports); i++ {
+			if reports[i] <= reports[i-1] {
+				return fmt.Errorf("report %d went from %v to %v", i, reports[i-1], reports[i])
+			}
+		}
+		if last := reports[len(reports)-1]; last != 100 {
+			return fmt.Errorf("last report %v, want 100", last)
+		}
+		logf("%d reports", len(reports))
+		return nil
+	})
+
+	// With no step every report crosses into Go, and an interval longer
+	// than the run lets through only the first and the last.
+	register("progress/throttle", func() error {
+		n := 0
+		_, err := mylib.CrunchProgress(context.Background(), progressIterations, func(float64) { n++ }, mylib.ProgressStep(0))
+		if err != nil {
+			return err
+		}
+		if n != progressIterations/1024 {
+			return fmt.Errorf("%d reports without a step, want %d", n, progressIterations/1024)
+		}
+
+		var reports []float64
+		_, err = mylib.CrunchProgress(context.Background(), progressIterations, func(percent float64) {
+			reports = append(reports, percent)
+		}, mylib.ProgressStep(0), mylib.ProgressInterval(time.Hour))
+		if err != nil {
+			return err
+		}
+		if len(reports) != 2 || reports[1] != 100 {
+			return fmt.Errorf("reports %v with an hour's interval, want two ending at 100", reports)
+		}
+		return nil
+	})
+
+	register("progress/chan", func() error {
+		job := mylib.StartCrunch(context.Background(), progressIterations)
+		var last mylib.ProgressEvent
+		n := 0
+		for ev := range job.Progress() {
+			if ev.Done <= last.Done || ev.Total != progressIterations {
+				return fmt.Errorf("got %+v after %+v", ev, last)
+			}
+			last = ev
+			n++
+		}
+		got, err := job.Wait()
+		if err != nil {
+			return err
+		}
+		want, _ := mylib.Crunch(context.Background(), progressIterations)
+		if got != want || last.Percent != 100 {
+			return fmt.Errorf("Wait = %d, last %+v; want %d and 100%%", got, last, want)
+		}
+		logf("%d events received", n)
+		return nil
+	})
+
+	// The filter in C sees the cancellation at its next report, without
+	// asking Go.
+	register("progress/cancel", func() error {
+		liveBefore := handles.Live()
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		var last float64
+		_, err := mylib.CrunchProgress(ctx, progressIterations, func(percent float64) {
+			last = percent
+			if percent >= 10 {
+				cancel()
+			}
+		})
+		if !errors.Is(err, context.Canceled) {
+			return fmt.Errorf("CrunchProgress cancelled at 10%% = %v, want context.Canceled", err)
+		}
+		if last >= 20 {
+			return fmt.Errorf("reports went on to %v%% after cancelling", last)
+		}
+		if live := handles.Live(); live != liveBefore {
+			return fmt.Errorf("%d handles live after the call, want %d", live, liveBefore)
+		}
+		return nil
+	})
+}
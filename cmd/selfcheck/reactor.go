@@ -0,0 +1,173 @@
+//go:build !nocgo && !windows
+
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"runtime"
+	"syscall"
+	"time"
+
+	"github.com/lxwagn/using-go-with-c-libraries/pkg/cpoll"
+	"github.com/lxwagn/using-go-with-c-libraries/pkg/mylib"
+)
+
+func init() {
+	// A handler that reads its pipe and removes it is called once for a
+	// write, and Close then returns with nothing watched.
+	register("cpoll/pipe", func() error {
+		var fds [2]int
+		if err := syscall.Pipe(fds[:]); err != nil {
+			return err
+		}
+		defer syscall.Close(fds[0])
+		defer syscall.Close(fds[1])
+		syscall.SetNonblock(fds[0], true)
+
+		p, err := cpoll.New()
+		if err != nil {
+			return err
+		}
+		got := make(chan string, 2)
+		err = p.Add(fds[0], cpoll.Readable, func(fd int, ev cpoll.Events) {
+			buf := make([]byte, 16)
+			n, _ := syscall.Read(fd, buf)
+			got <- fmt.Sprintf("%v %q", ev, buf[:max(n, 0)])
+			p.Remove(fd)
+		})
+		if err != nil {
+			p.Close()
+			return err
+		}
+		if err := p.Add(fds[0], cpoll.Readable, nil); !errors.Is(err, os.ErrExist) {
+			p.Close()
+			return fmt.Errorf("adding fd %d twice: %v, want ErrExist", fds[0], err)
+		}
+		syscall.Write(fds[1], []byte("ping"))
+		select {
+		case s := <-got:
+			if want := `readable "ping"`; s != want {
+				p.Close()
+				return fmt.Errorf("handler got %s, want %s", s, want)
+			}
+		case <-time.After(5 * time.Second):
+			p.Close()
+			return errors.New("handler not called within 5s")
+		}
+		syscall.Write(fds[1], []byte("again"))
+		time.Sleep(10 * time.Millisecond)
+		if err := p.Close(); err != nil {
+			return err
+		}
+		if len(got) != 0 || p.Len() != 0 {
+			return fmt.Errorf("handler called %d more times after Remove; %d fds watched", len(got), p.Len())
+		}
+		if err := p.Close(); !errors.Is(err, cpoll.ErrClosed) {
+			return fmt.Errorf("second Close = %v, want ErrClosed", err)
+		}
+		return nil
+	})
+
+	// Many connections, each sending more than the reactor's buffer and
+	// the socket's, are all echoed by the poller's one goroutine.
+	register("reactor/echo", func() error {
+		r, err := mylib.NewReactor()
+		if err != nil {
+			return err
+		}
+		defer r.Close()
+
+		const conns, size = 16, 256 << 10
+		before := runtime.NumGoroutine()
+		cs := make([]net.Conn, conns)
+		for i := range cs {
+			if cs[i], err = r.Connect(); err != nil {
+				return err
+			}
+			defer cs[i].Close()
+		}
+		if n := runtime.NumGoroutine(); n > before || r.Conns() != conns {
+			return fmt.Errorf("%d connections: %d goroutines, was %d; reactor serving %d", conns, n, before, r.Conns())
+		}
+
+		errs := make(chan error, conns)
+		for i, c := range cs {
+			msg := bytes.Repeat([]byte{byte('a' + i)}, size)
+			go func() {
+				go c.Write(msg)
+				back := make([]byte, size)
+				if _, err := io.ReadFull(c, back); err != nil {
+					errs <- err
+				} else if !bytes.Equal(back, msg) {
+					errs <- fmt.Errorf("connection %d echoed other bytes", i)
+				} else {
+					errs <- nil
+				}
+			}()
+		}
+		for range conns {
+			if err := <-errs; err != nil {
+				return err
+			}
+		}
+		logf("reactor echoed %d bytes", r.Bytes())
+		if n := r.Bytes(); n != conns*size {
+			return fmt.Errorf("reactor echoed %d bytes, want %d", n, conns*size)
+		}
+
+		for _, c := range cs {
+			c.Close()
+		}
+		deadline := time.Now().Add(5 * time.Second)
+		for r.Conns() > 0 && time.Now().Before(deadline) {
+			time.Sleep(time.Millisecond)
+		}
+		if n := r.Conns(); n != 0 {
+			return fmt.Errorf("reactor still serving %d connections after their peers closed", n)
+		}
+		return nil
+	})
+
+	// The library's limit surfaces as EMFILE, and Close ends every
+	// connection still open.
+	register("reactor/close", func() error {
+		r, err := mylib.NewReactor()
+		if err != nil {
+			return err
+		}
+		var cs []net.Conn
+		defer func() {
+			for _, c := range cs {
+				c.Close()
+			}
+		}()
+		for range mylib.MaxReactorConns {
+			c, err := r.Connect()
+			if err != nil {
+				r.Close()
+				return err
+			}
+			cs = append(cs, c)
+		}
+		if _, err := r.Connect(); !errors.Is(err, syscall.EMFILE) {
+			r.Close()
+			return fmt.Errorf("connection %d: %v, want EMFILE", mylib.MaxReactorConns+1, err)
+		}
+		if err := r.Close(); err != nil {
+			return err
+		}
+		cs[0].SetReadDeadline(time.Now().Add(5 * time.Second))
+		if n, err := cs[0].Read(make([]byte, 1)); err != io.EOF {
+			return fmt.Errorf("read after Close = %d, %v; want end of file", n, err)
+		}
+		if _, err := r.Connect(); !errors.Is(err, mylib.ErrClosed) {
+			return fmt.Errorf("Connect after Close: %v, want ErrClosed", err)
+		}
+		return nil
+	})
+}
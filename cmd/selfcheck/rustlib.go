@@ -0,0 +1,109 @@
+//go:build rustlib && !nocgo && !windows
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+
+	"github.com/lxwagn/using-go-with-c-libraries/examples/rustlib"
+	"github.com/lxwagn/using-go-with-c-libraries/pkg/handles"
+)
+
+func init() {
+	register("rustlib/strings", func() error {
+		logf("%s", rustlib.Version())
+		got, err := rustlib.Greet("Göpher")
+		if err != nil {
+			return err
+		}
+		// Rust counts characters, not bytes.
+		if want := "Hello, Göpher! (6 characters)"; got != want {
+			return fmt.Errorf("Greet = %q, want %q", got, want)
+		}
+		var e *rustlib.Error
+		if _, err := rustlib.Greet(""); !errors.As(err, &e) || e.Msg != "empty name" {
+			return fmt.Errorf("empty name: %v", err)
+		}
+		if _, err := rustlib.Greet("a\x00b"); !errors.Is(err, rustlib.ErrNUL) {
+			return fmt.Errorf("NUL: %v", err)
+		}
+		// Invalid UTF-8 is Rust's to reject.
+		if _, err := rustlib.Greet("\xff"); !errors.As(err, &e) {
+			return fmt.Errorf("invalid UTF-8: %v", err)
+		}
+		return nil
+	})
+
+	register("rustlib/structs", func() error {
+		a, b := rustlib.Point{X: 1, Y: 2}, rustlib.Point{X: 4, Y: 6}
+		if d := rustlib.Distance(a, b); d != 5 {
+			return fmt.Errorf("Distance = %v", d)
+		}
+		if m := rustlib.Midpoint(a, b); m != (rustlib.Point{X: 2.5, Y: 4}) {
+			return fmt.Errorf("Midpoint = %v", m)
+		}
+		p, err := rustlib.ParsePoint(" 1.5, -2 ")
+		if err != nil || p != (rustlib.Point{X: 1.5, Y: -2}) {
+			return fmt.Errorf("ParsePoint = %v, %v", p, err)
+		}
+		if _, err := rustlib.ParsePoint("1;2"); err == nil || !strings.Contains(err.Error(), "want x,y") {
+			return fmt.Errorf("bad point: %v", err)
+		}
+		return nil
+	})
+
+	register("rustlib/panic", func() error {
+		if q, err := rustlib.Divide(7, 2); err != nil || q != 3 {
+			return fmt.Errorf("Divide(7, 2) = %d, %v", q, err)
+		}
+		_, err := rustlib.Divide(1, 0)
+		if err == nil || !strings.Contains(err.Error(), "panic: attempt to divide by zero") {
+			return fmt.Errorf("Divide(1, 0): %v", err)
+		}
+		logf("%v", err)
+		return nil
+	})
+
+	register("rustlib/objects", func() error {
+		live := handles.Live()
+		s := rustlib.NewStats()
+		for _, v := range []float64{3, 1, 4, 1, 5} {
+			if err := s.Add(v); err != nil {
+				return err
+			}
+		}
+		if err := s.Add(math.NaN()); err == nil {
+			return errors.New("NaN accepted")
+		}
+		sum, err := s.Summary()
+		if err != nil {
+			return err
+		}
+		if sum != (rustlib.Summary{Count: 5, Mean: 2.8, Min: 1, Max: 5}) {
+			return fmt.Errorf("Summary = %+v", sum)
+		}
+
+		var seen []float64
+		n, err := s.Each(func(i int, v float64) bool {
+			seen = append(seen, v)
+			return i < 2
+		})
+		if err != nil || n != 3 || fmt.Sprint(seen) != "[3 1 4]" {
+			return fmt.Errorf("Each visited %d: %v, %v", n, seen, err)
+		}
+		if handles.Live() != live {
+			return errors.New("Each leaked its handle")
+		}
+
+		if err := s.Close(); err != nil {
+			return err
+		}
+		if err := s.Add(1); !errors.Is(err, rustlib.ErrClosed) {
+			return fmt.Errorf("Add after Close: %v", err)
+		}
+		return nil
+	})
+}
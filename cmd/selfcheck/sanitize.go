@@ -0,0 +1,65 @@
+//go:build (asan || msan) && !nocgo && !windows
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/lxwagn/using-go-with-c-libraries/internal/sanbugs"
+)
+
+// These checks need a sanitizer:
+//
+//	go run -asan ./cmd/selfcheck -run sanitize
+//	CC=clang go run -msan ./cmd/selfcheck -run sanitize
+//
+// A sanitizer report aborts the process, so each bug is committed in a
+// child process.
+
+func init() {
+	bugs := map[string]func() int{
+		"out-of-bounds":  sanbugs.OutOfBounds,
+		"use-after-free": sanbugs.UseAfterFree,
+		"uninitialized":  sanbugs.Uninitialized,
+	}
+	sanitizer, report := "address", map[string]string{
+		"out-of-bounds":  "heap-buffer-overflow",
+		"use-after-free": "heap-use-after-free",
+	}
+	if msanEnabled {
+		sanitizer, report = "memory", map[string]string{
+			"uninitialized": "use-of-uninitialized-value",
+		}
+	}
+
+	for _, bug := range []string{"out-of-bounds", "use-after-free", "uninitialized"} {
+		child := "sanbug-" + bug
+		registerChild(child, func() { bugs[bug]() })
+
+		want, ok := report[bug]
+		register("sanitize/"+bug, func() error {
+			if !ok {
+				logf("not detected by the %s sanitizer; skipped", sanitizer)
+				return nil
+			}
+			out, err := runChild(child)
+			if errors.Is(err, errSurvived) {
+				return errors.New("bug went undetected")
+			}
+			if err != nil {
+				return err
+			}
+			// The report's first line, e.g. "ERROR: AddressSanitizer:
+			// heap-buffer-overflow on address ...".
+			for line := range strings.Lines(out) {
+				if strings.Contains(line, want) {
+					logf("%s", strings.TrimSpace(line))
+					return nil
+				}
+			}
+			return fmt.Errorf("no %q in the output:\n%s", want, out)
+		})
+	}
+}
@@ -0,0 +1,5 @@
+//go:build asan && !msan && !nocgo && !windows
+
+package main
+
+const msanEnabled = false
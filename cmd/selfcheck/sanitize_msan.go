@@ -0,0 +1,5 @@
+//go:build msan && !nocgo && !windows
+
+package main
+
+const msanEnabled = true
@@ -0,0 +1,192 @@
+//go:build linux && !nocgo
+
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/lxwagn/using-go-with-c-libraries/pkg/mylib"
+	"github.com/lxwagn/using-go-with-c-libraries/pkg/shm"
+)
+
+func init() {
+	// A ring created by package shm and used by the C library, and the
+	// other way round, across enough messages to wrap around many times.
+	register("shm/go-to-c", func() error {
+		name := shmName("go-to-c")
+		g, err := shm.CreateRing(name, 256)
+		if err != nil {
+			return err
+		}
+		defer shm.Unlink(name)
+		defer g.Close()
+		c, err := mylib.OpenRing(name)
+		if err != nil {
+			return err
+		}
+		defer c.Close()
+		return pumpRing(g.Push, c.Pop, func(err error) bool { return errors.Is(err, shm.ErrFull) })
+	})
+
+	register("shm/c-to-go", func() error {
+		name := shmName("c-to-go")
+		c, err := mylib.CreateRing(name, 256)
+		if err != nil {
+			return err
+		}
+		defer mylib.UnlinkRing(name)
+		defer c.Close()
+		g, err := shm.OpenRing(name)
+		if err != nil {
+			return err
+		}
+		defer g.Close()
+		return pumpRing(c.Push, g.Pop, func(err error) bool { return errors.Is(err, mylib.ErrRange) })
+	})
+
+	register("shm/limits", func() error {
+		name := shmName("limits")
+		g, err := shm.CreateRing(name, 64)
+		if err != nil {
+			return err
+		}
+		defer shm.Unlink(name)
+		defer g.Close()
+		c, err := mylib.OpenRing(name)
+		if err != nil {
+			return err
+		}
+		defer c.Close()
+
+		if _, err := c.Pop(nil); !errors.Is(err, mylib.ErrNotFound) {
+			return fmt.Errorf("C Pop of empty ring: %v, want ErrNotFound", err)
+		}
+		if _, err := g.Pop(nil); !errors.Is(err, shm.ErrEmpty) {
+			return fmt.Errorf("Go Pop of empty ring: %v, want ErrEmpty", err)
+		}
+		if err := c.Push(make([]byte, 61)); !errors.Is(err, mylib.ErrInvalid) {
+			return fmt.Errorf("C Push of oversized message: %v, want ErrInvalid", err)
+		}
+		if err := g.Push(make([]byte, 61)); !errors.Is(err, shm.ErrTooLarge) {
+			return fmt.Errorf("Go Push of oversized message: %v, want ErrTooLarge", err)
+		}
+		if err := g.Push(make([]byte, 40)); err != nil {
+			return err
+		}
+		if err := c.Push(make([]byte, 20)); !errors.Is(err, mylib.ErrRange) {
+			return fmt.Errorf("C Push into full ring: %v, want ErrRange", err)
+		}
+		if n, err := c.Pop(make([]byte, 10)); !errors.Is(err, mylib.ErrRange) || n != 40 {
+			return fmt.Errorf("C Pop into short buffer = %d, %v; want 40, ErrRange", n, err)
+		}
+		if n, err := g.Pop(make([]byte, 10)); err != io.ErrShortBuffer || n != 40 {
+			return fmt.Errorf("Go Pop into short buffer = %d, %v; want 40, io.ErrShortBuffer", n, err)
+		}
+		if n, err := c.Pop(make([]byte, 40)); err != nil || n != 40 {
+			return fmt.Errorf("C Pop = %d, %v; want 40, nil", n, err)
+		}
+
+		if _, err := mylib.CreateRing(name, 64); err == nil {
+			return errors.New("C CreateRing of an existing name succeeded")
+		}
+		if _, err := mylib.CreateRing(shmName("odd"), 100); !errors.Is(err, mylib.ErrInvalid) {
+			return fmt.Errorf("C CreateRing with capacity 100: %v, want ErrInvalid", err)
+		}
+		return nil
+	})
+
+	// Two mappings of one object see each other's writes through typed
+	// views.
+	register("shm/view", func() error {
+		type record struct {
+			ID    uint32
+			Flags uint32
+			Value float64
+		}
+		name := shmName("view")
+		a, err := shm.Create(name, 4096)
+		if err != nil {
+			return err
+		}
+		defer shm.Unlink(name)
+		defer a.Close()
+		b, err := shm.Open(name)
+		if err != nil {
+			return err
+		}
+		defer b.Close()
+
+		ra, err := shm.ViewSlice[record](a, 64, 4)
+		if err != nil {
+			return err
+		}
+		for i := range ra {
+			ra[i] = record{ID: uint32(i), Value: float64(i) / 2}
+		}
+		rb, err := shm.View[record](b, 64+3*16)
+		if err != nil {
+			return err
+		}
+		if *rb != ra[3] {
+			return fmt.Errorf("second mapping sees %+v, want %+v", *rb, ra[3])
+		}
+
+		if _, err := shm.View[record](a, 4); err == nil {
+			return errors.New("misaligned View succeeded")
+		}
+		if _, err := shm.ViewSlice[record](a, 4096-16, 2); err == nil {
+			return errors.New("View past the end succeeded")
+		}
+		return nil
+	})
+}
+
+func shmName(what string) string {
+	return fmt.Sprintf("/selfcheck-%d-%s", os.Getpid(), what)
+}
+
+// pumpRing pushes messages of varying length until several times the
+// ring's capacity has gone through it, popping whenever it is full, and
+// checks that every message comes out intact and in order.
+func pumpRing(push func([]byte) error, pop func([]byte) (int, error), full func(error) bool) error {
+	var sent, recv int
+	buf := make([]byte, 64)
+	msg := func(i int) []byte {
+		return bytes.Repeat([]byte{byte('a' + i%26)}, i%37)
+	}
+	drain := func() error {
+		for recv < sent {
+			n, err := pop(buf)
+			if err != nil {
+				return fmt.Errorf("pop %d: %w", recv, err)
+			}
+			if want := msg(recv); !bytes.Equal(buf[:n], want) {
+				return fmt.Errorf("message %d = %q, want %q", recv, buf[:n], want)
+			}
+			recv++
+		}
+		return nil
+	}
+	for sent < 2000 {
+		err := push(msg(sent))
+		switch {
+		case err == nil:
+			sent++
+		case full(err):
+			if err := drain(); err != nil {
+				return err
+			}
+		default:
+			return fmt.Errorf("push %d: %w", sent, err)
+		}
+	}
+	if err := drain(); err != nil {
+		return err
+	}
+	logf("%d messages through the ring", recv)
+	return nil
+}
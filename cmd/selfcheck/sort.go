@@ -0,0 +1,80 @@
+//go:build !nocgo && !windows
+
+package main
+
+import (
+	"cmp"
+	"fmt"
+	"math/rand/v2"
+	"slices"
+	"sync"
+
+	"github.com/lxwagn/using-go-with-c-libraries/examples/qsort"
+	"github.com/lxwagn/using-go-with-c-libraries/pkg/mylib"
+)
+
+func init() {
+	// mySort keeps equal keys in the order they came, which qsort does
+	// not promise.
+	register("sort/mylib", func() error {
+		type pair struct{ key, seq int32 }
+		r := rand.New(rand.NewPCG(3, 4))
+		s := make([]pair, 5000)
+		for i := range s {
+			s[i] = pair{r.Int32N(50), int32(i)}
+		}
+		if err := mylib.Sort(s, func(a, b pair) int { return cmp.Compare(a.key, b.key) }); err != nil {
+			return err
+		}
+		for i := 1; i < len(s); i++ {
+			if a, b := s[i-1], s[i]; a.key > b.key || a.key == b.key && a.seq > b.seq {
+				return fmt.Errorf("%v before %v at %d", a, b, i)
+			}
+		}
+		return nil
+	})
+
+	// qsort's comparison can only be found through a package variable,
+	// so concurrent sorts must not see each other's.
+	register("sort/qsort", func() error {
+		var wg sync.WaitGroup
+		errs := make([]error, 8)
+		for g := range errs {
+			wg.Go(func() {
+				s := sortInput(1000, uint64(g))
+				want := slices.Clone(s)
+				// Half the goroutines sort descending.
+				order := cmp.Compare[int64]
+				if g%2 == 1 {
+					order = func(a, b int64) int { return cmp.Compare(b, a) }
+				}
+				slices.SortFunc(want, order)
+				qsort.Slice(s, order)
+				if !slices.Equal(s, want) {
+					errs[g] = fmt.Errorf("goroutine %d: qsort.Slice gave a different order", g)
+				}
+			})
+		}
+		wg.Wait()
+		for _, err := range errs {
+			if err != nil {
+				return err
+			}
+		}
+		s := sortInput(1000, 99)
+		qsort.Int64s(s)
+		if !slices.IsSorted(s) {
+			return fmt.Errorf("qsort.Int64s left the slice unsorted")
+		}
+		return nil
+	})
+}
+
+func sortInput(n int, seed uint64) []int64 {
+	r := rand.New(rand.NewPCG(seed, 0))
+	s := make([]int64, n)
+	for i := range s {
+		s[i] = r.Int64N(1000)
+	}
+	return s
+}
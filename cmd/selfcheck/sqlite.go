@@ -0,0 +1,208 @@
+//go:build !nocgo && !windows
+
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/lxwagn/using-go-with-c-libraries/examples/sqlite"
+	"github.com/lxwagn/using-go-with-c-libraries/pkg/handles"
+)
+
+func init() {
+	register("sqlite/round-trip", func() error {
+		db, err := sqlite.Open(":memory:")
+		if err != nil {
+			return err
+		}
+		defer db.Close()
+		logf("SQLite %s", sqlite.Version())
+
+		err = db.Exec(`
+			CREATE TABLE t (id INTEGER PRIMARY KEY, n INTEGER, f REAL, s TEXT, b BLOB);
+			INSERT INTO t (n, f, s, b) VALUES (?, ?, ?, ?);
+		`, 1<<40, 2.5, "héllo\x00world", []byte{0, 1, 2, 255})
+		if err != nil {
+			return err
+		}
+		if id := db.LastInsertID(); id != 1 {
+			return fmt.Errorf("LastInsertID = %d, want 1", id)
+		}
+		if err := db.Exec("INSERT INTO t (n, f, s, b) VALUES (?, ?, ?, ?)", nil, nil, "", []byte{}); err != nil {
+			return err
+		}
+
+		type row struct {
+			n    int64
+			f    float64
+			s    string
+			b    []byte
+			null any
+		}
+		var got []row
+		for r, err := range db.Query("SELECT n, f, s, b, n FROM t ORDER BY id") {
+			if err != nil {
+				return err
+			}
+			var x row
+			if err := r.Scan(&x.n, &x.f, &x.s, &x.b, &x.null); err != nil {
+				return err
+			}
+			got = append(got, x)
+		}
+		if len(got) != 2 {
+			return fmt.Errorf("got %d rows, want 2", len(got))
+		}
+		if r := got[0]; r.n != 1<<40 || r.f != 2.5 || r.s != "héllo\x00world" ||
+			!bytes.Equal(r.b, []byte{0, 1, 2, 255}) || r.null != int64(1<<40) {
+			return fmt.Errorf("first row = %+v", r)
+		}
+		// An empty string and blob stay values; only nil is NULL.
+		if r := got[1]; r.s != "" || r.b == nil || len(r.b) != 0 || r.null != nil {
+			return fmt.Errorf("second row = %+v", r)
+		}
+		return nil
+	})
+
+	register("sqlite/prepared", func() error {
+		db, err := sqlite.Open(":memory:")
+		if err != nil {
+			return err
+		}
+		defer db.Close()
+		if err := db.Exec("CREATE TABLE kv (k TEXT PRIMARY KEY, v INTEGER)"); err != nil {
+			return err
+		}
+
+		ins, err := db.Prepare("INSERT INTO kv VALUES (?, ?)")
+		if err != nil {
+			return err
+		}
+		defer ins.Close()
+		for i := range 100 {
+			if err := ins.Bind(fmt.Sprintf("key%03d", i), i*i); err != nil {
+				return err
+			}
+			if _, err := ins.Step(); err != nil {
+				return err
+			}
+		}
+
+		sel, err := db.Prepare("SELECT k, v FROM kv WHERE v >= ? ORDER BY v LIMIT 3")
+		if err != nil {
+			return err
+		}
+		defer sel.Close()
+		if cols := sel.Columns(); !slices.Equal(cols, []string{"k", "v"}) {
+			return fmt.Errorf("Columns = %q", cols)
+		}
+		// The same statement, run twice, once stopped early.
+		for _, limit := range []int{3, 1} {
+			var keys []string
+			for r, err := range sel.Rows(50) {
+				if err != nil {
+					return err
+				}
+				var k string
+				var v int
+				if err := r.Scan(&k, &v); err != nil {
+					return err
+				}
+				if keys = append(keys, k); len(keys) == limit {
+					break
+				}
+			}
+			if want := []string{"key008", "key009", "key010"}[:limit]; !slices.Equal(keys, want) {
+				return fmt.Errorf("keys = %q, want %q", keys, want)
+			}
+		}
+		if err := ins.Bind("only one"); err == nil {
+			return errors.New("Bind with too few arguments succeeded")
+		}
+		return nil
+	})
+
+	register("sqlite/errors", func() error {
+		db, err := sqlite.Open(":memory:")
+		if err != nil {
+			return err
+		}
+		if err := db.Exec("CREATE TABLE u (x INTEGER UNIQUE NOT NULL); INSERT INTO u VALUES (1)"); err != nil {
+			return err
+		}
+		err = db.Exec("INSERT INTO u VALUES (1)")
+		var se *sqlite.Error
+		if !errors.Is(err, sqlite.ErrConstraint) || !errors.As(err, &se) || !strings.Contains(se.Msg, "UNIQUE") {
+			return fmt.Errorf("duplicate insert: %v, want a UNIQUE constraint error", err)
+		}
+		if se.Code == int(se.Code&0xff) {
+			return fmt.Errorf("code %d is not an extended result code", se.Code)
+		}
+		if _, err := db.Prepare("SELEKT 1"); !errors.Is(err, sqlite.ErrGeneric) {
+			return fmt.Errorf("syntax error: %v, want ErrGeneric", err)
+		}
+		if _, err := sqlite.Open("/nonexistent/dir/db"); !errors.Is(err, sqlite.ErrCantOpen) {
+			return fmt.Errorf("Open in a missing directory: %v, want ErrCantOpen", err)
+		}
+		db.Close()
+		if err := db.Exec("SELECT 1"); !errors.Is(err, sqlite.ErrClosed) {
+			return fmt.Errorf("Exec after Close: %v, want ErrClosed", err)
+		}
+		return nil
+	})
+
+	// Go functions called from SQL, including ones that fail, and the
+	// handles SQLite holds for them freed with the connection.
+	register("sqlite/functions", func() error {
+		before := handles.Live()
+		db, err := sqlite.Open(":memory:")
+		if err != nil {
+			return err
+		}
+		reverse := func(args []any) (any, error) {
+			s, ok := args[0].(string)
+			if !ok {
+				return nil, fmt.Errorf("reverse wants text, got %T", args[0])
+			}
+			r := []rune(s)
+			slices.Reverse(r)
+			return string(r), nil
+		}
+		if err := db.CreateFunction("reverse", 1, true, reverse); err != nil {
+			return err
+		}
+		if err := db.CreateFunction("boom", 0, false, func([]any) (any, error) { panic("boom") }); err != nil {
+			return err
+		}
+
+		var got string
+		for r, err := range db.Query("SELECT reverse(?)", "gopher") {
+			if err != nil {
+				return err
+			}
+			if err := r.Scan(&got); err != nil {
+				return err
+			}
+		}
+		if got != "rehpog" {
+			return fmt.Errorf("reverse = %q, want rehpog", got)
+		}
+		if err := db.Exec("SELECT reverse(1)"); err == nil || !strings.Contains(err.Error(), "wants text") {
+			return fmt.Errorf("reverse(1): %v, want the function's error", err)
+		}
+		if err := db.Exec("SELECT boom()"); err == nil || !strings.Contains(err.Error(), "panic: boom") {
+			return fmt.Errorf("boom(): %v, want the panic as an error", err)
+		}
+		if err := db.Close(); err != nil {
+			return err
+		}
+		if n := handles.Live(); n != before {
+			return fmt.Errorf("%d handles live after Close, want %d", n, before)
+		}
+		return nil
+	})
+}
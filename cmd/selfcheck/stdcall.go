@@ -0,0 +1,39 @@
+//go:build !nocgo || windows
+
+package main
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/lxwagn/using-go-with-c-libraries/pkg/mylib"
+)
+
+func init() {
+	// On windows/386, a wrong idea of who pops the arguments would move
+	// the stack pointer by their 16 bytes on every call, so the check
+	// calls often enough for that to crash. The cases need the high half
+	// of both the long long argument and the result.
+	register("stdcall/muladd", func() error {
+		cases := []struct {
+			a, b int32
+			c    int64
+		}{
+			{-3, 7, 1 << 40},
+			{math.MaxInt32, math.MaxInt32, -1},
+			{math.MinInt32, 2, math.MaxInt64 / 2},
+		}
+		for i := 0; i < 1000; i++ {
+			for _, tc := range cases {
+				got, err := mylib.MulAdd(tc.a, tc.b, tc.c)
+				if err != nil {
+					return err
+				}
+				if want := int64(tc.a)*int64(tc.b) + tc.c; got != want {
+					return fmt.Errorf("MulAdd(%d, %d, %d) = %d, want %d", tc.a, tc.b, tc.c, got, want)
+				}
+			}
+		}
+		return nil
+	})
+}
@@ -0,0 +1,72 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/lxwagn/using-go-with-c-libraries/pkg/mylib"
+)
+
+func init() {
+	register("timeout/in-time", func() error {
+		s, err := mylib.NewSession("timeout", 10)
+		if err != nil {
+			return err
+		}
+		defer s.Close()
+
+		err = s.WithTimeout(time.Second, func(s *mylib.Session) error {
+			return s.Sync(10 * time.Millisecond)
+		})
+		if err != nil || s.Poisoned() {
+			return fmt.Errorf("WithTimeout(1s, Sync(10ms)) = %v, poisoned %v", err, s.Poisoned())
+		}
+		if err := s.Sync(-time.Millisecond); !errors.Is(err, mylib.ErrInvalid) {
+			return fmt.Errorf("Sync(-1ms) = %v, want ErrInvalid", err)
+		}
+		return nil
+	})
+
+	// A call that outlives its deadline: the caller gets control back,
+	// the session refuses further calls, and the rest of the library
+	// keeps working while the call is still stuck in C.
+	register("timeout/hung", func() error {
+		s, err := mylib.NewSession("timeout", 10)
+		if err != nil {
+			return err
+		}
+
+		start := time.Now()
+		err = s.WithTimeout(20*time.Millisecond, func(s *mylib.Session) error {
+			return s.Sync(500 * time.Millisecond)
+		})
+		if !errors.Is(err, mylib.ErrTimeout) || !errors.Is(err, context.DeadlineExceeded) {
+			return fmt.Errorf("WithTimeout(20ms, Sync(500ms)) = %v, want ErrTimeout", err)
+		}
+		if elapsed := time.Since(start); elapsed > 250*time.Millisecond {
+			return fmt.Errorf("WithTimeout returned after %v", elapsed)
+		}
+		if !s.Poisoned() {
+			return errors.New("session not poisoned")
+		}
+
+		if _, err := s.Add(1); !errors.Is(err, mylib.ErrPoisoned) {
+			return fmt.Errorf("Add on a poisoned session = %v, want ErrPoisoned", err)
+		}
+		other, err := mylib.NewSession("other", 10)
+		if err != nil {
+			return err
+		}
+		defer other.Close()
+		if _, err := other.Add(1); err != nil {
+			return fmt.Errorf("Add on another session: %v", err)
+		}
+		if err := s.Close(); !errors.Is(err, mylib.ErrPoisoned) {
+			return fmt.Errorf("Close on a poisoned session = %v, want ErrPoisoned", err)
+		}
+		logf("gave up after %v", time.Since(start))
+		return nil
+	})
+}
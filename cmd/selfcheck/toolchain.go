@@ -0,0 +1,65 @@
+//go:build !nocgo || windows
+
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"strings"
+
+	"github.com/lxwagn/using-go-with-c-libraries/pkg/mylib"
+)
+
+// wantCompiler is set by the windows-matrix target of the Makefile, so
+// that a DLL left over from another toolchain's build fails the run
+// instead of passing for the one meant.
+var wantCompiler = flag.String("compiler", "", "require the library to be built by a compiler whose name starts with `prefix`")
+
+func init() {
+	register("toolchain/compiler", func() error {
+		name, err := mylib.Compiler()
+		if errors.Is(err, errors.ErrUnsupported) && *wantCompiler == "" {
+			logf("%v; skipped", err)
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		logf("library built by %s", name)
+		if !strings.HasPrefix(name, *wantCompiler) {
+			return fmt.Errorf("library built by %s, want %s", name, *wantCompiler)
+		}
+		return nil
+	})
+
+	// Structs passed and returned by value and arrays of them are where
+	// compilers' ABIs part ways, if anywhere: a 16-byte myStruct comes
+	// back through a hidden pointer on Windows x64 and in registers
+	// elsewhere.
+	register("toolchain/structs", func() error {
+		s, err := mylib.MakeStruct(7, "seven")
+		if err != nil {
+			return err
+		}
+		if s != (mylib.MyStruct{A: 7, B: "seven"}) {
+			return fmt.Errorf("MakeStruct(7, %q) = %+v", "seven", s)
+		}
+		if s, err = mylib.ScaleStruct(s, -3); err != nil {
+			return err
+		}
+		if s != (mylib.MyStruct{A: -21, B: "seven"}) {
+			return fmt.Errorf("ScaleStruct by -3 = %+v, want A -21", s)
+		}
+
+		pts := []mylib.Point{{X: 1, Y: 2, Weight: 0.5}, {X: -4, Y: 8, Weight: 2}}
+		mylib.TranslatePoints(pts, 10, -1)
+		want := []mylib.Point{{X: 11, Y: 1, Weight: 0.5}, {X: 6, Y: 7, Weight: 2}}
+		for i := range pts {
+			if pts[i] != want[i] {
+				return fmt.Errorf("TranslatePoints: point %d = %+v, want %+v", i, pts[i], want[i])
+			}
+		}
+		return nil
+	})
+}
@@ -0,0 +1,88 @@
+//go:build tsan && !nocgo && !windows
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"runtime"
+	"strings"
+	"sync"
+
+	"github.com/lxwagn/using-go-with-c-libraries/internal/sanbugs"
+)
+
+// These checks need the C code built with ThreadSanitizer; go build -race
+// only instruments Go, and cannot see a race between two calls into C:
+//
+//	make tsan
+//
+// which builds libmylib with make tsan in src, and selfcheck with
+//
+//	CGO_CFLAGS=-fsanitize=thread CGO_LDFLAGS=-fsanitize=thread \
+//		go build -tags tsan ./cmd/selfcheck
+//
+// Given those flags, cgo tells ThreadSanitizer about each call into C,
+// so two calls overlapping on a C variable are reported, and calls the
+// wrapper serializes are not.
+
+const tsanReport = "WARNING: ThreadSanitizer: data race"
+
+func init() {
+	registerChild("tsan-racy-c", func() {
+		runtime.GOMAXPROCS(4)
+		var wg sync.WaitGroup
+		for range 4 {
+			wg.Go(func() {
+				for range 20 {
+					sanbugs.RacyIncrement()
+				}
+			})
+		}
+		wg.Wait()
+	})
+
+	register("tsan/racy-c", func() error {
+		// ThreadSanitizer reports without stopping the process, so the
+		// child may well exit normally.
+		out, _ := runChild("tsan-racy-c")
+		if !strings.Contains(out, tsanReport) {
+			return fmt.Errorf("race went undetected:\n%s", out)
+		}
+		if !strings.Contains(out, "sanRacyIncrement") {
+			return fmt.Errorf("no sanRacyIncrement frame in the report:\n%s", out)
+		}
+		logf("%s in sanRacyIncrement", tsanReport)
+		return nil
+	})
+
+	// The concurrent checks again, in a process of their own so that a
+	// report is tied to them rather than lost among the other checks.
+	registerChild("tsan-wrapper", func() {
+		runtime.GOMAXPROCS(4)
+		for _, c := range checks {
+			if !strings.HasPrefix(c.name, "concurrent/") {
+				continue
+			}
+			if err := c.run(); err != nil {
+				fmt.Printf("%s: %v\n", c.name, err)
+				os.Exit(1)
+			}
+		}
+	})
+
+	register("tsan/wrapper", func() error {
+		out, err := runChild("tsan-wrapper")
+		if err == nil {
+			return fmt.Errorf("child failed:\n%s", out)
+		}
+		if !errors.Is(err, errSurvived) {
+			return err
+		}
+		if strings.Contains(out, "ThreadSanitizer") {
+			return fmt.Errorf("report for the wrapper's calls:\n%s", out)
+		}
+		return nil
+	})
+}
@@ -0,0 +1,5 @@
+//go:build tsan && !nocgo && !windows
+
+package main
+
+const tsanEnabled = true
@@ -0,0 +1,109 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"slices"
+	"unicode/utf8"
+
+	"github.com/lxwagn/using-go-with-c-libraries/pkg/mylib"
+	"github.com/lxwagn/using-go-with-c-libraries/pkg/wchar"
+)
+
+// wideSamples cover ASCII, the Basic Multilingual Plane and characters
+// beyond it, which are surrogate pairs where wchar_t is 2 bytes wide.
+var wideSamples = []string{"", "héllo", "日本語", "a😀b𝄞c", "😀😀"}
+
+func init() {
+	register("wide/count-reverse", func() error {
+		for _, s := range wideSamples {
+			n, err := mylib.WideCount(s)
+			if err != nil {
+				return err
+			}
+			if want := utf8.RuneCountInString(s); n != want {
+				return fmt.Errorf("WideCount(%q) = %d, want %d", s, n, want)
+			}
+
+			r, err := mylib.WideReverse(s)
+			if err != nil {
+				return err
+			}
+			runes := []rune(s)
+			slices.Reverse(runes)
+			if want := string(runes); r != want {
+				return fmt.Errorf("WideReverse(%q) = %q, want %q", s, r, want)
+			}
+		}
+		return nil
+	})
+
+	// A fixed-seed stand-in for a fuzz target: random strings, including
+	// invalid UTF-8, must survive the trip
+	// through wchar_t with only invalid bytes replaced.
+	register("wide/round-trip", func() error {
+		rng := rand.New(rand.NewPCG(1, 2))
+		for range 5000 {
+			s := randomWide(rng)
+			w, err := wchar.Encode(s)
+			if err != nil {
+				return fmt.Errorf("Encode(%q): %v", s, err)
+			}
+			if want := string([]rune(s)); wchar.Decode(w) != want {
+				return fmt.Errorf("Decode(Encode(%q)) = %q, want %q", s, wchar.Decode(w), want)
+			}
+
+			r, err := mylib.WideReverse(s)
+			if err != nil {
+				return err
+			}
+			back, err := mylib.WideReverse(r)
+			if err != nil {
+				return err
+			}
+			if want := string([]rune(s)); back != want {
+				return fmt.Errorf("WideReverse twice(%q) = %q, want %q", s, back, want)
+			}
+		}
+		return nil
+	})
+
+	register("wide/invalid", func() error {
+		// Unpaired surrogates, and on Linux values past U+10FFFF, decode
+		// to U+FFFD rather than to bytes that are not UTF-8.
+		got := wchar.Decode([]wchar.Char{0xD800, 'a', 0xDC00, 0})
+		if want := "\uFFFDa\uFFFD"; got != want {
+			return fmt.Errorf("Decode of lone surrogates = %q, want %q", got, want)
+		}
+
+		if _, err := wchar.Encode("a\x00b"); !errors.Is(err, wchar.ErrNUL) {
+			return fmt.Errorf("Encode with NUL: err = %v, want %v", err, wchar.ErrNUL)
+		}
+		if _, err := mylib.WideCount("a\x00b"); !errors.Is(err, mylib.ErrNUL) {
+			return fmt.Errorf("WideCount with NUL: err = %v, want %v", err, mylib.ErrNUL)
+		}
+		return nil
+	})
+}
+
+// randomWide returns a short string mixing ASCII, BMP and supplementary
+// characters with the occasional invalid byte.
+func randomWide(rng *rand.Rand) string {
+	b := make([]byte, 0, 32)
+	for range rng.IntN(16) {
+		switch rng.IntN(5) {
+		case 0:
+			b = append(b, byte('a'+rng.IntN(26)))
+		case 1:
+			b = utf8.AppendRune(b, rune(0x80+rng.IntN(0xD800-0x80)))
+		case 2:
+			b = utf8.AppendRune(b, rune(0xE000+rng.IntN(0x10000-0xE000)))
+		case 3:
+			b = utf8.AppendRune(b, rune(0x10000+rng.IntN(0x110000-0x10000)))
+		case 4:
+			b = append(b, byte(0x80+rng.IntN(0x80)))
+		}
+	}
+	return string(b)
+}
@@ -0,0 +1,73 @@
+//go:build !nocgo && !windows
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"strings"
+
+	"github.com/lxwagn/using-go-with-c-libraries/pkg/mylib"
+)
+
+func init() {
+	// A string read in place has its length passed with it, so NUL bytes
+	// and the empty string hash like any other bytes; each way of
+	// passing must give hash/fnv's result.
+	register("zerocopy/hash", func() error {
+		for _, s := range []string{"", "a", "hello, world", "a\x00b", "\x00", strings.Repeat("xyz", 1<<15)} {
+			h := fnv.New32a()
+			h.Write([]byte(s))
+			want := h.Sum32()
+
+			got, err := mylib.Hash(s)
+			if err != nil {
+				return err
+			}
+			if got != want {
+				return fmt.Errorf("Hash(%.20q) = %#x, want %#x", s, got, want)
+			}
+			if got, err = mylib.HashBytes([]byte(s)); err != nil {
+				return err
+			} else if got != want {
+				return fmt.Errorf("HashBytes(%.20q) = %#x, want %#x", s, got, want)
+			}
+
+			got, err = mylib.HashCString(s)
+			if strings.IndexByte(s, 0) >= 0 {
+				if !errors.Is(err, mylib.ErrNUL) {
+					return fmt.Errorf("HashCString(%.20q): %v, want ErrNUL", s, err)
+				}
+				continue
+			}
+			if err != nil {
+				return err
+			}
+			if got != want {
+				return fmt.Errorf("HashCString(%.20q) = %#x, want %#x", s, got, want)
+			}
+		}
+		return nil
+	})
+
+	// A subslice is passed from where it starts, not from the start of
+	// its array.
+	register("zerocopy/subslice", func() error {
+		b := []byte("0123456789")
+		for i := 0; i <= len(b); i++ {
+			want, err := mylib.Hash(string(b[i:]))
+			if err != nil {
+				return err
+			}
+			got, err := mylib.HashBytes(b[i:])
+			if err != nil {
+				return err
+			}
+			if got != want {
+				return fmt.Errorf("HashBytes(b[%d:]) = %#x, want %#x", i, got, want)
+			}
+		}
+		return nil
+	})
+}
@@ -0,0 +1,172 @@
+//go:build !nocgo && !windows
+
+package main
+
+import (
+	"bytes"
+	"compress/zlib"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand/v2"
+
+	"github.com/lxwagn/using-go-with-c-libraries/examples/zlibc"
+)
+
+func init() {
+	register("zlibc/round-trip", func() error {
+		logf("zlib %s", zlibc.Version())
+		for _, size := range []int{0, 1, 1000, zlibc.BufferSize + 1, 1 << 20} {
+			data := zlibData(size)
+			for _, level := range []int{zlibc.NoCompression, zlibc.BestSpeed, zlibc.DefaultCompression, zlibc.BestCompression} {
+				var buf bytes.Buffer
+				w, err := zlibc.NewWriterLevel(&buf, level)
+				if err != nil {
+					return err
+				}
+				if err := zlibWrite(w, data); err != nil {
+					return err
+				}
+
+				// Both readers, the Go one checking the C stream is
+				// standard zlib.
+				got, err := zlibReadC(bytes.NewReader(buf.Bytes()), 4096)
+				if err != nil {
+					return fmt.Errorf("size %d level %d: %v", size, level, err)
+				}
+				if !bytes.Equal(got, data) {
+					return fmt.Errorf("size %d level %d: C round trip differs", size, level)
+				}
+				zr, err := zlib.NewReader(&buf)
+				if err != nil {
+					return err
+				}
+				if got, err = io.ReadAll(zr); err != nil || !bytes.Equal(got, data) {
+					return fmt.Errorf("size %d level %d: compress/zlib read %d bytes, %v", size, level, len(got), err)
+				}
+			}
+		}
+		return nil
+	})
+
+	register("zlibc/from-go", func() error {
+		data := zlibData(300_000)
+		var buf bytes.Buffer
+		zw := zlib.NewWriter(&buf)
+		zw.Write(data)
+		zw.Close()
+		// One byte at a time, to exercise the pending output.
+		got, err := zlibReadC(&buf, 1)
+		if err != nil {
+			return err
+		}
+		if !bytes.Equal(got, data) {
+			return errors.New("decompressed data differs")
+		}
+		return nil
+	})
+
+	register("zlibc/flush-and-reset", func() error {
+		var buf bytes.Buffer
+		w := zlibc.NewWriter(&buf)
+		defer w.Close()
+		w.Write([]byte("first part"))
+		if err := w.Flush(); err != nil {
+			return err
+		}
+		// Everything written so far can be read, and then the stream
+		// ends too soon.
+		r, err := zlibc.NewReader(bytes.NewReader(buf.Bytes()))
+		if err != nil {
+			return err
+		}
+		defer r.Close()
+		got, err := io.ReadAll(r)
+		if string(got) != "first part" || !errors.Is(err, io.ErrUnexpectedEOF) {
+			return fmt.Errorf("read %q, %v; want the first part and ErrUnexpectedEOF", got, err)
+		}
+
+		var second bytes.Buffer
+		if err := w.Reset(&second); err != nil {
+			return err
+		}
+		w.Write([]byte("second stream"))
+		if err := w.Close(); err != nil {
+			return err
+		}
+		if err := r.Reset(&second); err != nil {
+			return err
+		}
+		if got, err := io.ReadAll(r); string(got) != "second stream" || err != nil {
+			return fmt.Errorf("after Reset read %q, %v", got, err)
+		}
+		return nil
+	})
+
+	register("zlibc/corrupt", func() error {
+		var buf bytes.Buffer
+		w := zlibc.NewWriter(&buf)
+		if err := zlibWrite(w, zlibData(10_000)); err != nil {
+			return err
+		}
+		stream := buf.Bytes()
+
+		bad := bytes.Clone(stream)
+		bad[len(bad)-1] ^= 0xff // the Adler-32 checksum
+		if _, err := zlibReadC(bytes.NewReader(bad), 4096); !errors.Is(err, zlibc.ErrCorrupt) {
+			return fmt.Errorf("bad checksum: %v, want ErrCorrupt", err)
+		}
+		if _, err := zlibReadC(bytes.NewReader([]byte("not zlib at all")), 4096); !errors.Is(err, zlibc.ErrCorrupt) {
+			return fmt.Errorf("bad header: %v, want ErrCorrupt", err)
+		}
+		if _, err := zlibReadC(bytes.NewReader(stream[:len(stream)/2]), 4096); !errors.Is(err, io.ErrUnexpectedEOF) {
+			return fmt.Errorf("truncated: %v, want ErrUnexpectedEOF", err)
+		}
+		if _, err := zlibc.NewWriterLevel(io.Discard, 10); err == nil {
+			return errors.New("level 10 accepted")
+		}
+		return nil
+	})
+}
+
+// zlibData returns n bytes of text-like data that compresses, but not
+// trivially.
+func zlibData(n int) []byte {
+	words := []string{"cgo ", "zlib ", "stream ", "buffer ", "deflate ", "inflate ", "\n"}
+	r := rand.New(rand.NewPCG(uint64(n), 7))
+	var b bytes.Buffer
+	for b.Len() < n {
+		b.WriteString(words[r.IntN(len(words))])
+	}
+	return b.Bytes()[:n]
+}
+
+func zlibWrite(w *zlibc.Writer, data []byte) error {
+	if _, err := w.Write(data); err != nil {
+		w.Close()
+		return err
+	}
+	return w.Close()
+}
+
+// zlibReadC decompresses everything from r with zlibc, reading chunk
+// bytes at a time.
+func zlibReadC(r io.Reader, chunk int) ([]byte, error) {
+	zr, err := zlibc.NewReader(r)
+	if err != nil {
+		return nil, err
+	}
+	defer zr.Close()
+	var out bytes.Buffer
+	p := make([]byte, chunk)
+	for {
+		n, err := zr.Read(p)
+		out.Write(p[:n])
+		if err == io.EOF {
+			return out.Bytes(), nil
+		}
+		if err != nil {
+			return out.Bytes(), err
+		}
+	}
+}
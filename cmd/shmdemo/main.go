@@ -0,0 +1,96 @@
+//go:build linux
+
+// Command shmdemo passes messages from a C process to a Go one through
+// shared memory. It creates a ring with package shm, starts the C producer
+// in producer/, which pushes into the ring with libmylib, and prints what
+// arrives until the producer says it is done:
+//
+//	make shmdemo
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"os/exec"
+	"strconv"
+	"time"
+
+	"github.com/lxwagn/using-go-with-c-libraries/pkg/shm"
+)
+
+func main() {
+	var (
+		producer = flag.String("producer", "bin/shmdemo-producer", "path to the C producer")
+		count    = flag.Int("n", 1000, "number of messages to send")
+		capacity = flag.Int("cap", 4096, "ring capacity in bytes, a power of two")
+		quiet    = flag.Bool("q", false, "print only a summary")
+	)
+	flag.Parse()
+	log.SetFlags(0)
+	log.SetPrefix("shmdemo: ")
+
+	name := fmt.Sprintf("/shmdemo-%d", os.Getpid())
+	ring, err := shm.CreateRing(name, *capacity)
+	if err != nil {
+		log.Fatal(err)
+	}
+	// The producer opens the object by name, so it can be unlinked as soon
+	// as this program exits, however it exits.
+	defer shm.Unlink(name)
+	defer ring.Close()
+
+	cmd := exec.Command(*producer, name, strconv.Itoa(*count))
+	cmd.Stdout, cmd.Stderr = os.Stdout, os.Stderr
+	if err := cmd.Start(); err != nil {
+		log.Print(err)
+		return
+	}
+	exited := make(chan error, 1)
+	go func() { exited <- cmd.Wait() }()
+
+	start := time.Now()
+	var (
+		got, bytes int
+		waitErr    error
+		gone       bool
+	)
+	buf := make([]byte, 256)
+	for {
+		n, err := ring.Pop(buf)
+		switch {
+		case errors.Is(err, shm.ErrEmpty) && gone:
+			// Still empty after the producer exited: nothing more will come.
+			log.Printf("producer exited before it was done: %v", waitErr)
+			return
+		case errors.Is(err, shm.ErrEmpty):
+			select {
+			case waitErr = <-exited:
+				gone = true
+			case <-time.After(50 * time.Microsecond):
+			}
+			continue
+		case err != nil:
+			log.Print(err)
+			return
+		}
+		msg := buf[:n]
+		if string(msg) == "done" {
+			break
+		}
+		got++
+		bytes += n
+		if !*quiet {
+			fmt.Printf("%s\n", msg)
+		}
+	}
+	if !gone {
+		waitErr = <-exited
+	}
+	if waitErr != nil {
+		log.Printf("producer: %v", waitErr)
+	}
+	fmt.Printf("received %d messages, %d bytes, in %v\n", got, bytes, time.Since(start).Round(time.Microsecond))
+}
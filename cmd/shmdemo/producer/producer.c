@@ -0,0 +1,51 @@
+/*
+ * The producer half of cmd/shmdemo: a C program that opens the ring the Go
+ * consumer created and pushes messages into it with libmylib's myRing
+ * functions. Build it with the shmdemo target of the top-level Makefile.
+ */
+#include <stdio.h>
+#include <stdlib.h>
+#include <string.h>
+#include <unistd.h>
+
+#include "mylib.h"
+
+static int push(myRing *r, const char *msg) {
+	int rc;
+
+	/* Wait for the consumer to make room. */
+	while ((rc = myRingPush(r, (const unsigned char *)msg, strlen(msg))) == MYLIB_ERANGE)
+		usleep(100);
+	return rc;
+}
+
+int main(int argc, char **argv) {
+	char msg[64];
+	myRing *r;
+	int i, count = 1000;
+
+	if (argc < 2) {
+		fprintf(stderr, "usage: %s name [count]\n", argv[0]);
+		return 2;
+	}
+	if (argc > 2)
+		count = atoi(argv[2]);
+
+	r = myRingOpen(argv[1], 0);
+	if (r == NULL) {
+		perror(argv[1]);
+		return 1;
+	}
+	for (i = 0; i < count; i++) {
+		snprintf(msg, sizeof(msg), "message %d from C", i);
+		if (push(r, msg) != MYLIB_OK)
+			break;
+	}
+	if (i < count || push(r, "done") != MYLIB_OK) {
+		fprintf(stderr, "%s: push failed\n", argv[1]);
+		myRingClose(r);
+		return 1;
+	}
+	myRingClose(r);
+	return 0;
+}
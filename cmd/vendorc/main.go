@@ -0,0 +1,77 @@
+// Command vendorc copies C source files into a Go package, so that cgo can
+// compile them as part of the package. Each copy starts with a comment
+// saying where it came from, and is otherwise the file unchanged. It is
+// meant to be run through go:generate:
+//
+//	//go:generate go run ../../cmd/vendorc -o csrc ../../src/mylib.c ../../src/mylib.h ../../src/mylib_layout.h
+//
+// With -check it writes nothing and exits with status 1 if any copy is
+// missing or differs from what it would write.
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+func main() {
+	var (
+		out   = flag.String("o", ".", "directory to copy the files into")
+		check = flag.Bool("check", false, "only report copies that are out of date")
+	)
+	flag.Usage = func() {
+		fmt.Fprintln(os.Stderr, "usage: vendorc [-check] [-o dir] file...")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	if flag.NArg() == 0 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	stale := false
+	for _, src := range flag.Args() {
+		dst := filepath.Join(*out, filepath.Base(src))
+		want, err := vendored(src)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "vendorc:", err)
+			os.Exit(1)
+		}
+		if *check {
+			if got, err := os.ReadFile(dst); err != nil || !bytes.Equal(got, want) {
+				fmt.Fprintf(os.Stderr, "vendorc: %s is out of date with %s\n", dst, src)
+				stale = true
+			}
+			continue
+		}
+		if err := os.MkdirAll(*out, 0o755); err != nil {
+			fmt.Fprintln(os.Stderr, "vendorc:", err)
+			os.Exit(1)
+		}
+		if err := os.WriteFile(dst, want, 0o644); err != nil {
+			fmt.Fprintln(os.Stderr, "vendorc:", err)
+			os.Exit(1)
+		}
+	}
+	if stale {
+		fmt.Fprintln(os.Stderr, "vendorc: run go generate to update")
+		os.Exit(1)
+	}
+}
+
+// vendored returns the contents of the copy of src.
+func vendored(src string) ([]byte, error) {
+	data, err := os.ReadFile(src)
+	if err != nil {
+		return nil, err
+	}
+	// The path is written with slashes so that the copy is the same
+	// whichever system generated it.
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "/* Code generated by vendorc from %s; DO NOT EDIT. */\n\n", filepath.ToSlash(src))
+	b.Write(data)
+	return b.Bytes(), nil
+}
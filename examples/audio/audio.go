@@ -0,0 +1,244 @@
+package audio
+
+/*
+
+#cgo CFLAGS: -O2
+#cgo LDFLAGS: -lm -pthread
+#include <stdint.h>
+#include <string.h>
+#include "device.h"
+
+// A ring is a single-producer, single-consumer queue of samples. head
+// and tail are free-running sample counts, written only by the producer
+// and the consumer respectively; data is Go memory, pinned for as long
+// as the ring exists.
+typedef struct {
+	uint64_t head, tail, size;
+	float *data;
+} ring;
+
+// The rings and counters of a stream, in C memory so that the device
+// thread can use them without touching the Go runtime. The Go side
+// reads the counters with sync/atomic.
+typedef struct {
+	ring in, out;
+	uint64_t callbacks, underruns, overruns;
+	int channels;
+} stream;
+
+// ringCopy moves n samples between a ring's data, starting at index
+// pos, and buf, in the direction given by toRing.
+static void ringCopy(ring *r, uint64_t pos, float *buf, uint64_t n, int toRing) {
+	uint64_t i = pos % r->size;
+	uint64_t first = n < r->size - i ? n : r->size - i;
+	if (toRing) {
+		memcpy(r->data + i, buf, first * sizeof(float));
+		memcpy(r->data, buf + first, (n - first) * sizeof(float));
+	} else {
+		memcpy(buf, r->data + i, first * sizeof(float));
+		memcpy(buf + first, r->data, (n - first) * sizeof(float));
+	}
+}
+
+// streamCallback is the whole real-time path: it copies the input into
+// one ring and the output out of the other, and counts what did not
+// fit. It uses nothing but the stream's memory and a few atomic
+// operations, and never calls Go.
+static int streamCallback(const float *in, float *out, unsigned long frames, void *user) {
+	stream *s = user;
+	uint64_t n = frames * s->channels;
+
+	ring *r = &s->in;
+	uint64_t head = r->head;
+	uint64_t room = r->size - (head - __atomic_load_n(&r->tail, __ATOMIC_ACQUIRE));
+	uint64_t m = n < room ? n : room;
+	ringCopy(r, head, (float *)in, m, 1);
+	__atomic_store_n(&r->head, head + m, __ATOMIC_RELEASE);
+	if (m < n)
+		__atomic_fetch_add(&s->overruns, 1, __ATOMIC_RELAXED);
+
+	r = &s->out;
+	uint64_t tail = r->tail;
+	uint64_t avail = __atomic_load_n(&r->head, __ATOMIC_ACQUIRE) - tail;
+	m = n < avail ? n : avail;
+	ringCopy(r, tail, out, m, 0);
+	__atomic_store_n(&r->tail, tail + m, __ATOMIC_RELEASE);
+	if (m < n) {
+		memset(out + m, 0, (n - m) * sizeof(float));
+		__atomic_fetch_add(&s->underruns, 1, __ATOMIC_RELAXED);
+	}
+
+	__atomic_fetch_add(&s->callbacks, 1, __ATOMIC_RELAXED);
+	return 0;
+}
+
+static audioDevice *openStream(stream *s, int rate, unsigned long frames, int loopback) {
+	return audioOpen(rate, s->channels, frames, loopback, streamCallback, s);
+}
+
+*/
+import "C"
+
+import (
+	"errors"
+	"fmt"
+	"runtime"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"time"
+	"unsafe"
+
+	"github.com/lxwagn/using-go-with-c-libraries/pkg/cmem"
+)
+
+// ringState in ring.go is the Go view of C's ring; these fail to compile
+// if the two layouts differ.
+var (
+	_ = [1]struct{}{}[unsafe.Sizeof(C.ring{})-unsafe.Sizeof(ringState{})]
+	_ = [1]struct{}{}[unsafe.Offsetof(C.ring{}.tail)-unsafe.Offsetof(ringState{}.tail)]
+	_ = [1]struct{}{}[unsafe.Offsetof(C.ring{}.data)-unsafe.Offsetof(ringState{}.data)]
+)
+
+// A Config describes a stream.
+type Config struct {
+	SampleRate      int  // frames per second
+	Channels        int  // samples per frame
+	FramesPerBuffer int  // frames per callback, the device's period
+	BufferFrames    int  // frames each ring holds; zero means 16 periods
+	Loopback        bool // the device's input is its own output
+}
+
+// Stats counts what happened on the device thread.
+type Stats struct {
+	Callbacks uint64 // periods the callback has run for
+	Underruns uint64 // periods the output ring ran dry, played partly silent
+	Overruns  uint64 // periods the input ring was full, and input was lost
+	Realtime  bool   // the device thread has real-time scheduling
+}
+
+var (
+	// ErrClosed is returned for calls on a closed Stream.
+	ErrClosed = errors.New("audio: stream closed")
+	// ErrStopped is returned by Read and Write when they would wait
+	// for a stream that is not running.
+	ErrStopped = errors.New("audio: stream not running")
+)
+
+// A Stream is an open device together with an input and an output ring.
+// Read and Write may be called from different goroutines at once, but
+// each from only one at a time.
+type Stream struct {
+	mu      sync.Mutex // Start, Stop and Close
+	dev     *C.audioDevice
+	s       *C.stream
+	pinner  runtime.Pinner
+	in, out []float32
+	period  time.Duration
+	running atomic.Bool
+	closed  atomic.Bool
+}
+
+// Open opens a stopped stream. All the memory the device thread will
+// touch is allocated here: the C stream and the two rings, whose storage
+// is Go memory pinned for the life of the stream.
+func Open(cfg Config) (*Stream, error) {
+	if cfg.SampleRate <= 0 || cfg.Channels <= 0 || cfg.FramesPerBuffer <= 0 || cfg.BufferFrames < 0 {
+		return nil, fmt.Errorf("audio: invalid config %+v", cfg)
+	}
+	if cfg.BufferFrames == 0 {
+		cfg.BufferFrames = 16 * cfg.FramesPerBuffer
+	}
+
+	st := &Stream{
+		s:      (*C.stream)(cmem.Calloc(1, C.sizeof_stream)),
+		in:     make([]float32, cfg.BufferFrames*cfg.Channels),
+		out:    make([]float32, cfg.BufferFrames*cfg.Channels),
+		period: time.Duration(cfg.FramesPerBuffer) * time.Second / time.Duration(cfg.SampleRate),
+	}
+	st.s.channels = C.int(cfg.Channels)
+	// C memory may hold pointers to Go memory only while it is pinned.
+	st.pinner.Pin(&st.in[0])
+	st.pinner.Pin(&st.out[0])
+	st.s.in = C.ring{size: C.uint64_t(len(st.in)), data: (*C.float)(&st.in[0])}
+	st.s.out = C.ring{size: C.uint64_t(len(st.out)), data: (*C.float)(&st.out[0])}
+
+	loopback := 0
+	if cfg.Loopback {
+		loopback = 1
+	}
+	dev, err := C.openStream(st.s, C.int(cfg.SampleRate), C.ulong(cfg.FramesPerBuffer), C.int(loopback))
+	if dev == nil {
+		st.free()
+		return nil, fmt.Errorf("audio: audioOpen: %w", err)
+	}
+	st.dev = dev
+	return st, nil
+}
+
+func (st *Stream) free() {
+	st.pinner.Unpin()
+	cmem.Free(unsafe.Pointer(st.s))
+	st.s = nil
+}
+
+// Start starts the device thread.
+func (st *Stream) Start() error {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	if st.dev == nil {
+		return ErrClosed
+	}
+	if rc := C.audioStart(st.dev); rc != 0 {
+		return fmt.Errorf("audio: audioStart: %w", syscall.Errno(rc))
+	}
+	st.running.Store(true)
+	return nil
+}
+
+// Stop stops the device thread after its current period. What is left
+// in the rings stays there.
+func (st *Stream) Stop() error {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	if st.dev == nil {
+		return ErrClosed
+	}
+	st.running.Store(false)
+	if rc := C.audioStop(st.dev); rc != 0 {
+		return fmt.Errorf("audio: audioStop: %w", syscall.Errno(rc))
+	}
+	return nil
+}
+
+// Close stops the stream and frees it. Read and Write must have
+// returned.
+func (st *Stream) Close() error {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	if st.dev == nil {
+		return ErrClosed
+	}
+	st.running.Store(false)
+	st.closed.Store(true)
+	// The device thread is joined before the rings go away.
+	C.audioClose(st.dev)
+	st.dev = nil
+	st.free()
+	return nil
+}
+
+// Stats returns the stream's counters.
+func (st *Stream) Stats() Stats {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	if st.dev == nil {
+		return Stats{}
+	}
+	return Stats{
+		Callbacks: atomic.LoadUint64((*uint64)(unsafe.Pointer(&st.s.callbacks))),
+		Underruns: atomic.LoadUint64((*uint64)(unsafe.Pointer(&st.s.underruns))),
+		Overruns:  atomic.LoadUint64((*uint64)(unsafe.Pointer(&st.s.overruns))),
+		Realtime:  C.audioRealtime(st.dev) != 0,
+	}
+}
@@ -0,0 +1,154 @@
+#include "device.h"
+
+#include <errno.h>
+#include <math.h>
+#include <pthread.h>
+#include <sched.h>
+#include <stdlib.h>
+#include <string.h>
+#include <time.h>
+
+struct audioDevice {
+	int rate, channels, loopback;
+	unsigned long frames;
+	audioCallback cb;
+	void *user;
+
+	float *in, *out;
+	double phase;
+
+	pthread_t thread;
+	int running, realtime;
+	int stop; // accessed atomically
+};
+
+audioDevice *audioOpen(int rate, int channels, unsigned long frames, int loopback,
+		       audioCallback cb, void *user) {
+	if (rate <= 0 || channels <= 0 || frames == 0 || cb == NULL) {
+		errno = EINVAL;
+		return NULL;
+	}
+	audioDevice *d = calloc(1, sizeof *d);
+	if (d == NULL)
+		return NULL;
+	d->in = calloc(frames * channels, sizeof(float));
+	d->out = calloc(frames * channels, sizeof(float));
+	if (d->in == NULL || d->out == NULL) {
+		audioClose(d);
+		errno = ENOMEM;
+		return NULL;
+	}
+	d->rate = rate;
+	d->channels = channels;
+	d->frames = frames;
+	d->loopback = loopback;
+	d->cb = cb;
+	d->user = user;
+	return d;
+}
+
+static void fillInput(audioDevice *d) {
+	unsigned long n = d->frames * d->channels;
+	if (d->loopback) {
+		memcpy(d->in, d->out, n * sizeof(float));
+		return;
+	}
+	for (unsigned long i = 0; i < d->frames; i++) {
+		float v = (float)(0.5 * sin(d->phase));
+		for (int c = 0; c < d->channels; c++)
+			d->in[i * d->channels + c] = v;
+		d->phase += 2 * M_PI * 440 / d->rate;
+	}
+	d->phase = fmod(d->phase, 2 * M_PI);
+}
+
+static void addNanos(struct timespec *t, long ns) {
+	t->tv_nsec += ns;
+	while (t->tv_nsec >= 1000000000L) {
+		t->tv_nsec -= 1000000000L;
+		t->tv_sec++;
+	}
+}
+
+// sleepUntil sleeps until the monotonic clock reaches t.
+static void sleepUntil(const struct timespec *t) {
+#ifdef __linux__
+	while (clock_nanosleep(CLOCK_MONOTONIC, TIMER_ABSTIME, t, NULL) == EINTR)
+		;
+#else
+	struct timespec now, left;
+	clock_gettime(CLOCK_MONOTONIC, &now);
+	left.tv_sec = t->tv_sec - now.tv_sec;
+	left.tv_nsec = t->tv_nsec - now.tv_nsec;
+	if (left.tv_nsec < 0) {
+		left.tv_nsec += 1000000000L;
+		left.tv_sec--;
+	}
+	if (left.tv_sec >= 0)
+		nanosleep(&left, NULL);
+#endif
+}
+
+static void *run(void *arg) {
+	audioDevice *d = arg;
+	long period = (long)(d->frames * 1000000000.0 / d->rate);
+	struct timespec next;
+
+	clock_gettime(CLOCK_MONOTONIC, &next);
+	while (!__atomic_load_n(&d->stop, __ATOMIC_ACQUIRE)) {
+		fillInput(d);
+		if (d->cb(d->in, d->out, d->frames, d->user) != 0)
+			break;
+		// Like hardware, the device does not wait for a late
+		// callback: the next period starts on time regardless.
+		addNanos(&next, period);
+		sleepUntil(&next);
+	}
+	return NULL;
+}
+
+int audioStart(audioDevice *d) {
+	if (d->running)
+		return EBUSY;
+	__atomic_store_n(&d->stop, 0, __ATOMIC_RELEASE);
+
+	// Ask for SCHED_FIFO, as audio servers do, and settle for normal
+	// scheduling without the privilege for it.
+	pthread_attr_t attr;
+	struct sched_param param = {.sched_priority = sched_get_priority_min(SCHED_FIFO)};
+	pthread_attr_init(&attr);
+	pthread_attr_setinheritsched(&attr, PTHREAD_EXPLICIT_SCHED);
+	pthread_attr_setschedpolicy(&attr, SCHED_FIFO);
+	pthread_attr_setschedparam(&attr, &param);
+	int rc = pthread_create(&d->thread, &attr, run, d);
+	pthread_attr_destroy(&attr);
+	d->realtime = rc == 0;
+	if (rc == EPERM)
+		rc = pthread_create(&d->thread, NULL, run, d);
+	if (rc != 0)
+		return rc;
+	d->running = 1;
+	return 0;
+}
+
+int audioStop(audioDevice *d) {
+	if (!d->running)
+		return 0;
+	__atomic_store_n(&d->stop, 1, __ATOMIC_RELEASE);
+	int rc = pthread_join(d->thread, NULL);
+	d->running = 0;
+	return rc;
+}
+
+void audioClose(audioDevice *d) {
+	if (d == NULL)
+		return;
+	audioStop(d);
+	free(d->in);
+	free(d->out);
+	free(d);
+}
+
+int audioRealtime(audioDevice *d) {
+	return d->running && d->realtime;
+}
@@ -0,0 +1,37 @@
+// A simulated audio device with the shape of PortAudio's callback API:
+// once started, a thread of the device's own calls the callback every
+// period with a buffer of input frames to consume and a buffer of output
+// frames to fill, and the period ends on the device's clock whether or
+// not the callback was done in time. The frames are interleaved 32-bit
+// float samples.
+//
+// There is no hardware behind it: the input is a 440 Hz tone, or with
+// loopback the output played one period before, and the output goes
+// nowhere.
+#ifndef AUDIO_DEVICE_H
+#define AUDIO_DEVICE_H
+
+// The callback returns 0 to go on or anything else to stop the device
+// after this period. It runs on the device thread, which asks for
+// real-time scheduling, and must return well within the period: it must
+// not block, take locks, allocate or make system calls.
+typedef int (*audioCallback)(const float *in, float *out, unsigned long frames, void *user);
+
+typedef struct audioDevice audioDevice;
+
+// audioOpen returns a stopped device, or NULL with errno set.
+audioDevice *audioOpen(int rate, int channels, unsigned long frames, int loopback,
+		       audioCallback cb, void *user);
+
+// audioStart starts the device thread and audioStop stops and joins it;
+// both return 0 or an errno value. audioClose stops the device if it is
+// running and frees it.
+int audioStart(audioDevice *d);
+int audioStop(audioDevice *d);
+void audioClose(audioDevice *d);
+
+// audioRealtime reports whether the running device thread got real-time
+// scheduling, which usually takes privileges.
+int audioRealtime(audioDevice *d);
+
+#endif
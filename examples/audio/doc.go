@@ -0,0 +1,30 @@
+// Package audio streams samples to and from an audio device whose C
+// callback runs on a real-time thread, in the style of PortAudio's
+// callback API. The device is simulated by device.c, so the example
+// runs anywhere, but the constraints are those of real audio hardware.
+//
+// The callback must finish within a period, a few milliseconds, every
+// time; if it is late the device plays silence and the listener hears a
+// click. So the callback here is C, does nothing but copy samples
+// between the device's buffers and two lock-free single-producer,
+// single-consumer rings, and Go code reads and writes the other ends of
+// the rings on ordinary goroutines:
+//
+//	st, err := audio.Open(audio.Config{SampleRate: 48000, Channels: 2, FramesPerBuffer: 256})
+//	...
+//	defer st.Close()
+//	st.Write(samples) // prefill the output ring
+//	st.Start()
+//	for {
+//		st.Write(next())
+//	}
+//
+// Why not a Go callback? Calling Go from a thread the Go runtime did not
+// create means taking an M for it; the call can wait for a garbage
+// collection's stop-the-world pause or be made to assist the collector,
+// and any allocation on the way can do both. None of that has a bounded
+// duration. The rings avoid it: all their memory is allocated by Open
+// and stays put, C memory for the counters and pinned Go slices for the
+// samples, and neither end allocates or locks. Stats reports the
+// underruns and overruns if the Go side falls behind.
+package audio
@@ -0,0 +1,103 @@
+package audio
+
+import (
+	"sync/atomic"
+	"time"
+	"unsafe"
+)
+
+// The Go ends of the rings. They touch only the ring counters, with
+// sync/atomic, and the pinned Go slices behind the data pointers, so
+// moving samples takes no cgo call at all; the device thread sees the
+// samples once the counter store is visible to its atomic load, exactly
+// as if both ends were C.
+
+// ringState is the layout of the C ring struct in audio.go, which checks
+// that the two agree.
+type ringState struct {
+	head, tail, size uint64
+	data             unsafe.Pointer
+}
+
+// Write appends interleaved samples to the output ring, waiting for the
+// device to make room while the stream is running. It returns early
+// with ErrStopped if the ring is full and the stream is not running.
+//
+// There is no way for the device thread to wake Write that would be
+// safe to use from a real-time callback, so Write polls, sleeping half a
+// period at a time, as PortAudio's blocking API does.
+func (st *Stream) Write(p []float32) (int, error) {
+	if st.closed.Load() {
+		return 0, ErrClosed
+	}
+	r := (*ringState)(unsafe.Pointer(&st.s.out))
+	written := 0
+	for len(p) > 0 {
+		head := r.head
+		room := r.size - (head - atomic.LoadUint64(&r.tail))
+		if room == 0 {
+			if err := st.wait(); err != nil {
+				return written, err
+			}
+			continue
+		}
+		n := min(uint64(len(p)), room)
+		copyRing(st.out, head, p[:n], true)
+		atomic.StoreUint64(&r.head, head+n)
+		p = p[n:]
+		written += int(n)
+	}
+	return written, nil
+}
+
+// Read fills p with interleaved samples from the input ring, waiting
+// for the device to capture them while the stream is running. It returns
+// early with ErrStopped if the ring is empty and the stream is not
+// running.
+func (st *Stream) Read(p []float32) (int, error) {
+	if st.closed.Load() {
+		return 0, ErrClosed
+	}
+	r := (*ringState)(unsafe.Pointer(&st.s.in))
+	read := 0
+	for len(p) > 0 {
+		tail := r.tail
+		avail := atomic.LoadUint64(&r.head) - tail
+		if avail == 0 {
+			if err := st.wait(); err != nil {
+				return read, err
+			}
+			continue
+		}
+		n := min(uint64(len(p)), avail)
+		copyRing(st.in, tail, p[:n], false)
+		atomic.StoreUint64(&r.tail, tail+n)
+		p = p[n:]
+		read += int(n)
+	}
+	return read, nil
+}
+
+func (st *Stream) wait() error {
+	if st.closed.Load() {
+		return ErrClosed
+	}
+	if !st.running.Load() {
+		return ErrStopped
+	}
+	time.Sleep(st.period / 2)
+	return nil
+}
+
+// copyRing copies between buf and ring starting at the free-running
+// index pos, in the direction given by toRing.
+func copyRing(ring []float32, pos uint64, buf []float32, toRing bool) {
+	i := int(pos % uint64(len(ring)))
+	if toRing {
+		n := copy(ring[i:], buf)
+		copy(ring, buf[n:])
+	} else {
+		n := copy(buf, ring[i:])
+		copy(buf[n:], ring)
+	}
+}
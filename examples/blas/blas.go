@@ -0,0 +1,163 @@
+//go:build blas
+
+package blas
+
+/*
+
+#cgo LDFLAGS: -lblas
+#include <stddef.h>
+
+// DGEMM as gfortran compiles it: C := alpha*op(A)*op(B) + beta*C.
+extern void dgemm_(const char *transa, const char *transb,
+		   const int *m, const int *n, const int *k,
+		   const double *alpha, const double *a, const int *lda,
+		   const double *b, const int *ldb,
+		   const double *beta, double *c, const int *ldc,
+		   size_t transa_len, size_t transb_len);
+
+*/
+import "C"
+
+import (
+	"errors"
+	"fmt"
+	"math"
+)
+
+// A Matrix is a dense matrix stored in row-major order: the element in
+// row i and column j is Data[i*Cols+j].
+type Matrix struct {
+	Rows, Cols int
+	Data       []float64
+}
+
+// NewMatrix returns a zero rows×cols matrix.
+func NewMatrix(rows, cols int) *Matrix {
+	return &Matrix{Rows: rows, Cols: cols, Data: make([]float64, rows*cols)}
+}
+
+// At returns the element in row i and column j.
+func (m *Matrix) At(i, j int) float64 {
+	return m.Data[i*m.Cols+j]
+}
+
+// Set sets the element in row i and column j.
+func (m *Matrix) Set(i, j int, v float64) {
+	m.Data[i*m.Cols+j] = v
+}
+
+// ColMajor returns m's elements in column-major order, the order Fortran
+// routines take.
+func ColMajor(m *Matrix) []float64 {
+	d := make([]float64, len(m.Data))
+	for i := range m.Rows {
+		for j := range m.Cols {
+			d[j*m.Rows+i] = m.Data[i*m.Cols+j]
+		}
+	}
+	return d
+}
+
+// FromColMajor returns the rows×cols matrix whose elements are data in
+// column-major order.
+func FromColMajor(rows, cols int, data []float64) *Matrix {
+	m := NewMatrix(rows, cols)
+	for i := range rows {
+		for j := range cols {
+			m.Data[i*cols+j] = data[j*rows+i]
+		}
+	}
+	return m
+}
+
+// ErrShape is returned for matrices whose dimensions do not fit
+// together.
+var ErrShape = errors.New("blas: matrix dimensions do not match")
+
+// Dgemm sets c to alpha*op(a)*op(b) + beta*c, where op(x) is x, or its
+// transpose if the corresponding trans argument is true. With beta 0,
+// c's elements are not read, so it need not be initialized.
+func Dgemm(transA, transB bool, alpha float64, a, b *Matrix, beta float64, c *Matrix) error {
+	for _, x := range []*Matrix{a, b, c} {
+		if err := check(x); err != nil {
+			return err
+		}
+	}
+	// op(a) is m×k and op(b) is k×n.
+	m, k := a.Rows, a.Cols
+	if transA {
+		m, k = k, m
+	}
+	kb, n := b.Rows, b.Cols
+	if transB {
+		kb, n = n, kb
+	}
+	if k != kb || c.Rows != m || c.Cols != n {
+		return fmt.Errorf("%w: %s×%s into %d×%d", ErrShape, shape(a, transA), shape(b, transB), c.Rows, c.Cols)
+	}
+	if m == 0 || n == 0 {
+		return nil
+	}
+
+	// In column-major terms each row-major matrix here is the
+	// transpose of itself, with a leading dimension of its column
+	// count, so Cᵀ = op(B)ᵀ·op(A)ᵀ is the n×m product of Fortran's
+	// op(B) and op(A). dgemm requires a leading dimension of at least
+	// 1 even for an empty matrix.
+	fm, fn, fk := C.int(n), C.int(m), C.int(k)
+	lda, ldb, ldc := C.int(max(a.Cols, 1)), C.int(max(b.Cols, 1)), C.int(c.Cols)
+	ta, tb := trans(transB), trans(transA)
+	calpha, cbeta := C.double(alpha), C.double(beta)
+
+	// The slices hold no Go pointers, so they are passed as they are;
+	// dgemm uses them only during the call. A k of 0 leaves A and B
+	// unread but dgemm still wants pointers to pass along.
+	var zero C.double
+	pa, pb := &zero, &zero
+	if k > 0 {
+		pa, pb = (*C.double)(&a.Data[0]), (*C.double)(&b.Data[0])
+	}
+	C.dgemm_(&ta, &tb, &fm, &fn, &fk, &calpha,
+		pb, &ldb, pa, &lda,
+		&cbeta, (*C.double)(&c.Data[0]), &ldc,
+		1, 1)
+	return nil
+}
+
+// Mul returns the product a·b.
+func Mul(a, b *Matrix) (*Matrix, error) {
+	if a == nil || b == nil {
+		return nil, errors.New("blas: nil matrix")
+	}
+	c := NewMatrix(a.Rows, b.Cols)
+	if err := Dgemm(false, false, 1, a, b, 0, c); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+func trans(t bool) C.char {
+	if t {
+		return 'T'
+	}
+	return 'N'
+}
+
+func check(m *Matrix) error {
+	switch {
+	case m == nil:
+		return errors.New("blas: nil matrix")
+	case m.Rows < 0 || m.Cols < 0 || m.Rows > math.MaxInt32 || m.Cols > math.MaxInt32:
+		return fmt.Errorf("%w: %d×%d", ErrShape, m.Rows, m.Cols)
+	case len(m.Data) != m.Rows*m.Cols:
+		return fmt.Errorf("%w: %d×%d matrix with %d elements", ErrShape, m.Rows, m.Cols, len(m.Data))
+	}
+	return nil
+}
+
+func shape(m *Matrix, t bool) string {
+	if t {
+		return fmt.Sprintf("%d×%dᵀ", m.Rows, m.Cols)
+	}
+	return fmt.Sprintf("%d×%d", m.Rows, m.Cols)
+}
@@ -0,0 +1,122 @@
+//go:build blas
+
+package blas_test
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"math/rand/v2"
+	"testing"
+
+	"github.com/lxwagn/using-go-with-c-libraries/examples/blas"
+)
+
+func TestDgemm(t *testing.T) {
+	rng := rand.New(rand.NewPCG(3, 4))
+	random := func(r, c int) *blas.Matrix {
+		m := blas.NewMatrix(r, c)
+		for i := range m.Data {
+			m.Data[i] = rng.Float64()*2 - 1
+		}
+		return m
+	}
+	for range 200 {
+		m, n, k := rng.IntN(20), rng.IntN(20), rng.IntN(20)
+		transA, transB := rng.IntN(2) == 1, rng.IntN(2) == 1
+		alpha, beta := rng.Float64()*4-2, float64(rng.IntN(3))-1
+		a, b := random(m, k), random(k, n)
+		if transA {
+			a = random(k, m)
+		}
+		if transB {
+			b = random(n, k)
+		}
+		c := random(m, n)
+		want := goGemm(transA, transB, alpha, a, b, beta, c)
+		if err := blas.Dgemm(transA, transB, alpha, a, b, beta, c); err != nil {
+			t.Fatal(err)
+		}
+		for i := range want.Data {
+			if d := math.Abs(c.Data[i] - want.Data[i]); d > 1e-12*(1+math.Abs(want.Data[i])) {
+				t.Fatalf("%d×%d×%d transA=%v transB=%v: element %d = %v, want %v", m, n, k, transA, transB, i, c.Data[i], want.Data[i])
+			}
+		}
+	}
+
+	// A non-square product, to catch a mixed-up layout that happens to
+	// work for square matrices.
+	a := &blas.Matrix{Rows: 2, Cols: 3, Data: []float64{1, 2, 3, 4, 5, 6}}
+	b := &blas.Matrix{Rows: 3, Cols: 1, Data: []float64{1, 0, -1}}
+	c, err := blas.Mul(a, b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.Rows != 2 || c.Cols != 1 || c.At(0, 0) != -2 || c.At(1, 0) != -2 {
+		t.Errorf("Mul = %+v", c)
+	}
+}
+
+func TestLayout(t *testing.T) {
+	m := &blas.Matrix{Rows: 2, Cols: 3, Data: []float64{1, 2, 3, 4, 5, 6}}
+	cm := blas.ColMajor(m)
+	if fmt.Sprint(cm) != "[1 4 2 5 3 6]" {
+		t.Errorf("ColMajor = %v", cm)
+	}
+	if back := blas.FromColMajor(2, 3, cm); fmt.Sprint(back.Data) != fmt.Sprint(m.Data) {
+		t.Errorf("FromColMajor = %v", back.Data)
+	}
+}
+
+// TestShapes checks the mismatches caught before dgemm, which would stop
+// the program.
+func TestShapes(t *testing.T) {
+	a, b := blas.NewMatrix(2, 3), blas.NewMatrix(2, 3)
+	if err := blas.Dgemm(false, false, 1, a, b, 0, blas.NewMatrix(2, 3)); !errors.Is(err, blas.ErrShape) {
+		t.Errorf("2×3·2×3: %v", err)
+	}
+	if err := blas.Dgemm(false, true, 1, a, b, 0, blas.NewMatrix(2, 2)); err != nil {
+		t.Errorf("2×3·(2×3)ᵀ: %v", err)
+	}
+	bad := &blas.Matrix{Rows: 2, Cols: 2, Data: make([]float64, 3)}
+	if _, err := blas.Mul(bad, bad); !errors.Is(err, blas.ErrShape) {
+		t.Errorf("short Data: %v", err)
+	}
+	// Empty products are fine.
+	if _, err := blas.Mul(blas.NewMatrix(0, 4), blas.NewMatrix(4, 5)); err != nil {
+		t.Error(err)
+	}
+	c, err := blas.Mul(blas.NewMatrix(3, 0), blas.NewMatrix(0, 2))
+	if err != nil || fmt.Sprint(c.Data) != "[0 0 0 0 0 0]" {
+		t.Errorf("3×0·0×2 = %v, %v", c, err)
+	}
+}
+
+// goGemm is Dgemm in Go, on copies.
+func goGemm(transA, transB bool, alpha float64, a, b *blas.Matrix, beta float64, c *blas.Matrix) *blas.Matrix {
+	at := func(m *blas.Matrix, t bool, i, j int) float64 {
+		if t {
+			return m.At(j, i)
+		}
+		return m.At(i, j)
+	}
+	k := a.Cols
+	if transA {
+		k = a.Rows
+	}
+	out := blas.NewMatrix(c.Rows, c.Cols)
+	for i := range c.Rows {
+		for j := range c.Cols {
+			var s float64
+			for l := range k {
+				s += at(a, transA, i, l) * at(b, transB, l, j)
+			}
+			v := alpha * s
+			if beta != 0 {
+				v += beta * c.At(i, j)
+			}
+			out.Set(i, j, v)
+		}
+	}
+	return out
+}
@@ -0,0 +1,33 @@
+// Package blas calls dgemm, the matrix multiply of the Fortran BLAS, as
+// an example of binding a library compiled from Fortran rather than C.
+//
+// cgo only speaks C, but a Fortran routine is callable from C once its
+// calling convention is spelled out, which is what the declaration of
+// dgemm_ in blas.go does, for gfortran and the compilers compatible with
+// it:
+//
+//   - The symbol is the routine's name in lower case with an underscore
+//     appended.
+//   - Every argument is passed by reference, scalars included.
+//   - Each CHARACTER argument has a hidden length, passed by value after
+//     all the others, in the order of the arguments: a size_t since
+//     gfortran 8, an int before.
+//   - INTEGER is a C int, unless the library was built for 64-bit
+//     integers (OpenBLAS's ILP64 builds, for one), which this package
+//     does not support.
+//
+// Fortran stores matrices in column-major order, Go code usually in
+// row-major order. A row-major matrix read as column-major is its
+// transpose, so Dgemm gets C = A·B by asking Fortran for Cᵀ = Bᵀ·Aᵀ,
+// with the operands swapped and no copies. ColMajor and FromColMajor
+// convert for routines where that trick does not apply.
+//
+// The reference BLAS reports invalid arguments by printing a message and
+// stopping the program, so Dgemm checks every argument first.
+//
+// The package links with -lblas, which the reference BLAS, OpenBLAS and
+// the other implementations all provide, and is only built with the blas
+// build tag:
+//
+//	go build -tags blas ./examples/blas
+package blas
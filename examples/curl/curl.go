@@ -0,0 +1,135 @@
+//go:build curl
+
+package curl
+
+/*
+
+#cgo pkg-config: libcurl
+#include <curl/curl.h>
+
+// curl_easy_setopt and curl_easy_getinfo are variadic, which cgo cannot
+// call, so each argument type gets a wrapper.
+static CURLcode setLong(CURL *h, CURLoption opt, long v) {
+	return curl_easy_setopt(h, opt, v);
+}
+
+static CURLcode setString(CURL *h, CURLoption opt, const char *v) {
+	return curl_easy_setopt(h, opt, v);
+}
+
+static CURLcode setPointer(CURL *h, CURLoption opt, void *v) {
+	return curl_easy_setopt(h, opt, v);
+}
+
+static CURLcode setOffset(CURL *h, CURLoption opt, curl_off_t v) {
+	return curl_easy_setopt(h, opt, v);
+}
+
+*/
+import "C"
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"unsafe"
+
+	"github.com/lxwagn/using-go-with-c-libraries/pkg/cerr"
+	"github.com/lxwagn/using-go-with-c-libraries/pkg/cmem"
+)
+
+// The libcurl result codes that callers commonly test for. An *Error
+// matches the one for its code with errors.Is.
+var (
+	codes = cerr.NewTable("curl")
+
+	ErrUnsupportedProtocol = codes.Register(C.CURLE_UNSUPPORTED_PROTOCOL, errors.New("curl: unsupported protocol"))
+	ErrCouldntResolveHost  = codes.Register(C.CURLE_COULDNT_RESOLVE_HOST, errors.New("curl: could not resolve host"))
+	ErrCouldntConnect      = codes.Register(C.CURLE_COULDNT_CONNECT, errors.New("curl: could not connect"))
+	ErrTimeout             = codes.Register(C.CURLE_OPERATION_TIMEDOUT, errors.New("curl: timeout"))
+	ErrAborted             = codes.Register(C.CURLE_ABORTED_BY_CALLBACK, errors.New("curl: aborted"))
+	ErrPeerVerification    = codes.Register(C.CURLE_PEER_FAILED_VERIFICATION, errors.New("curl: peer certificate not verified"))
+)
+
+// An Error is a failed transfer: libcurl's result code and the message it
+// gave for it.
+type Error struct {
+	Code int    // CURLcode
+	Msg  string // the transfer's error buffer, or curl_easy_strerror
+
+	err error
+}
+
+func (e *Error) Error() string {
+	return "curl: " + e.Msg
+}
+
+func (e *Error) Unwrap() error {
+	return e.err
+}
+
+// newError returns the error for the result code rc of a transfer whose
+// error buffer is errbuf.
+func newError(rc C.CURLcode, errbuf *C.char) error {
+	e := &Error{Code: int(rc), err: codes.Error("curl_easy_perform", int(rc))}
+	if errbuf != nil {
+		e.Msg = C.GoString(errbuf)
+	}
+	if e.Msg == "" {
+		e.Msg = C.GoString(C.curl_easy_strerror(rc))
+	}
+	return e
+}
+
+// setoptError is the error for a curl_easy_setopt that failed, which
+// means this libcurl lacks the option or the feature behind it.
+func setoptError(name string, rc C.CURLcode) error {
+	return fmt.Errorf("curl: setting %s: %s", name, C.GoString(C.curl_easy_strerror(rc)))
+}
+
+// curl_global_init is not thread-safe and must come before any other
+// libcurl call.
+var globalInit = sync.OnceValue(func() error {
+	if rc := C.curl_global_init(C.CURL_GLOBAL_DEFAULT); rc != C.CURLE_OK {
+		return newError(rc, nil)
+	}
+	return nil
+})
+
+// Version returns libcurl's version string, such as "libcurl/8.5.0
+// OpenSSL/3.0.13 zlib/1.3".
+func Version() string {
+	return C.GoString(C.curl_version())
+}
+
+// An options sets the options of one easy handle, remembering the first
+// failure so that a run of calls needs one check at the end.
+type options struct {
+	h   unsafe.Pointer // a CURL *, which is void to cgo
+	err error
+}
+
+func (o *options) check(name string, rc C.CURLcode) {
+	if rc != C.CURLE_OK && o.err == nil {
+		o.err = setoptError(name, rc)
+	}
+}
+
+func (o *options) long(name string, opt C.CURLoption, v int64) {
+	o.check(name, C.setLong(o.h, opt, C.long(v)))
+}
+
+func (o *options) string(name string, opt C.CURLoption, v string) {
+	// libcurl copies string options.
+	cs := (*C.char)(cmem.CString(v))
+	defer cmem.Free(unsafe.Pointer(cs))
+	o.check(name, C.setString(o.h, opt, cs))
+}
+
+func (o *options) pointer(name string, opt C.CURLoption, v unsafe.Pointer) {
+	o.check(name, C.setPointer(o.h, opt, v))
+}
+
+func (o *options) offset(name string, opt C.CURLoption, v int64) {
+	o.check(name, C.setOffset(o.h, opt, C.curl_off_t(v)))
+}
@@ -0,0 +1,34 @@
+// Package curl is an http.RoundTripper that hands each request to
+// libcurl, as an example of a C library that drives the I/O itself and
+// calls back into Go with the data.
+//
+// A Transport plugs into an http.Client like any other:
+//
+//	client := &http.Client{Transport: &curl.Transport{ConnectTimeout: 5 * time.Second}}
+//	resp, err := client.Get("https://example.com/")
+//
+// libcurl reports the response through callbacks run from inside
+// curl_easy_perform: one per header line, which the Transport parses into
+// the http.Response, and one per chunk of the body, which it feeds to the
+// Response's Body through a pipe. RoundTrip returns as soon as the header
+// block is complete, while the transfer goes on in its own goroutine, so
+// the body is streamed rather than buffered: the write callback blocks
+// until the reader has taken each chunk, and closing the Body early
+// aborts the transfer. The request body is streamed the same way, pulled
+// by libcurl's read callback.
+//
+// Timeouts are libcurl's, set from the Transport's fields and from the
+// request context's deadline. Cancelling the context makes RoundTrip and
+// Body reads return at once; libcurl notices from its progress callback,
+// which it calls at least once a second, and abandons the transfer.
+//
+// Redirects, cookies and retries are left to the http.Client. The
+// Response reports no TLS state, and libcurl, not the Transport, decides
+// whether to use a proxy, from the usual environment variables.
+//
+// The package links against libcurl found with pkg-config, and is only
+// built with the curl build tag, since its development files are less
+// commonly installed than those of the other examples:
+//
+//	go build -tags curl ./examples/curl
+package curl
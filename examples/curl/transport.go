@@ -0,0 +1,547 @@
+//go:build curl
+
+package curl
+
+/*
+
+#include <curl/curl.h>
+#include <stdint.h>
+
+// Defined in transport_export.go.
+extern size_t goCurlHeader(char *p, size_t size, size_t n, void *x);
+extern size_t goCurlWrite(char *p, size_t size, size_t n, void *x);
+extern size_t goCurlRead(char *p, size_t size, size_t n, void *x);
+extern int goCurlProgress(void *x, curl_off_t dltotal, curl_off_t dlnow, curl_off_t ultotal, curl_off_t ulnow);
+
+// setCallbacks points all of a handle's callbacks at the gateways, with
+// the transfer's handle as their user data.
+static CURLcode setCallbacks(CURL *h, uintptr_t x) {
+	void *p = (void *)x;
+	CURLcode rc;
+
+	if ((rc = curl_easy_setopt(h, CURLOPT_HEADERFUNCTION, goCurlHeader)) ||
+	    (rc = curl_easy_setopt(h, CURLOPT_HEADERDATA, p)) ||
+	    (rc = curl_easy_setopt(h, CURLOPT_WRITEFUNCTION, goCurlWrite)) ||
+	    (rc = curl_easy_setopt(h, CURLOPT_WRITEDATA, p)) ||
+	    (rc = curl_easy_setopt(h, CURLOPT_READFUNCTION, goCurlRead)) ||
+	    (rc = curl_easy_setopt(h, CURLOPT_READDATA, p)) ||
+	    (rc = curl_easy_setopt(h, CURLOPT_XFERINFOFUNCTION, goCurlProgress)) ||
+	    (rc = curl_easy_setopt(h, CURLOPT_XFERINFODATA, p)))
+		return rc;
+	return curl_easy_setopt(h, CURLOPT_NOPROGRESS, 0L);
+}
+
+*/
+import "C"
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/textproto"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+	"unsafe"
+
+	"github.com/lxwagn/using-go-with-c-libraries/pkg/cmem"
+	"github.com/lxwagn/using-go-with-c-libraries/pkg/cpanic"
+	"github.com/lxwagn/using-go-with-c-libraries/pkg/handles"
+)
+
+// A Transport is an http.RoundTripper that performs requests with
+// libcurl. The zero value is ready to use, and a Transport is safe for
+// concurrent use.
+//
+// Each request runs on an easy handle of its own. Finished handles are
+// kept for reuse, together with the connections libcurl keeps open on
+// them, up to MaxIdle of them.
+type Transport struct {
+	// Timeout limits the whole transfer, including the time spent
+	// reading the response body. Zero means no limit.
+	Timeout time.Duration
+
+	// ConnectTimeout limits connecting to the server, TLS handshake
+	// included. Zero means libcurl's default of 300 seconds.
+	ConnectTimeout time.Duration
+
+	// MaxIdle is the number of idle easy handles to keep. Zero means 4.
+	MaxIdle int
+
+	mu   sync.Mutex
+	idle []unsafe.Pointer
+}
+
+var errNoResponse = errors.New("curl: transfer ended without a response")
+
+// RoundTrip performs req and returns its response once the header block
+// has been received. The response body is read from the network as the
+// caller reads it.
+func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	x, err := t.start(req)
+	if err != nil {
+		if req.Body != nil {
+			req.Body.Close()
+		}
+		return nil, err
+	}
+	go x.run()
+
+	select {
+	case <-x.ready:
+	case <-x.ctx.Done():
+		x.abort()
+		return nil, x.ctx.Err()
+	}
+	if x.err != nil {
+		return nil, x.err
+	}
+	return x.resp, nil
+}
+
+// CloseIdleConnections frees the idle easy handles and with them the
+// connections libcurl kept open. http.Client.CloseIdleConnections calls
+// it.
+func (t *Transport) CloseIdleConnections() {
+	t.mu.Lock()
+	idle := t.idle
+	t.idle = nil
+	t.mu.Unlock()
+	for _, h := range idle {
+		C.curl_easy_cleanup(h)
+	}
+}
+
+func (t *Transport) get() (unsafe.Pointer, error) {
+	t.mu.Lock()
+	if n := len(t.idle); n > 0 {
+		h := t.idle[n-1]
+		t.idle = t.idle[:n-1]
+		t.mu.Unlock()
+		return h, nil
+	}
+	t.mu.Unlock()
+
+	if err := globalInit(); err != nil {
+		return nil, err
+	}
+	h := C.curl_easy_init()
+	if h == nil {
+		return nil, errors.New("curl: curl_easy_init failed")
+	}
+	return h, nil
+}
+
+func (t *Transport) put(h unsafe.Pointer) {
+	// curl_easy_reset clears the options but keeps the handle's
+	// connections and caches.
+	C.curl_easy_reset(h)
+	limit := t.MaxIdle
+	if limit == 0 {
+		limit = 4
+	}
+	t.mu.Lock()
+	if len(t.idle) < limit {
+		t.idle = append(t.idle, h)
+		h = nil
+	}
+	t.mu.Unlock()
+	if h != nil {
+		C.curl_easy_cleanup(h)
+	}
+}
+
+// A transfer is one request in progress. Its callbacks run on the
+// goroutine calling curl_easy_perform; RoundTrip and the response body
+// see their results once ready is closed.
+type transfer struct {
+	t      *Transport
+	req    *http.Request
+	ctx    context.Context
+	h      unsafe.Pointer
+	hdrs   *C.struct_curl_slist
+	errbuf *C.char
+	handle handles.Handle[*transfer]
+	stop   func() bool // stops the context's AfterFunc
+
+	// The timeout set is the context's deadline.
+	deadline bool
+
+	// Set by the header callback until ready is closed, then by run.
+	resp      *http.Response
+	lastKey   string
+	headersOK bool
+	err       error
+	ready     chan struct{}
+
+	// The body reaches the Response through a pipe; the write callback
+	// blocks on pw until the reader has taken each chunk.
+	pr *io.PipeReader
+	pw *io.PipeWriter
+
+	reqErr error       // from reading the request body
+	closed atomic.Bool // the response body was closed
+
+	// The callbacks run behind barrier, so that a panic in them, such
+	// as one in the request body's Read, does not unwind libcurl.
+	barrier cpanic.Barrier
+}
+
+// start sets up an easy handle for req.
+func (t *Transport) start(req *http.Request) (*transfer, error) {
+	if req.URL == nil {
+		return nil, errors.New("curl: nil Request.URL")
+	}
+	if req.URL.Scheme != "http" && req.URL.Scheme != "https" {
+		return nil, &Error{Code: C.CURLE_UNSUPPORTED_PROTOCOL, Msg: "unsupported protocol scheme " + strconv.Quote(req.URL.Scheme), err: ErrUnsupportedProtocol}
+	}
+	ctx := req.Context()
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	hdrs, err := headerList(req)
+	if err != nil {
+		return nil, err
+	}
+
+	h, err := t.get()
+	if err != nil {
+		C.curl_slist_free_all(hdrs)
+		return nil, err
+	}
+	x := &transfer{
+		t:      t,
+		req:    req,
+		ctx:    ctx,
+		h:      h,
+		hdrs:   hdrs,
+		errbuf: (*C.char)(cmem.Calloc(C.CURL_ERROR_SIZE, 1)),
+		ready:  make(chan struct{}),
+	}
+	x.pr, x.pw = io.Pipe()
+	x.handle = handles.New(x)
+
+	if err := x.setOptions(); err != nil {
+		x.free()
+		return nil, err
+	}
+	// Cancelling the context fails a write blocked in the pipe, and
+	// Body reads, at once; libcurl itself stops at its next progress
+	// callback.
+	x.stop = context.AfterFunc(ctx, func() {
+		x.pw.CloseWithError(ctx.Err())
+	})
+	return x, nil
+}
+
+func (x *transfer) setOptions() error {
+	req, t := x.req, x.t
+	o := options{h: x.h}
+	o.string("CURLOPT_URL", C.CURLOPT_URL, req.URL.String())
+	o.pointer("CURLOPT_ERRORBUFFER", C.CURLOPT_ERRORBUFFER, unsafe.Pointer(x.errbuf))
+	o.pointer("CURLOPT_HTTPHEADER", C.CURLOPT_HTTPHEADER, unsafe.Pointer(x.hdrs))
+	o.long("CURLOPT_NOSIGNAL", C.CURLOPT_NOSIGNAL, 1)
+	o.long("CURLOPT_SUPPRESS_CONNECT_HEADERS", C.CURLOPT_SUPPRESS_CONNECT_HEADERS, 1)
+	if rc := C.setCallbacks(x.h, C.uintptr_t(x.handle.Uintptr())); rc != C.CURLE_OK {
+		return setoptError("callbacks", rc)
+	}
+
+	method := req.Method
+	if method == "" {
+		method = http.MethodGet
+	}
+	switch {
+	case method == http.MethodHead:
+		o.long("CURLOPT_NOBODY", C.CURLOPT_NOBODY, 1)
+	case hasBody(req) || method == http.MethodPost || method == http.MethodPut || method == http.MethodPatch:
+		// CURLOPT_UPLOAD streams the body from the read callback,
+		// chunked if its size is unknown. It implies PUT.
+		o.long("CURLOPT_UPLOAD", C.CURLOPT_UPLOAD, 1)
+		o.offset("CURLOPT_INFILESIZE_LARGE", C.CURLOPT_INFILESIZE_LARGE, bodySize(req))
+		if method != http.MethodPut {
+			o.string("CURLOPT_CUSTOMREQUEST", C.CURLOPT_CUSTOMREQUEST, method)
+		}
+	case method != http.MethodGet:
+		o.string("CURLOPT_CUSTOMREQUEST", C.CURLOPT_CUSTOMREQUEST, method)
+	}
+
+	timeout := t.Timeout
+	if d, ok := x.ctx.Deadline(); ok {
+		if left := time.Until(d); timeout == 0 || left < timeout {
+			// Zero would mean no limit.
+			timeout = max(left, time.Millisecond)
+			x.deadline = true
+		}
+	}
+	if timeout > 0 {
+		o.long("CURLOPT_TIMEOUT_MS", C.CURLOPT_TIMEOUT_MS, timeout.Milliseconds())
+	}
+	if t.ConnectTimeout > 0 {
+		o.long("CURLOPT_CONNECTTIMEOUT_MS", C.CURLOPT_CONNECTTIMEOUT_MS, t.ConnectTimeout.Milliseconds())
+	}
+	return o.err
+}
+
+// hasBody reports whether req has a body to upload.
+func hasBody(req *http.Request) bool {
+	return req.Body != nil && req.Body != http.NoBody
+}
+
+// bodySize is req's body length for CURLOPT_INFILESIZE_LARGE, where -1
+// means unknown. A client request with a body and a ContentLength of 0
+// has an unknown length too.
+func bodySize(req *http.Request) int64 {
+	if !hasBody(req) {
+		return 0
+	}
+	if req.ContentLength > 0 {
+		return req.ContentLength
+	}
+	return -1
+}
+
+// headerList converts req's header to the list CURLOPT_HTTPHEADER
+// takes. A header libcurl would add on its own and net/http would not is
+// disabled with an empty "Name:" entry.
+func headerList(req *http.Request) (*C.struct_curl_slist, error) {
+	var lines []string
+	add := func(k, v string) {
+		if v == "" {
+			// "Name:" removes a header; "Name;" sends it empty.
+			lines = append(lines, k+";")
+		} else {
+			lines = append(lines, k+": "+v)
+		}
+	}
+	for k, vs := range req.Header {
+		switch textproto.CanonicalMIMEHeaderKey(k) {
+		case "Host", "Content-Length", "Transfer-Encoding":
+			// From req.Host and the body.
+			continue
+		}
+		for _, v := range vs {
+			add(k, v)
+		}
+	}
+	if req.Host != "" && req.Host != req.URL.Host {
+		add("Host", req.Host)
+	}
+	for _, k := range []string{"Accept", "Expect"} {
+		if _, ok := req.Header[k]; !ok {
+			lines = append(lines, k+":")
+		}
+	}
+
+	var list *C.struct_curl_slist
+	for _, line := range lines {
+		if strings.ContainsAny(line, "\r\n\x00") {
+			C.curl_slist_free_all(list)
+			return nil, errors.New("curl: invalid header " + strconv.Quote(line))
+		}
+		cs := (*C.char)(cmem.CString(line))
+		next := C.curl_slist_append(list, cs)
+		cmem.Free(unsafe.Pointer(cs))
+		if next == nil {
+			C.curl_slist_free_all(list)
+			return nil, errors.New("curl: curl_slist_append failed")
+		}
+		list = next
+	}
+	return list, nil
+}
+
+// run performs the transfer and releases everything it holds. A panic in
+// a callback fails the transfer, and is raised again once everything is
+// released, as net/http's transport leaves a panicking body to crash the
+// program.
+func (x *transfer) run() {
+	rc := C.curl_easy_perform(x.h)
+	err := x.result(rc)
+
+	x.stop()
+	if x.req.Body != nil {
+		x.req.Body.Close()
+	}
+	// A nil err gives the reader io.EOF.
+	x.pw.CloseWithError(err)
+	if !x.headersOK {
+		if err == nil {
+			err = errNoResponse
+		}
+		x.err = err
+		close(x.ready)
+	}
+	x.free()
+	x.barrier.Repanic()
+}
+
+func (x *transfer) result(rc C.CURLcode) error {
+	if rc == C.CURLE_OK {
+		return nil
+	}
+	if p := x.barrier.Recovered(); p != nil {
+		return p
+	}
+	if err := x.ctx.Err(); err != nil {
+		return err
+	}
+	if x.reqErr != nil {
+		return x.reqErr
+	}
+	if rc == C.CURLE_OPERATION_TIMEDOUT && x.deadline {
+		// libcurl may get there just before the context.
+		return context.DeadlineExceeded
+	}
+	return newError(rc, x.errbuf)
+}
+
+func (x *transfer) free() {
+	x.handle.Delete()
+	x.t.put(x.h)
+	C.curl_slist_free_all(x.hdrs)
+	cmem.Free(unsafe.Pointer(x.errbuf))
+}
+
+// abort makes libcurl give up the transfer at its next callback.
+func (x *transfer) abort() {
+	x.closed.Store(true)
+	x.pr.Close()
+}
+
+// header handles one line of the response header, with its line ending.
+// It returns false to abort the transfer.
+func (x *transfer) header(line string) bool {
+	if x.headersOK {
+		// Trailers are not reported.
+		return true
+	}
+	switch {
+	case strings.HasPrefix(line, "HTTP/"):
+		// A new status line: the first one, or the real one after a
+		// 1xx response.
+		resp, ok := parseStatus(strings.TrimRight(line, "\r\n"))
+		if !ok {
+			x.reqErr = errors.New("curl: malformed status line " + strconv.Quote(line))
+			return false
+		}
+		resp.Request = x.req
+		resp.Body = &body{x}
+		x.resp = resp
+	case x.resp == nil:
+		return true
+	case line == "\r\n" || line == "\n":
+		if x.resp.StatusCode >= 200 {
+			x.finishHeader()
+		}
+	case line[0] == ' ' || line[0] == '\t':
+		// A continuation of the previous line.
+		if vs := x.resp.Header[x.lastKey]; len(vs) > 0 {
+			vs[len(vs)-1] += " " + strings.TrimSpace(line)
+		}
+	default:
+		k, v, ok := strings.Cut(line, ":")
+		if !ok {
+			return true
+		}
+		x.lastKey = textproto.CanonicalMIMEHeaderKey(strings.TrimSpace(k))
+		x.resp.Header.Add(x.lastKey, strings.TrimSpace(v))
+	}
+	return true
+}
+
+func (x *transfer) finishHeader() {
+	resp := x.resp
+	resp.ContentLength = -1
+	if cl := resp.Header.Get("Content-Length"); cl != "" {
+		if n, err := strconv.ParseInt(cl, 10, 64); err == nil && n >= 0 {
+			resp.ContentLength = n
+		}
+	}
+	if x.req.Method == http.MethodHead || resp.StatusCode == http.StatusNoContent || resp.StatusCode == http.StatusNotModified {
+		resp.Body = http.NoBody
+	}
+	x.headersOK = true
+	close(x.ready)
+}
+
+// parseStatus parses a status line such as "HTTP/1.1 200 OK" or
+// "HTTP/2 404".
+func parseStatus(line string) (*http.Response, bool) {
+	proto, status, _ := strings.Cut(line, " ")
+	codeStr, reason, _ := strings.Cut(status, " ")
+	code, err := strconv.Atoi(codeStr)
+	if err != nil || len(codeStr) != 3 {
+		return nil, false
+	}
+	resp := &http.Response{
+		Proto:      proto,
+		StatusCode: code,
+		Header:     make(http.Header),
+	}
+	switch proto {
+	case "HTTP/2", "HTTP/3":
+		resp.ProtoMajor = int(proto[5] - '0')
+	default:
+		var ok bool
+		if resp.ProtoMajor, resp.ProtoMinor, ok = http.ParseHTTPVersion(proto); !ok {
+			return nil, false
+		}
+	}
+	if reason == "" {
+		reason = http.StatusText(code)
+	}
+	resp.Status = codeStr + " " + reason
+	return resp, true
+}
+
+// write passes a chunk of the response body to the reader, returning
+// false if the body was closed or the context cancelled.
+func (x *transfer) write(p []byte) bool {
+	if !x.headersOK {
+		// A response with no header block.
+		return false
+	}
+	_, err := x.pw.Write(p)
+	return err == nil
+}
+
+// read fills p from the request body. It returns 0 at the end of the
+// body and -1 if reading failed.
+func (x *transfer) read(p []byte) int {
+	if !hasBody(x.req) {
+		return 0
+	}
+	for {
+		n, err := x.req.Body.Read(p)
+		if n > 0 || err == io.EOF {
+			return n
+		}
+		if err != nil {
+			x.reqErr = err
+			return -1
+		}
+	}
+}
+
+// progress reports whether the transfer should go on.
+func (x *transfer) progress() bool {
+	return !x.closed.Load() && x.ctx.Err() == nil
+}
+
+// A body is a Response body fed by a transfer.
+type body struct {
+	x *transfer
+}
+
+func (b *body) Read(p []byte) (int, error) {
+	return b.x.pr.Read(p)
+}
+
+// Close stops the transfer if the body has not been read to the end.
+func (b *body) Close() error {
+	b.x.abort()
+	return nil
+}
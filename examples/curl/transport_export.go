@@ -0,0 +1,64 @@
+//go:build curl
+
+package curl
+
+// The gateways libcurl calls are declared in transport.go; a file with
+// //export directives may only declare C functions, not define them.
+
+/*
+#include <curl/curl.h>
+*/
+import "C"
+
+import (
+	"unsafe"
+
+	"github.com/lxwagn/using-go-with-c-libraries/pkg/cpanic"
+	"github.com/lxwagn/using-go-with-c-libraries/pkg/handles"
+)
+
+func transferOf(p unsafe.Pointer) *transfer {
+	return handles.FromUintptr[*transfer](uintptr(p)).Value()
+}
+
+// Each callback runs behind the transfer's barrier, and after a panic
+// answers as though it had failed, which makes libcurl abort.
+
+//export goCurlHeader
+func goCurlHeader(p *C.char, size, n C.size_t, xp unsafe.Pointer) C.size_t {
+	x, line := transferOf(xp), C.GoStringN(p, C.int(size*n))
+	if !cpanic.Call(&x.barrier, false, func() bool { return x.header(line) }) {
+		return 0
+	}
+	return size * n
+}
+
+//export goCurlWrite
+func goCurlWrite(p *C.char, size, n C.size_t, xp unsafe.Pointer) C.size_t {
+	// The chunk is only valid during the call, which is as long as
+	// the pipe needs it.
+	x, chunk := transferOf(xp), unsafe.Slice((*byte)(unsafe.Pointer(p)), size*n)
+	if !cpanic.Call(&x.barrier, false, func() bool { return x.write(chunk) }) {
+		return 0
+	}
+	return size * n
+}
+
+//export goCurlRead
+func goCurlRead(p *C.char, size, n C.size_t, xp unsafe.Pointer) C.size_t {
+	x, buf := transferOf(xp), unsafe.Slice((*byte)(unsafe.Pointer(p)), size*n)
+	m := cpanic.Call(&x.barrier, -1, func() int { return x.read(buf) })
+	if m < 0 {
+		return C.CURL_READFUNC_ABORT
+	}
+	return C.size_t(m)
+}
+
+//export goCurlProgress
+func goCurlProgress(xp unsafe.Pointer, dltotal, dlnow, ultotal, ulnow C.curl_off_t) C.int {
+	x := transferOf(xp)
+	if !cpanic.Call(&x.barrier, false, x.progress) {
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,149 @@
+// The API of gpu.h on the CUDA runtime, for the cuda build tag. Built
+// with nvcc into libgpucuda.a by the Makefile here, since cgo cannot
+// compile CUDA; the Go package links the archive and libcudart.
+
+#include <cuda_runtime.h>
+#include <stdlib.h>
+#include <string.h>
+
+extern "C" {
+#include "../gpu.h"
+}
+
+struct gpuStream {
+	cudaStream_t stream;
+};
+
+static int code(cudaError_t e) {
+	switch (e) {
+	case cudaSuccess:
+		return GPU_SUCCESS;
+	case cudaErrorInvalidValue:
+		return GPU_ERROR_INVALID_VALUE;
+	case cudaErrorMemoryAllocation:
+		return GPU_ERROR_MEMORY_ALLOCATION;
+	case cudaErrorIllegalAddress:
+		return GPU_ERROR_ILLEGAL_ADDRESS;
+	case cudaErrorNoDevice:
+	case cudaErrorInsufficientDriver:
+		return GPU_ERROR_NO_DEVICE;
+	default:
+		return GPU_ERROR_UNKNOWN;
+	}
+}
+
+extern "C" int gpuDeviceName(char *name, size_t n) {
+	cudaDeviceProp prop;
+
+	if (name == NULL || n == 0)
+		return GPU_ERROR_INVALID_VALUE;
+	cudaError_t e = cudaGetDeviceProperties(&prop, 0);
+	if (e != cudaSuccess)
+		return code(e);
+	strncpy(name, prop.name, n - 1);
+	name[n - 1] = '\0';
+	return GPU_SUCCESS;
+}
+
+extern "C" int gpuMalloc(void **p, size_t n) {
+	return code(cudaMalloc(p, n));
+}
+
+// cudaFree waits for the device to be idle first.
+extern "C" int gpuFree(void *p) {
+	return code(cudaFree(p));
+}
+
+extern "C" int gpuHostAlloc(void **p, size_t n) {
+	return code(cudaHostAlloc(p, n, cudaHostAllocDefault));
+}
+
+extern "C" int gpuHostFree(void *p) {
+	return code(cudaFreeHost(p));
+}
+
+extern "C" int gpuStreamCreate(gpuStream **sp) {
+	gpuStream *s;
+
+	if (sp == NULL)
+		return GPU_ERROR_INVALID_VALUE;
+	if ((s = (gpuStream *)calloc(1, sizeof *s)) == NULL)
+		return GPU_ERROR_MEMORY_ALLOCATION;
+	cudaError_t e = cudaStreamCreateWithFlags(&s->stream, cudaStreamNonBlocking);
+	if (e != cudaSuccess) {
+		free(s);
+		return code(e);
+	}
+	*sp = s;
+	return GPU_SUCCESS;
+}
+
+// cudaStreamDestroy returns at once and lets queued work finish; gpu.h
+// promises that it has.
+extern "C" int gpuStreamDestroy(gpuStream *s) {
+	if (s == NULL)
+		return GPU_ERROR_INVALID_VALUE;
+	cudaError_t e = cudaStreamSynchronize(s->stream);
+	cudaStreamDestroy(s->stream);
+	free(s);
+	return code(e);
+}
+
+extern "C" int gpuMemcpyToDevice(void *dst, const void *src, size_t n, gpuStream *s) {
+	return code(cudaMemcpyAsync(dst, src, n, cudaMemcpyHostToDevice, s->stream));
+}
+
+extern "C" int gpuMemcpyToHost(void *dst, const void *src, size_t n, gpuStream *s) {
+	return code(cudaMemcpyAsync(dst, src, n, cudaMemcpyDeviceToHost, s->stream));
+}
+
+__global__ static void saxpy(float a, const float *x, float *y, size_t n) {
+	size_t i = (size_t)blockIdx.x * blockDim.x + threadIdx.x;
+
+	if (i < n)
+		y[i] = a * x[i] + y[i];
+}
+
+extern "C" int gpuSaxpy(float a, const float *x, float *y, size_t n, gpuStream *s) {
+	if (x == NULL || y == NULL)
+		return GPU_ERROR_INVALID_VALUE;
+	if (n == 0)
+		return GPU_SUCCESS;
+	saxpy<<<(unsigned)((n + 255) / 256), 256, 0, s->stream>>>(a, x, y, n);
+	return code(cudaGetLastError());
+}
+
+struct callback {
+	gpuStream *s;
+	gpuCallback cb;
+	void *user;
+};
+
+static void CUDART_CB runCallback(cudaStream_t, cudaError_t status, void *p) {
+	struct callback *c = (struct callback *)p;
+
+	c->cb(c->s, code(status), c->user);
+	free(c);
+}
+
+extern "C" int gpuStreamAddCallback(gpuStream *s, gpuCallback cb, void *user) {
+	struct callback *c;
+
+	if (s == NULL || cb == NULL)
+		return GPU_ERROR_INVALID_VALUE;
+	if ((c = (struct callback *)malloc(sizeof *c)) == NULL)
+		return GPU_ERROR_MEMORY_ALLOCATION;
+	c->s = s;
+	c->cb = cb;
+	c->user = user;
+	cudaError_t e = cudaStreamAddCallback(s->stream, runCallback, c, 0);
+	if (e != cudaSuccess)
+		free(c);
+	return code(e);
+}
+
+extern "C" int gpuStreamSynchronize(gpuStream *s) {
+	if (s == NULL)
+		return GPU_ERROR_INVALID_VALUE;
+	return code(cudaStreamSynchronize(s->stream));
+}
@@ -0,0 +1,32 @@
+// Package gpu queues copies and kernels on the streams of a compute
+// device, in the style of the CUDA runtime's asynchronous API. Without
+// the cuda build tag, sim.c stands in for the device, a thread per
+// stream, so the example runs anywhere; with it, and libgpucuda.a built
+// by make in cuda/, the same Go code runs on a real GPU.
+//
+// Every call queues its work and returns before the work runs:
+//
+//	x, _ := gpu.NewHostBuffer(n) // page-locked
+//	dx, _ := gpu.Malloc(n)
+//	dy, _ := gpu.Malloc(n)
+//	s, _ := gpu.NewStream()
+//	s.CopyToDevice(dx, x.Data())
+//	s.CopyToDevice(dy, y) // a Go slice, pinned until the copy has run
+//	s.Saxpy(2, dx, dy)
+//	s.CopyToHost(y, dy)
+//	err := <-s.Done()
+//
+// So C holds on to host memory after the call that passed it returns,
+// which the cgo rules allow only for memory Go did not allocate. A
+// HostBuffer is C memory, page-locked, as the device reads it best. A Go
+// slice works too, but the stream pins it with a runtime.Pinner for each
+// copy, and unpins it from a callback queued after the copy, so the
+// garbage collector cannot move or free it while the device reads it.
+//
+// The callbacks run on the runtime's own thread, which Go did not
+// create, so they do no more than pass a status on to a goroutine: Done
+// returns a channel that receives it. A kernel's error, such as the
+// illegal address of a read out of bounds, is not returned by the call
+// that queued it but by the next thing that waits for the stream, Done
+// or Synchronize, as on a GPU; the stream's later work is then skipped.
+package gpu
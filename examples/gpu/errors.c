@@ -0,0 +1,19 @@
+// The error strings, the same for both implementations of gpu.h.
+
+#include "gpu.h"
+
+const char *gpuErrorString(int err) {
+	switch (err) {
+	case GPU_SUCCESS:
+		return "no error";
+	case GPU_ERROR_INVALID_VALUE:
+		return "invalid argument";
+	case GPU_ERROR_MEMORY_ALLOCATION:
+		return "out of memory";
+	case GPU_ERROR_ILLEGAL_ADDRESS:
+		return "an illegal memory access was encountered";
+	case GPU_ERROR_NO_DEVICE:
+		return "no device";
+	}
+	return "unknown error";
+}
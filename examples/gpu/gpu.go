@@ -0,0 +1,273 @@
+package gpu
+
+/*
+
+#cgo CFLAGS: -O2
+#cgo LDFLAGS: -pthread
+#include <stdint.h>
+#include "gpu.h"
+
+// Defined in gpu_export.go.
+extern void goStreamCallback(gpuStream *s, int status, void *user);
+
+static int addCallback(gpuStream *s, uintptr_t handle) {
+	return gpuStreamAddCallback(s, goStreamCallback, (void *)handle);
+}
+
+*/
+import "C"
+
+import (
+	"errors"
+	"fmt"
+	"runtime"
+	"sync"
+	"unsafe"
+
+	"github.com/lxwagn/using-go-with-c-libraries/pkg/handles"
+)
+
+// An Error is an error code of the compute API.
+type Error int
+
+const (
+	ErrInvalidValue     Error = C.GPU_ERROR_INVALID_VALUE
+	ErrMemoryAllocation Error = C.GPU_ERROR_MEMORY_ALLOCATION
+	ErrIllegalAddress   Error = C.GPU_ERROR_ILLEGAL_ADDRESS
+	ErrNoDevice         Error = C.GPU_ERROR_NO_DEVICE
+)
+
+func (e Error) Error() string {
+	return C.GoString(C.gpuErrorString(C.int(e)))
+}
+
+// ErrClosed is returned for work queued on a closed Stream.
+var ErrClosed = errors.New("gpu: stream closed")
+
+func check(op string, rc C.int) error {
+	if rc == C.GPU_SUCCESS {
+		return nil
+	}
+	return fmt.Errorf("gpu: %s: %w", op, Error(rc))
+}
+
+// DeviceName returns the name of the device, "CPU simulator" without the
+// cuda build tag.
+func DeviceName() (string, error) {
+	var name [256]C.char
+	if err := check("gpuDeviceName", C.gpuDeviceName(&name[0], C.size_t(len(name)))); err != nil {
+		return "", err
+	}
+	return C.GoString(&name[0]), nil
+}
+
+// A DeviceBuffer is device memory for a number of float32s. Go cannot
+// read or write it; the copies of a Stream do.
+type DeviceBuffer struct {
+	p unsafe.Pointer
+	n int
+}
+
+// Malloc allocates device memory for n float32s.
+func Malloc(n int) (*DeviceBuffer, error) {
+	if n <= 0 {
+		return nil, fmt.Errorf("gpu: gpuMalloc: %w", ErrInvalidValue)
+	}
+	var p unsafe.Pointer
+	if err := check("gpuMalloc", C.gpuMalloc(&p, C.size_t(n)*C.sizeof_float)); err != nil {
+		return nil, err
+	}
+	return &DeviceBuffer{p: p, n: n}, nil
+}
+
+// Len returns the number of float32s the buffer holds.
+func (d *DeviceBuffer) Len() int {
+	return d.n
+}
+
+// Free waits for the work queued on every stream to finish, and frees the
+// buffer. Calls after the first do nothing.
+func (d *DeviceBuffer) Free() error {
+	p := d.p
+	d.p, d.n = nil, 0
+	return check("gpuFree", C.gpuFree(p))
+}
+
+// A HostBuffer is page-locked host memory for a number of float32s,
+// which the device copies to and from directly, while other work runs.
+// A copy from Go memory works too, but the driver stages it through a
+// page-locked buffer of its own, and with real CUDA the copy then does
+// not overlap with the host.
+type HostBuffer struct {
+	p    unsafe.Pointer
+	data []float32
+}
+
+// NewHostBuffer allocates page-locked memory for n float32s, zeroed.
+func NewHostBuffer(n int) (*HostBuffer, error) {
+	if n <= 0 {
+		return nil, fmt.Errorf("gpu: gpuHostAlloc: %w", ErrInvalidValue)
+	}
+	var p unsafe.Pointer
+	if err := check("gpuHostAlloc", C.gpuHostAlloc(&p, C.size_t(n)*C.sizeof_float)); err != nil {
+		return nil, err
+	}
+	data := unsafe.Slice((*float32)(p), n)
+	clear(data)
+	return &HostBuffer{p: p, data: data}, nil
+}
+
+// Data returns the buffer's memory as a slice. It is valid until Free.
+func (h *HostBuffer) Data() []float32 {
+	return h.data
+}
+
+// Free frees the buffer, which no work still queued may use. Calls after
+// the first do nothing.
+func (h *HostBuffer) Free() error {
+	if h.p == nil {
+		return nil
+	}
+	p := h.p
+	h.p, h.data = nil, nil
+	return check("gpuHostFree", C.gpuHostFree(p))
+}
+
+// A Stream queues work for the device, which runs it in order while the
+// calls queuing it return at once. Its methods may be called from several
+// goroutines, the work then being queued in the order the calls are made.
+type Stream struct {
+	mu sync.Mutex
+	s  *C.gpuStream
+}
+
+// NewStream creates a stream.
+func NewStream() (*Stream, error) {
+	var s *C.gpuStream
+	if err := check("gpuStreamCreate", C.gpuStreamCreate(&s)); err != nil {
+		return nil, err
+	}
+	return &Stream{s: s}, nil
+}
+
+// then queues fn to run with the status of the work queued before it, on
+// the runtime's callback thread. fn must not block: the stream's later
+// work waits for it.
+func (s *Stream) then(fn func(error)) error {
+	h := handles.New(fn)
+	if err := check("gpuStreamAddCallback", C.addCallback(s.s, C.uintptr_t(h.Uintptr()))); err != nil {
+		h.Delete()
+		return err
+	}
+	return nil
+}
+
+// copyAsync queues a copy between device memory and b, which C holds on
+// to after the call returns, and so must be pinned until the copy has
+// run if it is Go memory. Pinning memory that Go did not allocate, a
+// HostBuffer's, does nothing.
+func (s *Stream) copyAsync(op string, b []float32, copy func(host unsafe.Pointer) C.int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.s == nil {
+		return ErrClosed
+	}
+	p := unsafe.Pointer(unsafe.SliceData(b))
+	pinner := new(runtime.Pinner)
+	pinner.Pin(p)
+	if err := check(op, copy(p)); err != nil {
+		pinner.Unpin()
+		return err
+	}
+	if err := s.then(func(error) { pinner.Unpin() }); err != nil {
+		// Without the callback there is no knowing when the copy is
+		// done but to wait for it.
+		C.gpuStreamSynchronize(s.s)
+		pinner.Unpin()
+		return err
+	}
+	return nil
+}
+
+// CopyToDevice queues a copy of src to the start of dst. src must not
+// change until the copy has run, as Done or Synchronize tell.
+func (s *Stream) CopyToDevice(dst *DeviceBuffer, src []float32) error {
+	if len(src) > dst.Len() {
+		return fmt.Errorf("gpu: gpuMemcpyToDevice: %d values into a buffer of %d: %w", len(src), dst.Len(), ErrInvalidValue)
+	}
+	return s.copyAsync("gpuMemcpyToDevice", src, func(host unsafe.Pointer) C.int {
+		return C.gpuMemcpyToDevice(dst.p, host, C.size_t(len(src))*C.sizeof_float, s.s)
+	})
+}
+
+// CopyToHost queues a copy of the start of src to dst, which must not be
+// read until the copy has run, as Done or Synchronize tell.
+func (s *Stream) CopyToHost(dst []float32, src *DeviceBuffer) error {
+	if len(dst) > src.Len() {
+		return fmt.Errorf("gpu: gpuMemcpyToHost: %d values from a buffer of %d: %w", len(dst), src.Len(), ErrInvalidValue)
+	}
+	return s.copyAsync("gpuMemcpyToHost", dst, func(host unsafe.Pointer) C.int {
+		return C.gpuMemcpyToHost(host, src.p, C.size_t(len(dst))*C.sizeof_float, s.s)
+	})
+}
+
+// Saxpy queues the kernel y = a*x + y over the y.Len() values of y. As
+// with a real kernel, its reads of x are checked on the device, not here:
+// an x shorter than y fails the stream with ErrIllegalAddress when the
+// kernel runs, which Done and Synchronize then report.
+func (s *Stream) Saxpy(a float32, x, y *DeviceBuffer) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.s == nil {
+		return ErrClosed
+	}
+	return check("gpuSaxpy", C.gpuSaxpy(C.float(a), (*C.float)(x.p), (*C.float)(y.p), C.size_t(y.Len()), s.s))
+}
+
+// Done returns a channel that receives the status of the work queued so
+// far once it has finished, and is then closed, as a completion callback
+// would report it. Unlike Synchronize it does not block, so a goroutine
+// can wait for several streams, or a stream and a timeout, in a select.
+func (s *Stream) Done() <-chan error {
+	ch := make(chan error, 1)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.s == nil {
+		ch <- ErrClosed
+		close(ch)
+		return ch
+	}
+	err := s.then(func(err error) {
+		ch <- err
+		close(ch)
+	})
+	if err != nil {
+		ch <- err
+		close(ch)
+	}
+	return ch
+}
+
+// Synchronize waits for the work queued so far to finish, and returns its
+// status.
+func (s *Stream) Synchronize() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.s == nil {
+		return ErrClosed
+	}
+	return check("gpuStreamSynchronize", C.gpuStreamSynchronize(s.s))
+}
+
+// Close waits for the stream's work and callbacks to finish, and destroys
+// it. The channels Done returned have all received a value by then.
+func (s *Stream) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.s == nil {
+		return ErrClosed
+	}
+	err := check("gpuStreamDestroy", C.gpuStreamDestroy(s.s))
+	s.s = nil
+	return err
+}
@@ -0,0 +1,74 @@
+// A compute API in the shape of the CUDA runtime's stream interface, cut
+// down to what the example needs. Work is queued on a stream and the call
+// returns at once; the device runs each stream's work in order, and
+// different streams' work in any order. Device memory is not addressable
+// from the host: it is reached only through the copies.
+//
+// sim.c implements it on the CPU, with a thread per stream standing in
+// for the device, and cuda/gpu_cuda.cu with the CUDA runtime; the `cuda`
+// build tag picks the latter.
+//
+// Every function returns GPU_SUCCESS or an error code. Work that fails on
+// the device fails its stream: the stream's later work is skipped, and
+// its callbacks and gpuStreamSynchronize report the error, much as CUDA
+// reports a kernel's error at the next call that waits for it.
+#ifndef GPU_H
+#define GPU_H
+
+#include <stddef.h>
+
+enum {
+	GPU_SUCCESS = 0,
+	GPU_ERROR_INVALID_VALUE = 1,
+	GPU_ERROR_MEMORY_ALLOCATION = 2,
+	GPU_ERROR_ILLEGAL_ADDRESS = 3,
+	GPU_ERROR_NO_DEVICE = 4,
+	GPU_ERROR_UNKNOWN = 5,
+};
+
+typedef struct gpuStream gpuStream;
+
+// A completion callback runs once the work queued on the stream before
+// it has finished, with that work's status, on a thread of the runtime's
+// own. It must not call into the API, as with cudaStreamAddCallback, and
+// while it runs the stream's later work waits.
+typedef void (*gpuCallback)(gpuStream *s, int status, void *user);
+
+const char *gpuErrorString(int err);
+
+// gpuDeviceName writes the device's name, NUL-terminated, to the n bytes
+// at name.
+int gpuDeviceName(char *name, size_t n);
+
+// gpuMalloc allocates n bytes of device memory, gpuFree frees it once all
+// work queued on every stream has finished.
+int gpuMalloc(void **p, size_t n);
+int gpuFree(void *p);
+
+// gpuHostAlloc allocates n bytes of page-locked host memory, which the
+// device reads and writes without a staging copy, so that copies to and
+// from it overlap with other work; gpuHostFree frees it.
+int gpuHostAlloc(void **p, size_t n);
+int gpuHostFree(void *p);
+
+int gpuStreamCreate(gpuStream **s);
+// gpuStreamDestroy waits for the stream's work and callbacks to finish,
+// and frees it.
+int gpuStreamDestroy(gpuStream *s);
+
+// Queued copies of n bytes. The host memory must stay valid, and
+// unchanged for a copy to the device, until the copy has run.
+int gpuMemcpyToDevice(void *dst, const void *src, size_t n, gpuStream *s);
+int gpuMemcpyToHost(void *dst, const void *src, size_t n, gpuStream *s);
+
+// gpuSaxpy queues the kernel y[i] = a*x[i] + y[i] for i < n over device
+// memory.
+int gpuSaxpy(float a, const float *x, float *y, size_t n, gpuStream *s);
+
+int gpuStreamAddCallback(gpuStream *s, gpuCallback cb, void *user);
+
+// gpuStreamSynchronize waits for the work queued so far, and returns its
+// status.
+int gpuStreamSynchronize(gpuStream *s);
+
+#endif
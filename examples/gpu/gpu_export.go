@@ -0,0 +1,30 @@
+package gpu
+
+// A file containing //export directives may only declare C functions in
+// its preamble, not define them, which is why addCallback lives in
+// gpu.go.
+
+// #include "gpu.h"
+import "C"
+
+import (
+	"unsafe"
+
+	"github.com/lxwagn/using-go-with-c-libraries/pkg/handles"
+)
+
+// goStreamCallback runs on the compute runtime's callback thread, which
+// Go did not create, so each call first binds the thread to an M of the
+// Go runtime, as any callback from such a thread does. Each callback is
+// queued to run once, so its handle is deleted with it.
+//
+//export goStreamCallback
+func goStreamCallback(s *C.gpuStream, status C.int, user unsafe.Pointer) {
+	h := handles.FromUintptr[func(error)](uintptr(user))
+	fn, err := h.Get()
+	if err != nil {
+		return
+	}
+	h.Delete()
+	fn(check("stream", status))
+}
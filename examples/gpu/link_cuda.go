@@ -0,0 +1,10 @@
+//go:build cuda
+
+package gpu
+
+// Link the CUDA implementation of gpu.h, built into cuda/ by make cuda at
+// the top level, and the CUDA runtime. CGO_LDFLAGS=-L<dir> finds the
+// runtime if it is elsewhere than /usr/local/cuda.
+
+// #cgo LDFLAGS: -L${SRCDIR}/cuda -lgpucuda -L/usr/local/cuda/lib64 -lcudart -lstdc++
+import "C"
@@ -0,0 +1,302 @@
+//go:build !cuda
+
+// The API of gpu.h on the CPU. Each stream has a thread that runs its
+// queue in order, which is what makes the calls asynchronous; device
+// memory is ordinary heap memory, but every access to it is checked
+// against the live allocations, so that a kernel or copy out of bounds
+// fails the stream with GPU_ERROR_ILLEGAL_ADDRESS as it would on a GPU.
+
+#include "gpu.h"
+
+#include <pthread.h>
+#include <stdint.h>
+#include <stdio.h>
+#include <stdlib.h>
+#include <string.h>
+#include <sys/mman.h>
+
+enum { OP_TO_DEVICE, OP_TO_HOST, OP_SAXPY, OP_CALLBACK };
+
+struct op {
+	int kind;
+	void *dst;
+	const void *src;
+	size_t n;
+	float a;
+	gpuCallback cb;
+	void *user;
+	struct op *next;
+};
+
+struct gpuStream {
+	pthread_mutex_t mu;
+	pthread_cond_t work, idle;
+	struct op *head, *tail;
+	int busy, stop, status;
+	pthread_t thread;
+	gpuStream *next; // in the list of streams
+};
+
+// The device allocations and the streams, each list under its lock.
+struct alloc {
+	uintptr_t p;
+	size_t n;
+	struct alloc *next;
+};
+
+static pthread_mutex_t allocMu = PTHREAD_MUTEX_INITIALIZER;
+static struct alloc *allocs;
+
+static pthread_mutex_t streamsMu = PTHREAD_MUTEX_INITIALIZER;
+static gpuStream *streams;
+
+int gpuDeviceName(char *name, size_t n) {
+	if (name == NULL || n == 0)
+		return GPU_ERROR_INVALID_VALUE;
+	snprintf(name, n, "CPU simulator");
+	return GPU_SUCCESS;
+}
+
+// inDevice reports whether the n bytes at p lie within one live device
+// allocation.
+static int inDevice(const void *p, size_t n) {
+	uintptr_t q = (uintptr_t)p;
+	int ok = 0;
+
+	pthread_mutex_lock(&allocMu);
+	for (struct alloc *a = allocs; a != NULL; a = a->next) {
+		if (q >= a->p && n <= a->n && q - a->p <= a->n - n) {
+			ok = 1;
+			break;
+		}
+	}
+	pthread_mutex_unlock(&allocMu);
+	return ok;
+}
+
+int gpuMalloc(void **p, size_t n) {
+	struct alloc *a;
+
+	if (p == NULL || n == 0)
+		return GPU_ERROR_INVALID_VALUE;
+	if ((a = malloc(sizeof *a)) == NULL)
+		return GPU_ERROR_MEMORY_ALLOCATION;
+	if ((*p = malloc(n)) == NULL) {
+		free(a);
+		return GPU_ERROR_MEMORY_ALLOCATION;
+	}
+	a->p = (uintptr_t)*p;
+	a->n = n;
+	pthread_mutex_lock(&allocMu);
+	a->next = allocs;
+	allocs = a;
+	pthread_mutex_unlock(&allocMu);
+	return GPU_SUCCESS;
+}
+
+int gpuFree(void *p) {
+	struct alloc **ap, *a = NULL;
+
+	if (p == NULL)
+		return GPU_SUCCESS;
+	// Like cudaFree, wait for the device to be done with everything.
+	pthread_mutex_lock(&streamsMu);
+	for (gpuStream *s = streams; s != NULL; s = s->next)
+		gpuStreamSynchronize(s);
+	pthread_mutex_unlock(&streamsMu);
+
+	pthread_mutex_lock(&allocMu);
+	for (ap = &allocs; *ap != NULL; ap = &(*ap)->next) {
+		if ((*ap)->p == (uintptr_t)p) {
+			a = *ap;
+			*ap = a->next;
+			break;
+		}
+	}
+	pthread_mutex_unlock(&allocMu);
+	if (a == NULL)
+		return GPU_ERROR_INVALID_VALUE;
+	free(a);
+	free(p);
+	return GPU_SUCCESS;
+}
+
+// Without privileges mlock may fail, or be limited to a few pages; the
+// memory then works the same, only pageable.
+int gpuHostAlloc(void **p, size_t n) {
+	if (p == NULL || n == 0)
+		return GPU_ERROR_INVALID_VALUE;
+	if (posix_memalign(p, 4096, n) != 0)
+		return GPU_ERROR_MEMORY_ALLOCATION;
+	mlock(*p, n);
+	return GPU_SUCCESS;
+}
+
+int gpuHostFree(void *p) {
+	free(p);
+	return GPU_SUCCESS;
+}
+
+// run runs a copy or kernel, and returns its status.
+static int run(struct op *o) {
+	switch (o->kind) {
+	case OP_TO_DEVICE:
+		if (!inDevice(o->dst, o->n))
+			return GPU_ERROR_ILLEGAL_ADDRESS;
+		memcpy(o->dst, o->src, o->n);
+		break;
+	case OP_TO_HOST:
+		if (!inDevice(o->src, o->n))
+			return GPU_ERROR_ILLEGAL_ADDRESS;
+		memcpy(o->dst, o->src, o->n);
+		break;
+	case OP_SAXPY: {
+		const float *x = o->src;
+		float *y = o->dst;
+		if (!inDevice(x, o->n * sizeof(float)) || !inDevice(y, o->n * sizeof(float)))
+			return GPU_ERROR_ILLEGAL_ADDRESS;
+		for (size_t i = 0; i < o->n; i++)
+			y[i] = o->a * x[i] + y[i];
+		break;
+	}
+	}
+	return GPU_SUCCESS;
+}
+
+// worker is the stream's device thread. After an error the stream's
+// copies and kernels are skipped; its callbacks still run, with the
+// error.
+static void *worker(void *arg) {
+	gpuStream *s = arg;
+
+	pthread_mutex_lock(&s->mu);
+	for (;;) {
+		while (s->head == NULL && !s->stop)
+			pthread_cond_wait(&s->work, &s->mu);
+		if (s->head == NULL)
+			break;
+		struct op *o = s->head;
+		if ((s->head = o->next) == NULL)
+			s->tail = NULL;
+		s->busy = 1;
+		int status = s->status;
+		pthread_mutex_unlock(&s->mu);
+
+		if (o->kind == OP_CALLBACK)
+			o->cb(s, status, o->user);
+		else if (status == GPU_SUCCESS)
+			status = run(o);
+		free(o);
+
+		pthread_mutex_lock(&s->mu);
+		s->status = status;
+		s->busy = 0;
+		if (s->head == NULL)
+			pthread_cond_broadcast(&s->idle);
+	}
+	pthread_mutex_unlock(&s->mu);
+	return NULL;
+}
+
+int gpuStreamCreate(gpuStream **sp) {
+	gpuStream *s;
+
+	if (sp == NULL)
+		return GPU_ERROR_INVALID_VALUE;
+	if ((s = calloc(1, sizeof *s)) == NULL)
+		return GPU_ERROR_MEMORY_ALLOCATION;
+	pthread_mutex_init(&s->mu, NULL);
+	pthread_cond_init(&s->work, NULL);
+	pthread_cond_init(&s->idle, NULL);
+	if (pthread_create(&s->thread, NULL, worker, s) != 0) {
+		free(s);
+		return GPU_ERROR_MEMORY_ALLOCATION;
+	}
+	pthread_mutex_lock(&streamsMu);
+	s->next = streams;
+	streams = s;
+	pthread_mutex_unlock(&streamsMu);
+	*sp = s;
+	return GPU_SUCCESS;
+}
+
+int gpuStreamDestroy(gpuStream *s) {
+	if (s == NULL)
+		return GPU_ERROR_INVALID_VALUE;
+	pthread_mutex_lock(&streamsMu);
+	for (gpuStream **sp = &streams; *sp != NULL; sp = &(*sp)->next) {
+		if (*sp == s) {
+			*sp = s->next;
+			break;
+		}
+	}
+	pthread_mutex_unlock(&streamsMu);
+
+	pthread_mutex_lock(&s->mu);
+	s->stop = 1;
+	pthread_cond_signal(&s->work);
+	pthread_mutex_unlock(&s->mu);
+	pthread_join(s->thread, NULL);
+	pthread_mutex_destroy(&s->mu);
+	pthread_cond_destroy(&s->work);
+	pthread_cond_destroy(&s->idle);
+	free(s);
+	return GPU_SUCCESS;
+}
+
+static int enqueue(gpuStream *s, struct op o) {
+	struct op *p;
+
+	if (s == NULL)
+		return GPU_ERROR_INVALID_VALUE;
+	if ((p = malloc(sizeof *p)) == NULL)
+		return GPU_ERROR_MEMORY_ALLOCATION;
+	*p = o;
+	p->next = NULL;
+	pthread_mutex_lock(&s->mu);
+	if (s->tail != NULL)
+		s->tail->next = p;
+	else
+		s->head = p;
+	s->tail = p;
+	pthread_cond_signal(&s->work);
+	pthread_mutex_unlock(&s->mu);
+	return GPU_SUCCESS;
+}
+
+int gpuMemcpyToDevice(void *dst, const void *src, size_t n, gpuStream *s) {
+	if (dst == NULL || (src == NULL && n > 0))
+		return GPU_ERROR_INVALID_VALUE;
+	return enqueue(s, (struct op){.kind = OP_TO_DEVICE, .dst = dst, .src = src, .n = n});
+}
+
+int gpuMemcpyToHost(void *dst, const void *src, size_t n, gpuStream *s) {
+	if ((dst == NULL && n > 0) || src == NULL)
+		return GPU_ERROR_INVALID_VALUE;
+	return enqueue(s, (struct op){.kind = OP_TO_HOST, .dst = dst, .src = src, .n = n});
+}
+
+int gpuSaxpy(float a, const float *x, float *y, size_t n, gpuStream *s) {
+	if (x == NULL || y == NULL)
+		return GPU_ERROR_INVALID_VALUE;
+	return enqueue(s, (struct op){.kind = OP_SAXPY, .dst = y, .src = x, .n = n, .a = a});
+}
+
+int gpuStreamAddCallback(gpuStream *s, gpuCallback cb, void *user) {
+	if (cb == NULL)
+		return GPU_ERROR_INVALID_VALUE;
+	return enqueue(s, (struct op){.kind = OP_CALLBACK, .cb = cb, .user = user});
+}
+
+int gpuStreamSynchronize(gpuStream *s) {
+	int status;
+
+	if (s == NULL)
+		return GPU_ERROR_INVALID_VALUE;
+	pthread_mutex_lock(&s->mu);
+	while (s->head != NULL || s->busy)
+		pthread_cond_wait(&s->idle, &s->mu);
+	status = s->status;
+	pthread_mutex_unlock(&s->mu);
+	return status;
+}
@@ -0,0 +1,25 @@
+// Package imagec decodes PNG images with libpng, as an example of the
+// ways to hand a large buffer filled by C to Go.
+//
+// Decode leaves the pixels where libpng wrote them, in C memory, and
+// returns an Image whose embedded *image.NRGBA is a view of that memory:
+// nothing is copied, and the garbage collector neither scans the pixels
+// nor counts them towards its heap goal. The price is that the caller
+// owns the memory and must Free it, after which the view is gone:
+//
+//	img, err := imagec.Decode(data)
+//	...
+//	defer img.Free()
+//	draw.Draw(dst, r, img, image.Point{}, draw.Src)
+//
+// Copy moves an Image into Go memory for keeping. DecodeNRGBA gets there
+// directly by handing libpng a buffer allocated by Go, which is allowed
+// because the buffer holds no Go pointers and libpng only writes it
+// during the call; it is the simplest choice whenever the decoder takes
+// the caller's buffer, and the only copy-free one that is also safe to
+// forget about.
+//
+// The package links against libpng, found with pkg-config, through its
+// simplified API, which converts every PNG to 8-bit non-premultiplied
+// RGBA.
+package imagec
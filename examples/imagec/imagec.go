@@ -0,0 +1,170 @@
+package imagec
+
+/*
+
+#cgo pkg-config: libpng
+#include <png.h>
+#include <string.h>
+
+// begin reads the header of the PNG in data and asks for RGBA output.
+// libpng goes on using data, and keeps pointers to img, until the image
+// is finished or freed.
+static int begin(png_image *img, const void *data, size_t n) {
+	memset(img, 0, sizeof *img);
+	img->version = PNG_IMAGE_VERSION;
+	if (!png_image_begin_read_from_memory(img, data, n))
+		return 0;
+	img->format = PNG_FORMAT_RGBA;
+	return 1;
+}
+
+// png_image is an anonymous struct, which cgo cannot pass to libpng's
+// own functions, so those get wrappers.
+static void freeImage(png_image *img) {
+	png_image_free(img);
+}
+
+static int finish(png_image *img, void *pix) {
+	return png_image_finish_read(img, NULL, pix, 0, NULL);
+}
+
+*/
+import "C"
+
+import (
+	"errors"
+	"image"
+	"image/color"
+	"runtime"
+	"unsafe"
+
+	"github.com/lxwagn/using-go-with-c-libraries/pkg/cmem"
+)
+
+var (
+	// ErrFormat is returned for data that does not start with the PNG
+	// signature.
+	ErrFormat = errors.New("imagec: not a PNG image")
+	// ErrTooLarge is returned for images of more than MaxPixels pixels.
+	ErrTooLarge = errors.New("imagec: image too large")
+)
+
+// MaxPixels is the largest image, in pixels, that the package decodes:
+// 1 GiB of RGBA.
+const MaxPixels = 1 << 28
+
+const signature = "\x89PNG\r\n\x1a\n"
+
+// An Image is a decoded image whose pixels are in C memory. The embedded
+// NRGBA, and any SubImage of it, is valid until Free.
+type Image struct {
+	*image.NRGBA
+	pix unsafe.Pointer
+}
+
+// Decode decodes the PNG in data into C memory.
+func Decode(data []byte) (*Image, error) {
+	m := &Image{}
+	nrgba, err := decode(data, func(size int) []byte {
+		m.pix = cmem.Malloc(size)
+		return unsafe.Slice((*byte)(m.pix), size)
+	})
+	if err != nil {
+		cmem.Free(m.pix)
+		return nil, err
+	}
+	m.NRGBA = nrgba
+	return m, nil
+}
+
+// Free frees the pixels. The Image's Pix, and that of every view taken
+// from it, is set to nil, so a later use panics instead of reading freed
+// memory; a copy of the Pix slice made earlier is not and must not be
+// used either. Free may be called more than once.
+func (m *Image) Free() {
+	if m.pix == nil {
+		return
+	}
+	m.NRGBA.Pix = nil
+	cmem.Free(m.pix)
+	m.pix = nil
+}
+
+// Copy returns a copy of the image in Go memory.
+func (m *Image) Copy() *image.NRGBA {
+	c := *m.NRGBA
+	c.Pix = append([]byte(nil), m.NRGBA.Pix...)
+	return &c
+}
+
+// DecodeNRGBA decodes the PNG in data straight into Go memory.
+func DecodeNRGBA(data []byte) (*image.NRGBA, error) {
+	return decode(data, func(size int) []byte {
+		return make([]byte, size)
+	})
+}
+
+// DecodeConfig returns the dimensions of the PNG in data without
+// decoding it. The color model is always NRGBA, which the package
+// converts every image to.
+func DecodeConfig(data []byte) (image.Config, error) {
+	h, err := newHeader(data)
+	if err != nil {
+		return image.Config{}, err
+	}
+	defer h.free()
+	return image.Config{ColorModel: color.NRGBAModel, Width: int(h.img.width), Height: int(h.img.height)}, nil
+}
+
+// A header is a PNG whose header libpng has read. libpng keeps a pointer
+// to the data from the first call to the last, so the data stays pinned
+// in between, and the png_image it points back to is in C memory.
+type header struct {
+	img    *C.png_image
+	pinner runtime.Pinner
+}
+
+func newHeader(data []byte) (*header, error) {
+	if len(data) < len(signature) || string(data[:len(signature)]) != signature {
+		return nil, ErrFormat
+	}
+	h := &header{img: (*C.png_image)(cmem.Calloc(1, C.sizeof_png_image))}
+	h.pinner.Pin(&data[0])
+	if C.begin(h.img, unsafe.Pointer(&data[0]), C.size_t(len(data))) == 0 {
+		err := h.err()
+		h.free()
+		return nil, err
+	}
+	return h, nil
+}
+
+func (h *header) err() error {
+	return errors.New("imagec: " + C.GoString(&h.img.message[0]))
+}
+
+func (h *header) free() {
+	C.freeImage(h.img)
+	cmem.Free(unsafe.Pointer(h.img))
+	h.pinner.Unpin()
+}
+
+// decode decodes data into the buffer alloc returns for size bytes.
+func decode(data []byte, alloc func(size int) []byte) (*image.NRGBA, error) {
+	h, err := newHeader(data)
+	if err != nil {
+		return nil, err
+	}
+	defer h.free()
+
+	w, ht := int(h.img.width), int(h.img.height)
+	if uint64(w)*uint64(ht) > MaxPixels {
+		return nil, ErrTooLarge
+	}
+	pix := alloc(w * ht * 4)
+	// pix holds no Go pointers, so it may be passed to C for the call
+	// even when it is Go memory.
+	if C.finish(h.img, unsafe.Pointer(unsafe.SliceData(pix))) == 0 {
+		return nil, h.err()
+	}
+	return &image.NRGBA{Pix: pix, Stride: w * 4, Rect: image.Rect(0, 0, w, ht)}, nil
+}
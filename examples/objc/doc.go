@@ -0,0 +1,25 @@
+// Package objc calls Objective-C APIs in Apple's Foundation framework
+// from Go, through a shim of C functions written in Objective-C. It
+// builds only on darwin.
+//
+// cgo compiles the package's .m files with clang alongside the Go code,
+// so the shim in shim_darwin.m can use any Objective-C API while offering
+// cgo a plain C interface, declared in shim.h, of void pointers, C
+// strings and numbers. The shim is compiled with ARC, and its bridging
+// casts decide who owns each object: a function that returns an object to
+// Go transfers a reference (__bridge_retained), which the Go value
+// holding it gives back with CFRelease in a cleanup.
+//
+// Objective-C methods routinely return autoreleased objects, which live
+// until the thread's innermost autorelease pool is drained. Threads the
+// Go runtime creates have no pool, so without one such objects are never
+// freed; each shim function therefore runs inside its own
+// @autoreleasepool block, and copies what Go needs out of the pool's
+// objects before returning. WithAutoreleasePool provides a pool that
+// spans several calls, on one locked OS thread, for code that needs
+// autoreleased objects to outlive a single call.
+//
+// Strings cross as UTF-8: NewString copies the bytes of a Go string into
+// an NSString, and String copies an NSString's UTF-8 form back into Go
+// memory.
+package objc
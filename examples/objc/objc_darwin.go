@@ -0,0 +1,125 @@
+package objc
+
+/*
+
+#cgo CFLAGS: -fobjc-arc
+#cgo LDFLAGS: -framework Foundation
+#include "shim.h"
+
+*/
+import "C"
+
+import (
+	"errors"
+	"runtime"
+	"unsafe"
+
+	"github.com/lxwagn/using-go-with-c-libraries/pkg/cmem"
+)
+
+// ErrInvalidUTF8 is returned by NewString for a string that is not valid
+// UTF-8, which NSString refuses.
+var ErrInvalidUTF8 = errors.New("objc: string is not valid UTF-8")
+
+// A String holds a reference to an NSString, released once the String
+// is unreachable.
+type String struct {
+	ref unsafe.Pointer
+}
+
+func newString(ref unsafe.Pointer) *String {
+	s := &String{ref}
+	runtime.AddCleanup(s, func(ref unsafe.Pointer) { C.objcRelease(ref) }, ref)
+	return s
+}
+
+// NewString returns an NSString holding a copy of s.
+func NewString(s string) (*String, error) {
+	// NSString copies the bytes during the call, so they are passed
+	// straight from the Go string. An empty string still needs a
+	// valid pointer.
+	p := unsafe.StringData(s)
+	if p == nil {
+		p = unsafe.StringData("\x00")
+	}
+	ref := C.objcStringNew((*C.char)(unsafe.Pointer(p)), C.size_t(len(s)))
+	if ref == nil {
+		return nil, ErrInvalidUTF8
+	}
+	return newString(ref), nil
+}
+
+// String returns the NSString as a Go string.
+func (s *String) String() string {
+	var n C.size_t
+	p := C.objcStringUTF8(s.ref, &n)
+	runtime.KeepAlive(s)
+	defer cmem.Free(unsafe.Pointer(p))
+	return C.GoStringN(p, C.int(n))
+}
+
+// Len returns the NSString's length, which is in UTF-16 code units.
+func (s *String) Len() int {
+	n := C.objcStringLength(s.ref)
+	runtime.KeepAlive(s)
+	return int(n)
+}
+
+// Upper returns the NSString in upper case by the rules of locale, an
+// identifier such as "en_US" or "tr_TR".
+func (s *String) Upper(locale string) *String {
+	cl := (*C.char)(cmem.CString(locale))
+	defer cmem.Free(unsafe.Pointer(cl))
+	ref := C.objcStringUppercase(s.ref, cl)
+	runtime.KeepAlive(s)
+	return newString(ref)
+}
+
+// Compare compares a and b the way the Finder sorts file names, with
+// localizedStandardCompare:, and returns -1, 0 or 1.
+func Compare(a, b *String) int {
+	r := C.objcStringCompare(a.ref, b.ref)
+	runtime.KeepAlive(a)
+	runtime.KeepAlive(b)
+	return int(r)
+}
+
+// takeString returns the Go copy of a malloc'd string from the shim and
+// frees the original.
+func takeString(p *C.char) string {
+	defer cmem.Free(unsafe.Pointer(p))
+	return C.GoString(p)
+}
+
+// HostName returns the host name from NSProcessInfo.
+func HostName() string {
+	return takeString(C.objcHostName())
+}
+
+// OSVersion returns NSProcessInfo's description of the operating system
+// version, such as "Version 14.4 (Build 23E214)".
+func OSVersion() string {
+	return takeString(C.objcOSVersion())
+}
+
+// FormatNumber formats v with NSNumberFormatter's decimal style for
+// locale.
+func FormatNumber(v float64, locale string) string {
+	cl := (*C.char)(cmem.CString(locale))
+	defer cmem.Free(unsafe.Pointer(cl))
+	return takeString(C.objcFormatNumber(C.double(v), cl))
+}
+
+// WithAutoreleasePool runs fn with an autorelease pool that is drained
+// when fn returns, for code that makes several calls into Objective-C
+// through shims of its own and needs autoreleased objects to live from
+// one call to the next. A pool belongs to a thread, so fn runs locked to
+// the calling goroutine's thread and must make its Objective-C calls on
+// that goroutine.
+func WithAutoreleasePool(fn func()) {
+	runtime.LockOSThread()
+	defer runtime.UnlockOSThread()
+	pool := C.objcPoolPush()
+	defer C.objcPoolPop(pool)
+	fn()
+}
@@ -0,0 +1,28 @@
+// The C interface of shim_darwin.m. Objects are passed as void pointers;
+// a function documented as returning a reference gives the caller one,
+// to be released with objcRelease. Strings returned as char * are
+// malloc'd UTF-8 copies for the caller to free.
+#ifndef OBJC_SHIM_H
+#define OBJC_SHIM_H
+
+#include <stddef.h>
+
+// objcStringNew returns a reference to an NSString holding the n bytes
+// of UTF-8 at p, or NULL if they are not valid UTF-8.
+void *objcStringNew(const char *p, size_t n);
+char *objcStringUTF8(void *s, size_t *n);
+size_t objcStringLength(void *s);
+// objcStringUppercase returns a reference.
+void *objcStringUppercase(void *s, const char *locale);
+int objcStringCompare(void *a, void *b);
+void objcRelease(void *o);
+
+char *objcHostName(void);
+char *objcOSVersion(void);
+char *objcFormatNumber(double v, const char *locale);
+
+// Push and pop an autorelease pool on the calling thread.
+void *objcPoolPush(void);
+void objcPoolPop(void *pool);
+
+#endif
@@ -0,0 +1,91 @@
+#import <Foundation/Foundation.h>
+
+#include <stdlib.h>
+#include <string.h>
+
+#include "shim.h"
+
+// The runtime's pool functions, which @autoreleasepool compiles to. They
+// are exported by libobjc but not declared in its public headers.
+extern void *objc_autoreleasePoolPush(void);
+extern void objc_autoreleasePoolPop(void *pool);
+
+// copyUTF8 returns a malloc'd, NUL-terminated UTF-8 copy of s and its
+// length without the NUL. The NSData it goes through is autoreleased, so
+// callers run it inside a pool.
+static char *copyUTF8(NSString *s, size_t *n) {
+	NSData *d = [s dataUsingEncoding:NSUTF8StringEncoding];
+	char *p = malloc(d.length + 1);
+	if (p == NULL)
+		return NULL;
+	memcpy(p, d.bytes, d.length);
+	p[d.length] = 0;
+	if (n != NULL)
+		*n = d.length;
+	return p;
+}
+
+void *objcStringNew(const char *p, size_t n) {
+	@autoreleasepool {
+		NSString *s = [[NSString alloc] initWithBytes:p length:n encoding:NSUTF8StringEncoding];
+		return (__bridge_retained void *)s;
+	}
+}
+
+char *objcStringUTF8(void *s, size_t *n) {
+	@autoreleasepool {
+		return copyUTF8((__bridge NSString *)s, n);
+	}
+}
+
+size_t objcStringLength(void *s) {
+	return ((__bridge NSString *)s).length;
+}
+
+void *objcStringUppercase(void *s, const char *locale) {
+	@autoreleasepool {
+		NSLocale *l = [NSLocale localeWithLocaleIdentifier:@(locale)];
+		return (__bridge_retained void *)[(__bridge NSString *)s uppercaseStringWithLocale:l];
+	}
+}
+
+int objcStringCompare(void *a, void *b) {
+	@autoreleasepool {
+		return (int)[(__bridge NSString *)a localizedStandardCompare:(__bridge NSString *)b];
+	}
+}
+
+void objcRelease(void *o) {
+	CFRelease(o);
+}
+
+char *objcHostName(void) {
+	@autoreleasepool {
+		return copyUTF8(NSProcessInfo.processInfo.hostName, NULL);
+	}
+}
+
+char *objcOSVersion(void) {
+	@autoreleasepool {
+		return copyUTF8(NSProcessInfo.processInfo.operatingSystemVersionString, NULL);
+	}
+}
+
+char *objcFormatNumber(double v, const char *locale) {
+	@autoreleasepool {
+		NSNumberFormatter *f = [NSNumberFormatter new];
+		f.numberStyle = NSNumberFormatterDecimalStyle;
+		f.maximumFractionDigits = 6;
+		f.locale = [NSLocale localeWithLocaleIdentifier:@(locale)];
+		return copyUTF8([f stringFromNumber:@(v)], NULL);
+	}
+}
+
+void *objcPoolPush(void) {
+	return objc_autoreleasePoolPush();
+}
+
+void objcPoolPop(void *pool) {
+	objc_autoreleasePoolPop(pool);
+}
+
@@ -0,0 +1,24 @@
+// Package osslhash implements hash.Hash with OpenSSL's EVP digest
+// functions, as an example of a C object whose lifetime is tied to a Go
+// value.
+//
+// Each hash owns an EVP_MD_CTX allocated by OpenSSL. There is no Close
+// in hash.Hash to free it with, so the context is freed by a cleanup
+// attached with runtime.AddCleanup once the hash is no longer reachable,
+// and every method keeps the hash alive until its C call has returned:
+//
+//	h, err := osslhash.New("SHA3-256")
+//	...
+//	h.Write(data)
+//	sum := h.Sum(nil)
+//
+// The results are byte for byte those of crypto/sha256, crypto/sha512
+// and the other Go implementations of the same algorithms.
+//
+// OpenSSL queues its error reports per thread, and a goroutine may move
+// to another thread between two cgo calls, so the C helpers here collect
+// the error in the same call that failed.
+//
+// The package links against OpenSSL 3's libcrypto, found with
+// pkg-config.
+package osslhash
@@ -0,0 +1,7 @@
+//go:build race || asan || msan
+
+package osslhash_test
+
+// The race detector and the sanitizers keep shadow memory and quarantine
+// freed blocks, so the resident set size tells nothing about leaks.
+const heapInstrumented = true
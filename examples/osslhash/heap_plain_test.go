@@ -0,0 +1,5 @@
+//go:build !race && !asan && !msan
+
+package osslhash_test
+
+const heapInstrumented = false
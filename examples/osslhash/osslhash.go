@@ -0,0 +1,243 @@
+package osslhash
+
+/*
+
+#cgo pkg-config: libcrypto
+#include <openssl/err.h>
+#include <openssl/evp.h>
+
+// Each helper returns 0 or the OpenSSL error code for its failure, taken
+// from this thread's error queue before anything else can use it.
+static unsigned long opensslError(void) {
+	unsigned long e = ERR_peek_last_error();
+	ERR_clear_error();
+	return e ? e : ERR_PACK(ERR_LIB_EVP, 0, ERR_R_INTERNAL_ERROR);
+}
+
+static unsigned long fetchDigest(const char *name, EVP_MD **md) {
+	*md = EVP_MD_fetch(NULL, name, NULL);
+	return *md ? 0 : opensslError();
+}
+
+static unsigned long newContext(const EVP_MD *md, EVP_MD_CTX **ctx) {
+	*ctx = EVP_MD_CTX_new();
+	if (!*ctx)
+		return opensslError();
+	if (!EVP_DigestInit_ex(*ctx, md, NULL)) {
+		EVP_MD_CTX_free(*ctx);
+		*ctx = NULL;
+		return opensslError();
+	}
+	return 0;
+}
+
+static unsigned long reset(EVP_MD_CTX *ctx, const EVP_MD *md) {
+	return EVP_DigestInit_ex(ctx, md, NULL) ? 0 : opensslError();
+}
+
+static unsigned long update(EVP_MD_CTX *ctx, const void *p, size_t n) {
+	return EVP_DigestUpdate(ctx, p, n) ? 0 : opensslError();
+}
+
+static unsigned long copyContext(EVP_MD_CTX *dst, const EVP_MD_CTX *src) {
+	return EVP_MD_CTX_copy_ex(dst, src) ? 0 : opensslError();
+}
+
+// final finishes a copy of ctx, so that ctx can go on taking input. tmp
+// is a context to make the copy in.
+static unsigned long final(EVP_MD_CTX *ctx, EVP_MD_CTX *tmp, unsigned char *out) {
+	if (!EVP_MD_CTX_copy_ex(tmp, ctx) || !EVP_DigestFinal_ex(tmp, out, NULL))
+		return opensslError();
+	return 0;
+}
+
+*/
+import "C"
+
+import (
+	"errors"
+	"hash"
+	"runtime"
+	"strings"
+	"sync"
+	"unsafe"
+
+	"github.com/lxwagn/using-go-with-c-libraries/pkg/cmem"
+)
+
+// An Error is a failed OpenSSL call.
+type Error struct {
+	Op   string // the OpenSSL function that failed
+	Code uint64 // the packed code from ERR_get_error
+}
+
+func (e *Error) Error() string {
+	var buf [256]C.char
+	C.ERR_error_string_n(C.ulong(e.Code), &buf[0], C.size_t(len(buf)))
+	return "osslhash: " + e.Op + ": " + C.GoString(&buf[0])
+}
+
+func check(op string, e C.ulong) error {
+	if e == 0 {
+		return nil
+	}
+	return &Error{Op: op, Code: uint64(e)}
+}
+
+// The C objects behind a digest, freed together by its cleanup.
+type evp struct {
+	md       *C.EVP_MD
+	ctx, tmp *C.EVP_MD_CTX
+}
+
+func (e evp) free() {
+	C.EVP_MD_CTX_free(e.tmp)
+	C.EVP_MD_CTX_free(e.ctx)
+	C.EVP_MD_free(e.md)
+}
+
+type digest struct {
+	evp
+	size, blockSize int
+}
+
+// New returns a hash computing the digest OpenSSL calls name, such as
+// "SHA256", "SHA512-256", "SHA3-256" or "BLAKE2b512". It fails if the
+// loaded providers have no such digest. The hash also implements
+// hash.Cloner.
+func New(name string) (hash.Hash, error) {
+	if strings.IndexByte(name, 0) >= 0 {
+		return nil, errors.New("osslhash: digest name contains NUL byte")
+	}
+	md, err := fetch(name)
+	if err != nil {
+		return nil, err
+	}
+	return newDigest(evp{md: md})
+}
+
+// Fetching a digest means a search of the loaded providers, which costs
+// more than hashing a short input, so each EVP_MD is fetched once and
+// kept for good.
+var digests sync.Map // name -> *C.EVP_MD
+
+// fetch returns the EVP_MD called name with a reference for the caller.
+func fetch(name string) (*C.EVP_MD, error) {
+	v, ok := digests.Load(name)
+	if !ok {
+		cname := (*C.char)(cmem.CString(name))
+		defer cmem.Free(unsafe.Pointer(cname))
+
+		var md *C.EVP_MD
+		if err := check("EVP_MD_fetch", C.fetchDigest(cname, &md)); err != nil {
+			return nil, err
+		}
+		if v, ok = digests.LoadOrStore(name, md); ok {
+			C.EVP_MD_free(md)
+		}
+	}
+	md := v.(*C.EVP_MD)
+	if C.EVP_MD_up_ref(md) == 0 {
+		return nil, errors.New("osslhash: EVP_MD_up_ref failed")
+	}
+	return md, nil
+}
+
+func newDigest(e evp) (*digest, error) {
+	if err := check("EVP_DigestInit_ex", C.newContext(e.md, &e.ctx)); err != nil {
+		e.free()
+		return nil, err
+	}
+	if e.tmp = C.EVP_MD_CTX_new(); e.tmp == nil {
+		e.free()
+		return nil, errors.New("osslhash: EVP_MD_CTX_new failed")
+	}
+	d := &digest{
+		evp:       e,
+		size:      int(C.EVP_MD_get_size(e.md)),
+		blockSize: int(C.EVP_MD_get_block_size(e.md)),
+	}
+	runtime.AddCleanup(d, evp.free, e)
+	return d, nil
+}
+
+// NewSHA256 returns a SHA-256 hash, the same as crypto/sha256.New's.
+func NewSHA256() hash.Hash {
+	return mustNew("SHA256")
+}
+
+// NewSHA512 returns a SHA-512 hash, the same as crypto/sha512.New's.
+func NewSHA512() hash.Hash {
+	return mustNew("SHA512")
+}
+
+func mustNew(name string) hash.Hash {
+	h, err := New(name)
+	if err != nil {
+		// The default provider always has the SHA-2 digests.
+		panic(err)
+	}
+	return h
+}
+
+// Write adds p to the input. It never returns an error; OpenSSL only
+// fails to update a valid context if it is broken, and then Write
+// panics.
+func (d *digest) Write(p []byte) (int, error) {
+	if len(p) > 0 {
+		// p holds no Go pointers, so it can be passed as it is.
+		err := check("EVP_DigestUpdate", C.update(d.ctx, unsafe.Pointer(unsafe.SliceData(p)), C.size_t(len(p))))
+		runtime.KeepAlive(d)
+		if err != nil {
+			panic(err)
+		}
+	}
+	return len(p), nil
+}
+
+// Sum appends the digest of the input so far to b. It does not change
+// the hash's state.
+func (d *digest) Sum(b []byte) []byte {
+	var out [C.EVP_MAX_MD_SIZE]byte
+	err := check("EVP_DigestFinal_ex", C.final(d.ctx, d.tmp, (*C.uchar)(&out[0])))
+	runtime.KeepAlive(d)
+	if err != nil {
+		panic(err)
+	}
+	return append(b, out[:d.size]...)
+}
+
+// Reset discards the input so far.
+func (d *digest) Reset() {
+	err := check("EVP_DigestInit_ex", C.reset(d.ctx, d.md))
+	runtime.KeepAlive(d)
+	if err != nil {
+		panic(err)
+	}
+}
+
+func (d *digest) Size() int {
+	return d.size
+}
+
+func (d *digest) BlockSize() int {
+	return d.blockSize
+}
+
+// Clone returns a hash with the same state, which goes on independently.
+func (d *digest) Clone() (hash.Cloner, error) {
+	// The clone holds its own reference to the EVP_MD.
+	if C.EVP_MD_up_ref(d.md) == 0 {
+		return nil, errors.New("osslhash: EVP_MD_up_ref failed")
+	}
+	c, err := newDigest(evp{md: d.md})
+	if err != nil {
+		return nil, err
+	}
+	err = check("EVP_MD_CTX_copy_ex", C.copyContext(c.ctx, d.ctx))
+	runtime.KeepAlive(d)
+	if err != nil {
+		return nil, err
+	}
+	return c, nil
+}
@@ -0,0 +1,169 @@
+//go:build cgo
+
+package osslhash_test
+
+import (
+	"bytes"
+	"crypto/md5"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha3"
+	"crypto/sha512"
+	"errors"
+	"hash"
+	"math/rand/v2"
+	"os"
+	"runtime"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/lxwagn/using-go-with-c-libraries/examples/osslhash"
+)
+
+func TestSHA256(t *testing.T) {
+	rng := rand.New(rand.NewPCG(1, 2))
+	data := make([]byte, 1<<20)
+	rand.NewChaCha8([32]byte{}).Read(data)
+	// Sizes around the 64-byte block and the 56-byte padding limit.
+	for _, size := range []int{0, 1, 55, 56, 63, 64, 65, 119, 120, 1000, 1 << 20} {
+		want := sha256.Sum256(data[:size])
+		h := osslhash.NewSHA256()
+		// In chunks of random size, to cross block boundaries every way.
+		for p := data[:size]; len(p) > 0; {
+			n := min(len(p), rng.IntN(200)+1)
+			h.Write(p[:n])
+			p = p[n:]
+		}
+		if got := h.Sum(nil); !bytes.Equal(got, want[:]) {
+			t.Errorf("size %d: %x, want %x", size, got, want)
+		}
+		// Sum appends and leaves the state alone.
+		if got := h.Sum([]byte("prefix")); string(got[:6]) != "prefix" || !bytes.Equal(got[6:], want[:]) {
+			t.Errorf("size %d: second Sum %x", size, got)
+		}
+	}
+
+	h := osslhash.NewSHA256()
+	if h.Size() != sha256.Size || h.BlockSize() != sha256.BlockSize {
+		t.Errorf("Size %d, BlockSize %d", h.Size(), h.BlockSize())
+	}
+	h.Write([]byte("hello, "))
+	h.Sum(nil)
+	h.Write([]byte("world"))
+	if got, want := h.Sum(nil), sha256.Sum256([]byte("hello, world")); !bytes.Equal(got, want[:]) {
+		t.Error("Write after Sum differs")
+	}
+	h.Reset()
+	if got, want := h.Sum(nil), sha256.Sum256(nil); !bytes.Equal(got, want[:]) {
+		t.Error("Reset did not discard the input")
+	}
+}
+
+func TestAlgorithms(t *testing.T) {
+	data := make([]byte, 100_000)
+	rand.NewChaCha8([32]byte{1}).Read(data)
+	for name, goHash := range map[string]func() hash.Hash{
+		"SHA1":       sha1.New,
+		"SHA224":     sha256.New224,
+		"SHA384":     sha512.New384,
+		"SHA512":     sha512.New,
+		"SHA512-256": sha512.New512_256,
+		"SHA3-256":   func() hash.Hash { return sha3.New256() },
+		"SHA3-512":   func() hash.Hash { return sha3.New512() },
+		"MD5":        md5.New,
+	} {
+		h, err := osslhash.New(name)
+		if err != nil {
+			t.Error(err)
+			continue
+		}
+		g := goHash()
+		if h.Size() != g.Size() || h.BlockSize() != g.BlockSize() {
+			t.Errorf("%s: Size %d, BlockSize %d, want %d, %d", name, h.Size(), h.BlockSize(), g.Size(), g.BlockSize())
+		}
+		h.Write(data)
+		g.Write(data)
+		if got, want := h.Sum(nil), g.Sum(nil); !bytes.Equal(got, want) {
+			t.Errorf("%s: %x, want %x", name, got, want)
+		}
+	}
+	if h := osslhash.NewSHA512(); !bytes.Equal(h.Sum(nil), sha512.New().Sum(nil)) {
+		t.Error("NewSHA512 differs")
+	}
+}
+
+func TestClone(t *testing.T) {
+	h := osslhash.NewSHA256()
+	h.Write([]byte("common "))
+	c, err := h.(hash.Cloner).Clone()
+	if err != nil {
+		t.Fatal(err)
+	}
+	h.Write([]byte("one"))
+	c.Write([]byte("two"))
+	for _, tc := range []struct {
+		h    hash.Hash
+		text string
+	}{{h, "common one"}, {c, "common two"}} {
+		if got, want := tc.h.Sum(nil), sha256.Sum256([]byte(tc.text)); !bytes.Equal(got, want[:]) {
+			t.Errorf("%q: %x, want %x", tc.text, got, want)
+		}
+	}
+}
+
+func TestErrors(t *testing.T) {
+	_, err := osslhash.New("NO-SUCH-DIGEST")
+	var e *osslhash.Error
+	if !errors.As(err, &e) || e.Op != "EVP_MD_fetch" {
+		t.Errorf("unknown digest: %v", err)
+	}
+	if _, err := osslhash.New("SHA256\x00"); err == nil {
+		t.Error("name with NUL accepted")
+	}
+}
+
+// TestCleanup checks that unreachable hashes free their OpenSSL memory.
+// Each holds a few hundred bytes of it; if the cleanups did not run, the
+// heap would grow by tens of megabytes.
+func TestCleanup(t *testing.T) {
+	if heapInstrumented {
+		t.Skip("the resident set size tells nothing about leaks in this build")
+	}
+	before, ok := residentBytes()
+	if !ok {
+		t.Skip("no resident set size on this system")
+	}
+	for range 20 {
+		for range 10_000 {
+			h := osslhash.NewSHA256()
+			h.Write([]byte("x"))
+			h.Sum(nil)
+		}
+		runtime.GC()
+	}
+	runtime.GC()
+	after, _ := residentBytes()
+	t.Logf("resident %d KiB before, %d KiB after", before>>10, after>>10)
+	if after-before > 30<<20 {
+		t.Errorf("resident memory grew by %d KiB", (after-before)>>10)
+	}
+}
+
+// residentBytes returns the process's resident set size, where Linux
+// reports it.
+func residentBytes() (int64, bool) {
+	b, err := os.ReadFile("/proc/self/statm")
+	if err != nil {
+		return 0, false
+	}
+	f := strings.Fields(string(b))
+	if len(f) < 2 {
+		return 0, false
+	}
+	pages, err := strconv.ParseInt(f[1], 10, 64)
+	if err != nil {
+		return 0, false
+	}
+	return pages * int64(os.Getpagesize()), true
+}
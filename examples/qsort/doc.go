@@ -0,0 +1,21 @@
+// Package qsort sorts Go slices with the C library's qsort and a Go
+// comparison function, to show what a C API that takes a callback but no
+// userdata pointer costs to use from Go.
+//
+// C cannot be handed a Go func, so qsort is given a C function that calls
+// an exported Go trampoline, which has to find the comparison to run.
+// qsort passes its comparison function nothing but the two elements, so
+// the comparison lives in a package variable, and a mutex lets only one
+// Slice run at a time. An API that passes a userdata pointer, as mySort
+// does, avoids both; pkg/mylib.Sort looks the comparison up by the id in
+// it instead.
+//
+// Every comparison is a call from C into Go, well over an order of
+// magnitude dearer than the comparison itself, so sorting this way is
+// much slower than slices.SortFunc. It is worth it only when the sort
+// has to happen in C, on data that lives there. Package bench has
+// benchmarks comparing the two, and a qsort with a C comparison to
+// isolate the cost of the callbacks:
+//
+//	go test -run '^$' -bench Sort ./bench
+package qsort
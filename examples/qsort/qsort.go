@@ -0,0 +1,77 @@
+package qsort
+
+/*
+
+#include <stdlib.h>
+
+// Defined in qsort_export.go.
+extern int goQsortCompare(void *a, void *b);
+
+static int compare(const void *a, const void *b) {
+	return goQsortCompare((void *)a, (void *)b);
+}
+
+static void sortWithGo(void *base, size_t n, size_t size) {
+	qsort(base, n, size, compare);
+}
+
+static int compareInt64(const void *a, const void *b) {
+	long long x = *(const long long *)a, y = *(const long long *)b;
+
+	return (x > y) - (x < y);
+}
+
+static void sortInt64s(long long *base, size_t n) {
+	qsort(base, n, sizeof *base, compareInt64);
+}
+
+*/
+import "C"
+
+import (
+	"sync"
+	"unsafe"
+
+	"github.com/lxwagn/using-go-with-c-libraries/pkg/cpanic"
+)
+
+var (
+	mu      sync.Mutex
+	current func(a, b unsafe.Pointer) int // the comparison of the Slice running, under mu
+	barrier *cpanic.Barrier               // and the barrier it runs behind
+)
+
+// Slice sorts s in place with qsort, calling cmp from C for every
+// comparison. cmp returns a negative number, zero or a positive number as
+// a sorts before, with or after b. The sort is not stable.
+//
+// s is handed to C where it is, so T must not contain Go pointers. Calls
+// to Slice from different goroutines run one at a time, and cmp must not
+// call Slice itself. If cmp panics, the rest of the sort goes on with
+// every comparison equal, and Slice raises the panic again, as a
+// *cpanic.Panic, once qsort has returned.
+func Slice[T any](s []T, cmp func(a, b T) int) {
+	if len(s) < 2 {
+		return
+	}
+	var zero T
+	mu.Lock()
+	defer mu.Unlock()
+	current = func(a, b unsafe.Pointer) int {
+		return cmp(*(*T)(a), *(*T)(b))
+	}
+	barrier = new(cpanic.Barrier)
+	defer func() { current, barrier = nil, nil }()
+	C.sortWithGo(unsafe.Pointer(unsafe.SliceData(s)), C.size_t(len(s)), C.size_t(unsafe.Sizeof(zero)))
+	barrier.Repanic()
+}
+
+// Int64s sorts s in ascending order with qsort and a comparison written
+// in C, so no call crosses back into Go. It is the baseline that shows
+// what the callbacks in Slice cost.
+func Int64s(s []int64) {
+	if len(s) < 2 {
+		return
+	}
+	C.sortInt64s((*C.longlong)(unsafe.Pointer(unsafe.SliceData(s))), C.size_t(len(s)))
+}
@@ -0,0 +1,27 @@
+package qsort
+
+// A file containing //export directives may only declare C functions in
+// its preamble, not define them, which is why the comparison that calls
+// this one lives in qsort.go.
+
+import "C"
+
+import (
+	"unsafe"
+
+	"github.com/lxwagn/using-go-with-c-libraries/pkg/cpanic"
+)
+
+// goQsortCompare runs the comparison of the Slice in progress, on the
+// goroutine that called it and while it holds mu.
+//
+//export goQsortCompare
+func goQsortCompare(a, b unsafe.Pointer) C.int {
+	switch r := cpanic.Call(barrier, 0, func() int { return current(a, b) }); {
+	case r < 0:
+		return -1
+	case r > 0:
+		return 1
+	}
+	return 0
+}
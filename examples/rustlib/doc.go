@@ -0,0 +1,26 @@
+// Package rustlib wraps a library written in Rust, as an example that the
+// techniques used on libmylib apply to any library with a C ABI.
+//
+// The crate in rust/ is built as a cdylib, a shared library with no Rust
+// runtime to set up, and exports #[no_mangle] extern "C" functions
+// declared in rustlib.h. From cgo's side it is a C library like any
+// other, and the wrapper uses the same patterns as pkg/mylib:
+//
+//   - Strings go in as C strings and come back as C strings allocated by
+//     Rust, which must be freed by rl_string_free since Rust's allocator
+//     need not be malloc.
+//   - #[repr(C)] structs are passed and returned by value as C structs
+//     and converted to and from Go structs.
+//   - Errors come back as a status and a message, including Rust panics,
+//     which the library catches at the boundary.
+//   - An opaque object is a pointer from Box::into_raw, owned by a Go
+//     value with a Close method.
+//   - Rust calls Go back through a function pointer and a pkg/handles
+//     handle as its user data.
+//
+// Build the library with make rustlib, which needs cargo, before building
+// the package with the rustlib build tag:
+//
+//	make rustlib
+//	go run -tags rustlib ./cmd/selfcheck -run rustlib
+package rustlib
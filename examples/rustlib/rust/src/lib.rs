@@ -0,0 +1,183 @@
+//! A small Rust library exporting a C ABI, declared for C (and cgo) in
+//! ../rustlib.h. Its conventions are those of a C library:
+//!
+//! - Strings cross as NUL-terminated UTF-8. A string returned to the
+//!   caller is allocated by Rust and must be given back to
+//!   rl_string_free, never to free(3): Rust's allocator is not
+//!   necessarily malloc.
+//! - Functions that can fail return 0 or -1 and, on failure, store an
+//!   error message the caller frees with rl_string_free in *err.
+//! - Objects are opaque pointers from Box::into_raw, freed by their own
+//!   function.
+//! - A panic is caught at the boundary and reported as an error. One
+//!   that escaped an extern "C" function would abort the process.
+
+use std::ffi::{c_char, c_int, c_void, CStr, CString};
+use std::panic::{catch_unwind, AssertUnwindSafe};
+use std::ptr;
+use std::sync::Once;
+
+#[repr(C)]
+#[derive(Clone, Copy)]
+pub struct RlPoint {
+    pub x: f64,
+    pub y: f64,
+}
+
+#[repr(C)]
+pub struct RlSummary {
+    pub count: u64,
+    pub mean: f64,
+    pub min: f64,
+    pub max: f64,
+}
+
+pub struct RlStats {
+    values: Vec<f64>,
+}
+
+pub type RlVisit = extern "C" fn(user: *mut c_void, index: usize, value: f64) -> c_int;
+
+fn into_c_string(s: String) -> *mut c_char {
+    // Messages built here have no interior NULs.
+    CString::new(s).map_or(ptr::null_mut(), CString::into_raw)
+}
+
+/// Runs f, turning an error or a panic into -1 and a message in *err.
+fn guard(err: *mut *mut c_char, f: impl FnOnce() -> Result<(), String>) -> c_int {
+    // The default hook prints every panic to stderr, which is the
+    // caller's, not ours; the message goes into *err instead. A cdylib
+    // has a standard library of its own, so this is the only code the
+    // hook applies to.
+    static QUIET: Once = Once::new();
+    QUIET.call_once(|| std::panic::set_hook(Box::new(|_| {})));
+
+    let msg = match catch_unwind(AssertUnwindSafe(f)) {
+        Ok(Ok(())) => return 0,
+        Ok(Err(msg)) => msg,
+        Err(p) => {
+            let what = p
+                .downcast_ref::<&str>()
+                .map(|s| s.to_string())
+                .or_else(|| p.downcast_ref::<String>().cloned())
+                .unwrap_or_else(|| "unknown".into());
+            format!("panic: {what}")
+        }
+    };
+    if !err.is_null() {
+        unsafe { *err = into_c_string(msg) };
+    }
+    -1
+}
+
+unsafe fn str_arg<'a>(s: *const c_char) -> Result<&'a str, String> {
+    if s.is_null() {
+        return Err("null string".into());
+    }
+    CStr::from_ptr(s).to_str().map_err(|e| e.to_string())
+}
+
+/// The library version, a static string.
+#[no_mangle]
+pub extern "C" fn rl_version() -> *const c_char {
+    c"rustlib 0.1.0".as_ptr()
+}
+
+#[no_mangle]
+pub unsafe extern "C" fn rl_string_free(s: *mut c_char) {
+    if !s.is_null() {
+        drop(CString::from_raw(s));
+    }
+}
+
+/// Stores a greeting for name in *out.
+#[no_mangle]
+pub unsafe extern "C" fn rl_greet(name: *const c_char, out: *mut *mut c_char, err: *mut *mut c_char) -> c_int {
+    guard(err, || {
+        let name = str_arg(name)?;
+        if name.is_empty() {
+            return Err("empty name".into());
+        }
+        *out = into_c_string(format!("Hello, {name}! ({} characters)", name.chars().count()));
+        Ok(())
+    })
+}
+
+/// Structs are passed and returned by value like C's.
+#[no_mangle]
+pub extern "C" fn rl_distance(a: RlPoint, b: RlPoint) -> f64 {
+    (a.x - b.x).hypot(a.y - b.y)
+}
+
+#[no_mangle]
+pub extern "C" fn rl_midpoint(a: RlPoint, b: RlPoint) -> RlPoint {
+    RlPoint { x: (a.x + b.x) / 2.0, y: (a.y + b.y) / 2.0 }
+}
+
+/// Parses "x,y" into *out.
+#[no_mangle]
+pub unsafe extern "C" fn rl_parse_point(s: *const c_char, out: *mut RlPoint, err: *mut *mut c_char) -> c_int {
+    guard(err, || {
+        let s = str_arg(s)?;
+        let (x, y) = s.split_once(',').ok_or_else(|| format!("{s:?}: want x,y"))?;
+        let parse = |v: &str| v.trim().parse::<f64>().map_err(|e| format!("{v:?}: {e}"));
+        *out = RlPoint { x: parse(x)?, y: parse(y)? };
+        Ok(())
+    })
+}
+
+/// Divides a by b. It does no checking of its own, so a zero b panics
+/// inside Rust, which the guard reports.
+#[no_mangle]
+pub unsafe extern "C" fn rl_divide(a: i64, b: i64, out: *mut i64, err: *mut *mut c_char) -> c_int {
+    guard(err, || {
+        *out = a / b;
+        Ok(())
+    })
+}
+
+#[no_mangle]
+pub extern "C" fn rl_stats_new() -> *mut RlStats {
+    Box::into_raw(Box::new(RlStats { values: Vec::new() }))
+}
+
+#[no_mangle]
+pub unsafe extern "C" fn rl_stats_free(s: *mut RlStats) {
+    if !s.is_null() {
+        drop(Box::from_raw(s));
+    }
+}
+
+#[no_mangle]
+pub unsafe extern "C" fn rl_stats_add(s: *mut RlStats, v: f64, err: *mut *mut c_char) -> c_int {
+    guard(err, || {
+        if !v.is_finite() {
+            return Err(format!("{v} is not a finite number"));
+        }
+        (*s).values.push(v);
+        Ok(())
+    })
+}
+
+#[no_mangle]
+pub unsafe extern "C" fn rl_stats_summary(s: *const RlStats, out: *mut RlSummary) {
+    let v = &(*s).values;
+    *out = RlSummary {
+        count: v.len() as u64,
+        mean: if v.is_empty() { 0.0 } else { v.iter().sum::<f64>() / v.len() as f64 },
+        min: v.iter().copied().fold(f64::INFINITY, f64::min),
+        max: v.iter().copied().fold(f64::NEG_INFINITY, f64::max),
+    };
+}
+
+/// Calls visit for each value in order until it returns nonzero, and
+/// returns the number of values visited.
+#[no_mangle]
+pub unsafe extern "C" fn rl_stats_each(s: *const RlStats, visit: RlVisit, user: *mut c_void) -> usize {
+    for (i, &v) in (*s).values.iter().enumerate() {
+        if visit(user, i, v) != 0 {
+            return i + 1;
+        }
+    }
+    (*s).values.len()
+}
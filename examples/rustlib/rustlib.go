@@ -0,0 +1,220 @@
+//go:build rustlib
+
+package rustlib
+
+/*
+
+#cgo LDFLAGS: -L${SRCDIR}/../../lib -lrustlib -Wl,-rpath,${SRCDIR}/../../lib
+#include <stdint.h>
+#include "rustlib.h"
+
+// Defined in rustlib_export.go.
+extern int goRlVisit(void *user, size_t index, double value);
+
+static size_t statsEach(const RlStats *s, uintptr_t h) {
+	return rl_stats_each(s, goRlVisit, (void *)h);
+}
+
+*/
+import "C"
+
+import (
+	"errors"
+	"strings"
+	"sync"
+	"unsafe"
+
+	"github.com/lxwagn/using-go-with-c-libraries/pkg/cmem"
+	"github.com/lxwagn/using-go-with-c-libraries/pkg/cpanic"
+	"github.com/lxwagn/using-go-with-c-libraries/pkg/handles"
+)
+
+// An Error is a failure the Rust library reported, panics included.
+type Error struct {
+	Op  string // the library function that failed
+	Msg string
+}
+
+func (e *Error) Error() string {
+	return "rustlib: " + e.Op + ": " + e.Msg
+}
+
+// ErrNUL is returned for strings containing a NUL byte, which cannot be
+// passed as C strings.
+var ErrNUL = errors.New("rustlib: string contains NUL byte")
+
+// ErrClosed is returned for calls on a closed Stats.
+var ErrClosed = errors.New("rustlib: use of closed Stats")
+
+// takeString returns the Go copy of a string allocated by Rust and frees
+// the original.
+func takeString(s *C.char) string {
+	if s == nil {
+		return ""
+	}
+	defer C.rl_string_free(s)
+	return C.GoString(s)
+}
+
+// result turns a status and error message into a Go error.
+func result(op string, rc C.int, msg *C.char) error {
+	if rc == 0 {
+		return nil
+	}
+	return &Error{Op: op, Msg: takeString(msg)}
+}
+
+func cString(s string) (*C.char, error) {
+	if strings.IndexByte(s, 0) >= 0 {
+		return nil, ErrNUL
+	}
+	return (*C.char)(cmem.CString(s)), nil
+}
+
+// Version returns the library's version string.
+func Version() string {
+	// A static string: not to be freed.
+	return C.GoString(C.rl_version())
+}
+
+// Greet returns the library's greeting for name.
+func Greet(name string) (string, error) {
+	cname, err := cString(name)
+	if err != nil {
+		return "", err
+	}
+	defer cmem.Free(unsafe.Pointer(cname))
+
+	var out, msg *C.char
+	if err := result("rl_greet", C.rl_greet(cname, &out, &msg), msg); err != nil {
+		return "", err
+	}
+	return takeString(out), nil
+}
+
+// A Point is RlPoint on the Go side.
+type Point struct {
+	X, Y float64
+}
+
+func (p Point) c() C.RlPoint {
+	return C.RlPoint{x: C.double(p.X), y: C.double(p.Y)}
+}
+
+func goPoint(p C.RlPoint) Point {
+	return Point{float64(p.x), float64(p.y)}
+}
+
+// Distance returns the distance between a and b.
+func Distance(a, b Point) float64 {
+	return float64(C.rl_distance(a.c(), b.c()))
+}
+
+// Midpoint returns the point halfway between a and b.
+func Midpoint(a, b Point) Point {
+	return goPoint(C.rl_midpoint(a.c(), b.c()))
+}
+
+// ParsePoint parses s, of the form "x,y".
+func ParsePoint(s string) (Point, error) {
+	cs, err := cString(s)
+	if err != nil {
+		return Point{}, err
+	}
+	defer cmem.Free(unsafe.Pointer(cs))
+
+	var p C.RlPoint
+	var msg *C.char
+	if err := result("rl_parse_point", C.rl_parse_point(cs, &p, &msg), msg); err != nil {
+		return Point{}, err
+	}
+	return goPoint(p), nil
+}
+
+// Divide returns a/b, computed in Rust. Dividing by zero panics there,
+// and the panic comes back as an *Error.
+func Divide(a, b int64) (int64, error) {
+	var q C.int64_t
+	var msg *C.char
+	if err := result("rl_divide", C.rl_divide(C.int64_t(a), C.int64_t(b), &q, &msg), msg); err != nil {
+		return 0, err
+	}
+	return int64(q), nil
+}
+
+// A Stats collects numbers in a Rust object. It is safe for concurrent
+// use.
+type Stats struct {
+	mu sync.Mutex
+	s  *C.RlStats
+}
+
+// A Summary describes the numbers added to a Stats.
+type Summary struct {
+	Count          int
+	Mean, Min, Max float64
+}
+
+// NewStats returns an empty Stats, which must be closed.
+func NewStats() *Stats {
+	return &Stats{s: C.rl_stats_new()}
+}
+
+// Close frees the Rust object.
+func (s *Stats) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.s == nil {
+		return ErrClosed
+	}
+	C.rl_stats_free(s.s)
+	s.s = nil
+	return nil
+}
+
+// Add adds v, which must be finite.
+func (s *Stats) Add(v float64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.s == nil {
+		return ErrClosed
+	}
+	var msg *C.char
+	return result("rl_stats_add", C.rl_stats_add(s.s, C.double(v), &msg), msg)
+}
+
+// Summary returns the count, mean and range of the numbers added.
+func (s *Stats) Summary() (Summary, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.s == nil {
+		return Summary{}, ErrClosed
+	}
+	var out C.RlSummary
+	C.rl_stats_summary(s.s, &out)
+	return Summary{Count: int(out.count), Mean: float64(out.mean), Min: float64(out.min), Max: float64(out.max)}, nil
+}
+
+// Each calls fn with each number added, in order, until fn returns
+// false, and returns how many numbers it visited. fn must not use s. If
+// fn panics, the visit stops there, and Each raises the panic again, as a
+// *cpanic.Panic, once rl_stats_each has returned.
+func (s *Stats) Each(fn func(i int, v float64) bool) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.s == nil {
+		return 0, ErrClosed
+	}
+	v := &visit{fn: fn}
+	h := handles.New(v)
+	defer h.Delete()
+	n := C.statsEach(s.s, C.uintptr_t(h.Uintptr()))
+	v.barrier.Repanic()
+	return int(n), nil
+}
+
+// visit is what the handle of an Each in progress refers to.
+type visit struct {
+	fn      func(i int, v float64) bool
+	barrier cpanic.Barrier
+}
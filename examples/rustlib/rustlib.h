@@ -0,0 +1,43 @@
+// The C interface of the Rust library in rust/, written by hand to match
+// rust/src/lib.rs; cbindgen can generate one like it.
+#ifndef RUSTLIB_H
+#define RUSTLIB_H
+
+#include <stddef.h>
+#include <stdint.h>
+
+typedef struct {
+	double x, y;
+} RlPoint;
+
+typedef struct {
+	uint64_t count;
+	double mean, min, max;
+} RlSummary;
+
+typedef struct RlStats RlStats;
+
+typedef int (*RlVisit)(void *user, size_t index, double value);
+
+const char *rl_version(void);
+
+// Strings returned through out or err parameters are allocated by Rust
+// and must be freed with rl_string_free.
+void rl_string_free(char *s);
+
+// Functions returning int return 0 on success and -1, with a message in
+// *err, on failure, a caught panic included.
+int rl_greet(const char *name, char **out, char **err);
+
+double rl_distance(RlPoint a, RlPoint b);
+RlPoint rl_midpoint(RlPoint a, RlPoint b);
+int rl_parse_point(const char *s, RlPoint *out, char **err);
+int rl_divide(int64_t a, int64_t b, int64_t *out, char **err);
+
+RlStats *rl_stats_new(void);
+void rl_stats_free(RlStats *s);
+int rl_stats_add(RlStats *s, double v, char **err);
+void rl_stats_summary(const RlStats *s, RlSummary *out);
+size_t rl_stats_each(const RlStats *s, RlVisit visit, void *user);
+
+#endif
@@ -0,0 +1,27 @@
+//go:build rustlib
+
+package rustlib
+
+// The gateway Rust calls is declared in rustlib.go; a file with //export
+// directives may only declare C functions, not define them.
+
+/*
+#include <stddef.h>
+*/
+import "C"
+
+import (
+	"unsafe"
+
+	"github.com/lxwagn/using-go-with-c-libraries/pkg/cpanic"
+	"github.com/lxwagn/using-go-with-c-libraries/pkg/handles"
+)
+
+//export goRlVisit
+func goRlVisit(user unsafe.Pointer, index C.size_t, value C.double) C.int {
+	v := handles.FromUintptr[*visit](uintptr(user)).Value()
+	if cpanic.Call(&v.barrier, false, func() bool { return v.fn(int(index), float64(value)) }) {
+		return 0
+	}
+	return 1
+}
@@ -0,0 +1,30 @@
+// Package sqlite is a small cgo binding for SQLite, as an example of what
+// binding a real C library involves beyond what the toy libmylib shows.
+//
+// It covers the parts most bindings need: a connection handle that C
+// owns, prepared statements whose parameters are bound from Go values and
+// whose rows are read back as Go types, text and blobs copied in both
+// directions, SQLite's result codes and messages turned into Go errors,
+// and Go functions that SQLite calls back from inside a query:
+//
+//	db, err := sqlite.Open(":memory:")
+//	...
+//	defer db.Close()
+//	err = db.Exec("CREATE TABLE t (id INTEGER PRIMARY KEY, name TEXT)")
+//	err = db.Exec("INSERT INTO t (name) VALUES (?)", "gopher")
+//	for row, err := range db.Query("SELECT id, name FROM t") {
+//		if err != nil {
+//			...
+//		}
+//		var id int64
+//		var name string
+//		err = row.Scan(&id, &name)
+//	}
+//
+// A Conn serializes its own calls and those of its statements, since
+// SQLite's error message is per connection and would otherwise belong to
+// whichever call failed last. Use one Conn per goroutine for parallelism.
+//
+// The package links against the system's SQLite, found with pkg-config.
+// It is not a database/sql driver; see the many that exist for that.
+package sqlite
@@ -0,0 +1,175 @@
+package sqlite
+
+/*
+
+#include <sqlite3.h>
+#include <stdint.h>
+
+// Defined in func_export.go.
+extern void goSQLiteFunc(sqlite3_context *ctx, int argc, sqlite3_value **argv);
+extern void goSQLiteFuncDestroy(void *app);
+
+static int createFunction(sqlite3 *db, const char *name, int nargs, int flags, uintptr_t handle) {
+	return sqlite3_create_function_v2(db, name, nargs, SQLITE_UTF8 | flags, (void *)handle,
+					  goSQLiteFunc, NULL, NULL, goSQLiteFuncDestroy);
+}
+
+static uintptr_t funcHandle(sqlite3_context *ctx) {
+	return (uintptr_t)sqlite3_user_data(ctx);
+}
+
+static sqlite3_value *arg(sqlite3_value **argv, int i) {
+	return argv[i];
+}
+
+static void resultText(sqlite3_context *ctx, const char *p, int n) {
+	sqlite3_result_text(ctx, n > 0 ? p : "", n, SQLITE_TRANSIENT);
+}
+
+static void resultBlob(sqlite3_context *ctx, const void *p, int n) {
+	if (n > 0)
+		sqlite3_result_blob(ctx, p, n, SQLITE_TRANSIENT);
+	else
+		sqlite3_result_zeroblob(ctx, 0);
+}
+
+*/
+import "C"
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+	"unsafe"
+
+	"github.com/lxwagn/using-go-with-c-libraries/pkg/cmem"
+	"github.com/lxwagn/using-go-with-c-libraries/pkg/handles"
+)
+
+// A Func is a Go function callable from SQL. Its arguments are nil,
+// int64, float64, string or []byte, and it may return any value Bind
+// accepts. An error it returns, or a panic, makes the statement calling it
+// fail with that message.
+//
+// A Func runs while the connection is busy with the statement calling it,
+// so it must not use the connection itself.
+type Func func(args []any) (any, error)
+
+// CreateFunction makes fn callable from SQL on this connection as name,
+// with nargs arguments, or any number if nargs is -1. A deterministic
+// function, one whose result depends only on its arguments, may be used
+// in indexes and is evaluated less often. Defining name again replaces
+// the earlier function.
+func (c *Conn) CreateFunction(name string, nargs int, deterministic bool, fn Func) error {
+	if strings.IndexByte(name, 0) >= 0 {
+		return errors.New("sqlite: function name contains NUL byte")
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.db == nil {
+		return ErrClosed
+	}
+
+	cname := (*C.char)(cmem.CString(name))
+	defer cmem.Free(unsafe.Pointer(cname))
+
+	var flags C.int
+	if deterministic {
+		flags = C.SQLITE_DETERMINISTIC
+	}
+	// SQLite owns the handle from here on: it calls the destructor, which
+	// deletes it, when the function is replaced or the connection closes,
+	// and also if the call fails.
+	h := handles.New(fn)
+	if rc := C.createFunction(c.db, cname, C.int(nargs), flags, C.uintptr_t(h.Uintptr())); rc != C.SQLITE_OK {
+		return newError("sqlite3_create_function_v2", c.db, rc)
+	}
+	return nil
+}
+
+// callFunc runs the Func behind ctx with the arguments in argv.
+func callFunc(ctx *C.sqlite3_context, argc C.int, argv **C.sqlite3_value) {
+	fn, err := handles.FromUintptr[Func](uintptr(C.funcHandle(ctx))).Get()
+	if err != nil {
+		resultError(ctx, err)
+		return
+	}
+
+	args := make([]any, argc)
+	for i := range args {
+		args[i] = goValue(C.arg(argv, C.int(i)))
+	}
+	v, err := fn(args)
+	if err != nil {
+		resultError(ctx, err)
+		return
+	}
+	setResult(ctx, v)
+}
+
+func goValue(v *C.sqlite3_value) any {
+	switch C.sqlite3_value_type(v) {
+	case C.SQLITE_INTEGER:
+		return int64(C.sqlite3_value_int64(v))
+	case C.SQLITE_FLOAT:
+		return float64(C.sqlite3_value_double(v))
+	case C.SQLITE_TEXT:
+		p := C.sqlite3_value_text(v)
+		return C.GoStringN((*C.char)(unsafe.Pointer(p)), C.sqlite3_value_bytes(v))
+	case C.SQLITE_BLOB:
+		p := C.sqlite3_value_blob(v)
+		return C.GoBytes(p, C.sqlite3_value_bytes(v))
+	}
+	return nil
+}
+
+func setResult(ctx *C.sqlite3_context, v any) {
+	switch v := v.(type) {
+	case nil:
+		C.sqlite3_result_null(ctx)
+	case bool:
+		b := 0
+		if v {
+			b = 1
+		}
+		C.sqlite3_result_int64(ctx, C.sqlite3_int64(b))
+	case float32:
+		C.sqlite3_result_double(ctx, C.double(v))
+	case float64:
+		C.sqlite3_result_double(ctx, C.double(v))
+	case string:
+		if len(v) > math.MaxInt32 {
+			C.sqlite3_result_error_toobig(ctx)
+			return
+		}
+		C.resultText(ctx, (*C.char)(unsafe.Pointer(unsafe.StringData(v))), C.int(len(v)))
+	case []byte:
+		if v == nil {
+			C.sqlite3_result_null(ctx)
+			return
+		}
+		if len(v) > math.MaxInt32 {
+			C.sqlite3_result_error_toobig(ctx)
+			return
+		}
+		C.resultBlob(ctx, unsafe.Pointer(unsafe.SliceData(v)), C.int(len(v)))
+	default:
+		n, ok := toInt64(v)
+		if !ok {
+			resultError(ctx, fmt.Errorf("cannot return %T to SQL", v))
+			return
+		}
+		C.sqlite3_result_int64(ctx, C.sqlite3_int64(n))
+	}
+}
+
+func resultError(ctx *C.sqlite3_context, err error) {
+	msg := err.Error()
+	cmsg := (*C.char)(cmem.CString(msg))
+	defer cmem.Free(unsafe.Pointer(cmsg))
+	// sqlite3_result_error copies the message.
+	C.sqlite3_result_error(ctx, cmsg, C.int(len(msg)))
+}
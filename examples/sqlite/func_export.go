@@ -0,0 +1,34 @@
+package sqlite
+
+// The gateways SQLite calls are declared in func.go; a file with //export
+// directives may only declare C functions, not define them.
+
+/*
+#include <sqlite3.h>
+#include <stdint.h>
+*/
+import "C"
+
+import (
+	"fmt"
+	"unsafe"
+
+	"github.com/lxwagn/using-go-with-c-libraries/pkg/cpanic"
+	"github.com/lxwagn/using-go-with-c-libraries/pkg/handles"
+)
+
+// goSQLiteFunc runs a Func. A panic must not unwind into SQLite's frames,
+// so it fails the SQL call instead, as an error from the Func does.
+//
+//export goSQLiteFunc
+func goSQLiteFunc(ctx *C.sqlite3_context, argc C.int, argv **C.sqlite3_value) {
+	var b cpanic.Barrier
+	if !cpanic.Do(&b, func() { callFunc(ctx, argc, argv) }) {
+		resultError(ctx, fmt.Errorf("panic: %v", b.Recovered().Value))
+	}
+}
+
+//export goSQLiteFuncDestroy
+func goSQLiteFuncDestroy(app unsafe.Pointer) {
+	handles.FromUintptr[Func](uintptr(app)).Delete()
+}
@@ -0,0 +1,198 @@
+package sqlite
+
+/*
+
+#cgo pkg-config: sqlite3
+#include <sqlite3.h>
+#include <stdlib.h>
+
+*/
+import "C"
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"unsafe"
+
+	"github.com/lxwagn/using-go-with-c-libraries/pkg/cerr"
+	"github.com/lxwagn/using-go-with-c-libraries/pkg/cmem"
+)
+
+// SQLite's primary result codes that callers commonly test for. An *Error
+// matches the one for its code with errors.Is; extended codes match their
+// primary code.
+var (
+	codes = cerr.NewTable("sqlite")
+
+	ErrGeneric    = codes.Register(C.SQLITE_ERROR, errors.New("sqlite: SQL error"))
+	ErrBusy       = codes.Register(C.SQLITE_BUSY, errors.New("sqlite: database is locked"))
+	ErrNoMem      = codes.Register(C.SQLITE_NOMEM, errors.New("sqlite: out of memory"))
+	ErrReadOnly   = codes.Register(C.SQLITE_READONLY, errors.New("sqlite: database is read-only"))
+	ErrCantOpen   = codes.Register(C.SQLITE_CANTOPEN, errors.New("sqlite: unable to open database"))
+	ErrConstraint = codes.Register(C.SQLITE_CONSTRAINT, errors.New("sqlite: constraint failed"))
+	ErrMismatch   = codes.Register(C.SQLITE_MISMATCH, errors.New("sqlite: datatype mismatch"))
+	ErrRange      = codes.Register(C.SQLITE_RANGE, errors.New("sqlite: parameter index out of range"))
+)
+
+// ErrClosed is returned for calls on a closed Conn or Stmt.
+var ErrClosed = errors.New("sqlite: use of closed connection or statement")
+
+// An Error is a failed SQLite call: its result code and the message
+// SQLite gave for it.
+type Error struct {
+	Op   string // the SQLite function that failed
+	Code int    // extended result code
+	Msg  string // sqlite3_errmsg, or sqlite3_errstr if there was none
+
+	err error
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("sqlite: %s: %s", e.Op, e.Msg)
+}
+
+func (e *Error) Unwrap() error {
+	return e.err
+}
+
+// A Conn is an open database connection.
+type Conn struct {
+	mu sync.Mutex
+	db *C.sqlite3
+}
+
+// Open opens the database file path, creating it if it does not exist.
+// ":memory:" opens a new in-memory database.
+func Open(path string) (*Conn, error) {
+	if strings.IndexByte(path, 0) >= 0 {
+		return nil, errors.New("sqlite: path contains NUL byte")
+	}
+	cpath := (*C.char)(cmem.CString(path))
+	defer cmem.Free(unsafe.Pointer(cpath))
+
+	var db *C.sqlite3
+	flags := C.SQLITE_OPEN_READWRITE | C.SQLITE_OPEN_CREATE | C.SQLITE_OPEN_URI
+	rc := C.sqlite3_open_v2(cpath, &db, C.int(flags), nil)
+	if rc != C.SQLITE_OK {
+		// Even a failed open usually allocates a handle, which holds the
+		// message and must still be closed.
+		err := newError("sqlite3_open_v2", db, rc)
+		C.sqlite3_close_v2(db)
+		return nil, err
+	}
+	C.sqlite3_extended_result_codes(db, 1)
+	return &Conn{db: db}, nil
+}
+
+// newError returns the error for the result code rc of op on db, which
+// may be nil. The caller must hold the Conn's lock.
+func newError(op string, db *C.sqlite3, rc C.int) error {
+	e := &Error{Op: op, Code: int(rc), err: codes.Error(op, int(rc&0xff))}
+	if db != nil {
+		e.Msg = C.GoString(C.sqlite3_errmsg(db))
+	} else {
+		e.Msg = C.GoString(C.sqlite3_errstr(rc))
+	}
+	return e
+}
+
+// Close closes the connection. Statements still open are finalized by
+// SQLite once they are closed too.
+func (c *Conn) Close() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.db == nil {
+		return ErrClosed
+	}
+	rc := C.sqlite3_close_v2(c.db)
+	c.db = nil
+	if rc != C.SQLITE_OK {
+		return newError("sqlite3_close_v2", nil, rc)
+	}
+	return nil
+}
+
+// Exec runs every statement in sql, which may hold several separated by
+// semicolons, discarding any rows. args are bound to the statements'
+// parameters in order, each statement taking as many as it has.
+func (c *Conn) Exec(sql string, args ...any) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.db == nil {
+		return ErrClosed
+	}
+
+	if strings.IndexByte(sql, 0) >= 0 {
+		return errors.New("sqlite: SQL contains NUL byte")
+	}
+	csql := (*C.char)(cmem.CString(sql))
+	defer cmem.Free(unsafe.Pointer(csql))
+
+	for p := csql; *p != 0; {
+		var s *C.sqlite3_stmt
+		var tail *C.char
+		if rc := C.sqlite3_prepare_v2(c.db, p, -1, &s, &tail); rc != C.SQLITE_OK {
+			return newError("sqlite3_prepare_v2", c.db, rc)
+		}
+		p = tail
+		if s == nil {
+			// Only whitespace or a comment was left.
+			continue
+		}
+		stmt := &Stmt{c: c, s: s}
+		n := int(C.sqlite3_bind_parameter_count(s))
+		if n > len(args) {
+			n = len(args)
+		}
+		err := stmt.bind(args[:n])
+		args = args[n:]
+		for err == nil {
+			var more bool
+			more, err = stmt.step()
+			if !more {
+				break
+			}
+		}
+		C.sqlite3_finalize(s)
+		if err != nil {
+			return err
+		}
+	}
+	if len(args) > 0 {
+		return fmt.Errorf("sqlite: %d arguments left over", len(args))
+	}
+	return nil
+}
+
+// Changes returns the number of rows the last INSERT, UPDATE or DELETE
+// on the connection changed.
+func (c *Conn) Changes() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.db == nil {
+		return 0
+	}
+	return int(C.sqlite3_changes(c.db))
+}
+
+// LastInsertID returns the rowid of the row last inserted on the
+// connection.
+func (c *Conn) LastInsertID() int64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.db == nil {
+		return 0
+	}
+	return int64(C.sqlite3_last_insert_rowid(c.db))
+}
+
+// Version returns the version of the SQLite library linked in.
+func Version() string {
+	return C.GoString(C.sqlite3_libversion())
+}
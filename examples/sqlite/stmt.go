@@ -0,0 +1,340 @@
+package sqlite
+
+/*
+
+#include <sqlite3.h>
+
+// SQLITE_TRANSIENT is a cast of -1 to a function pointer, which cgo
+// cannot express, so the binding calls that copy their argument go
+// through these. An empty string or blob still needs a non-NULL pointer
+// to be bound as a value rather than as NULL.
+static int bindText(sqlite3_stmt *s, int i, const char *p, int n) {
+	return sqlite3_bind_text(s, i, n > 0 ? p : "", n, SQLITE_TRANSIENT);
+}
+
+static int bindBlob(sqlite3_stmt *s, int i, const void *p, int n) {
+	return n > 0 ? sqlite3_bind_blob(s, i, p, n, SQLITE_TRANSIENT) : sqlite3_bind_zeroblob(s, i, 0);
+}
+
+*/
+import "C"
+
+import (
+	"errors"
+	"fmt"
+	"iter"
+	"math"
+	"strings"
+	"unsafe"
+
+	"github.com/lxwagn/using-go-with-c-libraries/pkg/cmem"
+)
+
+// A Stmt is a prepared statement. It belongs to the Conn that prepared
+// it, and is usable until either is closed.
+type Stmt struct {
+	c *Conn
+	s *C.sqlite3_stmt // nil once closed
+}
+
+// Prepare compiles the first statement in sql.
+func (c *Conn) Prepare(sql string) (*Stmt, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.db == nil {
+		return nil, ErrClosed
+	}
+
+	if strings.IndexByte(sql, 0) >= 0 {
+		return nil, errors.New("sqlite: SQL contains NUL byte")
+	}
+	csql := (*C.char)(cmem.CString(sql))
+	defer cmem.Free(unsafe.Pointer(csql))
+
+	var s *C.sqlite3_stmt
+	if rc := C.sqlite3_prepare_v2(c.db, csql, -1, &s, nil); rc != C.SQLITE_OK {
+		return nil, newError("sqlite3_prepare_v2", c.db, rc)
+	}
+	if s == nil {
+		return nil, fmt.Errorf("sqlite: no statement in %q", sql)
+	}
+	return &Stmt{c: c, s: s}, nil
+}
+
+// Close finalizes the statement.
+func (s *Stmt) Close() error {
+	s.c.mu.Lock()
+	defer s.c.mu.Unlock()
+
+	if s.s == nil {
+		return ErrClosed
+	}
+	C.sqlite3_finalize(s.s)
+	s.s = nil
+	return nil
+}
+
+// usable reports why s cannot be used, if it cannot. The caller holds the
+// Conn's lock.
+func (s *Stmt) usable() error {
+	if s.s == nil || s.c.db == nil {
+		return ErrClosed
+	}
+	return nil
+}
+
+// Bind resets the statement and binds args to its parameters in order.
+// nil binds NULL; integers, bool, floats, strings and []byte bind as
+// INTEGER, REAL, TEXT and BLOB. Strings and byte slices are copied.
+func (s *Stmt) Bind(args ...any) error {
+	s.c.mu.Lock()
+	defer s.c.mu.Unlock()
+
+	if err := s.usable(); err != nil {
+		return err
+	}
+	C.sqlite3_reset(s.s)
+	C.sqlite3_clear_bindings(s.s)
+	return s.bind(args)
+}
+
+func (s *Stmt) bind(args []any) error {
+	if n := int(C.sqlite3_bind_parameter_count(s.s)); len(args) != n {
+		return fmt.Errorf("sqlite: statement takes %d arguments, got %d", n, len(args))
+	}
+	for i, arg := range args {
+		col := C.int(i + 1)
+		var rc C.int
+		switch v := arg.(type) {
+		case nil:
+			rc = C.sqlite3_bind_null(s.s, col)
+		case bool:
+			b := 0
+			if v {
+				b = 1
+			}
+			rc = C.sqlite3_bind_int64(s.s, col, C.sqlite3_int64(b))
+		case float32:
+			rc = C.sqlite3_bind_double(s.s, col, C.double(v))
+		case float64:
+			rc = C.sqlite3_bind_double(s.s, col, C.double(v))
+		case string:
+			if len(v) > math.MaxInt32 {
+				return fmt.Errorf("sqlite: argument %d: string too long", i)
+			}
+			rc = C.bindText(s.s, col, (*C.char)(unsafe.Pointer(unsafe.StringData(v))), C.int(len(v)))
+		case []byte:
+			if v == nil {
+				rc = C.sqlite3_bind_null(s.s, col)
+				break
+			}
+			if len(v) > math.MaxInt32 {
+				return fmt.Errorf("sqlite: argument %d: blob too long", i)
+			}
+			rc = C.bindBlob(s.s, col, unsafe.Pointer(unsafe.SliceData(v)), C.int(len(v)))
+		default:
+			n, ok := toInt64(arg)
+			if !ok {
+				return fmt.Errorf("sqlite: argument %d: cannot bind %T", i, arg)
+			}
+			rc = C.sqlite3_bind_int64(s.s, col, C.sqlite3_int64(n))
+		}
+		if rc != C.SQLITE_OK {
+			return newError("sqlite3_bind", s.c.db, rc)
+		}
+	}
+	return nil
+}
+
+func toInt64(v any) (int64, bool) {
+	switch n := v.(type) {
+	case int:
+		return int64(n), true
+	case int8:
+		return int64(n), true
+	case int16:
+		return int64(n), true
+	case int32:
+		return int64(n), true
+	case int64:
+		return n, true
+	case uint8:
+		return int64(n), true
+	case uint16:
+		return int64(n), true
+	case uint32:
+		return int64(n), true
+	case uint:
+		return int64(n), n <= math.MaxInt64
+	case uint64:
+		return int64(n), n <= math.MaxInt64
+	}
+	return 0, false
+}
+
+// Step advances the statement to its next row, reporting whether there
+// is one. After the last row the statement can be bound and run again.
+func (s *Stmt) Step() (bool, error) {
+	s.c.mu.Lock()
+	defer s.c.mu.Unlock()
+
+	if err := s.usable(); err != nil {
+		return false, err
+	}
+	return s.step()
+}
+
+func (s *Stmt) step() (bool, error) {
+	switch rc := C.sqlite3_step(s.s); rc {
+	case C.SQLITE_ROW:
+		return true, nil
+	case C.SQLITE_DONE:
+		C.sqlite3_reset(s.s)
+		return false, nil
+	default:
+		err := newError("sqlite3_step", s.c.db, rc)
+		C.sqlite3_reset(s.s)
+		return false, err
+	}
+}
+
+// Columns returns the names of the statement's result columns.
+func (s *Stmt) Columns() []string {
+	s.c.mu.Lock()
+	defer s.c.mu.Unlock()
+
+	if s.usable() != nil {
+		return nil
+	}
+	names := make([]string, C.sqlite3_column_count(s.s))
+	for i := range names {
+		names[i] = C.GoString(C.sqlite3_column_name(s.s, C.int(i)))
+	}
+	return names
+}
+
+// Scan copies the columns of the current row into dest, which holds one
+// pointer per column: *int, *int64, *float64, *bool, *string, *[]byte or
+// *any. SQLite converts between its types as it always does, so an
+// INTEGER column can be read as a string. NULL reads as the zero value,
+// or nil through *[]byte and *any, which gets int64, float64, string or
+// []byte otherwise. Strings and byte slices are copies.
+func (s *Stmt) Scan(dest ...any) error {
+	s.c.mu.Lock()
+	defer s.c.mu.Unlock()
+
+	if err := s.usable(); err != nil {
+		return err
+	}
+	if n := int(C.sqlite3_data_count(s.s)); len(dest) != n {
+		if n == 0 {
+			return fmt.Errorf("sqlite: Scan without a current row")
+		}
+		return fmt.Errorf("sqlite: row has %d columns, Scan got %d", n, len(dest))
+	}
+	for i, d := range dest {
+		col := C.int(i)
+		switch d := d.(type) {
+		case *int:
+			*d = int(C.sqlite3_column_int64(s.s, col))
+		case *int64:
+			*d = int64(C.sqlite3_column_int64(s.s, col))
+		case *float64:
+			*d = float64(C.sqlite3_column_double(s.s, col))
+		case *bool:
+			*d = C.sqlite3_column_int64(s.s, col) != 0
+		case *string:
+			*d = s.text(col)
+		case *[]byte:
+			*d = s.blob(col)
+		case *any:
+			*d = s.value(col)
+		default:
+			return fmt.Errorf("sqlite: cannot scan column %d into %T", i, d)
+		}
+	}
+	return nil
+}
+
+// text and blob read column col. The pointer must be fetched before the
+// length: fetching it may convert the value and change its length.
+func (s *Stmt) text(col C.int) string {
+	p := C.sqlite3_column_text(s.s, col)
+	return C.GoStringN((*C.char)(unsafe.Pointer(p)), C.sqlite3_column_bytes(s.s, col))
+}
+
+func (s *Stmt) blob(col C.int) []byte {
+	p := C.sqlite3_column_blob(s.s, col)
+	n := C.sqlite3_column_bytes(s.s, col)
+	if p == nil {
+		if C.sqlite3_column_type(s.s, col) == C.SQLITE_NULL {
+			return nil
+		}
+		return []byte{}
+	}
+	return C.GoBytes(p, n)
+}
+
+func (s *Stmt) value(col C.int) any {
+	switch C.sqlite3_column_type(s.s, col) {
+	case C.SQLITE_INTEGER:
+		return int64(C.sqlite3_column_int64(s.s, col))
+	case C.SQLITE_FLOAT:
+		return float64(C.sqlite3_column_double(s.s, col))
+	case C.SQLITE_TEXT:
+		return s.text(col)
+	case C.SQLITE_BLOB:
+		return s.blob(col)
+	}
+	return nil
+}
+
+// Rows binds args and returns an iterator over the statement's rows. The
+// Stmt yielded is s itself, positioned on the row, for Scan. A failure
+// ends the iteration with a final nil Stmt and the error. Stopping early
+// resets the statement.
+func (s *Stmt) Rows(args ...any) iter.Seq2[*Stmt, error] {
+	return func(yield func(*Stmt, error) bool) {
+		if err := s.Bind(args...); err != nil {
+			yield(nil, err)
+			return
+		}
+		for {
+			more, err := s.Step()
+			if err != nil {
+				yield(nil, err)
+				return
+			}
+			if !more {
+				return
+			}
+			if !yield(s, nil) {
+				s.c.mu.Lock()
+				if s.usable() == nil {
+					C.sqlite3_reset(s.s)
+				}
+				s.c.mu.Unlock()
+				return
+			}
+		}
+	}
+}
+
+// Query prepares sql and returns an iterator over its rows, as Stmt.Rows
+// does, closing the statement when the iteration ends.
+func (c *Conn) Query(sql string, args ...any) iter.Seq2[*Stmt, error] {
+	return func(yield func(*Stmt, error) bool) {
+		s, err := c.Prepare(sql)
+		if err != nil {
+			yield(nil, err)
+			return
+		}
+		defer s.Close()
+		for row, err := range s.Rows(args...) {
+			if !yield(row, err) {
+				return
+			}
+		}
+	}
+}
@@ -0,0 +1,24 @@
+// Package zlibc reads and writes the zlib format through the C zlib
+// library, as an io.Reader and an io.Writer, producing and accepting the
+// same streams as compress/zlib.
+//
+// It is an example of a streaming C API behind Go's io interfaces. zlib
+// keeps pointers to its input and output buffers in a z_stream between
+// calls, and C memory must not hold Go pointers, so each Writer and
+// Reader owns a z_stream and two buffers allocated in C. Data is copied
+// into and out of them, in chunks of BufferSize, and each chunk costs one
+// cgo call. Close frees the C memory; a Writer or Reader that is never
+// closed has it freed when collected.
+//
+// Whether the native path is worth it depends on the work and on the zlib
+// build. Decompression through C is usually faster than compress/zlib at
+// any size. Compressing short inputs is too, because a compress/flate
+// Writer allocates around a megabyte of state while zlib's lives in C.
+// For long inputs compress/flate's compressor is often the faster one,
+// so measure before switching; package bench has benchmarks comparing
+// the two:
+//
+//	go test -run '^$' -bench Zlib ./bench
+//
+// The package links against the system's zlib, found with pkg-config.
+package zlibc
@@ -0,0 +1,118 @@
+package zlibc
+
+/*
+
+#include <zlib.h>
+
+*/
+import "C"
+
+import (
+	"io"
+	"runtime"
+)
+
+// A Reader decompresses a zlib stream read from an underlying reader. It
+// reads ahead, in chunks of up to BufferSize, so it may consume input
+// past the end of the stream.
+type Reader struct {
+	r       io.Reader
+	s       *stream
+	cleanup runtime.Cleanup
+
+	pending []byte // decompressed data not yet returned, in s.out
+	rerr    error  // error from r, returned once the input is used up
+	err     error  // sticky error, io.EOF at the end of the stream
+	closed  bool
+}
+
+// NewReader returns a Reader decompressing from r.
+func NewReader(r io.Reader) (*Reader, error) {
+	s := newStream(true)
+	if rc := s.initInflate(); rc != C.Z_OK {
+		err := zError("inflateInit", rc, s)
+		freeStream(s)
+		return nil, err
+	}
+	z := &Reader{r: r, s: s}
+	z.cleanup = runtime.AddCleanup(z, freeStream, s)
+	return z, nil
+}
+
+// Read reads decompressed data into p. It returns io.EOF at the end of
+// the stream, having checked its checksum, an error matching ErrCorrupt
+// for invalid data and io.ErrUnexpectedEOF if the input ends too soon.
+func (z *Reader) Read(p []byte) (int, error) {
+	if z.closed {
+		return 0, ErrClosed
+	}
+	for {
+		if len(z.pending) > 0 {
+			n := copy(p, z.pending)
+			z.pending = z.pending[n:]
+			return n, nil
+		}
+		if z.err != nil {
+			return 0, z.err
+		}
+		if len(p) == 0 {
+			return 0, nil
+		}
+		if z.s.z.avail_in == 0 {
+			if z.rerr != nil {
+				z.err = z.rerr
+				if z.err == io.EOF {
+					z.err = io.ErrUnexpectedEOF
+				}
+				continue
+			}
+			n, err := z.r.Read(z.s.in)
+			z.s.setIn(n)
+			z.rerr = err
+			if n == 0 {
+				continue
+			}
+		}
+
+		z.s.setOut()
+		rc := C.inflate(z.s.z, C.Z_NO_FLUSH)
+		z.pending = z.s.produced()
+		switch rc {
+		case C.Z_OK:
+		case C.Z_STREAM_END:
+			z.err = io.EOF
+		case C.Z_BUF_ERROR:
+			// No progress was possible: inflate needs more input,
+			// which the next round reads.
+		default:
+			z.err = zError("inflate", rc, z.s)
+		}
+	}
+}
+
+// Close frees the Reader's C memory. It does not close the underlying
+// reader.
+func (z *Reader) Close() error {
+	if z.closed {
+		return ErrClosed
+	}
+	z.closed = true
+	z.cleanup.Stop()
+	freeStream(z.s)
+	z.s, z.pending = nil, nil
+	return nil
+}
+
+// Reset discards the Reader's state and makes it read a new stream from
+// r, reusing its C memory. It cannot revive a closed Reader.
+func (z *Reader) Reset(r io.Reader) error {
+	if z.closed {
+		return ErrClosed
+	}
+	if rc := C.inflateReset(z.s.z); rc != C.Z_OK {
+		return zError("inflateReset", rc, z.s)
+	}
+	z.s.setIn(0)
+	z.r, z.pending, z.rerr, z.err = r, nil, nil, nil
+	return nil
+}
@@ -0,0 +1,144 @@
+package zlibc
+
+/*
+
+#include <zlib.h>
+
+*/
+import "C"
+
+import (
+	"fmt"
+	"io"
+	"runtime"
+)
+
+// A Writer compresses what is written to it and writes the zlib stream to
+// an underlying writer. Close must be called to finish the stream.
+type Writer struct {
+	w       io.Writer
+	s       *stream
+	cleanup runtime.Cleanup
+	err     error
+	closed  bool
+}
+
+// NewWriter returns a Writer compressing at DefaultCompression.
+func NewWriter(w io.Writer) *Writer {
+	z, _ := NewWriterLevel(w, DefaultCompression)
+	return z
+}
+
+// NewWriterLevel returns a Writer compressing at level, from
+// NoCompression to BestCompression, or DefaultCompression.
+func NewWriterLevel(w io.Writer, level int) (*Writer, error) {
+	if level != DefaultCompression && (level < NoCompression || level > BestCompression) {
+		return nil, fmt.Errorf("zlibc: invalid compression level %d", level)
+	}
+	s := newStream(false)
+	if rc := s.initDeflate(level); rc != C.Z_OK {
+		err := zError("deflateInit", rc, s)
+		freeStream(s)
+		return nil, err
+	}
+	z := &Writer{w: w, s: s}
+	z.cleanup = runtime.AddCleanup(z, freeStream, s)
+	return z, nil
+}
+
+// Write compresses p. Output is written to the underlying writer as
+// zlib's buffer fills, so not all of p may have reached it yet.
+func (z *Writer) Write(p []byte) (int, error) {
+	if z.closed {
+		return 0, ErrClosed
+	}
+	if z.err != nil {
+		return 0, z.err
+	}
+	written := 0
+	for len(p) > 0 {
+		n := copy(z.s.in, p)
+		z.s.setIn(n)
+		if err := z.deflate(C.Z_NO_FLUSH); err != nil {
+			return written, err
+		}
+		written += n
+		p = p[n:]
+	}
+	return written, nil
+}
+
+// deflate runs deflate with flush until it has consumed all input and, for
+// Z_SYNC_FLUSH and Z_FINISH, written out everything, passing each full or
+// final output buffer on.
+func (z *Writer) deflate(flush C.int) error {
+	for {
+		z.s.setOut()
+		rc := C.deflate(z.s.z, flush)
+		if rc != C.Z_OK && rc != C.Z_STREAM_END && rc != C.Z_BUF_ERROR {
+			z.err = zError("deflate", rc, z.s)
+			return z.err
+		}
+		if out := z.s.produced(); len(out) > 0 {
+			if _, err := z.w.Write(out); err != nil {
+				z.err = err
+				return err
+			}
+		}
+		// A buffer left with room means deflate had nothing more to
+		// give for now; Z_FINISH is done only at the end of the stream.
+		if flush == C.Z_FINISH {
+			if rc == C.Z_STREAM_END {
+				return nil
+			}
+		} else if z.s.z.avail_out != 0 {
+			return nil
+		}
+	}
+}
+
+// Flush writes out everything compressed so far, so that a reader can
+// decompress all the data written, at some cost in compression.
+func (z *Writer) Flush() error {
+	if z.closed {
+		return ErrClosed
+	}
+	if z.err != nil {
+		return z.err
+	}
+	z.s.setIn(0)
+	return z.deflate(C.Z_SYNC_FLUSH)
+}
+
+// Close finishes the stream, writing the rest of it to the underlying
+// writer, and frees the Writer's C memory. It does not close the
+// underlying writer.
+func (z *Writer) Close() error {
+	if z.closed {
+		return ErrClosed
+	}
+	var err error
+	if z.err == nil {
+		z.s.setIn(0)
+		err = z.deflate(C.Z_FINISH)
+	}
+	z.closed = true
+	z.cleanup.Stop()
+	freeStream(z.s)
+	z.s = nil
+	return err
+}
+
+// Reset discards the Writer's state and makes it write a new stream to
+// w, at the same level, reusing its C memory. It cannot revive a closed
+// Writer.
+func (z *Writer) Reset(w io.Writer) error {
+	if z.closed {
+		return ErrClosed
+	}
+	if rc := C.deflateReset(z.s.z); rc != C.Z_OK {
+		return zError("deflateReset", rc, z.s)
+	}
+	z.w, z.err = w, nil
+	return nil
+}
@@ -0,0 +1,130 @@
+package zlibc
+
+/*
+
+#cgo pkg-config: zlib
+#include <zlib.h>
+
+// deflateInit and inflateInit are macros.
+static int initDeflate(z_stream *s, int level) {
+	return deflateInit(s, level);
+}
+
+static int initInflate(z_stream *s) {
+	return inflateInit(s);
+}
+
+*/
+import "C"
+
+import (
+	"errors"
+	"fmt"
+	"unsafe"
+
+	"github.com/lxwagn/using-go-with-c-libraries/pkg/cmem"
+)
+
+// BufferSize is the size of each Writer's and Reader's C input and output
+// buffers.
+const BufferSize = 32 << 10
+
+// The compression levels, as in compress/zlib.
+const (
+	NoCompression      = C.Z_NO_COMPRESSION
+	BestSpeed          = C.Z_BEST_SPEED
+	BestCompression    = C.Z_BEST_COMPRESSION
+	DefaultCompression = C.Z_DEFAULT_COMPRESSION
+)
+
+var (
+	// ErrCorrupt is matched by errors for input that is not a valid zlib
+	// stream, including a wrong checksum.
+	ErrCorrupt = errors.New("zlibc: invalid data")
+	// ErrClosed is returned by a closed Writer or Reader.
+	ErrClosed = errors.New("zlibc: use of closed stream")
+)
+
+// Version returns the version of the zlib library linked in.
+func Version() string {
+	return C.GoString(C.zlibVersion())
+}
+
+// stream is the C side of a Writer or Reader: the z_stream and its two
+// buffers, in one allocation so that a single cleanup frees them.
+type stream struct {
+	z        *C.z_stream
+	in, out  []byte // views of the C buffers
+	inflater bool
+}
+
+func newStream(inflater bool) *stream {
+	size := int(unsafe.Sizeof(C.z_stream{}))
+	mem := cmem.Calloc(1, size+2*BufferSize)
+	s := &stream{
+		z:        (*C.z_stream)(mem),
+		in:       cmem.View(unsafe.Add(mem, size), BufferSize),
+		out:      cmem.View(unsafe.Add(mem, size+BufferSize), BufferSize),
+		inflater: inflater,
+	}
+	return s
+}
+
+// The init macros are wrapped here, since C functions in this preamble
+// are not visible from the package's other files.
+func (s *stream) initDeflate(level int) C.int { return C.initDeflate(s.z, C.int(level)) }
+func (s *stream) initInflate() C.int          { return C.initInflate(s.z) }
+
+// setIn points zlib at the first n bytes of the input buffer, and setOut
+// at the whole output buffer.
+func (s *stream) setIn(n int) {
+	s.z.next_in = (*C.Bytef)(unsafe.Pointer(unsafe.SliceData(s.in)))
+	s.z.avail_in = C.uInt(n)
+}
+
+func (s *stream) setOut() {
+	s.z.next_out = (*C.Bytef)(unsafe.Pointer(unsafe.SliceData(s.out)))
+	s.z.avail_out = C.uInt(len(s.out))
+}
+
+// produced returns the part of the output buffer zlib filled since setOut.
+func (s *stream) produced() []byte {
+	return s.out[:len(s.out)-int(s.z.avail_out)]
+}
+
+func (s *stream) msg() string {
+	if s.z.msg == nil {
+		return ""
+	}
+	return C.GoString(s.z.msg)
+}
+
+// freeStream releases zlib's state and the memory. It runs from Close or, for a
+// stream never closed, from a cleanup: it must not touch the Writer or
+// Reader.
+func freeStream(s *stream) {
+	if s.inflater {
+		C.inflateEnd(s.z)
+	} else {
+		C.deflateEnd(s.z)
+	}
+	cmem.Free(unsafe.Pointer(s.z))
+}
+
+// zError turns a zlib result code other than Z_OK into an error.
+func zError(op string, rc C.int, s *stream) error {
+	switch rc {
+	case C.Z_DATA_ERROR, C.Z_NEED_DICT:
+		if msg := s.msg(); msg != "" {
+			return fmt.Errorf("%w: %s", ErrCorrupt, msg)
+		}
+		return ErrCorrupt
+	case C.Z_MEM_ERROR:
+		return fmt.Errorf("zlibc: %s: out of memory", op)
+	case C.Z_STREAM_ERROR:
+		return fmt.Errorf("zlibc: %s: invalid stream state or level", op)
+	case C.Z_VERSION_ERROR:
+		return fmt.Errorf("zlibc: %s: zlib version mismatch", op)
+	}
+	return fmt.Errorf("zlibc: %s: error %d %s", op, int(rc), s.msg())
+}
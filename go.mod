@@ -0,0 +1,7 @@
+module github.com/lxwagn/using-go-with-c-libraries
+
+go 1.26.0
+
+require github.com/ebitengine/purego v0.11.1
+
+require golang.org/x/sys v0.48.0
@@ -0,0 +1,30 @@
+// Package cgotest makes the cgo calls that tests compare against or
+// check with, since a _test.go file cannot import "C" itself.
+package cgotest
+
+/*
+#include <stdlib.h>
+*/
+import "C"
+
+import "unsafe"
+
+// CString calls C.CString.
+func CString(s string) unsafe.Pointer {
+	return unsafe.Pointer(C.CString(s))
+}
+
+// Free calls C.free.
+func Free(p unsafe.Pointer) {
+	C.free(p)
+}
+
+// CBytes calls C.CBytes.
+func CBytes(b []byte) unsafe.Pointer {
+	return C.CBytes(b)
+}
+
+// GoBytes calls C.GoBytes.
+func GoBytes(p unsafe.Pointer, n int) []byte {
+	return C.GoBytes(p, C.int(n))
+}
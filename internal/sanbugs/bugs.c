@@ -0,0 +1,51 @@
+//go:build asan || msan || tsan
+
+#include <stdlib.h>
+
+#include "bugs.h"
+
+/* Each function keeps its result observable so the compiler cannot drop
+ * the faulty access. */
+
+int sanOutOfBounds(void) {
+	volatile char *p = malloc(8);
+	int n = 8;
+	int v;
+
+	v = p[n]; /* one past the end */
+	free((void *)p);
+	return v;
+}
+
+int sanUseAfterFree(void) {
+	volatile int *p = malloc(sizeof(*p));
+	int v;
+
+	*p = 42;
+	free((void *)p);
+	v = *p; /* already freed */
+	return v;
+}
+
+int sanUninitialized(void) {
+	int *p = malloc(sizeof(*p));
+	int v = 0;
+
+	if (*p > 0) /* never written */
+		v = 1;
+	free(p);
+	return v;
+}
+
+static volatile int sanCounter;
+
+/* cgo orders each call into C after the previous ones for ThreadSanitizer,
+ * so only calls that overlap in time race. Many increments per call make
+ * overlapping calls likely, even on one CPU. */
+int sanRacyIncrement(void) {
+	int i;
+
+	for (i = 0; i < 1000000; i++)
+		sanCounter++; /* no lock, no atomic */
+	return sanCounter;
+}
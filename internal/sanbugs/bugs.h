@@ -0,0 +1,6 @@
+/* Deliberate memory bugs; see sanbugs.go. */
+
+int sanOutOfBounds(void);
+int sanUseAfterFree(void);
+int sanUninitialized(void);
+int sanRacyIncrement(void);
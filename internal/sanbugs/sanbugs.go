@@ -0,0 +1,41 @@
+//go:build asan || msan || tsan
+
+// Package sanbugs holds deliberately broken C code for seeing sanitizer
+// reports travel through a Go program. Each function commits one class of
+// bug; built with go build -asan or -msan, calling it makes the sanitizer
+// print a report and abort the process. RacyIncrement is the exception:
+// the race detector cannot see C, so it needs the C code compiled with
+// -fsanitize=thread and the tsan tag (see the tsan target in the
+// Makefile), and ThreadSanitizer reports the race when the process exits.
+//
+// It is only built with one of those flags or the tag, and nothing
+// outside cmd/selfcheck should import it.
+package sanbugs
+
+// #include "bugs.h"
+import "C"
+
+// OutOfBounds reads one byte past the end of a heap allocation. ASan
+// reports a heap-buffer-overflow.
+func OutOfBounds() int {
+	return int(C.sanOutOfBounds())
+}
+
+// UseAfterFree reads a heap allocation after freeing it. ASan reports a
+// heap-use-after-free.
+func UseAfterFree() int {
+	return int(C.sanUseAfterFree())
+}
+
+// Uninitialized branches on heap memory that was never written. MSan
+// reports a use-of-uninitialized-value; ASan does not detect it.
+func Uninitialized() int {
+	return int(C.sanUninitialized())
+}
+
+// RacyIncrement increments a C counter many times without synchronization
+// and returns its new value. Called from two goroutines at once, it races,
+// and ThreadSanitizer reports a data race in sanRacyIncrement.
+func RacyIncrement() int {
+	return int(C.sanRacyIncrement())
+}
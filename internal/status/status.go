@@ -0,0 +1,55 @@
+// Package status holds the errors for the C library's status codes, which
+// pkg/mylib, linking the library, and pkg/dynload and the versioned
+// bindings in pkg/mylib/v1 and v2, loading it at run time, all return.
+// Sharing one set of sentinels means an error from any of them matches
+// the others' with errors.Is.
+package status
+
+import (
+	"errors"
+
+	"github.com/lxwagn/using-go-with-c-libraries/pkg/cerr"
+)
+
+// The status codes of enum myStatus in mylib.h. pkg/mylib checks them
+// against its generated Status constants.
+const (
+	OK       = 0 // MYLIB_OK
+	NotFound = 1 // MYLIB_ENOTFOUND
+	Invalid  = 2 // MYLIB_EINVAL
+	Range    = 3 // MYLIB_ERANGE
+	NoMemory = 5 // MYLIB_ENOMEM
+)
+
+// Codes maps the status codes to the errors below.
+var Codes = cerr.NewTable("mylib")
+
+// ErrNUL is returned when a string passed to the library contains a NUL
+// byte. C would silently stop reading at that byte.
+var ErrNUL = errors.New("mylib: string contains NUL byte")
+
+// Errors returned by the C library.
+var (
+	ErrNotFound = Codes.Register(NotFound, errors.New("mylib: not found"))
+	ErrInvalid  = Codes.Register(Invalid, errors.New("mylib: invalid argument"))
+	ErrRange    = Codes.Register(Range, errors.New("mylib: value out of range"))
+	ErrNoMemory = Codes.Register(NoMemory, errors.New("mylib: out of memory"))
+)
+
+// Of returns the status code behind err: OK for a nil err, and false if
+// err is not one of the errors above.
+func Of(err error) (int, bool) {
+	switch {
+	case err == nil:
+		return OK, true
+	case errors.Is(err, ErrNotFound):
+		return NotFound, true
+	case errors.Is(err, ErrInvalid):
+		return Invalid, true
+	case errors.Is(err, ErrRange):
+		return Range, true
+	case errors.Is(err, ErrNoMemory):
+		return NoMemory, true
+	}
+	return 0, false
+}
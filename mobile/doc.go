@@ -0,0 +1,30 @@
+// Package mylibmobile is the part of pkg/mylib that an Android app can
+// use, through the Java classes gomobile bind generates from it:
+//
+//	gomobile bind -target=android -javapkg=com.example -o bin/mylib.aar ./mobile
+//
+// or make aar. The app then calls the library like any Java code:
+//
+//	import com.example.mylibmobile.Mylibmobile;
+//	import com.example.mylibmobile.Session;
+//
+//	long sum = Mylibmobile.checksum(data);
+//	Session s = Mylibmobile.newSession("steps", 1000);
+//	s.add(5);
+//
+// gomobile only binds functions, methods, fields and constants whose
+// types are signed integers, floats, bool, string, []byte, error, or
+// pointers to this package's structs and interfaces, and skips anything
+// else with no more than a warning. The package therefore exports nothing
+// of any other type, and translates at its edge what pkg/mylib uses
+// instead: unsigned results widen to int64, a context becomes a Task that
+// Java code can cancel, a func becomes the Callback interface, which a
+// Java class can implement, and a status error becomes the int StatusOf
+// returns for it. Errors reach Java as exceptions carrying the message.
+//
+// pkg/mylib compiles the C library from source when built for Android, so
+// gomobile's NDK compiler builds it along with the Go code, once per ABI,
+// and the .aar carries it inside the Go library rather than as a
+// libmylib.so of its own. Like pkg/mylib, the package also builds for the
+// host, which is how it is vetted and tested here.
+package mylibmobile
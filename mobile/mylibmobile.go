@@ -0,0 +1,104 @@
+package mylibmobile
+
+import (
+	"context"
+
+	"github.com/lxwagn/using-go-with-c-libraries/pkg/mylib"
+)
+
+// The library's status codes, as StatusOf returns them.
+const (
+	StatusOK       = int(mylib.StatusOK)
+	StatusNotFound = int(mylib.StatusNotFound)
+	StatusInvalid  = int(mylib.StatusInvalid)
+	StatusRange    = int(mylib.StatusRange)
+	StatusNoMemory = int(mylib.StatusNoMemory)
+)
+
+// StatusOf returns the library status code for err, StatusOK for nil, or
+// -1 if err did not come from the library.
+func StatusOf(err error) int {
+	st, ok := mylib.StatusOf(err)
+	if !ok {
+		return -1
+	}
+	return int(st)
+}
+
+// Version returns the C library's version, such as "1.4.0", or
+// "unversioned" for a build too old to report one.
+func Version() string {
+	v, ok := mylib.Features().Version()
+	if !ok {
+		return "unversioned"
+	}
+	return v.String()
+}
+
+// Print writes s and a newline to the C library's standard output, which
+// on Android goes nowhere unless the app redirects it.
+func Print(s string) error {
+	return mylib.Print(s)
+}
+
+// Checksum returns the C library's checksum of b. The checksum is 32
+// bits; it is widened so that Java sees it unsigned.
+func Checksum(b []byte) int64 {
+	return int64(mylib.Checksum(b))
+}
+
+// Lookup returns the value the C library holds for key, or an error with
+// status StatusNotFound if there is none.
+func Lookup(key string) (int, error) {
+	return mylib.Lookup(key)
+}
+
+// Reverse returns s with its characters in reverse order.
+func Reverse(s string) (string, error) {
+	return mylib.WideReverse(s)
+}
+
+// A Callback is called by CallN. Java code implements it with a class of
+// its own.
+type Callback interface {
+	Call(value int) int
+}
+
+// CallN has the C library call cb with 0, 1, ..., n-1 and returns the sum
+// of the results, or 0 for a nil cb. cb must not call back into this
+// package.
+func CallN(n int, cb Callback) int {
+	if cb == nil {
+		return 0
+	}
+	return mylib.CallN(n, cb.Call)
+}
+
+// A Task runs the C library's long computations so that another thread
+// can cancel them, standing in for the context pkg/mylib takes.
+type Task struct {
+	ctx    context.Context
+	cancel context.CancelFunc
+}
+
+// NewTask returns a Task that has not been cancelled.
+func NewTask() *Task {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &Task{ctx: ctx, cancel: cancel}
+}
+
+// Crunch runs iterations rounds of the C library's computation and
+// returns its result, or an error if the task is cancelled first.
+func (t *Task) Crunch(iterations int64) (int64, error) {
+	return mylib.Crunch(t.ctx, iterations)
+}
+
+// Cancel stops the Crunch in progress, if any, and every later one.
+func (t *Task) Cancel() {
+	t.cancel()
+}
+
+// Cancelled reports whether Cancel has been called.
+func (t *Task) Cancelled() bool {
+	return t.ctx.Err() != nil
+}
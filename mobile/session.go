@@ -0,0 +1,55 @@
+package mylibmobile
+
+import "github.com/lxwagn/using-go-with-c-libraries/pkg/mylib"
+
+// A Session is a pkg/mylib Session: a named running total in the C
+// library, bounded by a limit. Close it when done; the Java object being
+// collected frees it too, but only eventually.
+type Session struct {
+	s *mylib.Session
+}
+
+// NewSession creates a session whose total must stay within [-limit,
+// limit].
+func NewSession(name string, limit int64) (*Session, error) {
+	s, err := mylib.NewSession(name, limit)
+	if err != nil {
+		return nil, err
+	}
+	return &Session{s: s}, nil
+}
+
+// Name returns the name the session was created with.
+func (s *Session) Name() string {
+	return s.s.Name()
+}
+
+// Add adds delta to the total and returns the new total. The total is
+// left unchanged, and the error's status is StatusRange, if it would
+// leave the limit.
+func (s *Session) Add(delta int64) (int64, error) {
+	return s.s.Add(delta)
+}
+
+// Total returns the session's total.
+func (s *Session) Total() (int64, error) {
+	total, _, err := s.s.Stats()
+	return total, err
+}
+
+// Calls returns the number of successful Add calls since the session was
+// created or last reset.
+func (s *Session) Calls() (int, error) {
+	_, calls, err := s.s.Stats()
+	return calls, err
+}
+
+// Reset sets the total and the call count back to zero.
+func (s *Session) Reset() error {
+	return s.s.Reset()
+}
+
+// Close frees the C session. Calls after the first return an error.
+func (s *Session) Close() error {
+	return s.s.Close()
+}
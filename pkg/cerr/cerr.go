@@ -0,0 +1,99 @@
+// Package cerr turns the error reports of C functions into Go errors.
+//
+// C libraries report failure in two ways: by returning a library-specific
+// status code, or by returning a marker such as -1 and setting errno.
+// A Table maps a library's status codes to sentinel errors, and Errno
+// wraps the errno value cgo hands back from a call. Both produce an
+// *Error that unwraps to the sentinel or syscall.Errno, so callers can use
+// errors.Is instead of comparing raw C ints.
+package cerr
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"syscall"
+)
+
+// Error describes a failed C call.
+type Error struct {
+	Op   string // name of the C function that failed
+	Code int    // status code or errno value
+	Err  error  // sentinel error or syscall.Errno
+}
+
+func (e *Error) Error() string {
+	return e.Op + ": " + e.Err.Error()
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// A Table maps the status codes of one C library to sentinel errors. The
+// code 0 always means success.
+type Table struct {
+	name string
+
+	mu    sync.RWMutex
+	codes map[int]error
+}
+
+// NewTable returns an empty table. name prefixes the messages of errors
+// for codes that were never registered.
+func NewTable(name string) *Table {
+	return &Table{name: name, codes: make(map[int]error)}
+}
+
+// Register maps code to sentinel and returns sentinel, so tables can be
+// filled in from var declarations:
+//
+//	var ErrNotFound = codes.Register(C.MYLIB_ENOTFOUND, errors.New("mylib: not found"))
+func (t *Table) Register(code int, sentinel error) error {
+	if code == 0 {
+		panic("cerr: code 0 means success")
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.codes[code] = sentinel
+	return sentinel
+}
+
+// Error returns nil if code is 0 and otherwise an *Error wrapping the
+// sentinel registered for code.
+func (t *Table) Error(op string, code int) error {
+	if code == 0 {
+		return nil
+	}
+
+	t.mu.RLock()
+	err, ok := t.codes[code]
+	t.mu.RUnlock()
+	if !ok {
+		err = fmt.Errorf("%s: unknown error code %d", t.name, code)
+	}
+	return &Error{Op: op, Code: code, Err: err}
+}
+
+// Errno wraps the error returned as the second result of a cgo call,
+// which is a syscall.Errno when the C function set errno. It returns nil
+// if err is nil. Since syscall.Errno implements Is, the result matches
+// fs.ErrNotExist, fs.ErrPermission and friends as well as the raw value.
+//
+// cgo reports errno even when the call succeeded, so only call Errno when
+// the function's return value indicates failure.
+func Errno(op string, err error) error {
+	if err == nil {
+		return nil
+	}
+
+	var errno syscall.Errno
+	if !errors.As(err, &errno) {
+		return &Error{Op: op, Err: err}
+	}
+	if errno == 0 {
+		return nil
+	}
+	return &Error{Op: op, Code: int(errno), Err: errno}
+}
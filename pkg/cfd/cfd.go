@@ -0,0 +1,62 @@
+//go:build !windows
+
+package cfd
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"syscall"
+)
+
+// Adopt takes ownership of fd and returns an *os.File for it. From then on
+// only the file may close fd, and it does so when closed or, failing that,
+// when garbage collected. If Adopt fails, it has closed fd already, so the
+// caller never needs to.
+func Adopt(fd int, name string) (*os.File, error) {
+	if fd < 0 {
+		return nil, fmt.Errorf("cfd: adopt %s: invalid descriptor %d", name, fd)
+	}
+	syscall.CloseOnExec(fd)
+	if err := syscall.SetNonblock(fd, true); err != nil {
+		syscall.Close(fd)
+		return nil, fmt.Errorf("cfd: adopt %s: %w", name, err)
+	}
+	return os.NewFile(uintptr(fd), name), nil
+}
+
+// Borrow returns an *os.File for a duplicate of fd, which stays open and
+// owned by whoever had it. Closing the file closes only the duplicate.
+func Borrow(fd int, name string) (*os.File, error) {
+	if fd < 0 {
+		return nil, fmt.Errorf("cfd: borrow %s: invalid descriptor %d", name, fd)
+	}
+	// Hold off forks while the duplicate is not yet close-on-exec, as the
+	// os package does, so that no child inherits it.
+	syscall.ForkLock.RLock()
+	dup, err := syscall.Dup(fd)
+	if err == nil {
+		syscall.CloseOnExec(dup)
+	}
+	syscall.ForkLock.RUnlock()
+	if err != nil {
+		return nil, fmt.Errorf("cfd: borrow %s: %w", name, err)
+	}
+	return Adopt(dup, name)
+}
+
+// Conn returns a net.Conn for the socket f and closes f. net.FileConn
+// works on a duplicate of its own, so without the close the process
+// would keep a second copy of the socket open, and the peer would never
+// see end of file.
+func Conn(f *os.File) (net.Conn, error) {
+	c, err := net.FileConn(f)
+	if cerr := f.Close(); err == nil && cerr != nil {
+		c.Close()
+		err = cerr
+	}
+	if err != nil {
+		return nil, fmt.Errorf("cfd: %s: %w", f.Name(), err)
+	}
+	return c, nil
+}
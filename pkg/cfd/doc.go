@@ -0,0 +1,22 @@
+// Package cfd turns file descriptors that come from C into *os.File and
+// net.Conn values, so that Go reads and writes them through the runtime's
+// network poller instead of blocking a thread per call.
+//
+// What matters is who closes the descriptor. A C function that hands its
+// caller a descriptor to close is transferring it, and Adopt takes it
+// over: the returned *os.File closes it, and C must not. A descriptor the
+// library keeps and closes itself can only be borrowed, and Borrow works
+// on a duplicate, so that closing the *os.File never closes C's copy and
+// C closing its own never pulls the descriptor out from under Go:
+//
+//	f, err := cfd.Adopt(int(C.open_thing()), "thing") // Go closes it
+//	g, err := cfd.Borrow(int(C.thing_fd()), "thing")  // C closes its own
+//
+// Either way the descriptor is made non-blocking, which os.NewFile needs
+// before it registers a descriptor with the poller. A duplicate shares
+// that flag with the original, so C code reading a borrowed descriptor
+// itself must cope with EAGAIN.
+//
+// The package is not available on Windows, whose handles are not file
+// descriptors.
+package cfd
@@ -0,0 +1,165 @@
+// Package cgocheck checks values against the cgo pointer-passing rules
+// before they are handed to C.
+//
+// The rules (see https://pkg.go.dev/cmd/cgo#hdr-Passing_pointers) allow Go
+// code to pass C a pointer to Go memory only if that memory holds no
+// unpinned Go pointers. The runtime enforces this when GODEBUG=cgocheck=1,
+// the default, by panicking in the call; a program built with
+// GOEXPERIMENT=cgocheck2 also checks every store of a Go pointer into C
+// memory. Check finds the same mistakes as an error, before any C code
+// runs, and says which field is to blame.
+//
+// Check cannot tell Go memory from C memory. It assumes that an
+// unsafe.Pointer, and a pointer to a type cgo generated (C.char,
+// C.struct_foo and so on), point to C memory and are fine to pass along;
+// any other pointer inside the memory being passed is reported, even if it
+// happens to be pinned.
+package cgocheck
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// An Error describes an argument that breaks the pointer-passing rules.
+type Error struct {
+	Arg    int    // index of the argument
+	Path   string // where in it the Go pointer is, such as "[2].next"
+	Reason string
+}
+
+func (e *Error) Error() string {
+	if e.Path == "" {
+		return fmt.Sprintf("cgocheck: argument %d: %s", e.Arg, e.Reason)
+	}
+	return fmt.Sprintf("cgocheck: argument %d: %s: %s", e.Arg, e.Path, e.Reason)
+}
+
+// Check reports whether each argument may be passed to C.
+//
+// A pointer argument stands for the memory it points to, and a slice for
+// its backing array, which is what C receives; a struct or array argument
+// is checked field by field, as if each were an argument. Strings are
+// accepted, since cgo lets a Go string be passed as _GoString_, as are
+// scalars. Maps, channels and functions are always rejected.
+func Check(args ...any) error {
+	for i, arg := range args {
+		if err := checkArg(i, reflect.ValueOf(arg)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// SafePass calls f, which should pass args to C, only if Check accepts
+// them, and returns Check's error otherwise.
+func SafePass(f func(), args ...any) error {
+	if err := Check(args...); err != nil {
+		return err
+	}
+	f()
+	return nil
+}
+
+func checkArg(i int, v reflect.Value) *Error {
+	if !v.IsValid() {
+		return nil
+	}
+	return wrap(i, checkPassed(v, ""))
+}
+
+// checkPassed checks v, which C receives by value: a pointer in it may
+// point to Go memory as long as that memory holds no Go pointers.
+func checkPassed(v reflect.Value, path string) *pathError {
+	switch v.Kind() {
+	case reflect.Pointer:
+		if v.IsNil() || isCType(v.Type().Elem()) {
+			return nil
+		}
+		return findGoPointer(v.Elem(), path)
+	case reflect.Slice:
+		for j := range v.Len() {
+			if err := findGoPointer(v.Index(j), fmt.Sprintf("%s[%d]", path, j)); err != nil {
+				return err
+			}
+		}
+	case reflect.Map, reflect.Chan, reflect.Func:
+		return &pathError{path, v.Kind().String() + " cannot be passed to C"}
+	case reflect.Interface:
+		if !v.IsNil() {
+			return checkPassed(v.Elem(), path)
+		}
+	case reflect.Struct:
+		t := v.Type()
+		for j := range v.NumField() {
+			if err := checkPassed(v.Field(j), path+"."+t.Field(j).Name); err != nil {
+				return err
+			}
+		}
+	case reflect.Array:
+		for j := range v.Len() {
+			if err := checkPassed(v.Index(j), fmt.Sprintf("%s[%d]", path, j)); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// pathError is an Error whose argument index is not known yet.
+type pathError struct {
+	path, reason string
+}
+
+func wrap(i int, err *pathError) *Error {
+	if err == nil {
+		return nil
+	}
+	return &Error{Arg: i, Path: strings.TrimPrefix(err.path, "."), Reason: err.reason}
+}
+
+// findGoPointer looks for a Go pointer stored in v.
+func findGoPointer(v reflect.Value, path string) *pathError {
+	switch v.Kind() {
+	case reflect.Pointer:
+		if v.IsNil() || isCType(v.Type().Elem()) {
+			return nil
+		}
+		return &pathError{path, "Go pointer to Go pointer"}
+	case reflect.Slice:
+		if v.IsNil() {
+			return nil
+		}
+		return &pathError{path, "slice of Go memory inside Go memory"}
+	case reflect.String:
+		if v.Len() == 0 {
+			return nil
+		}
+		return &pathError{path, "string inside Go memory"}
+	case reflect.Map, reflect.Chan, reflect.Func, reflect.Interface:
+		if v.IsNil() {
+			return nil
+		}
+		return &pathError{path, v.Kind().String() + " inside Go memory"}
+	case reflect.Struct:
+		t := v.Type()
+		for j := range v.NumField() {
+			if err := findGoPointer(v.Field(j), path+"."+t.Field(j).Name); err != nil {
+				return err
+			}
+		}
+	case reflect.Array:
+		for j := range v.Len() {
+			if err := findGoPointer(v.Index(j), fmt.Sprintf("%s[%d]", path, j)); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// isCType reports whether t is a type cgo generated for a C type.
+func isCType(t reflect.Type) bool {
+	return strings.HasPrefix(t.Name(), "_Ctype_")
+}
@@ -0,0 +1,95 @@
+package cmem
+
+/*
+#include <stdlib.h>
+#include <string.h>
+*/
+import "C"
+
+import "unsafe"
+
+// Malloc allocates n bytes of uninitialized C memory, like C.malloc. It
+// panics if the C allocator is out of memory.
+func Malloc(n int) unsafe.Pointer {
+	if n < 0 {
+		panic("cmem: negative allocation size")
+	}
+	p := C.malloc(C.size_t(max(n, 1)))
+	if p == nil {
+		panic("cmem: out of memory")
+	}
+	account(p, n)
+	track(p, n)
+	return p
+}
+
+// Calloc allocates zeroed C memory for count elements of size bytes, like
+// C.calloc. It panics if the C allocator is out of memory.
+func Calloc(count, size int) unsafe.Pointer {
+	if count < 0 || size < 0 {
+		panic("cmem: negative allocation size")
+	}
+	p := C.calloc(C.size_t(max(count, 1)), C.size_t(max(size, 1)))
+	if p == nil {
+		panic("cmem: out of memory")
+	}
+	account(p, count*size)
+	track(p, count*size)
+	return p
+}
+
+// Realloc resizes the allocation at p to n bytes, like C.realloc, and
+// returns its new address; p is nil for a new allocation. It panics if
+// the C allocator is out of memory, leaving p allocated.
+func Realloc(p unsafe.Pointer, n int) unsafe.Pointer {
+	if n < 0 {
+		panic("cmem: negative allocation size")
+	}
+	if p == nil {
+		return Malloc(n)
+	}
+	q := C.realloc(p, C.size_t(max(n, 1)))
+	if q == nil {
+		panic("cmem: out of memory")
+	}
+	untrack(p)
+	reaccount(p, q, n)
+	track(q, n)
+	return q
+}
+
+// CString returns a NUL-terminated C copy of s, like C.CString. The
+// caller must release it with Free.
+func CString(s string) unsafe.Pointer {
+	p := Malloc(len(s) + 1)
+	if len(s) > 0 {
+		C.memcpy(p, unsafe.Pointer(unsafe.StringData(s)), C.size_t(len(s)))
+	}
+	*(*byte)(unsafe.Add(p, len(s))) = 0
+	return p
+}
+
+// Free releases memory allocated by this package. Memory the C library
+// allocated can be freed with it as well; it is just not tracked.
+func Free(p unsafe.Pointer) {
+	if p == nil {
+		return
+	}
+	untrack(p)
+	release(p)
+	C.free(p)
+}
+
+// A Leak is a C allocation that is still live.
+type Leak struct {
+	Ptr  unsafe.Pointer
+	Size int
+	Site string // file:line of the first caller outside this package
+}
+
+// TB is the part of testing.TB that CheckLeaks uses.
+type TB interface {
+	Helper()
+	Cleanup(func())
+	Errorf(format string, args ...any)
+}
@@ -0,0 +1,124 @@
+// Package cmem provides helpers for managing C memory from Go.
+//
+// Calling C.CString and C.free for every argument means one malloc and
+// one free per call. An Arena hands out memory from a few large C blocks
+// and releases all of it at once, and a StringCache keeps C copies of
+// strings that are passed over and over again.
+//
+// Malloc, Calloc, Realloc, CString and Free stand in for their C and cgo
+// namesakes, and everything else in the package allocates through them.
+// Built with -tags cmemdbg, they record the size and call site of every
+// allocation until it is freed; DumpLeaks and CheckLeaks then report the C
+// memory a program or test forgot to free. After EnableStats they also
+// keep the totals that ReadStats returns, and pkg/cmetrics exports.
+//
+// Memory that a C function allocates and hands to its caller can be taken
+// over with TakeCString and TakeCBytes, which copy it into Go and free it,
+// or with Adopt, which frees it later. CStrings builds the NULL-terminated
+// char * array that argv-style parameters take, in one allocation.
+// BorrowString and BorrowSlice skip the copy altogether for C calls that
+// take a length and only read the memory while they run.
+//
+// Pointers are returned as unsafe.Pointer because C types are local to
+// the package that imports "C"; convert them with (*C.char)(p) and so on.
+package cmem
+
+/*
+#include <stdlib.h>
+#include <string.h>
+*/
+import "C"
+
+import "unsafe"
+
+const (
+	// align is the alignment of every allocation, large enough for any
+	// scalar C type.
+	align = 16
+
+	// DefaultBlockSize is the size of the C blocks an Arena allocates
+	// when none is given to NewArena.
+	DefaultBlockSize = 4096
+)
+
+// An Arena allocates C memory from large blocks and frees all of it with
+// a single call to Free. It is meant for short-lived allocations such as
+// the arguments of one batch of C calls.
+//
+// An Arena is not safe for concurrent use.
+type Arena struct {
+	blockSize int
+	blocks    []unsafe.Pointer
+	cur       unsafe.Pointer // start of the current block
+	off       int            // bytes used in the current block
+	size      int            // size of the current block
+}
+
+// NewArena returns an Arena that allocates blocks of blockSize bytes.
+// A blockSize of zero or less means DefaultBlockSize.
+func NewArena(blockSize int) *Arena {
+	if blockSize <= 0 {
+		blockSize = DefaultBlockSize
+	}
+	return &Arena{blockSize: blockSize}
+}
+
+// Alloc returns n bytes of zeroed C memory that stay valid until Free is
+// called. It panics if the C allocator is out of memory.
+func (a *Arena) Alloc(n int) unsafe.Pointer {
+	if n < 0 {
+		panic("cmem: negative allocation size")
+	}
+	if n == 0 {
+		n = 1
+	}
+
+	off := (a.off + align - 1) &^ (align - 1)
+	if a.cur == nil || off+n > a.size {
+		// Requests larger than a block get a block of their own so the
+		// rest of the current block is not wasted.
+		size := a.blockSize
+		if n > size {
+			size = n
+		}
+		p := Calloc(1, size)
+		a.blocks = append(a.blocks, p)
+		if n > a.blockSize {
+			return p
+		}
+		a.cur, a.off, a.size = p, 0, size
+		off = 0
+	}
+
+	a.off = off + n
+	return unsafe.Add(a.cur, off)
+}
+
+// CString copies s into the arena as a NUL-terminated C string. Like
+// C.CString, a NUL byte inside s ends the string early on the C side.
+func (a *Arena) CString(s string) unsafe.Pointer {
+	p := a.Alloc(len(s) + 1)
+	if len(s) > 0 {
+		C.memcpy(p, unsafe.Pointer(unsafe.StringData(s)), C.size_t(len(s)))
+	}
+	return p
+}
+
+// Bytes copies b into the arena and returns a pointer to the copy.
+func (a *Arena) Bytes(b []byte) unsafe.Pointer {
+	p := a.Alloc(len(b))
+	if len(b) > 0 {
+		C.memcpy(p, unsafe.Pointer(unsafe.SliceData(b)), C.size_t(len(b)))
+	}
+	return p
+}
+
+// Free releases every allocation made from the arena. The arena can be
+// used again afterwards.
+func (a *Arena) Free() {
+	for _, p := range a.blocks {
+		Free(p)
+	}
+	a.blocks = a.blocks[:0]
+	a.cur, a.off, a.size = nil, 0, 0
+}
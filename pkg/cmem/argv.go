@@ -0,0 +1,65 @@
+package cmem
+
+/*
+#include <string.h>
+*/
+import "C"
+
+import "unsafe"
+
+const ptrSize = int(unsafe.Sizeof(uintptr(0)))
+
+// CStrings returns a C copy of ss as an argv-style array: len(ss) char *
+// pointers followed by a NULL, each pointing to a NUL-terminated copy of
+// one string. The array and the strings share a single allocation, so
+// one call to Free releases them all. Like C.CString, a NUL byte inside a
+// string ends it early on the C side.
+func CStrings(ss []string) unsafe.Pointer {
+	size := (len(ss) + 1) * ptrSize
+	for _, s := range ss {
+		size += len(s) + 1
+	}
+	p := Malloc(size)
+	ptrs := unsafe.Slice((*unsafe.Pointer)(p), len(ss)+1)
+	data := unsafe.Add(p, (len(ss)+1)*ptrSize)
+	for i, s := range ss {
+		ptrs[i] = data
+		if len(s) > 0 {
+			C.memcpy(data, unsafe.Pointer(unsafe.StringData(s)), C.size_t(len(s)))
+		}
+		*(*byte)(unsafe.Add(data, len(s))) = 0
+		data = unsafe.Add(data, len(s)+1)
+	}
+	ptrs[len(ss)] = nil
+	return p
+}
+
+// GoStrings returns Go copies of the strings in the NULL-terminated char *
+// array at p, such as one from CStrings or the environ of a C program. A
+// nil p gives nil.
+func GoStrings(p unsafe.Pointer) []string {
+	if p == nil {
+		return nil
+	}
+	var ss []string
+	for i := 0; ; i++ {
+		s := *(**C.char)(unsafe.Add(p, i*ptrSize))
+		if s == nil {
+			return ss
+		}
+		ss = append(ss, C.GoString(s))
+	}
+}
+
+// GoStringsN returns Go copies of the first n strings in the char * array
+// at p, which need not be NULL-terminated.
+func GoStringsN(p unsafe.Pointer, n int) []string {
+	if n == 0 {
+		return nil
+	}
+	ss := make([]string, n)
+	for i, s := range unsafe.Slice((**C.char)(p), n) {
+		ss[i] = C.GoString(s)
+	}
+	return ss
+}
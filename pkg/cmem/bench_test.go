@@ -0,0 +1,89 @@
+package cmem
+
+import (
+	"testing"
+	"unsafe"
+
+	"github.com/lxwagn/using-go-with-c-libraries/internal/cgotest"
+)
+
+// benchArgs are the string arguments of one batch of C calls.
+var benchArgs = []string{"open", "/var/lib/mylib/data", "rw", "create", "user", "mode=0644", "sync", "retries=3"}
+
+// BenchmarkArena converts one batch of arguments and frees them, with an
+// Arena and with a C.CString and C.free per string.
+func BenchmarkArena(b *testing.B) {
+	b.Run("Arena", func(b *testing.B) {
+		for b.Loop() {
+			a := NewArena(0)
+			for _, s := range benchArgs {
+				a.CString(s)
+			}
+			a.Free()
+		}
+	})
+	b.Run("CString", func(b *testing.B) {
+		ps := make([]unsafe.Pointer, len(benchArgs))
+		for b.Loop() {
+			for i, s := range benchArgs {
+				ps[i] = cgotest.CString(s)
+			}
+			for _, p := range ps {
+				cgotest.Free(p)
+			}
+		}
+	})
+}
+
+// BenchmarkStringCache passes the same strings to C over and over, from
+// a StringCache and with a C.CString and C.free per use.
+func BenchmarkStringCache(b *testing.B) {
+	b.Run("StringCache", func(b *testing.B) {
+		c := NewStringCache()
+		defer c.Close()
+		for b.Loop() {
+			for _, s := range benchArgs {
+				c.CString(s)
+			}
+		}
+	})
+	b.Run("CString", func(b *testing.B) {
+		for b.Loop() {
+			for _, s := range benchArgs {
+				cgotest.Free(cgotest.CString(s))
+			}
+		}
+	})
+}
+
+// BenchmarkView reads a multi-megabyte C buffer from Go, through View,
+// Copy and C.GoBytes.
+func BenchmarkView(b *testing.B) {
+	const n = 8 << 20
+	p := Calloc(1, n)
+	defer Free(p)
+	sum := func(b []byte) (s byte) {
+		for _, c := range b {
+			s += c
+		}
+		return s
+	}
+	b.Run("View", func(b *testing.B) {
+		b.SetBytes(n)
+		for b.Loop() {
+			sum(View(p, n))
+		}
+	})
+	b.Run("Copy", func(b *testing.B) {
+		b.SetBytes(n)
+		for b.Loop() {
+			sum(Copy(p, n))
+		}
+	})
+	b.Run("GoBytes", func(b *testing.B) {
+		b.SetBytes(n)
+		for b.Loop() {
+			sum(cgotest.GoBytes(p, n))
+		}
+	})
+}
@@ -0,0 +1,37 @@
+package cmem
+
+import "unsafe"
+
+// Copying a Go string or slice into C memory for every call costs an
+// allocation, a copy and a free, which for a C function that only reads
+// its argument during the call is wasted work. BorrowString and
+// BorrowSlice pass the Go memory itself. This is allowed by the cgo
+// pointer rules as long as
+//
+//   - the C function does not keep the pointer after it returns,
+//   - the memory holds no Go pointers, which cgocheck verifies, and,
+//     for strings, C does not write to it: a string's bytes may be in
+//     read-only memory, and other strings may share them.
+//
+// A Go string has no terminating NUL, so a borrowed string only suits C
+// parameters that come with a length, such as a (const char *, size_t)
+// pair. A function wanting a NUL-terminated string still needs CString.
+
+// BorrowString returns a pointer to the bytes of s, for a C call that
+// takes them together with len(s). The pointer is nil for an empty s.
+func BorrowString(s string) unsafe.Pointer {
+	if len(s) == 0 {
+		return nil
+	}
+	return unsafe.Pointer(unsafe.StringData(s))
+}
+
+// BorrowSlice returns a pointer to the first element of s, for a C call
+// that takes it together with len(s). T must not contain Go pointers. The
+// pointer is nil for an empty s.
+func BorrowSlice[T any](s []T) unsafe.Pointer {
+	if len(s) == 0 {
+		return nil
+	}
+	return unsafe.Pointer(unsafe.SliceData(s))
+}
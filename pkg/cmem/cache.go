@@ -0,0 +1,54 @@
+package cmem
+
+import (
+	"sync"
+	"unsafe"
+)
+
+// A StringCache keeps one C copy of each string it is asked for, so a
+// string that is passed to C repeatedly is only allocated once. The
+// copies stay valid until Close is called, so C code must not free them
+// and must not keep them after Close.
+//
+// A StringCache is safe for concurrent use.
+type StringCache struct {
+	mu      sync.Mutex
+	strings map[string]unsafe.Pointer
+}
+
+// NewStringCache returns an empty StringCache.
+func NewStringCache() *StringCache {
+	return &StringCache{strings: make(map[string]unsafe.Pointer)}
+}
+
+// CString returns a NUL-terminated C copy of s, allocating it on first
+// use.
+func (c *StringCache) CString(s string) unsafe.Pointer {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if p, ok := c.strings[s]; ok {
+		return p
+	}
+	p := CString(s)
+	c.strings[s] = p
+	return p
+}
+
+// Len returns the number of strings in the cache.
+func (c *StringCache) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return len(c.strings)
+}
+
+// Close frees every C string held by the cache and empties it.
+func (c *StringCache) Close() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for s, p := range c.strings {
+		Free(p)
+		delete(c.strings, s)
+	}
+}
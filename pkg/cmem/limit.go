@@ -0,0 +1,79 @@
+package cmem
+
+import (
+	"math"
+	"runtime/debug"
+)
+
+// The Go runtime's memory limit, GOMEMLIMIT or debug.SetMemoryLimit,
+// covers only what the runtime maps, so a process whose C heap is large
+// can pass its container's limit while the garbage collector, seeing a
+// small Go heap, stays idle. SetTotalMemoryLimit gives the C memory
+// counted here a share of the limit: the Go limit becomes what is left of
+// the total, and shrinks as C allocates, which makes the collector run
+// sooner and return memory to the system to make room.
+//
+// The state below is guarded by accounts.mu.
+var limit struct {
+	on      bool
+	total   int64
+	saved   int64 // the Go limit before SetTotalMemoryLimit took it over
+	applied int64 // LiveBytes when the Go limit was last set
+}
+
+// SetTotalMemoryLimit makes total the limit for the Go heap and the C
+// memory allocated through this package together, and returns the
+// previous total, math.MaxInt64 if there was none. A negative total only
+// reads it; math.MaxInt64 stops sharing the limit, and puts back the Go
+// limit that was in effect before.
+//
+// It enables the statistics, and only allocations counted by them take a
+// share: in pkg/mylib, those of the bindings and, under GoAllocator, the
+// library's own. The Go limit is set anew when the live C bytes have
+// moved by a sixty-fourth of total, or 1MiB if that is more, since the
+// last time, and is never set below a sixteenth of total, so that a C
+// heap filling the total by itself leaves the collector a goal to work
+// to rather than running it back to back. Other changes to the Go limit
+// are overwritten while the total is in effect.
+func SetTotalMemoryLimit(total int64) int64 {
+	EnableStats()
+	accounts.mu.Lock()
+	defer accounts.mu.Unlock()
+	prev := int64(math.MaxInt64)
+	if limit.on {
+		prev = limit.total
+	}
+	switch {
+	case total < 0:
+	case total == math.MaxInt64:
+		if limit.on {
+			limit.on = false
+			debug.SetMemoryLimit(limit.saved)
+		}
+	default:
+		if !limit.on {
+			limit.on = true
+			limit.saved = debug.SetMemoryLimit(-1)
+		}
+		limit.total = total
+		applyLimit()
+	}
+	return prev
+}
+
+// limitChanged sets the Go limit again if the live bytes have moved far
+// enough since it was last set. It is called with accounts.mu held.
+func limitChanged() {
+	if !limit.on {
+		return
+	}
+	d := accounts.LiveBytes - limit.applied
+	if max(d, -d) >= max(limit.total/64, 1<<20) {
+		applyLimit()
+	}
+}
+
+func applyLimit() {
+	limit.applied = accounts.LiveBytes
+	debug.SetMemoryLimit(max(limit.total-accounts.LiveBytes, limit.total/16))
+}
@@ -0,0 +1,92 @@
+package cmem
+
+import (
+	"runtime"
+	"unsafe"
+)
+
+// MallocBytes allocates n bytes of zeroed C memory and returns them as a
+// Go slice. No copy is ever made: writes through the slice are seen by C
+// and the other way around. The slice must be released with FreeBytes,
+// and must not be used afterwards.
+func MallocBytes(n int) []byte {
+	if n < 0 {
+		panic("cmem: negative allocation size")
+	}
+	return unsafe.Slice((*byte)(Calloc(1, n)), n)
+}
+
+// FreeBytes frees a slice returned by MallocBytes.
+func FreeBytes(b []byte) {
+	Free(unsafe.Pointer(unsafe.SliceData(b[:cap(b)])))
+}
+
+// View returns the n bytes of C memory at p as a Go slice without
+// copying. The slice is only valid for as long as the C memory is, and
+// the garbage collector will not keep that memory alive.
+func View(p unsafe.Pointer, n int) []byte {
+	if p == nil || n == 0 {
+		return nil
+	}
+	return unsafe.Slice((*byte)(p), n)
+}
+
+// Copy returns a Go copy of the n bytes of C memory at p, like C.GoBytes.
+// The result is independent of the C memory, at the price of copying n
+// bytes. Unlike C.GoBytes, whose length is a C int, it copies more than
+// 2 GiB whole.
+func Copy(p unsafe.Pointer, n int) []byte {
+	if p == nil || n == 0 {
+		return nil
+	}
+	return append([]byte(nil), unsafe.Slice((*byte)(p), n)...)
+}
+
+// CBytes returns a C copy of b, like C.CBytes. The caller must free it
+// with Free.
+func CBytes(b []byte) unsafe.Pointer {
+	p := Malloc(len(b))
+	copy(unsafe.Slice((*byte)(p), len(b)), b)
+	return p
+}
+
+// Pinned is a Go byte slice that may be held by C across calls.
+//
+// The cgo rules let a Go pointer be passed to C only for the duration of
+// the call. Pinning the slice's backing array lifts that restriction until
+// Unpin is called: the garbage collector will neither move nor free it.
+type Pinned struct {
+	b      []byte
+	pinner runtime.Pinner
+}
+
+// Pin pins the backing array of b. b must hold no Go pointers, which a
+// []byte never does, and must not be empty.
+func Pin(b []byte) *Pinned {
+	if len(b) == 0 {
+		panic("cmem: cannot pin an empty slice")
+	}
+	p := &Pinned{b: b}
+	p.pinner.Pin(unsafe.SliceData(b))
+	return p
+}
+
+// Pointer returns the address of the first byte of the pinned slice.
+func (p *Pinned) Pointer() unsafe.Pointer {
+	return unsafe.Pointer(unsafe.SliceData(p.b))
+}
+
+// Len returns the length of the pinned slice.
+func (p *Pinned) Len() int {
+	return len(p.b)
+}
+
+// Bytes returns the pinned slice.
+func (p *Pinned) Bytes() []byte {
+	return p.b
+}
+
+// Unpin releases the pin. C must no longer use the pointer afterwards.
+func (p *Pinned) Unpin() {
+	p.pinner.Unpin()
+}
@@ -0,0 +1,31 @@
+package cmem
+
+import (
+	"bytes"
+	"testing"
+	"unsafe"
+
+	"github.com/lxwagn/using-go-with-c-libraries/internal/cgotest"
+)
+
+// TestCopy checks that Copy gives what C.GoBytes does and shares no
+// memory with the C buffer.
+func TestCopy(t *testing.T) {
+	b := MallocBytes(1 << 20)
+	defer FreeBytes(b)
+	for i := range b {
+		b[i] = byte(i * 7)
+	}
+	p := unsafe.Pointer(&b[0])
+	c := Copy(p, len(b))
+	if !bytes.Equal(c, cgotest.GoBytes(p, len(b))) {
+		t.Fatal("Copy differs from C.GoBytes")
+	}
+	b[0]++
+	if c[0] == b[0] {
+		t.Error("Copy shares memory with the C buffer")
+	}
+	if got := Copy(nil, 10); got != nil {
+		t.Errorf("Copy(nil, 10) = %v, want nil", got)
+	}
+}
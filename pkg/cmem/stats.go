@@ -0,0 +1,102 @@
+package cmem
+
+import (
+	"sync"
+	"sync/atomic"
+	"unsafe"
+)
+
+// Stats describes the C memory allocated through this package, which the
+// Go runtime's own statistics, such as runtime.MemStats, never see.
+type Stats struct {
+	LiveBytes int64  // bytes allocated and not yet freed
+	PeakBytes int64  // the highest LiveBytes has been
+	Allocs    uint64 // allocations made
+	Frees     uint64 // allocations freed
+}
+
+// Live returns the number of allocations not yet freed.
+func (s Stats) Live() uint64 {
+	return s.Allocs - s.Frees
+}
+
+// counting is off until EnableStats. Accounting needs a map entry per
+// live allocation, to know at Free how many bytes it releases, and that
+// shows next to a malloc: about 100ns on CString and Free together.
+var (
+	counting atomic.Bool
+	accounts struct {
+		mu    sync.Mutex
+		sizes map[unsafe.Pointer]int
+		Stats
+	}
+)
+
+// EnableStats starts keeping the statistics ReadStats returns. They cover
+// the allocations made from then on; freeing older ones changes nothing.
+// Enabling them again has no effect.
+func EnableStats() {
+	counting.Store(true)
+}
+
+func account(p unsafe.Pointer, n int) {
+	if !counting.Load() {
+		return
+	}
+	accounts.mu.Lock()
+
+	defer accounts.mu.Unlock()
+	if accounts.sizes == nil {
+		accounts.sizes = make(map[unsafe.Pointer]int)
+	}
+	accounts.sizes[p] = n
+	accounts.Allocs++
+	accounts.LiveBytes += int64(n)
+	accounts.PeakBytes = max(accounts.PeakBytes, accounts.LiveBytes)
+	limitChanged()
+}
+
+// release forgets p. Memory the C library allocated was never accounted
+// for, and freeing it changes nothing.
+func release(p unsafe.Pointer) {
+	if !counting.Load() {
+		return
+	}
+	accounts.mu.Lock()
+	defer accounts.mu.Unlock()
+	n, ok := accounts.sizes[p]
+	if !ok {
+		return
+	}
+	delete(accounts.sizes, p)
+	accounts.Frees++
+	accounts.LiveBytes -= int64(n)
+	limitChanged()
+}
+
+// reaccount moves the entry for p, which realloc resized to n bytes at q.
+// Being the same allocation, it counts as neither an alloc nor a free.
+func reaccount(p, q unsafe.Pointer, n int) {
+	if !counting.Load() {
+		return
+	}
+	accounts.mu.Lock()
+	defer accounts.mu.Unlock()
+	old, ok := accounts.sizes[p]
+	if !ok {
+		return
+	}
+	delete(accounts.sizes, p)
+	accounts.sizes[q] = n
+	accounts.LiveBytes += int64(n - old)
+	accounts.PeakBytes = max(accounts.PeakBytes, accounts.LiveBytes)
+	limitChanged()
+}
+
+// ReadStats returns the statistics as they are now, all zero unless
+// EnableStats has been called.
+func ReadStats() Stats {
+	accounts.mu.Lock()
+	defer accounts.mu.Unlock()
+	return accounts.Stats
+}
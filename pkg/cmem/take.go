@@ -0,0 +1,127 @@
+package cmem
+
+/*
+#include <string.h>
+*/
+import "C"
+
+import (
+	"runtime"
+	"sync"
+	"unsafe"
+)
+
+// C functions that return a pointer to memory they allocated hand its
+// ownership to the caller, who must free it with whatever the function
+// documents: free, or a function of the library's own when it has its own
+// allocator. The functions below take over such memory in one of two
+// ways. TakeCString and TakeCBytes copy it into Go and free it at once,
+// which suits small results. Adopt keeps it in C and frees it at
+// Owned.Free, with a cleanup as a backstop, which avoids copying large
+// ones. Each takes the free function to use; nil means Free, for memory
+// from C's malloc.
+
+// TakeCString returns a Go copy of the NUL-terminated string at p and
+// frees p. A nil p gives "".
+func TakeCString(p unsafe.Pointer, free func(unsafe.Pointer)) string {
+	if p == nil {
+		return ""
+	}
+	s := C.GoString((*C.char)(p))
+	freeWith(free, p)
+	return s
+}
+
+// TakeCBytes returns a Go copy of the n bytes at p and frees p. A nil p
+// gives nil.
+func TakeCBytes(p unsafe.Pointer, n int, free func(unsafe.Pointer)) []byte {
+	if p == nil {
+		return nil
+	}
+	b := Copy(p, n)
+	freeWith(free, p)
+	return b
+}
+
+func freeWith(free func(unsafe.Pointer), p unsafe.Pointer) {
+	if free == nil {
+		free = Free
+	}
+	free(p)
+}
+
+// An Owned is C memory that Go has taken ownership of without copying it.
+//
+// Call Free when done. If an Owned becomes unreachable first, a cleanup
+// frees the memory eventually, but the garbage collector knows nothing of
+// its size and may take a long time to get to it.
+//
+// An Owned is safe for concurrent use, although the slice Bytes returns
+// is not guarded.
+type Owned struct {
+	mu      sync.Mutex
+	p       unsafe.Pointer
+	n       int
+	free    func(unsafe.Pointer)
+	cleanup runtime.Cleanup
+}
+
+type ownedMem struct {
+	p    unsafe.Pointer
+	free func(unsafe.Pointer)
+}
+
+// Adopt takes ownership of the n bytes of C memory at p. p must not be
+// nil or be freed by anything else.
+func Adopt(p unsafe.Pointer, n int, free func(unsafe.Pointer)) *Owned {
+	if p == nil {
+		panic("cmem: Adopt of a nil pointer")
+	}
+	if free == nil {
+		free = Free
+	}
+	o := &Owned{p: p, n: n, free: free}
+	o.cleanup = runtime.AddCleanup(o, freeOwned, ownedMem{p, free})
+	return o
+}
+
+// freeOwned must not refer to the Owned itself, or the Owned would never
+// become unreachable.
+func freeOwned(m ownedMem) {
+	m.free(m.p)
+}
+
+// Bytes returns the memory as a Go slice, without copying. The slice is
+// valid until Free, and o must stay reachable while it is in use, with
+// runtime.KeepAlive if need be, or the cleanup may free the memory under
+// it. Bytes returns nil after Free.
+func (o *Owned) Bytes() []byte {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if o.p == nil {
+		return nil
+	}
+	return View(o.p, o.n)
+}
+
+// Len returns the size of the memory, or 0 after Free.
+func (o *Owned) Len() int {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if o.p == nil {
+		return 0
+	}
+	return o.n
+}
+
+// Free frees the memory. Calls after the first do nothing.
+func (o *Owned) Free() {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if o.p == nil {
+		return
+	}
+	o.cleanup.Stop()
+	o.free(o.p)
+	o.p = nil
+}
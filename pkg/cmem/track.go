@@ -0,0 +1,34 @@
+//go:build !cmemdbg
+
+package cmem
+
+import (
+	"io"
+	"unsafe"
+)
+
+// Tracking reports whether allocations are being recorded, which they
+// are only when built with -tags cmemdbg.
+const Tracking = false
+
+func track(p unsafe.Pointer, n int) {}
+
+func untrack(p unsafe.Pointer) {}
+
+// Leaks returns the allocations that have not been freed. Without
+// -tags cmemdbg it always returns nil.
+func Leaks() []Leak {
+	return nil
+}
+
+// DumpLeaks writes one line per allocation that has not been freed to w,
+// and returns their number. Without -tags cmemdbg it writes nothing.
+func DumpLeaks(w io.Writer) int {
+	return 0
+}
+
+// CheckLeaks fails t if C memory allocated through this package while t
+// runs is still allocated when t finishes. Call it first, so that
+// cleanups registered later have run by the time it checks. Without
+// -tags cmemdbg it does nothing.
+func CheckLeaks(t TB) {}
@@ -0,0 +1,124 @@
+//go:build cmemdbg
+
+package cmem
+
+import (
+	"cmp"
+	"fmt"
+	"io"
+	"runtime"
+	"slices"
+	"strings"
+	"sync"
+	"unsafe"
+)
+
+// Tracking reports whether allocations are being recorded, which they
+// are only when built with -tags cmemdbg.
+const Tracking = true
+
+type allocation struct {
+	seq  uint64 // order of allocation, for CheckLeaks
+	size int
+	site string
+}
+
+var (
+	mu     sync.Mutex
+	live   = make(map[unsafe.Pointer]allocation)
+	seq    uint64
+	prefix = pkgPrefix()
+)
+
+// pkgPrefix returns the prefix of the names of this package's functions,
+// such as "example.com/pkg/cmem.".
+func pkgPrefix() string {
+	pc, _, _, _ := runtime.Caller(0)
+	name := runtime.FuncForPC(pc).Name()
+	return name[:strings.LastIndexByte(name, '.')+1]
+}
+
+// site returns the position of the first caller outside this package.
+func site() string {
+	var pcs [16]uintptr
+	frames := runtime.CallersFrames(pcs[:runtime.Callers(3, pcs[:])])
+	for {
+		f, more := frames.Next()
+		if !strings.HasPrefix(f.Function, prefix) {
+			return fmt.Sprintf("%s:%d", f.File, f.Line)
+		}
+		if !more {
+			return "unknown"
+		}
+	}
+}
+
+func track(p unsafe.Pointer, n int) {
+	s := site()
+	mu.Lock()
+	defer mu.Unlock()
+	seq++
+	live[p] = allocation{seq: seq, size: n, site: s}
+}
+
+func untrack(p unsafe.Pointer) {
+	mu.Lock()
+	defer mu.Unlock()
+	delete(live, p)
+}
+
+func leaksSince(after uint64) []Leak {
+	mu.Lock()
+	type leak struct {
+		Leak
+		seq uint64
+	}
+	var ls []leak
+	for p, a := range live {
+		if a.seq > after {
+			ls = append(ls, leak{Leak{Ptr: p, Size: a.size, Site: a.site}, a.seq})
+		}
+	}
+	mu.Unlock()
+
+	slices.SortFunc(ls, func(a, b leak) int { return cmp.Compare(a.seq, b.seq) })
+	out := make([]Leak, len(ls))
+	for i, l := range ls {
+		out[i] = l.Leak
+	}
+	return out
+}
+
+// Leaks returns the allocations that have not been freed, oldest first.
+// Without -tags cmemdbg it always returns nil.
+func Leaks() []Leak {
+	return leaksSince(0)
+}
+
+// DumpLeaks writes one line per allocation that has not been freed to w,
+// and returns their number. Without -tags cmemdbg it writes nothing.
+func DumpLeaks(w io.Writer) int {
+	ls := Leaks()
+	for _, l := range ls {
+		fmt.Fprintf(w, "cmem: %d bytes at %p allocated at %s\n", l.Size, l.Ptr, l.Site)
+	}
+	return len(ls)
+}
+
+// CheckLeaks fails t if C memory allocated through this package while t
+// runs is still allocated when t finishes. Call it first, so that
+// cleanups registered later have run by the time it checks. Without
+// -tags cmemdbg it does nothing.
+func CheckLeaks(t TB) {
+	t.Helper()
+	mu.Lock()
+	start := seq
+	mu.Unlock()
+
+	t.Cleanup(func() {
+		t.Helper()
+		for _, l := range leaksSince(start) {
+			t.Errorf("C memory leaked: %d bytes allocated at %s", l.Size, l.Site)
+		}
+	})
+}
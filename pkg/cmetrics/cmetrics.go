@@ -0,0 +1,77 @@
+// Package cmetrics exports the C heap statistics kept by pkg/cmem, so that
+// an operator can watch native memory next to the Go heap.
+//
+// Publish adds them to the expvar variables served at /debug/vars, and
+// Handler serves them in the Prometheus text format, which a Prometheus
+// server scrapes without any client library in the program. Both enable
+// the statistics, which count from then on, so call them at start-up:
+//
+//	cmetrics.Publish("cmem")
+//	http.Handle("/metrics/cmem", cmetrics.Handler())
+//
+// A program that already uses the Prometheus client can instead register
+// a collector that reads cmem.ReadStats in its Collect method, after
+// calling cmem.EnableStats.
+//
+// Only allocations made through pkg/cmem are counted, which includes all
+// of pkg/mylib's; memory the C library allocates for itself is not.
+package cmetrics
+
+import (
+	"bufio"
+	"expvar"
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/lxwagn/using-go-with-c-libraries/pkg/cmem"
+)
+
+// Publish publishes the statistics as the expvar variable name, a JSON
+// object with the fields of cmem.Stats read afresh on each request. Like
+// expvar.Publish it panics if name is already taken.
+func Publish(name string) {
+	cmem.EnableStats()
+	expvar.Publish(name, expvar.Func(func() any {
+		return cmem.ReadStats()
+	}))
+}
+
+type metric struct {
+	name, kind, help string
+	value            func(cmem.Stats) int64
+}
+
+var metrics = []metric{
+	{"cmem_live_bytes", "gauge", "C memory allocated through cmem and not yet freed, in bytes.",
+		func(s cmem.Stats) int64 { return s.LiveBytes }},
+	{"cmem_peak_bytes", "gauge", "The highest cmem_live_bytes has been.",
+		func(s cmem.Stats) int64 { return s.PeakBytes }},
+	{"cmem_live_allocations", "gauge", "C allocations made through cmem and not yet freed.",
+		func(s cmem.Stats) int64 { return int64(s.Live()) }},
+	{"cmem_allocs_total", "counter", "C allocations made through cmem.",
+		func(s cmem.Stats) int64 { return int64(s.Allocs) }},
+	{"cmem_frees_total", "counter", "C allocations made through cmem and freed.",
+		func(s cmem.Stats) int64 { return int64(s.Frees) }},
+}
+
+// WritePrometheus writes the statistics to w in the Prometheus text
+// exposition format. All values come from one snapshot.
+func WritePrometheus(w io.Writer) error {
+	s := cmem.ReadStats()
+	bw := bufio.NewWriter(w)
+	for _, m := range metrics {
+		fmt.Fprintf(bw, "# HELP %s %s\n# TYPE %s %s\n%s %d\n", m.name, m.help, m.name, m.kind, m.name, m.value(s))
+	}
+	return bw.Flush()
+}
+
+// Handler enables the statistics and returns a handler that serves
+// WritePrometheus.
+func Handler() http.Handler {
+	cmem.EnableStats()
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+		WritePrometheus(w)
+	})
+}
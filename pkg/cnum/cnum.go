@@ -0,0 +1,157 @@
+// Package cnum converts integers between Go's types and C's, reporting a
+// value that does not fit in its destination instead of truncating it as
+// a Go conversion does.
+//
+// A Go int is 64 bits on the usual platforms and a C int 32, so
+// C.int(n) quietly wraps a large n around. On 32-bit platforms the same
+// happens the other way, from size_t and long into Go's int. The
+// functions here return the value in a Go type of the C type's width,
+// ready for the conversion to the cgo type, or a *RangeError:
+//
+//	n, err := cnum.ToCInt(len(values))
+//	if err != nil {
+//		return err
+//	}
+//	C.mySum(p, C.int(n))
+//
+// The package does not use cgo, so builds without it share it. It takes
+// the sizes of C's types from the platforms Go supports: int and unsigned
+// int are 32 bits, long long 64, size_t as wide as a pointer, and long 32
+// bits on Windows and 32-bit platforms and 64 bits elsewhere.
+package cnum
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Integer is the set of Go integer types.
+type Integer interface {
+	~int | ~int8 | ~int16 | ~int32 | ~int64 |
+		~uint | ~uint8 | ~uint16 | ~uint32 | ~uint64 | ~uintptr
+}
+
+// ErrRange is matched by every *RangeError.
+var ErrRange = errors.New("cnum: value out of range")
+
+// A RangeError reports a value that does not fit in the type it was
+// converted to.
+type RangeError struct {
+	Value string // the value, in decimal
+	Type  string // the destination type, such as "C int" or "Go int"
+}
+
+func (e *RangeError) Error() string {
+	return fmt.Sprintf("cnum: %s does not fit in %s", e.Value, e.Type)
+}
+
+// Unwrap returns ErrRange.
+func (e *RangeError) Unwrap() error {
+	return ErrRange
+}
+
+// Fits reports whether v can be converted to To without changing its
+// value.
+func Fits[To, From Integer](v From) bool {
+	t := To(v)
+	return From(t) == v && (t < 0) == (v < 0)
+}
+
+// Convert converts v to To, or fails with a *RangeError naming typ if it
+// does not fit.
+func Convert[To, From Integer](v From, typ string) (To, error) {
+	if !Fits[To](v) {
+		return 0, &RangeError{Value: decimal(v), Type: typ}
+	}
+	return To(v), nil
+}
+
+func decimal[T Integer](v T) string {
+	if v < 0 {
+		return fmt.Sprint(int64(v))
+	}
+	return fmt.Sprint(uint64(v))
+}
+
+// LongBits is the width of C's long.
+const LongBits = longBits
+
+// The ranges of C's long, which the Go type int64 can hold whatever its
+// width.
+const (
+	maxLong = 1<<(LongBits-1) - 1
+	minLong = -1 << (LongBits - 1)
+)
+
+// ToCInt converts v for a C int.
+func ToCInt[T Integer](v T) (int32, error) {
+	return Convert[int32](v, "C int")
+}
+
+// ToCUint converts v for a C unsigned int.
+func ToCUint[T Integer](v T) (uint32, error) {
+	return Convert[uint32](v, "C unsigned int")
+}
+
+// ToCLong converts v for a C long.
+func ToCLong[T Integer](v T) (int64, error) {
+	n, err := Convert[int64](v, "C long")
+	if err == nil && (n > maxLong || n < minLong) {
+		err = &RangeError{Value: decimal(v), Type: "C long"}
+	}
+	if err != nil {
+		return 0, err
+	}
+	return n, nil
+}
+
+// ToCLongLong converts v for a C long long.
+func ToCLongLong[T Integer](v T) (int64, error) {
+	return Convert[int64](v, "C long long")
+}
+
+// ToCSizeT converts v for a C size_t. Go's uint has the same width.
+func ToCSizeT[T Integer](v T) (uint, error) {
+	return Convert[uint](v, "C size_t")
+}
+
+// FromCInt converts a C int, as an int32, to T.
+func FromCInt[T Integer](v int32) (T, error) {
+	return Convert[T](v, goName[T]())
+}
+
+// FromCUint converts a C unsigned int, as a uint32, to T.
+func FromCUint[T Integer](v uint32) (T, error) {
+	return Convert[T](v, goName[T]())
+}
+
+// FromCLong converts a C long, as an int64, to T.
+func FromCLong[T Integer](v int64) (T, error) {
+	return Convert[T](v, goName[T]())
+}
+
+// FromCLongLong converts a C long long, as an int64, to T.
+func FromCLongLong[T Integer](v int64) (T, error) {
+	return Convert[T](v, goName[T]())
+}
+
+// FromCSizeT converts a C size_t, as a uint, to T. On 32-bit platforms a
+// size_t of 2 GiB or more does not fit in an int.
+func FromCSizeT[T Integer](v uint) (T, error) {
+	return Convert[T](v, goName[T]())
+}
+
+// goName names T for a RangeError.
+func goName[T Integer]() string {
+	return fmt.Sprintf("Go %T", T(0))
+}
+
+// Must returns v, and panics if err is not nil. It suits wrappers whose
+// signatures have no error result, for which a value that does not fit is
+// a programming error, like an index out of range.
+func Must[T any](v T, err error) T {
+	if err != nil {
+		panic(err)
+	}
+	return v
+}
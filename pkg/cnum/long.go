@@ -0,0 +1,9 @@
+//go:build !windows
+
+package cnum
+
+import "math/bits"
+
+// Unix platforms use the LP64 model on 64-bit platforms, where long is
+// as wide as a pointer, and ILP32 on 32-bit ones.
+const longBits = bits.UintSize
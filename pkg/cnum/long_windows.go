@@ -0,0 +1,4 @@
+package cnum
+
+// Windows uses the LLP64 model: long stays 32 bits on 64-bit platforms.
+const longBits = 32
@@ -0,0 +1,121 @@
+// Package cpanic stops panics in Go callbacks at the C frames that called
+// them.
+//
+// A panic in a function exported to C unwinds through the C frames below
+// it without running any of their cleanup: a lock the library took stays
+// taken, memory it allocated leaks, and a structure it was updating is
+// left half done. The library carries on with that state later, if the
+// program recovers further up. A Barrier runs each callback under a
+// recover instead, hands C the status a failed callback would return, and
+// keeps the panic. Once the C function has returned, Repanic raises it
+// again on the goroutine that called it, where unwinding is safe. The
+// barrier goes with the callback's handle, and the exported function
+// calls through it itself:
+//
+//	//export goCallbackTrampoline
+//	func goCallbackTrampoline(userdata unsafe.Pointer, value C.int) C.int {
+//		c := handles.FromUintptr[*call](uintptr(userdata)).Value()
+//		return C.int(cpanic.Call(&c.b, 0, func() int { return c.fn(int(value)) }))
+//	}
+//
+//	c := &call{fn: fn}
+//	h := handles.New(c)
+//	rc := C.callN(..., C.uintptr_t(h.Uintptr()))
+//	c.b.Repanic()
+//
+// A barrier does not stop runtime.Goexit, and so not t.FailNow either,
+// which has no value to recover and always unwinds.
+package cpanic
+
+import (
+	"fmt"
+	"runtime/debug"
+	"sync"
+	"sync/atomic"
+)
+
+// A Panic is a panic recovered from a callback.
+type Panic struct {
+	Value any    // the value passed to panic
+	Stack []byte // the callback's goroutine stack where it panicked
+}
+
+// Error returns the value and the stack it was recovered at, which is
+// what the program prints when the panic raised again by Repanic is not
+// recovered: the stack of the new panic goes only as far as Repanic.
+func (p *Panic) Error() string {
+	return fmt.Sprintf("%v [recovered from a callback]\n\n%s", p.Value, p.Stack)
+}
+
+// Unwrap returns the value if it is an error, such as a runtime.Error.
+func (p *Panic) Unwrap() error {
+	err, _ := p.Value.(error)
+	return err
+}
+
+// A Barrier records the first panic of the callbacks made during one C
+// call. The zero value is ready to use, and a Barrier is safe for
+// concurrent use by callbacks on threads of the library's own.
+type Barrier struct {
+	panicked atomic.Bool
+	mu       sync.Mutex
+	p        *Panic
+}
+
+// Call calls f and returns its result, or fail if f panics. After the
+// first panic it returns fail without calling f at all: whatever Go state
+// the callbacks share may have been left half updated, so the rest of the
+// C call is answered as though each callback failed.
+func Call[T any](b *Barrier, fail T, f func() T) (r T) {
+	if b.panicked.Load() {
+		return fail
+	}
+	defer func() {
+		if v := recover(); v != nil {
+			b.record(v)
+			r = fail
+		}
+	}()
+	return f()
+}
+
+// Do is Call for a callback without a result. It reports whether f ran
+// to the end.
+func Do(b *Barrier, f func()) bool {
+	return Call(b, false, func() bool {
+		f()
+		return true
+	})
+}
+
+func (b *Barrier) record(v any) {
+	stack := debug.Stack()
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.p == nil {
+		b.p = &Panic{Value: v, Stack: stack}
+		b.panicked.Store(true)
+	}
+}
+
+// Panicked reports whether a callback has panicked, for a C call that
+// can be told to stop early.
+func (b *Barrier) Panicked() bool {
+	return b.panicked.Load()
+}
+
+// Recovered returns the first panic recovered, or nil.
+func (b *Barrier) Recovered() *Panic {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.p
+}
+
+// Repanic panics with the first panic recovered, as a *Panic, if there
+// was one. It is called once the C function has returned, with nothing
+// of C's left on the stack to unwind.
+func (b *Barrier) Repanic() {
+	if p := b.Recovered(); p != nil {
+		panic(p)
+	}
+}
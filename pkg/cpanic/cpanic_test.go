@@ -0,0 +1,109 @@
+package cpanic_test
+
+import (
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/lxwagn/using-go-with-c-libraries/pkg/cpanic"
+)
+
+// catch runs f and returns what it panicked with, or nil.
+func catch(f func()) (v any) {
+	defer func() { v = recover() }()
+	f()
+	return nil
+}
+
+func TestCall(t *testing.T) {
+	var b cpanic.Barrier
+	if got := cpanic.Call(&b, -1, func() int { return 7 }); got != 7 {
+		t.Errorf("Call = %d, want 7", got)
+	}
+	if b.Panicked() || b.Recovered() != nil {
+		t.Fatal("barrier reports a panic before any")
+	}
+	b.Repanic()
+
+	if got := cpanic.Call(&b, -1, func() int { panic("boom") }); got != -1 {
+		t.Errorf("Call of a panicking f = %d, want the fail value -1", got)
+	}
+	if !b.Panicked() {
+		t.Fatal("Panicked = false after a panic")
+	}
+	ran := false
+	if got := cpanic.Call(&b, -1, func() int { ran = true; return 7 }); got != -1 || ran {
+		t.Errorf("Call after the panic = %d, ran %v; want -1 without running f", got, ran)
+	}
+	if cpanic.Do(&b, func() { ran = true }) || ran {
+		t.Error("Do after the panic ran f")
+	}
+}
+
+func TestDo(t *testing.T) {
+	var b cpanic.Barrier
+	if !cpanic.Do(&b, func() {}) {
+		t.Error("Do of a returning f = false")
+	}
+	if cpanic.Do(&b, func() { panic("boom") }) {
+		t.Error("Do of a panicking f = true")
+	}
+	if p := b.Recovered(); p == nil || p.Value != "boom" {
+		t.Errorf("Recovered = %v, want boom", p)
+	}
+}
+
+// panicHere is a named function, for the recorded stack to show.
+func panicHere() int {
+	var none []int
+	return none[1]
+}
+
+func TestRepanic(t *testing.T) {
+	var b cpanic.Barrier
+	cpanic.Call(&b, 0, panicHere)
+	cpanic.Call(&b, 0, func() int { panic("second") })
+
+	v := catch(b.Repanic)
+	p, ok := v.(*cpanic.Panic)
+	if !ok {
+		t.Fatalf("Repanic panicked with %T %v, want a *cpanic.Panic", v, v)
+	}
+	if p != b.Recovered() {
+		t.Error("Repanic raised a different panic than Recovered returns")
+	}
+	if !strings.Contains(string(p.Stack), "panicHere") {
+		t.Errorf("recorded stack does not show where f panicked:\n%s", p.Stack)
+	}
+	// The first panic is the one kept, and a runtime error still is one.
+	var re interface{ RuntimeError() }
+	if !errors.As(p, &re) {
+		t.Errorf("Panic %v does not unwrap to the runtime error", p.Value)
+	}
+	if !strings.Contains(p.Error(), "[recovered from a callback]") {
+		t.Errorf("Error() = %q, want it marked as recovered", p.Error())
+	}
+}
+
+// TestConcurrent panics in callbacks on many goroutines at once, as
+// callbacks on a library's own threads do, and keeps exactly one.
+func TestConcurrent(t *testing.T) {
+	var b cpanic.Barrier
+	var wg sync.WaitGroup
+	for i := range 16 {
+		wg.Go(func() {
+			for range 100 {
+				cpanic.Do(&b, func() { panic(i) })
+			}
+		})
+	}
+	wg.Wait()
+	p := b.Recovered()
+	if p == nil {
+		t.Fatal("no panic recorded")
+	}
+	if _, ok := p.Value.(int); !ok {
+		t.Errorf("recorded %v, want one of the goroutines' panics", p.Value)
+	}
+}
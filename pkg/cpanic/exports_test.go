@@ -0,0 +1,118 @@
+package cpanic_test
+
+import (
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"io/fs"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// noCallback lists the functions exported to C that run no function of
+// their caller's, and so need no barrier, with the reason.
+var noCallback = map[string]string{
+	"goMalloc":            "recovers cmem's out-of-memory panic itself",
+	"goRealloc":           "recovers cmem's out-of-memory panic itself",
+	"goFree":              "only frees",
+	"goFillDone":          "only finishes the FillOp",
+	"goEventTrampoline":   "only sends on the subscription's channel",
+	"goThreadTrampoline":  "only sends on the group's channel",
+	"goWordTrampoline":    "only counts the word",
+	"goReactorWatch":      "only updates the reactor's poller",
+	"cstdioClose":         "only deletes the handle",
+	"goSQLiteFuncDestroy": "only deletes the handle",
+	"goStreamCallback":    "runs only the gpu package's own callbacks",
+	"benchGoCallback":     "only adds 1",
+
+	// A Go library's entry points, called by C programs rather than
+	// calling back into a caller's Go code.
+	"GoRuntimeReady": "entry point of cmd/goarchive",
+	"GoMultiply":     "entry point of cmd/goarchive",
+	"GoWatchSignal":  "entry point of cmd/goarchive",
+	"GoSignalCount":  "entry point of cmd/goarchive",
+	"GoAdd":          "entry point of cmd/goshared",
+	"GoSum":          "entry point of cmd/goshared",
+	"GoGreet":        "entry point of cmd/goshared",
+	"GoUpper":        "entry point of cmd/goshared",
+	"GoFree":         "entry point of cmd/goshared",
+}
+
+// TestExports checks that every function of the module exported to C
+// calls cpanic.Call or cpanic.Do itself, unless noCallback lists it: a
+// trampoline handing the barrier on to a helper, or to a wrapper built
+// where the callback is registered, is easily lost in a later change.
+func TestExports(t *testing.T) {
+	root := filepath.Join("..", "..")
+	seen := make(map[string]bool)
+	fset := token.NewFileSet()
+	err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			switch rel, _ := filepath.Rel(root, path); {
+			case rel == "test", rel == "lib", d.Name() == "target", d.Name() == "testdata",
+				strings.HasPrefix(d.Name(), ".") && rel != ".":
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if filepath.Ext(path) != ".go" || strings.HasSuffix(path, "_test.go") {
+			return nil
+		}
+		f, err := parser.ParseFile(fset, path, nil, parser.ParseComments|parser.SkipObjectResolution)
+		if err != nil {
+			return err
+		}
+		for _, decl := range f.Decls {
+			fn, ok := decl.(*ast.FuncDecl)
+			if !ok || !exported(fn) {
+				continue
+			}
+			name := fn.Name.Name
+			seen[name] = true
+			if _, ok := noCallback[name]; ok {
+				continue
+			}
+			if !callsBarrier(fn.Body) {
+				t.Errorf("%s: //export %s calls neither cpanic.Call nor cpanic.Do", fset.Position(fn.Pos()), name)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for name := range noCallback {
+		if !seen[name] {
+			t.Errorf("noCallback lists %s, which is not exported to C", name)
+		}
+	}
+}
+
+func exported(fn *ast.FuncDecl) bool {
+	if fn.Doc == nil {
+		return false
+	}
+	for _, c := range fn.Doc.List {
+		if strings.HasPrefix(c.Text, "//export ") {
+			return true
+		}
+	}
+	return false
+}
+
+func callsBarrier(body *ast.BlockStmt) bool {
+	found := false
+	ast.Inspect(body, func(n ast.Node) bool {
+		if sel, ok := n.(*ast.SelectorExpr); ok {
+			if x, ok := sel.X.(*ast.Ident); ok && x.Name == "cpanic" && (sel.Sel.Name == "Call" || sel.Sel.Name == "Do") {
+				found = true
+			}
+		}
+		return !found
+	})
+	return found
+}
@@ -0,0 +1,231 @@
+//go:build !windows
+
+package cplugin
+
+/*
+
+#cgo CFLAGS: -I${SRCDIR}/../../src/plugins
+#include <stdlib.h>
+#include "plugin.h"
+
+// cgo cannot call through a function pointer, so each ABI function has a
+// shim casting the pointer dlsym returned to its type from plugin.h.
+
+static int callInit(void *fn, int abi) {
+	return ((int (*)(int))fn)(abi);
+}
+
+static void callShutdown(void *fn) {
+	((void (*)(void))fn)();
+}
+
+static const char *callName(void *fn) {
+	return ((const char *(*)(void))fn)();
+}
+
+static long callProcess(void *fn, const unsigned char *in, size_t n, unsigned char *out, size_t cap) {
+	return ((long (*)(const unsigned char *, size_t, unsigned char *, size_t))fn)(in, n, out, cap);
+}
+
+*/
+import "C"
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"sync"
+	"unsafe"
+
+	"github.com/lxwagn/using-go-with-c-libraries/pkg/dynload"
+)
+
+// ABI is the version of plugin.h the package implements, passed to every
+// plugin's myPluginInit.
+const ABI = C.MYPLUGIN_ABI
+
+// ErrClosed is returned by the methods of a closed Plugin.
+var ErrClosed = errors.New("cplugin: plugin is closed")
+
+// A LoadError reports a library that could not be loaded as a plugin.
+type LoadError struct {
+	Path string
+	Err  error
+}
+
+func (e *LoadError) Error() string {
+	return "cplugin: " + e.Path + ": " + e.Err.Error()
+}
+
+func (e *LoadError) Unwrap() error {
+	return e.Err
+}
+
+// A Plugin is a loaded, initialized plugin. It stays loaded until Close.
+// A Plugin is safe for concurrent use.
+type Plugin struct {
+	name string
+	path string
+
+	mu       sync.Mutex
+	h        *dynload.Handle
+	process  unsafe.Pointer
+	shutdown unsafe.Pointer // nil if the plugin has no myPluginShutdown
+}
+
+// Ext is the file name extension of shared libraries on this system.
+var Ext = ".so"
+
+func init() {
+	if runtime.GOOS == "darwin" {
+		Ext = ".dylib"
+	}
+}
+
+// Load loads the plugin at path and initializes it.
+func Load(path string) (*Plugin, error) {
+	h, err := dynload.OpenHandle(path, dynload.Now)
+	if err != nil {
+		return nil, &LoadError{path, err}
+	}
+
+	syms := make(map[string]unsafe.Pointer)
+	for _, name := range []string{"myPluginInit", "myPluginName", "myPluginProcess", "myPluginShutdown"} {
+		syms[name], _ = h.Sym(name)
+		if syms[name] == nil && name != "myPluginShutdown" {
+			h.Close()
+			return nil, &LoadError{path, fmt.Errorf("missing %s", name)}
+		}
+	}
+
+	if rc := C.callInit(syms["myPluginInit"], ABI); rc != 0 {
+		h.Close()
+		return nil, &LoadError{path, fmt.Errorf("myPluginInit(%d) refused with %d", ABI, int(rc))}
+	}
+	p := &Plugin{
+		path:     path,
+		h:        h,
+		process:  syms["myPluginProcess"],
+		shutdown: syms["myPluginShutdown"],
+	}
+	p.name = C.GoString(C.callName(syms["myPluginName"]))
+	if p.name == "" {
+		p.Close()
+		return nil, &LoadError{path, errors.New("empty name")}
+	}
+	return p, nil
+}
+
+// LoadDir loads every shared library in dir, in name order, as a plugin.
+// It returns the plugins that loaded and, if any failed, an error joining
+// a *LoadError for each. A plugin whose name an earlier one already has
+// is refused too.
+func LoadDir(dir string) ([]*Plugin, error) {
+	paths, err := filepath.Glob(filepath.Join(dir, "*"+Ext))
+	if err != nil {
+		return nil, err
+	}
+	if len(paths) == 0 {
+		if _, err := os.Stat(dir); err != nil {
+			return nil, err
+		}
+	}
+	slices.Sort(paths)
+
+	var (
+		plugins []*Plugin
+		errs    []error
+		names   = make(map[string]bool)
+	)
+	for _, path := range paths {
+		p, err := Load(path)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		if names[p.name] {
+			p.Close()
+			errs = append(errs, &LoadError{path, fmt.Errorf("duplicate plugin name %q", p.name)})
+			continue
+		}
+		names[p.name] = true
+		plugins = append(plugins, p)
+	}
+	return plugins, errors.Join(errs...)
+}
+
+// Name returns the name the plugin reported when it was loaded.
+func (p *Plugin) Name() string {
+	return p.name
+}
+
+// Path returns the file the plugin was loaded from.
+func (p *Plugin) Path() string {
+	return p.path
+}
+
+// Symbol returns the address of name, a symbol the plugin exports beyond
+// the ABI, such as a function to call with pkg/ffi. The address is only
+// valid until Close.
+func (p *Plugin) Symbol(name string) (unsafe.Pointer, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.h == nil {
+		return nil, ErrClosed
+	}
+
+	sym, err := p.h.Sym(name)
+	if err != nil {
+		return nil, fmt.Errorf("cplugin: %s: no symbol %s", p.name, name)
+	}
+	return sym, nil
+}
+
+// Process passes in to the plugin and returns its result.
+func (p *Plugin) Process(in []byte) ([]byte, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.h == nil {
+		return nil, ErrClosed
+	}
+
+	out := make([]byte, max(len(in), 64))
+	// The first call reports the size of a result that did not fit;
+	// the second has room for it. A plugin that asks for more again is
+	// broken.
+	for range 2 {
+		n := C.callProcess(p.process,
+			(*C.uchar)(unsafe.SliceData(in)), C.size_t(len(in)),
+			(*C.uchar)(unsafe.SliceData(out)), C.size_t(len(out)))
+		switch {
+		case n < 0:
+			return nil, fmt.Errorf("cplugin: %s: myPluginProcess failed", p.name)
+		case int(n) <= len(out):
+			return out[:n], nil
+		}
+		out = make([]byte, n)
+	}
+	return nil, fmt.Errorf("cplugin: %s: myPluginProcess result keeps growing", p.name)
+}
+
+// Close shuts the plugin down and unloads it. Calls after the first
+// return ErrClosed.
+func (p *Plugin) Close() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.h == nil {
+		return ErrClosed
+	}
+	if p.shutdown != nil {
+		C.callShutdown(p.shutdown)
+	}
+	err := p.h.Close()
+	p.h, p.process, p.shutdown = nil, nil, nil
+	return err
+}
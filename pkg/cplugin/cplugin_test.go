@@ -0,0 +1,107 @@
+//go:build cgo && !windows
+
+package cplugin_test
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/lxwagn/using-go-with-c-libraries/pkg/cplugin"
+)
+
+// pluginDir returns lib/plugins, skipping the test if make plugins has
+// not built the samples there.
+func pluginDir(t *testing.T) string {
+	t.Helper()
+	dir := filepath.Join("..", "..", "lib", "plugins")
+	if _, err := os.Stat(filepath.Join(dir, "repeat"+cplugin.Ext)); err != nil {
+		t.Skipf("no sample plugins (make plugins): %v", err)
+	}
+	return dir
+}
+
+func TestLoadDir(t *testing.T) {
+	plugins, err := cplugin.LoadDir(pluginDir(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		for _, p := range plugins {
+			p.Close()
+		}
+	}()
+
+	var names []string
+	for _, p := range plugins {
+		names = append(names, p.Name())
+	}
+	if got := strings.Join(names, " "); got != "repeat upper" {
+		t.Fatalf("loaded %q, want the repeat and upper samples", got)
+	}
+
+	// A result longer than the input takes a second call with a bigger
+	// buffer.
+	long := bytes.Repeat([]byte("ab"), 100)
+	for _, c := range []struct {
+		plugin  *cplugin.Plugin
+		in, out []byte
+	}{
+		{plugins[0], []byte("hey"), []byte("hey hey")},
+		{plugins[0], long, []byte(string(long) + " " + string(long))},
+		{plugins[1], []byte("Hello, C!"), []byte("HELLO, C!")},
+		{plugins[1], nil, []byte{}},
+	} {
+		out, err := c.plugin.Process(c.in)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(out, c.out) {
+			t.Errorf("%s.Process(%.20q) = %.20q, want %.20q", c.plugin.Name(), c.in, out, c.out)
+		}
+	}
+}
+
+// TestLifecycle loads, closes and loads again, which must go through
+// myPluginInit and myPluginShutdown each time.
+func TestLifecycle(t *testing.T) {
+	path := filepath.Join(pluginDir(t), "repeat"+cplugin.Ext)
+	for range 3 {
+		p, err := cplugin.Load(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		// dlopen hands out the same copy of a library that is already
+		// open, and repeat refuses a second init.
+		if q, err := cplugin.Load(path); err == nil {
+			q.Close()
+			p.Close()
+			t.Fatal("loaded repeat twice at once")
+		}
+		if _, err := p.Process([]byte("x")); err != nil {
+			t.Fatal(err)
+		}
+		if err := p.Close(); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := p.Process([]byte("x")); !errors.Is(err, cplugin.ErrClosed) {
+			t.Fatalf("Process after Close: err = %v, want ErrClosed", err)
+		}
+	}
+}
+
+func TestLoadNotAPlugin(t *testing.T) {
+	path := filepath.Join(pluginDir(t), "..", "libmylib.so")
+	p, err := cplugin.Load(path)
+	if err == nil {
+		p.Close()
+		t.Fatalf("loaded %s as a plugin", path)
+	}
+	var lerr *cplugin.LoadError
+	if !errors.As(err, &lerr) || !strings.Contains(err.Error(), "myPluginInit") {
+		t.Errorf("err = %v, want a LoadError about myPluginInit", err)
+	}
+}
@@ -0,0 +1,24 @@
+// Package cplugin loads plugins written in C: shared libraries, each
+// exporting the functions declared in src/plugins/plugin.h, that a
+// program discovers at run time rather than links against.
+//
+// LoadDir opens every library in a directory, checks that it speaks the
+// ABI version the package was built for and returns the ones that do:
+//
+//	plugins, err := cplugin.LoadDir("lib/plugins")
+//	if err != nil {
+//		log.Print(err) // the plugins that failed; the rest are usable
+//	}
+//	for _, p := range plugins {
+//		defer p.Close()
+//		out, err := p.Process([]byte("hello"))
+//		...
+//	}
+//
+// Each plugin is opened with dynload.OpenHandle, and so with RTLD_LOCAL:
+// plugins exporting the same function names do not see one another's.
+// Calls into one plugin are serialized, since nothing in the ABI promises
+// that a plugin is thread-safe; different plugins run concurrently.
+//
+// The package uses dlopen and is not available on Windows.
+package cplugin
@@ -0,0 +1,236 @@
+//go:build linux || darwin || dragonfly || freebsd || netbsd || openbsd
+
+package cpoll
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"sync"
+	"syscall"
+
+	"golang.org/x/sys/unix"
+)
+
+// Events is a set of readiness events.
+type Events uint32
+
+const (
+	Readable Events = 1 << iota // reads will not block, or end of file or an error is pending
+	Writable                    // writes will not block, or an error is pending
+)
+
+func (ev Events) String() string {
+	switch ev {
+	case 0:
+		return "none"
+	case Readable:
+		return "readable"
+	case Writable:
+		return "writable"
+	case Readable | Writable:
+		return "readable|writable"
+	}
+	return fmt.Sprintf("Events(%#x)", uint32(ev))
+}
+
+// A Handler is called on the Poller's goroutine with a descriptor and
+// those of the events it was added for that it is ready for.
+type Handler func(fd int, ev Events)
+
+// ErrClosed is returned by a Poller's methods once it has been closed.
+var ErrClosed = errors.New("cpoll: poller closed")
+
+// A readiness is one event from the kernel, in this package's terms.
+type readiness struct {
+	fd int
+	ev Events
+}
+
+type watch struct {
+	ev Events
+	h  Handler
+}
+
+// A Poller watches descriptors for readiness. Its methods are safe for
+// concurrent use, and from its handlers.
+type Poller struct {
+	mu      sync.Mutex
+	watches map[int]watch
+	closed  bool
+	err     error // why the goroutine stopped, if not for Close
+
+	sys  sysPoller
+	wake [2]int // a pipe; a byte written to wake[1] interrupts the wait
+	done chan struct{}
+}
+
+// New returns a Poller watching nothing, whose goroutine is running.
+func New() (*Poller, error) {
+	sys, err := newSysPoller()
+	if err != nil {
+		return nil, fmt.Errorf("cpoll: %w", err)
+	}
+	p := &Poller{watches: make(map[int]watch), sys: sys, done: make(chan struct{})}
+	if err := unix.Pipe(p.wake[:]); err != nil {
+		sys.close()
+		return nil, fmt.Errorf("cpoll: %w", err)
+	}
+	for _, fd := range p.wake {
+		unix.CloseOnExec(fd)
+		unix.SetNonblock(fd, true)
+	}
+	if err := p.sys.add(p.wake[0], Readable); err != nil {
+		p.closeFds()
+		return nil, fmt.Errorf("cpoll: %w", err)
+	}
+	go p.loop()
+	return p, nil
+}
+
+// Add starts watching fd for ev, calling h when it is ready. It fails
+// with an error matching fs.ErrExist if fd is already watched.
+func (p *Poller) Add(fd int, ev Events, h Handler) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if err := p.usable(); err != nil {
+		return err
+	}
+	if _, ok := p.watches[fd]; ok {
+		return fmt.Errorf("cpoll: add %d: %w", fd, fs.ErrExist)
+	}
+	if err := p.sys.add(fd, ev); err != nil {
+		return fmt.Errorf("cpoll: add %d: %w", fd, err)
+	}
+	p.watches[fd] = watch{ev, h}
+	return nil
+}
+
+// Modify changes the events fd is watched for. With ev 0 it stays added
+// but its handler is not called. It fails with an error matching
+// fs.ErrNotExist if fd is not watched.
+func (p *Poller) Modify(fd int, ev Events) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if err := p.usable(); err != nil {
+		return err
+	}
+	w, ok := p.watches[fd]
+	if !ok {
+		return fmt.Errorf("cpoll: modify %d: %w", fd, fs.ErrNotExist)
+	}
+	if w.ev == ev {
+		return nil
+	}
+	if err := p.sys.modify(fd, ev); err != nil {
+		return fmt.Errorf("cpoll: modify %d: %w", fd, err)
+	}
+	w.ev = ev
+	p.watches[fd] = w
+	return nil
+}
+
+// Remove stops watching fd, which must still be open. Called from a
+// handler, it ensures fd's handler is not called again unless fd is added
+// anew; called from elsewhere, the goroutine may be about to call it one
+// last time. It fails with an error matching fs.ErrNotExist if fd is not
+// watched.
+func (p *Poller) Remove(fd int) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if err := p.usable(); err != nil {
+		return err
+	}
+	if _, ok := p.watches[fd]; !ok {
+		return fmt.Errorf("cpoll: remove %d: %w", fd, fs.ErrNotExist)
+	}
+	delete(p.watches, fd)
+	if err := p.sys.remove(fd); err != nil {
+		return fmt.Errorf("cpoll: remove %d: %w", fd, err)
+	}
+	return nil
+}
+
+// Len returns the number of descriptors watched.
+func (p *Poller) Len() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return len(p.watches)
+}
+
+// Close stops the goroutine, waiting for a handler that is running to
+// return, and stops watching every descriptor. Calling it from a handler
+// would wait for itself, and deadlocks. Close returns the error that
+// stopped the goroutine early, if one did; closing again returns
+// ErrClosed.
+func (p *Poller) Close() error {
+	p.mu.Lock()
+	if p.closed {
+		p.mu.Unlock()
+		return ErrClosed
+	}
+	p.closed = true
+	p.mu.Unlock()
+	unix.Write(p.wake[1], []byte{0})
+	<-p.done
+	p.closeFds()
+	return p.err
+}
+
+// usable returns the error a method of a closed or failed Poller
+// returns. It is called with p.mu held.
+func (p *Poller) usable() error {
+	if p.closed {
+		return ErrClosed
+	}
+	return p.err
+}
+
+func (p *Poller) closeFds() {
+	p.sys.close()
+	unix.Close(p.wake[0])
+	unix.Close(p.wake[1])
+}
+
+// loop waits for readiness and dispatches it until Close.
+func (p *Poller) loop() {
+	defer close(p.done)
+	ready := make([]readiness, 128)
+	for {
+		n, err := p.sys.wait(ready)
+		if err == syscall.EINTR {
+			continue
+		}
+		if err != nil {
+			p.mu.Lock()
+			p.err = fmt.Errorf("cpoll: wait: %w", err)
+			p.mu.Unlock()
+			return
+		}
+		for _, r := range ready[:n] {
+			if r.fd == p.wake[0] {
+				var buf [16]byte
+				unix.Read(p.wake[0], buf[:])
+				continue
+			}
+			// Look the descriptor up for each event, since a handler
+			// before it in this batch may have removed or changed it.
+			p.mu.Lock()
+			w, ok := p.watches[r.fd]
+			closed := p.closed
+			p.mu.Unlock()
+			if closed {
+				return
+			}
+			if ev := r.ev & w.ev; ok && ev != 0 {
+				w.h(r.fd, ev)
+			}
+		}
+		p.mu.Lock()
+		closed := p.closed
+		p.mu.Unlock()
+		if closed {
+			return
+		}
+	}
+}
@@ -0,0 +1,32 @@
+// Package cpoll watches file descriptors that belong to a C library for
+// readiness, on one goroutine, and calls a handler for each descriptor
+// that becomes ready.
+//
+// Event-driven C libraries, such as c-ares or libcurl's multi interface,
+// keep their own sockets and expect the program to poll them: the
+// library says which descriptors it wants to read or write, and the
+// program calls back into it when one is ready. The descriptors cannot be
+// given to the runtime's network poller with os.NewFile, which would take
+// them over and close them, and a goroutine blocked reading each one
+// would cost a thread while it sits in C. A Poller instead keeps an
+// epoll instance, or a kqueue on the BSDs and macOS, which a single
+// goroutine waits on for every descriptor:
+//
+//	p, err := cpoll.New()
+//	...
+//	err = p.Add(fd, cpoll.Readable, func(fd int, ev cpoll.Events) {
+//		C.lib_process(C.int(fd), C.int(ev)) // may call p.Modify or p.Remove
+//	})
+//
+// Handlers run one at a time on that goroutine, with no lock held, so a
+// handler may call back into C, and C into the Poller, to change what it
+// watches. Descriptors are polled level-triggered: a handler is called
+// again as long as the descriptor stays ready, and should be
+// non-blocking, since being told a descriptor is ready does not
+// guarantee that a read or write will not return EAGAIN.
+//
+// Descriptors stay the library's. Remove one before the library closes
+// it; Close stops watching them all and closes none.
+//
+// The package is available on Linux, macOS and the BSDs.
+package cpoll
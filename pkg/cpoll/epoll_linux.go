@@ -0,0 +1,65 @@
+package cpoll
+
+import "golang.org/x/sys/unix"
+
+// A sysPoller is an epoll instance.
+type sysPoller struct {
+	fd  int
+	buf []unix.EpollEvent
+}
+
+func newSysPoller() (sysPoller, error) {
+	fd, err := unix.EpollCreate1(unix.EPOLL_CLOEXEC)
+	return sysPoller{fd: fd}, err
+}
+
+func epollEvents(ev Events) uint32 {
+	var e uint32
+	if ev&Readable != 0 {
+		e |= unix.EPOLLIN | unix.EPOLLRDHUP
+	}
+	if ev&Writable != 0 {
+		e |= unix.EPOLLOUT
+	}
+	return e
+}
+
+func (s *sysPoller) add(fd int, ev Events) error {
+	return unix.EpollCtl(s.fd, unix.EPOLL_CTL_ADD, fd, &unix.EpollEvent{Events: epollEvents(ev), Fd: int32(fd)})
+}
+
+func (s *sysPoller) modify(fd int, ev Events) error {
+	return unix.EpollCtl(s.fd, unix.EPOLL_CTL_MOD, fd, &unix.EpollEvent{Events: epollEvents(ev), Fd: int32(fd)})
+}
+
+func (s *sysPoller) remove(fd int) error {
+	return unix.EpollCtl(s.fd, unix.EPOLL_CTL_DEL, fd, nil)
+}
+
+// wait blocks until a descriptor is ready and fills out with what is.
+// A hangup or error wakes both readers and writers, who find out which
+// it was from their next read or write.
+func (s *sysPoller) wait(out []readiness) (int, error) {
+	if len(s.buf) < len(out) {
+		s.buf = make([]unix.EpollEvent, len(out))
+	}
+	n, err := unix.EpollWait(s.fd, s.buf[:len(out)], -1)
+	if err != nil {
+		return 0, err
+	}
+	for i, e := range s.buf[:n] {
+		var ev Events
+		if e.Events&(unix.EPOLLIN|unix.EPOLLRDHUP|unix.EPOLLHUP|unix.EPOLLERR) != 0 {
+			ev |= Readable
+		}
+		if e.Events&(unix.EPOLLOUT|unix.EPOLLHUP|unix.EPOLLERR) != 0 {
+			ev |= Writable
+		}
+		out[i] = readiness{int(e.Fd), ev}
+	}
+	return n, nil
+}
+
+func (s *sysPoller) close() {
+	unix.Close(s.fd)
+}
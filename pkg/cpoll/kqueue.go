@@ -0,0 +1,84 @@
+//go:build darwin || dragonfly || freebsd || netbsd || openbsd
+
+package cpoll
+
+import "golang.org/x/sys/unix"
+
+// A sysPoller is a kqueue, with a read and a write filter for every
+// descriptor, each enabled while the descriptor is watched for it.
+type sysPoller struct {
+	fd  int
+	buf []unix.Kevent_t
+}
+
+func newSysPoller() (sysPoller, error) {
+	fd, err := unix.Kqueue()
+	if err != nil {
+		return sysPoller{}, err
+	}
+	unix.CloseOnExec(fd)
+	return sysPoller{fd: fd}, nil
+}
+
+func (s *sysPoller) change(fd int, ev Events) error {
+	var changes [2]unix.Kevent_t
+	for i, f := range [2]struct {
+		filter int
+		on     bool
+	}{{unix.EVFILT_READ, ev&Readable != 0}, {unix.EVFILT_WRITE, ev&Writable != 0}} {
+		flags := unix.EV_ADD | unix.EV_DISABLE
+		if f.on {
+			flags = unix.EV_ADD | unix.EV_ENABLE
+		}
+		unix.SetKevent(&changes[i], fd, f.filter, flags)
+	}
+	_, err := unix.Kevent(s.fd, changes[:], nil, nil)
+	return err
+}
+
+func (s *sysPoller) add(fd int, ev Events) error {
+	return s.change(fd, ev)
+}
+
+func (s *sysPoller) modify(fd int, ev Events) error {
+	return s.change(fd, ev)
+}
+
+func (s *sysPoller) remove(fd int) error {
+	var changes [2]unix.Kevent_t
+	unix.SetKevent(&changes[0], fd, unix.EVFILT_READ, unix.EV_DELETE)
+	unix.SetKevent(&changes[1], fd, unix.EVFILT_WRITE, unix.EV_DELETE)
+	_, err := unix.Kevent(s.fd, changes[:], nil, nil)
+	return err
+}
+
+// wait blocks until a descriptor is ready and fills out with what is.
+// End of file comes with its filter's event; an error wakes both
+// readers and writers, who find out what it was from their next read or
+// write.
+func (s *sysPoller) wait(out []readiness) (int, error) {
+	if len(s.buf) < len(out) {
+		s.buf = make([]unix.Kevent_t, len(out))
+	}
+	n, err := unix.Kevent(s.fd, nil, s.buf[:len(out)], nil)
+	if err != nil {
+		return 0, err
+	}
+	for i, e := range s.buf[:n] {
+		var ev Events
+		switch {
+		case e.Flags&unix.EV_ERROR != 0:
+			ev = Readable | Writable
+		case e.Filter == unix.EVFILT_READ:
+			ev = Readable
+		case e.Filter == unix.EVFILT_WRITE:
+			ev = Writable
+		}
+		out[i] = readiness{int(e.Ident), ev}
+	}
+	return n, nil
+}
+
+func (s *sysPoller) close() {
+	unix.Close(s.fd)
+}
@@ -0,0 +1,71 @@
+package cptr
+
+import (
+	"fmt"
+	"unsafe"
+
+	"github.com/lxwagn/using-go-with-c-libraries/pkg/cmem"
+)
+
+// A CArray is n consecutive Ts in C memory, as C would index them.
+type CArray[T any] struct {
+	p *T
+	n int
+}
+
+// AllocArray allocates a zeroed array of n Ts in C memory. It must be
+// released with Free.
+func AllocArray[T any](n int) CArray[T] {
+	var zero T
+	return CArray[T]{(*T)(cmem.Calloc(n, int(unsafe.Sizeof(zero)))), n}
+}
+
+// ArrayFrom returns a CArray for the n Ts starting at p, typically memory
+// that C allocated.
+func ArrayFrom[T any](p unsafe.Pointer, n int) CArray[T] {
+	if n < 0 || (p == nil && n > 0) {
+		panic("cptr: invalid array")
+	}
+	return CArray[T]{(*T)(p), n}
+}
+
+// Len returns the number of elements in a.
+func (a CArray[T]) Len() int {
+	return a.n
+}
+
+// Index returns a Ptr to element i. It panics if i is out of range, as
+// indexing a Go slice would.
+func (a CArray[T]) Index(i int) Ptr[T] {
+	if uint(i) >= uint(a.n) {
+		panic(fmt.Sprintf("cptr: index %d out of range [0:%d]", i, a.n))
+	}
+	var zero T
+	return Ptr[T]{(*T)(unsafe.Add(unsafe.Pointer(a.p), uintptr(i)*unsafe.Sizeof(zero)))}
+}
+
+// Slice returns the array as a Go slice, without copying: writes through
+// the slice change the C memory. The slice is only valid until a is freed.
+func (a CArray[T]) Slice() []T {
+	if a.n == 0 {
+		return nil
+	}
+	return unsafe.Slice(a.p, a.n)
+}
+
+// P returns a pointer to the first element, to be passed to C along with
+// Len.
+func (a CArray[T]) P() *T {
+	return a.p
+}
+
+// Pointer returns a pointer to the first element as an unsafe.Pointer.
+func (a CArray[T]) Pointer() unsafe.Pointer {
+	return unsafe.Pointer(a.p)
+}
+
+// Free releases memory allocated by AllocArray, or by C's malloc. a and
+// every slice and Ptr taken from it must not be used afterwards.
+func (a CArray[T]) Free() {
+	cmem.Free(unsafe.Pointer(a.p))
+}
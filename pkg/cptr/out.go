@@ -0,0 +1,79 @@
+package cptr
+
+import (
+	"unsafe"
+
+	"github.com/lxwagn/using-go-with-c-libraries/pkg/cmem"
+)
+
+// An Out is a slot in C memory for a C function to store one result in,
+// as it does through a parameter of type T *. For the common
+// pointer-to-pointer parameter, such as myBuffer **out, T is itself a
+// pointer type: Out[*C.myBuffer].
+//
+// The slot is in C memory, so C may go on writing to it after the call
+// returns, as an asynchronous API does, until Free. For a call done with
+// the slot by the time it returns a Go variable would do as well; Receive
+// then only spares the caller the declaration and the error plumbing.
+type Out[T any] struct {
+	p *T
+}
+
+// NewOut allocates a zeroed slot, which is a nil pointer when T is a
+// pointer type. It must be released with Free.
+func NewOut[T any]() Out[T] {
+	var zero T
+	return Out[T]{(*T)(cmem.Calloc(1, int(unsafe.Sizeof(zero))))}
+}
+
+// Arg returns the address of the slot, to be passed to C.
+func (o Out[T]) Arg() *T {
+	return o.p
+}
+
+// Value returns what C stored in the slot.
+func (o Out[T]) Value() T {
+	return *o.p
+}
+
+// Free releases the slot, but not whatever a pointer in it points to,
+// which belongs to the caller under the C function's own rules.
+func (o Out[T]) Free() {
+	cmem.Free(unsafe.Pointer(o.p))
+}
+
+// Receive calls f with a fresh slot for a T and returns what f left there
+// along with f's error, freeing the slot. It turns
+//
+//	var b *C.myBuffer
+//	rc := C.myBufferFrom(cs, &b)
+//
+// into a call whose result is the value itself:
+//
+//	b, err := cptr.Receive(func(out **C.myBuffer) error {
+//		return check(C.myBufferFrom(cs, out))
+//	})
+//
+// On error the zero T is returned, whatever f stored.
+func Receive[T any](f func(out *T) error) (T, error) {
+	o := NewOut[T]()
+	defer o.Free()
+	if err := f(o.Arg()); err != nil {
+		var zero T
+		return zero, err
+	}
+	return o.Value(), nil
+}
+
+// Receive2 is Receive for a function with two out-parameters.
+func Receive2[A, B any](f func(a *A, b *B) error) (A, B, error) {
+	oa, ob := NewOut[A](), NewOut[B]()
+	defer oa.Free()
+	defer ob.Free()
+	if err := f(oa.Arg(), ob.Arg()); err != nil {
+		var za A
+		var zb B
+		return za, zb, err
+	}
+	return oa.Value(), ob.Value(), nil
+}
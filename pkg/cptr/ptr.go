@@ -0,0 +1,79 @@
+// Package cptr provides typed handles to C memory.
+//
+// Code that keeps values in C memory usually ends up converting between
+// unsafe.Pointer and typed pointers, and computing element addresses with
+// unsafe.Add, at every use. A Ptr[T] is a pointer to one T in C memory and
+// a CArray[T] a run of them, so the arithmetic and the conversions happen
+// in one place. An Out[T] is the slot a C function stores a result in
+// through an out-parameter, and Receive hands that result back as a plain
+// return value.
+//
+// Memory is allocated with cmem, and so is tracked by its leak checks when
+// built with -tags cmemdbg. T may be any type whose layout matches what C
+// expects, including a C.struct_ type from the caller's own package. The
+// pointer-passing rules apply as usual: T must not hold Go pointers, since
+// the garbage collector does not scan C memory.
+package cptr
+
+import (
+	"unsafe"
+
+	"github.com/lxwagn/using-go-with-c-libraries/pkg/cmem"
+)
+
+// A Ptr is a pointer to a T in C memory. The zero Ptr is nil.
+type Ptr[T any] struct {
+	p *T
+}
+
+// Alloc allocates a zeroed T in C memory. It must be released with Free.
+func Alloc[T any]() Ptr[T] {
+	var zero T
+	return Ptr[T]{(*T)(cmem.Calloc(1, int(unsafe.Sizeof(zero))))}
+}
+
+// New allocates a T in C memory and sets it to v.
+func New[T any](v T) Ptr[T] {
+	p := Alloc[T]()
+	p.Set(v)
+	return p
+}
+
+// From returns a Ptr to the T at p, typically memory that C allocated.
+func From[T any](p unsafe.Pointer) Ptr[T] {
+	return Ptr[T]{(*T)(p)}
+}
+
+// Get returns a copy of the value p points to.
+func (p Ptr[T]) Get() T {
+	return *p.p
+}
+
+// Set stores v where p points.
+func (p Ptr[T]) Set(v T) {
+	*p.p = v
+}
+
+// IsNil reports whether p is nil.
+func (p Ptr[T]) IsNil() bool {
+	return p.p == nil
+}
+
+// P returns p as a *T, to be passed to C. It is still C memory; the
+// garbage collector does not know about it.
+func (p Ptr[T]) P() *T {
+	return p.p
+}
+
+// Pointer returns p as an unsafe.Pointer, to be converted to a pointer to
+// a C type.
+func (p Ptr[T]) Pointer() unsafe.Pointer {
+	return unsafe.Pointer(p.p)
+}
+
+// Free releases memory allocated by Alloc or New, or by C's malloc. p and
+// every copy of it must not be used afterwards. Freeing a nil Ptr does
+// nothing.
+func (p Ptr[T]) Free() {
+	cmem.Free(unsafe.Pointer(p.p))
+}
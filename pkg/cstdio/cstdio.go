@@ -0,0 +1,186 @@
+//go:build !windows
+
+package cstdio
+
+/*
+#include <stdio.h>
+*/
+import "C"
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"sync"
+	"syscall"
+
+	"golang.org/x/sys/unix"
+
+	"github.com/lxwagn/using-go-with-c-libraries/pkg/cfd"
+)
+
+// A Stream is one of the standard output descriptors.
+type Stream int
+
+const (
+	Stdout Stream = 1
+	Stderr Stream = 2
+)
+
+func (s Stream) String() string {
+	switch s {
+	case Stdout:
+		return "stdout"
+	case Stderr:
+		return "stderr"
+	}
+	return fmt.Sprintf("fd %d", int(s))
+}
+
+// ErrBusy is returned by Start when the stream is already captured.
+var ErrBusy = errors.New("cstdio: stream is already captured")
+
+var (
+	mu     sync.Mutex
+	active = map[Stream]bool{}
+)
+
+// A Capture is a redirection of one stream, running until Stop.
+type Capture struct {
+	stream Stream
+	saved  int // the descriptor the stream had before
+
+	stopOnce sync.Once
+	stopErr  error
+	done     chan struct{}
+	err      error // set by the reader before done is closed
+}
+
+// Start redirects s and writes each line printed to it to w, with its
+// newline. A last line without one is written at Stop. The writes come
+// from a single goroutine, so w need not be safe for concurrent use. w
+// must not write to s itself, or each line would come back to it.
+func Start(s Stream, w io.Writer) (*Capture, error) {
+	return start(s, func(line []byte) error {
+		_, err := w.Write(line)
+		return err
+	})
+}
+
+// StartLog redirects s and logs each line printed to it at level, without
+// its newline, with the stream's name as the attribute "stream". As with
+// Start, l must not write to s: the default logger writes to stderr.
+func StartLog(s Stream, l *slog.Logger, level slog.Level) (*Capture, error) {
+	attr := slog.String("stream", s.String())
+	return start(s, func(line []byte) error {
+		l.LogAttrs(context.Background(), level, string(bytes.TrimSuffix(line, []byte("\n"))), attr)
+		return nil
+	})
+}
+
+func start(s Stream, forward func([]byte) error) (*Capture, error) {
+	if s != Stdout && s != Stderr {
+		return nil, fmt.Errorf("cstdio: cannot capture %v", s)
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if active[s] {
+		return nil, fmt.Errorf("%w: %v", ErrBusy, s)
+	}
+
+	// What C printed before the capture goes where it was headed.
+	C.fflush(nil)
+
+	// Hold off forks, as the os package does, until every new descriptor
+	// is close-on-exec.
+	syscall.ForkLock.RLock()
+	saved, err := syscall.Dup(int(s))
+	if err != nil {
+		syscall.ForkLock.RUnlock()
+		return nil, fmt.Errorf("cstdio: capture %v: %w", s, err)
+	}
+	syscall.CloseOnExec(saved)
+	var p [2]int
+	if err := syscall.Pipe(p[:]); err != nil {
+		syscall.ForkLock.RUnlock()
+		syscall.Close(saved)
+		return nil, fmt.Errorf("cstdio: capture %v: %w", s, err)
+	}
+	syscall.CloseOnExec(p[0])
+	syscall.CloseOnExec(p[1])
+	syscall.ForkLock.RUnlock()
+
+	// The stream itself becomes the pipe's only writer, so the reader
+	// sees end of file once Stop puts the old descriptor back.
+	err = unix.Dup2(p[1], int(s))
+	syscall.Close(p[1])
+	if err != nil {
+		syscall.Close(p[0])
+		syscall.Close(saved)
+		return nil, fmt.Errorf("cstdio: capture %v: %w", s, err)
+	}
+	r, err := cfd.Adopt(p[0], s.String()+" capture")
+	if err != nil {
+		unix.Dup2(saved, int(s))
+		syscall.Close(saved)
+		return nil, err
+	}
+
+	c := &Capture{stream: s, saved: saved, done: make(chan struct{})}
+	active[s] = true
+	go c.read(r, forward)
+	return c, nil
+}
+
+// read forwards lines until end of file. After a failed forward it keeps
+// draining the pipe, so that C never blocks on a full one.
+func (c *Capture) read(r io.ReadCloser, forward func([]byte) error) {
+	defer close(c.done)
+	defer r.Close()
+	br := bufio.NewReader(r)
+	for {
+		line, err := br.ReadBytes('\n')
+		if len(line) > 0 && c.err == nil {
+			c.err = forward(line)
+		}
+		if err != nil {
+			if err != io.EOF && c.err == nil {
+				c.err = err
+			}
+			return
+		}
+	}
+}
+
+// Stream returns the stream c captures.
+func (c *Capture) Stream() Stream {
+	return c.stream
+}
+
+// Stop flushes C's buffers, restores the stream and waits until every
+// line printed during the capture has been forwarded. It returns the
+// first error from forwarding or reading. Calling Stop again does nothing
+// and returns the same error.
+func (c *Capture) Stop() error {
+	c.stopOnce.Do(func() {
+		mu.Lock()
+		defer mu.Unlock()
+
+		C.fflush(nil)
+		if err := unix.Dup2(c.saved, int(c.stream)); err != nil {
+			// The stream still writes into the pipe, so the
+			// reader never finishes.
+			c.stopErr = fmt.Errorf("cstdio: restore %v: %w", c.stream, err)
+			return
+		}
+		syscall.Close(c.saved)
+		<-c.done
+		delete(active, c.stream)
+		c.stopErr = c.err
+	})
+	return c.stopErr
+}
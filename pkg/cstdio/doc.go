@@ -0,0 +1,38 @@
+// Package cstdio redirects what C code prints into Go and bridges C's
+// FILE streams to Go's io interfaces.
+//
+// A C library that calls printf writes to file descriptor 1 through its
+// own stdio buffer, out of reach of the log package, log/slog and
+// os.Stdout. Start points the descriptor at a pipe instead and forwards
+// each line read from the pipe to an io.Writer, and StartLog hands each
+// line to a slog.Logger. Stop flushes C's buffers, puts the original
+// descriptor back and returns once every line has been forwarded:
+//
+//	c, err := cstdio.StartLog(cstdio.Stdout, logger, slog.LevelInfo)
+//	...
+//	mylib.Print("hello") // logged as msg=hello stream=stdout
+//	err = c.Stop()
+//
+// The descriptors belong to the whole process, so a capture also takes
+// what Go writes to os.Stdout or os.Stderr meanwhile, and a child started
+// during it inherits the pipe. Only one capture of each stream may run at
+// a time.
+//
+// C decides how to buffer stdout the first time it writes to it. A pipe
+// is fully buffered, so output from a process whose C code has not yet
+// printed may arrive only at Stop, when the package flushes it, unless
+// the C code flushes its own.
+//
+// For C functions that take or return a FILE * of their own, a Writer is
+// a FILE * whose output goes to any io.Writer, built with fopencookie on
+// Linux and funopen on macOS and the BSDs, and a Reader adopts a FILE *
+// the C code opened as an io.ReadCloser:
+//
+//	w, err := cstdio.NewWriter(&buf)
+//	...
+//	C.myWriteReport((*C.FILE)(w.File()), title, values, n)
+//	err = w.Close() // flushes into buf
+//
+// The package is not available on Windows, where the C runtime's
+// descriptors are not the process's handles.
+package cstdio
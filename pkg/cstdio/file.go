@@ -0,0 +1,249 @@
+//go:build !windows
+
+package cstdio
+
+/*
+
+#define _GNU_SOURCE
+#include <errno.h>
+#include <stdint.h>
+#include <stdio.h>
+#include <sys/types.h>
+
+// Defined in file_export.go.
+extern long cstdioWrite(uintptr_t handle, char *buf, size_t n);
+extern void cstdioClose(uintptr_t handle);
+
+// glibc, musl and Android's bionic build a FILE from callbacks with
+// fopencookie, the BSDs and macOS with funopen. Both hand the callbacks a
+// void * cookie, here a handle to the Go writer.
+#if defined(__linux__)
+
+static ssize_t cookieWrite(void *cookie, const char *buf, size_t n) {
+	long rc = cstdioWrite((uintptr_t)cookie, (char *)buf, n);
+
+	if (rc < 0)
+		errno = EIO;
+	return rc;
+}
+
+static int cookieClose(void *cookie) {
+	cstdioClose((uintptr_t)cookie);
+	return 0;
+}
+
+static FILE *openWriter(uintptr_t handle) {
+	cookie_io_functions_t io = {.write = cookieWrite, .close = cookieClose};
+
+	return fopencookie((void *)handle, "w", io);
+}
+
+#else
+
+static int cookieWrite(void *cookie, const char *buf, int n) {
+	long rc = cstdioWrite((uintptr_t)cookie, (char *)buf, (size_t)n);
+
+	if (rc < 0)
+		errno = EIO;
+	return (int)rc;
+}
+
+static int cookieClose(void *cookie) {
+	cstdioClose((uintptr_t)cookie);
+	return 0;
+}
+
+static FILE *openWriter(uintptr_t handle) {
+	return funopen((void *)handle, NULL, cookieWrite, NULL, cookieClose);
+}
+
+#endif
+
+*/
+import "C"
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+	"syscall"
+	"unsafe"
+
+	"github.com/lxwagn/using-go-with-c-libraries/pkg/cpanic"
+	"github.com/lxwagn/using-go-with-c-libraries/pkg/handles"
+)
+
+// ErrClosed is returned by the methods of a closed Writer or Reader.
+var ErrClosed = errors.New("cstdio: file already closed")
+
+// A Writer is a C FILE * whose output goes to a Go io.Writer, for C
+// functions that print to a stream they are given.
+//
+// The FILE has stdio's own buffer, so what C writes reaches the io.Writer
+// when the buffer fills, at Flush and at Close. Each write happens on the
+// goroutine whose C call caused it. C must not use the FILE after Close.
+//
+// A panic in the io.Writer does not unwind the C call writing: C sees a
+// failed write, Err returns the panic as a *cpanic.Panic, and Flush and
+// Close raise it again once fflush or fclose has returned. Close closes
+// the FILE all the same.
+type Writer struct {
+	mu sync.Mutex
+	f  *C.FILE
+	s  *writerState
+}
+
+type writerState struct {
+	w       io.Writer
+	err     error // the first error from w
+	barrier cpanic.Barrier
+}
+
+// NewWriter returns a FILE * writing to w.
+func NewWriter(w io.Writer) (*Writer, error) {
+	s := &writerState{w: w}
+	h := handles.New(s)
+	f, err := C.openWriter(C.uintptr_t(h.Uintptr()))
+	if f == nil {
+		h.Delete()
+		return nil, cError("open", err)
+	}
+	return &Writer{f: f, s: s}, nil
+}
+
+// File returns the FILE *, for conversion to *C.FILE in the calling
+// package. It is nil once w is closed.
+func (w *Writer) File() unsafe.Pointer {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return unsafe.Pointer(w.f)
+}
+
+// Err returns the first error the io.Writer returned. C sees that error
+// as a failed write with errno set to EIO, which is all it can tell the
+// caller of the C function.
+func (w *Writer) Err() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.s.err
+}
+
+// Flush writes out what the FILE has buffered.
+func (w *Writer) Flush() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.f == nil {
+		return ErrClosed
+	}
+	rc, err := C.fflush(w.f)
+	w.s.barrier.Repanic()
+	if rc != 0 {
+		return w.fail("fflush", err)
+	}
+	return nil
+}
+
+// Close flushes and closes the FILE, returning the first error the
+// io.Writer returned, if any. It does not close the io.Writer.
+func (w *Writer) Close() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.f == nil {
+		return ErrClosed
+	}
+	rc, err := C.fclose(w.f)
+	w.f = nil
+	w.s.barrier.Repanic()
+	if rc != 0 || w.s.err != nil {
+		return w.fail("fclose", err)
+	}
+	return nil
+}
+
+// fail prefers the io.Writer's own error to the errno C reported for it.
+func (w *Writer) fail(op string, errno error) error {
+	if w.s.err != nil {
+		return w.s.err
+	}
+	return cError(op, errno)
+}
+
+// write runs in the callback, while a C call holds the FILE's lock but
+// not w.mu, which C's caller may be holding for Flush or Close.
+func (s *writerState) write(p []byte) error {
+	if s.err != nil {
+		return s.err
+	}
+	for len(p) > 0 {
+		n, err := s.w.Write(p)
+		if err == nil && n == 0 {
+			err = io.ErrShortWrite
+		}
+		if err != nil {
+			s.err = err
+			return err
+		}
+		p = p[n:]
+	}
+	return nil
+}
+
+// cError reports a failed stdio call, with the errno it left, or EIO if
+// it left none.
+func cError(op string, errno error) error {
+	if errno == nil {
+		errno = syscall.EIO
+	}
+	return fmt.Errorf("cstdio: %s: %w", op, errno)
+}
+
+// A Reader reads a C FILE * that C code opened and handed over, such as
+// one from fopen, popen or tmpfile, and closes it with fclose.
+type Reader struct {
+	mu sync.Mutex
+	f  *C.FILE
+}
+
+// NewReader adopts the FILE * f, which must be open for reading. The
+// Reader owns f from then on: nothing else may read or close it.
+func NewReader(f unsafe.Pointer) *Reader {
+	return &Reader{f: (*C.FILE)(f)}
+}
+
+// Read reads with fread, returning io.EOF at the end of the file.
+func (r *Reader) Read(p []byte) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.f == nil {
+		return 0, ErrClosed
+	}
+	if len(p) == 0 {
+		return 0, nil
+	}
+	// p holds no Go pointers, so C may fill it in place.
+	n, err := C.fread(unsafe.Pointer(unsafe.SliceData(p)), 1, C.size_t(len(p)), r.f)
+	if n > 0 {
+		return int(n), nil
+	}
+	if C.ferror(r.f) != 0 {
+		C.clearerr(r.f)
+		return 0, cError("fread", err)
+	}
+	return 0, io.EOF
+}
+
+// Close closes the FILE with fclose.
+func (r *Reader) Close() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.f == nil {
+		return ErrClosed
+	}
+	rc, err := C.fclose(r.f)
+	r.f = nil
+	if rc != 0 {
+		return cError("fclose", err)
+	}
+	return nil
+}
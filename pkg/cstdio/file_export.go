@@ -0,0 +1,44 @@
+//go:build !windows
+
+package cstdio
+
+/*
+#include <stdint.h>
+#include <stddef.h>
+*/
+import "C"
+
+import (
+	"unsafe"
+
+	"github.com/lxwagn/using-go-with-c-libraries/pkg/cpanic"
+	"github.com/lxwagn/using-go-with-c-libraries/pkg/handles"
+)
+
+// cstdioWrite is the FILE's write callback. It returns n, or -1 once the
+// io.Writer has failed or panicked, which the C side turns into EIO. The io.Writer
+// gets stdio's buffer itself, which io.Writer's contract forbids it to
+// keep.
+//
+//export cstdioWrite
+func cstdioWrite(handle C.uintptr_t, buf *C.char, n C.size_t) C.long {
+	s, err := handles.FromUintptr[*writerState](uintptr(handle)).Get()
+	if err == nil {
+		p := unsafe.Slice((*byte)(unsafe.Pointer(buf)), int(n))
+		if !cpanic.Do(&s.barrier, func() { err = s.write(p) }) {
+			s.err = s.barrier.Recovered()
+			err = s.err
+		}
+	}
+	if err != nil {
+		return -1
+	}
+	return C.long(n)
+}
+
+// cstdioClose is the FILE's close callback, the last use of the handle.
+//
+//export cstdioClose
+func cstdioClose(handle C.uintptr_t) {
+	handles.FromUintptr[*writerState](uintptr(handle)).Delete()
+}
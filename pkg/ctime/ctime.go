@@ -0,0 +1,159 @@
+package ctime
+
+/*
+
+#include <time.h>
+
+*/
+import "C"
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"time"
+	"unsafe"
+
+	"github.com/lxwagn/using-go-with-c-libraries/pkg/cnum"
+)
+
+// ErrInvalid is matched by the errors for C structs whose fraction of a
+// second is negative or a whole second or more.
+var ErrInvalid = errors.New("ctime: invalid time")
+
+// An IntType describes a C integer type by its width and signedness.
+type IntType struct {
+	Bits   int
+	Signed bool
+}
+
+// TimeT is this build's time_t.
+var TimeT = IntType{Bits: C.sizeof_time_t * 8, Signed: ^C.time_t(0) < 0}
+
+func (it IntType) String() string {
+	if it.Signed {
+		return fmt.Sprintf("signed %d-bit", it.Bits)
+	}
+	return fmt.Sprintf("unsigned %d-bit", it.Bits)
+}
+
+// Fits reports whether v is in the range of it.
+func (it IntType) Fits(v int64) bool {
+	if it.Signed {
+		return it.Bits >= 64 || -1<<(it.Bits-1) <= v && v < 1<<(it.Bits-1)
+	}
+	return v >= 0 && (it.Bits >= 63 || v < 1<<it.Bits)
+}
+
+// Seconds returns t as whole seconds since 1970 UTC, rounded down, or a
+// *cnum.RangeError if they do not fit in a time_t of type it.
+func (it IntType) Seconds(t time.Time) (int64, error) {
+	sec := t.Unix()
+	if err := it.check(sec); err != nil {
+		return 0, err
+	}
+	return sec, nil
+}
+
+func (it IntType) check(sec int64) error {
+	if !it.Fits(sec) {
+		return &cnum.RangeError{Value: fmt.Sprint(sec), Type: it.String() + " time_t"}
+	}
+	return nil
+}
+
+// ToTimeT converts t for a time_t, dropping any fraction of a second.
+func ToTimeT(t time.Time) (int64, error) {
+	return TimeT.Seconds(t)
+}
+
+// FromTimeT returns the time a time_t, as an int64, stands for, in the
+// local time zone as time.Unix gives it.
+func FromTimeT(sec int64) time.Time {
+	return time.Unix(sec, 0)
+}
+
+// split divides d into whole seconds and a fraction in units of unit,
+// rounding down so that the fraction is never negative, as C's structs
+// require.
+func split(d, unit time.Duration) (sec, frac int64) {
+	sec, rem := int64(d/time.Second), d%time.Second
+	if rem < 0 {
+		sec--
+		rem += time.Second
+	}
+	return sec, int64(rem / unit)
+}
+
+// join is the inverse of split, reporting a sum that overflows a
+// time.Duration.
+func join(sec, frac int64, unit time.Duration) (time.Duration, error) {
+	const (
+		maxSec = math.MaxInt64 / int64(time.Second)
+		minSec = math.MinInt64 / int64(time.Second)
+	)
+	s, d := sec, time.Duration(frac)*unit
+	if s < 0 {
+		// Borrow a second, which brings the most negative durations
+		// within reach of s*time.Second.
+		s, d = s+1, d-time.Second
+	}
+	base := time.Duration(s) * time.Second
+	if s > maxSec || s < minSec || d > 0 && base > math.MaxInt64-d || d < 0 && base < math.MinInt64-d {
+		return 0, &cnum.RangeError{Value: fmt.Sprint(sec), Type: "time.Duration, in seconds"}
+	}
+	return base + d, nil
+}
+
+// checkFrac reports a fraction outside [0, limit).
+func checkFrac(field string, frac, limit int64) error {
+	if frac < 0 || frac >= limit {
+		return fmt.Errorf("%w: %s %d not in [0, %d)", ErrInvalid, field, frac, limit)
+	}
+	return nil
+}
+
+// SetTimespec stores t in the struct timespec at p.
+func SetTimespec(p unsafe.Pointer, t time.Time) error {
+	sec, err := ToTimeT(t)
+	if err != nil {
+		return err
+	}
+	ts := (*C.struct_timespec)(p)
+	ts.tv_sec = C.time_t(sec)
+	ts.tv_nsec = C.long(t.Nanosecond())
+	return nil
+}
+
+// Timespec returns the time stored in the struct timespec at p.
+func Timespec(p unsafe.Pointer) (time.Time, error) {
+	ts := (*C.struct_timespec)(p)
+	if err := checkFrac("tv_nsec", int64(ts.tv_nsec), 1e9); err != nil {
+		return time.Time{}, err
+	}
+	return time.Unix(int64(ts.tv_sec), int64(ts.tv_nsec)), nil
+}
+
+// SetTimespecDuration stores d in the struct timespec at p. A negative d
+// has a negative tv_sec and a tv_nsec counting up from it, so -1.5s is
+// stored as -2s + 500000000ns.
+func SetTimespecDuration(p unsafe.Pointer, d time.Duration) error {
+	sec, nsec := split(d, time.Nanosecond)
+	if err := TimeT.check(sec); err != nil {
+		return err
+	}
+	ts := (*C.struct_timespec)(p)
+	ts.tv_sec = C.time_t(sec)
+	ts.tv_nsec = C.long(nsec)
+	return nil
+}
+
+// TimespecDuration returns the duration stored in the struct timespec at
+// p.
+func TimespecDuration(p unsafe.Pointer) (time.Duration, error) {
+	ts := (*C.struct_timespec)(p)
+	if err := checkFrac("tv_nsec", int64(ts.tv_nsec), 1e9); err != nil {
+		return 0, err
+	}
+	return join(int64(ts.tv_sec), int64(ts.tv_nsec), time.Nanosecond)
+}
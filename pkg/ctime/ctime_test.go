@@ -0,0 +1,147 @@
+//go:build cgo && unix
+
+package ctime_test
+
+import (
+	"errors"
+	"math"
+	"syscall"
+	"testing"
+	"time"
+	"unsafe"
+
+	"github.com/lxwagn/using-go-with-c-libraries/pkg/cnum"
+	"github.com/lxwagn/using-go-with-c-libraries/pkg/ctime"
+)
+
+// The tests stand syscall.Timespec and syscall.Timeval in for the C
+// structs, which a test file cannot name. They mirror the kernel's, and
+// so the C library's wherever its time_t is as wide as the kernel's.
+func checkMirror(t *testing.T) {
+	t.Helper()
+	var ts syscall.Timespec
+	if bits := int(unsafe.Sizeof(ts.Sec)) * 8; bits != ctime.TimeT.Bits {
+		t.Skipf("time_t is %v, syscall.Timespec has %d-bit seconds", ctime.TimeT, bits)
+	}
+}
+
+// set stores v in a field of a mirror, whose width varies with the
+// platform.
+func set[T ~int32 | ~int64](p *T, v int64) {
+	*p = T(v)
+}
+
+// TestRoundTrip checks that times either side of 1970, with and without
+// a fraction, survive a trip through each struct, as do negative
+// durations, whose tv_sec rounds down.
+func TestRoundTrip(t *testing.T) {
+	checkMirror(t)
+	times := []time.Time{
+		time.Unix(0, 0),
+		time.Unix(-1, 999_999_999),
+		time.Date(1901, 12, 13, 20, 45, 52, 123_456_789, time.UTC),
+		time.Date(2038, 1, 19, 3, 14, 8, 1, time.UTC),
+		time.Date(9999, 12, 31, 23, 59, 59, 999_999_999, time.UTC),
+	}
+	for _, tm := range times {
+		var ts syscall.Timespec
+		if err := ctime.SetTimespec(unsafe.Pointer(&ts), tm); err != nil {
+			t.Errorf("SetTimespec(%v): %v", tm, err)
+			continue
+		}
+		if got, err := ctime.Timespec(unsafe.Pointer(&ts)); err != nil || !got.Equal(tm) {
+			t.Errorf("timespec of %v came back as %v, %v", tm, got, err)
+		}
+		var tv syscall.Timeval
+		if err := ctime.SetTimeval(unsafe.Pointer(&tv), tm); err != nil {
+			t.Errorf("SetTimeval(%v): %v", tm, err)
+			continue
+		}
+		want := time.Unix(tm.Unix(), int64(tm.Nanosecond()/1000*1000))
+		if got, err := ctime.Timeval(unsafe.Pointer(&tv)); err != nil || !got.Equal(want) {
+			t.Errorf("timeval of %v came back as %v, %v, want %v", tm, got, err, want)
+		}
+	}
+
+	durations := []time.Duration{
+		0, 1, -1, 1500 * time.Millisecond, -1500 * time.Millisecond,
+		math.MaxInt64, math.MinInt64,
+	}
+	for _, d := range durations {
+		var ts syscall.Timespec
+		if err := ctime.SetTimespecDuration(unsafe.Pointer(&ts), d); err != nil {
+			t.Errorf("SetTimespecDuration(%v): %v", d, err)
+			continue
+		}
+		if ts.Nsec < 0 || ts.Nsec >= 1e9 {
+			t.Errorf("%v stored with tv_nsec %d", d, ts.Nsec)
+		}
+		if got, err := ctime.TimespecDuration(unsafe.Pointer(&ts)); err != nil || got != d {
+			t.Errorf("timespec of %v came back as %v, %v", d, got, err)
+		}
+		var tv syscall.Timeval
+		if err := ctime.SetTimevalDuration(unsafe.Pointer(&tv), d); err != nil {
+			t.Errorf("SetTimevalDuration(%v): %v", d, err)
+			continue
+		}
+		if d == math.MinInt64 {
+			// Rounded down to a microsecond it is past the minimum.
+			if _, err := ctime.TimevalDuration(unsafe.Pointer(&tv)); !errors.Is(err, cnum.ErrRange) {
+				t.Errorf("timeval of %v: %v, want a range error", d, err)
+			}
+			continue
+		}
+		want := d - ((d%time.Microsecond)+time.Microsecond)%time.Microsecond
+		if got, err := ctime.TimevalDuration(unsafe.Pointer(&tv)); err != nil || got != want {
+			t.Errorf("timeval of %v came back as %v, %v, want %v", d, got, err, want)
+		}
+	}
+}
+
+// TestRange checks that what a narrower or unsigned time_t cannot hold,
+// or a Duration, is refused, and so are structs with their fraction out
+// of range.
+func TestRange(t *testing.T) {
+	y2038 := time.Date(2038, 1, 19, 3, 14, 8, 0, time.UTC)
+	narrow := ctime.IntType{Bits: 32, Signed: true}
+	if _, err := narrow.Seconds(y2038.Add(-time.Second)); err != nil {
+		t.Errorf("32-bit time_t refused the last second before 2038: %v", err)
+	}
+	if _, err := narrow.Seconds(y2038); !errors.Is(err, cnum.ErrRange) {
+		t.Errorf("32-bit time_t took %v: %v", y2038, err)
+	}
+	unsigned := ctime.IntType{Bits: 32, Signed: false}
+	if _, err := unsigned.Seconds(time.Unix(-1, 0)); !errors.Is(err, cnum.ErrRange) {
+		t.Errorf("unsigned time_t took 1969: %v", err)
+	}
+	if sec, err := unsigned.Seconds(y2038); err != nil || sec != 1<<31 {
+		t.Errorf("unsigned 32-bit time_t gave %d, %v for %v", sec, err, y2038)
+	}
+
+	checkMirror(t)
+	if ctime.TimeT.Bits < 64 {
+		t.Skipf("time_t is %v, too narrow for the Duration limits", ctime.TimeT)
+	}
+	var ts syscall.Timespec
+	set(&ts.Sec, math.MaxInt64/int64(time.Second))
+	set(&ts.Nsec, 854_775_808) // one past time.Duration's maximum
+	if _, err := ctime.TimespecDuration(unsafe.Pointer(&ts)); !errors.Is(err, cnum.ErrRange) {
+		t.Errorf("TimespecDuration past the maximum: %v", err)
+	}
+	set(&ts.Sec, math.MinInt64/int64(time.Second)-1)
+	set(&ts.Nsec, 145_224_191) // one before the minimum
+	if _, err := ctime.TimespecDuration(unsafe.Pointer(&ts)); !errors.Is(err, cnum.ErrRange) {
+		t.Errorf("TimespecDuration before the minimum: %v", err)
+	}
+	for _, nsec := range []int64{-1, 1e9} {
+		set(&ts.Sec, 0)
+		set(&ts.Nsec, nsec)
+		if _, err := ctime.Timespec(unsafe.Pointer(&ts)); !errors.Is(err, ctime.ErrInvalid) {
+			t.Errorf("tv_nsec %d: %v, want ErrInvalid", nsec, err)
+		}
+	}
+	tv := syscall.Timeval{Usec: 1e6}
+	if _, err := ctime.TimevalDuration(unsafe.Pointer(&tv)); !errors.Is(err, ctime.ErrInvalid) {
+		t.Errorf("tv_usec %d: %v, want ErrInvalid", tv.Usec, err)
+	}
+}
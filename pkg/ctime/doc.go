@@ -0,0 +1,28 @@
+// Package ctime converts between Go's time.Time and time.Duration and C's
+// time_t, struct timespec and struct timeval.
+//
+// C leaves the width and signedness of time_t to the platform. It is a
+// signed 64-bit integer on the platforms Go supports today, but a 32-bit
+// time_t, still found on older 32-bit systems, ends in January 2038, and
+// an unsigned one cannot go before 1970. TimeT describes the time_t of
+// this build, and every conversion to it fails with a *cnum.RangeError
+// instead of wrapping a time it cannot hold around.
+//
+// In the other direction C can hand Go a timespec whose tv_nsec is not in
+// [0, 1e9), or a timeval whose tv_usec is not in [0, 1e6). Go's time.Unix
+// would quietly carry the excess into the seconds, which hides a bug in
+// the C code, so this package reports ErrInvalid instead. A duration read
+// from C that does not fit in a time.Duration, about 292 years, is a
+// *cnum.RangeError.
+//
+// The structs are passed as unsafe.Pointer, since each package that uses
+// cgo has its own Go type for struct timespec:
+//
+//	var ts C.struct_timespec
+//	if err := ctime.SetTimespec(unsafe.Pointer(&ts), deadline); err != nil {
+//		return err
+//	}
+//	C.waitUntil(&ts)
+//
+// struct timeval is not available on Windows.
+package ctime
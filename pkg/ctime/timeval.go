@@ -0,0 +1,59 @@
+//go:build !windows
+
+package ctime
+
+/*
+
+#include <sys/time.h>
+
+*/
+import "C"
+
+import (
+	"time"
+	"unsafe"
+)
+
+// SetTimeval stores t in the struct timeval at p, rounded down to a
+// microsecond.
+func SetTimeval(p unsafe.Pointer, t time.Time) error {
+	sec, err := ToTimeT(t)
+	if err != nil {
+		return err
+	}
+	tv := (*C.struct_timeval)(p)
+	tv.tv_sec = C.time_t(sec)
+	tv.tv_usec = C.suseconds_t(t.Nanosecond() / 1000)
+	return nil
+}
+
+// Timeval returns the time stored in the struct timeval at p.
+func Timeval(p unsafe.Pointer) (time.Time, error) {
+	tv := (*C.struct_timeval)(p)
+	if err := checkFrac("tv_usec", int64(tv.tv_usec), 1e6); err != nil {
+		return time.Time{}, err
+	}
+	return time.Unix(int64(tv.tv_sec), int64(tv.tv_usec)*1000), nil
+}
+
+// SetTimevalDuration stores d in the struct timeval at p, rounded down to
+// a microsecond, with a negative d stored as for SetTimespecDuration.
+func SetTimevalDuration(p unsafe.Pointer, d time.Duration) error {
+	sec, usec := split(d, time.Microsecond)
+	if err := TimeT.check(sec); err != nil {
+		return err
+	}
+	tv := (*C.struct_timeval)(p)
+	tv.tv_sec = C.time_t(sec)
+	tv.tv_usec = C.suseconds_t(usec)
+	return nil
+}
+
+// TimevalDuration returns the duration stored in the struct timeval at p.
+func TimevalDuration(p unsafe.Pointer) (time.Duration, error) {
+	tv := (*C.struct_timeval)(p)
+	if err := checkFrac("tv_usec", int64(tv.tv_usec), 1e6); err != nil {
+		return 0, err
+	}
+	return join(int64(tv.tv_sec), int64(tv.tv_usec), time.Microsecond)
+}
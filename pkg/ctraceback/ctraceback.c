@@ -0,0 +1,97 @@
+//go:build linux && (amd64 || arm64)
+
+#define _GNU_SOURCE
+#include <dlfcn.h>
+#include <stdint.h>
+#include <sys/uio.h>
+#include <ucontext.h>
+#include <unistd.h>
+
+/* The argument structs of runtime.SetCgoTraceback. */
+struct tracebackArg {
+	uintptr_t context;
+	uintptr_t sigContext;
+	uintptr_t *buf;
+	uintptr_t max;
+};
+
+struct symbolizerArg {
+	uintptr_t pc;
+	const char *file;
+	uintptr_t lineno;
+	const char *func;
+	uintptr_t entry;
+	uintptr_t more;
+	uintptr_t data;
+};
+
+/* A frame record, pointed to by the frame pointer on both amd64 and arm64. */
+struct frame {
+	uintptr_t next;
+	uintptr_t ret;
+};
+
+/* The largest plausible gap between two frames of one C stack. */
+#define MAX_FRAME_GAP (1 << 20)
+
+/*
+ * readFrame copies the frame record at fp without touching it directly, so
+ * that a frame pointer into unmapped memory returns 0 instead of raising a
+ * second signal. Both process_vm_readv and getpid are async-signal safe.
+ */
+static int readFrame(uintptr_t fp, struct frame *f) {
+	struct iovec local = {f, sizeof(*f)};
+	struct iovec remote = {(void *)fp, sizeof(*f)};
+
+	return process_vm_readv(getpid(), &local, 1, &remote, 1, 0) == sizeof(*f);
+}
+
+void ctracebackTraceback(void *p) {
+	struct tracebackArg *arg = p;
+	uintptr_t fp;
+	uintptr_t n = 0;
+	struct frame f;
+
+	if (arg->max == 0)
+		return;
+	if (arg->sigContext != 0) {
+		ucontext_t *uc = (ucontext_t *)arg->sigContext;
+
+#if defined(__x86_64__)
+		arg->buf[n++] = uc->uc_mcontext.gregs[REG_RIP];
+		fp = uc->uc_mcontext.gregs[REG_RBP];
+#else
+		arg->buf[n++] = uc->uc_mcontext.pc;
+		fp = uc->uc_mcontext.regs[29];
+#endif
+	} else {
+		/* Called from C code asking for its own stack; skip this frame. */
+		fp = (uintptr_t)__builtin_frame_address(0);
+	}
+
+	while (n < arg->max && readFrame(fp, &f) && f.ret != 0) {
+		/* The call instruction, as the runtime wants, not the return address. */
+		arg->buf[n++] = f.ret - 1;
+		/* The stack grows down; anything else has left the C stack. */
+		if (f.next <= fp || f.next - fp > MAX_FRAME_GAP)
+			break;
+		fp = f.next;
+	}
+	if (n < arg->max)
+		arg->buf[n] = 0;
+}
+
+void ctracebackSymbolizer(void *p) {
+	struct symbolizerArg *arg = p;
+	Dl_info info;
+
+	arg->more = 0;
+	if (dladdr((void *)arg->pc, &info) == 0)
+		return;
+	arg->file = info.dli_fname;
+	arg->lineno = 0;
+	if (info.dli_sname != NULL) {
+		arg->func = info.dli_sname;
+		arg->entry = (uintptr_t)info.dli_saddr;
+	}
+}
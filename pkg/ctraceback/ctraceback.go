@@ -0,0 +1,23 @@
+//go:build linux && (amd64 || arm64)
+
+package ctraceback
+
+/*
+#cgo LDFLAGS: -ldl
+
+extern void ctracebackTraceback(void *);
+extern void ctracebackSymbolizer(void *);
+*/
+import "C"
+
+import (
+	"runtime"
+	"unsafe"
+)
+
+func init() {
+	runtime.SetCgoTraceback(0,
+		unsafe.Pointer(C.ctracebackTraceback),
+		nil,
+		unsafe.Pointer(C.ctracebackSymbolizer))
+}
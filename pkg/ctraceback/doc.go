@@ -0,0 +1,29 @@
+// Package ctraceback makes Go tracebacks show the C functions on the
+// stack. Importing it for its side effect is enough:
+//
+//	import _ "github.com/lxwagn/using-go-with-c-libraries/pkg/ctraceback"
+//
+// Without it, a crash inside a C function prints only the PC of the
+// fault and the Go frames that led to the cgo call. The package registers
+// C traceback and symbolizer functions with runtime.SetCgoTraceback, so
+// the runtime can list the C frames between the fault and Go, by name:
+//
+//	SIGSEGV: segmentation violation
+//	PC=0x7f63c886fef6 m=0 sigcode=1 addr=0x0
+//	signal arrived during cgo execution
+//
+//	goroutine 1 gp=0x1946b60121e0 m=0 mp=0x537560 [syscall]:
+//	myChecksum
+//		/usr/local/lib/libmylib.so:0 pc=0x7f63c886fef6
+//	...
+//	runtime.cgocall(0x481a30, 0x1946b605be90)
+//
+// The C frames are found by following frame pointers, read with
+// process_vm_readv so that a broken chain cannot fault again, and named
+// with dladdr, which knows only exported symbols: static functions show
+// as the nearest exported one before them. Code built with
+// -fomit-frame-pointer (the default at -O2 on amd64) ends the walk early.
+//
+// The runtime only asks for C frames at a signal on linux/amd64 and
+// linux/arm64; elsewhere importing the package has no effect.
+package ctraceback
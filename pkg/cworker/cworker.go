@@ -0,0 +1,159 @@
+// Package cworker funnels C calls through a fixed pool of OS threads.
+//
+// A goroutine in a cgo call keeps its OS thread for as long as the call
+// lasts, and the scheduler starts another thread to run the remaining
+// goroutines. A program making many slow or blocking C calls at once can
+// therefore pile up thousands of threads, and it crashes when it reaches
+// the runtime's limit (see runtime/debug.SetMaxThreads). A Pool bounds
+// that number: it owns n threads, locked to their goroutines, and runs
+// every function passed to Submit on one of them. The callers wait on a
+// channel, which costs a parked goroutine rather than a thread.
+//
+// Since the threads are the pool's own, C code run on them can also keep
+// thread-local state from one call to the next, though not which of the
+// threads the next call lands on.
+//
+// A function running on the pool must not submit to the same pool, even
+// indirectly, as by calling a method of a pkg/mylib Session created with
+// UseWorkers and the same pool. It would wait for a thread while holding
+// one, and once every thread does that, none is left to run what they
+// wait for: the pool deadlocks, and Go's deadlock detector cannot tell,
+// since the pool's goroutines are still alive.
+package cworker
+
+import (
+	"errors"
+	"runtime"
+	"sync"
+	"sync/atomic"
+
+	"github.com/lxwagn/using-go-with-c-libraries/pkg/cpanic"
+)
+
+// ErrClosed is returned by Submit once the pool is closed.
+var ErrClosed = errors.New("cworker: pool is closed")
+
+// A Pool runs functions on a fixed set of locked OS threads. The zero
+// Pool is not usable; create one with New.
+type Pool struct {
+	mu     sync.RWMutex // held for reading while submitting, for writing by Close
+	closed bool
+	reqs   chan *request
+	wg     sync.WaitGroup
+
+	workers   int
+	queued    atomic.Int64 // submitted and not yet started
+	busy      atomic.Int64
+	completed atomic.Int64
+	maxQueued atomic.Int64
+}
+
+type request struct {
+	f    func()
+	b    cpanic.Barrier // holds f's panic, raised again by Submit
+	done chan struct{}
+}
+
+// New starts a pool of n threads, at least one, whose queue holds up to
+// queue functions waiting for a thread. Submit blocks while the queue is
+// full.
+func New(n, queue int) *Pool {
+	n = max(n, 1)
+	p := &Pool{
+		reqs:    make(chan *request, max(queue, 0)),
+		workers: n,
+	}
+	p.wg.Add(n)
+	for range n {
+		go p.work()
+	}
+	return p
+}
+
+func (p *Pool) work() {
+	defer p.wg.Done()
+	// The thread is never unlocked, so when the goroutine returns the
+	// runtime terminates it rather than handing it, with whatever
+	// thread-local state C left behind, to other goroutines.
+	runtime.LockOSThread()
+	for r := range p.reqs {
+		p.queued.Add(-1)
+		p.busy.Add(1)
+		cpanic.Do(&r.b, r.f)
+		p.busy.Add(-1)
+		p.completed.Add(1)
+		close(r.done)
+	}
+}
+
+// Submit runs f on one of the pool's threads and waits for it to return.
+// It returns ErrClosed, without running f, if the pool is closed. f runs
+// with its goroutine locked to the thread, and must not unlock it, nor
+// call Submit on the same pool (see the package comment). If f panics,
+// the thread recovers and Submit panics with a *cpanic.Panic holding
+// the value and f's stack, on the caller's goroutine.
+func (p *Pool) Submit(f func()) error {
+	p.mu.RLock()
+	if p.closed {
+		p.mu.RUnlock()
+		return ErrClosed
+	}
+	r := &request{f: f, done: make(chan struct{})}
+	raise(&p.maxQueued, p.queued.Add(1))
+	p.reqs <- r
+	p.mu.RUnlock()
+
+	<-r.done
+	r.b.Repanic()
+	return nil
+}
+
+// raise sets v to n if n is larger.
+func raise(v *atomic.Int64, n int64) {
+	for {
+		m := v.Load()
+		if n <= m || v.CompareAndSwap(m, n) {
+			return
+		}
+	}
+}
+
+// Call runs f on one of p's threads, like Submit, and returns its result.
+func Call[T any](p *Pool, f func() T) (T, error) {
+	var v T
+	err := p.Submit(func() { v = f() })
+	return v, err
+}
+
+// Stats describes the load on a pool at one moment.
+type Stats struct {
+	Workers   int   // threads in the pool
+	Queued    int   // submitted functions waiting for a thread
+	Busy      int   // threads running a function
+	Completed int64 // functions that have returned
+	MaxQueued int   // the most functions ever waiting at once
+}
+
+// Stats returns the pool's current queue depth and counters. The fields
+// are read one at a time, so under load they need not add up exactly.
+func (p *Pool) Stats() Stats {
+	return Stats{
+		Workers:   p.workers,
+		Queued:    int(p.queued.Load()),
+		Busy:      int(p.busy.Load()),
+		Completed: p.completed.Load(),
+		MaxQueued: int(p.maxQueued.Load()),
+	}
+}
+
+// Close stops accepting functions, waits for those already submitted to
+// return and ends the pool's threads. It can be called more than once.
+func (p *Pool) Close() {
+	p.mu.Lock()
+	if !p.closed {
+		p.closed = true
+		close(p.reqs)
+	}
+	p.mu.Unlock()
+	p.wg.Wait()
+}
@@ -0,0 +1,85 @@
+package cworker_test
+
+import (
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/lxwagn/using-go-with-c-libraries/pkg/cpanic"
+	"github.com/lxwagn/using-go-with-c-libraries/pkg/cworker"
+)
+
+// TestBounded submits from many more callers than threads: no more than
+// four functions ever run at once, and every one of them completes.
+func TestBounded(t *testing.T) {
+	p := cworker.New(4, 16)
+	defer p.Close()
+
+	var running, peak atomic.Int64
+	var wg sync.WaitGroup
+	for range 64 {
+		wg.Go(func() {
+			for range 20 {
+				p.Submit(func() {
+					n := running.Add(1)
+					for m := peak.Load(); n > m && !peak.CompareAndSwap(m, n); m = peak.Load() {
+					}
+					time.Sleep(10 * time.Microsecond)
+					running.Add(-1)
+				})
+			}
+		})
+	}
+	wg.Wait()
+
+	if peak.Load() > 4 {
+		t.Errorf("%d functions running at once on a pool of 4", peak.Load())
+	}
+	if st := p.Stats(); st.Completed != 64*20 || st.Queued != 0 || st.Busy != 0 {
+		t.Errorf("Stats() = %+v after 1280 calls", st)
+	}
+}
+
+func TestCall(t *testing.T) {
+	p := cworker.New(2, 0)
+	if v, err := cworker.Call(p, func() int { return 3 }); err != nil || v != 3 {
+		t.Errorf("Call = %d, %v, want 3", v, err)
+	}
+
+	p.Close()
+	p.Close()
+	ran := false
+	if err := p.Submit(func() { ran = true }); !errors.Is(err, cworker.ErrClosed) || ran {
+		t.Errorf("Submit after Close = %v, ran %v; want ErrClosed without running", err, ran)
+	}
+}
+
+// TestPanic panics on the pool's threads: each Submit raises its own
+// function's panic, and the threads carry on running the next ones.
+func TestPanic(t *testing.T) {
+	p := cworker.New(2, 0)
+	defer p.Close()
+
+	var wg sync.WaitGroup
+	for i := range 8 {
+		wg.Go(func() {
+			defer func() {
+				cp, ok := recover().(*cpanic.Panic)
+				if !ok || cp.Value != i {
+					t.Errorf("Submit of a function panicking with %d panicked with %v", i, cp)
+				}
+			}()
+			p.Submit(func() { panic(i) })
+		})
+	}
+	wg.Wait()
+
+	if v, err := cworker.Call(p, func() int { return 1 }); err != nil || v != 1 {
+		t.Errorf("Call after the panics = %d, %v, want 1", v, err)
+	}
+	if st := p.Stats(); st.Completed != 9 || st.Busy != 0 {
+		t.Errorf("Stats() = %+v after 9 calls", st)
+	}
+}
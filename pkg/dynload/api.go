@@ -0,0 +1,165 @@
+package dynload
+
+/*
+
+#cgo CFLAGS: -I${SRCDIR}/../../src
+#include <stdint.h>
+#include <stdlib.h>
+#include "mylib.h"
+
+// Each shim casts a symbol resolved with dlsym back to its real type from
+// mylib.h and calls it. cgo cannot call through a function pointer
+// itself.
+
+static void callPrint(void *fn, char *s) {
+	((void (*)(char *))fn)(s);
+}
+
+static int callLookup(void *fn, const char *key, int *value) {
+	return ((int (*)(const char *, int *))fn)(key, value);
+}
+
+static int callCounterAdd(void *fn, int delta) {
+	return ((int (*)(int))fn)(delta);
+}
+
+// Defined in callback_export.go.
+extern int goDynloadCallback(void *userdata, int value);
+
+static int callCallN(void *fn, uintptr_t handle, int n) {
+	return ((int (*)(myCallback, void *, int))fn)((myCallback)goDynloadCallback, (void *)handle, n);
+}
+
+static unsigned int callChecksum(void *fn, const unsigned char *buf, size_t n) {
+	return ((unsigned int (*)(const unsigned char *, size_t))fn)(buf, n);
+}
+
+*/
+import "C"
+
+import (
+	"strings"
+	"unsafe"
+
+	"github.com/lxwagn/using-go-with-c-libraries/internal/status"
+	"github.com/lxwagn/using-go-with-c-libraries/pkg/cmem"
+	"github.com/lxwagn/using-go-with-c-libraries/pkg/cnum"
+	"github.com/lxwagn/using-go-with-c-libraries/pkg/cpanic"
+	"github.com/lxwagn/using-go-with-c-libraries/pkg/handles"
+)
+
+// symbols lists every symbol the methods below use, for Options.Eager.
+var symbols = []string{"myPrintFunction", "myLookup", "myCounterAdd", "myCallN", "myChecksum"}
+
+// codes is pkg/mylib's table, so the errors below are pkg/mylib's too.
+var codes = status.Codes
+
+// Errors returned by the library. They are the same values as pkg/mylib's
+// errors of the same names.
+var (
+	ErrNUL      = status.ErrNUL
+	ErrNotFound = status.ErrNotFound
+	ErrInvalid  = status.ErrInvalid
+	ErrRange    = status.ErrRange
+	ErrNoMemory = status.ErrNoMemory
+)
+
+// StatusOf returns the status code behind err, as mylib.StatusOf does.
+func StatusOf(err error) (int, bool) {
+	return status.Of(err)
+}
+
+// mustSym is sym for the methods whose pkg/mylib counterparts return no
+// error: it panics with the error instead, as pkg/mylib's nocgo build
+// does when the library cannot be loaded.
+func (l *Library) mustSym(name string) (*table, unsafe.Pointer) {
+	t, fn, err := l.sym(name)
+	if err != nil {
+		panic(err)
+	}
+	return t, fn
+}
+
+// Print writes s followed by a newline using myPrintFunction.
+func (l *Library) Print(s string) error {
+	if strings.IndexByte(s, 0) >= 0 {
+		return ErrNUL
+	}
+	t, fn, err := l.sym("myPrintFunction")
+	if err != nil {
+		return err
+	}
+	defer t.release()
+
+	cs := (*C.char)(cmem.CString(s))
+	defer cmem.Free(unsafe.Pointer(cs))
+
+	C.callPrint(fn, cs)
+	return nil
+}
+
+// Lookup returns the value stored in the library's table under key.
+func (l *Library) Lookup(key string) (int, error) {
+	if strings.IndexByte(key, 0) >= 0 {
+		return 0, ErrNUL
+	}
+	t, fn, err := l.sym("myLookup")
+	if err != nil {
+		return 0, err
+	}
+	defer t.release()
+
+	ckey := (*C.char)(cmem.CString(key))
+	defer cmem.Free(unsafe.Pointer(ckey))
+
+	var v C.int
+	if err := codes.Error("myLookup", int(C.callLookup(fn, ckey, &v))); err != nil {
+		return 0, err
+	}
+	return int(v), nil
+}
+
+// CounterAdd adds delta to the library's global counter and returns the
+// new value. Unlike pkg/mylib, calls are not serialized. It panics if
+// delta does not fit in a C int, and with the error, such as ErrUnloaded,
+// if the library cannot be called.
+func (l *Library) CounterAdd(delta int) int {
+	cd := cnum.Must(cnum.ToCInt(delta))
+	t, fn := l.mustSym("myCounterAdd")
+	defer t.release()
+	return int(C.callCounterAdd(fn, C.int(cd)))
+}
+
+// CallN has myCallN call fn with 0 through n-1 and returns the sum of
+// the results. The library is not unloaded while fn runs, so fn may call
+// l's other methods; while Unload or Close waits for it, those fail with
+// ErrUnloaded or ErrClosed. It panics if n does not fit in a C int, and
+// with the error if the library cannot be called. If fn panics, the calls
+// left return 0 without running fn, and CallN raises the panic again, as
+// a *cpanic.Panic, once myCallN has returned.
+func (l *Library) CallN(n int, fn func(int) int) int {
+	cn := cnum.Must(cnum.ToCInt(n))
+	t, sym := l.mustSym("myCallN")
+	defer t.release()
+
+	c := &callN{fn: fn}
+	h := handles.New(c)
+	defer h.Delete()
+	r := C.callCallN(sym, C.uintptr_t(h.Uintptr()), C.int(cn))
+	c.barrier.Repanic()
+	return int(r)
+}
+
+// callN is what the handle of a CallN in progress refers to.
+type callN struct {
+	fn      func(int) int
+	barrier cpanic.Barrier
+}
+
+// Checksum returns the Adler-32 checksum of b as computed by the library.
+// It panics with the error if the library cannot be called.
+func (l *Library) Checksum(b []byte) uint32 {
+	t, fn := l.mustSym("myChecksum")
+	defer t.release()
+	return uint32(C.callChecksum(fn, (*C.uchar)(unsafe.Pointer(unsafe.SliceData(b))), C.size_t(len(b))))
+}
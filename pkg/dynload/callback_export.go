@@ -0,0 +1,22 @@
+package dynload
+
+import "C"
+
+import (
+	"unsafe"
+
+	"github.com/lxwagn/using-go-with-c-libraries/pkg/cpanic"
+	"github.com/lxwagn/using-go-with-c-libraries/pkg/handles"
+)
+
+// goDynloadCallback runs within myCallN, on the goroutine that called
+// CallN.
+//
+//export goDynloadCallback
+func goDynloadCallback(userdata unsafe.Pointer, value C.int) C.int {
+	c, err := handles.FromUintptr[*callN](uintptr(userdata)).Get()
+	if err != nil {
+		return 0
+	}
+	return C.int(cpanic.Call(&c.barrier, 0, func() int { return c.fn(int(value)) }))
+}
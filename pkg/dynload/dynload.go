@@ -0,0 +1,368 @@
+// Package dynload loads libmylib at run time with dlopen instead of
+// linking against it.
+//
+// A program using pkg/mylib will not even start when libmylib.so is
+// missing: the dynamic linker refuses to run it. A program using this
+// package starts normally, and Open reports which paths were tried and
+// why they failed, so the program can print a friendly message or fall
+// back to something else. By default it searches the directories listed
+// in pkg/libpath.
+//
+// A Library's methods have the signatures of pkg/mylib's functions of
+// the same names, and return the same errors. Where pkg/mylib returns no
+// error, as from CounterAdd, a call the library cannot take, because a
+// symbol is missing or the library is unloaded, panics with the error.
+//
+// Symbols are looked up the first time they are used, unless
+// Options.Eager is set. Reload swaps in a new build of the library while
+// the program runs, and Unload unloads it until the next Reload.
+//
+// Other libraries can be loaded with OpenHandle, which is the dlopen
+// underneath a Library, without any of the above.
+package dynload
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"unsafe"
+
+	"github.com/lxwagn/using-go-with-c-libraries/pkg/libpath"
+)
+
+// DefaultName is the file name of the library.
+const DefaultName = "libmylib.so"
+
+// Options configure Open.
+type Options struct {
+	// Name is the library's file name. It defaults to DefaultName.
+	Name string
+
+	// Paths are the directories to search, in order. They default to
+	// libpath.Dirs: $MYLIB_PATH, the executable's directory and ../lib
+	// next to it, the XDG data directories and the dynamic linker's own
+	// search path. The empty string stands for the latter.
+	Paths []string
+
+	// Eager resolves every symbol the package uses during Open, so a
+	// library missing one of them fails to open rather than failing at
+	// the first call.
+	Eager bool
+}
+
+// An OpenError reports that the library could not be loaded from any of
+// the paths searched, with the dlerror message for each. Open's error
+// wraps one.
+type OpenError = libpath.Error
+
+// A SymbolError reports that the library lacks a symbol.
+type SymbolError struct {
+	Name string
+	Err  string // the dlerror message
+}
+
+func (e *SymbolError) Error() string {
+	return "dynload: missing symbol " + e.Name + ": " + e.Err
+}
+
+var (
+	// ErrClosed is returned by methods of a closed Library.
+	ErrClosed = errors.New("dynload: library is closed")
+
+	// ErrUnloaded is returned by calls into a Library that Unload has
+	// unloaded, until Reload loads it again.
+	ErrUnloaded = errors.New("dynload: library is unloaded")
+)
+
+// A Library is a loaded copy of libmylib. Its methods mirror pkg/mylib.
+// A Library is safe for concurrent use, including calls made while Reload
+// is replacing the library.
+type Library struct {
+	path  string
+	eager bool
+
+	// cur is the loaded copy, or nil while Reload is replacing it and
+	// after Close or a failed Reload.
+	cur atomic.Pointer[table]
+
+	mu    sync.Mutex // held by Reload, Unload and Close throughout
+	err   error      // why cur is nil, when it is not being replaced
+	names []string   // the symbols to resolve at the next Reload, if cur is nil
+
+	// gone points to ErrUnloaded or ErrClosed from the moment Unload or
+	// Close begins, so that calls made meanwhile fail at once rather than
+	// wait for l.mu. Among them may be calls from a callback of a call
+	// being waited for, which would otherwise never return.
+	gone atomic.Pointer[error]
+}
+
+// A table is one dlopen of the library and the symbols resolved in it.
+// Every call into C holds a reference to the table it uses, so that the
+// table is not closed under it.
+type table struct {
+	h *Handle
+
+	mu   sync.Mutex
+	syms map[string]unsafe.Pointer
+
+	refs    atomic.Int64
+	retired atomic.Bool
+	once    sync.Once
+	drained chan struct{} // closed once retired and unreferenced
+}
+
+// Open loads the library, searching the configured paths in order.
+func Open(opts Options) (*Library, error) {
+	name := opts.Name
+	if name == "" {
+		name = DefaultName
+	}
+
+	var t *table
+	path, err := libpath.Search(name, opts.Paths, func(path string) (err error) {
+		t, err = openTable(path)
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("dynload: %w", err)
+	}
+	if opts.Eager {
+		if err := t.resolve(symbols); err != nil {
+			t.close()
+			return nil, err
+		}
+	}
+	l := &Library{path: path, eager: opts.Eager}
+	l.cur.Store(t)
+	return l, nil
+}
+
+// Path returns the path the library was loaded from.
+func (l *Library) Path() string {
+	return l.path
+}
+
+// Close unloads the library, once the calls in progress have returned.
+// Calls made afterwards return ErrClosed.
+func (l *Library) Close() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.err == ErrClosed {
+		return ErrClosed
+	}
+	l.gone.Store(&ErrClosed)
+	l.err = ErrClosed
+	t := l.cur.Swap(nil)
+	if t == nil {
+		return nil
+	}
+	t.drain()
+	return t.close()
+}
+
+// Unload unloads the library once the calls in progress have returned,
+// including any callbacks they are running, and forgets the symbols
+// resolved in it. Calls made from then on, and from those callbacks
+// meanwhile, return ErrUnloaded until Reload loads the library again.
+// Unloading an unloaded library does nothing.
+//
+// As with Reload, the library's code is only unmapped if nothing else in
+// the process holds it; either way, no call made through l runs it again.
+func (l *Library) Unload() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.err == ErrClosed {
+		return ErrClosed
+	}
+	// Before cur is cleared, so that no caller finds it nil and waits.
+	l.gone.Store(&ErrUnloaded)
+	t := l.cur.Swap(nil)
+	l.err = ErrUnloaded
+	if t == nil {
+		return nil
+	}
+	if !l.eager {
+		l.names = t.resolved()
+	}
+	t.drain()
+	t.invalidate()
+	return t.close()
+}
+
+// Reload unloads the library and loads it again from the same path, so
+// that a new build installed there takes effect without restarting the
+// program. Calls already in progress finish on the old library first;
+// calls made in the meantime wait and then run on the new one. The
+// symbols used so far, or all of them with Options.Eager, are resolved in
+// the new library before it is used.
+//
+// The old library is gone before the new one is opened: dlopen would
+// otherwise hand back the copy that is already loaded. If the new one
+// cannot be loaded, or lacks a symbol, calls fail with Reload's error
+// until a later Reload succeeds.
+//
+// dlclose only unloads a library that nothing else holds. A program that
+// also links it, through pkg/mylib for example, keeps running the copy it
+// started with unless the Library was opened from a path of its own.
+//
+// Unlike Unload, Reload makes callers wait, and a callback of a call in
+// progress that calls into l would wait for Reload while Reload waits for
+// it. Such a callback should not run while its Library is reloaded.
+func (l *Library) Reload() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.err == ErrClosed {
+		return ErrClosed
+	}
+	l.gone.Store(nil)
+
+	names := symbols
+	if !l.eager && l.names != nil {
+		names = l.names
+	}
+	if old := l.cur.Swap(nil); old != nil {
+		if !l.eager {
+			names = old.resolved()
+		}
+		old.drain()
+		if err := old.close(); err != nil {
+			l.err = err
+			return err
+		}
+	}
+
+	l.names = names
+	t, err := openTable(l.path)
+	if err == nil {
+		if err = t.resolve(names); err != nil {
+			t.close()
+		}
+	}
+	if err != nil {
+		l.err = err
+		return err
+	}
+	l.err = nil
+	l.cur.Store(t)
+	return nil
+}
+
+// sym returns the address of the named symbol, resolving it on first use,
+// and the table it belongs to. The caller must release the table once its
+// call into C has returned.
+func (l *Library) sym(name string) (*table, unsafe.Pointer, error) {
+	t, err := l.acquire()
+	if err != nil {
+		return nil, nil, err
+	}
+	p, err := t.sym(name)
+	if err != nil {
+		t.release()
+		return nil, nil, err
+	}
+	return t, p, nil
+}
+
+// acquire returns the current table with a reference held.
+func (l *Library) acquire() (*table, error) {
+	for {
+		if t := l.cur.Load(); t != nil {
+			t.refs.Add(1)
+			// Reload may have retired t between the two loads; it
+			// then waits for references taken before it did, and
+			// this one must not be used.
+			if l.cur.Load() == t {
+				return t, nil
+			}
+			t.release()
+			continue
+		}
+
+		if err := l.gone.Load(); err != nil {
+			return nil, *err
+		}
+		// Wait out a Reload in progress.
+		l.mu.Lock()
+		t, err := l.cur.Load(), l.err
+		l.mu.Unlock()
+		if t == nil {
+			return nil, err
+		}
+	}
+}
+
+func openTable(path string) (*table, error) {
+	h, err := OpenHandle(path, Lazy)
+	if err != nil {
+		return nil, err
+	}
+	return &table{h: h, syms: make(map[string]unsafe.Pointer), drained: make(chan struct{})}, nil
+}
+
+func (t *table) release() {
+	if t.refs.Add(-1) == 0 && t.retired.Load() {
+		t.once.Do(func() { close(t.drained) })
+	}
+}
+
+// drain waits until no call uses t. t must no longer be current.
+func (t *table) drain() {
+	t.retired.Store(true)
+	if t.refs.Load() == 0 {
+		t.once.Do(func() { close(t.drained) })
+	}
+	<-t.drained
+}
+
+// invalidate forgets the symbols resolved in t, which is about to be
+// closed, so that none of their addresses outlives it.
+func (t *table) invalidate() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.syms = nil
+}
+
+func (t *table) close() error {
+	return t.h.Close()
+}
+
+// resolved returns the names of the symbols resolved so far.
+func (t *table) resolved() []string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return slices.Collect(maps.Keys(t.syms))
+}
+
+func (t *table) resolve(names []string) error {
+	for _, name := range names {
+		if _, err := t.sym(name); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (t *table) sym(name string) (unsafe.Pointer, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.syms == nil {
+		return nil, ErrUnloaded
+	}
+	if p, ok := t.syms[name]; ok {
+		return p, nil
+	}
+
+	p, err := t.h.Sym(name)
+	if err != nil {
+		return nil, err
+	}
+	t.syms[name] = p
+	return p, nil
+}
@@ -0,0 +1,38 @@
+//go:build cgo && !windows
+
+package dynload_test
+
+import (
+	"testing"
+
+	"github.com/lxwagn/using-go-with-c-libraries/pkg/cpanic"
+)
+
+// TestCallNPanic panics in a callback of CallN: the calls after it are
+// skipped, the panic comes back as a *cpanic.Panic once myCallN has
+// returned, and the library stays usable.
+func TestCallNPanic(t *testing.T) {
+	l, _ := openPrivate(t)
+	calls := 0
+	func() {
+		defer func() {
+			if p, ok := recover().(*cpanic.Panic); !ok || p.Value != "boom" {
+				t.Errorf("CallN panicked with %v, want boom", p)
+			}
+		}()
+		l.CallN(10, func(v int) int {
+			calls++
+			if v == 2 {
+				panic("boom")
+			}
+			return v
+		})
+		t.Error("CallN returned")
+	}()
+	if calls != 3 {
+		t.Errorf("%d calls, want 3: calls after the panic are skipped", calls)
+	}
+	if got := l.CallN(4, func(v int) int { return v }); got != 6 {
+		t.Errorf("CallN after the panic = %d, want 6", got)
+	}
+}
@@ -0,0 +1,123 @@
+package dynload
+
+/*
+
+#cgo LDFLAGS: -ldl
+#include <dlfcn.h>
+#include <stdio.h>
+
+// dlerror's message is per thread and overwritten by the next dl call, so
+// it is copied out before returning to Go.
+static void *openLib(const char *path, int flags, char *buf, size_t n) {
+	void *h = dlopen(path, flags | RTLD_LOCAL);
+	if (h == NULL)
+		snprintf(buf, n, "%s", dlerror());
+	return h;
+}
+
+static void *lookupSym(void *h, const char *name, char *buf, size_t n) {
+	void *p;
+
+	dlerror();
+	p = dlsym(h, name);
+	if (p == NULL)
+		snprintf(buf, n, "%s", dlerror());
+	return p;
+}
+
+static int closeLib(void *h, char *buf, size_t n) {
+	if (dlclose(h) != 0) {
+		snprintf(buf, n, "%s", dlerror());
+		return -1;
+	}
+	return 0;
+}
+
+*/
+import "C"
+
+import (
+	"errors"
+	"unsafe"
+
+	"github.com/lxwagn/using-go-with-c-libraries/pkg/cmem"
+)
+
+// errBufSize is the size of the buffer dlerror messages are copied into.
+const errBufSize = 512
+
+// A Mode says when OpenHandle resolves a library's function references.
+type Mode int
+
+const (
+	// Lazy resolves each function the first time it is called.
+	Lazy Mode = C.RTLD_LAZY
+
+	// Now resolves them all during OpenHandle, which fails if one is
+	// missing.
+	Now Mode = C.RTLD_NOW
+)
+
+// A Handle is one dlopen of a shared library, whatever it contains. It
+// is opened with RTLD_LOCAL, so that its symbols never replace those of
+// another library, and two libraries defining the same ones can be
+// loaded side by side. A Library holds one for each load of libmylib;
+// pkg/cplugin and the versioned bindings under pkg/mylib use them for
+// libraries of their own.
+//
+// A Handle's methods may be called concurrently, but not with Close.
+type Handle struct {
+	h    unsafe.Pointer
+	path string
+}
+
+// OpenHandle loads the library at path, which like dlopen's argument is
+// looked up on the dynamic linker's search path if it contains no slash.
+// The error is dlerror's message, which names the library.
+func OpenHandle(path string, mode Mode) (*Handle, error) {
+	buf := (*C.char)(cmem.Malloc(errBufSize))
+	defer cmem.Free(unsafe.Pointer(buf))
+
+	cpath := (*C.char)(cmem.CString(path))
+	defer cmem.Free(unsafe.Pointer(cpath))
+
+	h := C.openLib(cpath, C.int(mode), buf, errBufSize)
+	if h == nil {
+		return nil, errors.New(C.GoString(buf))
+	}
+	return &Handle{h: h, path: path}, nil
+}
+
+// Path returns the path the library was opened with.
+func (h *Handle) Path() string {
+	return h.path
+}
+
+// Sym returns the address of the named symbol. Its error is a
+// *SymbolError.
+func (h *Handle) Sym(name string) (unsafe.Pointer, error) {
+	buf := (*C.char)(cmem.Malloc(errBufSize))
+	defer cmem.Free(unsafe.Pointer(buf))
+
+	cname := (*C.char)(cmem.CString(name))
+	defer cmem.Free(unsafe.Pointer(cname))
+
+	p := C.lookupSym(h.h, cname, buf, errBufSize)
+	if p == nil {
+		return nil, &SymbolError{Name: name, Err: C.GoString(buf)}
+	}
+	return p, nil
+}
+
+// Close drops the dlopen reference. The library is unloaded once nothing
+// else in the process holds it, and no address from Sym may be used
+// afterwards.
+func (h *Handle) Close() error {
+	buf := (*C.char)(cmem.Malloc(errBufSize))
+	defer cmem.Free(unsafe.Pointer(buf))
+
+	if C.closeLib(h.h, buf, errBufSize) != 0 {
+		return errors.New("dynload: close " + h.path + ": " + C.GoString(buf))
+	}
+	return nil
+}
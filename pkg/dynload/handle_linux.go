@@ -0,0 +1,46 @@
+package dynload
+
+/*
+
+#define _GNU_SOURCE
+#include <dlfcn.h>
+#include <stdio.h>
+
+static void *lookupVersioned(void *h, const char *name, const char *version, char *buf, size_t n) {
+	void *p;
+
+	dlerror();
+	p = dlvsym(h, name, version);
+	if (p == NULL)
+		snprintf(buf, n, "%s", dlerror());
+	return p;
+}
+
+*/
+import "C"
+
+import (
+	"unsafe"
+
+	"github.com/lxwagn/using-go-with-c-libraries/pkg/cmem"
+)
+
+// VSym returns the address of the named symbol with the given version,
+// with dlvsym, rather than of its default version. Its error is a
+// *SymbolError naming the symbol as name@version. dlvsym is a GNU
+// extension, so VSym is only on Linux.
+func (h *Handle) VSym(name, version string) (unsafe.Pointer, error) {
+	buf := (*C.char)(cmem.Malloc(errBufSize))
+	defer cmem.Free(unsafe.Pointer(buf))
+
+	cname := (*C.char)(cmem.CString(name))
+	defer cmem.Free(unsafe.Pointer(cname))
+	cversion := (*C.char)(cmem.CString(version))
+	defer cmem.Free(unsafe.Pointer(cversion))
+
+	p := C.lookupVersioned(h.h, cname, cversion, buf, errBufSize)
+	if p == nil {
+		return nil, &SymbolError{Name: name + "@" + version, Err: C.GoString(buf)}
+	}
+	return p, nil
+}
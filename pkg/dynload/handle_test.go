@@ -0,0 +1,54 @@
+//go:build cgo && linux
+
+package dynload_test
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+
+	"github.com/lxwagn/using-go-with-c-libraries/pkg/dynload"
+)
+
+// openHandle opens a library of lib/ with OpenHandle, skipping the test
+// if it has not been built.
+func openHandle(t *testing.T, name string) *dynload.Handle {
+	t.Helper()
+	h, err := dynload.OpenHandle(filepath.Join("..", "..", "lib", name), dynload.Now)
+	if err != nil {
+		t.Skipf("library not built: %v", err)
+	}
+	return h
+}
+
+func TestHandle(t *testing.T) {
+	h := openHandle(t, dynload.DefaultName)
+	if p, err := h.Sym("myVersion"); p == nil || err != nil {
+		t.Errorf("Sym(myVersion) = %v, %v", p, err)
+	}
+	var se *dynload.SymbolError
+	if _, err := h.Sym("myNoSuchFunction"); !errors.As(err, &se) || se.Name != "myNoSuchFunction" || se.Err == "" {
+		t.Errorf("Sym(myNoSuchFunction) error = %v, want a SymbolError with dlerror's message", err)
+	}
+	// 1.x has no versioned symbols.
+	if _, err := h.VSym("myVersion", "MYLIB_2.0"); !errors.As(err, &se) || se.Name != "myVersion@MYLIB_2.0" {
+		t.Errorf("VSym(myVersion, MYLIB_2.0) on 1.x error = %v, want a SymbolError", err)
+	}
+	if err := h.Close(); err != nil {
+		t.Error(err)
+	}
+
+	h2 := openHandle(t, "libmylib.so.2")
+	defer h2.Close()
+	if p, err := h2.VSym("myVersion", "MYLIB_2.0"); p == nil || err != nil {
+		t.Errorf("VSym(myVersion, MYLIB_2.0) on 2.0 = %v, %v", p, err)
+	}
+}
+
+func TestOpenHandleError(t *testing.T) {
+	_, err := dynload.OpenHandle(filepath.Join(t.TempDir(), "libmissing.so"), dynload.Lazy)
+	if err == nil {
+		t.Fatal("OpenHandle of a missing file succeeded")
+	}
+	t.Log(err)
+}
@@ -0,0 +1,54 @@
+//go:build cgo && !windows
+
+package dynload_test
+
+import (
+	"errors"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/lxwagn/using-go-with-c-libraries/pkg/dynload"
+	"github.com/lxwagn/using-go-with-c-libraries/pkg/libpath"
+)
+
+// TestOpenEnv checks that a copy installed only in a directory named by
+// $MYLIB_PATH is found there.
+func TestOpenEnv(t *testing.T) {
+	dir := t.TempDir()
+	install(t, dir)
+	t.Setenv(libpath.EnvVar, dir)
+	l, err := dynload.Open(dynload.Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+	if want := filepath.Join(dir, dynload.DefaultName); l.Path() != want {
+		t.Errorf("loaded %s, want %s", l.Path(), want)
+	}
+}
+
+// TestOpenError checks that a library found nowhere is reported with
+// every path tried, dlerror's reason for each, and what to do about it.
+func TestOpenError(t *testing.T) {
+	dir := t.TempDir()
+	_, err := dynload.Open(dynload.Options{Name: "libmylib-missing.so", Paths: []string{dir, ""}})
+	var oerr *dynload.OpenError
+	if !errors.As(err, &oerr) {
+		t.Fatalf("Open: %v, want an OpenError", err)
+	}
+	t.Log(err)
+	want := []string{filepath.Join(dir, "libmylib-missing.so"), "libmylib-missing.so"}
+	if !slices.Equal(oerr.Tried, want) {
+		t.Errorf("tried %q, want %q", oerr.Tried, want)
+	}
+	for i, msg := range oerr.Errs {
+		if !strings.Contains(msg, "libmylib-missing.so") {
+			t.Errorf("message for %s is %q, not dlerror's", oerr.Tried[i], msg)
+		}
+	}
+	if msg := err.Error(); !strings.Contains(msg, libpath.EnvVar) || !strings.Contains(msg, "LD_LIBRARY_PATH") {
+		t.Errorf("error %q does not say how to fix it", msg)
+	}
+}
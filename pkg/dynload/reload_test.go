@@ -0,0 +1,171 @@
+//go:build cgo && !windows
+
+package dynload_test
+
+import (
+	"errors"
+	"hash/adler32"
+	"io"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/lxwagn/using-go-with-c-libraries/pkg/dynload"
+)
+
+// libDir is where make builds libmylib.so.
+var libDir = filepath.Join("..", "..", "lib")
+
+// install copies the built library into dir the way an install step
+// would, by renaming a new file over the old, so the old one's inode
+// stays valid while it is still mapped.
+func install(t *testing.T, dir string) {
+	t.Helper()
+	src, err := os.Open(filepath.Join(libDir, dynload.DefaultName))
+	if err != nil {
+		t.Skipf("library not built: %v", err)
+	}
+	defer src.Close()
+	tmp, err := os.CreateTemp(dir, "install-*")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := io.Copy(tmp, src); err != nil {
+		tmp.Close()
+		t.Fatal(err)
+	}
+	if err := tmp.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Rename(tmp.Name(), filepath.Join(dir, dynload.DefaultName)); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// openPrivate opens a copy of the library of the test's own, which
+// dlclose can actually unload, and returns its directory too. The copy
+// the test binary may link stays loaded for good.
+func openPrivate(t *testing.T) (*dynload.Library, string) {
+	t.Helper()
+	dir := t.TempDir()
+	install(t, dir)
+	l, err := dynload.Open(dynload.Options{Paths: []string{dir}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { l.Close() })
+	return l, dir
+}
+
+// callErr runs f, which calls a Library method that returns no error,
+// and returns the error the method panicked with, if any.
+func callErr(f func()) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			var ok bool
+			if err, ok = r.(error); !ok {
+				panic(r)
+			}
+		}
+	}()
+	f()
+	return nil
+}
+
+// TestReloadFreshState checks that a reloaded library starts with fresh
+// global state, which shows the old copy really was unloaded.
+func TestReloadFreshState(t *testing.T) {
+	l, dir := openPrivate(t)
+	l.CounterAdd(5)
+	install(t, dir)
+	if err := l.Reload(); err != nil {
+		t.Fatal(err)
+	}
+	if n := l.CounterAdd(1); n != 1 {
+		t.Errorf("counter after Reload = %d, want 1", n)
+	}
+}
+
+// TestReloadInFlight races calls with reloads. Reload drains the calls
+// in progress before it unloads their copy, so each one finishes on the
+// copy that was current when it started, and none fails.
+func TestReloadInFlight(t *testing.T) {
+	l, dir := openPrivate(t)
+
+	buf := make([]byte, 1<<16)
+	for i := range buf {
+		buf[i] = byte(i * 7)
+	}
+	want := adler32.Checksum(buf)
+
+	var (
+		stop  atomic.Bool
+		calls atomic.Int64
+		wg    sync.WaitGroup
+	)
+	for range 8 {
+		wg.Go(func() {
+			for !stop.Load() {
+				var sum uint32
+				if err := callErr(func() { sum = l.Checksum(buf) }); err != nil {
+					t.Error(err)
+					return
+				}
+				if sum != want {
+					t.Errorf("checksum %#x, want %#x", sum, want)
+					return
+				}
+				calls.Add(1)
+			}
+		})
+	}
+	reloads := 0
+	for ; reloads < 20 || calls.Load() < 2000; reloads++ {
+		runtime.Gosched()
+		install(t, dir)
+		if err := l.Reload(); err != nil {
+			t.Error(err)
+			break
+		}
+		if t.Failed() {
+			break
+		}
+	}
+	stop.Store(true)
+	wg.Wait()
+	t.Logf("%d calls across %d reloads", calls.Load(), reloads)
+}
+
+// TestReloadFailure checks that calls fail with a failed Reload's error
+// until a later Reload succeeds, and that Reload reports a closed
+// Library.
+func TestReloadFailure(t *testing.T) {
+	l, dir := openPrivate(t)
+
+	if err := os.Remove(filepath.Join(dir, dynload.DefaultName)); err != nil {
+		t.Fatal(err)
+	}
+	rerr := l.Reload()
+	if rerr == nil {
+		t.Fatal("Reload of a missing library succeeded")
+	}
+	if err := callErr(func() { l.CounterAdd(1) }); err == nil || err.Error() != rerr.Error() {
+		t.Errorf("call after a failed Reload: err = %v, want %v", err, rerr)
+	}
+
+	install(t, dir)
+	if err := l.Reload(); err != nil {
+		t.Fatal(err)
+	}
+	if err := callErr(func() { l.CounterAdd(1) }); err != nil {
+		t.Errorf("call after Reload: %v", err)
+	}
+
+	l.Close()
+	if err := l.Reload(); !errors.Is(err, dynload.ErrClosed) {
+		t.Errorf("Reload after Close: err = %v, want ErrClosed", err)
+	}
+}
@@ -0,0 +1,178 @@
+//go:build cgo && !windows
+
+package dynload_test
+
+import (
+	"errors"
+	"hash/adler32"
+	"runtime"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/lxwagn/using-go-with-c-libraries/pkg/dynload"
+)
+
+// TestUnloadInFlight races calls with Unload. Each either finishes on the
+// library or fails with ErrUnloaded, and once one has failed every later
+// one does, until Reload brings the library back with fresh state.
+func TestUnloadInFlight(t *testing.T) {
+	l, _ := openPrivate(t)
+	l.CounterAdd(5)
+
+	buf := make([]byte, 1<<16)
+	want := adler32.Checksum(buf)
+	var (
+		calls atomic.Int64
+		wg    sync.WaitGroup
+	)
+	for range 8 {
+		wg.Go(func() {
+			for {
+				var sum uint32
+				err := callErr(func() { sum = l.Checksum(buf) })
+				if errors.Is(err, dynload.ErrUnloaded) {
+					break
+				}
+				if err != nil {
+					t.Error(err)
+					return
+				}
+				if sum != want {
+					t.Errorf("checksum %#x, want %#x", sum, want)
+					return
+				}
+				calls.Add(1)
+			}
+			if err := callErr(func() { l.Checksum(buf) }); !errors.Is(err, dynload.ErrUnloaded) {
+				t.Errorf("call after ErrUnloaded: err = %v, want ErrUnloaded", err)
+			}
+		})
+	}
+	for calls.Load() < 1000 && !t.Failed() {
+		runtime.Gosched()
+	}
+	if err := l.Unload(); err != nil {
+		t.Fatal(err)
+	}
+	wg.Wait()
+	t.Logf("%d calls before Unload", calls.Load())
+	if err := l.Unload(); err != nil {
+		t.Errorf("second Unload: %v", err)
+	}
+
+	if err := l.Reload(); err != nil {
+		t.Fatal(err)
+	}
+	var n int
+	if err := callErr(func() { n = l.CounterAdd(1) }); err != nil || n != 1 {
+		t.Errorf("counter after Unload and Reload = %d, %v; want 1, nil", n, err)
+	}
+
+	l.Close()
+	if err := l.Unload(); !errors.Is(err, dynload.ErrClosed) {
+		t.Errorf("Unload after Close: err = %v, want ErrClosed", err)
+	}
+}
+
+// blockInCallback starts CallN(3, ...) on l, whose second callback
+// signals entered, waits for proceed and then runs f. CallN's result
+// arrives on the channel returned.
+func blockInCallback(l *dynload.Library, entered, proceed chan struct{}, f func()) <-chan int {
+	done := make(chan int, 1)
+	go func() {
+		done <- l.CallN(3, func(i int) int {
+			if i == 1 {
+				close(entered)
+				<-proceed
+				f()
+			}
+			return i * 10
+		})
+	}()
+	return done
+}
+
+// TestUnloadCallback checks that Unload waits for a call whose callback
+// is still running, and that the callback's own calls into the library
+// fail rather than wait for Unload, which would never return.
+func TestUnloadCallback(t *testing.T) {
+	l, _ := openPrivate(t)
+
+	entered, proceed := make(chan struct{}), make(chan struct{})
+	var nested error
+	done := blockInCallback(l, entered, proceed, func() { _, nested = l.Lookup("one") })
+	<-entered
+	unloaded := make(chan error, 1)
+	go func() { unloaded <- l.Unload() }()
+	select {
+	case err := <-unloaded:
+		close(proceed)
+		t.Fatalf("Unload returned %v while a callback was running", err)
+	case <-time.After(20 * time.Millisecond):
+	}
+	close(proceed)
+
+	if sum := <-done; sum != 30 {
+		t.Errorf("CallN across Unload = %d, want 30", sum)
+	}
+	if !errors.Is(nested, dynload.ErrUnloaded) {
+		t.Errorf("call from a callback during Unload: err = %v, want ErrUnloaded", nested)
+	}
+	select {
+	case err := <-unloaded:
+		if err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Unload did not return after the call finished")
+	}
+	if err := callErr(func() { l.CallN(1, func(int) int { return 0 }) }); !errors.Is(err, dynload.ErrUnloaded) {
+		t.Errorf("CallN after Unload: err = %v, want ErrUnloaded", err)
+	}
+	if err := l.Reload(); err != nil {
+		t.Fatal(err)
+	}
+	if got := l.CallN(3, func(i int) int { return i }); got != 3 {
+		t.Errorf("CallN after Reload = %d, want 3", got)
+	}
+}
+
+// TestReloadDrains checks that Reload waits for a call whose callback is
+// still running before it unloads the library under it, and that calls
+// made afterwards run on the new copy. Unlike Unload's, the callback must
+// not call into l, which would wait for Reload as Reload waits for it.
+func TestReloadDrains(t *testing.T) {
+	l, dir := openPrivate(t)
+	l.CounterAdd(5)
+
+	entered, proceed := make(chan struct{}), make(chan struct{})
+	done := blockInCallback(l, entered, proceed, func() {})
+	<-entered
+	install(t, dir)
+	reloaded := make(chan error, 1)
+	go func() { reloaded <- l.Reload() }()
+	select {
+	case err := <-reloaded:
+		close(proceed)
+		t.Fatalf("Reload returned %v while a callback was running", err)
+	case <-time.After(20 * time.Millisecond):
+	}
+	close(proceed)
+
+	if sum := <-done; sum != 30 {
+		t.Errorf("CallN across Reload = %d, want 30", sum)
+	}
+	select {
+	case err := <-reloaded:
+		if err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Reload did not return after the call finished")
+	}
+	if n := l.CounterAdd(1); n != 1 {
+		t.Errorf("counter after Reload = %d, want 1", n)
+	}
+}
@@ -0,0 +1,260 @@
+// Package features describes the optional parts of libmylib and which of
+// them a loaded copy of the library provides.
+//
+// A program built against the current mylib.h may meet an older
+// libmylib.so at run time. Functions added since then are missing from
+// it, and calling one ends the process with a symbol lookup error, so
+// pkg/mylib probes for them before the first call: Probe looks up each
+// feature's symbols, with dlsym or its equivalent, and asks myVersion for
+// the library's version. The wrappers of a missing feature then return an
+// error matching errors.ErrUnsupported.
+package features
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// A Feature is an optional group of library functions.
+type Feature int
+
+const (
+	WordLists    Feature = iota // mySplitWords and myWordsFree
+	WideStrings                 // myWideCount and myWideReverse
+	SessionOwner                // mySessionOwner
+	Threads                     // myThreadsStart and myThreadsJoin
+	Rings                       // the myRing functions
+	Descriptors                 // myEchoOpen and the myNotify functions
+	Parsers                     // the myParser functions and myParseRecord
+	Events                      // myEmitterStart and myEmitterStop
+	SessionSync                 // mySessionSync
+	Progress                    // myCrunchProgress
+	Allocator                   // mySetAllocator and myAllocations
+	Lifecycle                   // myInit, myShutdown and myInitialized
+	Complex                     // myComplexMul, myComplexScale and myComplexSum
+	Time                        // myTimeFormat, myTimespecAdd and, except on Windows, myTimevalSub
+	Streams                     // myWriteReport and myOpenReport
+	Ownership                   // myJoin, myRepeat and myFree
+	OutParams                   // myParseKeyValue and myBufferFrom
+	Args                        // myParseArgs
+	Sort                        // mySort
+	Async                       // myFillAsync
+	Hash                        // myHash and myHashString
+	Mean                        // myMean
+	ABI                         // myGetABI and myCompiler
+	MulAdd                      // myMulAdd, a __stdcall function on Windows
+	Config                      // myLogLevel, myBufferSize and their lock
+	Wire                        // myWireEncode and myWireDecode
+	Reactors                    // the myReactor functions
+	Coverage                    // myCoverageFlush
+	Messages                    // myMessageNew, myMessageChecksum
+	LogHandler                  // mySetLogHandler and myLogAt
+	numFeatures
+)
+
+var symbols = [numFeatures][]string{
+	WordLists:    {"mySplitWords", "myWordsFree"},
+	WideStrings:  {"myWideCount", "myWideReverse"},
+	SessionOwner: {"mySessionOwner"},
+	Threads:      {"myThreadsStart", "myThreadsJoin"},
+	Rings:        {"myRingOpen", "myRingClose", "myRingUnlink", "myRingPush", "myRingPop"},
+	Descriptors:  {"myEchoOpen", "myNotifyFd", "myNotify", "myNotifyClose"},
+	Parsers:      {"myParserNew", "myParserFree", "myParserJmpbuf", "myParserError", "myParseRecord"},
+	Events:       {"myEmitterStart", "myEmitterStop"},
+	SessionSync:  {"mySessionSync"},
+	Progress:     {"myCrunchProgress"},
+	Allocator:    {"mySetAllocator", "myAllocations"},
+	Lifecycle:    {"myInit", "myShutdown", "myInitialized"},
+	Complex:      {"myComplexMul", "myComplexScale", "myComplexSum"},
+	Time:         timeSymbols,
+	Streams:      {"myWriteReport", "myOpenReport"},
+	Ownership:    {"myJoin", "myRepeat", "myFree"},
+	OutParams:    {"myParseKeyValue", "myBufferFrom"},
+	Args:         {"myParseArgs"},
+	Sort:         {"mySort"},
+	Async:        {"myFillAsync"},
+	Hash:         {"myHash", "myHashString"},
+	Mean:         {"myMean"},
+	ABI:          {"myGetABI", "myCompiler"},
+	MulAdd:       {"myMulAdd"},
+	Config:       {"myLogLevel", "myBufferSize", "myConfigLock", "myConfigUnlock"},
+	Wire:         {"myWireEncode", "myWireDecode"},
+	Reactors:     {"myReactorNew", "myReactorFree", "myReactorConnect", "myReactorReady", "myReactorBytes"},
+	Coverage:     {"myCoverageFlush"},
+	Messages:     {"myMessageNew", "myMessageChecksum"},
+	LogHandler:   {"mySetLogHandler", "myLogAt"},
+}
+
+var names = [numFeatures]string{
+	WordLists:    "word lists",
+	WideStrings:  "wide strings",
+	SessionOwner: "session owner",
+	Threads:      "threads",
+	Rings:        "rings",
+	Descriptors:  "file descriptors",
+	Parsers:      "parsers",
+	Events:       "events",
+	SessionSync:  "session sync",
+	Progress:     "progress",
+	Allocator:    "allocator",
+	Lifecycle:    "lifecycle",
+	Complex:      "complex",
+	Time:         "time",
+	Streams:      "streams",
+	Ownership:    "ownership",
+	OutParams:    "out-parameters",
+	Args:         "arguments",
+	Sort:         "sort",
+	Async:        "async",
+	Hash:         "hash",
+	Mean:         "mean",
+	ABI:          "abi",
+	MulAdd:       "muladd",
+	Config:       "config",
+	Wire:         "wire",
+	Reactors:     "reactors",
+	Coverage:     "coverage",
+	Messages:     "messages",
+	LogHandler:   "log handler",
+}
+
+// All returns every feature, in order.
+func All() []Feature {
+	all := make([]Feature, numFeatures)
+	for i := range all {
+		all[i] = Feature(i)
+	}
+	return all
+}
+
+func (f Feature) String() string {
+	if f < 0 || f >= numFeatures {
+		return fmt.Sprintf("Feature(%d)", int(f))
+	}
+	return names[f]
+}
+
+// Symbols returns the C functions that make up f. The feature is present
+// only if all of them are.
+func (f Feature) Symbols() []string {
+	if f < 0 || f >= numFeatures {
+		return nil
+	}
+	return append([]string(nil), symbols[f]...)
+}
+
+// VersionSymbol is the function reporting the library's version. Builds
+// older than it have no version to report.
+const VersionSymbol = "myVersion"
+
+// A Version is a library version, as reported by myVersion.
+type Version struct {
+	Major, Minor, Patch int
+}
+
+// DecodeVersion splits the number myVersion returns, major*10000 +
+// minor*100 + patch, into its parts.
+func DecodeVersion(n int) Version {
+	return Version{n / 10000, n / 100 % 100, n % 100}
+}
+
+func (v Version) String() string {
+	return fmt.Sprintf("%d.%d.%d", v.Major, v.Minor, v.Patch)
+}
+
+// AtLeast reports whether v is w or newer.
+func (v Version) AtLeast(w Version) bool {
+	if v.Major != w.Major {
+		return v.Major > w.Major
+	}
+	if v.Minor != w.Minor {
+		return v.Minor > w.Minor
+	}
+	return v.Patch >= w.Patch
+}
+
+// A Set records the features and version of one loaded library. The zero
+// Set has no features and no version.
+type Set struct {
+	has        [numFeatures]bool
+	version    Version
+	hasVersion bool
+}
+
+// Probe builds a Set from lookup, which reports whether the library
+// defines a symbol, and version, which calls myVersion. version is only
+// called if lookup finds VersionSymbol.
+func Probe(lookup func(symbol string) bool, version func() int) Set {
+	var s Set
+	for f := range numFeatures {
+		s.has[f] = true
+		for _, sym := range symbols[f] {
+			if !lookup(sym) {
+				s.has[f] = false
+				break
+			}
+		}
+	}
+	if lookup(VersionSymbol) {
+		s.version, s.hasVersion = DecodeVersion(version()), true
+	}
+	return s
+}
+
+// Has reports whether the library provides f.
+func (s Set) Has(f Feature) bool {
+	return f >= 0 && f < numFeatures && s.has[f]
+}
+
+// Version returns the library's version, and false if the library is too
+// old to report one.
+func (s Set) Version() (Version, bool) {
+	return s.version, s.hasVersion
+}
+
+// Require returns nil if the library provides f and an *UnsupportedError
+// otherwise.
+func (s Set) Require(f Feature) error {
+	if s.Has(f) {
+		return nil
+	}
+	return &UnsupportedError{Feature: f}
+}
+
+func (s Set) String() string {
+	var have, lack []string
+	for f := range numFeatures {
+		if s.has[f] {
+			have = append(have, names[f])
+		} else {
+			lack = append(lack, names[f])
+		}
+	}
+	v := "unversioned"
+	if s.hasVersion {
+		v = s.version.String()
+	}
+	str := "libmylib " + v
+	if len(have) > 0 {
+		str += " with " + strings.Join(have, ", ")
+	}
+	if len(lack) > 0 {
+		str += " without " + strings.Join(lack, ", ")
+	}
+	return str
+}
+
+// An UnsupportedError reports a call to a feature the loaded library
+// lacks. It matches errors.ErrUnsupported.
+type UnsupportedError struct {
+	Feature Feature
+}
+
+func (e *UnsupportedError) Error() string {
+	return "mylib: library lacks " + e.Feature.String() + " (" + strings.Join(e.Feature.Symbols(), ", ") + ")"
+}
+
+func (e *UnsupportedError) Is(target error) bool {
+	return target == errors.ErrUnsupported
+}
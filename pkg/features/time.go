@@ -0,0 +1,5 @@
+//go:build !windows
+
+package features
+
+var timeSymbols = []string{"myTimeFormat", "myTimespecAdd", "myTimevalSub"}
@@ -0,0 +1,4 @@
+package features
+
+// The library has no struct timeval, and so no myTimevalSub, on Windows.
+var timeSymbols = []string{"myTimeFormat", "myTimespecAdd"}
@@ -0,0 +1,30 @@
+// Package ffi calls C functions whose signatures are only known at run
+// time, through libffi.
+//
+// cgo needs every C function it calls, and the types it takes, at compile
+// time. A plugin loader that finds functions with dlsym, or a tool that
+// reads prototypes from a header, only learns the signature later, and
+// would otherwise need a hand-written C shim per signature. A Signature
+// describes one at run time, and Call invokes any function pointer with
+// it:
+//
+//	sig, err := ffi.NewSignature(ffi.Double, ffi.Double, ffi.Int)
+//	...
+//	v, err := sig.Call(fn, 1.5, 3) // double fn(double, int)
+//	d := v.(float64)
+//
+// Arguments are converted from the Go values passed, which must suit the
+// parameter types, and the result is returned as the Go type matching
+// the return type (see Type). Functions with a variable number of
+// arguments, such as printf, need a Signature from NewVariadic naming the
+// types of one particular call.
+//
+// Only scalars and pointers can be passed. A pointer argument must point
+// to C memory, or to Go memory pinned for the duration of the call, as
+// when calling through cgo. A string argument is copied into C memory as
+// a NUL-terminated string for the duration of the call, for const char *
+// parameters.
+//
+// The package links against the system's libffi, found with pkg-config,
+// and is not available on Windows.
+package ffi
@@ -0,0 +1,475 @@
+//go:build !windows
+
+package ffi
+
+/*
+
+#cgo pkg-config: libffi
+#cgo linux LDFLAGS: -ldl
+#define _GNU_SOURCE
+#include <dlfcn.h>
+#include <ffi.h>
+
+// Indexed by Type.
+static ffi_type *types[] = {
+	&ffi_type_void,
+	&ffi_type_sint8, &ffi_type_uint8,
+	&ffi_type_sint16, &ffi_type_uint16,
+	&ffi_type_sint32, &ffi_type_uint32,
+	&ffi_type_sint64, &ffi_type_uint64,
+	&ffi_type_sint, &ffi_type_uint,
+	&ffi_type_slong, &ffi_type_ulong,
+	&ffi_type_float, &ffi_type_double,
+	&ffi_type_pointer,
+};
+
+static size_t typeSize(int t) {
+	return types[t]->size;
+}
+
+// prepare fills in cif and its argument type list, kept in the same
+// allocation right after it. nfixed < 0 means a function without
+// variadic arguments.
+static int prepare(ffi_cif *cif, ffi_type **atypes, int ret, const unsigned char *args, int nargs, int nfixed) {
+	int i;
+
+	for (i = 0; i < nargs; i++)
+		atypes[i] = types[args[i]];
+	if (nfixed >= 0)
+		return ffi_prep_cif_var(cif, FFI_DEFAULT_ABI, nfixed, nargs, types[ret], atypes);
+	return ffi_prep_cif(cif, FFI_DEFAULT_ABI, nargs, types[ret], atypes);
+}
+
+static void call(ffi_cif *cif, void *fn, void *ret, void **args) {
+	ffi_call(cif, FFI_FN(fn), ret, args);
+}
+
+static void *lookupDefault(const char *name) {
+	return dlsym(RTLD_DEFAULT, name);
+}
+
+*/
+import "C"
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"runtime"
+	"strings"
+	"unsafe"
+
+	"github.com/lxwagn/using-go-with-c-libraries/pkg/cmem"
+)
+
+// A Type is a C parameter or return type.
+//
+// Results are returned as the Go type of the same size and signedness:
+// int8 through uint64 for the fixed-size types, int32 and uint32 for Int
+// and Uint, int and uint for Long and Ulong (long is pointer-sized on the
+// Unix systems the package supports, as Go's int is), float32, float64,
+// and unsafe.Pointer for Pointer. A Void function returns nil.
+type Type uint8
+
+const (
+	Void Type = iota
+	Int8
+	Uint8
+	Int16
+	Uint16
+	Int32
+	Uint32
+	Int64
+	Uint64
+	Int   // C int
+	Uint  // C unsigned int
+	Long  // C long
+	Ulong // C unsigned long
+	Float
+	Double
+	Pointer
+	numTypes
+)
+
+var typeNames = [numTypes]string{
+	Void:    "void",
+	Int8:    "int8_t",
+	Uint8:   "uint8_t",
+	Int16:   "int16_t",
+	Uint16:  "uint16_t",
+	Int32:   "int32_t",
+	Uint32:  "uint32_t",
+	Int64:   "int64_t",
+	Uint64:  "uint64_t",
+	Int:     "int",
+	Uint:    "unsigned int",
+	Long:    "long",
+	Ulong:   "unsigned long",
+	Float:   "float",
+	Double:  "double",
+	Pointer: "void *",
+}
+
+// String returns the type's name in C.
+func (t Type) String() string {
+	if t >= numTypes {
+		return fmt.Sprintf("Type(%d)", int(t))
+	}
+	return typeNames[t]
+}
+
+// ErrArgs is matched by the errors Call returns for arguments that do not
+// suit the signature.
+var ErrArgs = errors.New("ffi: bad arguments")
+
+// A Signature is a C function type prepared for calls through libffi. It
+// may be used for any number of calls, concurrently.
+type Signature struct {
+	ret   Type
+	args  []Type
+	fixed int // number of fixed parameters of a variadic function, or -1
+
+	cif *C.ffi_cif // in C memory, followed by its argument type list
+}
+
+// NewSignature prepares the signature of a C function returning ret and
+// taking parameters of the types args.
+func NewSignature(ret Type, args ...Type) (*Signature, error) {
+	return newSignature(ret, args, -1)
+}
+
+// NewVariadic prepares the signature of a call to a variadic C function
+// returning ret, whose fixed parameters are of the types fixed, with
+// variadic arguments of the types variadic. The C rules for those apply:
+// a float must be passed as a Double, and integers narrower than int as
+// an Int.
+func NewVariadic(ret Type, fixed []Type, variadic ...Type) (*Signature, error) {
+	return newSignature(ret, append(fixed[:len(fixed):len(fixed)], variadic...), len(fixed))
+}
+
+func newSignature(ret Type, args []Type, fixed int) (*Signature, error) {
+	if ret >= numTypes {
+		return nil, fmt.Errorf("ffi: invalid return type %v", ret)
+	}
+	codes := make([]byte, len(args))
+	for i, t := range args {
+		if t >= numTypes || t == Void {
+			return nil, fmt.Errorf("ffi: invalid type %v for parameter %d", t, i)
+		}
+		codes[i] = byte(t)
+	}
+	s := &Signature{ret: ret, args: args, fixed: fixed}
+
+	size := int(unsafe.Sizeof(C.ffi_cif{})) + len(args)*int(unsafe.Sizeof((*C.ffi_type)(nil)))
+	mem := cmem.Calloc(1, size)
+	s.cif = (*C.ffi_cif)(mem)
+	atypes := (**C.ffi_type)(unsafe.Add(mem, unsafe.Sizeof(C.ffi_cif{})))
+
+	status := C.prepare(s.cif, atypes, C.int(ret),
+		(*C.uchar)(unsafe.SliceData(codes)), C.int(len(args)), C.int(fixed))
+	if status != C.FFI_OK {
+		cmem.Free(mem)
+		return nil, fmt.Errorf("ffi: cannot prepare %v: %s", s, statusText(status))
+	}
+	runtime.AddCleanup(s, cmem.Free, mem)
+	return s, nil
+}
+
+func statusText(status C.int) string {
+	switch status {
+	case C.FFI_BAD_TYPEDEF:
+		return "bad type"
+	case C.FFI_BAD_ABI:
+		return "bad ABI"
+	case C.FFI_BAD_ARGTYPE:
+		return "argument type not allowed here"
+	}
+	return fmt.Sprintf("status %d", int(status))
+}
+
+// Return returns the return type.
+func (s *Signature) Return() Type { return s.ret }
+
+// Args returns the parameter types, variadic ones included.
+func (s *Signature) Args() []Type { return append([]Type(nil), s.args...) }
+
+// String returns the signature as a C function type, such as
+// "int (void *, ...)" for a variadic function.
+func (s *Signature) String() string {
+	var b strings.Builder
+	b.WriteString(s.ret.String())
+	b.WriteString(" (")
+	n := len(s.args)
+	if s.fixed >= 0 {
+		n = s.fixed
+	}
+	for i, t := range s.args[:n] {
+		if i > 0 {
+			b.WriteString(", ")
+		}
+		b.WriteString(t.String())
+	}
+	switch {
+	case s.fixed >= 0 && n > 0:
+		b.WriteString(", ...")
+	case s.fixed >= 0:
+		b.WriteString("...")
+	case n == 0:
+		b.WriteString("void")
+	}
+	b.WriteString(")")
+	return b.String()
+}
+
+// slotSize is the room each argument, and the result, gets: enough for
+// any Type, and for the ffi_arg libffi widens small integer results to.
+const slotSize = 8
+
+// Call calls the C function at fn, which must have the signature s, with
+// args and returns its result. fn is typically from dlsym. Calling a
+// function through the wrong signature is undefined behaviour, as in C;
+// what Call does check is that the arguments suit the signature.
+func (s *Signature) Call(fn unsafe.Pointer, args ...any) (any, error) {
+	if fn == nil {
+		return nil, fmt.Errorf("ffi: call of nil function")
+	}
+	if len(args) != len(s.args) {
+		return nil, fmt.Errorf("%w: %v takes %d arguments, got %d", ErrArgs, s, len(s.args), len(args))
+	}
+
+	// The result, then one slot per argument, then the argument pointer
+	// array libffi reads, all in one allocation.
+	n := len(args)
+	mem := cmem.Calloc(1, slotSize*(1+n)+n*int(unsafe.Sizeof(uintptr(0))))
+	defer cmem.Free(mem)
+	ret := mem
+	slots := unsafe.Add(mem, slotSize)
+	avalues := unsafe.Slice((*unsafe.Pointer)(unsafe.Add(slots, slotSize*n)), n)
+
+	var strs []unsafe.Pointer
+	defer func() {
+		for _, p := range strs {
+			cmem.Free(p)
+		}
+	}()
+	for i, arg := range args {
+		slot := unsafe.Add(slots, slotSize*i)
+		if str, ok := arg.(string); ok && s.args[i] == Pointer {
+			if strings.IndexByte(str, 0) >= 0 {
+				return nil, fmt.Errorf("%w: argument %d: string contains NUL byte", ErrArgs, i)
+			}
+			p := cmem.CString(str)
+			strs = append(strs, p)
+			arg = p
+		}
+		if err := store(slot, s.args[i], arg); err != nil {
+			return nil, fmt.Errorf("%w: argument %d: %v", ErrArgs, i, err)
+		}
+		avalues[i] = slot
+	}
+
+	C.call(s.cif, fn, ret, unsafe.SliceData(avalues))
+	return load(ret, s.ret), nil
+}
+
+// store writes v to slot as a t.
+func store(slot unsafe.Pointer, t Type, v any) error {
+	switch t {
+	case Float:
+		f, ok := toFloat(v)
+		if !ok {
+			return fmt.Errorf("%T is not a float", v)
+		}
+		*(*float32)(slot) = float32(f)
+		return nil
+	case Double:
+		f, ok := toFloat(v)
+		if !ok {
+			return fmt.Errorf("%T is not a float", v)
+		}
+		*(*float64)(slot) = f
+		return nil
+	case Pointer:
+		switch p := v.(type) {
+		case nil:
+			*(*unsafe.Pointer)(slot) = nil
+		case unsafe.Pointer:
+			*(*unsafe.Pointer)(slot) = p
+		case uintptr:
+			*(*uintptr)(slot) = p
+		default:
+			return fmt.Errorf("%T is not a pointer", v)
+		}
+		return nil
+	}
+
+	size := uintptr(C.typeSize(C.int(t)))
+	if signed(t) {
+		n, ok := toInt(v)
+		if !ok {
+			return fmt.Errorf("%T is not an integer", v)
+		}
+		if bits := size * 8; bits < 64 && (n < -1<<(bits-1) || n >= 1<<(bits-1)) {
+			return fmt.Errorf("%d overflows %v", n, t)
+		}
+		switch size {
+		case 1:
+			*(*int8)(slot) = int8(n)
+		case 2:
+			*(*int16)(slot) = int16(n)
+		case 4:
+			*(*int32)(slot) = int32(n)
+		default:
+			*(*int64)(slot) = n
+		}
+		return nil
+	}
+	n, ok := toUint(v)
+	if !ok {
+		return fmt.Errorf("%T is not a non-negative integer", v)
+	}
+	if bits := size * 8; bits < 64 && n >= 1<<bits {
+		return fmt.Errorf("%d overflows %v", n, t)
+	}
+	switch size {
+	case 1:
+		*(*uint8)(slot) = uint8(n)
+	case 2:
+		*(*uint16)(slot) = uint16(n)
+	case 4:
+		*(*uint32)(slot) = uint32(n)
+	default:
+		*(*uint64)(slot) = n
+	}
+	return nil
+}
+
+// load reads a result of type t. libffi stores integer results narrower
+// than ffi_arg widened to it, so they are read at that width and then
+// narrowed.
+func load(ret unsafe.Pointer, t Type) any {
+	switch t {
+	case Void:
+		return nil
+	case Float:
+		return *(*float32)(ret)
+	case Double:
+		return *(*float64)(ret)
+	case Pointer:
+		return *(*unsafe.Pointer)(ret)
+	}
+	if signed(t) {
+		n := int64(*(*C.ffi_sarg)(ret))
+		switch t {
+		case Int8:
+			return int8(n)
+		case Int16:
+			return int16(n)
+		case Int32:
+			return int32(n)
+		case Int:
+			return int32(n)
+		case Long:
+			return int(n)
+		}
+		return n
+	}
+	n := uint64(*(*C.ffi_arg)(ret))
+	switch t {
+	case Uint8:
+		return uint8(n)
+	case Uint16:
+		return uint16(n)
+	case Uint32:
+		return uint32(n)
+	case Uint:
+		return uint32(n)
+	case Ulong:
+		return uint(n)
+	}
+	return n
+}
+
+func signed(t Type) bool {
+	switch t {
+	case Int8, Int16, Int32, Int64, Int, Long:
+		return true
+	}
+	return false
+}
+
+func toInt(v any) (int64, bool) {
+	switch n := v.(type) {
+	case int:
+		return int64(n), true
+	case int8:
+		return int64(n), true
+	case int16:
+		return int64(n), true
+	case int32:
+		return int64(n), true
+	case int64:
+		return n, true
+	case uint8:
+		return int64(n), true
+	case uint16:
+		return int64(n), true
+	case uint32:
+		return int64(n), true
+	case uint, uint64, uintptr:
+		u, _ := toUint(n)
+		if u > math.MaxInt64 {
+			return 0, false
+		}
+		return int64(u), true
+	}
+	return 0, false
+}
+
+func toUint(v any) (uint64, bool) {
+	switch n := v.(type) {
+	case uint:
+		return uint64(n), true
+	case uint8:
+		return uint64(n), true
+	case uint16:
+		return uint64(n), true
+	case uint32:
+		return uint64(n), true
+	case uint64:
+		return n, true
+	case uintptr:
+		return uint64(n), true
+	}
+	if n, ok := toInt(v); ok && n >= 0 {
+		return uint64(n), true
+	}
+	return 0, false
+}
+
+func toFloat(v any) (float64, bool) {
+	switch f := v.(type) {
+	case float32:
+		return float64(f), true
+	case float64:
+		return f, true
+	}
+	if n, ok := toInt(v); ok {
+		return float64(n), true
+	}
+	return 0, false
+}
+
+// Lookup returns the address of the function name in the program or one
+// of the shared libraries it has loaded, as dlsym(RTLD_DEFAULT, name)
+// finds it.
+func Lookup(name string) (unsafe.Pointer, error) {
+	cname := (*C.char)(cmem.CString(name))
+	defer cmem.Free(unsafe.Pointer(cname))
+
+	p := C.lookupDefault(cname)
+	if p == nil {
+		return nil, fmt.Errorf("ffi: symbol %s not found", name)
+	}
+	return p, nil
+}
@@ -0,0 +1,105 @@
+// Package handles maps Go values to integers that C can hold, like
+// runtime/cgo.Handle but typed.
+//
+// The cgo pointer rules forbid C from keeping a Go pointer, so a Go value
+// that C passes back to Go later (typically as a void *userdata argument
+// to a callback) has to be replaced by a handle: an integer that Go maps
+// back to the value. A Handle[T] remembers the type of that value, so
+// getting it back needs no type assertion at the call site.
+//
+// Every handle must be deleted once C can no longer use it, or the value
+// it refers to is never collected. Live reports how many handles are
+// outstanding, which lets tests check for leaks.
+//
+// The table is kept here rather than in runtime/cgo, which a program
+// built with CGO_ENABLED=0 cannot link alongside purego: pkg/mylib's
+// nocgo build passes its callbacks through this package too.
+package handles
+
+import (
+	"fmt"
+	"sync"
+	"sync/atomic"
+)
+
+var (
+	live   atomic.Int64
+	next   atomic.Uintptr
+	values sync.Map // uintptr to the value, as in runtime/cgo
+)
+
+// A Handle refers to a Go value of type T that can be passed through C.
+// The zero Handle is invalid.
+type Handle[T any] struct {
+	h uintptr
+}
+
+// New returns a handle for v.
+func New[T any](v T) Handle[T] {
+	h := next.Add(1)
+	values.Store(h, v)
+	live.Add(1)
+	return Handle[T]{h}
+}
+
+// FromUintptr converts a value previously returned by Handle.Uintptr back
+// into a Handle. It is typically called on the userdata argument of a
+// callback.
+func FromUintptr[T any](u uintptr) Handle[T] {
+	return Handle[T]{u}
+}
+
+// Uintptr returns the integer to hand to C, usually cast to void * on the
+// C side.
+func (h Handle[T]) Uintptr() uintptr {
+	return h.h
+}
+
+func (h Handle[T]) value() any {
+	v, ok := values.Load(h.h)
+	if !ok {
+		panic(fmt.Sprintf("handles: invalid handle %d", h.h))
+	}
+	return v
+}
+
+// Value returns the value the handle refers to. It panics if the handle
+// is invalid or has been deleted, like cgo.Handle.Value.
+func (h Handle[T]) Value() T {
+	return h.value().(T)
+}
+
+// Get is like Value but reports an error instead of panicking if the
+// handle is invalid, has been deleted, or refers to a value of another
+// type. It is the safer choice inside callbacks, where a panic would have
+// to unwind through C frames.
+func (h Handle[T]) Get() (v T, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("handles: invalid handle %d", h.h)
+		}
+	}()
+
+	x := h.value()
+	v, ok := x.(T)
+	if !ok {
+		return v, fmt.Errorf("handles: handle %d refers to %T, not %T", h.h, x, v)
+	}
+	return v, nil
+}
+
+// Delete invalidates the handle and lets the value be collected. It must
+// be called exactly once, after C is done with the handle, and panics
+// if the handle is invalid, like cgo.Handle.Delete.
+func (h Handle[T]) Delete() {
+	if _, ok := values.LoadAndDelete(h.h); !ok {
+		panic(fmt.Sprintf("handles: invalid handle %d", h.h))
+	}
+	live.Add(-1)
+}
+
+// Live returns the number of handles created by New that have not been
+// deleted yet.
+func Live() int {
+	return int(live.Load())
+}
@@ -0,0 +1,56 @@
+package handles
+
+import (
+	"sync"
+	"testing"
+)
+
+func TestLive(t *testing.T) {
+	before := Live()
+	hs := make([]Handle[*int], 100)
+	for round := range 10 {
+		for i := range hs {
+			v := round*len(hs) + i
+			hs[i] = New(&v)
+		}
+		if got, want := Live(), before+len(hs); got != want {
+			t.Fatalf("round %d: Live() = %d with %d handles made, want %d", round, got, len(hs), want)
+		}
+		for i, h := range hs {
+			if v, err := FromUintptr[*int](h.Uintptr()).Get(); err != nil || *v != round*len(hs)+i {
+				t.Fatalf("round %d: handle %d: Get() = %v, %v", round, i, v, err)
+			}
+			h.Delete()
+		}
+		if got := Live(); got != before {
+			t.Fatalf("round %d: Live() = %d after deleting every handle, want %d", round, got, before)
+		}
+	}
+}
+
+func TestLiveConcurrent(t *testing.T) {
+	before := Live()
+	var wg sync.WaitGroup
+	for range 16 {
+		wg.Go(func() {
+			for i := range 1000 {
+				New(i).Delete()
+			}
+		})
+	}
+	wg.Wait()
+	if got := Live(); got != before {
+		t.Errorf("Live() = %d, want %d", got, before)
+	}
+}
+
+func TestGet(t *testing.T) {
+	h := New("value")
+	if _, err := FromUintptr[int](h.Uintptr()).Get(); err == nil {
+		t.Error("Get of a string handle as int succeeded")
+	}
+	h.Delete()
+	if _, err := h.Get(); err == nil {
+		t.Error("Get after Delete succeeded")
+	}
+}
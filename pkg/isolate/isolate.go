@@ -0,0 +1,335 @@
+//go:build !windows
+
+// Package isolate runs C calls that may crash the process in a worker
+// subprocess, so that an abort, a failed assert or a segmentation fault
+// in C surfaces as an error in the caller instead of taking it down.
+//
+// Go cannot fork a process and keep running Go in the child: only the
+// forking thread survives, without the runtime's other threads. The
+// worker is therefore a fresh copy of the same program, started with an
+// environment variable that sends it into Main's serving loop before it
+// does anything else. Functions registered with Register in both copies
+// are called by name, with their argument and result gob-encoded over a
+// pair of pipes. Every program that uses a Worker must call Main before
+// it does anything else, first thing in main:
+//
+//	func main() {
+//		isolate.Main()
+//		...
+//	}
+//
+// or from an init function of package main, which runs after those of
+// every package it imports, and so after their calls to Register.
+//
+// A Worker runs one call at a time. When the worker dies during a call,
+// Call returns a *CrashError with the signal or exit status and the start
+// of what the worker wrote to stderr: for a failed assert, the assertion,
+// followed by the Go runtime's report of the signal. The next call starts
+// a new worker. Each call costs two pipe round trips and the encoding,
+// some tens of microseconds, so isolation suits calls that are risky
+// rather than frequent.
+package isolate
+
+import (
+	"bytes"
+	"context"
+	"encoding/gob"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"runtime/debug"
+	"sync"
+)
+
+// envVar is set in the environment of a worker.
+const envVar = "GO_ISOLATE_WORKER"
+
+// stderrHead is how much of a worker's stderr a CrashError keeps.
+const stderrHead = 4 << 10
+
+// ErrClosed is returned by Call once the Worker is closed.
+var ErrClosed = errors.New("isolate: worker is closed")
+
+// A CrashError reports that the worker died during a call.
+type CrashError struct {
+	Name   string           // the function called
+	State  *os.ProcessState // how the worker ended
+	Stderr string           // the start of its stderr
+}
+
+func (e *CrashError) Error() string {
+	msg := fmt.Sprintf("isolate: worker died in %s: %v", e.Name, e.State)
+	if e.Stderr != "" {
+		msg += "\n" + e.Stderr
+	}
+	return msg
+}
+
+// A RemoteError is an error returned by a function in the worker. Only
+// its text crosses the pipe, so errors.Is does not match it against the
+// original error.
+type RemoteError struct {
+	Name string
+	Msg  string
+}
+
+func (e *RemoteError) Error() string {
+	return e.Name + ": " + e.Msg
+}
+
+type request struct {
+	Name string
+	Arg  []byte
+}
+
+type response struct {
+	Result []byte
+	Err    string
+}
+
+var funcs = map[string]func(arg []byte) ([]byte, error){}
+
+// Register makes f callable by name through Call. It must be called the
+// same way in the parent and the worker, typically from an init function.
+func Register[Req, Resp any](name string, f func(Req) (Resp, error)) {
+	if _, dup := funcs[name]; dup {
+		panic("isolate: " + name + " registered twice")
+	}
+	funcs[name] = func(arg []byte) ([]byte, error) {
+		var req Req
+		if err := gob.NewDecoder(bytes.NewReader(arg)).Decode(&req); err != nil {
+			return nil, err
+		}
+		resp, err := f(req)
+		if err != nil {
+			return nil, err
+		}
+		var b bytes.Buffer
+		if err := gob.NewEncoder(&b).Encode(resp); err != nil {
+			return nil, err
+		}
+		return b.Bytes(), nil
+	}
+}
+
+// Main serves calls and exits if the program was started as a worker, and
+// returns at once otherwise.
+func Main() {
+	if os.Getenv(envVar) == "" {
+		return
+	}
+	// The runtime catches a signal raised in C, such as abort's SIGABRT,
+	// and exits with status 2. With "crash" it dies of the signal after
+	// its report instead, so that CrashError can say which it was.
+	debug.SetTraceback("crash")
+
+	// The requests arrive on descriptor 3 and the responses leave on 4,
+	// so that what the C code prints to stdout does not mix with them.
+	in := gob.NewDecoder(os.NewFile(3, "requests"))
+	out := gob.NewEncoder(os.NewFile(4, "responses"))
+	for {
+		var req request
+		if err := in.Decode(&req); err != nil {
+			if err == io.EOF {
+				os.Exit(0)
+			}
+			fmt.Fprintln(os.Stderr, "isolate:", err)
+			os.Exit(2)
+		}
+		var resp response
+		if f, ok := funcs[req.Name]; !ok {
+			resp.Err = "no such function"
+		} else if result, err := f(req.Arg); err != nil {
+			resp.Err = err.Error()
+		} else {
+			resp.Result = result
+		}
+		if err := out.Encode(&resp); err != nil {
+			fmt.Fprintln(os.Stderr, "isolate:", err)
+			os.Exit(2)
+		}
+	}
+}
+
+// A Worker is a subprocess running registered functions. It is safe for
+// concurrent use; calls are made one at a time.
+type Worker struct {
+	mu     sync.Mutex
+	closed bool
+	proc   *process // nil until the first call and after a crash
+}
+
+// A process is one run of the worker.
+type process struct {
+	cmd    *exec.Cmd
+	req    *os.File // the write end of the requests pipe
+	resp   *os.File // the read end of the responses pipe
+	enc    *gob.Encoder
+	dec    *gob.Decoder
+	stderr *head
+}
+
+// NewWorker returns a Worker. Its subprocess is started by the first
+// call.
+func NewWorker() *Worker {
+	return &Worker{}
+}
+
+// Call runs the function registered as name in the worker with req and
+// returns its result. If ctx is done before the call returns, the worker
+// is killed and Call returns ctx's error.
+func Call[Req, Resp any](ctx context.Context, w *Worker, name string, req Req) (Resp, error) {
+	var resp Resp
+	var arg bytes.Buffer
+	if err := gob.NewEncoder(&arg).Encode(req); err != nil {
+		return resp, err
+	}
+	result, err := w.call(ctx, name, arg.Bytes())
+	if err != nil {
+		return resp, err
+	}
+	err = gob.NewDecoder(bytes.NewReader(result)).Decode(&resp)
+	return resp, err
+}
+
+func (w *Worker) call(ctx context.Context, name string, arg []byte) ([]byte, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.closed {
+		return nil, ErrClosed
+	}
+	if w.proc == nil {
+		p, err := start()
+		if err != nil {
+			return nil, err
+		}
+		w.proc = p
+	}
+	p := w.proc
+
+	stop := context.AfterFunc(ctx, func() { p.cmd.Process.Kill() })
+	var resp response
+	err := p.enc.Encode(&request{Name: name, Arg: arg})
+	if err == nil {
+		err = p.dec.Decode(&resp)
+	}
+	if !stop() && err == nil {
+		// ctx was done just as the call returned, and the worker is
+		// being killed.
+		err = ctx.Err()
+	}
+	if err != nil {
+		// The worker is gone, or its pipes are no use after a partial
+		// message. Wait reports how it ended.
+		w.proc = nil
+		p.cmd.Process.Kill()
+		p.wait()
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		return nil, &CrashError{Name: name, State: p.cmd.ProcessState, Stderr: p.stderr.String()}
+	}
+	if resp.Err != "" {
+		return nil, &RemoteError{Name: name, Msg: resp.Err}
+	}
+	return resp.Result, nil
+}
+
+func start() (*process, error) {
+	exe, err := os.Executable()
+	if err != nil {
+		return nil, err
+	}
+	reqR, reqW, err := os.Pipe()
+	if err != nil {
+		return nil, err
+	}
+	respR, respW, err := os.Pipe()
+	if err != nil {
+		reqR.Close()
+		reqW.Close()
+		return nil, err
+	}
+
+	p := &process{
+		cmd:    exec.Command(exe),
+		req:    reqW,
+		resp:   respR,
+		enc:    gob.NewEncoder(reqW),
+		dec:    gob.NewDecoder(respR),
+		stderr: &head{max: stderrHead},
+	}
+	p.cmd.Env = append(os.Environ(), envVar+"=1")
+	p.cmd.Stdout = os.Stdout
+	p.cmd.Stderr = p.stderr
+	p.cmd.ExtraFiles = []*os.File{reqR, respW}
+	err = p.cmd.Start()
+	// The worker has its own copies now. The parent's would keep the
+	// pipes open after the worker dies, where without them the worker's
+	// exit ends Decode with EOF.
+	reqR.Close()
+	respW.Close()
+	if err != nil {
+		reqW.Close()
+		respR.Close()
+		return nil, err
+	}
+	return p, nil
+}
+
+// wait closes the pipes and waits for the worker to exit.
+func (p *process) wait() error {
+	p.req.Close()
+	err := p.cmd.Wait()
+	p.resp.Close()
+	return err
+}
+
+// Close stops the worker. Calls made afterwards return ErrClosed.
+func (w *Worker) Close() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	w.closed = true
+	p := w.proc
+	if p == nil {
+		return nil
+	}
+	w.proc = nil
+	// Closing the requests makes the worker's Main see EOF and exit.
+	err := p.wait()
+	var exit *exec.ExitError
+	if errors.As(err, &exit) {
+		return &CrashError{Name: "Close", State: exit.ProcessState, Stderr: p.stderr.String()}
+	}
+	return err
+}
+
+// A head keeps the first max bytes written to it.
+type head struct {
+	mu  sync.Mutex
+	max int
+	b   []byte
+}
+
+func (h *head) Write(p []byte) (int, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if n := h.max - len(h.b); n > 0 {
+		h.b = append(h.b, p[:min(n, len(p))]...)
+	}
+	return len(p), nil
+}
+
+func (h *head) String() string {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return string(bytes.TrimSpace(h.b))
+}
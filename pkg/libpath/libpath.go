@@ -0,0 +1,161 @@
+// Package libpath decides where libmylib is looked for when it is loaded
+// at run time, and explains what went wrong when it is not found.
+//
+// A program linked against the library relies on the dynamic linker,
+// which finds it through the rpath recorded at link time or through
+// LD_LIBRARY_PATH, and refuses to start otherwise. A path like ./lib in
+// the rpath or in a loader's search list depends on the directory the
+// program is started from. The loaders in pkg/dynload and in pkg/mylib's
+// nocgo and Windows builds search Dirs instead, which do not:
+//
+//  1. each directory in $MYLIB_PATH, separated like PATH entries;
+//  2. the executable's directory, and lib next to it (../lib);
+//  3. $XDG_DATA_HOME/mylib/lib, by default ~/.local/share/mylib/lib;
+//  4. the dynamic linker's own search path;
+//  5. mylib/lib under each directory in $XDG_DATA_DIRS, by default
+//     /usr/local/share and /usr/share.
+//
+// Windows has no XDG directories, so steps 3 and 5 are skipped there. Its
+// DLL search order already starts with the executable's directory.
+//
+// When every path fails, Search returns an *Error listing each path tried
+// with the loader's message for it, followed by what to do about it.
+package libpath
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"strings"
+)
+
+// EnvVar names the environment variable whose directories are searched
+// first.
+const EnvVar = "MYLIB_PATH"
+
+// Dirs returns the directories to search, in order, without duplicates.
+// The empty string stands for the dynamic linker's own search path
+// (LD_LIBRARY_PATH, the ld.so cache, /usr/lib and so on, or the DLL
+// search order on Windows). The environment is read on every call.
+func Dirs() []string {
+	var dirs []string
+	for _, dir := range filepath.SplitList(os.Getenv(EnvVar)) {
+		if dir != "" {
+			dirs = append(dirs, dir)
+		}
+	}
+	if exe, err := os.Executable(); err == nil {
+		dir := filepath.Dir(exe)
+		dirs = append(dirs, dir, filepath.Join(dir, "..", "lib"))
+	}
+	if runtime.GOOS != "windows" {
+		if home := dataHome(); home != "" {
+			dirs = append(dirs, filepath.Join(home, "mylib", "lib"))
+		}
+	}
+	dirs = append(dirs, "")
+	if runtime.GOOS != "windows" {
+		for _, dir := range dataDirs() {
+			dirs = append(dirs, filepath.Join(dir, "mylib", "lib"))
+		}
+	}
+
+	var out []string
+	for _, dir := range dirs {
+		if dir != "" {
+			dir = filepath.Clean(dir)
+		}
+		if !slices.Contains(out, dir) {
+			out = append(out, dir)
+		}
+	}
+	return out
+}
+
+// dataHome returns $XDG_DATA_HOME, or its default when it is unset or
+// not absolute, as the XDG Base Directory Specification requires.
+func dataHome() string {
+	if dir := os.Getenv("XDG_DATA_HOME"); filepath.IsAbs(dir) {
+		return dir
+	}
+	if home, err := os.UserHomeDir(); err == nil {
+		return filepath.Join(home, ".local", "share")
+	}
+	return ""
+}
+
+// dataDirs returns the absolute directories in $XDG_DATA_DIRS, or their
+// default.
+func dataDirs() []string {
+	var dirs []string
+	for _, dir := range filepath.SplitList(os.Getenv("XDG_DATA_DIRS")) {
+		if filepath.IsAbs(dir) {
+			dirs = append(dirs, dir)
+		}
+	}
+	if len(dirs) == 0 {
+		dirs = []string{"/usr/local/share", "/usr/share"}
+	}
+	return dirs
+}
+
+// Search tries name in each of dirs, or in Dirs if dirs is empty, by
+// calling open with the path, until one succeeds. It returns that path,
+// or an *Error if open failed for all of them. A directory of "" passes
+// name alone, for the dynamic linker to search for.
+func Search(name string, dirs []string, open func(path string) error) (string, error) {
+	if len(dirs) == 0 {
+		dirs = Dirs()
+	}
+	e := &Error{Name: name}
+	for _, dir := range dirs {
+		path := name
+		if dir != "" {
+			path = filepath.Join(dir, name)
+		}
+		err := open(path)
+		if err == nil {
+			return path, nil
+		}
+		e.Tried = append(e.Tried, path)
+		e.Errs = append(e.Errs, err.Error())
+	}
+	return "", e
+}
+
+// An Error reports that a library could not be loaded from any of the
+// paths searched.
+type Error struct {
+	Name  string
+	Tried []string // the paths passed to the loader
+	Errs  []string // the loader's message for each path, such as dlerror's
+}
+
+func (e *Error) Error() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "cannot load %s; tried:", e.Name)
+	for i, p := range e.Tried {
+		// dlerror's messages start with the path themselves.
+		msg := strings.TrimPrefix(e.Errs[i], p+": ")
+		if p == e.Name {
+			p += " (" + linkerPath() + ")"
+		}
+		fmt.Fprintf(&b, "\n\t%s: %s", p, msg)
+	}
+	fmt.Fprintf(&b, "\nset %s to the directory containing %s, or install it in one of the directories above", EnvVar, e.Name)
+	return b.String()
+}
+
+// linkerPath describes where the platform's loader looks for a library
+// given by name alone.
+func linkerPath() string {
+	switch runtime.GOOS {
+	case "windows":
+		return "DLL search order, including PATH"
+	case "darwin", "ios":
+		return "dyld search path, including DYLD_LIBRARY_PATH"
+	}
+	return "dynamic linker search path, including LD_LIBRARY_PATH"
+}
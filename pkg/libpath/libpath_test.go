@@ -0,0 +1,38 @@
+//go:build !windows
+
+package libpath_test
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"github.com/lxwagn/using-go-with-c-libraries/pkg/libpath"
+)
+
+// TestDirs checks that $MYLIB_PATH comes first and the working directory
+// not at all, so that where the program is started from makes no
+// difference.
+func TestDirs(t *testing.T) {
+	t.Setenv(libpath.EnvVar, "/opt/a"+string(os.PathListSeparator)+"/opt/b")
+	dirs := libpath.Dirs()
+	t.Logf("search order: %q", dirs)
+	if len(dirs) < 2 || dirs[0] != "/opt/a" || dirs[1] != "/opt/b" {
+		t.Fatalf("Dirs() = %q, want /opt/a and /opt/b first", dirs)
+	}
+	exe, err := os.Executable()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{filepath.Dir(exe), filepath.Join(filepath.Dir(exe), "..", "lib"), ""} {
+		if !slices.Contains(dirs, want) {
+			t.Errorf("Dirs() = %q, missing %q", dirs, want)
+		}
+	}
+	for _, dir := range dirs {
+		if dir != "" && !filepath.IsAbs(dir) {
+			t.Errorf("Dirs() has relative directory %q", dir)
+		}
+	}
+}
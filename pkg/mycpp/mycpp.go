@@ -0,0 +1,160 @@
+// Package mycpp wraps an example C++ library, src/mycpp.
+//
+// Go cannot call C++ directly: there is no stable ABI for classes,
+// std::string or exceptions. The library exports a flat C interface,
+// mycpp.h, through extern "C" functions in shim.cpp, and this package
+// calls that like any other C library. The shim catches every exception
+// and returns a status code instead, which is essential: a C++ exception
+// unwinding into Go frames crashes the program.
+package mycpp
+
+/*
+#cgo pkg-config: mycpp
+#include <stdlib.h>
+#include "mycpp.h"
+*/
+import "C"
+
+import (
+	"errors"
+	"fmt"
+	"runtime"
+	"strings"
+	"sync"
+	"unsafe"
+
+	"github.com/lxwagn/using-go-with-c-libraries/pkg/cerr"
+	"github.com/lxwagn/using-go-with-c-libraries/pkg/cmem"
+)
+
+var codes = cerr.NewTable("mycpp")
+
+// Errors for the exceptions the library throws. The errors returned by
+// Inventory's methods also carry the exception's message; check for them
+// with errors.Is.
+var (
+	ErrNotFound = codes.Register(C.MYCPP_ENOTFOUND, errors.New("mycpp: not found"))
+	ErrInvalid  = codes.Register(C.MYCPP_EINVAL, errors.New("mycpp: invalid argument"))
+	ErrRange    = codes.Register(C.MYCPP_ERANGE, errors.New("mycpp: out of range"))
+	ErrNoMemory = codes.Register(C.MYCPP_ENOMEM, errors.New("mycpp: out of memory"))
+	ErrUnknown  = codes.Register(C.MYCPP_EUNKNOWN, errors.New("mycpp: unexpected exception"))
+)
+
+// ErrNUL is returned when a name contains a NUL byte.
+var ErrNUL = errors.New("mycpp: string contains NUL byte")
+
+// ErrClosed is returned when a method is called on a closed Inventory.
+var ErrClosed = errors.New("mycpp: use of closed object")
+
+// An Inventory counts the stock of named items. It wraps a C++
+// mycpp::Inventory object.
+//
+// Call Close when done with an Inventory; as with mylib.Buffer, a runtime
+// cleanup frees it eventually if it is dropped without being closed. An
+// Inventory is safe for concurrent use.
+type Inventory struct {
+	mu      sync.Mutex
+	p       *C.myInventory
+	cleanup runtime.Cleanup
+}
+
+// NewInventory creates an empty inventory.
+func NewInventory() (*Inventory, error) {
+	p := C.myInventoryNew()
+	if p == nil {
+		return nil, ErrNoMemory
+	}
+	inv := &Inventory{p: p}
+	inv.cleanup = runtime.AddCleanup(inv, freeInventory, p)
+	return inv, nil
+}
+
+func freeInventory(p *C.myInventory) {
+	C.myInventoryFree(p)
+}
+
+// Close frees the C++ object. Calls after the first return ErrClosed.
+func (inv *Inventory) Close() error {
+	inv.mu.Lock()
+	defer inv.mu.Unlock()
+
+	if inv.p == nil {
+		return ErrClosed
+	}
+	inv.cleanup.Stop()
+	freeInventory(inv.p)
+	inv.p = nil
+	return nil
+}
+
+// call runs f on the C++ object with name converted to a C string, and
+// turns the status code it returns into an error carrying the exception's
+// message.
+func (inv *Inventory) call(op, name string, f func(p *C.myInventory, name *C.char) C.int) error {
+	if strings.IndexByte(name, 0) >= 0 {
+		return ErrNUL
+	}
+	cname := (*C.char)(cmem.CString(name))
+	defer cmem.Free(unsafe.Pointer(cname))
+
+	inv.mu.Lock()
+	defer inv.mu.Unlock()
+
+	if inv.p == nil {
+		return ErrClosed
+	}
+	return inv.status(op, f(inv.p, cname))
+}
+
+// status must be called with inv.mu held, before the next call on the
+// object replaces its last error.
+func (inv *Inventory) status(op string, st C.int) error {
+	err := codes.Error(op, int(st))
+	if err == nil {
+		return nil
+	}
+	if msg := C.GoString(C.myInventoryLastError(inv.p)); msg != "" {
+		return fmt.Errorf("%w: %s", err, msg)
+	}
+	return err
+}
+
+// Add increases the stock of name by qty, which must be positive.
+func (inv *Inventory) Add(name string, qty int) error {
+	return inv.call("myInventoryAdd", name, func(p *C.myInventory, name *C.char) C.int {
+		return C.myInventoryAdd(p, name, C.int(qty))
+	})
+}
+
+// Remove decreases the stock of name by qty. It fails with ErrRange if
+// there is not enough stock and ErrNotFound for an unknown item.
+func (inv *Inventory) Remove(name string, qty int) error {
+	return inv.call("myInventoryRemove", name, func(p *C.myInventory, name *C.char) C.int {
+		return C.myInventoryRemove(p, name, C.int(qty))
+	})
+}
+
+// Count returns the stock of name.
+func (inv *Inventory) Count(name string) (int, error) {
+	var n C.int
+	err := inv.call("myInventoryCount", name, func(p *C.myInventory, name *C.char) C.int {
+		return C.myInventoryCount(p, name, &n)
+	})
+	return int(n), err
+}
+
+// String lists the items as "name=qty" pairs in name order.
+func (inv *Inventory) String() string {
+	inv.mu.Lock()
+	defer inv.mu.Unlock()
+
+	if inv.p == nil {
+		return ""
+	}
+	var out *C.char
+	if inv.status("myInventoryDescribe", C.myInventoryDescribe(inv.p, &out)) != nil {
+		return ""
+	}
+	defer cmem.Free(unsafe.Pointer(out))
+	return C.GoString(out)
+}
@@ -0,0 +1,30 @@
+//go:build !nocgo && !windows
+
+package mylib
+
+/*
+
+#include "mylib.h"
+
+*/
+import "C"
+
+import "github.com/lxwagn/using-go-with-c-libraries/pkg/features"
+
+// Compiler returns the compiler the C library was built with, as
+// myCompiler names it, such as "GCC 13.2.0".
+//
+// cgo lays out the structs from the header the program is compiled
+// against, so unlike the Windows build this one has no layout of its own
+// to check against myGetABI's.
+func Compiler() (string, error) {
+	if err := require(features.ABI); err != nil {
+		return "", err
+	}
+	var name string
+	err := callC(func() error {
+		name = C.GoString(C.myCompiler())
+		return nil
+	})
+	return name, err
+}
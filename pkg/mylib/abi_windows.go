@@ -0,0 +1,111 @@
+package mylib
+
+import (
+	"fmt"
+	"unsafe"
+
+	"golang.org/x/sys/windows"
+
+	"github.com/lxwagn/using-go-with-c-libraries/pkg/features"
+)
+
+// The DLL may have been built with MinGW-w64 or with MSVC. Both follow
+// the Windows ABI, so the same Go code calls either:
+//
+//   - On amd64 and arm64 there is one calling convention. On 386 both
+//     compilers default to cdecl, where the caller pops the arguments,
+//     and syscall's calls restore the stack pointer after returning, so
+//     they work whether the callee popped them (stdcall) or not.
+//   - A struct of 8 bytes or less comes back in registers (EDX:EAX on
+//     386) and a larger one through a hidden pointer; struct_windows.go
+//     handles myStruct either way.
+//   - long is 4 bytes and wchar_t 2 with both; mylib.c asserts the
+//     layouts below at compile time.
+//
+// What differs is the C runtime: an MSVC DLL links the UCRT, and MinGW's
+// links msvcrt.dll unless built for the UCRT. Memory the DLL allocates
+// goes back through myFree rather than another runtime's free, and a
+// FILE * from one runtime means nothing to another.
+
+// cABI has the memory layout of struct myABI.
+type cABI struct {
+	structSize  uintptr
+	structB     uintptr
+	pointSize   uintptr
+	pointWeight uintptr
+	wcharSize   uintptr
+	longSize    uintptr
+	pointerSize uintptr
+}
+
+var (
+	procGetABI   *windows.LazyProc
+	procCompiler *windows.LazyProc
+)
+
+// expectedABI is the layout of the Go mirrors of the C structs.
+var expectedABI = cABI{
+	structSize:  unsafe.Sizeof(cMyStruct{}),
+	structB:     unsafe.Offsetof(cMyStruct{}.b),
+	pointSize:   unsafe.Sizeof(Point{}),
+	pointWeight: unsafe.Offsetof(Point{}.Weight),
+	wcharSize:   unsafe.Sizeof(uint16(0)),
+	longSize:    unsafe.Sizeof(int32(0)),
+	pointerSize: unsafe.Sizeof(uintptr(0)),
+}
+
+// checkABI compares the layout the DLL at path was compiled with against
+// the Go mirrors, so that a DLL built with packing or type sizes of its
+// own fails to load instead of corrupting memory. A DLL too old to have
+// myGetABI is trusted.
+func checkABI(path string) error {
+	if procGetABI.Find() != nil {
+		return nil
+	}
+	var got cABI
+	err := callC(func() error {
+		procGetABI.Call(uintptr(unsafe.Pointer(&got)))
+		return nil
+	})
+	if err != nil || got == expectedABI {
+		return nil
+	}
+
+	compiler := "an unknown compiler"
+	if procCompiler.Find() == nil {
+		r, _, _ := procCompiler.Call()
+		compiler = goString((*byte)(cptr(r)))
+	}
+	for _, f := range []struct {
+		name      string
+		got, want uintptr
+	}{
+		{"sizeof(struct myStruct)", got.structSize, expectedABI.structSize},
+		{"offsetof(struct myStruct, b)", got.structB, expectedABI.structB},
+		{"sizeof(struct myPoint)", got.pointSize, expectedABI.pointSize},
+		{"offsetof(struct myPoint, weight)", got.pointWeight, expectedABI.pointWeight},
+		{"sizeof(wchar_t)", got.wcharSize, expectedABI.wcharSize},
+		{"sizeof(long)", got.longSize, expectedABI.longSize},
+		{"sizeof(void *)", got.pointerSize, expectedABI.pointerSize},
+	} {
+		if f.got != f.want {
+			return fmt.Errorf("mylib: %s was built by %s with %s = %d, but this program needs %d", path, compiler, f.name, f.got, f.want)
+		}
+	}
+	return nil
+}
+
+// Compiler returns the compiler mylib.dll was built with, as myCompiler
+// names it, such as "MSVC 1939" or "MinGW-w64 GCC 13.2.0".
+func Compiler() (string, error) {
+	if err := require(features.ABI); err != nil {
+		return "", err
+	}
+	var s string
+	err := callC(func() error {
+		r, _, _ := procCompiler.Call()
+		s = goString((*byte)(cptr(r)))
+		return nil
+	})
+	return s, err
+}
@@ -0,0 +1,186 @@
+//go:build !nocgo && !windows
+
+package mylib
+
+/*
+
+#include <stdlib.h>
+#include "mylib.h"
+
+// Defined in allocator_export.go.
+extern void *goMalloc(size_t n);
+extern void *goRealloc(void *p, size_t n);
+extern void goFree(void *p);
+
+static int setGoAllocator(void) {
+	return mySetAllocator(goMalloc, goRealloc, goFree);
+}
+
+// The counting allocator keeps each block's size in a header in front of
+// it, 16 bytes so that the block stays aligned for any type. It calls
+// malloc, which is jemalloc's or tcmalloc's in a program linked with one
+// of them, and counts without calling into Go.
+enum { countHeader = 16 };
+
+struct allocCounts {
+	long long live, peak, allocs, frees;
+};
+
+static struct allocCounts counts;
+
+static void countBytes(long long n) {
+	long long live = __atomic_add_fetch(&counts.live, n, __ATOMIC_RELAXED);
+	long long peak = __atomic_load_n(&counts.peak, __ATOMIC_RELAXED);
+
+	while (live > peak && !__atomic_compare_exchange_n(&counts.peak, &peak, live, 1, __ATOMIC_RELAXED, __ATOMIC_RELAXED))
+		;
+}
+
+static void *countingMalloc(size_t n) {
+	char *h = malloc(countHeader + n);
+
+	if (h == NULL)
+		return NULL;
+	*(size_t *)h = n;
+	__atomic_add_fetch(&counts.allocs, 1, __ATOMIC_RELAXED);
+	countBytes(n);
+	return h + countHeader;
+}
+
+static void *countingRealloc(void *p, size_t n) {
+	char *h;
+	size_t old;
+
+	if (p == NULL)
+		return countingMalloc(n);
+	h = (char *)p - countHeader;
+	old = *(size_t *)h;
+	h = realloc(h, countHeader + n);
+	if (h == NULL)
+		return NULL;
+	*(size_t *)h = n;
+	countBytes((long long)n - (long long)old);
+	return h + countHeader;
+}
+
+static void countingFree(void *p) {
+	char *h;
+
+	if (p == NULL)
+		return;
+	h = (char *)p - countHeader;
+	countBytes(-(long long)*(size_t *)h);
+	__atomic_add_fetch(&counts.frees, 1, __ATOMIC_RELAXED);
+	free(h);
+}
+
+static int setCountingAllocator(void) {
+	return mySetAllocator(countingMalloc, countingRealloc, countingFree);
+}
+
+static void readCounts(struct allocCounts *c) {
+	c->live = __atomic_load_n(&counts.live, __ATOMIC_RELAXED);
+	c->peak = __atomic_load_n(&counts.peak, __ATOMIC_RELAXED);
+	c->allocs = __atomic_load_n(&counts.allocs, __ATOMIC_RELAXED);
+	c->frees = __atomic_load_n(&counts.frees, __ATOMIC_RELAXED);
+}
+
+*/
+import "C"
+
+import (
+	"fmt"
+
+	"github.com/lxwagn/using-go-with-c-libraries/pkg/cmem"
+	"github.com/lxwagn/using-go-with-c-libraries/pkg/features"
+)
+
+// An Allocator is a set of functions for the C library to allocate its
+// own memory with, such as its buffers and sessions; see SetAllocator.
+type Allocator int
+
+const (
+	// SystemAllocator is C's malloc, realloc and free, which the library
+	// uses until told otherwise.
+	SystemAllocator Allocator = iota
+
+	// GoAllocator routes every allocation through Go, and so through
+	// pkg/cmem: it shows in cmem.ReadStats and, with -tags cmemdbg, in
+	// cmem.Leaks. Each allocation and free is a callback from C, which
+	// costs far more than the malloc it makes.
+	GoAllocator
+
+	// CountingAllocator is a shim in C around malloc that counts bytes
+	// and allocations without calling into Go; AllocatorStats reads the
+	// counts. In a program linked with jemalloc or tcmalloc, the malloc
+	// it wraps is theirs.
+	CountingAllocator
+)
+
+func (a Allocator) String() string {
+	switch a {
+	case SystemAllocator:
+		return "system"
+	case GoAllocator:
+		return "go"
+	case CountingAllocator:
+		return "counting"
+	}
+	return fmt.Sprintf("Allocator(%d)", int(a))
+}
+
+// SetAllocator makes the C library allocate with a from now on. Memory
+// can only be freed by the allocator that gave it out, so SetAllocator
+// fails with ErrInvalid while any of the library's allocations is live:
+// call it at start-up, before creating buffers, sessions and the like.
+func SetAllocator(a Allocator) error {
+	if err := require(features.Allocator); err != nil {
+		return err
+	}
+
+	if a != SystemAllocator && a != GoAllocator && a != CountingAllocator {
+		return fmt.Errorf("mylib: set allocator %v: %w", a, ErrInvalid)
+	}
+	return callC(func() error {
+		var rc C.int
+		switch a {
+		case SystemAllocator:
+			rc = C.mySetAllocator(nil, nil, nil)
+		case GoAllocator:
+			rc = C.setGoAllocator()
+		case CountingAllocator:
+			rc = C.setCountingAllocator()
+		}
+		if rc == C.MYLIB_EINVAL {
+			return fmt.Errorf("mylib: set allocator %v: %d allocations live: %w", a, C.myAllocations(), ErrInvalid)
+		}
+		return codes.Error("mySetAllocator", int(rc))
+	})
+}
+
+// Allocations returns the number of the C library's allocations that are
+// still live, whichever allocator made them.
+func Allocations() (int64, error) {
+	if err := require(features.Allocator); err != nil {
+		return 0, err
+	}
+	var n int64
+	err := callCUnlocked(func() error {
+		n = int64(C.myAllocations())
+		return nil
+	})
+	return n, err
+}
+
+// AllocatorStats returns the counts kept by CountingAllocator since the
+// program started, all zero if it was never used.
+func AllocatorStats() cmem.Stats {
+	var c C.struct_allocCounts
+	C.readCounts(&c)
+	return cmem.Stats{
+		LiveBytes: int64(c.live),
+		PeakBytes: int64(c.peak),
+		Allocs:    uint64(c.allocs),
+		Frees:     uint64(c.frees),
+	}
+}
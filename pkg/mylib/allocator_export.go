@@ -0,0 +1,41 @@
+//go:build !nocgo && !windows
+
+package mylib
+
+// #include <stddef.h>
+import "C"
+
+import (
+	"unsafe"
+
+	"github.com/lxwagn/using-go-with-c-libraries/pkg/cmem"
+)
+
+// goMalloc, goRealloc and goFree are GoAllocator's functions. cmem panics
+// when C is out of memory, and a panic must not unwind into the library's
+// frames, so they report it the way malloc would, with NULL.
+//
+//export goMalloc
+func goMalloc(n C.size_t) (p unsafe.Pointer) {
+	defer func() {
+		if recover() != nil {
+			p = nil
+		}
+	}()
+	return cmem.Malloc(int(n))
+}
+
+//export goRealloc
+func goRealloc(p unsafe.Pointer, n C.size_t) (q unsafe.Pointer) {
+	defer func() {
+		if recover() != nil {
+			q = nil
+		}
+	}()
+	return cmem.Realloc(p, int(n))
+}
+
+//export goFree
+func goFree(p unsafe.Pointer) {
+	cmem.Free(p)
+}
@@ -0,0 +1,49 @@
+//go:build cgo && !nocgo && !windows
+
+package mylib
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/lxwagn/using-go-with-c-libraries/pkg/cmem"
+)
+
+// TestBufferGoAllocator routes the library's own allocations through
+// pkg/cmem, so that the memory behind a Buffer, and not only the strings
+// passed to it, must be freed by Close.
+func TestBufferGoAllocator(t *testing.T) {
+	if err := SetAllocator(GoAllocator); err != nil {
+		t.Skip(err)
+	}
+	t.Cleanup(func() {
+		if err := SetAllocator(SystemAllocator); err != nil {
+			t.Error(err)
+		}
+	})
+	cmem.CheckLeaks(t)
+	cmem.EnableStats()
+	before := cmem.ReadStats()
+
+	b, err := NewBuffer()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for range 100 {
+		if err := b.Append(strings.Repeat("x", 100)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if got := cmem.ReadStats().Live(); got <= before.Live() {
+		t.Errorf("cmem live allocations = %d with a buffer open, want more than %d", got, before.Live())
+	}
+	if err := b.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if n, _ := Allocations(); n != 0 {
+		t.Errorf("Allocations = %d after Close, want 0", n)
+	}
+	if got := cmem.ReadStats().Live(); got != before.Live() {
+		t.Errorf("cmem live allocations = %d after Close, want %d", got, before.Live())
+	}
+}
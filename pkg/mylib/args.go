@@ -0,0 +1,29 @@
+package mylib
+
+import (
+	"fmt"
+
+	"github.com/lxwagn/using-go-with-c-libraries/pkg/cerr"
+	"github.com/lxwagn/using-go-with-c-libraries/pkg/cnum"
+)
+
+// Arguments that do not fit in their C parameter types are refused
+// rather than truncated. Wrappers with an error result return argError;
+// those without one panic, through cnum.Must, as for an index out of
+// range.
+
+// argError reports an argument of op found by pkg/cnum not to fit in its
+// C type. It matches ErrInvalid, as the library's own checks of its
+// arguments do, and cnum.ErrRange.
+func argError(op string, err error) error {
+	return &cerr.Error{Op: op, Code: int(StatusInvalid), Err: fmt.Errorf("%w: %w", ErrInvalid, err)}
+}
+
+// cint converts v for a C int parameter of op.
+func cint[T cnum.Integer](op string, v T) (int32, error) {
+	n, err := cnum.ToCInt(v)
+	if err != nil {
+		return 0, argError(op, err)
+	}
+	return n, nil
+}
@@ -0,0 +1,64 @@
+//go:build !nocgo && !windows
+
+package mylib
+
+/*
+
+#include "mylib.h"
+
+*/
+import "C"
+
+import (
+	"strings"
+
+	"github.com/lxwagn/using-go-with-c-libraries/pkg/cmem"
+	"github.com/lxwagn/using-go-with-c-libraries/pkg/features"
+)
+
+// Args holds the options ParseArgs found.
+type Args struct {
+	Verbose bool     // -v
+	Count   int64    // -n COUNT
+	Name    string   // --name=NAME
+	Rest    []string // the arguments after the options
+}
+
+// ParseArgs parses argv, the program name first, with the C library's
+// myParseArgs, which takes it as main's argc and argv. It fails with
+// ErrInvalid for an unknown option or a bad value.
+func ParseArgs(argv []string) (Args, error) {
+	if len(argv) == 0 {
+		return Args{}, ErrInvalid
+	}
+	for _, s := range argv {
+		if strings.IndexByte(s, 0) >= 0 {
+			return Args{}, ErrNUL
+		}
+	}
+	argc, err := cint("myParseArgs", len(argv))
+	if err != nil {
+		return Args{}, err
+	}
+	if err := require(features.Args); err != nil {
+		return Args{}, err
+	}
+
+	cargv := cmem.CStrings(argv)
+	defer cmem.Free(cargv)
+
+	var a C.struct_myArgs
+	err = callC(func() error {
+		return codes.Error("myParseArgs", int(C.myParseArgs(C.int(argc), (**C.char)(cargv), &a)))
+	})
+	if err != nil {
+		return Args{}, err
+	}
+	args := Args{Verbose: a.verbose != 0, Count: int64(a.count), Rest: argv[a.rest:]}
+	// name points into cargv, so it has to be copied before the deferred
+	// Free.
+	if a.name != nil {
+		args.Name = C.GoString(a.name)
+	}
+	return args, nil
+}
@@ -0,0 +1,96 @@
+//go:build !nocgo && !windows
+
+package mylib
+
+/*
+
+#include <stdint.h>
+#include "mylib.h"
+
+// Defined in async_export.go.
+extern void goFillDone(uintptr_t handle, int status, unsigned int checksum);
+
+static void fillDone(void *userdata, int status, unsigned int checksum) {
+	goFillDone((uintptr_t)userdata, status, checksum);
+}
+
+static int fillAsyncGateway(unsigned char *buf, size_t n, unsigned char seed, int delay, uintptr_t handle) {
+	return myFillAsync(buf, n, seed, delay, fillDone, (void *)handle);
+}
+
+*/
+import "C"
+
+import (
+	"runtime"
+	"time"
+	"unsafe"
+
+	"github.com/lxwagn/using-go-with-c-libraries/pkg/cnum"
+	"github.com/lxwagn/using-go-with-c-libraries/pkg/features"
+	"github.com/lxwagn/using-go-with-c-libraries/pkg/handles"
+)
+
+// A FillOp is a fill started by FillAsync.
+type FillOp struct {
+	pinner   runtime.Pinner
+	done     chan struct{}
+	checksum uint32
+	err      error
+}
+
+// FillAsync starts filling buf as Fill does on a thread of the C
+// library's own, after waiting delay, and returns at once. The library
+// keeps writing to buf after FillAsync returns, which the cgo rules only
+// allow for pinned memory, so buf is pinned with a runtime.Pinner until
+// the library reports the fill done: until then the garbage collector
+// neither frees nor moves it, even if the caller drops every reference to
+// it. The caller must not touch buf until Wait returns or Done is closed.
+//
+// It panics if delay in microseconds does not fit in a C int.
+func FillAsync(buf []byte, seed byte, delay time.Duration) (*FillOp, error) {
+	if delay < 0 {
+		return nil, ErrInvalid
+	}
+	us := cnum.Must(cnum.ToCInt(delay.Microseconds()))
+	if err := require(features.Async); err != nil {
+		return nil, err
+	}
+
+	op := &FillOp{done: make(chan struct{})}
+	p := unsafe.SliceData(buf)
+	if len(buf) > 0 {
+		op.pinner.Pin(p)
+	}
+	h := handles.New(op)
+	err := callC(func() error {
+		rc := C.fillAsyncGateway((*C.uchar)(unsafe.Pointer(p)), C.size_t(len(buf)), C.uchar(seed), C.int(us), C.uintptr_t(h.Uintptr()))
+		return codes.Error("myFillAsync", int(rc))
+	})
+	if err != nil {
+		// done will never be called.
+		h.Delete()
+		op.pinner.Unpin()
+		return nil, err
+	}
+	return op, nil
+}
+
+// finish runs on the library's thread when the fill is done.
+func (op *FillOp) finish(status int, checksum uint32) {
+	op.checksum, op.err = checksum, codes.Error("myFillAsync", status)
+	op.pinner.Unpin()
+	close(op.done)
+}
+
+// Done returns a channel that is closed once the fill is done.
+func (op *FillOp) Done() <-chan struct{} {
+	return op.done
+}
+
+// Wait waits for the fill to be done and returns the checksum of the
+// filled buffer, as Checksum computes it.
+func (op *FillOp) Wait() (uint32, error) {
+	<-op.done
+	return op.checksum, op.err
+}
@@ -0,0 +1,24 @@
+//go:build !nocgo && !windows
+
+package mylib
+
+/*
+#include <stdint.h>
+*/
+import "C"
+
+import "github.com/lxwagn/using-go-with-c-libraries/pkg/handles"
+
+// goFillDone runs on the library's thread once myFillAsync is done with
+// the buffer. It is the last use of the handle.
+//
+//export goFillDone
+func goFillDone(handle C.uintptr_t, status C.int, checksum C.uint) {
+	h := handles.FromUintptr[*FillOp](uintptr(handle))
+	op, err := h.Get()
+	if err != nil {
+		return
+	}
+	h.Delete()
+	op.finish(int(status), uint32(checksum))
+}
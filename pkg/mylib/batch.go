@@ -0,0 +1,174 @@
+//go:build !nocgo && !windows
+
+package mylib
+
+/*
+
+#include <stdlib.h>
+#include "mylib.h"
+
+*/
+import "C"
+
+import (
+	"runtime"
+	"strings"
+	"unsafe"
+
+	"github.com/lxwagn/using-go-with-c-libraries/pkg/cmem"
+)
+
+// A Batch queues library operations and runs them all in a single cgo
+// call when flushed. Every cgo call has a fixed cost of tens of
+// nanoseconds, more than many C functions take to run, so a loop of small
+// calls spends most of its time crossing between Go and C. A Batch pays
+// that cost once per Flush instead of once per operation.
+//
+// A Batch reuses its memory from one Flush to the next, so keeping one
+// around for repeated batches avoids allocating. Call Close to free its C
+// memory when done. The zero Batch is empty and ready to use. A Batch is
+// not safe for concurrent use.
+type Batch struct {
+	mem     *batchMem
+	keys    []string
+	results []Result
+	cleanup runtime.Cleanup
+}
+
+// batchMem holds the request array in C memory. Go memory would work too,
+// since the only pointers in a request point to C memory, but cgo would
+// then scan every request for Go pointers on each call, which costs as
+// much as the crossings the batch saves.
+type batchMem struct {
+	reqs []C.struct_myRequest // len is the number queued
+}
+
+func freeBatchMem(m *batchMem) {
+	cmem.Free(unsafe.Pointer(unsafe.SliceData(m.reqs)))
+}
+
+// A Result is the outcome of one queued operation.
+type Result struct {
+	Value int
+	Err   error
+}
+
+// CounterAdd queues a CounterAdd call and returns its index in the
+// results of the next Flush, where Value is the counter's new value.
+func (b *Batch) CounterAdd(delta int) int {
+	return b.add(C.MYLIB_OP_COUNTER_ADD, int64(delta), "")
+}
+
+// Lookup queues a Lookup call and returns its index in the results of the
+// next Flush, where Value is the value found or Err matches ErrNotFound.
+func (b *Batch) Lookup(key string) int {
+	return b.add(C.MYLIB_OP_LOOKUP, 0, key)
+}
+
+func (b *Batch) add(op C.int, arg int64, key string) int {
+	if b.mem == nil {
+		b.mem = new(batchMem)
+		b.cleanup = runtime.AddCleanup(b, freeBatchMem, b.mem)
+	}
+	m := b.mem
+	n := len(m.reqs)
+	if n == cap(m.reqs) {
+		grown := max(2*n, 16)
+		p := cmem.Calloc(grown, C.sizeof_struct_myRequest)
+		reqs := unsafe.Slice((*C.struct_myRequest)(p), grown)[:n]
+		copy(reqs, m.reqs)
+		freeBatchMem(m)
+		m.reqs = reqs
+	}
+	m.reqs = m.reqs[:n+1]
+	m.reqs[n] = C.struct_myRequest{op: op, arg: C.longlong(arg)}
+	b.keys = append(b.keys, key)
+	return n
+}
+
+// Len returns the number of queued operations.
+func (b *Batch) Len() int {
+	if b.mem == nil {
+		return 0
+	}
+	return len(b.mem.reqs)
+}
+
+// Close frees the batch's C memory, dropping any queued operations. The
+// Batch can be used again afterwards.
+func (b *Batch) Close() {
+	if b.mem == nil {
+		return
+	}
+	b.cleanup.Stop()
+	freeBatchMem(b.mem)
+	b.mem = nil
+	b.keys = b.keys[:0]
+}
+
+// Flush runs the queued operations in order with one call to myBatch and
+// empties the batch. The results are indexed as returned by the queueing
+// methods and stay valid until the next Flush.
+//
+// The error is for the batch as a whole, in which case nothing ran; the
+// batch is emptied either way. Each operation's own error is in its
+// Result.
+func (b *Batch) Flush() ([]Result, error) {
+	if b.Len() == 0 {
+		return nil, nil
+	}
+	reqs, keys := b.mem.reqs, b.keys
+	b.mem.reqs, b.keys = reqs[:0], keys[:0]
+	n, err := cint("myBatch", len(reqs))
+	if err != nil {
+		return nil, err
+	}
+
+	var a *cmem.Arena
+	for i := range reqs {
+		if reqs[i].op != C.MYLIB_OP_LOOKUP {
+			continue
+		}
+		if strings.IndexByte(keys[i], 0) >= 0 {
+			if a != nil {
+				a.Free()
+			}
+			return nil, ErrNUL
+		}
+		if a == nil {
+			a = cmem.NewArena(0)
+			defer a.Free()
+		}
+		reqs[i].key = (*C.char)(a.CString(keys[i]))
+	}
+
+	err = callC(func() error {
+		C.myBatch(&reqs[0], C.int(n))
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	results := b.results[:0]
+	for i := range reqs {
+		r := Result{Err: codes.Error(batchOpName(reqs[i].op), int(reqs[i].status))}
+		if r.Err == nil {
+			r.Value = int(reqs[i].result)
+		}
+		results = append(results, r)
+		keys[i] = ""
+	}
+	b.results = results
+	return results, nil
+}
+
+func batchOpName(op C.int) string {
+	switch Op(op) {
+	case OpCounterAdd:
+		return "myBatch: myCounterAdd"
+	case OpLookup:
+		return "myBatch: myLookup"
+	}
+	return "myBatch"
+}
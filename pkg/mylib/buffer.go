@@ -0,0 +1,148 @@
+//go:build !nocgo && !windows
+
+package mylib
+
+/*
+
+#include <stdlib.h>
+#include "mylib.h"
+
+*/
+import "C"
+
+import (
+	"runtime"
+	"strings"
+	"sync"
+	"unsafe"
+
+	"github.com/lxwagn/using-go-with-c-libraries/pkg/cmem"
+	"github.com/lxwagn/using-go-with-c-libraries/pkg/cnum"
+)
+
+// A Buffer is a growable string owned by the C library.
+//
+// Call Close when done with a Buffer to release the C memory. If a Buffer
+// becomes unreachable without being closed, a cleanup registered with the
+// runtime frees it eventually, but that is only a backstop: the garbage
+// collector knows nothing about the size of C allocations and may take a
+// long time to get to it.
+//
+// A Buffer is safe for concurrent use.
+type Buffer struct {
+	mu      sync.Mutex
+	p       *C.myBuffer
+	cleanup runtime.Cleanup
+}
+
+// NewBuffer creates an empty Buffer.
+func NewBuffer() (*Buffer, error) {
+	var p *C.myBuffer
+	err := callC(func() error {
+		var err error
+		if p, err = C.myBufferNew(); p == nil {
+			return lastError("myBufferNew", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	b := &Buffer{p: p}
+	b.cleanup = runtime.AddCleanup(b, freeBuffer, p)
+	return b, nil
+}
+
+// freeBuffer must not refer to the Buffer itself, or the Buffer would
+// never become unreachable.
+func freeBuffer(p *C.myBuffer) {
+	releaseC(func() {
+		C.myBufferFree(p)
+	})
+}
+
+// Close frees the C buffer. It is safe to call more than once; calls after
+// the first return ErrClosed.
+func (b *Buffer) Close() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.p == nil {
+		return ErrClosed
+	}
+	b.cleanup.Stop()
+	freeBuffer(b.p)
+	b.p = nil
+	return nil
+}
+
+// Append adds s to the end of the buffer. It fails with an error matching
+// ErrNoMemory if the library cannot grow the buffer to hold s.
+func (b *Buffer) Append(s string) error {
+	if strings.IndexByte(s, 0) >= 0 {
+		return ErrNUL
+	}
+
+	cs := (*C.char)(cmem.CString(s))
+	defer cmem.Free(unsafe.Pointer(cs))
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.p == nil {
+		return ErrClosed
+	}
+
+	return callC(func() error {
+		return codes.Error("myBufferAppend", int(C.myBufferAppend(b.p, cs)))
+	})
+}
+
+// String returns a copy of the buffer's contents, or "" once it is
+// closed.
+func (b *Buffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.p == nil {
+		return ""
+	}
+
+	var s string
+	callC(func() error {
+		s = string(unsafe.Slice((*byte)(unsafe.Pointer(C.myBufferData(b.p))), C.myBufferLen(b.p)))
+		return nil
+	})
+	return s
+}
+
+// Len returns the length of the buffer's contents in bytes, or 0 once it
+// is closed.
+func (b *Buffer) Len() int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.p == nil {
+		return 0
+	}
+
+	var n int
+	callC(func() error {
+		n = cnum.Must(cnum.FromCSizeT[int](uint(C.myBufferLen(b.p))))
+		return nil
+	})
+	return n
+}
+
+// LiveBuffers returns the number of C buffers that have been created and
+// not yet freed, whether by Close or by the cleanup. Tests use it to
+// check for leaks.
+func LiveBuffers() int {
+	var n int
+	callC(func() error {
+		n = int(C.myBufferLive())
+		return nil
+	})
+	return n
+}
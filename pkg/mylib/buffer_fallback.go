@@ -0,0 +1,97 @@
+//go:build !cgo && !nocgo && !windows
+
+package mylib
+
+import (
+	"runtime"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// A Buffer is a growable string. In the cgo build it is owned by the C
+// library; here it is Go memory, but Close and LiveBuffers behave the
+// same.
+//
+// A Buffer is safe for concurrent use.
+type Buffer struct {
+	mu      sync.Mutex
+	b       *strings.Builder // nil once closed
+	cleanup runtime.Cleanup
+}
+
+// buffersLive stands in for the C library's count of live buffers.
+var buffersLive atomic.Int32
+
+// NewBuffer creates an empty Buffer.
+func NewBuffer() (*Buffer, error) {
+	buffersLive.Add(1)
+	b := &Buffer{b: new(strings.Builder)}
+	b.cleanup = runtime.AddCleanup(b, freeBuffer, 0)
+	return b, nil
+}
+
+func freeBuffer(int) {
+	buffersLive.Add(-1)
+}
+
+// Close releases the buffer. It is safe to call more than once; calls
+// after the first return ErrClosed.
+func (b *Buffer) Close() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.b == nil {
+		return ErrClosed
+	}
+	b.cleanup.Stop()
+	freeBuffer(0)
+	b.b = nil
+	return nil
+}
+
+// Append adds s to the end of the buffer.
+func (b *Buffer) Append(s string) error {
+	if strings.IndexByte(s, 0) >= 0 {
+		return ErrNUL
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.b == nil {
+		return ErrClosed
+	}
+	b.b.WriteString(s)
+	return nil
+}
+
+// String returns a copy of the buffer's contents, or "" once it is
+// closed.
+func (b *Buffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.b == nil {
+		return ""
+	}
+	return b.b.String()
+}
+
+// Len returns the length of the buffer's contents in bytes, or 0 once it
+// is closed.
+func (b *Buffer) Len() int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.b == nil {
+		return 0
+	}
+	return b.b.Len()
+}
+
+// LiveBuffers returns the number of buffers that have been created and
+// not yet freed. Tests use it to check for leaks.
+func LiveBuffers() int {
+	return int(buffersLive.Load())
+}
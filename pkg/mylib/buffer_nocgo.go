@@ -0,0 +1,136 @@
+//go:build nocgo && !windows
+
+package mylib
+
+import (
+	"runtime"
+	"strings"
+	"sync"
+	"unsafe"
+
+	"github.com/lxwagn/using-go-with-c-libraries/pkg/cnum"
+)
+
+// A Buffer is a growable string owned by the C library.
+//
+// Call Close when done with a Buffer to release the C memory. If a Buffer
+// becomes unreachable without being closed, a cleanup registered with the
+// runtime frees it eventually, but that is only a backstop.
+//
+// A Buffer is safe for concurrent use.
+type Buffer struct {
+	mu      sync.Mutex
+	p       uintptr
+	cleanup runtime.Cleanup
+}
+
+// NewBuffer creates an empty Buffer.
+func NewBuffer() (*Buffer, error) {
+	if err := load(); err != nil {
+		return nil, err
+	}
+
+	var p uintptr
+	err := callC(func() error {
+		if errno := withErrno(func() { p = myBufferNew() }); p == 0 {
+			return lastError("myBufferNew", errno)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	b := &Buffer{p: p}
+	b.cleanup = runtime.AddCleanup(b, freeBuffer, p)
+	return b, nil
+}
+
+func freeBuffer(p uintptr) {
+	releaseC(func() {
+		myBufferFree(p)
+	})
+}
+
+// Close frees the C buffer. It is safe to call more than once; calls after
+// the first return ErrClosed.
+func (b *Buffer) Close() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.p == 0 {
+		return ErrClosed
+	}
+	b.cleanup.Stop()
+	freeBuffer(b.p)
+	b.p = 0
+	return nil
+}
+
+// Append adds s to the end of the buffer. It fails with an error matching
+// ErrNoMemory if the library cannot grow the buffer to hold s.
+func (b *Buffer) Append(s string) error {
+	if strings.IndexByte(s, 0) >= 0 {
+		return ErrNUL
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.p == 0 {
+		return ErrClosed
+	}
+
+	return callC(func() error {
+		return codes.Error("myBufferAppend", int(myBufferAppend(b.p, s)))
+	})
+}
+
+// String returns a copy of the buffer's contents, or "" once it is
+// closed.
+func (b *Buffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.p == 0 {
+		return ""
+	}
+
+	var s string
+	callC(func() error {
+		s = string(unsafe.Slice(myBufferData(b.p), myBufferLen(b.p)))
+		return nil
+	})
+	return s
+}
+
+// Len returns the length of the buffer's contents in bytes, or 0 once it
+// is closed.
+func (b *Buffer) Len() int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.p == 0 {
+		return 0
+	}
+
+	var n int
+	callC(func() error {
+		n = cnum.Must(cnum.FromCSizeT[int](uint(myBufferLen(b.p))))
+		return nil
+	})
+	return n
+}
+
+// LiveBuffers returns the number of C buffers that have been created and
+// not yet freed. Tests use it to check for leaks.
+func LiveBuffers() int {
+	mustLoad()
+
+	var n int
+	callC(func() error {
+		n = int(myBufferLive())
+		return nil
+	})
+	return n
+}
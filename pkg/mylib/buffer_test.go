@@ -0,0 +1,33 @@
+package mylib
+
+import (
+	"strings"
+	"testing"
+)
+
+// TestBufferLeaks grows buffers past their first allocation and closes
+// them, which must leave no buffer behind. TestBufferGoAllocator checks
+// the memory behind them in the cgo build.
+func TestBufferLeaks(t *testing.T) {
+	live := LiveBuffers()
+	for range 10 {
+		b, err := NewBuffer()
+		if err != nil {
+			t.Fatal(err)
+		}
+		for range 100 {
+			if err := b.Append(strings.Repeat("x", 100)); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if got := b.Len(); got != 100*100 {
+			t.Errorf("Len = %d, want %d", got, 100*100)
+		}
+		if err := b.Close(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if got := LiveBuffers(); got != live {
+		t.Errorf("LiveBuffers = %d after Close, want %d", got, live)
+	}
+}
@@ -0,0 +1,36 @@
+//go:build !nocgo && !windows
+
+package mylib
+
+/*
+
+#include "mylib.h"
+
+*/
+import "C"
+
+import "unsafe"
+
+// Fill has the C library write seed, seed+1, ... into b. C writes
+// straight into b's backing array; nothing is copied.
+func Fill(b []byte, seed byte) {
+	if len(b) == 0 {
+		return
+	}
+
+	callC(func() error {
+		C.myFill((*C.uchar)(unsafe.Pointer(unsafe.SliceData(b))), C.size_t(len(b)), C.uchar(seed))
+		return nil
+	})
+}
+
+// Checksum returns the Adler-32 checksum of b as computed by the C
+// library, reading b in place.
+func Checksum(b []byte) uint32 {
+	var sum uint32
+	callC(func() error {
+		sum = uint32(C.myChecksum((*C.uchar)(unsafe.Pointer(unsafe.SliceData(b))), C.size_t(len(b))))
+		return nil
+	})
+	return sum
+}
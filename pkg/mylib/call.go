@@ -0,0 +1,62 @@
+package mylib
+
+// callC makes a call into the C library: f, which makes it, runs with the
+// library locked, through the interceptors given to SetInterceptors, and
+// annotated in an execution trace as TraceMode says. Every wrapper goes
+// through callC, or one of the variants below, so that what is done
+// around a call is done in one place for all of them, in every build. f
+// returns the error of the call, if it failed, and callC returns it, or
+// an interceptor's error if one failed the call without making it.
+//
+// f may panic, as a callback's panic is raised again once C has returned,
+// and the lock is released and the annotation ended when it does.
+func callC(f func() error) error {
+	return doC(true, false, f)
+}
+
+// callCUnlocked is callC for the few calls that must not hold the library
+// lock, such as one waiting for a callback that takes it.
+func callCUnlocked(f func() error) error {
+	return doC(false, false, f)
+}
+
+// releaseC is callC for a call that frees or stops something the wrapper
+// forgets once it returns, such as a buffer's C object. Interceptors see
+// it, but it is made even if one fails it without calling next: skipping
+// it would leak the object, or for one holding a callback's handle, have
+// C call through the handle once it is deleted.
+func releaseC(f func()) {
+	doC(true, true, func() error { f(); return nil })
+}
+
+// releaseCUnlocked is releaseC for a call that must not hold the library
+// lock.
+func releaseCUnlocked(f func()) {
+	doC(false, true, func() error { f(); return nil })
+}
+
+func doC(serialize, release bool, f func() error) error {
+	chain := callChain.Load()
+	if chain == nil && !tracing() {
+		return makeCall(serialize, f)
+	}
+	name := wrapperName()
+	s := startTrace(name)
+	defer s.end()
+	var err error
+	if chain != nil {
+		err = chain.run(s.context(), name, serialize, release, f)
+	} else {
+		err = makeCall(serialize, f)
+	}
+	s.fail(err)
+	return err
+}
+
+func makeCall(serialize bool, f func() error) error {
+	if serialize {
+		lockC()
+		defer unlockC()
+	}
+	return f()
+}
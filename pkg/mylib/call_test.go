@@ -0,0 +1,148 @@
+// The fallback build makes no calls into C, and so has none to intercept.
+
+//go:build cgo || nocgo || windows
+
+package mylib
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+// intercept runs f with interceptors given to SetInterceptors.
+func intercept(t *testing.T, f func(), interceptors ...Interceptor) {
+	t.Helper()
+	if prev := SetInterceptors(interceptors...); prev != nil {
+		t.Fatalf("SetInterceptors replaced %d interceptors, want none", len(prev))
+	}
+	defer SetInterceptors()
+	f()
+}
+
+// TestSetInterceptors checks that the wrappers' calls, whatever makes
+// them, run through the chain in order.
+func TestSetInterceptors(t *testing.T) {
+	var order []string
+	h := NewLatencyHistogram()
+	intercept(t, func() {
+		if n, err := Lookup("two"); n != 2 || err != nil {
+			t.Errorf("Lookup(\"two\") = %d, %v; want 2, nil", n, err)
+		}
+		if _, err := CountWords("a b a"); err != nil {
+			t.Error(err)
+		}
+		s, err := NewSession("intercept", 10)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer s.Close()
+		if _, err := s.Add(1); err != nil {
+			t.Error(err)
+		}
+		// Sync's call leaves the library unlocked.
+		if err := s.Sync(time.Millisecond); err != nil && !errors.Is(err, errors.ErrUnsupported) {
+			t.Error(err)
+		}
+	}, recordCalls("a", &order), h.Interceptor(), recordCalls("b", &order))
+
+	for _, want := range []string{
+		"a>Lookup b>Lookup b<[] a<[]",
+		"a>CountWords b>CountWords b<[] a<[]",
+		"a>Session.Add b>Session.Add b<[] a<[]",
+	} {
+		if !strings.Contains(strings.Join(order, " "), want) {
+			t.Errorf("interceptors ran %s, want %s among them", strings.Join(order, " "), want)
+		}
+	}
+	for _, m := range []string{"Lookup", "CountWords", "NewSession", "Session.Add"} {
+		if !slices.Contains(h.Methods(), m) {
+			t.Errorf("histogram has methods %v, want %s among them", h.Methods(), m)
+		}
+	}
+
+	order = nil
+	Lookup("two")
+	if order != nil {
+		t.Errorf("interceptors ran %v after SetInterceptors()", order)
+	}
+}
+
+// TestInterceptorErrors checks that a wrapper returns its call's error,
+// after any retries, unless an interceptor failed the call without making
+// it.
+func TestInterceptorErrors(t *testing.T) {
+	errVeto := errors.New("veto")
+	veto := func(next CallFunc) CallFunc {
+		return func(ctx context.Context, call *Call) error {
+			if call.Method == "Lookup" {
+				return errVeto
+			}
+			return next(ctx, call)
+		}
+	}
+	intercept(t, func() {
+		if _, err := Lookup("two"); err != errVeto {
+			t.Errorf("vetoed Lookup = %v, want the interceptor's error", err)
+		}
+	}, veto)
+
+	override := func(next CallFunc) CallFunc {
+		return func(ctx context.Context, call *Call) error {
+			next(ctx, call)
+			return errVeto
+		}
+	}
+	intercept(t, func() {
+		if n, err := Lookup("two"); n != 2 || err != nil {
+			t.Errorf("Lookup = %d, %v after an interceptor failed it; want the call's 2, nil", n, err)
+		}
+	}, override)
+
+	calls := 0
+	count := func(next CallFunc) CallFunc {
+		return func(ctx context.Context, call *Call) error {
+			calls++
+			return next(ctx, call)
+		}
+	}
+	intercept(t, func() {
+		if _, err := Lookup("four"); !errors.Is(err, ErrNotFound) {
+			t.Errorf("retried Lookup(\"four\") = %v, want ErrNotFound", err)
+		}
+	}, retry, count)
+	if calls != 2 {
+		t.Errorf("retry made the call %d times, want 2", calls)
+	}
+}
+
+// TestInterceptRelease checks that a call freeing something is made even
+// if an interceptor fails it.
+func TestInterceptRelease(t *testing.T) {
+	b, err := NewBuffer()
+	if err != nil {
+		t.Fatal(err)
+	}
+	live := LiveBuffers()
+	vetoed := false
+	intercept(t, func() {
+		b.Close()
+	}, func(next CallFunc) CallFunc {
+		return func(ctx context.Context, call *Call) error {
+			if call.Method == "Buffer.Close" {
+				vetoed = true
+				return errors.New("veto")
+			}
+			return next(ctx, call)
+		}
+	})
+	if !vetoed {
+		t.Error("Buffer.Close's call was not intercepted")
+	}
+	if n := LiveBuffers(); n != live-1 {
+		t.Errorf("LiveBuffers = %d after Close, want %d", n, live-1)
+	}
+}
@@ -0,0 +1,47 @@
+//go:build !nocgo && !windows
+
+package mylib
+
+/*
+
+#include <stdint.h>
+#include "mylib.h"
+
+// Defined in callback_export.go.
+extern int goCallbackTrampoline(void *userdata, int value);
+
+// C code cannot be handed a Go func, so every callback goes through the
+// exported trampoline, with a handle of fn passed as the userdata.
+static int callNGateway(uintptr_t handle, int n) {
+	return myCallN(goCallbackTrampoline, (void *)handle, n);
+}
+
+*/
+import "C"
+
+import (
+	"github.com/lxwagn/using-go-with-c-libraries/pkg/cnum"
+	"github.com/lxwagn/using-go-with-c-libraries/pkg/cpanic"
+	"github.com/lxwagn/using-go-with-c-libraries/pkg/handles"
+)
+
+// CallN has the C library call fn with 0, 1, ..., n-1 and returns the sum
+// of the results. Unless the package is built with mylib_nolock, fn runs
+// while the library lock is held and must not call back into this package.
+// It panics if n does not fit in a C int. If fn panics, the calls left
+// return 0 without running fn, and CallN raises the panic again, as a
+// *cpanic.Panic, once myCallN has returned.
+func CallN(n int, fn Callback) int {
+	cn := cnum.Must(cnum.ToCInt(n))
+	var b cpanic.Barrier
+	h := handles.New(guardedCallback{&b, fn})
+	defer h.Delete()
+
+	var r C.int
+	callC(func() error {
+		r = C.callNGateway(C.uintptr_t(h.Uintptr()), C.int(cn))
+		return nil
+	})
+	b.Repanic()
+	return int(r)
+}
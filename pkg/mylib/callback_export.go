@@ -0,0 +1,28 @@
+//go:build !nocgo && !windows
+
+package mylib
+
+// A file containing //export directives may only declare C functions in
+// its preamble, not define them, which is why the gateway lives in
+// callback.go.
+
+/*
+#include <stdint.h>
+*/
+import "C"
+
+import (
+	"unsafe"
+
+	"github.com/lxwagn/using-go-with-c-libraries/pkg/cpanic"
+	"github.com/lxwagn/using-go-with-c-libraries/pkg/handles"
+)
+
+//export goCallbackTrampoline
+func goCallbackTrampoline(userdata unsafe.Pointer, value C.int) C.int {
+	g, err := handles.FromUintptr[guardedCallback](uintptr(userdata)).Get()
+	if err != nil {
+		return 0
+	}
+	return C.int(cpanic.Call(g.b, 0, func() int { return g.fn(int(value)) }))
+}
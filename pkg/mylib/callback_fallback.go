@@ -0,0 +1,46 @@
+//go:build !cgo && !nocgo && !windows
+
+package mylib
+
+import (
+	"strings"
+
+	"github.com/lxwagn/using-go-with-c-libraries/pkg/cnum"
+	"github.com/lxwagn/using-go-with-c-libraries/pkg/cpanic"
+)
+
+// CallN calls fn with 0, 1, ..., n-1 and returns the sum of the results,
+// as the C library's myCallN does, in 32 bits. Unless the package is
+// built with mylib_nolock, fn runs while the library lock is held and
+// must not call back into this package. It panics if n does not fit in a
+// C int. If fn panics, the calls left return 0 without running fn, and
+// CallN raises the panic again as a *cpanic.Panic, as the cgo build does.
+func CallN(n int, fn Callback) int {
+	cnum.Must(cnum.ToCInt(n))
+	var b cpanic.Barrier
+	var sum int32
+	callC(func() error {
+		for i := range n {
+			sum += int32(cpanic.Call(&b, 0, func() int { return fn(i) }))
+		}
+		return nil
+	})
+	b.Repanic()
+	return int(sum)
+}
+
+// CountWords splits text on spaces and returns how often each word
+// occurs.
+func CountWords(text string) (map[string]int, error) {
+	if strings.IndexByte(text, 0) >= 0 {
+		return nil, ErrNUL
+	}
+
+	counts := make(map[string]int)
+	for _, w := range strings.Split(text, " ") {
+		if w != "" {
+			counts[w]++
+		}
+	}
+	return counts, nil
+}
@@ -0,0 +1,84 @@
+//go:build nocgo && !windows
+
+package mylib
+
+import (
+	"strings"
+	"unsafe"
+
+	"github.com/lxwagn/using-go-with-c-libraries/pkg/cnum"
+	"github.com/lxwagn/using-go-with-c-libraries/pkg/cpanic"
+	"github.com/lxwagn/using-go-with-c-libraries/pkg/handles"
+)
+
+// C function pointers for the trampolines, made by purego.NewCallback
+// when the library is loaded. purego can only make a limited number of
+// callbacks, so there is one per signature and the Go function to run
+// is found from the userdata, as in the cgo build.
+var (
+	callNTrampoline    uintptr
+	eachWordTrampoline uintptr
+)
+
+func goCallbackTrampoline(userdata uintptr, value int32) int32 {
+	g, err := handles.FromUintptr[guardedCallback](userdata).Get()
+	if err != nil {
+		return 0
+	}
+	return int32(cpanic.Call(g.b, 0, func() int { return g.fn(int(value)) }))
+}
+
+func goWordTrampoline(userdata uintptr, word *byte, n int32) {
+	counts, err := handles.FromUintptr[map[string]int](userdata).Get()
+	if err != nil {
+		return
+	}
+	counts[string(unsafe.Slice(word, n))]++
+}
+
+// CallN has the C library call fn with 0, 1, ..., n-1 and returns the sum
+// of the results. Unless the package is built with mylib_nolock, fn runs
+// while the library lock is held and must not call back into this package.
+// It panics if n does not fit in a C int. If fn panics, the calls left
+// return 0 without running fn, and CallN raises the panic again, as a
+// *cpanic.Panic, once myCallN has returned.
+func CallN(n int, fn Callback) int {
+	cn := cnum.Must(cnum.ToCInt(n))
+	mustLoad()
+
+	var b cpanic.Barrier
+	h := handles.New(guardedCallback{&b, fn})
+	defer h.Delete()
+
+	var r int32
+	callC(func() error {
+		r = myCallN(callNTrampoline, h.Uintptr(), cn)
+		return nil
+	})
+	b.Repanic()
+	return int(r)
+}
+
+// CountWords splits text on spaces in C and returns how often each word
+// occurs.
+func CountWords(text string) (map[string]int, error) {
+	if strings.IndexByte(text, 0) >= 0 {
+		return nil, ErrNUL
+	}
+	if err := load(); err != nil {
+		return nil, err
+	}
+
+	counts := make(map[string]int)
+	h := handles.New(counts)
+	defer h.Delete()
+
+	err := callC(func() error {
+		myEachWord(text, eachWordTrampoline, h.Uintptr())
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return counts, nil
+}
@@ -0,0 +1,84 @@
+package mylib
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/lxwagn/using-go-with-c-libraries/pkg/cpanic"
+	"github.com/lxwagn/using-go-with-c-libraries/pkg/handles"
+)
+
+// catchPanic runs f and returns the *cpanic.Panic it panics with. It
+// fails t if f returns, or panics with anything else.
+func catchPanic(t *testing.T, f func()) (p *cpanic.Panic) {
+	t.Helper()
+	defer func() {
+		v := recover()
+		var ok bool
+		if p, ok = v.(*cpanic.Panic); !ok {
+			t.Fatalf("recovered %T %v, want a *cpanic.Panic", v, v)
+		}
+	}()
+	f()
+	return nil
+}
+
+// TestCallNPanic panics in the fourth of ten callbacks: the calls after
+// it are skipped, the panic reaches the caller of CallN once myCallN has
+// returned, and the library lock and the handle are released.
+func TestCallNPanic(t *testing.T) {
+	live := handles.Live()
+	calls := 0
+	p := catchPanic(t, func() {
+		CallN(10, func(v int) int {
+			calls++
+			if v == 3 {
+				panic("boom")
+			}
+			return v
+		})
+	})
+	if p.Value != "boom" || calls != 4 {
+		t.Errorf("panic %v after %d calls, want boom after 4", p.Value, calls)
+	}
+	if got := CallN(4, func(v int) int { return v }); got != 6 {
+		t.Errorf("CallN after the panic = %d, want 6", got)
+	}
+	if got := handles.Live(); got != live {
+		t.Errorf("%d handles live after the panic, want %d", got, live)
+	}
+}
+
+// TestCallNConcurrent runs CallN from many goroutines at once, each with
+// a callback of its own, which must get only its own calls, and leave
+// no handle behind.
+func TestCallNConcurrent(t *testing.T) {
+	const goroutines, n = 32, 200
+	live := handles.Live()
+	var wg sync.WaitGroup
+	for g := range goroutines {
+		wg.Go(func() {
+			calls := 0
+			seen := make([]bool, n)
+			sum := CallN(n, func(v int) int {
+				calls++
+				if v < 0 || v >= n || seen[v] {
+					t.Errorf("goroutine %d: unexpected call with %d", g, v)
+				} else {
+					seen[v] = true
+				}
+				return g*n + v
+			})
+			if calls != n {
+				t.Errorf("goroutine %d: %d calls, want %d", g, calls, n)
+			}
+			if want := g*n*n + n*(n-1)/2; sum != want {
+				t.Errorf("goroutine %d: CallN = %d, want %d", g, sum, want)
+			}
+		})
+	}
+	wg.Wait()
+	if got := handles.Live(); got != live {
+		t.Errorf("%d handles live after the calls, want %d", got, live)
+	}
+}
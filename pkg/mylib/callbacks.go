@@ -0,0 +1,14 @@
+package mylib
+
+import "github.com/lxwagn/using-go-with-c-libraries/pkg/cpanic"
+
+// A Callback is a Go function registered to be called from C.
+type Callback func(value int) int
+
+// A guardedCallback is a Callback with the barrier of the C call it is
+// handed to. The trampolines call fn behind b, so that a panic in it
+// stops short of the C frames calling it, which see the result 0.
+type guardedCallback struct {
+	b  *cpanic.Barrier
+	fn Callback
+}
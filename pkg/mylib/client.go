@@ -0,0 +1,98 @@
+package mylib
+
+import "context"
+
+// A Client is the part of the package's API that code depending on the
+// library needs most, as an interface, so that such code can take a
+// Client and be tested against pkg/mylib/mock: without cgo or the shared
+// library, with failures injected at will, and with no way for a bug in C
+// to crash the test binary. Native returns the implementation that calls
+// the library.
+//
+// The methods behave like the package functions of the same names.
+type Client interface {
+	Print(s string) error
+	Lookup(key string) (int, error)
+	FileSize(path string) (int64, error)
+	MakeStruct(a int, b string) (MyStruct, error)
+	ScaleStruct(s MyStruct, factor int) (MyStruct, error)
+	TranslatePoints(pts []Point, dx, dy int)
+	Fill(b []byte, seed byte)
+	Checksum(b []byte) uint32
+	CountWords(text string) (map[string]int, error)
+	WideReverse(s string) (string, error)
+	Crunch(ctx context.Context, iterations int64) (int64, error)
+	NewSession(name string, limit int64) (ClientSession, error)
+}
+
+// A ClientSession is a Session as a Client returns it.
+type ClientSession interface {
+	Name() string
+	Add(delta int64) (int64, error)
+	Reset() error
+	Stats() (total int64, calls int, err error)
+	Close() error
+}
+
+var _ ClientSession = (*Session)(nil)
+
+// Native returns the Client that calls the C library, through the
+// package functions.
+func Native() Client {
+	return native{}
+}
+
+type native struct{}
+
+func (native) Print(s string) error {
+	return Print(s)
+}
+
+func (native) Lookup(key string) (int, error) {
+	return Lookup(key)
+}
+
+func (native) FileSize(path string) (int64, error) {
+	return FileSize(path)
+}
+
+func (native) Fill(b []byte, seed byte) {
+	Fill(b, seed)
+}
+
+func (native) Checksum(b []byte) uint32 {
+	return Checksum(b)
+}
+
+func (native) WideReverse(s string) (string, error) {
+	return WideReverse(s)
+}
+
+func (native) TranslatePoints(pts []Point, dx, dy int) {
+	TranslatePoints(pts, dx, dy)
+}
+
+func (native) MakeStruct(a int, b string) (MyStruct, error) {
+	return MakeStruct(a, b)
+}
+
+func (native) ScaleStruct(s MyStruct, factor int) (MyStruct, error) {
+	return ScaleStruct(s, factor)
+}
+
+func (native) CountWords(text string) (map[string]int, error) {
+	return CountWords(text)
+}
+
+func (native) Crunch(ctx context.Context, iterations int64) (int64, error) {
+	return Crunch(ctx, iterations)
+}
+
+func (native) NewSession(name string, limit int64) (ClientSession, error) {
+	s, err := NewSession(name, limit)
+	if err != nil {
+		// Not s: a nil *Session in the interface would not be nil.
+		return nil, err
+	}
+	return s, nil
+}
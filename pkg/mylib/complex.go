@@ -0,0 +1,66 @@
+//go:build !nocgo && !windows
+
+package mylib
+
+/*
+
+// cgo's glue code spells the type of a double _Complex argument "double
+// complex", which only compiles where complex.h defines complex, so every
+// preamble that calls these functions needs the include.
+#include <complex.h>
+#include "mylib.h"
+
+*/
+import "C"
+
+import (
+	"github.com/lxwagn/using-go-with-c-libraries/pkg/features"
+	"github.com/lxwagn/using-go-with-c-libraries/pkg/mylib/marshal"
+)
+
+// ComplexMul returns a*b as the C library computes it. C follows Annex G
+// of C99 when a product comes out as NaN in both parts: an infinite
+// operand then gives an infinite result, where Go's * gives NaN.
+func ComplexMul(a, b complex128) (complex128, error) {
+	if err := require(features.Complex); err != nil {
+		return 0, err
+	}
+	var z complex128
+	err := callC(func() error {
+		z = complex128(C.myComplexMul(C.complexdouble(a), C.complexdouble(b)))
+		return nil
+	})
+	return z, err
+}
+
+// ComplexScale multiplies every number in z by factor in place. The slice
+// is handed to C as it is, since complex128 and double _Complex share a
+// layout.
+func ComplexScale(z []complex128, factor complex128) error {
+	if err := require(features.Complex); err != nil {
+		return err
+	}
+	if len(z) == 0 {
+		return nil
+	}
+	return callC(func() error {
+		C.myComplexScale((*C.complexdouble)(marshal.ComplexPtr(z)), C.size_t(len(z)), C.complexdouble(factor))
+		return nil
+	})
+}
+
+// ComplexSum returns the sum of the numbers in z.
+func ComplexSum(z []complex128) (complex128, error) {
+	if err := require(features.Complex); err != nil {
+		return 0, err
+	}
+	if len(z) == 0 {
+		return 0, nil
+	}
+	var sum complex128
+	err := callC(func() error {
+		sum = complex128(C.myComplexSum((*C.complexdouble)(marshal.ComplexPtr(z)), C.size_t(len(z))))
+		return nil
+	})
+	return sum, err
+}
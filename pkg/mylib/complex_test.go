@@ -0,0 +1,42 @@
+//go:build cgo && !nocgo && !windows
+
+package mylib
+
+import (
+	"math"
+	"math/cmplx"
+	"slices"
+	"testing"
+)
+
+func TestComplex(t *testing.T) {
+	a, b := complex(3, -4), complex(-2, 5)
+	if got, err := ComplexMul(a, b); err != nil || got != a*b {
+		t.Errorf("ComplexMul(%v, %v) = %v, %v; want %v", a, b, got, err, a*b)
+	}
+
+	z := []complex128{1, 1i, complex(2, -3)}
+	if err := ComplexScale(z, 2i); err != nil {
+		t.Fatal(err)
+	}
+	if want := []complex128{2i, -2, complex(6, 4)}; !slices.Equal(z, want) {
+		t.Errorf("ComplexScale by 2i gave %v, want %v", z, want)
+	}
+	if sum, err := ComplexSum(z); err != nil || sum != complex(4, 6) {
+		t.Errorf("ComplexSum(%v) = %v, %v", z, sum, err)
+	}
+}
+
+// TestComplexAnnexG checks that C's Annex G rescues a product that plain
+// arithmetic turns into NaN, as Go's * does not.
+func TestComplexAnnexG(t *testing.T) {
+	inf := complex(math.Inf(1), math.Inf(1))
+	one := complex(1, 0)
+	c, err := ComplexMul(inf, one)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if g := inf * one; !cmplx.IsInf(c) || !cmplx.IsNaN(g) {
+		t.Errorf("%v*%v is %v in C and %v in Go", inf, one, c, g)
+	}
+}
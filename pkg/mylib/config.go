@@ -0,0 +1,38 @@
+//go:build !nocgo && !windows
+
+package mylib
+
+/*
+
+#include "mylib.h"
+
+// Unlike a function, a variable has no lazy binding: the dynamic linker
+// resolves every reference to one when the program starts, and refuses
+// to start it against a library without the variable. A weak reference
+// resolves to address 0 instead, which Features reports as a missing
+// feature before ReadConfig or a setter uses it. Go code naming
+// C.myLogLevel would make a strong reference of cgo's own, so Go gets
+// the addresses from these functions.
+#pragma weak myLogLevel
+#pragma weak myBufferSize
+
+static int *logLevelAddr(void) { return &myLogLevel; }
+static size_t *bufferSizeAddr(void) { return &myBufferSize; }
+
+*/
+import "C"
+
+import (
+	"runtime"
+	"unsafe"
+)
+
+// withConfig calls f with the addresses of the C library's configuration
+// variables, holding its lock.
+func withConfig(f func(level *int32, size *uintptr)) {
+	runtime.LockOSThread()
+	defer runtime.UnlockOSThread()
+	C.myConfigLock()
+	defer C.myConfigUnlock()
+	f((*int32)(unsafe.Pointer(C.logLevelAddr())), (*uintptr)(unsafe.Pointer(C.bufferSizeAddr())))
+}
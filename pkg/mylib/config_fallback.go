@@ -0,0 +1,21 @@
+//go:build !cgo && !nocgo && !windows
+
+package mylib
+
+import "sync"
+
+// The fallback keeps the configuration in Go, starting from the C
+// library's values.
+var (
+	configMu   sync.Mutex
+	logLevel   = int32(LogLevelInfo)
+	bufferSize = uintptr(16)
+)
+
+// withConfig calls f with the addresses of the configuration variables,
+// holding their lock.
+func withConfig(f func(level *int32, size *uintptr)) {
+	configMu.Lock()
+	defer configMu.Unlock()
+	f(&logLevel, &bufferSize)
+}
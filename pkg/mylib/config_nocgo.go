@@ -0,0 +1,41 @@
+//go:build nocgo && !windows
+
+package mylib
+
+import (
+	"runtime"
+	"unsafe"
+
+	"github.com/ebitengine/purego"
+)
+
+// The configuration variables and their lock, bound by bind if the
+// library has them. dlsym returns a variable's address as it does a
+// function's.
+var (
+	myConfigLock   func()
+	myConfigUnlock func()
+	myLogLevel     *int32
+	myBufferSize   *uintptr
+)
+
+func bindConfig(h uintptr) {
+	optional(&myConfigLock, h, "myConfigLock")
+	optional(&myConfigUnlock, h, "myConfigUnlock")
+	if addr, err := purego.Dlsym(h, "myLogLevel"); err == nil {
+		myLogLevel = *(**int32)(unsafe.Pointer(&addr))
+	}
+	if addr, err := purego.Dlsym(h, "myBufferSize"); err == nil {
+		myBufferSize = *(**uintptr)(unsafe.Pointer(&addr))
+	}
+}
+
+// withConfig calls f with the addresses of the C library's configuration
+// variables, holding its lock.
+func withConfig(f func(level *int32, size *uintptr)) {
+	runtime.LockOSThread()
+	defer runtime.UnlockOSThread()
+	myConfigLock()
+	defer myConfigUnlock()
+	f(myLogLevel, myBufferSize)
+}
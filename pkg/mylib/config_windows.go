@@ -0,0 +1,27 @@
+package mylib
+
+import (
+	"runtime"
+
+	"golang.org/x/sys/windows"
+)
+
+// The configuration variables and their lock. GetProcAddress returns a
+// variable's address as it does a function's; mylib.def exports the
+// variables as DATA.
+var (
+	procConfigLock   *windows.LazyProc
+	procConfigUnlock *windows.LazyProc
+	procLogLevel     *windows.LazyProc
+	procBufferSize   *windows.LazyProc
+)
+
+// withConfig calls f with the addresses of the C library's configuration
+// variables, holding its lock.
+func withConfig(f func(level *int32, size *uintptr)) {
+	runtime.LockOSThread()
+	defer runtime.UnlockOSThread()
+	procConfigLock.Call()
+	defer procConfigUnlock.Call()
+	f((*int32)(cptr(procLogLevel.Addr())), (*uintptr)(cptr(procBufferSize.Addr())))
+}
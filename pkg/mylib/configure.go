@@ -0,0 +1,69 @@
+package mylib
+
+import (
+	"fmt"
+
+	"github.com/lxwagn/using-go-with-c-libraries/pkg/features"
+)
+
+// A Config is the C library's configuration, which it keeps in the global
+// variables myLogLevel and myBufferSize. ReadConfig, SetLogLevel and
+// SetBufferSize read and write those variables in place.
+//
+// Each access holds the library's myConfigLock, which its own threads
+// take to read the variables: a plain load or store from Go would race
+// with them, and could be seen late on another core. The lock has to be
+// released on the thread that took it, so the goroutine stays locked to
+// its thread in between.
+type Config struct {
+	// LogLevel is the level below which Logf prints nothing. Logf logs
+	// at LogLevelInfo.
+	LogLevel LogLevel
+
+	// BufferSize is the capacity in bytes of a buffer's first
+	// allocation.
+	BufferSize int
+}
+
+// ReadConfig returns the library's configuration, both variables read
+// under one hold of the lock.
+func ReadConfig() (Config, error) {
+	if err := require(features.Config); err != nil {
+		return Config{}, err
+	}
+	var c Config
+	withConfig(func(level *int32, size *uintptr) {
+		c = Config{LogLevel: LogLevel(*level), BufferSize: int(*size)}
+	})
+	return c, nil
+}
+
+// SetLogLevel sets the library's log level.
+func SetLogLevel(l LogLevel) error {
+	if err := require(features.Config); err != nil {
+		return err
+	}
+	if !l.IsValid() {
+		return fmt.Errorf("mylib: set log level %v: %w", l, ErrInvalid)
+	}
+	withConfig(func(level *int32, _ *uintptr) {
+		*level = int32(l)
+	})
+	return nil
+}
+
+// SetBufferSize sets the capacity of a buffer's first allocation to n
+// bytes, which must be positive. Buffers that have allocated keep their
+// capacity.
+func SetBufferSize(n int) error {
+	if err := require(features.Config); err != nil {
+		return err
+	}
+	if n < 1 {
+		return fmt.Errorf("mylib: set buffer size %d: %w", n, ErrInvalid)
+	}
+	withConfig(func(_ *int32, size *uintptr) {
+		*size = uintptr(n)
+	})
+	return nil
+}
@@ -0,0 +1,39 @@
+//go:build !nocgo && !windows
+
+package mylib
+
+/*
+
+#include "mylib.h"
+
+*/
+import "C"
+
+import "github.com/lxwagn/using-go-with-c-libraries/pkg/features"
+
+// FlushCoverage writes the line counts of a C library built for coverage
+// (cd src; make coverage) to its .gcda file, and reports whether it was
+// built that way. The library writes them itself only when the process
+// calls C's exit, which a Go program never does, so call FlushCoverage
+// before exiting; in a package's tests, from TestMain, as this package's
+// own tests do:
+//
+//	func TestMain(m *testing.M) {
+//		code := m.Run()
+//		mylib.FlushCoverage()
+//		os.Exit(code)
+//	}
+//
+// Each call adds the counts since the one before, so calling it more
+// than once counts nothing twice.
+func FlushCoverage() (bool, error) {
+	if err := require(features.Coverage); err != nil {
+		return false, err
+	}
+	var flushed bool
+	err := callC(func() error {
+		flushed = C.myCoverageFlush() != 0
+		return nil
+	})
+	return flushed, err
+}
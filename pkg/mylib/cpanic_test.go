@@ -0,0 +1,98 @@
+//go:build cgo && !nocgo && !windows
+
+package mylib
+
+import (
+	"context"
+	"errors"
+	"runtime"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/lxwagn/using-go-with-c-libraries/pkg/handles"
+)
+
+// The barriered trampolines of the cgo build, each with a callback that
+// panics: the panic comes back as a *cpanic.Panic from the wrapper, with
+// the stack it was raised on, and the library works afterwards.
+
+func TestCallNPanicStack(t *testing.T) {
+	p := catchPanic(t, func() {
+		CallN(2, func(int) int { panic("boom") })
+	})
+	if !strings.Contains(string(p.Stack), "goCallbackTrampoline") {
+		t.Errorf("recorded stack does not go through the trampoline:\n%s", p.Stack)
+	}
+}
+
+func TestSortPanic(t *testing.T) {
+	s := []int32{5, 1, 4, 2, 3}
+	var none []int32
+	calls := 0
+	p := catchPanic(t, func() {
+		Sort(s, func(a, b int32) int {
+			calls++
+			return int(none[a] - b)
+		})
+	})
+	var re runtime.Error
+	if !errors.As(p, &re) {
+		t.Errorf("recovered %v, want a runtime.Error", p.Value)
+	}
+	if calls != 1 {
+		t.Errorf("cmp called %d times, want once: comparisons after a panic are skipped", calls)
+	}
+	if !strings.Contains(string(p.Stack), "goCompareTrampoline") {
+		t.Errorf("recorded stack does not go through the trampoline:\n%s", p.Stack)
+	}
+	if err := Sort(s, func(a, b int32) int { return int(a - b) }); err != nil {
+		t.Fatal(err)
+	}
+	if want := []int32{1, 2, 3, 4, 5}; !slices.Equal(s, want) {
+		t.Errorf("Sort after the panic = %v, want %v", s, want)
+	}
+}
+
+func TestCrunchProgressPanic(t *testing.T) {
+	reports := 0
+	errReport := errors.New("report failed")
+	p := catchPanic(t, func() {
+		CrunchProgress(context.Background(), 1<<20, func(float64) {
+			reports++
+			panic(errReport)
+		})
+	})
+	if reports != 1 || !errors.Is(p, errReport) {
+		t.Errorf("panic %v after %d reports, want report failed after 1", p.Value, reports)
+	}
+	if !strings.Contains(string(p.Stack), "goProgressTrampoline") {
+		t.Errorf("recorded stack does not go through the trampoline:\n%s", p.Stack)
+	}
+	if _, err := CrunchProgress(context.Background(), 1000, func(float64) {}); err != nil {
+		t.Errorf("CrunchProgress after the panic: %v", err)
+	}
+}
+
+type panickingWriter struct{}
+
+func (panickingWriter) Write([]byte) (int, error) { panic("no room") }
+
+// A panicking io.Writer behind a cstdio FILE fails the C write instead
+// of unwinding stdio, and comes back from WriteReport once the FILE is
+// closed.
+func TestWriteReportPanic(t *testing.T) {
+	live := handles.Live()
+	p := catchPanic(t, func() {
+		WriteReport(panickingWriter{}, "doomed", []int64{1, 2, 3})
+	})
+	if p.Value != "no room" {
+		t.Errorf("recovered %v, want no room", p.Value)
+	}
+	if !strings.Contains(string(p.Stack), "cstdioWrite") {
+		t.Errorf("recorded stack does not go through the trampoline:\n%s", p.Stack)
+	}
+	if got := handles.Live(); got != live {
+		t.Errorf("%d handles live after the panic, want %d", got, live)
+	}
+}
@@ -0,0 +1,35 @@
+//go:build !cgo && !nocgo && !windows
+
+package mylib
+
+import "context"
+
+// Crunch runs iterations rounds of the computation of the C library's
+// myCrunch and returns its result. If ctx is done before or during the
+// call, Crunch returns ctx.Err(). Like myCrunch, it checks every 4096
+// rounds.
+func Crunch(ctx context.Context, iterations int64) (int64, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+	if iterations < 0 {
+		return 0, codes.Error("myCrunch", int(StatusInvalid))
+	}
+
+	done := ctx.Done()
+	x := uint64(88172645463325252)
+	for i := range iterations {
+		if i&0xfff == 0 {
+			select {
+			case <-done:
+				return 0, ctx.Err()
+			default:
+			}
+		}
+		// xorshift64
+		x ^= x << 13
+		x ^= x >> 7
+		x ^= x << 17
+	}
+	return int64(x >> 1), nil
+}
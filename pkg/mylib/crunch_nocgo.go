@@ -0,0 +1,46 @@
+//go:build nocgo && !windows
+
+package mylib
+
+import (
+	"context"
+	"sync/atomic"
+	"unsafe"
+)
+
+// Crunch runs iterations rounds of the C library's long-running myCrunch
+// and returns its result. If ctx is done before or during the call,
+// Crunch returns ctx.Err().
+//
+// A goroutine cannot be stopped while it is in C; the C code has to stop
+// itself. myCrunch polls a flag Crunch passes to it, and a function
+// registered with context.AfterFunc sets the flag when ctx is done. The
+// call holds the library lock until it returns.
+func Crunch(ctx context.Context, iterations int64) (int64, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+	if err := load(); err != nil {
+		return 0, err
+	}
+
+	// The flag is Go memory: C may only use it during the call, which is
+	// exactly how long the AfterFunc may write it. Both sides access it
+	// atomically.
+	var cancel atomic.Int32
+	stop := context.AfterFunc(ctx, func() { cancel.Store(1) })
+	defer stop()
+
+	var result int64
+	err := callC(func() error {
+		rc := myCrunch(iterations, (*int32)(unsafe.Pointer(&cancel)), &result)
+		if Status(rc) == StatusCanceled {
+			return ctx.Err()
+		}
+		return codes.Error("myCrunch", int(rc))
+	})
+	if err != nil {
+		return 0, err
+	}
+	return result, nil
+}
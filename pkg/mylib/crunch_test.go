@@ -0,0 +1,59 @@
+package mylib
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+// forever is more iterations of myCrunch than any test waits for.
+const forever = 1 << 50
+
+func TestCrunch(t *testing.T) {
+	a, err := Crunch(context.Background(), 1_000_000)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := Crunch(context.Background(), 1_000_000)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if a != b {
+		t.Errorf("two runs gave %d and %d", a, b)
+	}
+}
+
+func TestCrunchCancelBeforeStart(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	start := time.Now()
+	if _, err := Crunch(ctx, forever); !errors.Is(err, context.Canceled) {
+		t.Errorf("err = %v, want context.Canceled", err)
+	}
+	if d := time.Since(start); d > 100*time.Millisecond {
+		t.Errorf("took %v to notice a context canceled up front", d)
+	}
+}
+
+func TestCrunchCancelMidFlight(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(50*time.Millisecond, cancel)
+	start := time.Now()
+	if _, err := Crunch(ctx, forever); !errors.Is(err, context.Canceled) {
+		t.Errorf("err = %v, want context.Canceled", err)
+	}
+	d := time.Since(start)
+	t.Logf("stopped %v after starting", d)
+	if d < 50*time.Millisecond || d > time.Second {
+		t.Errorf("stopped after %v, want shortly after 50ms", d)
+	}
+}
+
+func TestCrunchDeadline(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if _, err := Crunch(ctx, forever); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("err = %v, want context.DeadlineExceeded", err)
+	}
+}
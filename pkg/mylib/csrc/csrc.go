@@ -0,0 +1,14 @@
+//go:build cgo
+
+// Package csrc compiles libmylib from source: cgo builds the copy of
+// src/mylib.c in this directory along with the package, and any program
+// importing it links the result. pkg/mylib imports it when built with
+// -tags mylib_source instead of linking libmylib.so.
+//
+// The files here are generated by go generate in pkg/mylib; edit src.
+package csrc
+
+// #cgo CFLAGS: -pthread
+// #cgo LDFLAGS: -pthread
+// #cgo linux,!android LDFLAGS: -lrt
+import "C"
@@ -0,0 +1,288 @@
+//go:build !nocgo && !windows
+
+#include <errno.h>
+#include <limits.h>
+#include <stdarg.h>
+#include <stdio.h>
+#include <stdlib.h>
+#include <string.h>
+#include <wchar.h>
+
+#include "mylib.h"
+#include "ctest.h"
+#include "_cgo_export.h"
+
+void ctestErrorf(ctestT t, const char *file, int line, const char *format, ...) {
+	char msg[512];
+	va_list ap;
+
+	va_start(ap, format);
+	vsnprintf(msg, sizeof(msg), format, ap);
+	va_end(ap);
+	goCtestFail(t, (char *)file, line, msg);
+}
+
+static void testVersion(ctestT t) {
+	CHECK_INT(t, myVersion(),
+		  MYLIB_VERSION_MAJOR * 10000 + MYLIB_VERSION_MINOR * 100 + MYLIB_VERSION_PATCH);
+}
+
+static void testLookup(ctestT t) {
+	int v = -1;
+
+	CHECK_INT(t, myLookup("two", &v), MYLIB_OK);
+	CHECK_INT(t, v, 2);
+	v = -1;
+	CHECK_INT(t, myLookup("four", &v), MYLIB_ENOTFOUND);
+	CHECK_INT(t, v, -1);
+	CHECK_INT(t, myLookup(NULL, &v), MYLIB_EINVAL);
+	CHECK_INT(t, myLookup("one", NULL), MYLIB_EINVAL);
+}
+
+static void testChecksum(ctestT t) {
+	const char *s = "Wikipedia";
+
+	/* The Adler-32 example from Wikipedia. */
+	CHECK_INT(t, myChecksum((const unsigned char *)s, strlen(s)), 0x11E60398);
+	CHECK_INT(t, myChecksum(NULL, 0), 1);
+}
+
+static void testFill(ctestT t) {
+	unsigned char buf[10];
+	int i;
+
+	memset(buf, 0, sizeof(buf));
+	myFill(buf, sizeof(buf), 250);
+	for (i = 0; i < 10; i++)
+		CHECK_INT(t, buf[i], (250 + i) & 0xff);
+}
+
+static void testSession(ctestT t) {
+	mySession *s;
+	long long total = -1;
+	int calls = -1;
+
+	errno = 0;
+	CHECK(t, mySessionNew(NULL, 10) == NULL);
+	CHECK_INT(t, errno, EINVAL);
+	CHECK(t, mySessionNew("s", -1) == NULL);
+
+	s = mySessionNew("steps", 10);
+	REQUIRE(t, s != NULL);
+	CHECK_STR(t, mySessionName(s), "steps");
+	CHECK_INT(t, mySessionAdd(s, 8, &total), MYLIB_OK);
+	CHECK_INT(t, total, 8);
+	CHECK_INT(t, mySessionAdd(s, 5, &total), MYLIB_ERANGE);
+	CHECK_INT(t, mySessionAdd(s, LLONG_MIN, &total), MYLIB_ERANGE);
+	CHECK_INT(t, mySessionStats(s, &total, &calls), MYLIB_OK);
+	CHECK_INT(t, total, 8);
+	CHECK_INT(t, calls, 1);
+	CHECK_INT(t, mySessionReset(s), MYLIB_OK);
+	CHECK_INT(t, mySessionStats(s, &total, &calls), MYLIB_OK);
+	CHECK_INT(t, total, 0);
+	CHECK_INT(t, calls, 0);
+	mySessionFree(s);
+	CHECK_INT(t, mySessionAdd(NULL, 1, &total), MYLIB_EINVAL);
+}
+
+static void testBuffer(ctestT t) {
+	int live = myBufferLive();
+	myBuffer *b;
+	int i;
+
+	b = myBufferNew();
+	REQUIRE(t, b != NULL);
+	CHECK_INT(t, myBufferLive(), live + 1);
+	CHECK_STR(t, myBufferData(b), "");
+	/* Past the initial capacity of 16, so the buffer has to grow. */
+	for (i = 0; i < 10; i++)
+		CHECK_INT(t, myBufferAppend(b, "abc"), MYLIB_OK);
+	CHECK_INT(t, myBufferLen(b), 30);
+	CHECK_STR(t, myBufferData(b) + 27, "abc");
+	CHECK_INT(t, myBufferAppend(b, NULL), MYLIB_EINVAL);
+	myBufferFree(b);
+	CHECK_INT(t, myBufferLive(), live);
+}
+
+static int square(void *userdata, int value) {
+	(*(int *)userdata)++;
+	return value * value;
+}
+
+static void testCallN(ctestT t) {
+	int calls = 0;
+
+	CHECK_INT(t, myCallN(square, &calls, 10), 285);
+	CHECK_INT(t, calls, 10);
+	CHECK_INT(t, myCallN(square, &calls, 0), 0);
+	CHECK_INT(t, calls, 10);
+}
+
+static void testCrunch(ctestT t) {
+	long long a = 0, b = 0;
+	int cancel = 1;
+
+	CHECK_INT(t, myCrunch(100000, NULL, &a), MYLIB_OK);
+	CHECK_INT(t, myCrunch(100000, NULL, &b), MYLIB_OK);
+	CHECK_INT(t, a, b);
+	CHECK_INT(t, myCrunch(100000, &cancel, &a), MYLIB_ECANCELED);
+	CHECK_INT(t, myCrunch(-1, NULL, &a), MYLIB_EINVAL);
+	CHECK_INT(t, myCrunch(1, NULL, NULL), MYLIB_EINVAL);
+}
+
+static void testReducers(ctestT t) {
+	int values[] = {3, -2, 9};
+	myReducer sum = myGetReducer("sum"), min = myGetReducer("min"), max = myGetReducer("max");
+
+	REQUIRE(t, sum != NULL && min != NULL && max != NULL);
+	CHECK_INT(t, sum(values, 3), 10);
+	CHECK_INT(t, min(values, 3), -2);
+	CHECK_INT(t, max(values, 3), 9);
+	CHECK_INT(t, sum(values, 0), 0);
+	CHECK_INT(t, min(values, 0), LLONG_MAX);
+	CHECK_INT(t, max(values, 0), LLONG_MIN);
+	CHECK(t, myGetReducer("mean") == NULL);
+	CHECK(t, myGetReducer(NULL) == NULL);
+}
+
+static void testBatch(ctestT t) {
+	struct myRequest reqs[] = {
+		{.op = MYLIB_OP_COUNTER_ADD, .arg = 5},
+		{.op = MYLIB_OP_LOOKUP, .key = "three"},
+		{.op = MYLIB_OP_LOOKUP, .key = "zero"},
+		{.op = MYLIB_OP_COUNTER_ADD, .arg = (long long)INT_MAX + 1},
+		{.op = 99},
+	};
+	int before = myCounterAdd(0);
+
+	CHECK_INT(t, myBatch(reqs, 5), 3);
+	CHECK_INT(t, reqs[0].status, MYLIB_OK);
+	CHECK_INT(t, reqs[0].result, before + 5);
+	CHECK_INT(t, reqs[1].status, MYLIB_OK);
+	CHECK_INT(t, reqs[1].result, 3);
+	CHECK_INT(t, reqs[2].status, MYLIB_ENOTFOUND);
+	CHECK_INT(t, reqs[3].status, MYLIB_ERANGE);
+	CHECK_INT(t, reqs[4].status, MYLIB_EINVAL);
+	/* Leave the global counter as the binding's callers last saw it. */
+	myCounterAdd(-5);
+}
+
+static void testSplitWords(ctestT t) {
+	struct myWord *list = NULL;
+
+	CHECK_INT(t, mySplitWords("  go  cgo ", &list), MYLIB_OK);
+	REQUIRE(t, list != NULL && list->next != NULL);
+	CHECK_STR(t, list->text, "go");
+	CHECK_INT(t, list->offset, 2);
+	CHECK_STR(t, list->next->text, "cgo");
+	CHECK_INT(t, list->next->offset, 6);
+	CHECK(t, list->next->next == NULL);
+	myWordsFree(list);
+
+	CHECK_INT(t, mySplitWords("   ", &list), MYLIB_OK);
+	CHECK(t, list == NULL);
+	CHECK_INT(t, mySplitWords(NULL, &list), MYLIB_EINVAL);
+}
+
+static void testWide(ctestT t) {
+	wchar_t s[] = L"héllo";
+
+	CHECK_INT(t, myWideCount(s), 5);
+	CHECK_INT(t, myWideReverse(s), MYLIB_OK);
+	CHECK(t, wcscmp(s, L"olléh") == 0);
+	CHECK_INT(t, myWideCount(NULL), -1);
+	CHECK_INT(t, myWideReverse(NULL), MYLIB_EINVAL);
+}
+
+/* parse returns 1 and stores the value if myParseRecord returns, and 0 if
+ * it jumps. */
+static int parse(myParser *p, const char *record, long long *value) {
+	if (setjmp(*myParserJmpbuf(p)) != 0)
+		return 0;
+	*value = myParseRecord(p, record);
+	return 1;
+}
+
+static void testParse(ctestT t) {
+	myParser *p = myParserNew();
+	long long v = -1;
+
+	REQUIRE(t, p != NULL);
+	CHECK_INT(t, parse(p, "answer=42", &v), 1);
+	CHECK_INT(t, v, 42);
+	v = -1;
+	CHECK_INT(t, parse(p, "answer", &v), 0);
+	CHECK_STR(t, myParserError(p), "missing '='");
+	CHECK_INT(t, v, -1);
+	CHECK_INT(t, parse(p, "n=99999999999999999999", &v), 0);
+	CHECK_STR(t, myParserError(p), "value out of range");
+	/* The parser stays usable after a jump. */
+	CHECK_INT(t, parse(p, "n=9223372036854775807", &v), 1);
+	CHECK_INT(t, v, LLONG_MAX);
+	myParserFree(p);
+}
+
+static int allocCalls[3];
+
+static void *testMalloc(size_t n) {
+	allocCalls[0]++;
+	return malloc(n);
+}
+
+static void *testRealloc(void *p, size_t n) {
+	allocCalls[1]++;
+	return realloc(p, n);
+}
+
+static void testFree(void *p) {
+	allocCalls[2]++;
+	free(p);
+}
+
+static void testAllocator(ctestT t) {
+	long long live = myAllocations();
+	myBuffer *b;
+
+	CHECK_INT(t, mySetAllocator(testMalloc, NULL, NULL), MYLIB_EINVAL);
+	b = myBufferNew();
+	REQUIRE(t, b != NULL);
+	CHECK_INT(t, myBufferAppend(b, "abc"), MYLIB_OK);
+	/* The struct, and the data realloc allocated from NULL. */
+	CHECK_INT(t, myAllocations(), live + 2);
+	CHECK_INT(t, mySetAllocator(testMalloc, testRealloc, testFree), MYLIB_EINVAL);
+	myBufferFree(b);
+	CHECK_INT(t, myAllocations(), live);
+	if (live != 0)
+		return; /* the Go side holds some; the allocator cannot change */
+
+	REQUIRE(t, mySetAllocator(testMalloc, testRealloc, testFree) == MYLIB_OK);
+	b = myBufferNew();
+	if (b != NULL) {
+		myBufferAppend(b, "abc");
+		myBufferFree(b);
+	}
+	CHECK_INT(t, mySetAllocator(NULL, NULL, NULL), MYLIB_OK);
+	REQUIRE(t, b != NULL);
+	CHECK_INT(t, allocCalls[0], 1);
+	CHECK_INT(t, allocCalls[1], 1);
+	CHECK_INT(t, allocCalls[2], 2);
+}
+
+const struct ctestCase ctestCases[] = {
+	{"version", testVersion},
+	{"lookup", testLookup},
+	{"checksum", testChecksum},
+	{"fill", testFill},
+	{"session", testSession},
+	{"buffer", testBuffer},
+	{"calln", testCallN},
+	{"crunch", testCrunch},
+	{"reducers", testReducers},
+	{"batch", testBatch},
+	{"splitwords", testSplitWords},
+	{"wide", testWide},
+	{"parse", testParse},
+	{"allocator", testAllocator},
+};
+
+const int ctestNumCases = sizeof(ctestCases) / sizeof(ctestCases[0]);
@@ -0,0 +1,61 @@
+//go:build !nocgo && !windows
+
+package ctest
+
+/*
+#cgo pkg-config: mylib
+#include "ctest.h"
+
+static const char *caseName(int i) {
+	return ctestCases[i].name;
+}
+
+static void runCase(int i, ctestT t) {
+	ctestCases[i].run(t);
+}
+*/
+import "C"
+
+import (
+	"github.com/lxwagn/using-go-with-c-libraries/pkg/cpanic"
+	"github.com/lxwagn/using-go-with-c-libraries/pkg/handles"
+)
+
+// A Reporter receives the failures of a C test. *testing.T is one.
+type Reporter interface {
+	Errorf(format string, args ...any)
+}
+
+// run is what the handle of a test in progress refers to.
+type run struct {
+	r       Reporter
+	barrier cpanic.Barrier
+}
+
+// A Case is one of the C tests.
+type Case struct {
+	Name string
+	i    C.int
+}
+
+// Cases returns the C tests, in the order ctest.c lists them.
+func Cases() []Case {
+	cases := make([]Case, C.ctestNumCases)
+	for i := range cases {
+		cases[i] = Case{Name: C.GoString(C.caseName(C.int(i))), i: C.int(i)}
+	}
+	return cases
+}
+
+// Run runs the test, reporting each failed check to r as it happens. The
+// test runs to completion on the calling goroutine, since a Reporter's
+// FailNow could not unwind the C frames. If r panics, the failures after
+// it are not reported, and Run raises the panic again, as a
+// *cpanic.Panic, once the test has returned.
+func (c Case) Run(r Reporter) {
+	st := &run{r: r}
+	h := handles.New(st)
+	defer h.Delete()
+	C.runCase(c.i, C.ctestT(h.Uintptr()))
+	st.barrier.Repanic()
+}
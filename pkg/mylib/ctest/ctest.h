@@ -0,0 +1,56 @@
+#ifndef CTEST_H
+#define CTEST_H
+
+#include <stdint.h>
+#include <string.h>
+
+/* ctestT identifies the Go Reporter a test reports to. */
+typedef uintptr_t ctestT;
+
+/* ctestErrorf reports a failure at file:line, formatted like printf. */
+void ctestErrorf(ctestT t, const char *file, int line, const char *format, ...)
+	__attribute__((format(printf, 4, 5)));
+
+/* CHECK reports a failure if cond is false and carries on; REQUIRE also
+ * returns from the test, for when the rest of it depends on cond. */
+#define CHECK(t, cond)                                                        \
+	do {                                                                  \
+		if (!(cond))                                                  \
+			ctestErrorf((t), __FILE__, __LINE__, "%s", #cond);    \
+	} while (0)
+
+#define REQUIRE(t, cond)                                                      \
+	do {                                                                  \
+		if (!(cond)) {                                                \
+			ctestErrorf((t), __FILE__, __LINE__, "%s", #cond);    \
+			return;                                               \
+		}                                                             \
+	} while (0)
+
+/* CHECK_INT and CHECK_STR also report both values. */
+#define CHECK_INT(t, got, want)                                               \
+	do {                                                                  \
+		long long got_ = (got), want_ = (want);                       \
+		if (got_ != want_)                                            \
+			ctestErrorf((t), __FILE__, __LINE__,                  \
+				    "%s = %lld, want %lld", #got, got_, want_); \
+	} while (0)
+
+#define CHECK_STR(t, got, want)                                               \
+	do {                                                                  \
+		const char *got_ = (got), *want_ = (want);                    \
+		if (got_ == NULL || strcmp(got_, want_) != 0)                 \
+			ctestErrorf((t), __FILE__, __LINE__,                  \
+				    "%s = \"%s\", want \"%s\"", #got,         \
+				    got_ ? got_ : "(null)", want_);           \
+	} while (0)
+
+struct ctestCase {
+	const char *name;
+	void (*run)(ctestT t);
+};
+
+extern const struct ctestCase ctestCases[];
+extern const int ctestNumCases;
+
+#endif
@@ -0,0 +1,26 @@
+//go:build !nocgo && !windows
+
+package ctest
+
+/*
+#include "ctest.h"
+*/
+import "C"
+
+import (
+	"path/filepath"
+
+	"github.com/lxwagn/using-go-with-c-libraries/pkg/cpanic"
+	"github.com/lxwagn/using-go-with-c-libraries/pkg/handles"
+)
+
+//export goCtestFail
+func goCtestFail(t C.ctestT, file *C.char, line C.int, msg *C.char) {
+	st, err := handles.FromUintptr[*run](uintptr(t)).Get()
+	if err != nil {
+		return
+	}
+	cpanic.Do(&st.barrier, func() {
+		st.r.Errorf("%s:%d: %s", filepath.Base(C.GoString(file)), int(line), C.GoString(msg))
+	})
+}
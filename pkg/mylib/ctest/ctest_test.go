@@ -0,0 +1,32 @@
+//go:build cgo && !nocgo && !windows
+
+package ctest
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"testing"
+
+	"github.com/lxwagn/using-go-with-c-libraries/pkg/mylib"
+)
+
+// TestMain writes the C library's line counts after the C tests, which
+// are most of what make coverage counts in C.
+func TestMain(m *testing.M) {
+	code := m.Run()
+	if _, err := mylib.FlushCoverage(); err != nil && !errors.Is(err, errors.ErrUnsupported) {
+		fmt.Fprintln(os.Stderr, err)
+		code = 1
+	}
+	os.Exit(code)
+}
+
+// TestC runs every C test as a subtest.
+func TestC(t *testing.T) {
+	for _, c := range Cases() {
+		t.Run(c.Name, func(t *testing.T) {
+			c.Run(t)
+		})
+	}
+}
@@ -0,0 +1,15 @@
+// Package ctest holds unit tests of libmylib written in C, which call the
+// library directly and report their failures to Go.
+//
+// The tests are C functions in ctest.c. A failed CHECK or REQUIRE in one
+// of them calls back into Go with the C file and line, and Case.Run passes
+// that on to a Reporter, such as a *testing.T. TestC, in this package's
+// own tests, runs each of them as a subtest under go test, and
+// cmd/selfcheck runs them as its ctest checks. Nothing in the binding
+// imports the package. Its C code links libmylib through pkg-config, as
+// pkg/mylib/raw does, and calls it without pkg/mylib's call guard, so run
+// the tests while nothing else uses the library.
+//
+// A new test is a function taking the ctestT and an entry in the cases
+// table at the end of ctest.c.
+package ctest
@@ -0,0 +1,50 @@
+//go:build cgo && !nocgo && !windows
+
+package mylib
+
+import (
+	"errors"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/lxwagn/using-go-with-c-libraries/pkg/ctime"
+)
+
+func TestFormatTime(t *testing.T) {
+	tm := time.Date(1969, 7, 20, 20, 17, 40, 500_000_000, time.UTC)
+	if s, err := FormatTime(tm); err != nil || s != "1969-07-20T20:17:40Z" {
+		t.Errorf("FormatTime(%v) = %q, %v", tm, s, err)
+	}
+	if _, err := FormatTime(time.Date(10000, 1, 1, 0, 0, 0, 0, time.UTC)); !errors.Is(err, ErrRange) {
+		t.Errorf("FormatTime of the year 10000: %v, want ErrRange", err)
+	}
+}
+
+func TestTimespecAdd(t *testing.T) {
+	tm := time.Date(1969, 7, 20, 20, 17, 40, 500_000_000, time.UTC)
+	// Adding -0.75s carries into the seconds, in C.
+	if got, err := TimespecAdd(tm, -750*time.Millisecond); err != nil || !got.Equal(tm.Add(-750*time.Millisecond)) {
+		t.Errorf("TimespecAdd(%v, -750ms) = %v, %v", tm, got, err)
+	}
+	if ctime.TimeT.Bits == 64 {
+		end := time.Unix(math.MaxInt64, 0)
+		if _, err := TimespecAdd(end, time.Second); !errors.Is(err, ErrRange) {
+			t.Errorf("TimespecAdd past the end of time_t: %v, want ErrRange", err)
+		}
+	}
+}
+
+func TestTimevalSub(t *testing.T) {
+	end := time.Date(1969, 7, 20, 20, 17, 40, 500_000_000, time.UTC)
+	start := end.Add(-90 * time.Minute)
+	if d, err := TimevalSub(end, start); err != nil || d != 90*time.Minute {
+		t.Errorf("TimevalSub = %v, %v, want 1h30m", d, err)
+	}
+	if d, err := TimevalSub(start, end); err != nil || d != -90*time.Minute {
+		t.Errorf("TimevalSub = %v, %v, want -1h30m", d, err)
+	}
+	if _, err := TimevalSub(time.Unix(1<<40, 0), time.Unix(-1<<40, 0)); !errors.Is(err, ErrRange) {
+		t.Errorf("TimevalSub over 70000 years: %v, want ErrRange", err)
+	}
+}
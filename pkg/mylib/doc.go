@@ -0,0 +1,20 @@
+// Package mylib is a Go binding for the C library in ./src.
+//
+// All cgo details (C strings, unsafe pointers, freeing memory) are kept
+// inside this package, so callers only deal with Go strings and errors.
+//
+// Building with -tags nocgo selects an implementation that loads the
+// library at run time with purego instead, so the package can be used
+// with CGO_ENABLED=0. On Windows the package loads mylib.dll with
+// LoadLibrary and GetProcAddress, passing text to the library as UTF-16.
+//
+// With CGO_ENABLED=0 and without the tag, the package falls back to Go:
+// the simple functions are reimplemented in Go, giving the C library's
+// results, and the rest return ErrNotSupported. A module importing the
+// package then still builds and tests without a C toolchain, although
+// nothing in that build calls C.
+//
+// All implementations share the core API. Examples that are about cgo
+// itself, such as the union and bitfield accessors, are only in the cgo
+// build.
+package mylib
@@ -0,0 +1,81 @@
+package mylib
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/lxwagn/using-go-with-c-libraries/internal/status"
+)
+
+// The library's enums, Status among them, are generated from mylib.h so
+// that every build can use them.
+//go:generate go run ../../cmd/cbindgen -header ../../src/mylib.h -enums -pkg mylib -trim my -trim-define MYLIB_ -o enums.go
+
+// So are the constants among its #defines. macros.go wraps the
+// function-like macros for the cgo build.
+//go:generate go run ../../cmd/cbindgen -header ../../src/mylib.h -defines -pkg mylib -trim-define MYLIB_ -o defines.go
+
+// Static assertions that the library lays out the structs raw.go mirrors
+// as Go would, included by mylib.c.
+//go:generate go run ../../cmd/cbindgen -header ../../src/mylib.h -layout -trim-define MYLIB_ -o ../../src/mylib_layout.h
+
+// A copy of the library's source, for builds with -tags mylib_source (see
+// link_source.go).
+//go:generate go run ../../cmd/vendorc -o csrc ../../src/mylib.c ../../src/mylib.h ../../src/mylib_layout.h
+
+// The exports of mylib.dll, for building it with MSVC (see the
+// windows-msvc target in src/Makefile) and for import libraries.
+//go:generate go run ../../cmd/cbindgen -header ../../src/mylib.h -def -D _WIN32 -D _MSC_VER -o ../../src/mylib.def
+
+// ErrNUL is returned when a string passed to the library contains a NUL
+// byte. C would silently stop reading at that byte.
+var ErrNUL = status.ErrNUL
+
+// ErrClosed is returned when a method is called on an object that has
+// already been closed.
+var ErrClosed = errors.New("mylib: use of closed object")
+
+// ErrNotSupported is returned by the few functions that the pure-Go
+// fallback, built with CGO_ENABLED=0 and without the nocgo tag, cannot
+// provide. It matches errors.ErrUnsupported.
+var ErrNotSupported = fmt.Errorf("mylib: not supported without cgo: %w", errors.ErrUnsupported)
+
+// ErrPoisoned is returned by calls on a Session after WithTimeout gave up
+// waiting for a call on it, which may still be running in C.
+var ErrPoisoned = errors.New("mylib: session poisoned by a call that timed out")
+
+// ErrShutdown is returned by Open once the library has been shut down.
+var ErrShutdown = errors.New("mylib: library is shut down")
+
+// ErrTimeout is returned by WithTimeout when the call does not return in
+// time. It matches context.DeadlineExceeded.
+var ErrTimeout = fmt.Errorf("mylib: call timed out: %w", context.DeadlineExceeded)
+
+// codes maps the library's status codes to the errors below. It is shared
+// with pkg/dynload, so the errors of both match the same sentinels.
+var codes = status.Codes
+
+// Errors returned by the C library. Check for them with errors.Is.
+var (
+	ErrNotFound = status.ErrNotFound
+	ErrInvalid  = status.ErrInvalid
+	ErrRange    = status.ErrRange
+	ErrNoMemory = status.ErrNoMemory
+)
+
+// StatusOf returns the status code behind err, which lets callers switch
+// over the library's failures:
+//
+//	switch st, _ := mylib.StatusOf(err); st {
+//	case mylib.StatusOK:
+//	case mylib.StatusNotFound:
+//	...
+//	}
+//
+// It returns StatusOK for a nil err, and false if err is not one of the
+// library's status errors.
+func StatusOf(err error) (Status, bool) {
+	st, ok := status.Of(err)
+	return Status(st), ok
+}
@@ -0,0 +1,36 @@
+package mylib
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/lxwagn/using-go-with-c-libraries/internal/status"
+)
+
+// TestStatusCodes checks the codes internal/status shares with
+// pkg/dynload against the Status constants generated from mylib.h, and
+// that each maps to its sentinel and back.
+func TestStatusCodes(t *testing.T) {
+	for _, tt := range []struct {
+		st     Status
+		shared int
+		err    error
+	}{
+		{StatusNotFound, status.NotFound, ErrNotFound},
+		{StatusInvalid, status.Invalid, ErrInvalid},
+		{StatusRange, status.Range, ErrRange},
+		// myBufferAppend's status when it cannot grow a buffer.
+		{StatusNoMemory, status.NoMemory, ErrNoMemory},
+	} {
+		if int(tt.st) != tt.shared {
+			t.Errorf("%v = %d, internal/status has %d", tt.st, int(tt.st), tt.shared)
+		}
+		err := codes.Error("op", int(tt.st))
+		if !errors.Is(err, tt.err) {
+			t.Errorf("errors.Is(%v, %v) = false", err, tt.err)
+		}
+		if st, ok := StatusOf(err); !ok || st != tt.st {
+			t.Errorf("StatusOf(%v) = %v, %t, want %v, true", err, st, ok, tt.st)
+		}
+	}
+}
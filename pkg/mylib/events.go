@@ -0,0 +1,196 @@
+//go:build !nocgo && !windows
+
+package mylib
+
+/*
+
+#include <stdint.h>
+#include "mylib.h"
+
+// Defined in events_export.go. Exported Go functions cannot take const
+// parameters, hence the cast below.
+extern void goEventTrampoline(void *userdata, struct myEvent *ev);
+
+static myEmitter *startEmitterGateway(long long count, int interval, uintptr_t handle) {
+	return myEmitterStart(count, interval, (myEventCallback)goEventTrampoline, (void *)handle);
+}
+
+*/
+import "C"
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/lxwagn/using-go-with-c-libraries/pkg/cnum"
+	"github.com/lxwagn/using-go-with-c-libraries/pkg/features"
+	"github.com/lxwagn/using-go-with-c-libraries/pkg/handles"
+)
+
+// An Event is one event from the C library's emitter thread.
+type Event struct {
+	Seq       int64 // number of events emitted before this one
+	Value     int
+	Coalesced int // earlier events this one replaced, under Coalesce
+}
+
+// A Backpressure policy decides what a Subscription does with an event
+// that arrives while its channel's buffer is full.
+type Backpressure int
+
+const (
+	// Block makes the C thread wait until the receiver makes room. No
+	// event is lost, but a slow receiver holds up the library.
+	Block Backpressure = iota
+
+	// Drop discards the new event and counts it in Dropped.
+	Drop
+
+	// Coalesce discards the oldest buffered event and lets the new one
+	// stand for it, counting it in the new event's Coalesced. It suits
+	// events that report a state, where only the latest one matters.
+	Coalesce
+)
+
+// A SubscribeOption configures a Subscription created by Subscribe.
+type SubscribeOption func(*subscribeConfig)
+
+type subscribeConfig struct {
+	buffer int
+	policy Backpressure
+}
+
+// EventBuffer sets the capacity of the Events channel, 64 by default. A
+// capacity below 1 counts as 1.
+func EventBuffer(n int) SubscribeOption {
+	return func(c *subscribeConfig) {
+		c.buffer = max(n, 1)
+	}
+}
+
+// OnFull sets what happens to an event that finds the Events channel
+// full. The default is Block.
+func OnFull(p Backpressure) SubscribeOption {
+	return func(c *subscribeConfig) {
+		c.policy = p
+	}
+}
+
+// A Subscription receives the events of an emitter thread in the C
+// library, which calls into Go for each one, and delivers them on a
+// channel.
+//
+// The callbacks run on the library's thread, which the runtime attaches
+// like the threads of a ThreadGroup. What they do when the channel is full
+// is the Subscription's backpressure policy.
+type Subscription struct {
+	events  chan Event
+	policy  Backpressure
+	stop    chan struct{} // closed by Close, to release a blocked callback
+	dropped atomic.Int64
+
+	emitter   *C.myEmitter
+	handle    handles.Handle[*Subscription]
+	closeOnce sync.Once
+}
+
+// Subscribe has the C library start a thread emitting count events, or
+// events until Close if count is 0, one per interval.
+func Subscribe(count int64, interval time.Duration, opts ...SubscribeOption) (*Subscription, error) {
+	if err := require(features.Events); err != nil {
+		return nil, err
+	}
+	if count < 0 || interval < 0 || !cnum.Fits[int32](interval.Microseconds()) {
+		return nil, codes.Error("myEmitterStart", int(StatusInvalid))
+	}
+	c := subscribeConfig{buffer: 64}
+	for _, opt := range opts {
+		opt(&c)
+	}
+	s := &Subscription{
+		events: make(chan Event, c.buffer),
+		policy: c.policy,
+		stop:   make(chan struct{}),
+	}
+	s.handle = handles.New(s)
+
+	err := callC(func() error {
+		var err error
+		if s.emitter, err = C.startEmitterGateway(C.longlong(count), C.int(interval.Microseconds()), C.uintptr_t(s.handle.Uintptr())); s.emitter == nil {
+			return lastError("myEmitterStart", err)
+		}
+		return nil
+	})
+	if err != nil {
+		s.handle.Delete()
+		return nil, err
+	}
+	return s, nil
+}
+
+// Events returns the channel the events are delivered on. It is closed
+// after the last event, or by Close; events already buffered then can
+// still be received.
+func (s *Subscription) Events() <-chan Event {
+	return s.events
+}
+
+// Dropped returns the number of events discarded under Drop.
+func (s *Subscription) Dropped() int64 {
+	return s.dropped.Load()
+}
+
+// deliver runs on the emitter thread, which is the only sender.
+func (s *Subscription) deliver(ev Event) {
+	switch s.policy {
+	case Drop:
+		select {
+		case s.events <- ev:
+		default:
+			s.dropped.Add(1)
+		}
+	case Coalesce:
+		for {
+			select {
+			case s.events <- ev:
+				return
+			default:
+			}
+			// Full: the receiver can only make more room, so this
+			// frees a slot unless it has just emptied one itself.
+			select {
+			case old := <-s.events:
+				ev.Coalesced += old.Coalesced + 1
+			default:
+			}
+		}
+	default:
+		select {
+		case s.events <- ev:
+		case <-s.stop:
+		}
+	}
+}
+
+// end runs on the emitter thread after its last callback.
+func (s *Subscription) end() {
+	close(s.events)
+}
+
+// Close stops the emitter thread and waits for it to finish, after which
+// Events is closed. It must be called even if the events have run out, to
+// free the emitter, and can be called more than once.
+func (s *Subscription) Close() error {
+	s.closeOnce.Do(func() {
+		close(s.stop)
+		// Stopping waits for a callback in progress, which the closed
+		// stop channel lets go, so it is not done under the library
+		// lock.
+		releaseCUnlocked(func() {
+			C.myEmitterStop(s.emitter)
+		})
+		s.handle.Delete()
+	})
+	return nil
+}
@@ -0,0 +1,28 @@
+//go:build !nocgo && !windows
+
+package mylib
+
+// #include "mylib.h"
+import "C"
+
+import (
+	"unsafe"
+
+	"github.com/lxwagn/using-go-with-c-libraries/pkg/handles"
+)
+
+// goEventTrampoline runs on the emitter thread, once per event and once
+// more with ev nil at the end.
+//
+//export goEventTrampoline
+func goEventTrampoline(userdata unsafe.Pointer, ev *C.struct_myEvent) {
+	s, err := handles.FromUintptr[*Subscription](uintptr(userdata)).Get()
+	if err != nil {
+		return
+	}
+	if ev == nil {
+		s.end()
+		return
+	}
+	s.deliver(Event{Seq: int64(ev.seq), Value: int(ev.value)})
+}
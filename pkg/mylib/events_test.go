@@ -0,0 +1,23 @@
+//go:build cgo && !nocgo && !windows
+
+package mylib
+
+import "testing"
+
+// TestCloseTraced checks that Close's call, which stops the emitter
+// without the library lock and from inside a sync.Once, is annotated
+// under Close's name.
+func TestCloseTraced(t *testing.T) {
+	s, err := Subscribe(0, 0, EventBuffer(1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	b := traceOf(t, TraceRegions, func() {
+		if err := s.Close(); err != nil {
+			t.Error(err)
+		}
+	})
+	if !hasString(b, "mylib.(*Subscription).Close") {
+		t.Error("no mylib.(*Subscription).Close region in the trace")
+	}
+}
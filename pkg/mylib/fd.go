@@ -0,0 +1,107 @@
+//go:build !nocgo && !windows
+
+package mylib
+
+/*
+
+#include "mylib.h"
+
+*/
+import "C"
+
+import (
+	"net"
+	"os"
+	"strings"
+	"unsafe"
+
+	"github.com/lxwagn/using-go-with-c-libraries/pkg/cfd"
+	"github.com/lxwagn/using-go-with-c-libraries/pkg/cmem"
+	"github.com/lxwagn/using-go-with-c-libraries/pkg/features"
+)
+
+// EchoConn returns a connection to a thread of the C library that writes
+// back whatever it reads, until the connection is closed. The descriptor
+// is the caller's, so the connection owns it and closing it is what ends
+// the C thread.
+func EchoConn() (net.Conn, error) {
+	if err := require(features.Descriptors); err != nil {
+		return nil, err
+	}
+
+	var fd C.int
+	err := callC(func() error {
+		var err error
+		if fd, err = C.myEchoOpen(); fd < 0 {
+			return lastError("myEchoOpen", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	f, err := cfd.Adopt(int(fd), "mylib-echo")
+	if err != nil {
+		return nil, err
+	}
+	return cfd.Conn(f)
+}
+
+// NotifyFile returns a file from which the lines passed to Notify can be
+// read. The library keeps its own pipe, so the file is a duplicate: close
+// it when done with it, and it reports end of file once NotifyClose has
+// closed the library's copy. Each call returns a new duplicate, all of
+// them reading from the same pipe.
+func NotifyFile() (*os.File, error) {
+	if err := require(features.Descriptors); err != nil {
+		return nil, err
+	}
+
+	var f *os.File
+	err := callC(func() error {
+		fd, err := C.myNotifyFd()
+		if fd < 0 {
+			return lastError("myNotifyFd", err)
+		}
+		// Duplicate under the guard, so that a concurrent NotifyClose
+		// cannot close fd, and another pipe take its number, in between.
+		f, err = cfd.Borrow(int(fd), "mylib-notify")
+		return err
+	})
+	return f, err
+}
+
+// Notify has the library write msg, which may not contain a newline, as a
+// line to its notification pipe. It returns an error matching ErrRange,
+// rather than blocking, if the pipe is full.
+func Notify(msg string) error {
+	if strings.IndexByte(msg, 0) >= 0 {
+		return ErrNUL
+	}
+	if strings.IndexByte(msg, '\n') >= 0 {
+		return ErrInvalid
+	}
+	if err := require(features.Descriptors); err != nil {
+		return err
+	}
+
+	cmsg := (*C.char)(cmem.CString(msg))
+	defer cmem.Free(unsafe.Pointer(cmsg))
+
+	return callC(func() error {
+		return codes.Error("myNotify", int(C.myNotify(cmsg)))
+	})
+}
+
+// NotifyClose closes the library's notification pipe. Files returned by
+// NotifyFile stay open, and read end of file once they have drained the
+// pipe; the next Notify or NotifyFile opens a new one.
+func NotifyClose() {
+	if require(features.Descriptors) != nil {
+		return
+	}
+
+	releaseC(func() {
+		C.myNotifyClose()
+	})
+}
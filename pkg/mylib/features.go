@@ -0,0 +1,22 @@
+package mylib
+
+import (
+	"sync"
+
+	"github.com/lxwagn/using-go-with-c-libraries/pkg/features"
+)
+
+var detected = sync.OnceValue(probe)
+
+// Features reports which optional parts of the C library, and which
+// version of it, the program found at run time. The library is probed
+// once, on first use. Wrappers of a missing feature return an error
+// matching errors.ErrUnsupported instead of calling into C.
+func Features() features.Set {
+	return detected()
+}
+
+// require returns an error unless the library provides f.
+func require(f features.Feature) error {
+	return Features().Require(f)
+}
@@ -0,0 +1,116 @@
+package mylib
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// ErrFormat is returned by Logf for a format string it cannot pass to C.
+var ErrFormat = errors.New("mylib: bad format")
+
+// maxLogArgs is the largest number of arguments Logf accepts: one
+// fixed-arity shim exists for each count up to it.
+const maxLogArgs = 4
+
+// cFormat prepares a printf-style call for the fixed-arity shims around
+// the variadic myLogf. cgo cannot call a variadic function, and a shim
+// per combination of argument types would not scale, so every argument is
+// formatted here, in Go, according to its conversion spec, and passed to C
+// as a string. The returned format has each spec replaced by %s.
+//
+// Checking each argument against its verb also catches the mistakes C
+// would turn into undefined behavior, such as passing a string for %d.
+func cFormat(format string, args []any) (string, []string, error) {
+	if len(args) > maxLogArgs {
+		return "", nil, fmt.Errorf("%w: %d arguments, at most %d are supported", ErrFormat, len(args), maxLogArgs)
+	}
+
+	var (
+		b    strings.Builder
+		strs []string
+	)
+	for i := 0; i < len(format); i++ {
+		if format[i] != '%' {
+			b.WriteByte(format[i])
+			continue
+		}
+
+		// %[flags][width][.precision][length]verb. The length modifier
+		// describes the C argument's size, which no longer matters once
+		// the value is a string, so it is dropped.
+		j := i + 1
+		j = skip(format, j, "-+ #0")
+		j = skip(format, j, "0123456789")
+		if j < len(format) && format[j] == '.' {
+			j = skip(format, j+1, "0123456789")
+		}
+		spec := format[i+1 : j]
+		j = skip(format, j, "hlLqjzt")
+		if j == len(format) {
+			return "", nil, fmt.Errorf("%w: incomplete verb at end of %q", ErrFormat, format)
+		}
+		verb := format[j]
+		i = j
+
+		if verb == '%' {
+			b.WriteString("%%")
+			continue
+		}
+		if len(strs) == len(args) {
+			return "", nil, fmt.Errorf("%w: missing argument for %%%c", ErrFormat, verb)
+		}
+		s, err := formatArg(spec, verb, args[len(strs)])
+		if err != nil {
+			return "", nil, err
+		}
+		strs = append(strs, s)
+		b.WriteString("%s")
+	}
+	if len(strs) < len(args) {
+		return "", nil, fmt.Errorf("%w: %d arguments for %d verbs", ErrFormat, len(args), len(strs))
+	}
+	return b.String(), strs, nil
+}
+
+func skip(s string, i int, chars string) int {
+	for i < len(s) && strings.IndexByte(chars, s[i]) >= 0 {
+		i++
+	}
+	return i
+}
+
+// formatArg formats arg the way C's printf would format it for
+// %<spec><verb>, using the equivalent Go verb.
+func formatArg(spec string, verb byte, arg any) (string, error) {
+	var kinds []reflect.Kind
+	goVerb := verb
+	switch verb {
+	case 'd', 'i', 'u':
+		goVerb = 'd'
+		fallthrough
+	case 'o', 'x', 'X', 'c':
+		kinds = []reflect.Kind{
+			reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+			reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr,
+		}
+	case 'F':
+		goVerb = 'f'
+		fallthrough
+	case 'e', 'E', 'f', 'g', 'G':
+		kinds = []reflect.Kind{reflect.Float32, reflect.Float64}
+	case 's':
+		kinds = []reflect.Kind{reflect.String}
+	default:
+		return "", fmt.Errorf("%w: unsupported verb %%%c", ErrFormat, verb)
+	}
+
+	k := reflect.ValueOf(arg).Kind()
+	for _, want := range kinds {
+		if k == want {
+			return fmt.Sprintf("%"+spec+string(goVerb), arg), nil
+		}
+	}
+	return "", fmt.Errorf("%w: %%%c with argument of type %T", ErrFormat, verb, arg)
+}
@@ -0,0 +1,20 @@
+// Package fuzz holds fuzz targets for pkg/mylib: properties that must
+// hold for any input, checked by passing the input through the binding
+// into the C library and comparing the result with what Go computes.
+//
+// The package's tests hand each Target to Go's fuzzing engine, with one
+// fuzz function per target:
+//
+//	func FuzzWords(f *testing.F) { fuzz(f, Find("words")) }
+//
+// go test runs each against its seeds, and go test -fuzz FuzzWords
+// searches for more.
+//
+// The C side's memory bugs only show up reliably under AddressSanitizer,
+// with libmylib instrumented as well:
+//
+//	cd src; make asan
+//	go test -asan -fuzz FuzzWords
+//
+// or make fuzz, which fuzzes every target that way in turn.
+package fuzz
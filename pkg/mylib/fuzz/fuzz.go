@@ -0,0 +1,27 @@
+//go:build !nocgo && !windows
+
+package fuzz
+
+// A Target is a property of the binding that must hold for any input.
+type Target struct {
+	Name  string
+	Seeds [][]byte
+
+	// Check returns an error describing how data breaks the property.
+	Check func(data []byte) error
+}
+
+// Targets returns every target, in a fixed order.
+func Targets() []Target {
+	return targets
+}
+
+// Find returns the target called name. It panics if there is none.
+func Find(name string) Target {
+	for _, t := range targets {
+		if t.Name == name {
+			return t
+		}
+	}
+	panic("fuzz: no target " + name)
+}
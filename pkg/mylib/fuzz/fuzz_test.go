@@ -0,0 +1,38 @@
+//go:build cgo && !nocgo && !windows
+
+package fuzz
+
+import (
+	"slices"
+	"testing"
+)
+
+func FuzzWords(f *testing.F)    { fuzz(f, Find("words")) }
+func FuzzWide(f *testing.F)     { fuzz(f, Find("wide")) }
+func FuzzLookup(f *testing.F)   { fuzz(f, Find("lookup")) }
+func FuzzBuffer(f *testing.F)   { fuzz(f, Find("buffer")) }
+func FuzzChecksum(f *testing.F) { fuzz(f, Find("checksum")) }
+
+// fuzz adds t's seeds to f's corpus and fuzzes t.
+func fuzz(f *testing.F, t Target) {
+	for _, s := range t.Seeds {
+		f.Add(s)
+	}
+	f.Fuzz(func(tt *testing.T, data []byte) {
+		if err := t.Check(data); err != nil {
+			tt.Fatalf("%q: %v", data, err)
+		}
+	})
+}
+
+// TestTargets fails when a target is added without a Fuzz function
+// above, which the fuzz target in the Makefile would then skip.
+func TestTargets(t *testing.T) {
+	var names []string
+	for _, tt := range Targets() {
+		names = append(names, tt.Name)
+	}
+	if want := []string{"words", "wide", "lookup", "buffer", "checksum"}; !slices.Equal(names, want) {
+		t.Errorf("targets %q, with Fuzz functions for %q", names, want)
+	}
+}
@@ -0,0 +1,196 @@
+//go:build !nocgo && !windows
+
+package fuzz
+
+import (
+	"errors"
+	"fmt"
+	"hash/adler32"
+	"maps"
+	"slices"
+	"strings"
+
+	"github.com/lxwagn/using-go-with-c-libraries/pkg/mylib"
+)
+
+var targets = []Target{
+	{
+		// mySplitWords and myEachWord scan text for words, the C
+		// library's closest thing to a parser.
+		Name:  "words",
+		Seeds: seeds("", " ", "go", "  go  cgo ", "a b a", "tab\tis\tno space", "héllo wörld"),
+		Check: checkWords,
+	},
+	{
+		// The strings cross to C as wchar_t and back.
+		Name:  "wide",
+		Seeds: seeds("", "abc", "héllo", "😀 é", "\xff\xfe", "a\xc3"),
+		Check: checkWide,
+	},
+	{
+		Name:  "lookup",
+		Seeds: seeds("one", "two", "three", "four", "", "on"),
+		Check: checkLookup,
+	},
+	{
+		Name:  "buffer",
+		Seeds: seeds("", "a", "abc def", strings.Repeat("x", 40)+" y"),
+		Check: checkBuffer,
+	},
+	{
+		Name:  "checksum",
+		Seeds: seeds("", "Wikipedia", strings.Repeat("\xff", 6000)),
+		Check: checkChecksum,
+	},
+}
+
+func seeds(s ...string) [][]byte {
+	b := make([][]byte, len(s))
+	for i := range s {
+		b[i] = []byte(s[i])
+	}
+	return b
+}
+
+// wantNUL checks that a call given a string with a NUL byte failed with
+// ErrNUL, since C would have seen only the part before it.
+func wantNUL(err error) error {
+	if !errors.Is(err, mylib.ErrNUL) {
+		return fmt.Errorf("got error %v for text with NUL, want ErrNUL", err)
+	}
+	return nil
+}
+
+// words splits text on spaces the way the C library does.
+func words(text string) []mylib.Word {
+	var w []mylib.Word
+	for i := 0; i < len(text); {
+		if text[i] == ' ' {
+			i++
+			continue
+		}
+		j := i
+		for j < len(text) && text[j] != ' ' {
+			j++
+		}
+		w = append(w, mylib.Word{Text: text[i:j], Offset: i})
+		i = j
+	}
+	return w
+}
+
+func checkWords(data []byte) error {
+	text := string(data)
+	list, err := mylib.SplitWords(text)
+	counts, cerr := mylib.CountWords(text)
+	if strings.IndexByte(text, 0) >= 0 {
+		if err := wantNUL(err); err != nil {
+			return err
+		}
+		return wantNUL(cerr)
+	}
+	if err != nil {
+		return err
+	}
+	if cerr != nil {
+		return cerr
+	}
+
+	got := list.CollectSlice()
+	want := words(text)
+	if !slices.Equal(got, want) {
+		return fmt.Errorf("SplitWords = %+v, want %+v", got, want)
+	}
+	wantCounts := make(map[string]int)
+	for _, w := range want {
+		wantCounts[w.Text]++
+	}
+	if !maps.Equal(counts, wantCounts) {
+		return fmt.Errorf("CountWords = %v, want %v", counts, wantCounts)
+	}
+	return nil
+}
+
+func checkWide(data []byte) error {
+	s := string(data)
+	rev, err := mylib.WideReverse(s)
+	n, nerr := mylib.WideCount(s)
+	if strings.IndexByte(s, 0) >= 0 {
+		if err := wantNUL(err); err != nil {
+			return err
+		}
+		return wantNUL(nerr)
+	}
+	if err != nil {
+		return err
+	}
+	if nerr != nil {
+		return nerr
+	}
+
+	// Invalid UTF-8 reaches C as U+FFFD, one per bad byte, as it does
+	// in a conversion to []rune.
+	r := []rune(s)
+	if n != len(r) {
+		return fmt.Errorf("WideCount = %d, want %d", n, len(r))
+	}
+	slices.Reverse(r)
+	if rev != string(r) {
+		return fmt.Errorf("WideReverse = %q, want %q", rev, string(r))
+	}
+	return nil
+}
+
+var table = map[string]int{"one": 1, "two": 2, "three": 3}
+
+func checkLookup(data []byte) error {
+	key := string(data)
+	v, err := mylib.Lookup(key)
+	if strings.IndexByte(key, 0) >= 0 {
+		return wantNUL(err)
+	}
+	want, ok := table[key]
+	switch {
+	case !ok && !errors.Is(err, mylib.ErrNotFound):
+		return fmt.Errorf("Lookup = %d, %v, want ErrNotFound", v, err)
+	case ok && (err != nil || v != want):
+		return fmt.Errorf("Lookup = %d, %v, want %d", v, err, want)
+	}
+	return nil
+}
+
+// checkBuffer appends the space-separated pieces of data to a Buffer one
+// by one, which makes it grow at arbitrary points.
+func checkBuffer(data []byte) error {
+	b, err := mylib.NewBuffer()
+	if err != nil {
+		return err
+	}
+	defer b.Close()
+
+	var want strings.Builder
+	for _, piece := range strings.Split(string(data), " ") {
+		err := b.Append(piece)
+		if strings.IndexByte(piece, 0) >= 0 {
+			if err := wantNUL(err); err != nil {
+				return err
+			}
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		want.WriteString(piece)
+	}
+	if got := b.String(); got != want.String() || b.Len() != want.Len() {
+		return fmt.Errorf("Buffer holds %q (Len %d), want %q", got, b.Len(), want.String())
+	}
+	return nil
+}
+
+func checkChecksum(data []byte) error {
+	if got, want := mylib.Checksum(data), adler32.Checksum(data); got != want {
+		return fmt.Errorf("Checksum = %#x, want %#x", got, want)
+	}
+	return nil
+}
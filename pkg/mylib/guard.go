@@ -0,0 +1,26 @@
+//go:build !mylib_nolock
+
+package mylib
+
+import "sync"
+
+// Serialized reports whether calls into the C library are serialized.
+// Build with -tags mylib_nolock to turn the guard off when the library is
+// known to be thread-safe.
+const Serialized = true
+
+// cmu is held for the duration of every call into the C library that
+// callC makes, which keeps state such as myCounterAdd's counter
+// consistent when several goroutines use the binding at once.
+//
+// The mutex is not reentrant: a Go callback invoked by C while the lock is
+// held must not call back into this package.
+var cmu sync.Mutex
+
+func lockC() {
+	cmu.Lock()
+}
+
+func unlockC() {
+	cmu.Unlock()
+}
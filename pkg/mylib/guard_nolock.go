@@ -0,0 +1,10 @@
+//go:build mylib_nolock
+
+package mylib
+
+// Serialized reports whether calls into the C library are serialized.
+const Serialized = false
+
+func lockC() {}
+
+func unlockC() {}
@@ -0,0 +1,41 @@
+package mylib
+
+import (
+	"errors"
+	"sync"
+	"testing"
+)
+
+// TestGuardConcurrent calls into the library from many goroutines at
+// once. myCounterAdd reads and writes its counter in separate steps, so
+// increments are only all counted if the guard serializes the calls; run
+// with -race, the test also checks the Go side of every call. Built with
+// mylib_nolock the calls run unserialized, as documented, and the lost
+// increments are logged instead.
+func TestGuardConcurrent(t *testing.T) {
+	const goroutines, adds = 16, 5000
+	start := CounterAdd(0)
+	var wg sync.WaitGroup
+	for range goroutines {
+		wg.Go(func() {
+			for i := range adds {
+				CounterAdd(1)
+				if i%100 == 0 {
+					if _, err := Lookup("no such key"); !errors.Is(err, ErrNotFound) {
+						t.Errorf("Lookup = %v, want ErrNotFound", err)
+					}
+					CallN(10, func(v int) int { return v })
+				}
+			}
+		})
+	}
+	wg.Wait()
+
+	got, want := CounterAdd(0)-start, goroutines*adds
+	switch {
+	case Serialized && got != want:
+		t.Errorf("counter grew by %d, want %d: the guard let calls overlap", got, want)
+	case !Serialized && got != want:
+		t.Logf("mylib_nolock: %d of %d increments lost to unserialized calls", want-got, want)
+	}
+}
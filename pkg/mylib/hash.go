@@ -0,0 +1,75 @@
+//go:build !nocgo && !windows
+
+package mylib
+
+/*
+
+#include "mylib.h"
+
+// A _GoString_ parameter receives a Go string as it is, its pointer and
+// length, without cgo copying it. _GoStringPtr and _GoStringLen take it
+// apart for C functions that accept a length.
+static unsigned int hashGoString(_GoString_ s) {
+	return myHash(_GoStringPtr(s), _GoStringLen(s));
+}
+
+*/
+import "C"
+
+import (
+	"strings"
+	"unsafe"
+
+	"github.com/lxwagn/using-go-with-c-libraries/pkg/cmem"
+	"github.com/lxwagn/using-go-with-c-libraries/pkg/features"
+)
+
+// Hash returns the 32-bit FNV-1a hash of s as the C library computes it.
+// s is passed to C in place, NUL bytes and all, with no copy.
+func Hash(s string) (uint32, error) {
+	if err := require(features.Hash); err != nil {
+		return 0, err
+	}
+	var h uint32
+	err := callC(func() error {
+		h = uint32(C.hashGoString(s))
+		return nil
+	})
+	return h, err
+}
+
+// HashBytes is Hash for a byte slice, which is likewise read in place.
+func HashBytes(b []byte) (uint32, error) {
+	if err := require(features.Hash); err != nil {
+		return 0, err
+	}
+	var h uint32
+	err := callC(func() error {
+		h = uint32(C.myHash((*C.char)(cmem.BorrowSlice(b)), C.size_t(len(b))))
+		return nil
+	})
+	return h, err
+}
+
+// HashCString is Hash through myHashString, which takes a NUL-terminated
+// string: s is copied into C memory for the call, the way the package
+// passes strings to functions that take no length. It
+// returns ErrNUL if s contains a NUL byte, which would end the C string
+// early. It is here for comparison with Hash.
+func HashCString(s string) (uint32, error) {
+	if err := require(features.Hash); err != nil {
+		return 0, err
+	}
+	if strings.IndexByte(s, 0) >= 0 {
+		return 0, ErrNUL
+	}
+	cs := (*C.char)(cmem.CString(s))
+	defer cmem.Free(unsafe.Pointer(cs))
+
+	var h uint32
+	err := callC(func() error {
+		h = uint32(C.myHashString(cs))
+		return nil
+	})
+	return h, err
+}
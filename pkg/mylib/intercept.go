@@ -0,0 +1,318 @@
+package mylib
+
+import (
+	"context"
+	"slices"
+	"strings"
+	"sync/atomic"
+)
+
+// A Call is one call into the C library, made by a wrapper of this
+// package, or one method call made through a Client that InterceptClient
+// returns. Interceptors may read it; changes to Args do not reach the
+// call.
+type Call struct {
+	Method  string // the wrapper or method, such as "Lookup" or "Session.Add"
+	Args    []any  // the arguments, without a context; nil for a wrapper's call
+	Results []any  // the results but the error, nil until the call is made; nil for a wrapper's call
+
+	fn doCall
+}
+
+// A CallFunc makes a call, or passes it on to the next CallFunc, and
+// returns its error.
+type CallFunc func(ctx context.Context, call *Call) error
+
+// An Interceptor wraps the CallFunc that makes each call, to do something
+// before or after it: log it, time it, fail it without calling next, such
+// as when its arguments are invalid, or call next again after an error to
+// retry.
+type Interceptor func(next CallFunc) CallFunc
+
+// chain returns the CallFunc making a call through interceptors, the
+// first of them outermost.
+func chain(interceptors []Interceptor) CallFunc {
+	call := CallFunc(func(ctx context.Context, call *Call) error {
+		return call.do(ctx)
+	})
+	for i := len(interceptors) - 1; i >= 0; i-- {
+		call = interceptors[i](call)
+	}
+	return call
+}
+
+// An interceptorChain is what SetInterceptors was last given.
+type interceptorChain struct {
+	interceptors []Interceptor
+	call         CallFunc
+}
+
+// callChain is nil while there are no interceptors, which callC checks
+// before doing anything else.
+var callChain atomic.Pointer[interceptorChain]
+
+// SetInterceptors runs every call into the C library, made by any of the
+// package's functions and methods, through interceptors from now on, the
+// first of them outermost, and returns the interceptors it replaces. With
+// none, calls are made directly again.
+//
+// A call's Method is the wrapper making it, as in a Client, such as
+// "Lookup" or "Session.Add", or "Subscription.Close", and its Args and
+// Results are nil: a wrapper's call is whatever C it runs, which for some
+// is several C functions. ctx is the call's task in an execution trace
+// under TraceTasks, and context.Background otherwise. Naming the wrapper
+// takes a walk of the caller's stack per call while there are
+// interceptors.
+//
+// Interceptors run on the goroutine making the call, before the library
+// lock is taken, so they run concurrently and must be safe for that, and
+// call next, if at all, before they return, on the same goroutine. They
+// must not call into this package, whose calls they would intercept in
+// turn. The wrapper returns the error of the last call made through next,
+// whatever the interceptor returns, since the wrapper has to act on what
+// the library did; only an interceptor that fails the call without
+// calling next has its error returned instead. That goes for retries too:
+// next may be called again only after it failed, as a wrapper starting
+// something would otherwise start it twice. Calls that free or stop
+// something, such as Buffer.Close's and Subscription.Close's, are made
+// even if an interceptor fails them, since the wrapper forgets the object
+// when they return.
+//
+// The calls of Native, a Client, are intercepted here too, so an
+// interceptor given both to SetInterceptors and to InterceptClient around
+// Native sees its calls twice.
+func SetInterceptors(interceptors ...Interceptor) (prev []Interceptor) {
+	var c *interceptorChain
+	if len(interceptors) > 0 {
+		interceptors = slices.Clone(interceptors)
+		c = &interceptorChain{interceptors: interceptors, call: chain(interceptors)}
+	}
+	if old := callChain.Swap(c); old != nil {
+		prev = old.interceptors
+	}
+	return prev
+}
+
+// run makes the call f for the wrapper name through the chain, as doC
+// does without one.
+func (c *interceptorChain) run(ctx context.Context, name string, serialize, release bool, f func() error) error {
+	var made bool
+	var err error
+	call := &Call{Method: callMethod(name), fn: func(ctx context.Context, call *Call) error {
+		made = true
+		err = makeCall(serialize, f)
+		return err
+	}}
+	if ierr := c.call(ctx, call); !made {
+		if release {
+			return makeCall(serialize, f)
+		}
+		return ierr
+	}
+	return err
+}
+
+// callMethod returns the Method of a Call made by the wrapper name, as
+// wrapperName gives it: "mylib.(*Session).Add" is "Session.Add".
+func callMethod(name string) string {
+	name = strings.TrimPrefix(name, "mylib.")
+	if rest, ok := strings.CutPrefix(name, "(*"); ok {
+		if i := strings.Index(rest, ")"); i >= 0 {
+			name = rest[:i] + rest[i+1:]
+		}
+	}
+	return name
+}
+
+// InterceptClient returns a Client whose methods, and those of the
+// sessions it returns, call c through the interceptors; the first is the
+// outermost. Session.Name is not a call into the library and is not
+// intercepted. It works the same around Native and a mock.Client, so the
+// interceptors can be tested without the library, and unlike
+// SetInterceptors, they see each call's arguments and results and can
+// replace its error. To intercept every call into the library, whichever
+// wrapper makes it, use SetInterceptors.
+//
+// ctx is Crunch's context, and context.Background for the methods that
+// take none. A method without an error result makes no call when an
+// interceptor fails it, and returns zero values, or for Fill and
+// TranslatePoints, leaves its arguments untouched.
+func InterceptClient(c Client, interceptors ...Interceptor) Client {
+	return &intercepted{next: c, call: chain(interceptors)}
+}
+
+// A doCall calls the method of the wrapped Client that a Call is for,
+// and stores its results in the Call.
+type doCall func(ctx context.Context, call *Call) error
+
+func (c *Call) do(ctx context.Context) error {
+	return c.fn(ctx, c)
+}
+
+type intercepted struct {
+	next Client
+	call CallFunc
+}
+
+// run makes the call method(args) through the interceptors, with fn
+// calling the wrapped Client.
+func (c *intercepted) run(ctx context.Context, method string, args []any, fn doCall) error {
+	return c.call(ctx, &Call{Method: method, Args: args, fn: fn})
+}
+
+func (c *intercepted) Print(s string) error {
+	return c.run(context.Background(), "Print", []any{s}, func(ctx context.Context, call *Call) error {
+		return c.next.Print(s)
+	})
+}
+
+func (c *intercepted) Lookup(key string) (n int, err error) {
+	err = c.run(context.Background(), "Lookup", []any{key}, func(ctx context.Context, call *Call) error {
+		var err error
+		n, err = c.next.Lookup(key)
+		call.Results = []any{n}
+		return err
+	})
+	return n, err
+}
+
+func (c *intercepted) FileSize(path string) (n int64, err error) {
+	err = c.run(context.Background(), "FileSize", []any{path}, func(ctx context.Context, call *Call) error {
+		var err error
+		n, err = c.next.FileSize(path)
+		call.Results = []any{n}
+		return err
+	})
+	return n, err
+}
+
+func (c *intercepted) MakeStruct(a int, b string) (s MyStruct, err error) {
+	err = c.run(context.Background(), "MakeStruct", []any{a, b}, func(ctx context.Context, call *Call) error {
+		var err error
+		s, err = c.next.MakeStruct(a, b)
+		call.Results = []any{s}
+		return err
+	})
+	return s, err
+}
+
+func (c *intercepted) ScaleStruct(s MyStruct, factor int) (r MyStruct, err error) {
+	err = c.run(context.Background(), "ScaleStruct", []any{s, factor}, func(ctx context.Context, call *Call) error {
+		var err error
+		r, err = c.next.ScaleStruct(s, factor)
+		call.Results = []any{r}
+		return err
+	})
+	return r, err
+}
+
+func (c *intercepted) TranslatePoints(pts []Point, dx, dy int) {
+	c.run(context.Background(), "TranslatePoints", []any{pts, dx, dy}, func(ctx context.Context, call *Call) error {
+		c.next.TranslatePoints(pts, dx, dy)
+		return nil
+	})
+}
+
+func (c *intercepted) Fill(b []byte, seed byte) {
+	c.run(context.Background(), "Fill", []any{b, seed}, func(ctx context.Context, call *Call) error {
+		c.next.Fill(b, seed)
+		return nil
+	})
+}
+
+func (c *intercepted) Checksum(b []byte) (sum uint32) {
+	c.run(context.Background(), "Checksum", []any{b}, func(ctx context.Context, call *Call) error {
+		sum = c.next.Checksum(b)
+		call.Results = []any{sum}
+		return nil
+	})
+	return sum
+}
+
+func (c *intercepted) CountWords(text string) (m map[string]int, err error) {
+	err = c.run(context.Background(), "CountWords", []any{text}, func(ctx context.Context, call *Call) error {
+		var err error
+		m, err = c.next.CountWords(text)
+		call.Results = []any{m}
+		return err
+	})
+	return m, err
+}
+
+func (c *intercepted) WideReverse(s string) (r string, err error) {
+	err = c.run(context.Background(), "WideReverse", []any{s}, func(ctx context.Context, call *Call) error {
+		var err error
+		r, err = c.next.WideReverse(s)
+		call.Results = []any{r}
+		return err
+	})
+	return r, err
+}
+
+func (c *intercepted) Crunch(ctx context.Context, iterations int64) (n int64, err error) {
+	err = c.run(ctx, "Crunch", []any{iterations}, func(ctx context.Context, call *Call) error {
+		var err error
+		n, err = c.next.Crunch(ctx, iterations)
+		call.Results = []any{n}
+		return err
+	})
+	return n, err
+}
+
+func (c *intercepted) NewSession(name string, limit int64) (s ClientSession, err error) {
+	err = c.run(context.Background(), "NewSession", []any{name, limit}, func(ctx context.Context, call *Call) error {
+		var err error
+		s, err = c.next.NewSession(name, limit)
+		call.Results = []any{s}
+		return err
+	})
+	if err != nil {
+		if s != nil {
+			// An interceptor failed the call after it was made.
+			s.Close()
+		}
+		return nil, err
+	}
+	return &interceptedSession{next: s, c: c}, nil
+}
+
+type interceptedSession struct {
+	next ClientSession
+	c    *intercepted
+}
+
+func (s *interceptedSession) Name() string {
+	return s.next.Name()
+}
+
+func (s *interceptedSession) Add(delta int64) (total int64, err error) {
+	err = s.c.run(context.Background(), "Session.Add", []any{delta}, func(ctx context.Context, call *Call) error {
+		var err error
+		total, err = s.next.Add(delta)
+		call.Results = []any{total}
+		return err
+	})
+	return total, err
+}
+
+func (s *interceptedSession) Reset() error {
+	return s.c.run(context.Background(), "Session.Reset", nil, func(ctx context.Context, call *Call) error {
+		return s.next.Reset()
+	})
+}
+
+func (s *interceptedSession) Stats() (total int64, calls int, err error) {
+	err = s.c.run(context.Background(), "Session.Stats", nil, func(ctx context.Context, call *Call) error {
+		var err error
+		total, calls, err = s.next.Stats()
+		call.Results = []any{total, calls}
+		return err
+	})
+	return total, calls, err
+}
+
+func (s *interceptedSession) Close() error {
+	return s.c.run(context.Background(), "Session.Close", nil, func(ctx context.Context, call *Call) error {
+		return s.next.Close()
+	})
+}
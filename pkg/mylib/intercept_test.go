@@ -0,0 +1,118 @@
+package mylib
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"testing"
+)
+
+// recordCalls returns an Interceptor that appends name>Method to *order
+// before each call and name<Results after it.
+func recordCalls(name string, order *[]string) Interceptor {
+	return func(next CallFunc) CallFunc {
+		return func(ctx context.Context, call *Call) error {
+			*order = append(*order, name+">"+call.Method)
+			err := next(ctx, call)
+			*order = append(*order, fmt.Sprintf("%s<%v", name, call.Results))
+			return err
+		}
+	}
+}
+
+// retry is an Interceptor that makes a failed call once more.
+func retry(next CallFunc) CallFunc {
+	return func(ctx context.Context, call *Call) error {
+		if err := next(ctx, call); err != nil {
+			return next(ctx, call)
+		}
+		return nil
+	}
+}
+
+// errEmptyKey is what noEmptyKeys fails Lookup of "" with.
+var errEmptyKey = errors.New("empty key")
+
+// noEmptyKeys is an Interceptor that fails Lookup of "" without making it.
+func noEmptyKeys(next CallFunc) CallFunc {
+	return func(ctx context.Context, call *Call) error {
+		if call.Method == "Lookup" && call.Args[0] == "" {
+			return errEmptyKey
+		}
+		return next(ctx, call)
+	}
+}
+
+// TestInterceptClient checks that interceptors run in order around each
+// call, see its results, and can fail it before it is made or make it
+// again.
+func TestInterceptClient(t *testing.T) {
+	var order []string
+	c := InterceptClient(Native(), recordCalls("a", &order), retry, noEmptyKeys, recordCalls("b", &order))
+
+	if n, err := c.Lookup("two"); n != 2 || err != nil {
+		t.Errorf("Lookup(\"two\") = %d, %v; want 2, nil", n, err)
+	}
+	if _, err := c.Lookup("four"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Lookup(\"four\") = %v, want ErrNotFound", err)
+	}
+	if got, want := strings.Join(order, " "), "a>Lookup b>Lookup b<[2] a<[2] a>Lookup b>Lookup b<[0] b>Lookup b<[0] a<[0]"; got != want {
+		t.Errorf("interceptors ran %s, want %s", got, want)
+	}
+
+	order = nil
+	if _, err := c.Lookup(""); err != errEmptyKey {
+		t.Errorf("Lookup(\"\") = %v, want the validation error", err)
+	}
+	if got, want := strings.Join(order, " "), "a>Lookup a<[]"; got != want {
+		t.Errorf("Lookup(\"\") ran %s, want %s without reaching the client", got, want)
+	}
+
+	s, err := c.NewSession("intercept", 100)
+	if err != nil {
+		t.Fatal(err)
+	}
+	order = nil
+	s.Add(5)
+	s.Close()
+	if got, want := strings.Join(order, " "), "a>Session.Add b>Session.Add b<[5] a<[5] a>Session.Close b>Session.Close b<[] a<[]"; got != want {
+		t.Errorf("session calls ran %s, want %s", got, want)
+	}
+}
+
+// TestBuiltinInterceptors times and logs Native's calls, with failures
+// logged as warnings.
+func TestBuiltinInterceptors(t *testing.T) {
+	var logged bytes.Buffer
+	l := slog.New(slog.NewTextHandler(&logged, &slog.HandlerOptions{Level: slog.LevelInfo}))
+	h := NewLatencyHistogram()
+	c := InterceptClient(Native(), LogCalls(l, slog.LevelDebug), h.Interceptor())
+
+	b := make([]byte, 1<<16)
+	for range 100 {
+		c.Fill(b, 1)
+		c.Checksum(b)
+	}
+	if _, err := c.Lookup("no such key"); err == nil {
+		t.Error("Lookup of a missing key succeeded")
+	}
+	if got := strings.Join(h.Methods(), " "); got != "Checksum Fill Lookup" {
+		t.Errorf("histogram has methods %s", got)
+	}
+	lat := h.Read("Checksum")
+	if lat.Count != 100 || lat.Errors != 0 || lat.Sum <= 0 {
+		t.Errorf("Checksum latencies %+v, want 100 calls without errors", lat)
+	}
+	if h.Read("Lookup").Errors != 1 {
+		t.Errorf("Lookup latencies %+v, want 1 error", h.Read("Lookup"))
+	}
+
+	// Only the failure is at Info or above.
+	out := strings.TrimSpace(logged.String())
+	if strings.Count(out, "\n") != 0 || !strings.Contains(out, "level=WARN") || !strings.Contains(out, "method=Lookup") {
+		t.Errorf("logged %q, want one warning for Lookup", out)
+	}
+}
@@ -0,0 +1,170 @@
+package mylib
+
+import (
+	"context"
+	"log/slog"
+	"math"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// LogCalls returns an Interceptor that logs every call to l once it has
+// returned, with its method and how long it took, at level, or at
+// slog.LevelWarn if that is higher and the call failed, with the error.
+// The arguments are left out: TranslatePoints and Fill take slices of any
+// size.
+func LogCalls(l *slog.Logger, level slog.Level) Interceptor {
+	return func(next CallFunc) CallFunc {
+		return func(ctx context.Context, call *Call) error {
+			start := time.Now()
+			err := next(ctx, call)
+			elapsed := time.Since(start)
+			if err != nil {
+				l.LogAttrs(ctx, max(level, slog.LevelWarn), "mylib call failed",
+					slog.String("method", call.Method), slog.Duration("elapsed", elapsed), slog.Any("err", err))
+			} else if l.Enabled(ctx, level) {
+				l.LogAttrs(ctx, level, "mylib call",
+					slog.String("method", call.Method), slog.Duration("elapsed", elapsed))
+			}
+			return err
+		}
+	}
+}
+
+// DefaultLatencyBounds are the bucket bounds of a LatencyHistogram made
+// without any: powers of 4 from 1µs to about 1s.
+var DefaultLatencyBounds = []time.Duration{
+	1 * time.Microsecond, 4 * time.Microsecond, 16 * time.Microsecond, 64 * time.Microsecond,
+	256 * time.Microsecond, 1024 * time.Microsecond, 4096 * time.Microsecond,
+	16384 * time.Microsecond, 65536 * time.Microsecond, 262144 * time.Microsecond,
+	1048576 * time.Microsecond,
+}
+
+// A LatencyHistogram counts the calls made through its Interceptor by
+// method and by how long each took. It is safe for
+// concurrent use, and counting a call takes no lock once its method has
+// been seen.
+type LatencyHistogram struct {
+	bounds []time.Duration
+
+	mu      sync.RWMutex
+	methods map[string]*latencies
+}
+
+type latencies struct {
+	counts []atomic.Uint64 // one more than bounds, for the longer calls
+	sum    atomic.Int64
+	errors atomic.Uint64
+}
+
+// NewLatencyHistogram returns a histogram with a bucket for the calls
+// taking up to each of bounds, which must be in increasing order, and one
+// for the calls taking longer. With no bounds it uses
+// DefaultLatencyBounds.
+func NewLatencyHistogram(bounds ...time.Duration) *LatencyHistogram {
+	if len(bounds) == 0 {
+		bounds = DefaultLatencyBounds
+	}
+	for i := 1; i < len(bounds); i++ {
+		if bounds[i] <= bounds[i-1] {
+			panic("mylib: latency bounds not in increasing order")
+		}
+	}
+	return &LatencyHistogram{bounds: slices.Clone(bounds), methods: make(map[string]*latencies)}
+}
+
+// Interceptor returns the Interceptor that times calls into h.
+func (h *LatencyHistogram) Interceptor() Interceptor {
+	return func(next CallFunc) CallFunc {
+		return func(ctx context.Context, call *Call) error {
+			start := time.Now()
+			err := next(ctx, call)
+			h.observe(call.Method, time.Since(start), err != nil)
+			return err
+		}
+	}
+}
+
+func (h *LatencyHistogram) observe(method string, d time.Duration, failed bool) {
+	h.mu.RLock()
+	l := h.methods[method]
+	h.mu.RUnlock()
+	if l == nil {
+		h.mu.Lock()
+		if l = h.methods[method]; l == nil {
+			l = &latencies{counts: make([]atomic.Uint64, len(h.bounds)+1)}
+			h.methods[method] = l
+		}
+		h.mu.Unlock()
+	}
+	i, _ := slices.BinarySearch(h.bounds, d)
+	l.counts[i].Add(1)
+	l.sum.Add(int64(d))
+	if failed {
+		l.errors.Add(1)
+	}
+}
+
+// Methods returns the methods that have been called, in order.
+func (h *LatencyHistogram) Methods() []string {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	methods := make([]string, 0, len(h.methods))
+	for m := range h.methods {
+		methods = append(methods, m)
+	}
+	slices.Sort(methods)
+	return methods
+}
+
+// Latencies are the counts of a LatencyHistogram for one method.
+type Latencies struct {
+	Bounds []time.Duration // the upper bounds of the buckets but the last
+	Counts []uint64        // the calls in each bucket, one more than Bounds
+	Count  uint64          // all calls
+	Errors uint64          // the calls that failed
+	Sum    time.Duration   // the time all calls took
+}
+
+// Read returns the counts for method, all zero if it has not been called.
+// The counts are read one at a time, so a snapshot taken while calls are
+// being made may have a call in Counts that is not yet in Errors or Sum.
+func (h *LatencyHistogram) Read(method string) Latencies {
+	r := Latencies{Bounds: slices.Clone(h.bounds), Counts: make([]uint64, len(h.bounds)+1)}
+	h.mu.RLock()
+	l := h.methods[method]
+	h.mu.RUnlock()
+	if l == nil {
+		return r
+	}
+	r.Errors = l.errors.Load()
+	r.Sum = time.Duration(l.sum.Load())
+	for i := range l.counts {
+		r.Counts[i] = l.counts[i].Load()
+		r.Count += r.Counts[i]
+	}
+	return r
+}
+
+// Quantile returns the upper bound of the bucket holding the q-quantile
+// of the calls, 0 <= q <= 1, such as 0.99 for the time 99% of calls took
+// at most. It returns 0 with no calls, and -1 if the quantile falls in
+// the last bucket, which has no upper bound.
+func (l Latencies) Quantile(q float64) time.Duration {
+	if l.Count == 0 {
+		return 0
+	}
+	rank := min(max(uint64(math.Ceil(q*float64(l.Count))), 1), l.Count)
+	var seen uint64
+	for i, n := range l.Counts {
+		if seen += n; seen >= rank {
+			if i == len(l.Bounds) {
+				return -1
+			}
+			return l.Bounds[i]
+		}
+	}
+	return -1
+}
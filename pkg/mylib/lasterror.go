@@ -0,0 +1,25 @@
+package mylib
+
+import (
+	"errors"
+
+	"github.com/lxwagn/using-go-with-c-libraries/pkg/cerr"
+)
+
+// lastError returns the error for a failed call to the C function op from
+// the error indicator the call left behind: errno on Unix and GetLastError
+// on Windows, as a syscall.Errno.
+//
+// Both are per thread, and a goroutine can move to another thread between
+// any two statements, after which the next C call on the old thread may
+// overwrite the value. So lastErr must have been read on the calling
+// thread as part of the call itself: as the second result of a cgo call,
+// by withErrno in the nocgo build, or as the third result of
+// LazyProc.Call on Windows. If the call left no error, or no error is
+// available, the result still names op.
+func lastError(op string, lastErr error) error {
+	if err := cerr.Errno(op, lastErr); err != nil {
+		return err
+	}
+	return errors.New("mylib: " + op + " failed")
+}
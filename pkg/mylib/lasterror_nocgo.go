@@ -0,0 +1,22 @@
+//go:build nocgo && !windows
+
+package mylib
+
+import (
+	"runtime"
+	"syscall"
+)
+
+// withErrno runs f, which makes one call into the C library, and returns
+// the errno it left behind. purego does not report errno, so it is
+// cleared before the call and read after it, with the goroutine locked to
+// its thread in between.
+func withErrno(f func()) error {
+	runtime.LockOSThread()
+	defer runtime.UnlockOSThread()
+
+	p := errnoLocation()
+	*p = 0
+	f()
+	return syscall.Errno(*p)
+}
@@ -0,0 +1,110 @@
+package mylib
+
+import (
+	"sync"
+	"sync/atomic"
+
+	"github.com/lxwagn/using-go-with-c-libraries/pkg/features"
+)
+
+// lifetime counts the references to the library's global state. once runs
+// myInit; Shutdown uses it up too, so that no myInit can follow it.
+var lifetime struct {
+	once    sync.Once
+	initErr error
+	up      bool // myInit succeeded
+
+	mu   sync.Mutex
+	refs int
+	down bool // shut down, for good
+}
+
+// A Library is one reference to the C library's global state, which
+// myInit sets up and myShutdown releases. Packages that use the library
+// each Open their own reference and Close it when done, without knowing
+// about one another; the state lives as long as any reference does.
+//
+// The rest of the package does not need a reference: this library, unlike
+// the ones it models, works without myInit.
+type Library struct {
+	closed atomic.Bool
+}
+
+// Open returns a new reference to the library's global state, calling
+// myInit before the first one is returned. Callers racing to be first all
+// wait for that single call and get its error. Once the last reference is
+// closed the library is shut down, and like many C libraries it cannot be
+// set up again: Open then returns ErrShutdown. A program whose packages
+// open and close references at will should hold one of its own in main.
+func Open() (*Library, error) {
+	if err := require(features.Lifecycle); err != nil {
+		return nil, err
+	}
+	lifetime.once.Do(func() {
+		lifetime.initErr = libInit()
+		lifetime.up = lifetime.initErr == nil
+	})
+	if lifetime.initErr != nil {
+		return nil, lifetime.initErr
+	}
+
+	lifetime.mu.Lock()
+	defer lifetime.mu.Unlock()
+	if lifetime.down {
+		return nil, ErrShutdown
+	}
+	lifetime.refs++
+	return &Library{}, nil
+}
+
+// Close drops the reference, and calls myShutdown if it was the last one.
+// Closing a reference again, or after Shutdown, does nothing.
+func (l *Library) Close() error {
+	if l == nil || !l.closed.CompareAndSwap(false, true) {
+		return nil
+	}
+	lifetime.mu.Lock()
+	defer lifetime.mu.Unlock()
+	if lifetime.down {
+		return nil
+	}
+	lifetime.refs--
+	if lifetime.refs == 0 {
+		lifetime.down = true
+		libShutdown()
+	}
+	return nil
+}
+
+// Shutdown calls myShutdown now, whatever references are still open, and
+// makes the library unusable as if the last one had been closed. A Go
+// program runs no exit hooks, and on Linux it exits without running C's
+// atexit handlers either, so a program that must release the library's
+// state before exiting defers Shutdown in main. os.Exit and log.Fatal
+// skip deferred calls.
+func Shutdown() error {
+	if err := require(features.Lifecycle); err != nil {
+		return err
+	}
+	lifetime.once.Do(func() {})
+
+	lifetime.mu.Lock()
+	defer lifetime.mu.Unlock()
+	if lifetime.down {
+		return nil
+	}
+	lifetime.down = true
+	if lifetime.up {
+		libShutdown()
+	}
+	return nil
+}
+
+// Initialized reports whether the library's global state is set up,
+// according to myInitialized.
+func Initialized() (bool, error) {
+	if err := require(features.Lifecycle); err != nil {
+		return false, err
+	}
+	return libInitialized(), nil
+}
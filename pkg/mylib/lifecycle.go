@@ -0,0 +1,30 @@
+//go:build !nocgo && !windows
+
+package mylib
+
+// #include "mylib.h"
+import "C"
+
+// libInit, libShutdown and libInitialized call the library's lifetime
+// functions for the reference counting in library.go.
+func libInit() error {
+	return callC(func() error {
+		return codes.Error("myInit", int(C.myInit()))
+	})
+}
+
+func libShutdown() {
+	callC(func() error {
+		C.myShutdown()
+		return nil
+	})
+}
+
+func libInitialized() bool {
+	var ok bool
+	callC(func() error {
+		ok = C.myInitialized() != 0
+		return nil
+	})
+	return ok
+}
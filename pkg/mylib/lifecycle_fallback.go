@@ -0,0 +1,33 @@
+//go:build !cgo && !nocgo && !windows
+
+package mylib
+
+// The fallback keeps no global state, but follows myInit's rules so that
+// the reference counting in library.go behaves the same.
+var fallbackInitialized bool
+
+func libInit() error {
+	return callC(func() error {
+		if fallbackInitialized {
+			return codes.Error("myInit", int(StatusInvalid))
+		}
+		fallbackInitialized = true
+		return nil
+	})
+}
+
+func libShutdown() {
+	callC(func() error {
+		fallbackInitialized = false
+		return nil
+	})
+}
+
+func libInitialized() bool {
+	var ok bool
+	callC(func() error {
+		ok = fallbackInitialized
+		return nil
+	})
+	return ok
+}
@@ -0,0 +1,27 @@
+//go:build nocgo && !windows
+
+package mylib
+
+// libInit, libShutdown and libInitialized call the library's lifetime
+// functions for the reference counting in library.go.
+func libInit() error {
+	return callC(func() error {
+		return codes.Error("myInit", int(myInit()))
+	})
+}
+
+func libShutdown() {
+	callC(func() error {
+		myShutdown()
+		return nil
+	})
+}
+
+func libInitialized() bool {
+	var ok bool
+	callC(func() error {
+		ok = myInitialized() != 0
+		return nil
+	})
+	return ok
+}
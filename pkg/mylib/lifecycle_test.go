@@ -0,0 +1,122 @@
+package mylib
+
+import (
+	"errors"
+	"os"
+	"os/exec"
+	"sync"
+	"testing"
+)
+
+// inChild reports whether the test is running in a process of its own.
+// If not, it runs the test again in a new one and reports false: shutting
+// the library down is for good.
+func inChild(t *testing.T) bool {
+	t.Helper()
+	const env = "MYLIB_TEST_CHILD"
+	if os.Getenv(env) == t.Name() {
+		return true
+	}
+	cmd := exec.Command(os.Args[0], "-test.run=^"+t.Name()+"$")
+	cmd.Env = append(os.Environ(), env+"="+t.Name())
+	if out, err := cmd.CombinedOutput(); err != nil {
+		t.Errorf("%v\n%s", err, out)
+	}
+	return false
+}
+
+func initialized(t *testing.T) bool {
+	t.Helper()
+	ok, err := Initialized()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return ok
+}
+
+// TestOpenCloseRace has goroutines race to open the first references,
+// which must cost a single myInit (a second would fail), then race to
+// open and close more on top of them, and finally to close their own,
+// the last of which shuts the library down.
+func TestOpenCloseRace(t *testing.T) {
+	if !inChild(t) {
+		return
+	}
+	if initialized(t) {
+		t.Fatal("initialized before the first Open")
+	}
+	const n = 16
+	libs := make([]*Library, n)
+	errs := make([]error, n)
+	var wg sync.WaitGroup
+	start := make(chan struct{})
+	for i := range n {
+		wg.Go(func() {
+			<-start
+			libs[i], errs[i] = Open()
+			for range 100 {
+				l, err := Open()
+				if err != nil {
+					errs[i] = err
+					return
+				}
+				l.Close()
+			}
+		})
+	}
+	close(start)
+	wg.Wait()
+	if err := errors.Join(errs...); err != nil {
+		t.Fatal(err)
+	}
+	if !initialized(t) {
+		t.Fatal("not initialized with references open")
+	}
+
+	for i := range n {
+		wg.Go(func() {
+			errs[i] = libs[i].Close()
+		})
+	}
+	wg.Wait()
+	if err := errors.Join(errs...); err != nil {
+		t.Fatal(err)
+	}
+	if initialized(t) {
+		t.Fatal("still initialized after the last Close")
+	}
+	if err := libs[0].Close(); err != nil {
+		t.Errorf("second Close: %v", err)
+	}
+	if _, err := Open(); !errors.Is(err, ErrShutdown) {
+		t.Errorf("Open after the last Close = %v, want ErrShutdown", err)
+	}
+}
+
+// TestShutdownEarly shuts the library down under open references, as
+// main would on its way out.
+func TestShutdownEarly(t *testing.T) {
+	if !inChild(t) {
+		return
+	}
+	a, err := Open()
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := Open()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := Shutdown(); err != nil {
+		t.Fatal(err)
+	}
+	if initialized(t) {
+		t.Fatal("still initialized after Shutdown")
+	}
+	if err := errors.Join(a.Close(), b.Close(), Shutdown()); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Open(); !errors.Is(err, ErrShutdown) {
+		t.Errorf("Open after Shutdown = %v, want ErrShutdown", err)
+	}
+}
@@ -0,0 +1,29 @@
+//go:build windows
+
+package mylib
+
+// libInit, libShutdown and libInitialized call the library's lifetime
+// functions for the reference counting in library.go.
+func libInit() error {
+	return callC(func() error {
+		rc, _, _ := procInit.Call()
+		return codes.Error("myInit", int(int32(rc)))
+	})
+}
+
+func libShutdown() {
+	callC(func() error {
+		procShutdown.Call()
+		return nil
+	})
+}
+
+func libInitialized() bool {
+	var ok bool
+	callC(func() error {
+		rc, _, _ := procInitialized.Call()
+		ok = int32(rc) != 0
+		return nil
+	})
+	return ok
+}
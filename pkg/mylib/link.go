@@ -0,0 +1,22 @@
+//go:build !nocgo && !windows && !android && !mylib_vendored && !mylib_origin && !mylib_source && !static
+
+package mylib
+
+// The compiler and linker flags for libmylib come from pkg-config, so the
+// package builds wherever the library is installed, including when it is
+// imported from another module. For the library built in this repository,
+// point pkg-config at lib/pkgconfig:
+//
+//	export PKG_CONFIG_PATH=$PWD/lib/pkgconfig
+//
+// Build with -tags mylib_vendored to use the paths relative to this
+// source tree instead (see link_vendored.go), with -tags mylib_origin for
+// a program that finds the library relative to itself (see
+// link_origin.go), or with -tags mylib_source to compile the library from
+// source as part of the package (see link_source.go). Android builds
+// always do the latter.
+
+/*
+#cgo pkg-config: mylib
+*/
+import "C"
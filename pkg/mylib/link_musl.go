@@ -0,0 +1,24 @@
+//go:build !nocgo && !windows && static && musl
+
+package mylib
+
+// With the static and musl tags the library is linked from
+// lib/musl/libmylib.a, built with musl-gcc (make musl), and the program
+// is linked fully statically against musl rather than glibc. A static
+// glibc still loads NSS modules at run time for getaddrinfo and
+// getpwnam, and warns about it at link time; musl has no such modules,
+// so the binary needs no file at all beyond itself and runs in an image
+// built FROM scratch. Build it with the netgo and osusergo tags as well,
+// so that package net and os/user resolve names in Go rather than calling
+// libc:
+//
+//	CC=musl-gcc go build -tags static,musl,netgo,osusergo
+//
+// On Alpine, whose gcc already targets musl, CC can stay unset.
+
+/*
+#cgo CFLAGS: -I${SRCDIR}/../../src
+#cgo LDFLAGS: ${SRCDIR}/../../lib/musl/libmylib.a -lpthread
+#cgo linux LDFLAGS: -static
+*/
+import "C"
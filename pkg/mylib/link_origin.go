@@ -0,0 +1,26 @@
+//go:build !nocgo && !windows && !android && mylib_origin && !mylib_vendored && !mylib_source && !static
+
+package mylib
+
+// With the mylib_origin tag the program is linked against lib/libmylib.so
+// in this source tree, but looks for it at run time in ../lib relative to
+// its own executable rather than in the directory it was built from. A
+// tree laid out as bin/ and lib/, as "demo install" does, then runs from
+// anywhere and can be moved as a whole. The dynamic linker expands
+// $ORIGIN to the executable's directory.
+//
+// macOS spells $ORIGIN @loader_path, which cgo does not allow in a #cgo
+// directive: it rejects every argument starting with @. Pass it at build
+// time instead:
+//
+//	go build -tags mylib_origin -ldflags=-extldflags=-Wl,-rpath,@loader_path/../lib
+//
+// The library must also have an install name of @rpath/libmylib.dylib,
+// as make cross builds it for darwin.
+
+/*
+#cgo CFLAGS: -I${SRCDIR}/../../src
+#cgo LDFLAGS: -L${SRCDIR}/../../lib -lmylib
+#cgo linux LDFLAGS: -Wl,-rpath,$ORIGIN/../lib
+*/
+import "C"
@@ -0,0 +1,18 @@
+//go:build (asan || msan) && !nocgo && !windows
+
+package mylib
+
+// go build -asan and -msan define the asan and msan build tags and already
+// compile every package's C code with -fsanitize=address or memory. The
+// extra flags here keep frame pointers and line numbers in that code, so
+// the sanitizer's stack traces name the C functions and lines involved.
+//
+// They do not reach libmylib itself, which must be built instrumented too
+// for its own bugs to be caught (cd src; make asan or make msan). MSan in
+// particular needs every C library the program uses instrumented, or it
+// reports reads of memory written by uninstrumented code.
+
+/*
+#cgo CFLAGS: -g -fno-omit-frame-pointer
+*/
+import "C"
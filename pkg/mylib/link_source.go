@@ -0,0 +1,29 @@
+//go:build !nocgo && !windows && (mylib_source || android) && !static
+
+package mylib
+
+// With the mylib_source tag the library is not linked at all: package
+// csrc compiles a copy of src/mylib.c, and the program links that. The
+// copy is part of the module, so a program importing pkg/mylib builds with
+//
+//	go build -tags mylib_source
+//
+// and nothing else, neither make nor pkg-config, and runs without
+// libmylib.so. Each program then has a copy of the library of its own,
+// with its own globals, which is why this is not the default: a process
+// that also loads libmylib.so, directly or through another library, would
+// have two. pkg/mylib/raw still links through pkg-config.
+//
+// Builds for Android compile the library from source without the tag.
+// There is no pkg-config or installed libmylib.so for an Android target,
+// and gomobile bind, which sets CC to the NDK's clang for each ABI it
+// builds, then needs nothing from make either (see mobile).
+//
+// go generate refreshes csrc after a change to src.
+
+/*
+#cgo CFLAGS: -I${SRCDIR}/csrc
+*/
+import "C"
+
+import _ "github.com/lxwagn/using-go-with-c-libraries/pkg/mylib/csrc"
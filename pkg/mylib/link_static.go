@@ -0,0 +1,16 @@
+//go:build !nocgo && !windows && static && !musl
+
+package mylib
+
+// With the static tag the library is linked from lib/libmylib.a (cd src;
+// make static) and, on Linux, the C runtime is linked statically as well,
+// so the program has no shared library dependencies at all. Naming the
+// archive rather than using -lmylib keeps the linker from picking
+// libmylib.so when both exist.
+
+/*
+#cgo CFLAGS: -I${SRCDIR}/../../src
+#cgo LDFLAGS: ${SRCDIR}/../../lib/libmylib.a -lpthread
+#cgo linux LDFLAGS: -static
+*/
+import "C"
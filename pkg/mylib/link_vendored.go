@@ -0,0 +1,18 @@
+//go:build !nocgo && !windows && !android && mylib_vendored && !mylib_source && !static
+
+package mylib
+
+// With the mylib_vendored tag the library is taken from this source tree
+// rather than found with pkg-config. The header comes from src. The
+// library directory depends on the target and is added by the
+// link_vendored_$GOOS_$GOARCH.go files: lib/$GOOS_$GOARCH, where make
+// cross puts a library built for that target, and then lib, where a
+// plain make puts the native one. The directories are also where the
+// program looks for the library at run time. Other targets need a file
+// of their own.
+
+/*
+#cgo CFLAGS: -I${SRCDIR}/../../src
+#cgo LDFLAGS: -lmylib
+*/
+import "C"
@@ -0,0 +1,9 @@
+//go:build !nocgo && mylib_vendored && !mylib_source && !static
+
+package mylib
+
+/*
+#cgo LDFLAGS: -L${SRCDIR}/../../lib/darwin_arm64 -L${SRCDIR}/../../lib
+#cgo LDFLAGS: -Wl,-rpath,${SRCDIR}/../../lib/darwin_arm64 -Wl,-rpath,${SRCDIR}/../../lib
+*/
+import "C"
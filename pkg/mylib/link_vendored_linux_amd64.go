@@ -0,0 +1,9 @@
+//go:build !nocgo && mylib_vendored && !mylib_source && !static
+
+package mylib
+
+/*
+#cgo LDFLAGS: -L${SRCDIR}/../../lib/linux_amd64 -L${SRCDIR}/../../lib
+#cgo LDFLAGS: -Wl,-rpath,${SRCDIR}/../../lib/linux_amd64 -Wl,-rpath,${SRCDIR}/../../lib
+*/
+import "C"
@@ -0,0 +1,112 @@
+//go:build !nocgo && !windows
+
+package mylib
+
+/*
+
+#include "mylib.h"
+
+*/
+import "C"
+
+import (
+	"iter"
+	"runtime"
+	"strings"
+	"unsafe"
+
+	"github.com/lxwagn/using-go-with-c-libraries/pkg/cmem"
+	"github.com/lxwagn/using-go-with-c-libraries/pkg/features"
+)
+
+// A Word is one word of a WordList, copied into Go memory.
+type Word struct {
+	Text   string
+	Offset int // byte offset of the word in the text that was split
+}
+
+// A WordList is a linked list of words built by the C library's
+// mySplitWords. The nodes stay in C memory, owned by the WordList, until
+// Free or CollectSlice releases them; the Words that All and CollectSlice
+// return are copies and stay valid afterwards.
+//
+// As with Buffer, a cleanup frees the list if the WordList becomes
+// unreachable first, but only as a backstop.
+//
+// A WordList is not safe for concurrent use.
+type WordList struct {
+	head    *C.struct_myWord
+	cleanup runtime.Cleanup
+}
+
+// SplitWords splits text on spaces in C and returns the words as a list.
+func SplitWords(text string) (*WordList, error) {
+	if strings.IndexByte(text, 0) >= 0 {
+		return nil, ErrNUL
+	}
+	if err := require(features.WordLists); err != nil {
+		return nil, err
+	}
+
+	ctext := (*C.char)(cmem.CString(text))
+	defer cmem.Free(unsafe.Pointer(ctext))
+
+	var head *C.struct_myWord
+	err := callC(func() error {
+		return codes.Error("mySplitWords", int(C.mySplitWords(ctext, &head)))
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	l := &WordList{head: head}
+	if head != nil {
+		l.cleanup = runtime.AddCleanup(l, freeWords, head)
+	}
+	return l, nil
+}
+
+func freeWords(head *C.struct_myWord) {
+	releaseC(func() {
+		C.myWordsFree(head)
+	})
+}
+
+// All returns an iterator over the words in the list. It walks the C list
+// lazily, copying one node at a time, so breaking out of the loop early
+// costs nothing for the rest of the list. Once the list is freed, All
+// yields nothing; freeing it from the loop body ends the loop.
+func (l *WordList) All() iter.Seq[Word] {
+	return func(yield func(Word) bool) {
+		for n := l.head; n != nil; n = n.next {
+			w := Word{Text: C.GoString(n.text), Offset: int(n.offset)}
+			if !yield(w) || l.head == nil {
+				return
+			}
+		}
+		// Keep the list reachable until its last node has been read;
+		// otherwise the cleanup could free the nodes under the loop.
+		runtime.KeepAlive(l)
+	}
+}
+
+// CollectSlice copies every word into a Go slice and frees the list. The
+// WordList is empty afterwards.
+func (l *WordList) CollectSlice() []Word {
+	var words []Word
+	for w := range l.All() {
+		words = append(words, w)
+	}
+	l.Free()
+	return words
+}
+
+// Free releases the C list. It is safe to call more than once.
+func (l *WordList) Free() {
+	if l.head == nil {
+		return
+	}
+	l.cleanup.Stop()
+	freeWords(l.head)
+	l.head = nil
+}
@@ -0,0 +1,197 @@
+//go:build nocgo && !windows
+
+package mylib
+
+import (
+	"fmt"
+	"path/filepath"
+	"runtime"
+	"sync"
+	"unsafe"
+
+	"github.com/ebitengine/purego"
+
+	"github.com/lxwagn/using-go-with-c-libraries/pkg/libpath"
+	"github.com/lxwagn/using-go-with-c-libraries/pkg/wchar"
+)
+
+// The C functions, bound with purego when the library is first used.
+// Their Go signatures must match mylib.h exactly; purego cannot check
+// them.
+//
+// myScaleStruct takes a struct myStruct by value. On amd64 and arm64 such
+// a 16-byte struct travels in two integer registers, exactly like its two
+// fields passed separately, which is how it is declared here: purego's
+// own support for struct arguments does not lay this one out correctly.
+//
+// myLogf is variadic, which purego does not support either. Where
+// variadic pointer arguments are passed exactly like fixed ones (see
+// variadicArgs), it is bound once per argument count with string
+// parameters, matching the fixed-arity shims of the cgo build.
+var (
+	myPrintFunction   func(s string)
+	myPrintStruct     func(s *cMyStruct)
+	myMakeStruct      func(a int32, b unsafe.Pointer) cMyStruct
+	myScaleStruct     func(a int32, b unsafe.Pointer, factor int32) cMyStruct
+	myTranslatePoints func(pts *Point, n, dx, dy int32)
+	myCallN           func(cb, userdata uintptr, n int32) int32
+	myEachWord        func(text string, cb, userdata uintptr)
+	myLookup          func(key string, value *int32) int32
+	myCounterAdd      func(delta int32) int32
+	myBufferNew       func() uintptr
+	myBufferFree      func(b uintptr)
+	myBufferAppend    func(b uintptr, s string) int32
+	myBufferData      func(b uintptr) *byte
+	myBufferLen       func(b uintptr) uintptr
+	myBufferLive      func() int32
+	mySessionNew      func(name string, limit int64) uintptr
+	mySessionFree     func(s uintptr)
+	mySessionAdd      func(s uintptr, delta int64, total *int64) int32
+	mySessionReset    func(s uintptr) int32
+	mySessionStats    func(s uintptr, total *int64, calls *int32) int32
+	myVersion         func() int32
+	mySessionOwner    func(s uintptr, owner *int32) int32
+	mySessionSync     func(s uintptr, millis int32) int32
+	myInit            func() int32
+	myShutdown        func()
+	myInitialized     func() int32
+	myWideCount       func(s *wchar.Char) int32
+	myWideReverse     func(s *wchar.Char) int32
+	myCrunch          func(iterations int64, cancel *int32, result *int64) int32
+	myGetReducer      func(name string) uintptr
+	myFill            func(buf *byte, n uintptr, seed uint8)
+	myChecksum        func(buf *byte, n uintptr) uint32
+	myFileSize        func(path string) int64
+	myLogf0           func(f string) int32
+	myLogf1           func(f, a string) int32
+	myLogf2           func(f, a, b string) int32
+	myLogf3           func(f, a, b, c string) int32
+	myLogf4           func(f, a, b, c, d string) int32
+
+	puts          func(s string) int32
+	fflush        func(stream uintptr) int32
+	malloc        func(n uintptr) unsafe.Pointer
+	free          func(p unsafe.Pointer)
+	errnoLocation func() *int32
+)
+
+var lib struct {
+	once sync.Once
+	err  error
+}
+
+// libHandle is the library's dlopen handle, once load has succeeded.
+var libHandle uintptr
+
+// libraryDirs returns the directories the library is looked for in:
+// those of libpath.Dirs, then the repository's lib directory, which is
+// what the cgo build's rpath points at.
+func libraryDirs() []string {
+	dirs := libpath.Dirs()
+	if _, file, _, ok := runtime.Caller(0); ok && filepath.IsAbs(file) {
+		dirs = append(dirs, filepath.Join(filepath.Dir(file), "..", "..", "lib"))
+	}
+	return dirs
+}
+
+// load opens libmylib and libc and binds every function above. The result
+// is remembered, so a missing library is reported the same way by every
+// call. The file names and the errno symbol differ by OS, and come from
+// load_nocgo_linux.go and load_nocgo_darwin.go.
+func load() error {
+	lib.once.Do(func() {
+		lib.err = bind()
+	})
+	return lib.err
+}
+
+// mustLoad is load for the functions that have no error result.
+func mustLoad() {
+	if err := load(); err != nil {
+		panic(err)
+	}
+}
+
+func bind() (err error) {
+	var h uintptr
+	_, err = libpath.Search(libName, libraryDirs(), func(path string) (err error) {
+		h, err = purego.Dlopen(path, purego.RTLD_NOW|purego.RTLD_LOCAL)
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("mylib: %w", err)
+	}
+
+	libc, err := purego.Dlopen(libcName, purego.RTLD_NOW|purego.RTLD_GLOBAL)
+	if err != nil {
+		return fmt.Errorf("mylib: cannot load libc: %w", err)
+	}
+
+	// RegisterLibFunc panics on a missing symbol; turn that into an
+	// error like a failed dlopen.
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("mylib: %v", r)
+		}
+	}()
+
+	purego.RegisterLibFunc(&myPrintFunction, h, "myPrintFunction")
+	purego.RegisterLibFunc(&myPrintStruct, h, "myPrintStruct")
+	purego.RegisterLibFunc(&myMakeStruct, h, "myMakeStruct")
+	purego.RegisterLibFunc(&myScaleStruct, h, "myScaleStruct")
+	purego.RegisterLibFunc(&myTranslatePoints, h, "myTranslatePoints")
+	purego.RegisterLibFunc(&myCallN, h, "myCallN")
+	purego.RegisterLibFunc(&myEachWord, h, "myEachWord")
+	purego.RegisterLibFunc(&myLookup, h, "myLookup")
+	purego.RegisterLibFunc(&myCounterAdd, h, "myCounterAdd")
+	purego.RegisterLibFunc(&myBufferNew, h, "myBufferNew")
+	purego.RegisterLibFunc(&myBufferFree, h, "myBufferFree")
+	purego.RegisterLibFunc(&myBufferAppend, h, "myBufferAppend")
+	purego.RegisterLibFunc(&myBufferData, h, "myBufferData")
+	purego.RegisterLibFunc(&myBufferLen, h, "myBufferLen")
+	purego.RegisterLibFunc(&myBufferLive, h, "myBufferLive")
+	purego.RegisterLibFunc(&mySessionNew, h, "mySessionNew")
+	purego.RegisterLibFunc(&mySessionFree, h, "mySessionFree")
+	purego.RegisterLibFunc(&mySessionAdd, h, "mySessionAdd")
+	purego.RegisterLibFunc(&mySessionReset, h, "mySessionReset")
+	purego.RegisterLibFunc(&mySessionStats, h, "mySessionStats")
+	purego.RegisterLibFunc(&myCrunch, h, "myCrunch")
+	purego.RegisterLibFunc(&myGetReducer, h, "myGetReducer")
+	purego.RegisterLibFunc(&myFill, h, "myFill")
+	purego.RegisterLibFunc(&myChecksum, h, "myChecksum")
+	purego.RegisterLibFunc(&myFileSize, h, "myFileSize")
+	purego.RegisterLibFunc(&myLogf0, h, "myLogf")
+	purego.RegisterLibFunc(&myLogf1, h, "myLogf")
+	purego.RegisterLibFunc(&myLogf2, h, "myLogf")
+	purego.RegisterLibFunc(&myLogf3, h, "myLogf")
+	purego.RegisterLibFunc(&myLogf4, h, "myLogf")
+	// Functions an older library may lack; Features says which.
+	optional(&myVersion, h, "myVersion")
+	optional(&mySessionOwner, h, "mySessionOwner")
+	optional(&mySessionSync, h, "mySessionSync")
+	optional(&myWideCount, h, "myWideCount")
+	optional(&myWideReverse, h, "myWideReverse")
+	optional(&myInit, h, "myInit")
+	optional(&myShutdown, h, "myShutdown")
+	optional(&myInitialized, h, "myInitialized")
+	bindConfig(h)
+
+	purego.RegisterLibFunc(&puts, libc, "puts")
+	purego.RegisterLibFunc(&fflush, libc, "fflush")
+	purego.RegisterLibFunc(&malloc, libc, "malloc")
+	purego.RegisterLibFunc(&free, libc, "free")
+	purego.RegisterLibFunc(&errnoLocation, libc, errnoSymbol)
+
+	callNTrampoline = purego.NewCallback(goCallbackTrampoline)
+	eachWordTrampoline = purego.NewCallback(goWordTrampoline)
+	libHandle = h
+	return nil
+}
+
+// optional binds fn like RegisterLibFunc, but leaves it nil if the library
+// has no such symbol.
+func optional(fn any, h uintptr, name string) {
+	if _, err := purego.Dlsym(h, name); err == nil {
+		purego.RegisterLibFunc(fn, h, name)
+	}
+}
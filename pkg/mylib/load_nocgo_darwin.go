@@ -0,0 +1,19 @@
+//go:build nocgo && darwin
+
+package mylib
+
+import "runtime"
+
+// The files and symbols bind looks for on macOS, where libc is part of
+// libSystem.
+const (
+	libName     = "libmylib.dylib"
+	libcName    = "/usr/lib/libSystem.B.dylib"
+	errnoSymbol = "__error"
+)
+
+// variadicArgs reports whether a variadic function's arguments can be
+// passed like fixed ones, which is how myLogf is bound. Apple's arm64
+// calling convention passes them on the stack instead of in registers,
+// so on Apple silicon they cannot.
+const variadicArgs = runtime.GOARCH != "arm64"
@@ -0,0 +1,15 @@
+//go:build nocgo && linux
+
+package mylib
+
+// The files and symbols bind looks for on Linux.
+const (
+	libName     = "libmylib.so"
+	libcName    = "libc.so.6"
+	errnoSymbol = "__errno_location"
+)
+
+// variadicArgs reports whether a variadic function's arguments can be
+// passed like fixed ones, which is how myLogf is bound. On Linux they
+// can: the calling conventions of amd64 and arm64 treat them alike.
+const variadicArgs = true
@@ -0,0 +1,196 @@
+package mylib
+
+import (
+	"fmt"
+	"path/filepath"
+	"runtime"
+	"sync"
+	"unsafe"
+
+	"golang.org/x/sys/windows"
+
+	"github.com/lxwagn/using-go-with-c-libraries/pkg/libpath"
+)
+
+// The C functions in mylib.dll, resolved with GetProcAddress when the
+// library is first used.
+var (
+	procPrintFunctionW  *windows.LazyProc
+	procPrintStruct     *windows.LazyProc
+	procMakeStruct      *windows.LazyProc
+	procScaleStruct     *windows.LazyProc
+	procTranslatePoints *windows.LazyProc
+	procCallN           *windows.LazyProc
+	procEachWord        *windows.LazyProc
+	procLookup          *windows.LazyProc
+	procFileSizeW       *windows.LazyProc
+	procCounterAdd      *windows.LazyProc
+	procBufferNew       *windows.LazyProc
+	procBufferFree      *windows.LazyProc
+	procBufferAppend    *windows.LazyProc
+	procBufferData      *windows.LazyProc
+	procBufferLen       *windows.LazyProc
+	procBufferLive      *windows.LazyProc
+	procSessionNew      *windows.LazyProc
+	procSessionFree     *windows.LazyProc
+	procSessionAdd      *windows.LazyProc
+	procSessionReset    *windows.LazyProc
+	procSessionStats    *windows.LazyProc
+	procVersion         *windows.LazyProc
+	procSessionOwner    *windows.LazyProc
+	procSessionSync     *windows.LazyProc
+	procWideCount       *windows.LazyProc
+	procWideReverse     *windows.LazyProc
+	procInit            *windows.LazyProc
+	procShutdown        *windows.LazyProc
+	procInitialized     *windows.LazyProc
+	procCrunch          *windows.LazyProc
+	procGetReducer      *windows.LazyProc
+	procFill            *windows.LazyProc
+	procChecksum        *windows.LazyProc
+	procLogf            *windows.LazyProc
+)
+
+var lib struct {
+	once sync.Once
+	err  error
+}
+
+// libDLL is the loaded DLL, once load has succeeded.
+var libDLL *windows.LazyDLL
+
+// libraryDirs returns the directories mylib.dll is looked for in: those
+// of libpath.Dirs, which include the standard DLL search order (the
+// executable's directory, the system directories, PATH), then the
+// repository's lib directory.
+func libraryDirs() []string {
+	dirs := libpath.Dirs()
+	if _, file, _, ok := runtime.Caller(0); ok && filepath.IsAbs(file) {
+		dirs = append(dirs, filepath.Join(filepath.Dir(file), "..", "..", "lib"))
+	}
+	return dirs
+}
+
+// load loads mylib.dll and resolves every function above. The result is
+// remembered, so a missing DLL is reported the same way by every call.
+func load() error {
+	lib.once.Do(func() {
+		lib.err = bind()
+	})
+	return lib.err
+}
+
+// mustLoad is load for the functions that have no error result.
+func mustLoad() {
+	if err := load(); err != nil {
+		panic(err)
+	}
+}
+
+func bind() error {
+	var dll *windows.LazyDLL
+	path, err := libpath.Search("mylib.dll", libraryDirs(), func(path string) error {
+		d := windows.NewLazyDLL(path)
+		if err := d.Load(); err != nil {
+			return err
+		}
+		dll = d
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("mylib: %w", err)
+	}
+
+	procs := []struct {
+		p    **windows.LazyProc
+		name string
+	}{
+		{&procPrintFunctionW, "myPrintFunctionW"},
+		{&procPrintStruct, "myPrintStruct"},
+		{&procMakeStruct, "myMakeStruct"},
+		{&procScaleStruct, "myScaleStruct"},
+		{&procTranslatePoints, "myTranslatePoints"},
+		{&procCallN, "myCallN"},
+		{&procEachWord, "myEachWord"},
+		{&procLookup, "myLookup"},
+		{&procFileSizeW, "myFileSizeW"},
+		{&procCounterAdd, "myCounterAdd"},
+		{&procBufferNew, "myBufferNew"},
+		{&procBufferFree, "myBufferFree"},
+		{&procBufferAppend, "myBufferAppend"},
+		{&procBufferData, "myBufferData"},
+		{&procBufferLen, "myBufferLen"},
+		{&procBufferLive, "myBufferLive"},
+		{&procSessionNew, "mySessionNew"},
+		{&procSessionFree, "mySessionFree"},
+		{&procSessionAdd, "mySessionAdd"},
+		{&procSessionReset, "mySessionReset"},
+		{&procSessionStats, "mySessionStats"},
+		{&procCrunch, "myCrunch"},
+		{&procGetReducer, "myGetReducer"},
+		{&procFill, "myFill"},
+		{&procChecksum, "myChecksum"},
+		{&procLogf, "myLogf"},
+	}
+	for _, p := range procs {
+		*p.p = dll.NewProc(p.name)
+		if err := (*p.p).Find(); err != nil {
+			return fmt.Errorf("mylib: %w", err)
+		}
+	}
+
+	// Functions an older DLL may lack; Features says which. Calling a
+	// missing one would panic, so the wrappers check first.
+	for _, p := range []struct {
+		p    **windows.LazyProc
+		name string
+	}{
+		{&procVersion, "myVersion"},
+		{&procSessionOwner, "mySessionOwner"},
+		{&procSessionSync, "mySessionSync"},
+		{&procWideCount, "myWideCount"},
+		{&procWideReverse, "myWideReverse"},
+		{&procInit, "myInit"},
+		{&procShutdown, "myShutdown"},
+		{&procInitialized, "myInitialized"},
+		{&procGetABI, "myGetABI"},
+		{&procCompiler, "myCompiler"},
+		{&procConfigLock, "myConfigLock"},
+		{&procConfigUnlock, "myConfigUnlock"},
+		{&procLogLevel, "myLogLevel"},
+		{&procBufferSize, "myBufferSize"},
+	} {
+		*p.p = dll.NewProc(p.name)
+	}
+	procMulAdd = stdcallProc(dll, "myMulAdd")
+	if err := checkABI(path); err != nil {
+		return err
+	}
+
+	callNTrampoline = windows.NewCallback(goCallbackTrampoline)
+	eachWordTrampoline = windows.NewCallback(goWordTrampoline)
+	libDLL = dll
+	return nil
+}
+
+// cptr turns an address returned by a C function into a pointer. The
+// conversion goes through memory so that vet does not mistake it for
+// arithmetic on a Go pointer; the address is C memory either way.
+func cptr(addr uintptr) unsafe.Pointer {
+	return *(*unsafe.Pointer)(unsafe.Pointer(&addr))
+}
+
+// cString returns a NUL-terminated copy of s for passing to C.
+func cString(s string) *byte {
+	p, err := windows.BytePtrFromString(s)
+	if err != nil {
+		// Callers reject strings holding NUL bytes before getting here.
+		panic(err)
+	}
+	return p
+}
+
+// goString copies the NUL-terminated C string at p.
+func goString(p *byte) string {
+	return windows.BytePtrToString(p)
+}
@@ -0,0 +1,83 @@
+//go:build !nocgo && !windows
+
+package mylib
+
+/*
+
+#include "mylib.h"
+
+// cgo cannot call variadic C functions, so each argument count gets a
+// fixed-arity shim. All arguments are strings; see cFormat.
+static int myLogf0(const char *f) { return myLogf(f); }
+static int myLogf1(const char *f, const char *a) { return myLogf(f, a); }
+static int myLogf2(const char *f, const char *a, const char *b) { return myLogf(f, a, b); }
+static int myLogf3(const char *f, const char *a, const char *b, const char *c) { return myLogf(f, a, b, c); }
+static int myLogf4(const char *f, const char *a, const char *b, const char *c, const char *d) { return myLogf(f, a, b, c, d); }
+
+*/
+import "C"
+
+import (
+	"strings"
+
+	"github.com/lxwagn/using-go-with-c-libraries/pkg/cmem"
+)
+
+// Logf logs a printf-style message through the C library's variadic
+// myLogf and returns the number of bytes it wrote for the message. The
+// format uses C's conversion specs (%d, %5.2f, %s, %x, ...) and takes at
+// most four arguments, whose types must match their verbs.
+// While the log level is below LogLevelInfo, it prints nothing and
+// returns 0. A panic in the handler of the Logger set by SetLogger on the
+// message is raised again, as a *cpanic.Panic, once myLogf has returned.
+func Logf(format string, args ...any) (int, error) {
+	cformat, strs, err := cFormat(format, args)
+	if err != nil {
+		return 0, err
+	}
+	if strings.IndexByte(cformat, 0) >= 0 {
+		return 0, ErrNUL
+	}
+	for _, s := range strs {
+		if strings.IndexByte(s, 0) >= 0 {
+			return 0, ErrNUL
+		}
+	}
+
+	a := cmem.NewArena(0)
+	defer a.Free()
+	f := (*C.char)(a.CString(cformat))
+	cs := make([]*C.char, len(strs))
+	for i, s := range strs {
+		cs[i] = (*C.char)(a.CString(s))
+	}
+
+	b := logBarrier()
+	was := b != nil && b.Panicked()
+	var n C.int
+	err = callC(func() error {
+		var err error
+		switch len(cs) {
+		case 0:
+			n, err = C.myLogf0(f)
+		case 1:
+			n, err = C.myLogf1(f, cs[0])
+		case 2:
+			n, err = C.myLogf2(f, cs[0], cs[1])
+		case 3:
+			n, err = C.myLogf3(f, cs[0], cs[1], cs[2])
+		case 4:
+			n, err = C.myLogf4(f, cs[0], cs[1], cs[2], cs[3])
+		}
+		if n < 0 {
+			return lastError("myLogf", err)
+		}
+		return nil
+	})
+	repanicLog(b, was)
+
+	if err != nil {
+		return 0, err
+	}
+	return int(n), nil
+}
@@ -0,0 +1,52 @@
+//go:build !cgo && !nocgo && !windows
+
+package mylib
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Logf logs a printf-style message prefixed with "mylib: ", as the C
+// library's myLogf does, and returns the number of bytes of the message.
+// The format uses C's conversion specs (%d, %5.2f, %s, %x, ...) and takes
+// at most four arguments, whose types must match their verbs.
+// While the log level is below LogLevelInfo, it prints nothing and
+// returns 0.
+func Logf(format string, args ...any) (int, error) {
+	cformat, strs, err := cFormat(format, args)
+	if err != nil {
+		return 0, err
+	}
+	if strings.IndexByte(cformat, 0) >= 0 {
+		return 0, ErrNUL
+	}
+	for _, s := range strs {
+		if strings.IndexByte(s, 0) >= 0 {
+			return 0, ErrNUL
+		}
+	}
+
+	// cFormat leaves only %s and %%, which mean the same to Sprintf.
+	vals := make([]any, len(strs))
+	for i, s := range strs {
+		vals[i] = s
+	}
+	msg := fmt.Sprintf(cformat, vals...)
+
+	var level int32
+	withConfig(func(l *int32, _ *uintptr) { level = *l })
+	if LogLevel(level) < LogLevelInfo {
+		return 0, nil
+	}
+
+	var n int
+	err = callC(func() error {
+		if _, err := fmt.Println("mylib: " + msg); err != nil {
+			return err
+		}
+		n = len(msg)
+		return nil
+	})
+	return n, err
+}
@@ -0,0 +1,61 @@
+//go:build nocgo && !windows
+
+package mylib
+
+import (
+	"strings"
+)
+
+// Logf logs a printf-style message through the C library's variadic
+// myLogf and returns the number of bytes it wrote for the message. The
+// format uses C's conversion specs (%d, %5.2f, %s, %x, ...) and takes at
+// most four arguments, whose types must match their verbs.
+// While the log level is below LogLevelInfo, it prints nothing and
+// returns 0. On Apple silicon, where myLogf cannot be called with
+// arguments without cgo, a format with verbs fails with ErrNotSupported.
+func Logf(format string, args ...any) (int, error) {
+	cformat, strs, err := cFormat(format, args)
+	if err != nil {
+		return 0, err
+	}
+	if strings.IndexByte(cformat, 0) >= 0 {
+		return 0, ErrNUL
+	}
+	for _, s := range strs {
+		if strings.IndexByte(s, 0) >= 0 {
+			return 0, ErrNUL
+		}
+	}
+	if !variadicArgs && len(strs) > 0 {
+		return 0, ErrNotSupported
+	}
+	if err := load(); err != nil {
+		return 0, err
+	}
+
+	var n int32
+	err = callC(func() error {
+		errno := withErrno(func() {
+			switch len(strs) {
+			case 0:
+				n = myLogf0(cformat)
+			case 1:
+				n = myLogf1(cformat, strs[0])
+			case 2:
+				n = myLogf2(cformat, strs[0], strs[1])
+			case 3:
+				n = myLogf3(cformat, strs[0], strs[1], strs[2])
+			case 4:
+				n = myLogf4(cformat, strs[0], strs[1], strs[2], strs[3])
+			}
+		})
+		if n < 0 {
+			return lastError("myLogf", errno)
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	return int(n), nil
+}
@@ -0,0 +1,61 @@
+package mylib
+
+import (
+	"runtime"
+	"strings"
+	"unsafe"
+)
+
+// Logf logs a printf-style message through the C library's variadic
+// myLogf and returns the number of bytes it wrote for the message. The
+// format uses C's conversion specs (%d, %5.2f, %s, %x, ...) and takes at
+// most four arguments, whose types must match their verbs.
+// While the log level is below LogLevelInfo, it prints nothing and
+// returns 0.
+//
+// Unlike Print, the text reaches the console as bytes, so non-ASCII
+// characters only display correctly with a UTF-8 console code page.
+func Logf(format string, args ...any) (int, error) {
+	cformat, strs, err := cFormat(format, args)
+	if err != nil {
+		return 0, err
+	}
+	if strings.IndexByte(cformat, 0) >= 0 {
+		return 0, ErrNUL
+	}
+	for _, s := range strs {
+		if strings.IndexByte(s, 0) >= 0 {
+			return 0, ErrNUL
+		}
+	}
+	if err := load(); err != nil {
+		return 0, err
+	}
+
+	// Variadic arguments are passed like fixed ones in the Windows
+	// calling conventions, so Call can pass them directly. The strings
+	// are kept alive explicitly because they travel in a slice.
+	ptrs := []*byte{cString(cformat)}
+	for _, s := range strs {
+		ptrs = append(ptrs, cString(s))
+	}
+	cargs := make([]uintptr, len(ptrs))
+	for i, p := range ptrs {
+		cargs[i] = uintptr(unsafe.Pointer(p))
+	}
+
+	var n int32
+	err = callC(func() error {
+		r, _, lastErr := procLogf.Call(cargs...)
+		if n = int32(r); n < 0 {
+			return lastError("myLogf", lastErr)
+		}
+		return nil
+	})
+	runtime.KeepAlive(ptrs)
+
+	if err != nil {
+		return 0, err
+	}
+	return int(n), nil
+}
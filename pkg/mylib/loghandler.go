@@ -0,0 +1,152 @@
+//go:build !nocgo && !windows
+
+package mylib
+
+/*
+
+#include <stdint.h>
+#include "mylib.h"
+
+// Defined in loghandler_export.go.
+extern void goLogHandler(void *r, void *userdata);
+
+static void setLogHandlerGateway(uintptr_t handle) {
+	mySetLogHandler((myLogHandler)goLogHandler, (void *)handle);
+}
+
+*/
+import "C"
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"time"
+	"unsafe"
+
+	"github.com/lxwagn/using-go-with-c-libraries/pkg/cpanic"
+	"github.com/lxwagn/using-go-with-c-libraries/pkg/features"
+	"github.com/lxwagn/using-go-with-c-libraries/pkg/handles"
+)
+
+// logger is the handle of the Logger installed by SetLogger.
+var logger struct {
+	mu  sync.Mutex
+	h   handles.Handle[*logSink]
+	set bool
+}
+
+// A logSink is a Logger installed by SetLogger. Its records come from
+// any C call and from the library's own threads, so the barrier is the
+// logger's rather than one call's: after the first panic the logger's
+// records are dropped.
+type logSink struct {
+	l *slog.Logger
+	b cpanic.Barrier
+}
+
+// logBarrier returns the barrier of the installed logger, or nil.
+func logBarrier() *cpanic.Barrier {
+	logger.mu.Lock()
+	defer logger.mu.Unlock()
+	if !logger.set {
+		return nil
+	}
+	return &logger.h.Value().b
+}
+
+// repanicLog raises again a panic that the logger with barrier b
+// recovered during a C call, once the call has returned; was is whether b
+// had panicked before it.
+func repanicLog(b *cpanic.Barrier, was bool) {
+	if b != nil && !was {
+		b.Repanic()
+	}
+}
+
+// SetLogger sends the C library's log records to l instead of stdout,
+// and with l nil, back to stdout. Each becomes a slog record at the level
+// of LogLevel.SlogLevel, with the C file and line that logged it as the
+// source, where there is one, and its attributes as string attributes.
+// Logf's records have no source. The library drops the records above its
+// own level before formatting them, so at the default LogLevelInfo l sees
+// no debug records; SetLogLevel(LogLevelDebug) leaves the choice to l.
+//
+// The library logs from its own threads too, so l's handler is called on
+// several threads at once, and must be safe for that, as slog handlers
+// are. It must not call into this package. Once SetLogger returns, the
+// logger it replaced is no longer called.
+//
+// A panic in l's handler does not unwind the C library: it is recovered,
+// l's later records are dropped, and Logf raises it again, as a
+// *cpanic.Panic, when it panicked on a record of Logf's own. Panics on the
+// library's threads have no call to raise them in, so SetLogger returns
+// the first panic of the logger it replaces, as a *cpanic.Panic, having
+// replaced it all the same.
+func SetLogger(l *slog.Logger) error {
+	if err := require(features.LogHandler); err != nil {
+		return err
+	}
+	logger.mu.Lock()
+	defer logger.mu.Unlock()
+	old, hadOld := logger.h, logger.set
+
+	swapped := false
+	err := callC(func() error {
+		if l == nil {
+			C.mySetLogHandler(nil, nil)
+			logger.set = false
+		} else {
+			logger.h, logger.set = handles.New(&logSink{l: l}), true
+			C.setLogHandlerGateway(C.uintptr_t(logger.h.Uintptr()))
+		}
+		swapped = true
+		return nil
+	})
+	if !swapped {
+		return err
+	}
+
+	// mySetLogHandler has waited for the calls to the old handler.
+	if hadOld {
+		b := &old.Value().b
+		old.Delete()
+		if p := b.Recovered(); p != nil {
+			return p
+		}
+	}
+	return nil
+}
+
+// SlogLevel returns the slog level of the records logged at l.
+func (l LogLevel) SlogLevel() slog.Level {
+	switch l {
+	case LogLevelError:
+		return slog.LevelError
+	case LogLevelDebug:
+		return slog.LevelDebug
+	}
+	return slog.LevelInfo
+}
+
+type logRecord = C.struct_myLogRecord
+
+// handleLogRecord passes r to h as a slog record. The record's strings
+// are C memory that is freed once the handler returns, so they are
+// copied.
+func handleLogRecord(h slog.Handler, r *logRecord) {
+	ctx := context.Background()
+	level := LogLevel(r.level).SlogLevel()
+	if !h.Enabled(ctx, level) {
+		return
+	}
+	rec := slog.NewRecord(time.Now(), level, C.GoString(r.message), 0)
+	if r.file != nil {
+		rec.AddAttrs(slog.Any(slog.SourceKey, &slog.Source{File: C.GoString(r.file), Line: int(r.line)}))
+	}
+	attrs := unsafe.Slice(r.attrs, 2*int(r.nattrs))
+	for i := 0; i+1 < len(attrs); i += 2 {
+		rec.AddAttrs(slog.String(C.GoString(attrs[i]), C.GoString(attrs[i+1])))
+	}
+	h.Handle(ctx, rec)
+}
@@ -0,0 +1,30 @@
+//go:build !nocgo && !windows
+
+package mylib
+
+import "C"
+
+import (
+	"unsafe"
+
+	"github.com/lxwagn/using-go-with-c-libraries/pkg/cpanic"
+	"github.com/lxwagn/using-go-with-c-libraries/pkg/handles"
+)
+
+// goLogHandler runs on whichever thread logged, often one of the C
+// library's own, which the runtime attaches for the call. r is a struct
+// myLogRecord, passed untyped since among the export files only
+// events_export.go may include mylib.h. A panic in the handler is
+// recovered into the logger's barrier: the library holds logMu while it
+// calls the handler, and unwinding the call would leave it held.
+//
+//export goLogHandler
+func goLogHandler(r, userdata unsafe.Pointer) {
+	s, err := handles.FromUintptr[*logSink](uintptr(userdata)).Get()
+	if err != nil {
+		return
+	}
+	cpanic.Do(&s.b, func() {
+		handleLogRecord(s.l.Handler(), (*logRecord)(r))
+	})
+}
@@ -0,0 +1,220 @@
+//go:build cgo && !nocgo && !windows
+
+package mylib
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"log/slog"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/lxwagn/using-go-with-c-libraries/pkg/cpanic"
+)
+
+// panicHandler is a slog handler that panics on every record.
+type panicHandler struct{ slog.Handler }
+
+func (panicHandler) Enabled(context.Context, slog.Level) bool { return true }
+
+func (panicHandler) Handle(context.Context, slog.Record) error { panic("handler panic") }
+
+// TestLogHandlerPanic checks that a panicking handler is raised again by
+// Logf, after myLogf has released logMu, and by SetLogger replacing it.
+func TestLogHandlerPanic(t *testing.T) {
+	if err := SetLogger(slog.New(panicHandler{})); err != nil {
+		t.Skip(err)
+	}
+	func() {
+		defer func() {
+			if p, ok := recover().(*cpanic.Panic); !ok || p.Value != "handler panic" {
+				t.Errorf("Logf panicked with %v, want the handler's panic", p)
+			}
+		}()
+		Logf("one")
+		t.Error("Logf returned")
+	}()
+	if _, err := Logf("two"); err != nil {
+		t.Errorf("Logf after the panic = %v", err)
+	}
+
+	done := make(chan error)
+	go func() { done <- SetLogger(nil) }()
+	select {
+	case err := <-done:
+		var p *cpanic.Panic
+		if !errors.As(err, &p) || p.Value != "handler panic" {
+			t.Errorf("SetLogger(nil) = %v, want the handler's panic", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("SetLogger(nil) deadlocked after the handler panicked")
+	}
+}
+
+// TestLogRecords checks the text a slog handler makes of a long Logf
+// record and of the library's thread records.
+func TestLogRecords(t *testing.T) {
+	if err := SetLogLevel(LogLevelDebug); err != nil {
+		t.Skip(err)
+	}
+	defer SetLogLevel(LogLevelInfo)
+	var buf bytes.Buffer
+	if err := SetLogger(slog.New(slog.NewTextHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug}))); err != nil {
+		t.Fatal(err)
+	}
+	defer SetLogger(nil)
+
+	long := strings.Repeat("x", 1000)
+	if n, err := Logf("long %s", long); n != 5+len(long) || err != nil {
+		t.Errorf("Logf of a long message = %d, %v; want %d, nil", n, err, 5+len(long))
+	}
+	g, err := StartThreads(1, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for range g.Events() {
+	}
+	g.Wait()
+	if err := SetLogger(nil); err != nil {
+		t.Fatal(err)
+	}
+	out := buf.String()
+	for _, want := range []string{
+		"level=INFO msg=\"long " + long + "\"\n",
+		"level=DEBUG msg=\"thread started\" source=mylib.c:",
+		"level=DEBUG msg=\"thread done\" source=mylib.c:",
+		" thread=0\n",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("log output has no %q:\n%s", want, out)
+		}
+	}
+}
+
+// recordHandler keeps the records it is given, and counts those it is
+// given after retire.
+type recordHandler struct {
+	mu      sync.Mutex
+	records []slog.Record
+	retired atomic.Bool
+	late    atomic.Int64
+}
+
+func (*recordHandler) Enabled(context.Context, slog.Level) bool { return true }
+func (h *recordHandler) WithAttrs([]slog.Attr) slog.Handler     { return h }
+func (h *recordHandler) WithGroup(string) slog.Handler          { return h }
+
+func (h *recordHandler) Handle(_ context.Context, r slog.Record) error {
+	if h.retired.Load() {
+		h.late.Add(1)
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.records = append(h.records, r)
+	return nil
+}
+
+// TestLogHandlerConcurrent swaps loggers while the library's own threads
+// log through them, and Logf does on other goroutines. Every record
+// reaches a handler as the C side logged it, and none reaches a handler
+// after the SetLogger replacing it has returned.
+func TestLogHandlerConcurrent(t *testing.T) {
+	if err := SetLogLevel(LogLevelDebug); err != nil {
+		t.Skip(err)
+	}
+	defer SetLogLevel(LogLevelInfo)
+	defer SetLogger(nil)
+
+	// A logger is installed before anything logs, so that no record goes
+	// to stdout.
+	all := []*recordHandler{{}}
+	if err := SetLogger(slog.New(all[0])); err != nil {
+		t.Fatal(err)
+	}
+
+	stop := make(chan struct{})
+	var wg sync.WaitGroup
+	groups := 0
+	wg.Go(func() {
+		for {
+			select {
+			case <-stop:
+				return
+			default:
+			}
+			g, err := StartThreads(4, 1)
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			for range g.Events() {
+			}
+			groups++
+		}
+	})
+	for range 2 {
+		wg.Go(func() {
+			for {
+				select {
+				case <-stop:
+					return
+				default:
+				}
+				Logf("from Logf")
+			}
+		})
+	}
+
+	for range 50 {
+		h := &recordHandler{}
+		if err := SetLogger(slog.New(h)); err != nil {
+			close(stop)
+			wg.Wait()
+			t.Fatal(err)
+		}
+		all[len(all)-1].retired.Store(true)
+		all = append(all, h)
+		time.Sleep(time.Millisecond)
+	}
+	close(stop)
+	wg.Wait()
+	SetLogger(nil)
+	all[len(all)-1].retired.Store(true)
+
+	var threadRecords, logfRecords int
+	for i, h := range all {
+		if n := h.late.Load(); n != 0 {
+			t.Errorf("handler %d got %d records after SetLogger replaced it", i, n)
+		}
+		for _, r := range h.records {
+			attrs := make(map[string]slog.Value)
+			r.Attrs(func(a slog.Attr) bool {
+				attrs[a.Key] = a.Value
+				return true
+			})
+			switch r.Message {
+			case "thread started", "thread done":
+				threadRecords++
+				src, _ := attrs[slog.SourceKey].Any().(*slog.Source)
+				if r.Level != slog.LevelDebug || src == nil || !strings.HasSuffix(src.File, "mylib.c") || src.Line <= 0 || attrs["thread"].String() == "" {
+					t.Errorf("record %q at %v with attributes %v, want debug with its C source and thread", r.Message, r.Level, attrs)
+				}
+			case "from Logf":
+				logfRecords++
+				if r.Level != slog.LevelInfo || len(attrs) != 0 {
+					t.Errorf("Logf record at %v with attributes %v, want info with none", r.Level, attrs)
+				}
+			default:
+				t.Errorf("unexpected record %q", r.Message)
+			}
+		}
+	}
+	t.Logf("%d thread groups, %d of their records and %d of Logf's over %d loggers", groups, threadRecords, logfRecords, len(all))
+	if threadRecords == 0 || logfRecords == 0 {
+		t.Error("no records reached a handler")
+	}
+}
@@ -0,0 +1,78 @@
+//go:build !nocgo && !windows
+
+package mylib
+
+/*
+
+#include <stdlib.h>
+#include "mylib.h"
+
+*/
+import "C"
+
+import (
+	"strings"
+	"unsafe"
+
+	"github.com/lxwagn/using-go-with-c-libraries/pkg/cmem"
+)
+
+// The Status constants in enums.go are generated from mylib.h so that the
+// nocgo build can use them. These fail to compile if enums.go is stale.
+var (
+	_ [C.MYLIB_ENOTFOUND - StatusNotFound]struct{}
+	_ [StatusNotFound - C.MYLIB_ENOTFOUND]struct{}
+	_ [C.MYLIB_EINVAL - StatusInvalid]struct{}
+	_ [StatusInvalid - C.MYLIB_EINVAL]struct{}
+	_ [C.MYLIB_ERANGE - StatusRange]struct{}
+	_ [StatusRange - C.MYLIB_ERANGE]struct{}
+	_ [C.MYLIB_ECANCELED - StatusCanceled]struct{}
+	_ [StatusCanceled - C.MYLIB_ECANCELED]struct{}
+	_ [C.MYLIB_ENOMEM - StatusNoMemory]struct{}
+	_ [StatusNoMemory - C.MYLIB_ENOMEM]struct{}
+)
+
+// Lookup returns the value stored in the C library's table under key.
+// It returns an error matching ErrNotFound if there is none.
+func Lookup(key string) (int, error) {
+	if strings.IndexByte(key, 0) >= 0 {
+		return 0, ErrNUL
+	}
+
+	ckey := (*C.char)(cmem.CString(key))
+	defer cmem.Free(unsafe.Pointer(ckey))
+
+	var v C.int
+	err := callC(func() error {
+		return codes.Error("myLookup", int(C.myLookup(ckey, &v)))
+	})
+	if err != nil {
+		return 0, err
+	}
+	return int(v), nil
+}
+
+// FileSize returns the size of the regular file at path, as reported by
+// the C library. Failures carry the errno set by C, so they match
+// fs.ErrNotExist and similar.
+func FileSize(path string) (int64, error) {
+	if strings.IndexByte(path, 0) >= 0 {
+		return 0, ErrNUL
+	}
+
+	cpath := (*C.char)(cmem.CString(path))
+	defer cmem.Free(unsafe.Pointer(cpath))
+
+	var n C.long
+	err := callC(func() error {
+		var err error
+		if n, err = C.myFileSize(cpath); n < 0 {
+			return lastError("myFileSize", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	return int64(n), nil
+}
@@ -0,0 +1,75 @@
+//go:build !nocgo && !windows
+
+package mylib
+
+/*
+
+#include "mylib.h"
+
+// cgo evaluates a #define whose value is a constant expression, but it
+// cannot call a function-like macro. Each of these wrappers expands one,
+// and the compiler inlines the expansion into the wrapper's body.
+
+static inline int versionNumber(int major, int minor, int patch) {
+	return MYLIB_VERSION_NUMBER(major, minor, patch);
+}
+
+static inline int valueIsNumber(int kind) {
+	return MYLIB_VALUE_IS_NUMBER(kind);
+}
+
+static inline size_t ringSize(unsigned int capacity) {
+	return MYLIB_RING_SIZE(capacity);
+}
+
+static inline uint64_t ringMessageSize(unsigned int n) {
+	return MYLIB_RING_MESSAGE_SIZE(n);
+}
+
+*/
+import "C"
+
+import "github.com/lxwagn/using-go-with-c-libraries/pkg/cnum"
+
+// HeaderVersion is the version of mylib.h the package was built against,
+// MYLIB_VERSION. The library loaded at run time may be another; the
+// version it reports is in Features.
+const HeaderVersion = C.MYLIB_VERSION
+
+// The ring constants of mylib.h, which defines.go lacks because the header
+// declares rings for Unix only.
+const (
+	RingMagic  = C.MYLIB_RING_MAGIC
+	RingHeader = C.MYLIB_RING_HEADER
+)
+
+// The wrappers below touch none of the library's state, so unlike the
+// others they do not take the library lock. Each is still a cgo call; Go
+// code that needs one in a loop is better off with the arithmetic itself.
+// Like the other wrappers without an error result, they panic on an
+// argument that does not fit in its C type.
+
+// VersionNumber returns the version major.minor.patch as a number, in the
+// form of HeaderVersion and myVersion, through MYLIB_VERSION_NUMBER.
+func VersionNumber(major, minor, patch int) int {
+	return int(C.versionNumber(C.int(cnum.Must(cnum.ToCInt(major))),
+		C.int(cnum.Must(cnum.ToCInt(minor))), C.int(cnum.Must(cnum.ToCInt(patch)))))
+}
+
+// ValueIsNumber reports whether a Value of the given kind holds a
+// number, through MYLIB_VALUE_IS_NUMBER.
+func ValueIsNumber(kind int) bool {
+	return C.valueIsNumber(C.int(cnum.Must(cnum.ToCInt(kind)))) != 0
+}
+
+// RingSize returns the size of the shared memory object holding a ring of
+// capacity bytes, through MYLIB_RING_SIZE.
+func RingSize(capacity int) int {
+	return cnum.Must(cnum.FromCSizeT[int](uint(C.ringSize(C.uint(cnum.Must(cnum.ToCUint(capacity)))))))
+}
+
+// RingMessageSize returns the room a message of n bytes takes in a ring,
+// through MYLIB_RING_MESSAGE_SIZE.
+func RingMessageSize(n int) int {
+	return int(C.ringMessageSize(C.uint(cnum.Must(cnum.ToCUint(n)))))
+}
@@ -0,0 +1,21 @@
+//go:build cgo && !nocgo && !windows
+
+package mylib
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"testing"
+)
+
+// TestMain writes the C library's line counts once the tests have run,
+// if it was built for coverage; see make coverage.
+func TestMain(m *testing.M) {
+	code := m.Run()
+	if _, err := FlushCoverage(); err != nil && !errors.Is(err, errors.ErrUnsupported) {
+		fmt.Fprintln(os.Stderr, err)
+		code = 1
+	}
+	os.Exit(code)
+}
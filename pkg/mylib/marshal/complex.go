@@ -0,0 +1,90 @@
+package marshal
+
+/*
+
+#include <complex.h>
+#include <stddef.h>
+
+// The tests pass values through these to check that C sees the same
+// bits Go does.
+
+static double _Complex complexSame(double _Complex z) {
+	return z;
+}
+
+static void complexParts(double _Complex z, double *re, double *im) {
+	*re = creal(z);
+	*im = cimag(z);
+}
+
+static void complexCopy(double _Complex *dst, const double _Complex *src, size_t n) {
+	size_t i;
+
+	for (i = 0; i < n; i++)
+		dst[i] = src[i];
+}
+
+*/
+import "C"
+
+import (
+	"unsafe"
+
+	"github.com/lxwagn/using-go-with-c-libraries/pkg/cmem"
+)
+
+// cgo maps double _Complex to C.complexdouble and float _Complex to
+// C.complexfloat, whose underlying types are complex128 and complex64. A
+// single number converts either way with a plain conversion, with no
+// need for creal, cimag or a struct of two doubles. C99 also lays out a
+// double _Complex as a double[2], real part first, which is how Go stores
+// a complex128, so arrays can be shared as well.
+
+// ComplexPtr returns a pointer to the first element of z for passing
+// straight to C as a double _Complex array. complex128 holds no Go
+// pointers, so the cgo pointer rules allow this as long as C does not keep
+// the pointer after the call returns.
+func ComplexPtr(z []complex128) unsafe.Pointer {
+	return unsafe.Pointer(unsafe.SliceData(z))
+}
+
+// ComplexView returns a Go slice backed by n double _Complex values at p,
+// without copying. The slice is only valid while the C memory is.
+func ComplexView(p unsafe.Pointer, n int) []complex128 {
+	return unsafe.Slice((*complex128)(p), n)
+}
+
+// CopyComplexToC copies z into a double _Complex array allocated from a.
+func CopyComplexToC(z []complex128, a *cmem.Arena) unsafe.Pointer {
+	p := a.Alloc(len(z) * C.sizeof_complexdouble)
+	cs := unsafe.Slice((*C.complexdouble)(p), len(z))
+	for i, v := range z {
+		cs[i] = C.complexdouble(v)
+	}
+	return p
+}
+
+// CopyComplexFromC copies n double _Complex values at p into dst, which
+// must have room for them.
+func CopyComplexFromC(dst []complex128, p unsafe.Pointer, n int) {
+	for i, v := range unsafe.Slice((*C.complexdouble)(p), n) {
+		dst[i] = complex128(v)
+	}
+}
+
+// cSame passes z to C and back by value.
+func cSame(z complex128) complex128 {
+	return complex128(C.complexSame(C.complexdouble(z)))
+}
+
+// cParts returns creal and cimag of z as C computes them.
+func cParts(z complex128) (re, im float64) {
+	var cre, cim C.double
+	C.complexParts(C.complexdouble(z), &cre, &cim)
+	return float64(cre), float64(cim)
+}
+
+// cCopy has C copy n double _Complex values from src to dst one by one.
+func cCopy(dst, src unsafe.Pointer, n int) {
+	C.complexCopy((*C.complexdouble)(dst), (*C.complexdouble)(src), C.size_t(n))
+}
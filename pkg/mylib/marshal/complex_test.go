@@ -0,0 +1,65 @@
+package marshal
+
+import (
+	"math"
+	"testing"
+
+	"github.com/lxwagn/using-go-with-c-libraries/pkg/cmem"
+)
+
+// awkwardComplex returns numbers whose bits a careless conversion would
+// change: signed zeros, infinities, subnormals and NaNs with payloads.
+func awkwardComplex() []complex128 {
+	parts := []float64{
+		0, math.Copysign(0, -1), 1.5, -2.25,
+		math.Inf(1), math.Inf(-1),
+		math.SmallestNonzeroFloat64, -math.MaxFloat64,
+		math.Float64frombits(0x7ff8000000000001), // quiet NaN with a payload
+		math.Float64frombits(0xfff4000000000abc), // negative signalling NaN
+	}
+	var z []complex128
+	for _, re := range parts {
+		for _, im := range parts {
+			z = append(z, complex(re, im))
+		}
+	}
+	return z
+}
+
+func sameBits(a, b complex128) bool {
+	return math.Float64bits(real(a)) == math.Float64bits(real(b)) &&
+		math.Float64bits(imag(a)) == math.Float64bits(imag(b))
+}
+
+func TestComplexRoundTrip(t *testing.T) {
+	z := awkwardComplex()
+	for _, v := range z {
+		if got := cSame(v); !sameBits(got, v) {
+			t.Errorf("%v came back from C by value as %v", v, got)
+		}
+		if re, im := cParts(v); !sameBits(complex(re, im), v) {
+			t.Errorf("C saw %v as creal %v, cimag %v", v, re, im)
+		}
+	}
+
+	a := cmem.NewArena(0)
+	defer a.Free()
+	p := CopyComplexToC(z, a)
+	back := make([]complex128, len(z))
+	CopyComplexFromC(back, p, len(z))
+	view := ComplexView(p, len(z))
+
+	// Go memory handed to C directly, copied by C element by element.
+	shared := make([]complex128, len(z))
+	cCopy(ComplexPtr(shared), p, len(z))
+	for i, v := range z {
+		switch {
+		case !sameBits(back[i], v):
+			t.Errorf("element %d, %v, copied back as %v", i, v, back[i])
+		case !sameBits(view[i], v):
+			t.Errorf("element %d, %v, viewed as %v", i, v, view[i])
+		case !sameBits(shared[i], v):
+			t.Errorf("element %d, %v, copied by C into Go memory as %v", i, v, shared[i])
+		}
+	}
+}
@@ -0,0 +1,62 @@
+// Package marshal converts between Go structs and the C structs declared
+// in mylib.h.
+//
+// Two strategies are provided. Structs holding C pointers (such as
+// struct myStruct and its char *b) are copied field by field. Structs made
+// only of scalars (such as struct myPoint) have a Go mirror with the same
+// memory layout, so a Go slice of them can be handed to C, or C memory
+// viewed as a Go slice, without copying. The layouts are compared when
+// the package is initialized and the zero-copy path is only used when
+// they match.
+//
+// Pointers to C structs are passed as unsafe.Pointer because cgo types
+// cannot cross package boundaries. The package only needs the header, and
+// takes it from pkg/mylib's copy, so it builds wherever pkg/mylib does.
+package marshal
+
+/*
+
+#cgo CFLAGS: -I${SRCDIR}/../csrc
+#include <stdlib.h>
+#include "mylib.h"
+
+*/
+import "C"
+
+import (
+	"unsafe"
+
+	"github.com/lxwagn/using-go-with-c-libraries/pkg/cmem"
+)
+
+// MyStruct is the Go form of struct myStruct.
+type MyStruct struct {
+	A int
+	B string
+}
+
+// PutMyStruct writes s into the struct myStruct at dst. The C copy of
+// s.B is allocated from a and lives as long as the arena does.
+func PutMyStruct(dst unsafe.Pointer, s MyStruct, a *cmem.Arena) {
+	cs := (*C.struct_myStruct)(dst)
+	cs.a = C.int(s.A)
+	cs.b = (*C.char)(a.CString(s.B))
+}
+
+// GetMyStruct copies the struct myStruct at src into a MyStruct. The C
+// string is copied too, so the result does not refer to C memory.
+func GetMyStruct(src unsafe.Pointer) MyStruct {
+	cs := (*C.struct_myStruct)(src)
+	s := MyStruct{A: int(cs.a)}
+	if cs.b != nil {
+		s.B = C.GoString(cs.b)
+	}
+	return s
+}
+
+// NewMyStruct allocates a struct myStruct in a and fills it from s.
+func NewMyStruct(s MyStruct, a *cmem.Arena) unsafe.Pointer {
+	p := a.Alloc(C.sizeof_struct_myStruct)
+	PutMyStruct(p, s, a)
+	return p
+}
@@ -0,0 +1,128 @@
+package marshal
+
+/*
+
+#include "mylib.h"
+
+*/
+import "C"
+
+import (
+	"fmt"
+	"unsafe"
+
+	"github.com/lxwagn/using-go-with-c-libraries/pkg/cmem"
+)
+
+// Point is the Go mirror of struct myPoint. Its fields are declared in
+// the same order and with the same sizes as the C struct so that the two
+// share a memory layout.
+type Point struct {
+	X      int32
+	Y      int32
+	Weight float64
+}
+
+// pointLayoutErr is non-nil when Point and struct myPoint differ in size,
+// alignment or field offsets.
+var pointLayoutErr = compareLayouts("Point", "struct myPoint", goPointLayout(), cPointLayout())
+
+// A layout is the size, alignment and fields of a struct as one compiler
+// lays it out.
+type layout struct {
+	size, align uintptr
+	fields      []fieldLayout
+}
+
+type fieldLayout struct {
+	name      string
+	off, size uintptr
+}
+
+func goPointLayout() layout {
+	var g Point
+	return layout{unsafe.Sizeof(g), unsafe.Alignof(g), []fieldLayout{
+		{"x", unsafe.Offsetof(g.X), unsafe.Sizeof(g.X)},
+		{"y", unsafe.Offsetof(g.Y), unsafe.Sizeof(g.Y)},
+		{"weight", unsafe.Offsetof(g.Weight), unsafe.Sizeof(g.Weight)},
+	}}
+}
+
+func cPointLayout() layout {
+	var c C.struct_myPoint
+	return layout{C.sizeof_struct_myPoint, unsafe.Alignof(c), []fieldLayout{
+		{"x", unsafe.Offsetof(c.x), unsafe.Sizeof(c.x)},
+		{"y", unsafe.Offsetof(c.y), unsafe.Sizeof(c.y)},
+		{"weight", unsafe.Offsetof(c.weight), unsafe.Sizeof(c.weight)},
+	}}
+}
+
+// compareLayouts returns an error describing the first difference between
+// the Go struct goName, laid out as g, and the C struct cName, laid out as
+// c. Both list the same fields in the same order.
+func compareLayouts(goName, cName string, g, c layout) error {
+	if g.size != c.size {
+		return fmt.Errorf("marshal: %s is %d bytes, %s is %d", goName, g.size, cName, c.size)
+	}
+	if g.align != c.align {
+		return fmt.Errorf("marshal: %s is aligned to %d bytes, %s to %d", goName, g.align, cName, c.align)
+	}
+	for i, gf := range g.fields {
+		cf := c.fields[i]
+		if gf.off != cf.off || gf.size != cf.size {
+			return fmt.Errorf("marshal: field %s is %d bytes at offset %d in Go, %d bytes at offset %d in C",
+				gf.name, gf.size, gf.off, cf.size, cf.off)
+		}
+	}
+	return nil
+}
+
+// PointLayout reports whether Point and struct myPoint share a memory
+// layout. A non-nil error describes the first difference found, and means
+// the zero-copy functions in this package must not be used.
+func PointLayout() error {
+	return pointLayoutErr
+}
+
+// PointsPtr returns a pointer to the first element of pts for passing
+// straight to C as a struct myPoint array. Point holds no Go pointers, so
+// this is allowed by the cgo pointer rules as long as C does not keep the
+// pointer after the call returns. It panics if the layouts differ.
+func PointsPtr(pts []Point) unsafe.Pointer {
+	if pointLayoutErr != nil {
+		panic(pointLayoutErr)
+	}
+	return unsafe.Pointer(unsafe.SliceData(pts))
+}
+
+// PointsView returns a Go slice backed by n struct myPoint values at p,
+// without copying. The slice is only valid while the C memory is. It
+// panics if the layouts differ.
+func PointsView(p unsafe.Pointer, n int) []Point {
+	if pointLayoutErr != nil {
+		panic(pointLayoutErr)
+	}
+	return unsafe.Slice((*Point)(p), n)
+}
+
+// CopyPointsToC copies pts field by field into a struct myPoint array
+// allocated from a. It works whatever the layouts are.
+func CopyPointsToC(pts []Point, a *cmem.Arena) unsafe.Pointer {
+	p := a.Alloc(len(pts) * C.sizeof_struct_myPoint)
+	cs := unsafe.Slice((*C.struct_myPoint)(p), len(pts))
+	for i, pt := range pts {
+		cs[i].x = C.int(pt.X)
+		cs[i].y = C.int(pt.Y)
+		cs[i].weight = C.double(pt.Weight)
+	}
+	return p
+}
+
+// CopyPointsFromC copies n struct myPoint values at p field by field into
+// dst, which must have room for them.
+func CopyPointsFromC(dst []Point, p unsafe.Pointer, n int) {
+	cs := unsafe.Slice((*C.struct_myPoint)(p), n)
+	for i := range cs {
+		dst[i] = Point{X: int32(cs[i].x), Y: int32(cs[i].y), Weight: float64(cs[i].weight)}
+	}
+}
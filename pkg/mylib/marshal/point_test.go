@@ -0,0 +1,61 @@
+package marshal
+
+import (
+	"strings"
+	"testing"
+	"unsafe"
+)
+
+func TestPointLayout(t *testing.T) {
+	if err := PointLayout(); err != nil {
+		t.Fatal(err)
+	}
+	g, c := goPointLayout(), cPointLayout()
+	if g.size != c.size || g.align != c.align {
+		t.Errorf("Point is %d bytes aligned to %d, struct myPoint %d aligned to %d", g.size, g.align, c.size, c.align)
+	}
+	for i, f := range g.fields {
+		if cf := c.fields[i]; f != cf {
+			t.Errorf("field %s: Go %+v, C %+v", f.name, f, cf)
+		}
+	}
+	// Two ints and a double: no padding on any platform cgo supports.
+	want := []uintptr{0, 4, 8}
+	for i, f := range c.fields {
+		if f.off != want[i] {
+			t.Errorf("offsetof(struct myPoint, %s) = %d, want %d", f.name, f.off, want[i])
+		}
+	}
+	if c.size != 16 {
+		t.Errorf("sizeof(struct myPoint) = %d, want 16", c.size)
+	}
+}
+
+// TestPointLayoutMismatch checks that a mirror whose padding differs from
+// the C struct's is caught: with Y after Weight, Go pads X out to 8 bytes
+// and the struct to 24.
+func TestPointLayoutMismatch(t *testing.T) {
+	type misPadded struct {
+		X      int32
+		Weight float64
+		Y      int32
+	}
+	var g misPadded
+	bad := layout{unsafe.Sizeof(g), unsafe.Alignof(g), []fieldLayout{
+		{"x", unsafe.Offsetof(g.X), unsafe.Sizeof(g.X)},
+		{"y", unsafe.Offsetof(g.Y), unsafe.Sizeof(g.Y)},
+		{"weight", unsafe.Offsetof(g.Weight), unsafe.Sizeof(g.Weight)},
+	}}
+	err := compareLayouts("misPadded", "struct myPoint", bad, cPointLayout())
+	if err == nil || !strings.Contains(err.Error(), "misPadded is 24 bytes, struct myPoint is 16") {
+		t.Errorf("compareLayouts = %v, want a size mismatch", err)
+	}
+
+	// Same size, but a field in the wrong place.
+	bad = goPointLayout()
+	bad.fields[1].off = 6
+	err = compareLayouts("Point", "struct myPoint", bad, cPointLayout())
+	if err == nil || !strings.Contains(err.Error(), "field y is 4 bytes at offset 6 in Go, 4 bytes at offset 4 in C") {
+		t.Errorf("compareLayouts = %v, want a field mismatch", err)
+	}
+}
@@ -0,0 +1,274 @@
+package marshal
+
+/*
+
+#include <stddef.h>
+#include <string.h>
+#include "mylib.h"
+
+// cgo turns the union into a byte array, which loses its alignment: the Go
+// view of struct myValue is only 4-byte aligned. The C compiler's own
+// answers are asked for here instead.
+enum {
+	myValueAlign = _Alignof(struct myValue),
+	myValueUnionOffset = offsetof(struct myValue, u),
+	myValueUnionSize = sizeof(((struct myValue *)0)->u),
+};
+
+// cgo cannot address a bitfield, and where the compiler puts each one
+// within its storage unit is up to the ABI. Setting a field to all ones
+// in C and looking at the bits that changed answers that for the compiler
+// that built the library.
+static unsigned int myFlagsMask(int field) {
+	union {
+		struct myFlags f;
+		unsigned int u;
+	} x;
+
+	// Decrementing a zero unsigned bitfield wraps it to all ones.
+	memset(&x, 0, sizeof x);
+	switch (field) {
+	case 0: x.f.readable--; break;
+	case 1: x.f.writable--; break;
+	case 2: x.f.mode--; break;
+	case 3: x.f.level--; break;
+	}
+	return x.u;
+}
+
+*/
+import "C"
+
+import (
+	"errors"
+	"fmt"
+	"math/bits"
+	"unsafe"
+)
+
+// The kinds of Value, from the MYLIB_VALUE_* constants in mylib.h.
+const (
+	KindInt  = C.MYLIB_VALUE_INT
+	KindReal = C.MYLIB_VALUE_REAL
+	KindText = C.MYLIB_VALUE_TEXT
+)
+
+// ErrTextTooLong is returned by SetText for text that does not fit in
+// struct myValue's 16-byte buffer with its terminating NUL.
+var ErrTextTooLong = errors.New("marshal: text too long for myValue")
+
+// Value is the Go mirror of struct myValue, a tagged union. cgo presents
+// a C union as an opaque byte array, so the union member is held the same
+// way here, and the accessors read and write it through unsafe casts to
+// the member's type. uint64 elements give it the union's 8-byte alignment.
+type Value struct {
+	Kind int32
+	u    [2]uint64
+}
+
+// Int returns the long long member. It is only meaningful when Kind is
+// KindInt.
+func (v *Value) Int() int64 {
+	return *(*int64)(unsafe.Pointer(&v.u))
+}
+
+// SetInt stores i and sets Kind to KindInt.
+func (v *Value) SetInt(i int64) {
+	v.u = [2]uint64{}
+	*(*int64)(unsafe.Pointer(&v.u)) = i
+	v.Kind = KindInt
+}
+
+// Real returns the double member. It is only meaningful when Kind is
+// KindReal.
+func (v *Value) Real() float64 {
+	return *(*float64)(unsafe.Pointer(&v.u))
+}
+
+// SetReal stores d and sets Kind to KindReal.
+func (v *Value) SetReal(d float64) {
+	v.u = [2]uint64{}
+	*(*float64)(unsafe.Pointer(&v.u)) = d
+	v.Kind = KindReal
+}
+
+// Text returns the char text[16] member up to its NUL. It is only
+// meaningful when Kind is KindText.
+func (v *Value) Text() string {
+	b := v.text()
+	for i, c := range b {
+		if c == 0 {
+			return string(b[:i])
+		}
+	}
+	return string(b[:])
+}
+
+// SetText stores s and sets Kind to KindText. s must be shorter than 16
+// bytes and contain no NUL.
+func (v *Value) SetText(s string) error {
+	b := v.text()
+	if len(s) >= len(b) {
+		return ErrTextTooLong
+	}
+	for i := 0; i < len(s); i++ {
+		if s[i] == 0 {
+			return errors.New("marshal: text contains NUL byte")
+		}
+	}
+	v.u = [2]uint64{}
+	copy(b[:], s)
+	v.Kind = KindText
+	return nil
+}
+
+func (v *Value) text() *[16]byte {
+	return (*[16]byte)(unsafe.Pointer(&v.u))
+}
+
+func (v *Value) String() string {
+	switch v.Kind {
+	case KindInt:
+		return fmt.Sprint(v.Int())
+	case KindReal:
+		return fmt.Sprint(v.Real())
+	case KindText:
+		return fmt.Sprintf("%q", v.Text())
+	}
+	return fmt.Sprintf("Value{Kind: %d}", v.Kind)
+}
+
+// valueLayoutErr is non-nil when Value and struct myValue differ, or when
+// a union member does not fit the space Value reserves for the union.
+var valueLayoutErr = checkValueLayout()
+
+func checkValueLayout() error {
+	var g Value
+	var c C.struct_myValue
+
+	if unsafe.Sizeof(g) != C.sizeof_struct_myValue {
+		return fmt.Errorf("marshal: Value is %d bytes, struct myValue is %d", unsafe.Sizeof(g), C.sizeof_struct_myValue)
+	}
+	if unsafe.Alignof(g) != C.myValueAlign {
+		return fmt.Errorf("marshal: Value is aligned to %d bytes, struct myValue to %d", unsafe.Alignof(g), C.myValueAlign)
+	}
+	if unsafe.Offsetof(g.Kind) != unsafe.Offsetof(c.kind) || unsafe.Offsetof(g.u) != C.myValueUnionOffset {
+		return fmt.Errorf("marshal: Value fields are at offsets %d and %d, struct myValue's at %d and %d",
+			unsafe.Offsetof(g.Kind), unsafe.Offsetof(g.u), unsafe.Offsetof(c.kind), C.myValueUnionOffset)
+	}
+	if unsafe.Sizeof(g.u) != C.myValueUnionSize {
+		return fmt.Errorf("marshal: Value's union is %d bytes, struct myValue's is %d", unsafe.Sizeof(g.u), C.myValueUnionSize)
+	}
+	if unsafe.Sizeof(C.longlong(0)) != 8 || unsafe.Sizeof(C.double(0)) != 8 {
+		return errors.New("marshal: long long or double is not 8 bytes")
+	}
+	return nil
+}
+
+// ValueLayout reports whether Value and struct myValue share a memory
+// layout. ValuePtr panics if they do not.
+func ValueLayout() error {
+	return valueLayoutErr
+}
+
+// ValuePtr returns v as a pointer to a struct myValue for passing to C.
+// Value holds no Go pointers, so C may read and write it during a call.
+func ValuePtr(v *Value) unsafe.Pointer {
+	if valueLayoutErr != nil {
+		panic(valueLayoutErr)
+	}
+	return unsafe.Pointer(v)
+}
+
+// Flags is the Go form of struct myFlags: the bits of its storage unit.
+// The position of each bitfield is read from C at init rather than
+// assumed, so the accessors match whatever compiler built the library.
+type Flags uint32
+
+// flagMasks holds the mask of each field of struct myFlags, in
+// declaration order.
+var flagMasks [4]uint32
+
+const (
+	flagReadable = iota
+	flagWritable
+	flagMode
+	flagLevel
+)
+
+var flagsLayoutErr = checkFlagsLayout()
+
+func checkFlagsLayout() error {
+	if C.sizeof_struct_myFlags != unsafe.Sizeof(Flags(0)) {
+		return fmt.Errorf("marshal: struct myFlags is %d bytes, Flags is %d", C.sizeof_struct_myFlags, unsafe.Sizeof(Flags(0)))
+	}
+	for i := range flagMasks {
+		flagMasks[i] = uint32(C.myFlagsMask(C.int(i)))
+		if flagMasks[i] == 0 {
+			return fmt.Errorf("marshal: struct myFlags field %d has no bits", i)
+		}
+	}
+	return nil
+}
+
+// FlagsLayout reports whether Flags can represent struct myFlags.
+// FlagsToC and FlagsFromC panic if it cannot.
+func FlagsLayout() error {
+	return flagsLayoutErr
+}
+
+func (f Flags) get(field int) uint32 {
+	m := flagMasks[field]
+	return (uint32(f) & m) >> bits.TrailingZeros32(m)
+}
+
+// set stores v in field, dropping the bits that do not fit, as assigning
+// to a bitfield in C does.
+func (f Flags) set(field int, v uint32) Flags {
+	m := flagMasks[field]
+	return Flags(uint32(f)&^m | (v<<bits.TrailingZeros32(m))&m)
+}
+
+// Readable, Writable, Mode and Level return the fields of struct myFlags.
+func (f Flags) Readable() bool { return f.get(flagReadable) != 0 }
+func (f Flags) Writable() bool { return f.get(flagWritable) != 0 }
+func (f Flags) Mode() int      { return int(f.get(flagMode)) }
+func (f Flags) Level() int     { return int(f.get(flagLevel)) }
+
+// SetReadable and SetWritable return a copy of f with the flag changed.
+func (f Flags) SetReadable(b bool) Flags { return f.set(flagReadable, b2u(b)) }
+func (f Flags) SetWritable(b bool) Flags { return f.set(flagWritable, b2u(b)) }
+
+// SetMode stores m in the 3-bit mode field, keeping only its low bits.
+func (f Flags) SetMode(m int) Flags { return f.set(flagMode, uint32(m)) }
+
+// SetLevel stores l in the 4-bit level field, keeping only its low bits.
+func (f Flags) SetLevel(l int) Flags { return f.set(flagLevel, uint32(l)) }
+
+func b2u(b bool) uint32 {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+func (f Flags) String() string {
+	return fmt.Sprintf("Flags{readable: %t, writable: %t, mode: %d, level: %d}",
+		f.Readable(), f.Writable(), f.Mode(), f.Level())
+}
+
+// FlagsToC stores f in the struct myFlags at dst.
+func FlagsToC(dst unsafe.Pointer, f Flags) {
+	if flagsLayoutErr != nil {
+		panic(flagsLayoutErr)
+	}
+	*(*uint32)(dst) = uint32(f)
+}
+
+// FlagsFromC reads the struct myFlags at src.
+func FlagsFromC(src unsafe.Pointer) Flags {
+	if flagsLayoutErr != nil {
+		panic(flagsLayoutErr)
+	}
+	return Flags(*(*uint32)(src))
+}
@@ -0,0 +1,47 @@
+//go:build !nocgo && !windows
+
+package mylib
+
+/*
+
+#include "mylib.h"
+
+*/
+import "C"
+
+import (
+	"context"
+
+	"github.com/lxwagn/using-go-with-c-libraries/pkg/cmem"
+	"github.com/lxwagn/using-go-with-c-libraries/pkg/features"
+	"github.com/lxwagn/using-go-with-c-libraries/pkg/isolate"
+)
+
+func init() {
+	isolate.Register("mylib.Mean", Mean)
+}
+
+// Mean returns the mean of v as computed by myMean.
+//
+// myMean asserts that v is not empty, and Mean passes v on unchecked, so
+// an empty v aborts the whole process, as a bug in the library would.
+// IsolatedMean contains the failure.
+func Mean(v []int32) (float64, error) {
+	if err := require(features.Mean); err != nil {
+		return 0, err
+	}
+	var m float64
+	err := callC(func() error {
+		m = float64(C.myMean((*C.int)(cmem.BorrowSlice(v)), C.size_t(len(v))))
+		return nil
+	})
+	return m, err
+}
+
+// IsolatedMean is Mean run in w's worker process. If myMean aborts, the
+// worker dies instead of this process, and IsolatedMean returns an
+// *isolate.CrashError holding the assertion message. The program must
+// call isolate.Main at the start of main.
+func IsolatedMean(ctx context.Context, w *isolate.Worker, v []int32) (float64, error) {
+	return isolate.Call[[]int32, float64](ctx, w, "mylib.Mean", v)
+}
@@ -0,0 +1,139 @@
+//go:build !nocgo && !windows
+
+package mylib
+
+/*
+
+#include "mylib.h"
+
+*/
+import "C"
+
+import (
+	"math"
+	"runtime"
+	"unsafe"
+
+	"github.com/lxwagn/using-go-with-c-libraries/pkg/cmem"
+	"github.com/lxwagn/using-go-with-c-libraries/pkg/features"
+)
+
+// messageHeader is the offset of the payload in a struct myMessage. cgo
+// gives the flexible array member data the type [0]C.uchar, which takes
+// no room, so the Go struct has the size of the C one: the header and 2
+// bytes of padding, into which the payload begins.
+const messageHeader = int(unsafe.Offsetof(C.struct_myMessage{}.data))
+
+// A Message is a struct myMessage: a type and a payload of any length,
+// stored after the header in the same C allocation.
+//
+// Call Free when done; a cleanup frees a Message that becomes unreachable
+// first. A Message is not safe for concurrent use.
+type Message struct {
+	mem *cmem.Owned
+}
+
+// MessageSize returns the number of bytes a message with an n-byte
+// payload takes.
+func MessageSize(n int) int {
+	return messageHeader + n
+}
+
+// NewMessage allocates a message of type typ with an n-byte payload of
+// zeros in C memory, for the caller to fill in through Payload, and C to
+// read in place.
+func NewMessage(typ uint16, n int) (*Message, error) {
+	if n < 0 || n > math.MaxUint32 || n > math.MaxInt-messageHeader {
+		return nil, argError("NewMessage", ErrRange)
+	}
+	p := cmem.Calloc(1, MessageSize(n))
+	m := (*C.struct_myMessage)(p)
+	m.length = C.uint(n)
+	m._type = C.ushort(typ)
+	return &Message{cmem.Adopt(p, MessageSize(n), nil)}, nil
+}
+
+// CopyMessage returns a message of type typ holding a copy of payload,
+// allocated by myMessageNew in the library's memory.
+func CopyMessage(typ uint16, payload []byte) (*Message, error) {
+	if err := require(features.Messages); err != nil {
+		return nil, err
+	}
+	var p *C.struct_myMessage
+	err := callC(func() error {
+		var errno error
+		// payload holds no Go pointers, so C may read it in place.
+		p, errno = C.myMessageNew(C.ushort(typ), unsafe.Pointer(unsafe.SliceData(payload)), C.size_t(len(payload)))
+		if p == nil {
+			return lastError("myMessageNew", errno)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	// The size is read from the header the library filled in, not taken
+	// from len(payload), as it would be for a message from anywhere else.
+	return &Message{cmem.Adopt(unsafe.Pointer(p), MessageSize(int(p.length)), libFree)}, nil
+}
+
+// header returns the C struct at the start of the message, or nil after
+// Free.
+func (m *Message) header() *C.struct_myMessage {
+	b := m.mem.Bytes()
+	if b == nil {
+		return nil
+	}
+	return (*C.struct_myMessage)(unsafe.Pointer(unsafe.SliceData(b)))
+}
+
+// Type returns the message's type, or 0 after Free.
+func (m *Message) Type() uint16 {
+	h := m.header()
+	if h == nil {
+		return 0
+	}
+	return uint16(h._type)
+}
+
+// Payload returns the message's payload as a slice of its C memory, with
+// the length in its header. Writes to it change the message. The slice is
+// valid until Free, which m must outlive; Payload returns nil after it.
+func (m *Message) Payload() []byte {
+	h := m.header()
+	if h == nil {
+		return nil
+	}
+	return unsafe.Slice((*byte)(unsafe.Pointer(&h.data)), h.length)
+}
+
+// Bytes returns the whole message, header and payload, as it lies in C
+// memory, under the same terms as Payload.
+func (m *Message) Bytes() []byte {
+	return m.mem.Bytes()
+}
+
+// Checksum returns the FNV-1a hash of the payload as myMessageChecksum
+// computes it from the header. It fails with ErrClosed after Free.
+func (m *Message) Checksum() (uint32, error) {
+	if err := require(features.Messages); err != nil {
+		return 0, err
+	}
+	h := m.header()
+	if h == nil {
+		return 0, ErrClosed
+	}
+	var sum C.uint
+	err := callC(func() error {
+		sum = C.myMessageChecksum(h)
+		return nil
+	})
+	runtime.KeepAlive(m)
+	return uint32(sum), err
+}
+
+// Free frees the message's memory with the allocator it came from. Calls
+// after the first do nothing.
+func (m *Message) Free() {
+	m.mem.Free()
+}
@@ -0,0 +1,415 @@
+// Package mock implements mylib.Client in Go, for testing code that uses
+// the C library through that interface. It needs neither cgo nor the
+// shared library, and returns errors built the way pkg/mylib builds them,
+// so errors.Is(err, mylib.ErrNotFound), mylib.StatusOf and
+// errors.Is(err, fs.ErrNotExist) treat them alike.
+//
+// A test sets up what the library would hold, injects failures and checks
+// what was called:
+//
+//	c := mock.New()
+//	c.SetValue("answer", 42)
+//	c.SetFileSize("/data/input", 1<<20)
+//	c.FailOnce("Session.Add", mylib.ErrNoMemory)
+//
+//	err := report.Run(c) // takes a mylib.Client
+//
+//	if n := c.Calls("Lookup"); n != 1 {
+//		t.Errorf("Lookup called %d times, want 1", n)
+//	}
+//
+// Methods are named as in mylib.Client, and those of a session with a
+// "Session." prefix. Only methods with an error result can be made to
+// fail. The selfcheck's client/mock check compares a Client from New
+// with mylib.Native on the same calls.
+package mock
+
+import (
+	"context"
+	"fmt"
+	"hash/adler32"
+	"math"
+	"slices"
+	"strings"
+	"sync"
+	"syscall"
+
+	"github.com/lxwagn/using-go-with-c-libraries/pkg/cerr"
+	"github.com/lxwagn/using-go-with-c-libraries/pkg/mylib"
+)
+
+var _ mylib.Client = (*Client)(nil)
+
+// A Client is a mylib.Client that keeps its state in Go. It is safe for
+// concurrent use.
+type Client struct {
+	mu      sync.Mutex
+	values  map[string]int
+	files   map[string]int64
+	printed []string
+	calls   map[string]int
+	always  map[string]error
+	once    map[string][]error
+}
+
+// New returns a Client whose table holds what the C library's does: one,
+// two and three, with those values. It knows no files.
+func New() *Client {
+	return &Client{
+		values: map[string]int{"one": 1, "two": 2, "three": 3},
+		files:  make(map[string]int64),
+		calls:  make(map[string]int),
+		always: make(map[string]error),
+		once:   make(map[string][]error),
+	}
+}
+
+// fallible lists the methods FailOn and FailOnce accept.
+var fallible = []string{
+	"Print", "Lookup", "FileSize", "MakeStruct", "ScaleStruct",
+	"CountWords", "WideReverse", "Crunch", "NewSession",
+	"Session.Add", "Session.Reset", "Session.Stats", "Session.Close",
+}
+
+func checkFallible(method string) {
+	if !slices.Contains(fallible, method) {
+		panic("mock: " + method + " cannot fail")
+	}
+}
+
+// FailOn makes every later call of method return err, until FailOn is
+// called again with a nil err. Errors queued by FailOnce come first.
+func (c *Client) FailOn(method string, err error) {
+	checkFallible(method)
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if err == nil {
+		delete(c.always, method)
+	} else {
+		c.always[method] = err
+	}
+}
+
+// FailOnce makes the next call of method return err. Calling it several
+// times queues the errors for as many calls.
+func (c *Client) FailOnce(method string, err error) {
+	checkFallible(method)
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.once[method] = append(c.once[method], err)
+}
+
+// SetValue stores v under key in the table Lookup reads.
+func (c *Client) SetValue(key string, v int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.values[key] = v
+}
+
+// SetFileSize makes FileSize report n for path. A negative n removes it.
+func (c *Client) SetFileSize(path string, n int64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if n < 0 {
+		delete(c.files, path)
+	} else {
+		c.files[path] = n
+	}
+}
+
+// Calls returns how many times method has been called, failed calls
+// included.
+func (c *Client) Calls(method string) int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.calls[method]
+}
+
+// Printed returns the lines passed to Print so far.
+func (c *Client) Printed() []string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return slices.Clone(c.printed)
+}
+
+// call counts a call of method and returns the error injected for it, if
+// any. It is called with c.mu held.
+func (c *Client) call(method string) error {
+	c.calls[method]++
+	if q := c.once[method]; len(q) > 0 {
+		c.once[method] = q[1:]
+		return q[0]
+	}
+	return c.always[method]
+}
+
+// begin is call for methods that take the lock only for it.
+func (c *Client) begin(method string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.call(method)
+}
+
+func status(op string, st mylib.Status, sentinel error) error {
+	return &cerr.Error{Op: op, Code: int(st), Err: sentinel}
+}
+
+// hasNUL reports whether s would be cut short as a C string.
+func hasNUL(s string) bool {
+	return strings.IndexByte(s, 0) >= 0
+}
+
+// toCInt converts v as pkg/mylib does for a C int parameter of op.
+func toCInt(op string, v int) (int32, error) {
+	if v < math.MinInt32 || v > math.MaxInt32 {
+		return 0, status(op, mylib.StatusInvalid, fmt.Errorf("%w: %d does not fit in a C int", mylib.ErrInvalid, v))
+	}
+	return int32(v), nil
+}
+
+// Print records s.
+func (c *Client) Print(s string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if err := c.call("Print"); err != nil {
+		return err
+	}
+	if hasNUL(s) {
+		return mylib.ErrNUL
+	}
+	c.printed = append(c.printed, s)
+	return nil
+}
+
+// Lookup returns the value SetValue stored under key, or one of New's.
+func (c *Client) Lookup(key string) (int, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if err := c.call("Lookup"); err != nil {
+		return 0, err
+	}
+	if hasNUL(key) {
+		return 0, mylib.ErrNUL
+	}
+	v, ok := c.values[key]
+	if !ok {
+		return 0, status("myLookup", mylib.StatusNotFound, mylib.ErrNotFound)
+	}
+	return v, nil
+}
+
+// FileSize returns the size SetFileSize gave path, or an error matching
+// fs.ErrNotExist.
+func (c *Client) FileSize(path string) (int64, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if err := c.call("FileSize"); err != nil {
+		return 0, err
+	}
+	if hasNUL(path) {
+		return 0, mylib.ErrNUL
+	}
+	n, ok := c.files[path]
+	if !ok {
+		return 0, cerr.Errno("myFileSize", syscall.ENOENT)
+	}
+	return n, nil
+}
+
+// MakeStruct returns a MyStruct holding a and b.
+func (c *Client) MakeStruct(a int, b string) (mylib.MyStruct, error) {
+	if err := c.begin("MakeStruct"); err != nil {
+		return mylib.MyStruct{}, err
+	}
+	if hasNUL(b) {
+		return mylib.MyStruct{}, mylib.ErrNUL
+	}
+	ca, err := toCInt("myMakeStruct", a)
+	if err != nil {
+		return mylib.MyStruct{}, err
+	}
+	return mylib.MyStruct{A: int(ca), B: b}, nil
+}
+
+// ScaleStruct multiplies s.A by factor in 32 bits, as C does.
+func (c *Client) ScaleStruct(s mylib.MyStruct, factor int) (mylib.MyStruct, error) {
+	if err := c.begin("ScaleStruct"); err != nil {
+		return mylib.MyStruct{}, err
+	}
+	if hasNUL(s.B) {
+		return mylib.MyStruct{}, mylib.ErrNUL
+	}
+	a, err := toCInt("myScaleStruct", s.A)
+	if err != nil {
+		return mylib.MyStruct{}, err
+	}
+	f, err := toCInt("myScaleStruct", factor)
+	if err != nil {
+		return mylib.MyStruct{}, err
+	}
+	s.A = int(a * f)
+	return s, nil
+}
+
+// TranslatePoints moves every point in pts by (dx, dy).
+func (c *Client) TranslatePoints(pts []mylib.Point, dx, dy int) {
+	c.begin("TranslatePoints")
+	for i := range pts {
+		pts[i].X += int32(dx)
+		pts[i].Y += int32(dy)
+	}
+}
+
+// Fill writes seed, seed+1, ... into b.
+func (c *Client) Fill(b []byte, seed byte) {
+	c.begin("Fill")
+	for i := range b {
+		b[i] = seed + byte(i)
+	}
+}
+
+// Checksum returns the Adler-32 checksum of b, as myChecksum does.
+func (c *Client) Checksum(b []byte) uint32 {
+	c.begin("Checksum")
+	return adler32.Checksum(b)
+}
+
+// CountWords counts the words of text separated by spaces.
+func (c *Client) CountWords(text string) (map[string]int, error) {
+	if err := c.begin("CountWords"); err != nil {
+		return nil, err
+	}
+	if hasNUL(text) {
+		return nil, mylib.ErrNUL
+	}
+	counts := make(map[string]int)
+	for _, w := range strings.Split(text, " ") {
+		if w != "" {
+			counts[w]++
+		}
+	}
+	return counts, nil
+}
+
+// WideReverse reverses s by character.
+func (c *Client) WideReverse(s string) (string, error) {
+	if err := c.begin("WideReverse"); err != nil {
+		return "", err
+	}
+	if hasNUL(s) {
+		return "", mylib.ErrNUL
+	}
+	r := []rune(s)
+	slices.Reverse(r)
+	return string(r), nil
+}
+
+// Crunch computes what myCrunch does, checking ctx as often.
+func (c *Client) Crunch(ctx context.Context, iterations int64) (int64, error) {
+	if err := c.begin("Crunch"); err != nil {
+		return 0, err
+	}
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+	if iterations < 0 {
+		return 0, status("myCrunch", mylib.StatusInvalid, mylib.ErrInvalid)
+	}
+	x := uint64(88172645463325252)
+	for i := range iterations {
+		if i&0xfff == 0 && ctx.Err() != nil {
+			return 0, ctx.Err()
+		}
+		// xorshift64
+		x ^= x << 13
+		x ^= x >> 7
+		x ^= x << 17
+	}
+	return int64(x >> 1), nil
+}
+
+// NewSession returns a session with the C library's rules: Add fails if
+// delta or the new total would leave [-limit, limit].
+func (c *Client) NewSession(name string, limit int64) (mylib.ClientSession, error) {
+	if err := c.begin("NewSession"); err != nil {
+		return nil, err
+	}
+	if hasNUL(name) {
+		return nil, mylib.ErrNUL
+	}
+	if limit < 0 {
+		return nil, status("mySessionNew", mylib.StatusInvalid, mylib.ErrInvalid)
+	}
+	return &session{c: c, name: name, limit: limit}, nil
+}
+
+type session struct {
+	c      *Client
+	name   string
+	limit  int64
+	total  int64
+	calls  int
+	closed bool
+}
+
+func (s *session) Name() string {
+	return s.name
+}
+
+func (s *session) Add(delta int64) (int64, error) {
+	s.c.mu.Lock()
+	defer s.c.mu.Unlock()
+	if err := s.c.call("Session.Add"); err != nil {
+		return 0, err
+	}
+	if s.closed {
+		return 0, mylib.ErrClosed
+	}
+	t := s.total + delta
+	if delta > s.limit || delta < -s.limit || t > s.limit || t < -s.limit {
+		return 0, status("mySessionAdd", mylib.StatusRange, mylib.ErrRange)
+	}
+	s.total = t
+	s.calls++
+	return t, nil
+}
+
+func (s *session) Reset() error {
+	s.c.mu.Lock()
+	defer s.c.mu.Unlock()
+	if err := s.c.call("Session.Reset"); err != nil {
+		return err
+	}
+	if s.closed {
+		return mylib.ErrClosed
+	}
+	s.total, s.calls = 0, 0
+	return nil
+}
+
+func (s *session) Stats() (int64, int, error) {
+	s.c.mu.Lock()
+	defer s.c.mu.Unlock()
+	if err := s.c.call("Session.Stats"); err != nil {
+		return 0, 0, err
+	}
+	if s.closed {
+		return 0, 0, mylib.ErrClosed
+	}
+	return s.total, s.calls, nil
+}
+
+// Close closes the session. Closing it again returns mylib.ErrClosed, as
+// with mylib.Session.
+func (s *session) Close() error {
+	s.c.mu.Lock()
+	defer s.c.mu.Unlock()
+	if err := s.c.call("Session.Close"); err != nil {
+		return err
+	}
+	if s.closed {
+		return mylib.ErrClosed
+	}
+	s.closed = true
+	return nil
+}
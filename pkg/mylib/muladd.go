@@ -0,0 +1,30 @@
+//go:build !nocgo && !windows
+
+package mylib
+
+/*
+
+#include "mylib.h"
+
+*/
+import "C"
+
+import "github.com/lxwagn/using-go-with-c-libraries/pkg/features"
+
+// MulAdd returns a*b + c, as computed by myMulAdd.
+//
+// myMulAdd is declared MYLIB_STDCALL, which is __stdcall on Windows and
+// nothing elsewhere, so this build calls an ordinary C function. The
+// Windows build has no cgo: it finds the function under its decorated
+// name and calls it through a windows.LazyProc (see stdcall_windows.go).
+func MulAdd(a, b int32, c int64) (int64, error) {
+	if err := require(features.MulAdd); err != nil {
+		return 0, err
+	}
+	var r int64
+	err := callC(func() error {
+		r = int64(C.myMulAdd(C.int(a), C.int(b), C.longlong(c)))
+		return nil
+	})
+	return r, err
+}
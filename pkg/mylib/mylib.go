@@ -0,0 +1,82 @@
+//go:build !nocgo && !windows
+
+package mylib
+
+/*
+
+#include "mylib.h"
+#include <stdlib.h>
+#include <stdio.h>
+
+void myPrintFunction2() {
+	printf("Hello from inline C\n");
+	fflush(stdout);
+}
+
+*/
+import "C"
+
+import (
+	"strings"
+	"unsafe"
+
+	"github.com/lxwagn/using-go-with-c-libraries/pkg/cmem"
+	"github.com/lxwagn/using-go-with-c-libraries/pkg/cnum"
+)
+
+// Print writes s followed by a newline using the C library's
+// myPrintFunction.
+func Print(s string) error {
+	if strings.IndexByte(s, 0) >= 0 {
+		return ErrNUL
+	}
+
+	cs := (*C.char)(cmem.CString(s))
+	defer cmem.Free(unsafe.Pointer(cs))
+
+	return callC(func() error {
+		C.myPrintFunction(cs)
+		return nil
+	})
+}
+
+// PrintAll prints each line like Print. The C copies of all lines are
+// carved out of a single arena, so the whole call costs a handful of
+// mallocs instead of one per line.
+func PrintAll(lines []string) error {
+	for _, s := range lines {
+		if strings.IndexByte(s, 0) >= 0 {
+			return ErrNUL
+		}
+	}
+
+	a := cmem.NewArena(0)
+	defer a.Free()
+
+	return callC(func() error {
+		for _, s := range lines {
+			C.myPrintFunction((*C.char)(a.CString(s)))
+		}
+		return nil
+	})
+}
+
+// PrintInline calls the inline C function defined in this package's
+// preamble.
+func PrintInline() {
+	C.myPrintFunction2()
+}
+
+// CounterAdd adds delta to the C library's global counter and returns the
+// new value. The C function is not thread-safe; the package's call guard
+// is what keeps concurrent callers from losing updates. It panics if delta
+// does not fit in a C int.
+func CounterAdd(delta int) int {
+	cdelta := C.int(cnum.Must(cnum.ToCInt(delta)))
+	var n int
+	callC(func() error {
+		n = int(C.myCounterAdd(cdelta))
+		return nil
+	})
+	return n
+}
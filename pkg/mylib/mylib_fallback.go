@@ -0,0 +1,120 @@
+//go:build !cgo && !nocgo && !windows
+
+package mylib
+
+import (
+	"errors"
+	"fmt"
+	"hash/adler32"
+	"os"
+	"strings"
+	"syscall"
+
+	"github.com/lxwagn/using-go-with-c-libraries/pkg/cnum"
+)
+
+// Print writes s followed by a newline, as the C library's
+// myPrintFunction does.
+func Print(s string) error {
+	if strings.IndexByte(s, 0) >= 0 {
+		return ErrNUL
+	}
+
+	return callC(func() error {
+		_, err := fmt.Println(s)
+		return err
+	})
+}
+
+// PrintAll prints each line like Print.
+func PrintAll(lines []string) error {
+	for _, s := range lines {
+		if strings.IndexByte(s, 0) >= 0 {
+			return ErrNUL
+		}
+	}
+
+	return callC(func() error {
+		for _, s := range lines {
+			if _, err := fmt.Println(s); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// PrintInline prints the same greeting as the cgo build's inline C
+// function.
+func PrintInline() {
+	fmt.Println("Hello from inline C")
+}
+
+// counter stands in for the C library's global counter.
+var counter int32
+
+// CounterAdd adds delta to the library's global counter and returns the
+// new value. The counter is 32 bits wide, as in C, and CounterAdd panics
+// if delta does not fit in a C int.
+func CounterAdd(delta int) int {
+	var n int
+	callC(func() error {
+		counter += cnum.Must(cnum.ToCInt(delta))
+		n = int(counter)
+		return nil
+	})
+	return n
+}
+
+// table is the C library's lookup table.
+var table = map[string]int{
+	"one":   1,
+	"two":   2,
+	"three": 3,
+}
+
+// Lookup returns the value stored in the library's table under key. It
+// returns an error matching ErrNotFound if there is none.
+func Lookup(key string) (int, error) {
+	if strings.IndexByte(key, 0) >= 0 {
+		return 0, ErrNUL
+	}
+	v, ok := table[key]
+	if !ok {
+		return 0, codes.Error("myLookup", int(StatusNotFound))
+	}
+	return v, nil
+}
+
+// FileSize returns the size of the regular file at path. Failures carry
+// the errno from stat, as they would from C, so they match
+// fs.ErrNotExist and similar.
+func FileSize(path string) (int64, error) {
+	if strings.IndexByte(path, 0) >= 0 {
+		return 0, ErrNUL
+	}
+
+	fi, err := os.Stat(path)
+	if err != nil {
+		var errno syscall.Errno
+		errors.As(err, &errno)
+		return 0, lastError("myFileSize", errno)
+	}
+	if !fi.Mode().IsRegular() {
+		return 0, lastError("myFileSize", syscall.EINVAL)
+	}
+	return fi.Size(), nil
+}
+
+// Fill writes seed, seed+1, ... into b.
+func Fill(b []byte, seed byte) {
+	for i := range b {
+		b[i] = seed + byte(i)
+	}
+}
+
+// Checksum returns the Adler-32 checksum of b, which is what the C
+// library computes.
+func Checksum(b []byte) uint32 {
+	return adler32.Checksum(b)
+}
@@ -0,0 +1,137 @@
+//go:build nocgo && !windows
+
+package mylib
+
+import (
+	"strings"
+	"unsafe"
+
+	"github.com/lxwagn/using-go-with-c-libraries/pkg/cnum"
+)
+
+// Print writes s followed by a newline using the C library's
+// myPrintFunction.
+func Print(s string) error {
+	if strings.IndexByte(s, 0) >= 0 {
+		return ErrNUL
+	}
+	if err := load(); err != nil {
+		return err
+	}
+
+	return callC(func() error {
+		myPrintFunction(s)
+		return nil
+	})
+}
+
+// PrintAll prints each line like Print.
+func PrintAll(lines []string) error {
+	for _, s := range lines {
+		if strings.IndexByte(s, 0) >= 0 {
+			return ErrNUL
+		}
+	}
+	if err := load(); err != nil {
+		return err
+	}
+
+	return callC(func() error {
+		for _, s := range lines {
+			myPrintFunction(s)
+		}
+		return nil
+	})
+}
+
+// PrintInline prints the same greeting as the cgo build's inline C
+// function. There is no C compiler in this build, so it calls libc
+// directly.
+func PrintInline() {
+	mustLoad()
+	puts("Hello from inline C")
+	fflush(0)
+}
+
+// CounterAdd adds delta to the C library's global counter and returns the
+// new value. It panics if delta does not fit in a C int.
+func CounterAdd(delta int) int {
+	mustLoad()
+
+	var n int
+	callC(func() error {
+		n = int(myCounterAdd(cnum.Must(cnum.ToCInt(delta))))
+		return nil
+	})
+	return n
+}
+
+// Lookup returns the value stored in the C library's table under key.
+// It returns an error matching ErrNotFound if there is none.
+func Lookup(key string) (int, error) {
+	if strings.IndexByte(key, 0) >= 0 {
+		return 0, ErrNUL
+	}
+	if err := load(); err != nil {
+		return 0, err
+	}
+
+	var v int32
+	err := callC(func() error {
+		return codes.Error("myLookup", int(myLookup(key, &v)))
+	})
+	if err != nil {
+		return 0, err
+	}
+	return int(v), nil
+}
+
+// FileSize returns the size of the regular file at path, as reported by
+// the C library. Failures carry the errno set by C, so they match
+// fs.ErrNotExist and similar.
+func FileSize(path string) (int64, error) {
+	if strings.IndexByte(path, 0) >= 0 {
+		return 0, ErrNUL
+	}
+	if err := load(); err != nil {
+		return 0, err
+	}
+
+	var n int64
+	err := callC(func() error {
+		if errno := withErrno(func() { n = myFileSize(path) }); n < 0 {
+			return lastError("myFileSize", errno)
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	return n, nil
+}
+
+// Fill has the C library write seed, seed+1, ... into b.
+func Fill(b []byte, seed byte) {
+	if len(b) == 0 {
+		return
+	}
+	mustLoad()
+
+	callC(func() error {
+		myFill(unsafe.SliceData(b), uintptr(len(b)), seed)
+		return nil
+	})
+}
+
+// Checksum returns the Adler-32 checksum of b as computed by the C
+// library.
+func Checksum(b []byte) uint32 {
+	mustLoad()
+
+	var sum uint32
+	callC(func() error {
+		sum = myChecksum(unsafe.SliceData(b), uintptr(len(b)))
+		return nil
+	})
+	return sum
+}
@@ -0,0 +1,144 @@
+package mylib
+
+import (
+	"fmt"
+	"runtime"
+	"strings"
+	"unsafe"
+
+	"github.com/lxwagn/using-go-with-c-libraries/pkg/cnum"
+	"golang.org/x/sys/windows"
+)
+
+// Print writes s followed by a newline using the C library's
+// myPrintFunctionW. Windows APIs take text as UTF-16, so s is converted
+// rather than passed as bytes in the console's code page.
+func Print(s string) error {
+	if strings.IndexByte(s, 0) >= 0 {
+		return ErrNUL
+	}
+	if err := load(); err != nil {
+		return err
+	}
+
+	ws, err := windows.UTF16PtrFromString(s)
+	if err != nil {
+		return err
+	}
+
+	return callC(func() error {
+		procPrintFunctionW.Call(uintptr(unsafe.Pointer(ws)))
+		return nil
+	})
+}
+
+// PrintAll prints each line like Print.
+func PrintAll(lines []string) error {
+	for _, s := range lines {
+		if err := Print(s); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// PrintInline prints the same greeting as the cgo build's inline C
+// function. There is no C compiler in this build, so it is printed from
+// Go.
+func PrintInline() {
+	fmt.Println("Hello from inline C")
+}
+
+// CounterAdd adds delta to the C library's global counter and returns the
+// new value. It panics if delta does not fit in a C int.
+func CounterAdd(delta int) int {
+	mustLoad()
+
+	var n int
+	callC(func() error {
+		r, _, _ := procCounterAdd.Call(uintptr(cnum.Must(cnum.ToCInt(delta))))
+		n = int(int32(r))
+		return nil
+	})
+	return n
+}
+
+// Lookup returns the value stored in the C library's table under key.
+// It returns an error matching ErrNotFound if there is none.
+func Lookup(key string) (int, error) {
+	if strings.IndexByte(key, 0) >= 0 {
+		return 0, ErrNUL
+	}
+	if err := load(); err != nil {
+		return 0, err
+	}
+
+	var v int32
+	err := callC(func() error {
+		rc, _, _ := procLookup.Call(uintptr(unsafe.Pointer(cString(key))), uintptr(unsafe.Pointer(&v)))
+		return codes.Error("myLookup", int(int32(rc)))
+	})
+	if err != nil {
+		return 0, err
+	}
+	return int(v), nil
+}
+
+// FileSize returns the size of the regular file at path, as reported by
+// the C library. Failures carry the Windows error code set by C, so they
+// match fs.ErrNotExist and similar.
+func FileSize(path string) (int64, error) {
+	if strings.IndexByte(path, 0) >= 0 {
+		return 0, ErrNUL
+	}
+	if err := load(); err != nil {
+		return 0, err
+	}
+
+	wpath, err := windows.UTF16PtrFromString(path)
+	if err != nil {
+		return 0, err
+	}
+
+	var n int64
+	err = callC(func() error {
+		r, _, lastErr := procFileSizeW.Call(uintptr(unsafe.Pointer(wpath)))
+		if n = int64(r); n < 0 {
+			return lastError("myFileSizeW", lastErr)
+		}
+		return nil
+	})
+	runtime.KeepAlive(wpath)
+
+	if err != nil {
+		return 0, err
+	}
+	return n, nil
+}
+
+// Fill has the C library write seed, seed+1, ... into b.
+func Fill(b []byte, seed byte) {
+	if len(b) == 0 {
+		return
+	}
+	mustLoad()
+
+	callC(func() error {
+		procFill.Call(uintptr(unsafe.Pointer(unsafe.SliceData(b))), uintptr(len(b)), uintptr(seed))
+		return nil
+	})
+}
+
+// Checksum returns the Adler-32 checksum of b as computed by the C
+// library.
+func Checksum(b []byte) uint32 {
+	mustLoad()
+
+	var sum uint32
+	callC(func() error {
+		r, _, _ := procChecksum.Call(uintptr(unsafe.Pointer(unsafe.SliceData(b))), uintptr(len(b)))
+		sum = uint32(r)
+		return nil
+	})
+	return sum
+}
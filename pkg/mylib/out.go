@@ -0,0 +1,68 @@
+//go:build !nocgo && !windows
+
+package mylib
+
+/*
+
+#include "mylib.h"
+
+*/
+import "C"
+
+import (
+	"runtime"
+	"strings"
+	"unsafe"
+
+	"github.com/lxwagn/using-go-with-c-libraries/pkg/cmem"
+	"github.com/lxwagn/using-go-with-c-libraries/pkg/cptr"
+	"github.com/lxwagn/using-go-with-c-libraries/pkg/features"
+)
+
+// ParseKeyValue splits line at its first '=' into a key and a value
+// without surrounding blanks, as the C library's myParseKeyValue does. It
+// fails with ErrInvalid if there is no '=' or the key is empty.
+func ParseKeyValue(line string) (key, value string, err error) {
+	if strings.IndexByte(line, 0) >= 0 {
+		return "", "", ErrNUL
+	}
+	if err := require(features.OutParams); err != nil {
+		return "", "", err
+	}
+	cline := (*C.char)(cmem.CString(line))
+	defer cmem.Free(unsafe.Pointer(cline))
+
+	k, v, err := cptr.Receive2(func(k, v **C.char) error {
+		return callC(func() error {
+			return codes.Error("myParseKeyValue", int(C.myParseKeyValue(cline, k, v)))
+		})
+	})
+	if err != nil {
+		return "", "", err
+	}
+	return cmem.TakeCString(unsafe.Pointer(k), libFree), cmem.TakeCString(unsafe.Pointer(v), libFree), nil
+}
+
+// NewBufferFrom creates a Buffer holding s, with myBufferFrom.
+func NewBufferFrom(s string) (*Buffer, error) {
+	if strings.IndexByte(s, 0) >= 0 {
+		return nil, ErrNUL
+	}
+	if err := require(features.OutParams); err != nil {
+		return nil, err
+	}
+	cs := (*C.char)(cmem.CString(s))
+	defer cmem.Free(unsafe.Pointer(cs))
+
+	p, err := cptr.Receive(func(out **C.myBuffer) error {
+		return callC(func() error {
+			return codes.Error("myBufferFrom", int(C.myBufferFrom(cs, out)))
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+	b := &Buffer{p: p}
+	b.cleanup = runtime.AddCleanup(b, freeBuffer, p)
+	return b, nil
+}
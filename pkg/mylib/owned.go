@@ -0,0 +1,114 @@
+//go:build !nocgo && !windows
+
+package mylib
+
+/*
+
+#include "mylib.h"
+
+*/
+import "C"
+
+import (
+	"strings"
+	"unsafe"
+
+	"github.com/lxwagn/using-go-with-c-libraries/pkg/cmem"
+	"github.com/lxwagn/using-go-with-c-libraries/pkg/cnum"
+	"github.com/lxwagn/using-go-with-c-libraries/pkg/features"
+)
+
+// libFree frees memory the library returned. It goes through myFree
+// rather than C.free, since the library may allocate with the functions
+// given to SetAllocator.
+func libFree(p unsafe.Pointer) {
+	releaseC(func() {
+		C.myFree(p)
+	})
+}
+
+// Join returns parts joined by sep, as the C library's myJoin builds them
+// in memory it allocates. The result is copied into Go and the C string
+// freed before Join returns.
+func Join(parts []string, sep string) (string, error) {
+	if strings.IndexByte(sep, 0) >= 0 {
+		return "", ErrNUL
+	}
+	for _, s := range parts {
+		if strings.IndexByte(s, 0) >= 0 {
+			return "", ErrNUL
+		}
+	}
+	if err := require(features.Ownership); err != nil {
+		return "", err
+	}
+
+	cparts := cmem.CStrings(parts)
+	defer cmem.Free(cparts)
+	csep := (*C.char)(cmem.CString(sep))
+	defer cmem.Free(unsafe.Pointer(csep))
+
+	var p *C.char
+	err := callC(func() error {
+		var err error
+		if p, err = C.myJoin((**C.char)(cparts), C.size_t(len(parts)), csep); p == nil {
+			return lastError("myJoin", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return "", err
+	}
+	return cmem.TakeCString(unsafe.Pointer(p), libFree), nil
+}
+
+// Repeat returns times copies of b, which myRepeat builds in C. The
+// result is copied into Go and the C buffer freed before Repeat returns.
+func Repeat(b []byte, times int) ([]byte, error) {
+	p, n, err := repeat(b, times)
+	if err != nil {
+		return nil, err
+	}
+	return cmem.TakeCBytes(p, n, libFree), nil
+}
+
+// RepeatOwned is Repeat without the copy: the result stays in the C
+// library's memory, which the caller frees with Owned.Free.
+func RepeatOwned(b []byte, times int) (*cmem.Owned, error) {
+	p, n, err := repeat(b, times)
+	if err != nil {
+		return nil, err
+	}
+	return cmem.Adopt(p, n, libFree), nil
+}
+
+func repeat(b []byte, times int) (unsafe.Pointer, int, error) {
+	ctimes, err := cnum.ToCSizeT(times)
+	if err != nil {
+		return nil, 0, argError("myRepeat", err)
+	}
+	if err := require(features.Ownership); err != nil {
+		return nil, 0, err
+	}
+
+	var n C.size_t
+	var p unsafe.Pointer
+	err = callC(func() error {
+		var errno error
+		// b holds no Go pointers, so C may read it in place.
+		p, errno = C.myRepeat(unsafe.Pointer(unsafe.SliceData(b)), C.size_t(len(b)), C.size_t(ctimes), &n)
+		if p == nil {
+			return lastError("myRepeat", errno)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, 0, err
+	}
+	size, err := cnum.FromCSizeT[int](uint(n))
+	if err != nil {
+		libFree(p)
+		return nil, 0, argError("myRepeat", err)
+	}
+	return p, size, nil
+}
@@ -0,0 +1,103 @@
+//go:build cgo && !nocgo && !windows
+
+package mylib
+
+import (
+	"bytes"
+	"errors"
+	"math"
+	"runtime"
+	"syscall"
+	"testing"
+	"time"
+)
+
+// allocations returns the library's live allocations.
+func allocations(t *testing.T) int64 {
+	t.Helper()
+	n, err := Allocations()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return n
+}
+
+// TestTake checks that memory the library hands over is freed, through
+// its own allocator, once the result has been copied into Go.
+func TestTake(t *testing.T) {
+	before := allocations(t)
+	s, err := Join([]string{"a", "", "cgo", "ü"}, ", ")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "a, , cgo, ü"; s != want {
+		t.Errorf("Join = %q, want %q", s, want)
+	}
+	if s, err := Join(nil, "-"); err != nil || s != "" {
+		t.Errorf("Join(nil) = %q, %v", s, err)
+	}
+	b, err := Repeat([]byte{1, 2, 3}, 4)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := bytes.Repeat([]byte{1, 2, 3}, 4); !bytes.Equal(b, want) {
+		t.Errorf("Repeat = %v, want %v", b, want)
+	}
+	if _, err := Repeat([]byte{1, 2, 3}, math.MaxInt); !errors.Is(err, syscall.ERANGE) {
+		t.Errorf("Repeat past the end of size_t: %v, want ERANGE", err)
+	}
+	if _, err := Join([]string{"a\x00b"}, ""); !errors.Is(err, ErrNUL) {
+		t.Errorf("Join of a NUL: %v, want ErrNUL", err)
+	}
+	if after := allocations(t); after != before {
+		t.Errorf("%d library allocations live afterwards, %d before", after, before)
+	}
+}
+
+// TestAdopt checks that adopted memory stays allocated until Free, and
+// that only the first Free frees it.
+func TestAdopt(t *testing.T) {
+	before := allocations(t)
+	o, err := RepeatOwned([]byte("ab"), 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer o.Free()
+	if got := string(o.Bytes()); got != "ababab" || o.Len() != 6 {
+		t.Errorf("RepeatOwned = %q of length %d", got, o.Len())
+	}
+	if during := allocations(t); during != before+1 {
+		t.Errorf("%d library allocations live while adopted, want %d", during, before+1)
+	}
+	o.Free()
+	o.Free()
+	if o.Bytes() != nil || o.Len() != 0 {
+		t.Error("Bytes or Len still give the memory after Free")
+	}
+	if after := allocations(t); after != before {
+		t.Errorf("%d library allocations live after Free, %d before", after, before)
+	}
+}
+
+// TestAdoptCleanup drops adopted buffers unfreed. Their cleanups free
+// them, at some collection after they become unreachable.
+func TestAdoptCleanup(t *testing.T) {
+	before := allocations(t)
+	for range 10 {
+		if _, err := RepeatOwned([]byte("lost"), 1000); err != nil {
+			t.Fatal(err)
+		}
+	}
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		runtime.GC()
+		n := allocations(t)
+		if n <= before {
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("%d library allocations still live, %d before", n, before)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
@@ -0,0 +1,81 @@
+//go:build !nocgo && !windows
+
+package mylib
+
+/*
+
+#include <stdlib.h>
+#include "mylib.h"
+
+// myParseRecord reports a malformed record by longjmp instead of
+// returning, and Go can neither call setjmp nor be jumped over: the frame
+// that called setjmp must still be running when the jump comes, and a
+// longjmp across Go frames corrupts the goroutine's stack. parseRecord
+// is that frame. It turns the jump into a status code, so everything the
+// jump skips is C.
+static int parseRecord(myParser *p, const char *record, long long *value) {
+	if (setjmp(*myParserJmpbuf(p)) != 0)
+		return MYLIB_EINVAL;
+	*value = myParseRecord(p, record);
+	return MYLIB_OK;
+}
+
+*/
+import "C"
+
+import (
+	"strconv"
+	"strings"
+	"unsafe"
+
+	"github.com/lxwagn/using-go-with-c-libraries/pkg/cmem"
+	"github.com/lxwagn/using-go-with-c-libraries/pkg/features"
+)
+
+// A ParseError reports a record the C parser rejected, with the parser's
+// message. It matches ErrInvalid.
+type ParseError struct {
+	Record string
+	Msg    string
+}
+
+func (e *ParseError) Error() string {
+	return "mylib: myParseRecord " + strconv.Quote(e.Record) + ": " + e.Msg
+}
+
+func (e *ParseError) Is(target error) bool {
+	return target == ErrInvalid
+}
+
+// ParseRecord parses record, "key=value" with value a decimal integer, and
+// returns the value. The C parser reports a malformed record with
+// longjmp, which a shim catches; the error is then a *ParseError.
+func ParseRecord(record string) (int64, error) {
+	if err := require(features.Parsers); err != nil {
+		return 0, err
+	}
+	if strings.IndexByte(record, 0) >= 0 {
+		return 0, ErrNUL
+	}
+
+	crecord := (*C.char)(cmem.CString(record))
+	defer cmem.Free(unsafe.Pointer(crecord))
+
+	var value C.longlong
+	err := callC(func() error {
+		p := C.myParserNew()
+		if p == nil {
+			return codes.Error("myParserNew", int(StatusNoMemory))
+		}
+		defer C.myParserFree(p)
+
+		if C.parseRecord(p, crecord, &value) != C.MYLIB_OK {
+			return &ParseError{Record: record, Msg: C.GoString(C.myParserError(p))}
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	return int64(value), nil
+}
@@ -0,0 +1,119 @@
+package mylib
+
+import (
+	"runtime"
+
+	"github.com/lxwagn/using-go-with-c-libraries/pkg/cworker"
+)
+
+// A SessionOption configures a Session created by NewSession.
+type SessionOption func(*sessionConfig)
+
+type sessionConfig struct {
+	pinned  bool
+	workers *cworker.Pool
+}
+
+// PinThread makes the session run every call into the C library, from
+// mySessionNew to mySessionFree, on one OS thread dedicated to it.
+//
+// Goroutines move between OS threads freely, so without it consecutive
+// calls on a session may come from different threads. That matters to C
+// libraries that keep state in thread-local storage or check that they are
+// used from the thread that set them up. A pinned session costs a thread
+// and two channel operations per call.
+func PinThread() SessionOption {
+	return func(c *sessionConfig) {
+		c.pinned = true
+	}
+}
+
+// UseWorkers makes the session run every call into the C library on one
+// of p's threads, so that sessions whose calls block in C, such as a slow
+// Sync, take no more threads than p has, however many goroutines use
+// them. The pool may be shared by any number of sessions, and must
+// outlive them: once p is closed, the session's calls run on the
+// caller's goroutine instead.
+//
+// With the default call guard only one call is in C at a time anyway,
+// except those like Sync that leave the library unlocked; the pool
+// matters most to them and to a build with -tags mylib_nolock. A later
+// PinThread overrides UseWorkers, and the other way round.
+func UseWorkers(p *cworker.Pool) SessionOption {
+	return func(c *sessionConfig) {
+		c.pinned = false
+		c.workers = p
+	}
+}
+
+func newSessionConfig(opts []SessionOption) sessionConfig {
+	var c sessionConfig
+	for _, opt := range opts {
+		opt(&c)
+	}
+	return c
+}
+
+// thread returns what the session's calls run on: nil, the caller's
+// goroutine, unless an option said otherwise.
+func (c sessionConfig) thread() *sessionThread {
+	switch {
+	case c.workers != nil:
+		return &sessionThread{pool: c.workers}
+	case c.pinned:
+		return newPinnedThread()
+	}
+	return nil
+}
+
+// A sessionThread runs functions on a single goroutine locked to its OS
+// thread, or on one of a pool's threads. A nil *sessionThread runs them
+// on the caller's goroutine.
+type sessionThread struct {
+	reqs chan func()
+	pool *cworker.Pool
+}
+
+func newPinnedThread() *sessionThread {
+	t := &sessionThread{reqs: make(chan func())}
+	go func() {
+		// The thread is never unlocked, so when the goroutine returns
+		// the runtime terminates it rather than handing it, with
+		// whatever thread-local state C left behind, to other
+		// goroutines.
+		runtime.LockOSThread()
+		for f := range t.reqs {
+			f()
+		}
+	}()
+	return t
+}
+
+// run calls f on the thread and waits for it to return.
+func (t *sessionThread) run(f func()) {
+	if t == nil {
+		f()
+		return
+	}
+	if t.pool != nil {
+		if t.pool.Submit(f) == cworker.ErrClosed {
+			f()
+		}
+		return
+	}
+	done := make(chan struct{})
+	t.reqs <- func() {
+		defer close(done)
+		f()
+	}
+	<-done
+}
+
+// stop ends the thread once the functions already passed to run have
+// returned, and leaves a pool running. The thread must not be used
+// afterwards.
+func (t *sessionThread) stop() {
+	if t != nil && t.pool == nil {
+		close(t.reqs)
+	}
+}
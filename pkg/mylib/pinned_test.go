@@ -0,0 +1,82 @@
+package mylib
+
+import (
+	"errors"
+	"runtime"
+	"sync"
+	"testing"
+)
+
+// TestPinThread calls a pinned session from many goroutines, which the
+// scheduler spreads over many threads, while the collector runs back to
+// back. Every call must still reach C on the thread that created the
+// session.
+func TestPinThread(t *testing.T) {
+	const goroutines, calls = 16, 200
+	s, err := NewSession("pinned", 1<<40, PinThread())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	if _, err := s.OnCreatingThread(); errors.Is(err, ErrNotSupported) {
+		t.Skip(err)
+	}
+
+	stop := make(chan struct{})
+	gcDone := make(chan struct{})
+	go func() {
+		defer close(gcDone)
+		for {
+			select {
+			case <-stop:
+				return
+			default:
+				runtime.GC()
+			}
+		}
+	}()
+	var wg sync.WaitGroup
+	for range goroutines {
+		wg.Go(func() {
+			for range calls {
+				if _, err := s.Add(1); err != nil {
+					t.Error(err)
+					return
+				}
+				ok, err := s.OnCreatingThread()
+				if err != nil {
+					t.Error(err)
+					return
+				}
+				if !ok {
+					t.Error("call ran on another thread")
+					return
+				}
+			}
+		})
+	}
+	wg.Wait()
+	close(stop)
+	<-gcDone
+
+	total, n, err := s.Stats()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if total != goroutines*calls || n != goroutines*calls {
+		t.Errorf("Stats() = %d, %d; want %d, %d", total, n, goroutines*calls, goroutines*calls)
+	}
+}
+
+func TestPinThreadClose(t *testing.T) {
+	s, err := NewSession("pinned", 10, PinThread())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.Add(1); !errors.Is(err, ErrClosed) {
+		t.Errorf("Add after Close: got %v, want ErrClosed", err)
+	}
+}
@@ -0,0 +1,48 @@
+//go:build !nocgo && !windows && !static && !mylib_source && !android
+
+package mylib
+
+/*
+
+#cgo linux LDFLAGS: -ldl
+#define _GNU_SOURCE
+#include <dlfcn.h>
+#include "mylib.h"
+
+// The library is linked in, so RTLD_DEFAULT finds its symbols. With lazy
+// binding, functions an older build lacks stay unresolved without stopping
+// the program, as long as they are never called; the wrappers rely on
+// that.
+static int hasSymbol(const char *name) {
+	return dlsym(RTLD_DEFAULT, name) != NULL;
+}
+
+static int callVersion(void) {
+	int (*fn)(void) = (int (*)(void))dlsym(RTLD_DEFAULT, "myVersion");
+	return fn != NULL ? fn() : 0;
+}
+
+*/
+import "C"
+
+import (
+	"unsafe"
+
+	"github.com/lxwagn/using-go-with-c-libraries/pkg/cmem"
+	"github.com/lxwagn/using-go-with-c-libraries/pkg/features"
+)
+
+func probe() features.Set {
+	return features.Probe(func(symbol string) bool {
+		cs := (*C.char)(cmem.CString(symbol))
+		defer cmem.Free(unsafe.Pointer(cs))
+		return C.hasSymbol(cs) != 0
+	}, func() int {
+		var v int
+		callC(func() error {
+			v = int(C.callVersion())
+			return nil
+		})
+		return v
+	})
+}
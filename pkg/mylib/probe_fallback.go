@@ -0,0 +1,26 @@
+//go:build !cgo && !nocgo && !windows
+
+package mylib
+
+import (
+	"slices"
+
+	"github.com/lxwagn/using-go-with-c-libraries/pkg/features"
+)
+
+// fallbackVersion is the version of mylib.h the fallback reimplements.
+const fallbackVersion = 1_00_00
+
+// The fallback has the features whose functions it reimplements, and
+// reports the version of the header it follows.
+func probe() features.Set {
+	return features.Probe(func(symbol string) bool {
+		return symbol == features.VersionSymbol ||
+			slices.Contains(features.WideStrings.Symbols(), symbol) ||
+			slices.Contains(features.SessionSync.Symbols(), symbol) ||
+			slices.Contains(features.Lifecycle.Symbols(), symbol) ||
+			slices.Contains(features.Config.Symbols(), symbol)
+	}, func() int {
+		return fallbackVersion
+	})
+}
@@ -0,0 +1,26 @@
+//go:build nocgo && !windows
+
+package mylib
+
+import (
+	"github.com/ebitengine/purego"
+
+	"github.com/lxwagn/using-go-with-c-libraries/pkg/features"
+)
+
+func probe() features.Set {
+	if err := load(); err != nil {
+		return features.Set{}
+	}
+	return features.Probe(func(symbol string) bool {
+		_, err := purego.Dlsym(libHandle, symbol)
+		return err == nil
+	}, func() int {
+		var v int
+		callC(func() error {
+			v = int(myVersion())
+			return nil
+		})
+		return v
+	})
+}
@@ -0,0 +1,26 @@
+//go:build !nocgo && !windows && (static || mylib_source || android)
+
+package mylib
+
+/*
+
+#include "mylib.h"
+
+*/
+import "C"
+
+import "github.com/lxwagn/using-go-with-c-libraries/pkg/features"
+
+// A static build links the library it was compiled against, and a
+// mylib_source or Android build compiles it, so every feature in mylib.h
+// is there.
+func probe() features.Set {
+	return features.Probe(func(string) bool { return true }, func() int {
+		var v int
+		callC(func() error {
+			v = int(C.myVersion())
+			return nil
+		})
+		return v
+	})
+}
@@ -0,0 +1,23 @@
+package mylib
+
+import "github.com/lxwagn/using-go-with-c-libraries/pkg/features"
+
+func probe() features.Set {
+	if err := load(); err != nil {
+		return features.Set{}
+	}
+	return features.Probe(func(symbol string) bool {
+		if _, ok := stdcallFuncs[symbol]; ok {
+			return stdcallProc(libDLL, symbol).Find() == nil
+		}
+		return libDLL.NewProc(symbol).Find() == nil
+	}, func() int {
+		var v int
+		callC(func() error {
+			n, _, _ := procVersion.Call()
+			v = int(int32(n))
+			return nil
+		})
+		return v
+	})
+}
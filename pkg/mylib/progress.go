@@ -0,0 +1,221 @@
+//go:build !nocgo && !windows
+
+package mylib
+
+/*
+
+#include <stdint.h>
+#include "mylib.h"
+
+// Defined in progress_export.go.
+extern int goProgressTrampoline(uintptr_t handle, long long done, long long total);
+
+// myCrunchProgress reports every 1024 iterations, and each report that
+// reaches Go costs a callback through the runtime. The filter forwards a
+// report only once the work has advanced by step iterations, and the
+// last one always. Between forwarded reports it answers the library's
+// question of whether to stop from a flag Go sets, without calling into
+// Go at all.
+struct progressFilter {
+	uintptr_t handle;
+	long long step, next;
+	int cancel;
+};
+
+static int filterProgress(void *userdata, long long done, long long total) {
+	struct progressFilter *f = userdata;
+
+	if (__atomic_load_n(&f->cancel, __ATOMIC_RELAXED))
+		return 1;
+	if (done < f->next && done < total)
+		return 0;
+	f->next = done + f->step;
+	return goProgressTrampoline(f->handle, done, total);
+}
+
+static int crunchProgressGateway(long long iterations, struct progressFilter *f, long long *result) {
+	return myCrunchProgress(iterations, filterProgress, f, result);
+}
+
+*/
+import "C"
+
+import (
+	"context"
+	"math"
+	"sync/atomic"
+	"time"
+	"unsafe"
+
+	"github.com/lxwagn/using-go-with-c-libraries/pkg/cpanic"
+	"github.com/lxwagn/using-go-with-c-libraries/pkg/features"
+	"github.com/lxwagn/using-go-with-c-libraries/pkg/handles"
+)
+
+// A ProgressEvent reports how far a long-running call has got.
+type ProgressEvent struct {
+	Done, Total int64
+	Percent     float64
+}
+
+// A ProgressOption throttles the progress reports of CrunchProgress and
+// StartCrunch.
+type ProgressOption func(*progressConfig)
+
+type progressConfig struct {
+	step     float64
+	interval time.Duration
+}
+
+// ProgressStep sets how far, in percent, the work must advance between
+// reports; 1 by default. The C side applies it, so the reports it
+// suppresses never cross into Go.
+func ProgressStep(percent float64) ProgressOption {
+	return func(c *progressConfig) {
+		c.step = percent
+	}
+}
+
+// ProgressInterval sets the least time between reports, none by default.
+// The last report is made however soon it follows the one before.
+func ProgressInterval(d time.Duration) ProgressOption {
+	return func(c *progressConfig) {
+		c.interval = d
+	}
+}
+
+// progressState is what the trampoline finds through the filter's handle.
+type progressState struct {
+	ctx      context.Context
+	report   func(ProgressEvent)
+	interval time.Duration
+	last     time.Time
+	barrier  cpanic.Barrier
+}
+
+// deliver reports unless throttled, and says whether to stop. It checks
+// the context itself: a goroutine in C keeps its P until the runtime
+// takes it back, so with few Ps the one setting the filter's flag may not
+// run for milliseconds.
+func (s *progressState) deliver(done, total int64) (stop bool) {
+	if s.ctx.Err() != nil {
+		return true
+	}
+	if s.interval > 0 && done < total {
+		now := time.Now()
+		if now.Sub(s.last) < s.interval {
+			return false
+		}
+		s.last = now
+	}
+	ev := ProgressEvent{Done: done, Total: total, Percent: 100}
+	if total > 0 {
+		ev.Percent = 100 * float64(done) / float64(total)
+	}
+	s.report(ev)
+	return s.ctx.Err() != nil
+}
+
+// CrunchProgress is Crunch, calling report with the percentage done as the
+// work advances and with 100 at the end. report runs while the library
+// lock is held and must not call back into this package. If report
+// panics, the library is asked to stop, and CrunchProgress raises the
+// panic again as a *cpanic.Panic once myCrunchProgress has returned.
+func CrunchProgress(ctx context.Context, iterations int64, report func(percent float64), opts ...ProgressOption) (int64, error) {
+	return crunchProgress(ctx, iterations, func(ev ProgressEvent) { report(ev.Percent) }, opts)
+}
+
+func crunchProgress(ctx context.Context, iterations int64, report func(ProgressEvent), opts []ProgressOption) (int64, error) {
+	if err := require(features.Progress); err != nil {
+		return 0, err
+	}
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+	c := progressConfig{step: 1}
+	for _, opt := range opts {
+		opt(&c)
+	}
+
+	s := &progressState{ctx: ctx, report: report, interval: c.interval}
+	h := handles.New(s)
+	defer h.Delete()
+
+	// Go memory, which C uses only during the call; it holds no Go
+	// pointers, the state being behind the handle.
+	f := &C.struct_progressFilter{
+		handle: C.uintptr_t(h.Uintptr()),
+		step:   C.longlong(max(1, math.Ceil(c.step/100*float64(iterations)))),
+	}
+	stop := context.AfterFunc(ctx, func() {
+		atomic.StoreInt32((*int32)(unsafe.Pointer(&f.cancel)), 1)
+	})
+	defer stop()
+
+	var result C.longlong
+	err := callC(func() error {
+		rc := C.crunchProgressGateway(C.longlong(iterations), f, &result)
+		if rc == C.MYLIB_ECANCELED {
+			return ctx.Err()
+		}
+		return codes.Error("myCrunchProgress", int(rc))
+	})
+	s.barrier.Repanic()
+
+	if err != nil {
+		return 0, err
+	}
+	return int64(result), nil
+}
+
+// A CrunchJob is a Crunch running in the background, reporting its
+// progress on a channel.
+type CrunchJob struct {
+	progress chan ProgressEvent
+	done     chan struct{}
+	result   int64
+	err      error
+}
+
+// StartCrunch starts CrunchProgress on a goroutine of its own. Its reports
+// go to the Progress channel, which holds only the latest: a receiver that
+// falls behind misses intermediate reports, never the last.
+func StartCrunch(ctx context.Context, iterations int64, opts ...ProgressOption) *CrunchJob {
+	j := &CrunchJob{
+		progress: make(chan ProgressEvent, 1),
+		done:     make(chan struct{}),
+	}
+	go func() {
+		defer close(j.done)
+		defer close(j.progress)
+		j.result, j.err = crunchProgress(ctx, iterations, j.send, opts)
+	}()
+	return j
+}
+
+// send runs on the crunching goroutine, the channel's only sender, so a
+// slot it empties stays empty.
+func (j *CrunchJob) send(ev ProgressEvent) {
+	select {
+	case j.progress <- ev:
+		return
+	default:
+	}
+	select {
+	case <-j.progress:
+	default:
+	}
+	j.progress <- ev
+}
+
+// Progress returns the channel the reports arrive on. It is closed when
+// the work ends.
+func (j *CrunchJob) Progress() <-chan ProgressEvent {
+	return j.progress
+}
+
+// Wait waits for the work to end and returns its result.
+func (j *CrunchJob) Wait() (int64, error) {
+	<-j.done
+	return j.result, j.err
+}
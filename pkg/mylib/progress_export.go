@@ -0,0 +1,30 @@
+//go:build !nocgo && !windows
+
+package mylib
+
+/*
+#include <stdint.h>
+*/
+import "C"
+
+import (
+	"github.com/lxwagn/using-go-with-c-libraries/pkg/cpanic"
+	"github.com/lxwagn/using-go-with-c-libraries/pkg/handles"
+)
+
+// goProgressTrampoline runs on the goroutine that called
+// myCrunchProgress, for each report the filter in progress.go lets
+// through. A non-zero result asks the library to stop, as a panic in the
+// report does.
+//
+//export goProgressTrampoline
+func goProgressTrampoline(handle C.uintptr_t, done, total C.longlong) C.int {
+	s, err := handles.FromUintptr[*progressState](uintptr(handle)).Get()
+	if err != nil {
+		return 1
+	}
+	if cpanic.Call(&s.barrier, true, func() bool { return s.deliver(int64(done), int64(total)) }) {
+		return 1
+	}
+	return 0
+}
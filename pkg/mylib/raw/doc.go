@@ -0,0 +1,14 @@
+//go:build !nocgo && !windows
+
+// Package raw holds bindings to libmylib generated from src/mylib.h by
+// cmd/cbindgen. Each function is a direct call into C: there is no call
+// guard, strings are truncated at the first NUL and errors are plain
+// status codes. String arguments are copied to C only for the duration of
+// the call, so a function that keeps the pointer, such as myMakeStruct,
+// is only safe through its hand-written wrapper. Package mylib builds the idiomatic API on top of the same
+// library; raw is for functions it does not wrap yet.
+//
+// Run go generate after changing the header.
+package raw
+
+//go:generate go run ../../../cmd/cbindgen -header ../../../src/mylib.h -pkg raw -build "!nocgo && !windows" -pkg-config mylib -trim my -trim-define MYLIB_ -o raw.go
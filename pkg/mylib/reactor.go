@@ -0,0 +1,208 @@
+//go:build !nocgo && !windows
+
+package mylib
+
+/*
+
+#include <stdint.h>
+#include "mylib.h"
+
+// Defined in reactor_export.go.
+extern void goReactorWatch(void *userdata, int fd, int events);
+
+static myReactor *newReactorGateway(uintptr_t handle) {
+	return myReactorNew(goReactorWatch, (void *)handle);
+}
+
+*/
+import "C"
+
+import (
+	"errors"
+	"net"
+	"sync"
+
+	"github.com/lxwagn/using-go-with-c-libraries/pkg/cfd"
+	"github.com/lxwagn/using-go-with-c-libraries/pkg/cpoll"
+	"github.com/lxwagn/using-go-with-c-libraries/pkg/features"
+	"github.com/lxwagn/using-go-with-c-libraries/pkg/handles"
+)
+
+// MaxReactorConns is the number of connections a Reactor can serve at
+// once.
+const MaxReactorConns = C.MYLIB_REACTOR_MAX
+
+// A Reactor is the C library's event-driven echo server, driven from Go.
+// The library owns its end of every connection and has no thread of its
+// own: it asks for each descriptor to be watched, and serves whichever
+// are ready when told. A Reactor watches them all with one cpoll.Poller,
+// whose goroutine calls myReactorReady for each, however many
+// connections are open, rather than each holding a goroutine and its
+// thread blocked in C.
+//
+// Close must be called when done, which stops the goroutine; a Reactor
+// is not reclaimed while it runs. A Reactor is safe for concurrent use.
+type Reactor struct {
+	// mu serializes the calls on p, which the library's lock does not
+	// under mylib_nolock, and guards watched, which only those calls
+	// change.
+	mu      sync.Mutex
+	p       *C.myReactor
+	watched map[int]bool
+	werr    error // the first error watch met, for Connect to return
+
+	poller    *cpoll.Poller
+	handle    handles.Handle[*Reactor]
+	closeOnce sync.Once
+}
+
+// NewReactor returns a Reactor with no connections.
+func NewReactor() (*Reactor, error) {
+	if err := require(features.Reactors); err != nil {
+		return nil, err
+	}
+	poller, err := cpoll.New()
+	if err != nil {
+		return nil, err
+	}
+	r := &Reactor{watched: make(map[int]bool), poller: poller}
+	r.handle = handles.New(r)
+
+	err = callC(func() error {
+		var err error
+		if r.p, err = C.newReactorGateway(C.uintptr_t(r.handle.Uintptr())); r.p == nil {
+			return lastError("myReactorNew", err)
+		}
+		return nil
+	})
+	if err != nil {
+		poller.Close()
+		r.handle.Delete()
+		return nil, err
+	}
+	return r, nil
+}
+
+// Connect returns a new connection served by the reactor. The connection
+// is the caller's to close, which the reactor sees as end of file and
+// closes its own end. It fails with an error matching
+// syscall.EMFILE once MaxReactorConns connections are open.
+func (r *Reactor) Connect() (net.Conn, error) {
+	r.mu.Lock()
+	if r.p == nil {
+		r.mu.Unlock()
+		return nil, ErrClosed
+	}
+	r.werr = nil
+	var fd C.int
+	err := callC(func() error {
+		var err error
+		if fd, err = C.myReactorConnect(r.p); fd < 0 {
+			return lastError("myReactorConnect", err)
+		}
+		return nil
+	})
+	werr := r.werr
+	r.mu.Unlock()
+	if err != nil {
+		return nil, err
+	}
+	f, err := cfd.Adopt(int(fd), "mylib-reactor")
+	if err != nil {
+		return nil, err
+	}
+	c, err := cfd.Conn(f)
+	if err == nil && werr != nil {
+		// The reactor's end is not being polled, so it would never
+		// answer.
+		c.Close()
+		err = werr
+	}
+	return c, err
+}
+
+// watch is the reactor's myWatchFunc. It is called with r.mu held.
+func (r *Reactor) watch(fd, events int) {
+	var ev cpoll.Events
+	if events&C.MYLIB_POLL_IN != 0 {
+		ev |= cpoll.Readable
+	}
+	if events&C.MYLIB_POLL_OUT != 0 {
+		ev |= cpoll.Writable
+	}
+	var err error
+	switch {
+	case ev == 0:
+		delete(r.watched, fd)
+		err = r.poller.Remove(fd)
+	case r.watched[fd]:
+		err = r.poller.Modify(fd, ev)
+	default:
+		r.watched[fd] = true
+		err = r.poller.Add(fd, ev, r.ready)
+	}
+	if err != nil && r.werr == nil && !errors.Is(err, cpoll.ErrClosed) {
+		r.werr = err
+	}
+}
+
+// ready runs on the poller's goroutine for a descriptor of the reactor's
+// that is ready.
+func (r *Reactor) ready(fd int, ev cpoll.Events) {
+	var events C.int
+	if ev&cpoll.Readable != 0 {
+		events |= C.MYLIB_POLL_IN
+	}
+	if ev&cpoll.Writable != 0 {
+		events |= C.MYLIB_POLL_OUT
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.p == nil {
+		return
+	}
+	callC(func() error {
+		C.myReactorReady(r.p, C.int(fd), events)
+		return nil
+	})
+}
+
+// Bytes returns the number of bytes the reactor has written back so far.
+func (r *Reactor) Bytes() int64 {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.p == nil {
+		return 0
+	}
+	var n int64
+	callC(func() error {
+		n = int64(C.myReactorBytes(r.p))
+		return nil
+	})
+	return n
+}
+
+// Conns returns the number of connections the reactor is serving.
+func (r *Reactor) Conns() int {
+	return r.poller.Len()
+}
+
+// Close stops polling and closes the reactor's end of every connection,
+// which their peers then read as end of file. Closing again returns
+// ErrClosed.
+func (r *Reactor) Close() error {
+	err := ErrClosed
+	r.closeOnce.Do(func() {
+		// Stop the goroutine first; ready takes r.mu, so it must not be
+		// held while Close waits for a handler to return.
+		err = r.poller.Close()
+		r.mu.Lock()
+		releaseC(func() {
+			C.myReactorFree(r.p)
+		})
+		r.p = nil
+		r.mu.Unlock()
+		r.handle.Delete()
+	})
+	return err
+}
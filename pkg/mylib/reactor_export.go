@@ -0,0 +1,23 @@
+//go:build !nocgo && !windows
+
+package mylib
+
+import "C"
+
+import (
+	"unsafe"
+
+	"github.com/lxwagn/using-go-with-c-libraries/pkg/handles"
+)
+
+// goReactorWatch runs within myReactorConnect or myReactorReady, on the
+// goroutine that called them.
+//
+//export goReactorWatch
+func goReactorWatch(userdata unsafe.Pointer, fd, events C.int) {
+	r, err := handles.FromUintptr[*Reactor](uintptr(userdata)).Get()
+	if err != nil {
+		return
+	}
+	r.watch(int(fd), int(events))
+}
@@ -0,0 +1,75 @@
+//go:build !nocgo && !windows
+
+package mylib
+
+/*
+
+#include <stdlib.h>
+#include "mylib.h"
+
+// cgo can call C functions by name only. Calling through a function
+// pointer needs a C function that does the call.
+static long long callReducer(myReducer fn, const int *values, int n) {
+	return fn(values, n);
+}
+
+*/
+import "C"
+
+import (
+	"strings"
+	"unsafe"
+
+	"github.com/lxwagn/using-go-with-c-libraries/pkg/cmem"
+	"github.com/lxwagn/using-go-with-c-libraries/pkg/cnum"
+)
+
+// A Reducer is a C function chosen by name at run time. The library hands
+// back a function pointer rather than exporting a symbol per reducer, and
+// Reduce calls through that pointer.
+type Reducer struct {
+	name string
+	fn   C.myReducer
+}
+
+// GetReducer returns the library's reducer called name: "sum", "min" or
+// "max". It returns an error matching ErrNotFound for any other name.
+// Reducing an empty slice gives 0 for "sum" and the largest and smallest
+// int64 for "min" and "max".
+func GetReducer(name string) (*Reducer, error) {
+	if strings.IndexByte(name, 0) >= 0 {
+		return nil, ErrNUL
+	}
+
+	cname := (*C.char)(cmem.CString(name))
+	defer cmem.Free(unsafe.Pointer(cname))
+
+	var fn C.myReducer
+	err := callC(func() error {
+		if fn = C.myGetReducer(cname); fn == nil {
+			return codes.Error("myGetReducer", int(StatusNotFound))
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &Reducer{name: name, fn: fn}, nil
+}
+
+// Name returns the name the reducer was looked up by.
+func (r *Reducer) Name() string {
+	return r.name
+}
+
+// Reduce calls the reducer on values. The slice is passed to C without
+// copying. It panics if len(values) does not fit in a C int.
+func (r *Reducer) Reduce(values []int32) int64 {
+	n := C.int(cnum.Must(cnum.ToCInt(len(values))))
+	var sum int64
+	callC(func() error {
+		sum = int64(C.callReducer(r.fn, (*C.int)(unsafe.SliceData(values)), n))
+		return nil
+	})
+	return sum
+}
@@ -0,0 +1,69 @@
+//go:build !cgo && !nocgo && !windows
+
+package mylib
+
+import (
+	"math"
+	"strings"
+
+	"github.com/lxwagn/using-go-with-c-libraries/pkg/cnum"
+)
+
+// A Reducer is a function chosen by name at run time. The cgo build calls
+// a function pointer the C library hands back; here the reducers are Go
+// functions.
+type Reducer struct {
+	name string
+	fn   func([]int32) int64
+}
+
+var reducers = map[string]func([]int32) int64{
+	"sum": func(values []int32) int64 {
+		var s int64
+		for _, v := range values {
+			s += int64(v)
+		}
+		return s
+	},
+	"min": func(values []int32) int64 {
+		m := int64(math.MaxInt64)
+		for _, v := range values {
+			m = min(m, int64(v))
+		}
+		return m
+	},
+	"max": func(values []int32) int64 {
+		m := int64(math.MinInt64)
+		for _, v := range values {
+			m = max(m, int64(v))
+		}
+		return m
+	},
+}
+
+// GetReducer returns the reducer called name: "sum", "min" or "max". It
+// returns an error matching ErrNotFound for any other name. Reducing an
+// empty slice gives 0 for "sum" and the largest and smallest int64 for
+// "min" and "max".
+func GetReducer(name string) (*Reducer, error) {
+	if strings.IndexByte(name, 0) >= 0 {
+		return nil, ErrNUL
+	}
+	fn, ok := reducers[name]
+	if !ok {
+		return nil, codes.Error("myGetReducer", int(StatusNotFound))
+	}
+	return &Reducer{name: name, fn: fn}, nil
+}
+
+// Name returns the name the reducer was looked up by.
+func (r *Reducer) Name() string {
+	return r.name
+}
+
+// Reduce calls the reducer on values. It panics if len(values) does not
+// fit in a C int, as the other builds do.
+func (r *Reducer) Reduce(values []int32) int64 {
+	cnum.Must(cnum.ToCInt(len(values)))
+	return r.fn(values)
+}
@@ -0,0 +1,63 @@
+//go:build nocgo && !windows
+
+package mylib
+
+import (
+	"strings"
+	"unsafe"
+
+	"github.com/ebitengine/purego"
+	"github.com/lxwagn/using-go-with-c-libraries/pkg/cnum"
+)
+
+// A Reducer is a C function chosen by name at run time. The library hands
+// back a function pointer rather than exporting a symbol per reducer, and
+// Reduce calls through that pointer.
+type Reducer struct {
+	name string
+	fn   uintptr
+}
+
+// GetReducer returns the library's reducer called name: "sum", "min" or
+// "max". It returns an error matching ErrNotFound for any other name.
+// Reducing an empty slice gives 0 for "sum" and the largest and smallest
+// int64 for "min" and "max".
+func GetReducer(name string) (*Reducer, error) {
+	if strings.IndexByte(name, 0) >= 0 {
+		return nil, ErrNUL
+	}
+	if err := load(); err != nil {
+		return nil, err
+	}
+
+	var fn uintptr
+	err := callC(func() error {
+		if fn = myGetReducer(name); fn == 0 {
+			return codes.Error("myGetReducer", int(StatusNotFound))
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &Reducer{name: name, fn: fn}, nil
+}
+
+// Name returns the name the reducer was looked up by.
+func (r *Reducer) Name() string {
+	return r.name
+}
+
+// Reduce calls the reducer on values. purego calls a function pointer
+// the same way it calls a symbol it looked up itself. It panics if
+// len(values) does not fit in a C int.
+func (r *Reducer) Reduce(values []int32) int64 {
+	n := cnum.Must(cnum.ToCInt(len(values)))
+	var sum int64
+	callC(func() error {
+		rc, _, _ := purego.SyscallN(r.fn, uintptr(unsafe.Pointer(unsafe.SliceData(values))), uintptr(n))
+		sum = int64(rc)
+		return nil
+	})
+	return sum
+}
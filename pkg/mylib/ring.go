@@ -0,0 +1,148 @@
+//go:build !nocgo && !windows
+
+package mylib
+
+/*
+
+#include "mylib.h"
+
+*/
+import "C"
+
+import (
+	"math"
+	"strings"
+	"sync"
+	"unsafe"
+
+	"github.com/lxwagn/using-go-with-c-libraries/pkg/cmem"
+	"github.com/lxwagn/using-go-with-c-libraries/pkg/cnum"
+	"github.com/lxwagn/using-go-with-c-libraries/pkg/features"
+)
+
+// A Ring is the C library's view of a message ring in POSIX shared
+// memory, which another process, or pkg/shm in this one, maps too. Only
+// one process may push and one pop.
+//
+// A Ring is safe for concurrent use, although calls are serialized.
+type Ring struct {
+	mu sync.Mutex
+	p  *C.myRing
+}
+
+// CreateRing creates the shared memory object name, which must start with
+// a slash, holding an empty ring with room for capacity bytes, a power of
+// two. Each message takes 4 bytes of it besides its own.
+func CreateRing(name string, capacity int) (*Ring, error) {
+	if capacity <= 0 || capacity&(capacity-1) != 0 || capacity > 1<<30 {
+		return nil, ErrInvalid
+	}
+	return openRing(name, capacity)
+}
+
+// OpenRing opens a ring another process created.
+func OpenRing(name string) (*Ring, error) {
+	return openRing(name, 0)
+}
+
+func openRing(name string, capacity int) (*Ring, error) {
+	if strings.IndexByte(name, 0) >= 0 {
+		return nil, ErrNUL
+	}
+	if err := require(features.Rings); err != nil {
+		return nil, err
+	}
+
+	cname := (*C.char)(cmem.CString(name))
+	defer cmem.Free(unsafe.Pointer(cname))
+
+	var p *C.myRing
+	err := callC(func() error {
+		var err error
+		if p, err = C.myRingOpen(cname, C.uint(capacity)); p == nil {
+			return lastError("myRingOpen", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &Ring{p: p}, nil
+}
+
+// UnlinkRing removes the shared memory object name. Processes that have
+// the ring open keep using it until they close it.
+func UnlinkRing(name string) error {
+	if strings.IndexByte(name, 0) >= 0 {
+		return ErrNUL
+	}
+	if err := require(features.Rings); err != nil {
+		return err
+	}
+
+	cname := (*C.char)(cmem.CString(name))
+	defer cmem.Free(unsafe.Pointer(cname))
+
+	return callC(func() error {
+		return codes.Error("myRingUnlink", int(C.myRingUnlink(cname)))
+	})
+}
+
+// Push adds msg to the ring. It returns an error matching ErrRange if the
+// ring is too full for it now and ErrInvalid if it never could hold it.
+func (r *Ring) Push(msg []byte) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.p == nil {
+		return ErrClosed
+	}
+	n, err := cnum.ToCUint(len(msg))
+	if err != nil {
+		return argError("myRingPush", err)
+	}
+
+	return callC(func() error {
+		return codes.Error("myRingPush", int(C.myRingPush(r.p, (*C.uchar)(unsafe.SliceData(msg)), C.uint(n))))
+	})
+}
+
+// Pop removes the oldest message from the ring, copying it into buf, and
+// returns its length. It returns an error matching ErrNotFound if the ring
+// is empty, and one matching ErrRange, with the message's length, if buf
+// is too short; the message then stays in the ring.
+func (r *Ring) Pop(buf []byte) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.p == nil {
+		return 0, ErrClosed
+	}
+
+	// No message is longer than a ring's capacity, which is a C unsigned
+	// int, so the room past that in a longer buf is never needed.
+	room := C.uint(min(len(buf), math.MaxUint32))
+
+	var n C.uint
+	err := callC(func() error {
+		return codes.Error("myRingPop", int(C.myRingPop(r.p, (*C.uchar)(unsafe.SliceData(buf)), room, &n)))
+	})
+	return int(n), err
+}
+
+// Close unmaps the ring. It does not remove the shared memory object; see
+// UnlinkRing.
+func (r *Ring) Close() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.p == nil {
+		return ErrClosed
+	}
+
+	releaseC(func() {
+		C.myRingClose(r.p)
+	})
+	r.p = nil
+	return nil
+}
@@ -0,0 +1,149 @@
+//go:build !cgo && !nocgo && !windows
+
+package mylib
+
+import (
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// A Session is a named running total bounded by a limit. In the cgo
+// build it wraps a mySession in the C library; here it is a Go value that
+// follows the same rules.
+//
+// Methods on a nil or closed Session return ErrClosed. A Session is safe
+// for concurrent use.
+type Session struct {
+	mu     sync.Mutex
+	name   string
+	limit  int64
+	total  int64
+	calls  int32
+	closed bool
+
+	poisoned atomic.Bool // see WithTimeout
+}
+
+// NewSession creates a session whose total must stay within [-limit,
+// limit]. PinThread and UseWorkers have no effect, since there is no C
+// library to keep on one thread.
+func NewSession(name string, limit int64, opts ...SessionOption) (*Session, error) {
+	if strings.IndexByte(name, 0) >= 0 {
+		return nil, ErrNUL
+	}
+	if limit < 0 {
+		return nil, codes.Error("mySessionNew", int(StatusInvalid))
+	}
+	return &Session{name: name, limit: limit}, nil
+}
+
+// do runs f with the session locked, or returns ErrClosed if it has been
+// closed and ErrPoisoned once WithTimeout has given up on a call.
+func (s *Session) do(f func() error) error {
+	if s == nil {
+		return ErrClosed
+	}
+	if s.poisoned.Load() {
+		return ErrPoisoned
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.closed {
+		return ErrClosed
+	}
+	return f()
+}
+
+// Name returns the name the session was created with. It stays available
+// after Close; a nil Session has the name "".
+func (s *Session) Name() string {
+	if s == nil {
+		return ""
+	}
+	return s.name
+}
+
+// Add adds delta to the session's total and returns the new total. If
+// the total would leave the session's limit, Add returns an error
+// matching ErrRange and leaves the total unchanged.
+func (s *Session) Add(delta int64) (int64, error) {
+	var total int64
+	err := s.do(func() error {
+		// Checking delta first keeps total+delta from overflowing.
+		if delta > s.limit || delta < -s.limit {
+			return codes.Error("mySessionAdd", int(StatusRange))
+		}
+		t := s.total + delta
+		if t > s.limit || t < -s.limit {
+			return codes.Error("mySessionAdd", int(StatusRange))
+		}
+		s.total = t
+		s.calls++
+		total = t
+		return nil
+	})
+	return total, err
+}
+
+// Reset sets the total and the call count back to zero.
+func (s *Session) Reset() error {
+	return s.do(func() error {
+		s.total, s.calls = 0, 0
+		return nil
+	})
+}
+
+// Stats returns the session's total and the number of successful Add
+// calls since it was created or last reset.
+func (s *Session) Stats() (total int64, calls int, err error) {
+	err = s.do(func() error {
+		total, calls = s.total, int(s.calls)
+		return nil
+	})
+	return total, calls, err
+}
+
+// OnCreatingThread returns ErrNotSupported: the question is about the
+// thread the C library runs on.
+func (s *Session) OnCreatingThread() (bool, error) {
+	return false, ErrNotSupported
+}
+
+// Sync waits for d with the session locked, as mySessionSync does.
+func (s *Session) Sync(d time.Duration) error {
+	if _, err := syncMillis(d); err != nil {
+		return err
+	}
+	return s.do(func() error {
+		time.Sleep(d)
+		return nil
+	})
+}
+
+// Close ends the session. Calls after the first return ErrClosed. On a
+// poisoned session it returns ErrPoisoned, and the session ends once the
+// call that timed out returns.
+func (s *Session) Close() error {
+	if s != nil && s.poisoned.Load() {
+		go s.close()
+		return ErrPoisoned
+	}
+	return s.close()
+}
+
+func (s *Session) close() error {
+	if s == nil {
+		return ErrClosed
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.closed {
+		return ErrClosed
+	}
+	s.closed = true
+	return nil
+}
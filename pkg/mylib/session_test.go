@@ -0,0 +1,56 @@
+package mylib
+
+import (
+	"errors"
+	"sync"
+	"testing"
+
+	"github.com/lxwagn/using-go-with-c-libraries/pkg/cworker"
+)
+
+// TestSessionWorkers runs sessions on a shared pool from many goroutines,
+// and the calls must all land on the pool's threads, until the pool is
+// closed.
+func TestSessionWorkers(t *testing.T) {
+	const sessions, adds = 8, 50
+	p := cworker.New(2, 4)
+	var wg sync.WaitGroup
+	for i := range sessions {
+		wg.Go(func() {
+			s, err := NewSession("workers", adds, UseWorkers(p))
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			defer s.Close()
+			for range adds {
+				if _, err := s.Add(1); err != nil {
+					t.Errorf("session %d: %v", i, err)
+					return
+				}
+			}
+			if total, _, err := s.Stats(); err != nil || total != adds {
+				t.Errorf("session %d: Stats = %d, %v; want %d, nil", i, total, err, adds)
+			}
+		})
+	}
+	wg.Wait()
+	// NewSession, every Add, Stats and Close. The fallback build has no
+	// C library to run on the pool's threads, and ignores UseWorkers.
+	_, ownerErr := new(Session).OnCreatingThread()
+	if got, want := p.Stats().Completed, int64(sessions*(adds+3)); got != want && !errors.Is(ownerErr, ErrNotSupported) {
+		t.Errorf("pool ran %d calls, want %d", got, want)
+	}
+
+	p.Close()
+	s, err := NewSession("after", 1, UseWorkers(p))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.Add(1); err != nil {
+		t.Errorf("Add on a closed pool: %v", err)
+	}
+	if err := s.Close(); err != nil {
+		t.Error(err)
+	}
+}
@@ -0,0 +1,65 @@
+package mylib
+
+import (
+	"runtime"
+	"time"
+
+	"github.com/lxwagn/using-go-with-c-libraries/pkg/cnum"
+)
+
+// WithTimeout calls f with s and waits up to d for it to return, for
+// calls into C that cannot be cancelled, such as Sync on a peer that has
+// stopped answering.
+//
+// f runs on a goroutine of its own, locked to its thread for good: if the
+// C call never returns, it is that thread which stays stuck, and if it
+// does, the runtime ends the thread rather than reusing whatever state
+// the call left on it. When d passes first, WithTimeout returns ErrTimeout
+// and poisons s. The call may still be using the C object, so from then
+// on every method of s returns ErrPoisoned at once instead of queueing
+// behind it. Calls already waiting for s keep waiting.
+//
+// WithTimeout only frees the rest of the program if f's call into C
+// leaves the library lock alone, as Sync does. Any other method of s
+// holds the lock for the length of its C call, and if that call never
+// returns, neither does any later call into the library, on any session
+// or none: WithTimeout still returns ErrTimeout, but the program is stuck
+// behind the lock all the same. Bound only calls that run unlocked.
+func (s *Session) WithTimeout(d time.Duration, f func(s *Session) error) error {
+	if s == nil {
+		return ErrClosed
+	}
+	if s.poisoned.Load() {
+		return ErrPoisoned
+	}
+
+	done := make(chan error, 1)
+	go func() {
+		runtime.LockOSThread()
+		done <- f(s)
+	}()
+
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case err := <-done:
+		return err
+	case <-timer.C:
+		s.poisoned.Store(true)
+		return ErrTimeout
+	}
+}
+
+// Poisoned reports whether WithTimeout has given up on a call on s.
+func (s *Session) Poisoned() bool {
+	return s != nil && s.poisoned.Load()
+}
+
+// syncMillis converts the duration passed to Sync to the millis argument
+// of mySessionSync.
+func syncMillis(d time.Duration) (int32, error) {
+	if d < 0 || !cnum.Fits[int32](d.Milliseconds()) {
+		return 0, codes.Error("mySessionSync", int(StatusInvalid))
+	}
+	return int32(d.Milliseconds()), nil
+}
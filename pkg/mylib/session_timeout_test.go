@@ -0,0 +1,49 @@
+package mylib
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+// TestWithTimeoutUnlocked times out a Sync that is still in C, which must
+// poison the session but leave the library lock free for other calls.
+func TestWithTimeoutUnlocked(t *testing.T) {
+	s, err := NewSession("timeout", 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = s.WithTimeout(10*time.Millisecond, func(s *Session) error {
+		return s.Sync(500 * time.Millisecond)
+	})
+	if errors.Is(err, ErrNotSupported) {
+		t.Skip(err)
+	}
+	if !errors.Is(err, ErrTimeout) {
+		t.Fatalf("WithTimeout = %v, want ErrTimeout", err)
+	}
+	if !s.Poisoned() {
+		t.Error("session not poisoned after ErrTimeout")
+	}
+
+	// The Sync is still sleeping in C. Calls that need the library lock
+	// must not wait for it.
+	done := make(chan struct{})
+	go func() {
+		CounterAdd(0)
+		Lookup("one")
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(250 * time.Millisecond):
+		t.Fatal("calls into the library waited for the Sync that timed out")
+	}
+
+	if _, err := s.Add(1); err != ErrPoisoned {
+		t.Errorf("Add on a poisoned session: err = %v, want ErrPoisoned", err)
+	}
+	if err := s.Close(); err != ErrPoisoned {
+		t.Errorf("Close on a poisoned session: err = %v, want ErrPoisoned", err)
+	}
+}
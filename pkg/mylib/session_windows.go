@@ -0,0 +1,215 @@
+package mylib
+
+import (
+	"runtime"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+	"unsafe"
+
+	"github.com/lxwagn/using-go-with-c-libraries/pkg/features"
+)
+
+// A Session is a stateful object in the C library: a named running total
+// bounded by a limit. Each Session wraps its own opaque mySession pointer,
+// so any number of them can be used side by side without sharing state,
+// unlike the library's single global counter behind CounterAdd.
+//
+// Methods on a nil or closed Session return ErrClosed rather than passing
+// a dangling pointer to C. Call Close when done; as for Buffer, a runtime
+// cleanup is only a backstop. A Session is safe for concurrent use.
+type Session struct {
+	mu       sync.Mutex
+	p        uintptr
+	name     string
+	thread   *sessionThread // nil unless created with PinThread or UseWorkers
+	cleanup  runtime.Cleanup
+	poisoned atomic.Bool // see WithTimeout
+}
+
+// NewSession creates a session whose total must stay within [-limit,
+// limit].
+func NewSession(name string, limit int64, opts ...SessionOption) (*Session, error) {
+	if strings.IndexByte(name, 0) >= 0 {
+		return nil, ErrNUL
+	}
+	if limit < 0 {
+		return nil, codes.Error("mySessionNew", int(StatusInvalid))
+	}
+
+	if err := load(); err != nil {
+		return nil, err
+	}
+
+	t := newSessionConfig(opts).thread()
+
+	var p uintptr
+	var err error
+	t.run(func() {
+		err = callC(func() error {
+			var lastErr error
+			if p, _, lastErr = procSessionNew.Call(uintptr(unsafe.Pointer(cString(name))), uintptr(limit)); p == 0 {
+				return lastError("mySessionNew", lastErr)
+			}
+			return nil
+		})
+	})
+	if err != nil {
+		t.stop()
+		return nil, err
+	}
+
+	s := &Session{p: p, name: name, thread: t}
+	s.cleanup = runtime.AddCleanup(s, func(p uintptr) {
+		t.run(func() { freeSession(p) })
+		t.stop()
+	}, p)
+	return s, nil
+}
+
+func freeSession(p uintptr) {
+	releaseC(func() {
+		procSessionFree.Call(p)
+	})
+}
+
+// do runs f on the session's C object with the session and the library
+// locked, or returns ErrClosed if there is none.
+func (s *Session) do(op string, f func(p uintptr) int32) error {
+	return s.call(op, true, f)
+}
+
+// call is do, but leaves the library unlocked unless serialize is set. It
+// returns ErrPoisoned, without waiting for the session, once WithTimeout
+// has given up on a call.
+func (s *Session) call(op string, serialize bool, f func(p uintptr) int32) error {
+	if s == nil {
+		return ErrClosed
+	}
+	if s.poisoned.Load() {
+		return ErrPoisoned
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.p == 0 {
+		return ErrClosed
+	}
+
+	call := func() error {
+		return codes.Error(op, int(f(s.p)))
+	}
+	var err error
+	s.thread.run(func() {
+		if serialize {
+			err = callC(call)
+		} else {
+			err = callCUnlocked(call)
+		}
+	})
+	return err
+}
+
+// Name returns the name the session was created with. It stays available
+// after Close; a nil Session has the name "".
+func (s *Session) Name() string {
+	if s == nil {
+		return ""
+	}
+	return s.name
+}
+
+// Add adds delta to the session's total and returns the new total. If
+// the total would leave the session's limit, Add returns an error
+// matching ErrRange and leaves the total unchanged.
+func (s *Session) Add(delta int64) (int64, error) {
+	var total int64
+	err := s.do("mySessionAdd", func(p uintptr) int32 {
+		rc, _, _ := procSessionAdd.Call(p, uintptr(delta), uintptr(unsafe.Pointer(&total)))
+		return int32(rc)
+	})
+	return total, err
+}
+
+// Reset sets the total and the call count back to zero.
+func (s *Session) Reset() error {
+	return s.do("mySessionReset", func(p uintptr) int32 {
+		rc, _, _ := procSessionReset.Call(p)
+		return int32(rc)
+	})
+}
+
+// Stats returns the session's total and the number of successful Add
+// calls since it was created or last reset.
+func (s *Session) Stats() (total int64, calls int, err error) {
+	var n int32
+	err = s.do("mySessionStats", func(p uintptr) int32 {
+		rc, _, _ := procSessionStats.Call(p, uintptr(unsafe.Pointer(&total)), uintptr(unsafe.Pointer(&n)))
+		return int32(rc)
+	})
+	return total, int(n), err
+}
+
+// OnCreatingThread reports whether calls on the session run on the OS
+// thread that created it. That always holds for a session created with
+// PinThread, and only by chance for any other.
+func (s *Session) OnCreatingThread() (bool, error) {
+	if err := require(features.SessionOwner); err != nil {
+		return false, err
+	}
+	var owner int32
+	err := s.do("mySessionOwner", func(p uintptr) int32 {
+		rc, _, _ := procSessionOwner.Call(p, uintptr(unsafe.Pointer(&owner)))
+		return int32(rc)
+	})
+	return owner != 0, err
+}
+
+// Sync flushes the session to its peer, which takes d. The C call cannot
+// be interrupted; bound it with WithTimeout. Sync holds the session but
+// not the library lock, which would stall every other call while it runs.
+func (s *Session) Sync(d time.Duration) error {
+	if err := require(features.SessionSync); err != nil {
+		return err
+	}
+	ms, err := syncMillis(d)
+	if err != nil {
+		return err
+	}
+	return s.call("mySessionSync", false, func(p uintptr) int32 {
+		rc, _, _ := procSessionSync.Call(p, uintptr(ms))
+		return int32(rc)
+	})
+}
+
+// Close frees the C session. Calls after the first return ErrClosed.
+//
+// A poisoned session cannot be freed while the call that timed out may
+// still be using it. Close then returns ErrPoisoned and frees the session
+// once that call returns.
+func (s *Session) Close() error {
+	if s == nil {
+		return ErrClosed
+	}
+	if s.poisoned.Load() {
+		go s.close()
+		return ErrPoisoned
+	}
+	return s.close()
+}
+
+func (s *Session) close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.p == 0 {
+		return ErrClosed
+	}
+	s.cleanup.Stop()
+	p := s.p
+	s.thread.run(func() { freeSession(p) })
+	s.thread.stop()
+	s.p = 0
+	return nil
+}
@@ -0,0 +1,82 @@
+//go:build !nocgo && !windows
+
+package mylib
+
+/*
+
+#include "mylib.h"
+
+*/
+import "C"
+
+import "errors"
+
+// ErrFault is returned by ProbeRead for an address that cannot be read.
+var ErrFault = errors.New("mylib: bad address")
+
+// handlersInstalled records, under the C lock, whether the C handlers are
+// in place, for ProbeRead to tell a fault from a call before them.
+var handlersInstalled bool
+
+// InstallSignalHandlers installs the C library's own SIGINT and SIGSEGV
+// handlers in the running Go program.
+//
+// The Go runtime installs handlers for every signal at startup, so the C
+// handlers replace Go's. They keep Go working by following the rules in
+// the os/signal documentation for non-Go code: they are installed with
+// SA_ONSTACK, and every signal they do not consume is passed on to the
+// handler they replaced. SIGINT is counted and forwarded, so signal.Notify
+// still sees it. A SIGSEGV is only consumed when it comes from ProbeRead;
+// any other, such as a nil dereference in Go code, reaches Go's handler
+// and becomes the usual run-time panic.
+func InstallSignalHandlers() error {
+	return callC(func() error {
+		if err := codes.Error("myInstallSignalHandlers", int(C.myInstallSignalHandlers())); err != nil {
+			return err
+		}
+		handlersInstalled = true
+		return nil
+	})
+}
+
+// RestoreSignalHandlers puts back the handlers InstallSignalHandlers
+// replaced.
+func RestoreSignalHandlers() error {
+	return callC(func() error {
+		if err := codes.Error("myRestoreSignalHandlers", int(C.myRestoreSignalHandlers())); err != nil {
+			return err
+		}
+		handlersInstalled = false
+		return nil
+	})
+}
+
+// SignalCount returns the number of SIGINTs the C handler has seen.
+func SignalCount() int {
+	var n int
+	callC(func() error {
+		n = int(C.mySignalCount())
+		return nil
+	})
+	return n
+}
+
+// ProbeRead reads the byte at addr in C, and returns ErrFault instead of
+// crashing if the read faults. It needs the handlers from
+// InstallSignalHandlers, and fails with ErrInvalid without them.
+func ProbeRead(addr uintptr) (byte, error) {
+	var b C.uchar
+	err := callC(func() error {
+		if rc := C.myProbeRead(C.uintptr_t(addr), &b); rc != C.MYLIB_OK {
+			if !handlersInstalled {
+				return codes.Error("myProbeRead", int(rc))
+			}
+			return ErrFault
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	return byte(b), nil
+}
@@ -0,0 +1,85 @@
+//go:build cgo && !nocgo && !windows
+
+package mylib
+
+import (
+	"errors"
+	"os"
+	"os/signal"
+	"syscall"
+	"testing"
+	"time"
+	"unsafe"
+)
+
+// installSignalHandlers installs the C handlers on top of Go's for the
+// rest of the test.
+func installSignalHandlers(t *testing.T) {
+	t.Helper()
+	if err := InstallSignalHandlers(); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		if err := RestoreSignalHandlers(); err != nil {
+			t.Error(err)
+		}
+	})
+}
+
+func TestSignalSIGINTReachesBoth(t *testing.T) {
+	installSignalHandlers(t)
+	ch := make(chan os.Signal, 1)
+	signal.Notify(ch, syscall.SIGINT)
+	defer signal.Stop(ch)
+
+	before := SignalCount()
+	if err := syscall.Kill(os.Getpid(), syscall.SIGINT); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case <-ch:
+	case <-time.After(time.Second):
+		t.Fatal("os/signal did not receive SIGINT")
+	}
+	if n := SignalCount() - before; n != 1 {
+		t.Errorf("C handler saw %d SIGINTs, want 1", n)
+	}
+}
+
+func TestSignalGoNilDereference(t *testing.T) {
+	installSignalHandlers(t)
+	defer func() {
+		if r := recover(); r == nil {
+			t.Error("nil dereference did not panic")
+		} else if _, ok := r.(interface{ RuntimeError() }); !ok {
+			t.Errorf("recovered %v, want a runtime error", r)
+		}
+	}()
+	var p *int
+	t.Log(*p)
+}
+
+func TestProbeRead(t *testing.T) {
+	installSignalHandlers(t)
+	if _, err := ProbeRead(0); !errors.Is(err, ErrFault) {
+		t.Fatalf("ProbeRead(0) = %v, want ErrFault", err)
+	}
+	// Probing must keep working after a fault, on any thread.
+	for range 100 {
+		if _, err := ProbeRead(8); !errors.Is(err, ErrFault) {
+			t.Fatalf("ProbeRead(8) = %v, want ErrFault", err)
+		}
+	}
+	x := byte(42)
+	if b, err := ProbeRead(uintptr(unsafe.Pointer(&x))); err != nil || b != 42 {
+		t.Errorf("ProbeRead of a valid address = %d, %v; want 42", b, err)
+	}
+}
+
+func TestProbeReadWithoutHandlers(t *testing.T) {
+	x := byte(42)
+	_, err := ProbeRead(uintptr(unsafe.Pointer(&x)))
+	if !errors.Is(err, ErrInvalid) || errors.Is(err, ErrFault) {
+		t.Errorf("ProbeRead before InstallSignalHandlers = %v, want ErrInvalid", err)
+	}
+}
@@ -0,0 +1,73 @@
+//go:build !nocgo && !windows
+
+package mylib
+
+/*
+
+#include <stdint.h>
+#include "mylib.h"
+
+// Defined in sort_export.go.
+extern int goCompareTrampoline(void *a, void *b, void *userdata);
+
+static int compareGateway(const void *a, const void *b, void *userdata) {
+	return goCompareTrampoline((void *)a, (void *)b, userdata);
+}
+
+static int sortGateway(void *base, size_t n, size_t size, uintptr_t handle) {
+	return mySort(base, n, size, compareGateway, (void *)handle);
+}
+
+*/
+import "C"
+
+import (
+	"unsafe"
+
+	"github.com/lxwagn/using-go-with-c-libraries/pkg/cpanic"
+	"github.com/lxwagn/using-go-with-c-libraries/pkg/features"
+	"github.com/lxwagn/using-go-with-c-libraries/pkg/handles"
+)
+
+// compareFunc is cmp with the element type erased, since the trampoline
+// cannot be generic.
+type compareFunc func(a, b unsafe.Pointer) int
+
+// sortState is what the handle of a Sort in progress refers to: the
+// comparison and the barrier the trampoline calls it behind.
+type sortState struct {
+	cmp     compareFunc
+	barrier cpanic.Barrier
+}
+
+// Sort sorts s in place with the C library's mySort, a stable merge
+// sort, calling cmp from C for every comparison. cmp returns a negative
+// number, zero or a positive number as a sorts before, with or after b,
+// as for slices.SortFunc.
+//
+// s is handed to C where it is, so T must not contain Go pointers. Each
+// comparison is a call from C into Go, which costs far more than the
+// comparison itself; see the Sort benchmarks in package bench. cmp runs
+// while the library lock is held and must not call back into this
+// package. If cmp panics, the rest of the sort goes on with every
+// comparison equal, leaving s in some order, and Sort raises the panic
+// again as a *cpanic.Panic once mySort has returned.
+func Sort[T any](s []T, cmp func(a, b T) int) error {
+	if len(s) < 2 {
+		return nil
+	}
+	if err := require(features.Sort); err != nil {
+		return err
+	}
+	st := &sortState{cmp: func(a, b unsafe.Pointer) int { return cmp(*(*T)(a), *(*T)(b)) }}
+	h := handles.New(st)
+	defer h.Delete()
+
+	var zero T
+	err := callC(func() error {
+		rc := C.sortGateway(unsafe.Pointer(unsafe.SliceData(s)), C.size_t(len(s)), C.size_t(unsafe.Sizeof(zero)), C.uintptr_t(h.Uintptr()))
+		return codes.Error("mySort", int(rc))
+	})
+	st.barrier.Repanic()
+	return err
+}
@@ -0,0 +1,33 @@
+//go:build !nocgo && !windows
+
+package mylib
+
+/*
+#include <stdint.h>
+*/
+import "C"
+
+import (
+	"unsafe"
+
+	"github.com/lxwagn/using-go-with-c-libraries/pkg/cpanic"
+	"github.com/lxwagn/using-go-with-c-libraries/pkg/handles"
+)
+
+// goCompareTrampoline is mySort's comparison function for every Sort,
+// with a handle of the comparison as the userdata.
+//
+//export goCompareTrampoline
+func goCompareTrampoline(a, b, userdata unsafe.Pointer) C.int {
+	st, err := handles.FromUintptr[*sortState](uintptr(userdata)).Get()
+	if err != nil {
+		return 0
+	}
+	switch r := cpanic.Call(&st.barrier, 0, func() int { return st.cmp(a, b) }); {
+	case r < 0:
+		return -1
+	case r > 0:
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,59 @@
+package mylib
+
+import (
+	"fmt"
+	"unsafe"
+
+	"golang.org/x/sys/windows"
+
+	"github.com/lxwagn/using-go-with-c-libraries/pkg/features"
+)
+
+// Functions the library exports as __stdcall, with the bytes of arguments
+// each takes on 386, which decorated names carry.
+//
+// Calling a __stdcall function needs nothing special from Go: Proc.Call
+// restores the stack pointer after the call, so it does not matter
+// whether the function popped its arguments or left them to the caller.
+// What differs is the name. Unless a .def file or --kill-at says
+// otherwise, MSVC exports myMulAdd as _myMulAdd@16 and MinGW as
+// myMulAdd@16, which is how many Win32-style SDK DLLs ship.
+var stdcallFuncs = map[string]int{
+	"myMulAdd": 16,
+}
+
+var procMulAdd *windows.LazyProc
+
+// stdcallProc returns the __stdcall function name from dll, under its
+// plain name or either decoration, whichever the DLL exports. If none is
+// found it returns the plain one, whose Find reports the error.
+func stdcallProc(dll *windows.LazyDLL, name string) *windows.LazyProc {
+	n := stdcallFuncs[name]
+	for _, export := range []string{name, fmt.Sprintf("%s@%d", name, n), fmt.Sprintf("_%s@%d", name, n)} {
+		if p := dll.NewProc(export); p.Find() == nil {
+			return p
+		}
+	}
+	return dll.NewProc(name)
+}
+
+// MulAdd returns a*b + c, as computed by myMulAdd.
+func MulAdd(a, b int32, c int64) (int64, error) {
+	if err := require(features.MulAdd); err != nil {
+		return 0, err
+	}
+	var r int64
+	err := callC(func() error {
+		if unsafe.Sizeof(uintptr(0)) == 4 {
+			// On 386 a long long argument takes two stack slots, low word
+			// first, and the result comes back in EDX:EAX.
+			lo, hi, _ := procMulAdd.Call(uintptr(a), uintptr(b), uintptr(uint32(c)), uintptr(uint32(uint64(c)>>32)))
+			r = int64(uint64(hi)<<32 | uint64(uint32(lo)))
+			return nil
+		}
+		rc, _, _ := procMulAdd.Call(uintptr(a), uintptr(b), uintptr(c))
+		r = int64(rc)
+		return nil
+	})
+	return r, err
+}
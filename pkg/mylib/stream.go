@@ -0,0 +1,88 @@
+//go:build !nocgo && !windows
+
+package mylib
+
+/*
+
+#include <stdio.h>
+#include "mylib.h"
+
+*/
+import "C"
+
+import (
+	"io"
+	"strings"
+	"unsafe"
+
+	"github.com/lxwagn/using-go-with-c-libraries/pkg/cmem"
+	"github.com/lxwagn/using-go-with-c-libraries/pkg/cstdio"
+	"github.com/lxwagn/using-go-with-c-libraries/pkg/features"
+)
+
+// WriteReport has the C library print a report of values to w: a line
+// for the title, one per value and one for their total. It returns the
+// number of bytes written. w is called on this goroutine while the
+// library's lock is held, so it must not call into this package.
+func WriteReport(w io.Writer, title string, values []int64) (int64, error) {
+	if strings.IndexByte(title, 0) >= 0 {
+		return 0, ErrNUL
+	}
+	if err := require(features.Streams); err != nil {
+		return 0, err
+	}
+	ctitle := (*C.char)(cmem.CString(title))
+	defer cmem.Free(unsafe.Pointer(ctitle))
+
+	f, err := cstdio.NewWriter(w)
+	if err != nil {
+		return 0, err
+	}
+	var n C.long
+	err = callC(func() error {
+		var errno error
+		if n, errno = C.myWriteReport((*C.FILE)(f.File()), ctitle, (*C.longlong)(unsafe.Pointer(unsafe.SliceData(values))), C.size_t(len(values))); n < 0 {
+			return lastError("myWriteReport", errno)
+		}
+		return nil
+	})
+	// Most of the report is still in the FILE's buffer, and reaches w
+	// only now.
+	closeErr := f.Close()
+	if err != nil {
+		if werr := f.Err(); werr != nil {
+			return 0, werr
+		}
+		return 0, err
+	}
+	if closeErr != nil {
+		return 0, closeErr
+	}
+	return int64(n), nil
+}
+
+// OpenReport has the C library write the same report to a temporary file
+// and returns the file for reading. The caller must close it.
+func OpenReport(title string, values []int64) (io.ReadCloser, error) {
+	if strings.IndexByte(title, 0) >= 0 {
+		return nil, ErrNUL
+	}
+	if err := require(features.Streams); err != nil {
+		return nil, err
+	}
+	ctitle := (*C.char)(cmem.CString(title))
+	defer cmem.Free(unsafe.Pointer(ctitle))
+
+	var f *C.FILE
+	err := callC(func() error {
+		var errno error
+		if f, errno = C.myOpenReport(ctitle, (*C.longlong)(unsafe.Pointer(unsafe.SliceData(values))), C.size_t(len(values))); f == nil {
+			return lastError("myOpenReport", errno)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return cstdio.NewReader(unsafe.Pointer(f)), nil
+}
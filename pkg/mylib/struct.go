@@ -0,0 +1,133 @@
+//go:build !nocgo && !windows
+
+package mylib
+
+/*
+
+#include <stdlib.h>
+#include "mylib.h"
+
+*/
+import "C"
+
+import (
+	"strings"
+	"unsafe"
+
+	"github.com/lxwagn/using-go-with-c-libraries/pkg/cmem"
+	"github.com/lxwagn/using-go-with-c-libraries/pkg/cnum"
+	"github.com/lxwagn/using-go-with-c-libraries/pkg/mylib/marshal"
+)
+
+// MyStruct is the Go form of struct myStruct.
+type MyStruct = marshal.MyStruct
+
+// Point is the Go form of struct myPoint.
+type Point = marshal.Point
+
+// PrintStruct prints s using the C library's myPrintStruct.
+func PrintStruct(s MyStruct) error {
+	if strings.IndexByte(s.B, 0) >= 0 {
+		return ErrNUL
+	}
+	if _, err := cint("myPrintStruct", s.A); err != nil {
+		return err
+	}
+
+	a := cmem.NewArena(0)
+	defer a.Free()
+
+	return callC(func() error {
+		C.myPrintStruct((*C.struct_myStruct)(marshal.NewMyStruct(s, a)))
+		return nil
+	})
+}
+
+// MakeStruct builds a MyStruct on the C side and returns it by value.
+func MakeStruct(a int, b string) (MyStruct, error) {
+	if strings.IndexByte(b, 0) >= 0 {
+		return MyStruct{}, ErrNUL
+	}
+
+	ca, err := cint("myMakeStruct", a)
+	if err != nil {
+		return MyStruct{}, err
+	}
+
+	cb := (*C.char)(cmem.CString(b))
+	defer cmem.Free(unsafe.Pointer(cb))
+
+	var cs C.struct_myStruct
+	err = callC(func() error {
+		cs = C.myMakeStruct(C.int(ca), cb)
+		return nil
+	})
+	if err != nil {
+		return MyStruct{}, err
+	}
+	return marshal.GetMyStruct(unsafe.Pointer(&cs)), nil
+}
+
+// ScaleStruct passes s to C by value and returns a copy with A
+// multiplied by factor.
+func ScaleStruct(s MyStruct, factor int) (MyStruct, error) {
+	if strings.IndexByte(s.B, 0) >= 0 {
+		return MyStruct{}, ErrNUL
+	}
+	if _, err := cint("myScaleStruct", s.A); err != nil {
+		return MyStruct{}, err
+	}
+	cf, err := cint("myScaleStruct", factor)
+	if err != nil {
+		return MyStruct{}, err
+	}
+
+	a := cmem.NewArena(0)
+	defer a.Free()
+
+	var cs C.struct_myStruct
+	marshal.PutMyStruct(unsafe.Pointer(&cs), s, a)
+
+	var out C.struct_myStruct
+	err = callC(func() error {
+		out = C.myScaleStruct(cs, C.int(cf))
+		return nil
+	})
+	if err != nil {
+		return MyStruct{}, err
+	}
+	return marshal.GetMyStruct(unsafe.Pointer(&out)), nil
+}
+
+// TranslatePoints moves every point in pts by (dx, dy) in place. When Go
+// and C agree on the layout of the struct, the slice is handed to C
+// directly; otherwise it is copied to C memory and back. It panics if
+// len(pts), dx or dy does not fit in a C int.
+func TranslatePoints(pts []Point, dx, dy int) {
+	if len(pts) == 0 {
+		return
+	}
+	n := C.int(cnum.Must(cnum.ToCInt(len(pts))))
+	cdx := C.int(cnum.Must(cnum.ToCInt(dx)))
+	cdy := C.int(cnum.Must(cnum.ToCInt(dy)))
+
+	if marshal.PointLayout() == nil {
+		callC(func() error {
+			C.myTranslatePoints((*C.struct_myPoint)(marshal.PointsPtr(pts)), n, cdx, cdy)
+			return nil
+		})
+		return
+	}
+
+	a := cmem.NewArena(0)
+	defer a.Free()
+
+	p := marshal.CopyPointsToC(pts, a)
+	err := callC(func() error {
+		C.myTranslatePoints((*C.struct_myPoint)(p), n, cdx, cdy)
+		return nil
+	})
+	if err == nil {
+		marshal.CopyPointsFromC(pts, p, len(pts))
+	}
+}
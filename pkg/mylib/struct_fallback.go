@@ -0,0 +1,81 @@
+//go:build !cgo && !nocgo && !windows
+
+package mylib
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/lxwagn/using-go-with-c-libraries/pkg/cnum"
+)
+
+// MyStruct is the Go form of struct myStruct.
+type MyStruct struct {
+	A int
+	B string
+}
+
+// Point is the Go form of struct myPoint.
+type Point struct {
+	X      int32
+	Y      int32
+	Weight float64
+}
+
+// PrintStruct prints s as the C library's myPrintStruct does.
+func PrintStruct(s MyStruct) error {
+	if strings.IndexByte(s.B, 0) >= 0 {
+		return ErrNUL
+	}
+	ca, err := cint("myPrintStruct", s.A)
+	if err != nil {
+		return err
+	}
+
+	return callC(func() error {
+		_, err := fmt.Printf("myStruct{a: %d, b: \"%s\"}\n", ca, s.B)
+		return err
+	})
+}
+
+// MakeStruct builds a MyStruct. Like the C library, it refuses an A that
+// does not fit in the C struct's int.
+func MakeStruct(a int, b string) (MyStruct, error) {
+	if strings.IndexByte(b, 0) >= 0 {
+		return MyStruct{}, ErrNUL
+	}
+	ca, err := cint("myMakeStruct", a)
+	if err != nil {
+		return MyStruct{}, err
+	}
+	return MyStruct{A: int(ca), B: b}, nil
+}
+
+// ScaleStruct returns a copy of s with A multiplied by factor, in 32
+// bits as in C.
+func ScaleStruct(s MyStruct, factor int) (MyStruct, error) {
+	if strings.IndexByte(s.B, 0) >= 0 {
+		return MyStruct{}, ErrNUL
+	}
+	ca, err := cint("myScaleStruct", s.A)
+	if err != nil {
+		return MyStruct{}, err
+	}
+	cf, err := cint("myScaleStruct", factor)
+	if err != nil {
+		return MyStruct{}, err
+	}
+	return MyStruct{A: int(ca * cf), B: s.B}, nil
+}
+
+// TranslatePoints moves every point in pts by (dx, dy) in place. It
+// panics if len(pts), dx or dy does not fit in a C int.
+func TranslatePoints(pts []Point, dx, dy int) {
+	cnum.Must(cnum.ToCInt(len(pts)))
+	cdx := cnum.Must(cnum.ToCInt(dx))
+	cdy := cnum.Must(cnum.ToCInt(dy))
+	for i := range pts {
+		pts[i].X += cdx
+		pts[i].Y += cdy
+	}
+}
@@ -0,0 +1,158 @@
+//go:build nocgo && !windows
+
+package mylib
+
+import (
+	"strings"
+	"unsafe"
+
+	"github.com/lxwagn/using-go-with-c-libraries/pkg/cnum"
+)
+
+// MyStruct is the Go form of struct myStruct.
+type MyStruct struct {
+	A int
+	B string
+}
+
+// Point is the Go form of struct myPoint. It shares the C struct's memory
+// layout, so slices of it are passed to C as they are.
+type Point struct {
+	X      int32
+	Y      int32
+	Weight float64
+}
+
+// cMyStruct has the memory layout of struct myStruct.
+type cMyStruct struct {
+	a int32
+	b unsafe.Pointer
+}
+
+// cString copies s into memory from the C allocator, where the garbage
+// collector cannot move or free it. Free it with free.
+func cString(s string) unsafe.Pointer {
+	p := malloc(uintptr(len(s) + 1))
+	if p == nil {
+		panic("mylib: out of memory")
+	}
+	b := unsafe.Slice((*byte)(p), len(s)+1)
+	copy(b, s)
+	b[len(s)] = 0
+	return p
+}
+
+func fromCMyStruct(cs cMyStruct) MyStruct {
+	s := MyStruct{A: int(cs.a)}
+	if cs.b != nil {
+		s.B = goString((*byte)(cs.b))
+	}
+	return s
+}
+
+// goString copies the NUL-terminated C string at p.
+func goString(p *byte) string {
+	n := 0
+	for *(*byte)(unsafe.Add(unsafe.Pointer(p), n)) != 0 {
+		n++
+	}
+	return string(unsafe.Slice(p, n))
+}
+
+// PrintStruct prints s using the C library's myPrintStruct.
+func PrintStruct(s MyStruct) error {
+	if strings.IndexByte(s.B, 0) >= 0 {
+		return ErrNUL
+	}
+	ca, err := cint("myPrintStruct", s.A)
+	if err != nil {
+		return err
+	}
+	if err := load(); err != nil {
+		return err
+	}
+
+	cs := cMyStruct{a: ca, b: cString(s.B)}
+	defer free(cs.b)
+
+	return callC(func() error {
+		myPrintStruct(&cs)
+		return nil
+	})
+}
+
+// MakeStruct builds a MyStruct on the C side and returns it by value.
+func MakeStruct(a int, b string) (MyStruct, error) {
+	if strings.IndexByte(b, 0) >= 0 {
+		return MyStruct{}, ErrNUL
+	}
+	ca, err := cint("myMakeStruct", a)
+	if err != nil {
+		return MyStruct{}, err
+	}
+	if err := load(); err != nil {
+		return MyStruct{}, err
+	}
+
+	cb := cString(b)
+	defer free(cb)
+
+	var cs cMyStruct
+	err = callC(func() error {
+		cs = myMakeStruct(ca, cb)
+		return nil
+	})
+	if err != nil {
+		return MyStruct{}, err
+	}
+	return fromCMyStruct(cs), nil
+}
+
+// ScaleStruct passes s to C by value and returns a copy with A
+// multiplied by factor.
+func ScaleStruct(s MyStruct, factor int) (MyStruct, error) {
+	if strings.IndexByte(s.B, 0) >= 0 {
+		return MyStruct{}, ErrNUL
+	}
+	ca, err := cint("myScaleStruct", s.A)
+	if err != nil {
+		return MyStruct{}, err
+	}
+	cf, err := cint("myScaleStruct", factor)
+	if err != nil {
+		return MyStruct{}, err
+	}
+	if err := load(); err != nil {
+		return MyStruct{}, err
+	}
+
+	cb := cString(s.B)
+	defer free(cb)
+
+	var out cMyStruct
+	err = callC(func() error {
+		out = myScaleStruct(ca, cb, cf)
+		return nil
+	})
+	if err != nil {
+		return MyStruct{}, err
+	}
+	return fromCMyStruct(out), nil
+}
+
+// TranslatePoints moves every point in pts by (dx, dy) in place. It
+// panics if len(pts), dx or dy does not fit in a C int.
+func TranslatePoints(pts []Point, dx, dy int) {
+	if len(pts) == 0 {
+		return
+	}
+	n := cnum.Must(cnum.ToCInt(len(pts)))
+	cdx := cnum.Must(cnum.ToCInt(dx))
+	cdy := cnum.Must(cnum.ToCInt(dy))
+	mustLoad()
+
+	callC(func() error {
+		myTranslatePoints(unsafe.SliceData(pts), n, cdx, cdy)
+		return nil
+	})
+}
@@ -0,0 +1,153 @@
+package mylib
+
+import (
+	"runtime"
+	"strings"
+	"unsafe"
+
+	"github.com/lxwagn/using-go-with-c-libraries/pkg/cnum"
+)
+
+// MyStruct is the Go form of struct myStruct.
+type MyStruct struct {
+	A int
+	B string
+}
+
+// Point is the Go form of struct myPoint. It shares the C struct's memory
+// layout, so slices of it are passed to C as they are.
+type Point struct {
+	X      int32
+	Y      int32
+	Weight float64
+}
+
+// cMyStruct has the memory layout of struct myStruct.
+type cMyStruct struct {
+	a int32
+	b *byte
+}
+
+func fromCMyStruct(cs *cMyStruct) MyStruct {
+	s := MyStruct{A: int(cs.a)}
+	if cs.b != nil {
+		s.B = goString(cs.b)
+	}
+	return s
+}
+
+// PrintStruct prints s using the C library's myPrintStruct.
+func PrintStruct(s MyStruct) error {
+	if strings.IndexByte(s.B, 0) >= 0 {
+		return ErrNUL
+	}
+	ca, err := cint("myPrintStruct", s.A)
+	if err != nil {
+		return err
+	}
+	if err := load(); err != nil {
+		return err
+	}
+
+	cs := cMyStruct{a: ca, b: cString(s.B)}
+
+	return callC(func() error {
+		procPrintStruct.Call(uintptr(unsafe.Pointer(&cs)))
+		return nil
+	})
+}
+
+// A struct myStruct is 16 bytes, which changes how it travels by value.
+// The x64 calling convention passes it as a pointer to a copy and returns
+// it through a pointer the caller supplies as a hidden first argument.
+// On arm64 it goes in two registers each way, like two separate fields.
+
+// MakeStruct builds a MyStruct on the C side and returns it by value.
+func MakeStruct(a int, b string) (MyStruct, error) {
+	if strings.IndexByte(b, 0) >= 0 {
+		return MyStruct{}, ErrNUL
+	}
+	ca, err := cint("myMakeStruct", a)
+	if err != nil {
+		return MyStruct{}, err
+	}
+	if err := load(); err != nil {
+		return MyStruct{}, err
+	}
+
+	cb := cString(b)
+	var out cMyStruct
+
+	err = callC(func() error {
+		if runtime.GOARCH == "amd64" {
+			procMakeStruct.Call(uintptr(unsafe.Pointer(&out)), uintptr(ca), uintptr(unsafe.Pointer(cb)))
+		} else {
+			r1, r2, _ := procMakeStruct.Call(uintptr(ca), uintptr(unsafe.Pointer(cb)))
+			out = cMyStruct{a: int32(r1), b: (*byte)(cptr(r2))}
+		}
+		return nil
+	})
+	if err != nil {
+		return MyStruct{}, err
+	}
+
+	s := fromCMyStruct(&out)
+	runtime.KeepAlive(cb)
+	return s, nil
+}
+
+// ScaleStruct passes s to C by value and returns a copy with A
+// multiplied by factor.
+func ScaleStruct(s MyStruct, factor int) (MyStruct, error) {
+	if strings.IndexByte(s.B, 0) >= 0 {
+		return MyStruct{}, ErrNUL
+	}
+	ca, err := cint("myScaleStruct", s.A)
+	if err != nil {
+		return MyStruct{}, err
+	}
+	cf, err := cint("myScaleStruct", factor)
+	if err != nil {
+		return MyStruct{}, err
+	}
+	if err := load(); err != nil {
+		return MyStruct{}, err
+	}
+
+	cs := cMyStruct{a: ca, b: cString(s.B)}
+	var out cMyStruct
+
+	err = callC(func() error {
+		if runtime.GOARCH == "amd64" {
+			procScaleStruct.Call(uintptr(unsafe.Pointer(&out)), uintptr(unsafe.Pointer(&cs)), uintptr(cf))
+		} else {
+			r1, r2, _ := procScaleStruct.Call(uintptr(cs.a), uintptr(unsafe.Pointer(cs.b)), uintptr(cf))
+			out = cMyStruct{a: int32(r1), b: (*byte)(cptr(r2))}
+		}
+		return nil
+	})
+	if err != nil {
+		return MyStruct{}, err
+	}
+
+	r := fromCMyStruct(&out)
+	runtime.KeepAlive(cs.b)
+	return r, nil
+}
+
+// TranslatePoints moves every point in pts by (dx, dy) in place. It
+// panics if len(pts), dx or dy does not fit in a C int.
+func TranslatePoints(pts []Point, dx, dy int) {
+	if len(pts) == 0 {
+		return
+	}
+	n := cnum.Must(cnum.ToCInt(len(pts)))
+	cdx := cnum.Must(cnum.ToCInt(dx))
+	cdy := cnum.Must(cnum.ToCInt(dy))
+	mustLoad()
+
+	callC(func() error {
+		procTranslatePoints.Call(uintptr(unsafe.Pointer(unsafe.SliceData(pts))), uintptr(n), uintptr(cdx), uintptr(cdy))
+		return nil
+	})
+}
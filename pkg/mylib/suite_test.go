@@ -0,0 +1,123 @@
+package mylib
+
+import (
+	"errors"
+	"hash/adler32"
+	"maps"
+	"testing"
+)
+
+// The tests in this package are one suite for every build: they use only
+// the API the cgo binding and the purego one, built with -tags nocgo,
+// have in common, so that both pass the same assertions.
+
+func TestLookup(t *testing.T) {
+	for key, want := range map[string]int{"one": 1, "two": 2, "three": 3} {
+		if got, err := Lookup(key); err != nil || got != want {
+			t.Errorf("Lookup(%q) = %d, %v; want %d, nil", key, got, err, want)
+		}
+	}
+	if _, err := Lookup("four"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Lookup(%q): err = %v, want ErrNotFound", "four", err)
+	}
+	if _, err := Lookup("o\x00ne"); err != ErrNUL {
+		t.Errorf("Lookup with a NUL byte: err = %v, want ErrNUL", err)
+	}
+}
+
+func TestChecksum(t *testing.T) {
+	b := make([]byte, 1<<16)
+	Fill(b, 7)
+	if got, want := Checksum(b), adler32.Checksum(b); got != want {
+		t.Errorf("Checksum = %#x, want %#x", got, want)
+	}
+	if got, want := Checksum(nil), adler32.Checksum(nil); got != want {
+		t.Errorf("Checksum(nil) = %#x, want %#x", got, want)
+	}
+}
+
+func TestStructs(t *testing.T) {
+	s, err := MakeStruct(21, "hello")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s, err = ScaleStruct(s, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s.A != 42 || s.B != "hello" {
+		t.Errorf("ScaleStruct(MakeStruct(21, %q), 2) = %+v, want {A:42 B:hello}", "hello", s)
+	}
+
+	pts := []Point{{X: 1, Y: 2, Weight: 0.5}, {X: -3, Y: 4, Weight: 1}}
+	TranslatePoints(pts, 10, -10)
+	want := []Point{{X: 11, Y: -8, Weight: 0.5}, {X: 7, Y: -6, Weight: 1}}
+	for i := range pts {
+		if pts[i] != want[i] {
+			t.Errorf("TranslatePoints: pts[%d] = %+v, want %+v", i, pts[i], want[i])
+		}
+	}
+}
+
+func TestCountWords(t *testing.T) {
+	got, err := CountWords("  the cat and  the hat ")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]int{"the": 2, "cat": 1, "and": 1, "hat": 1}
+	if !maps.Equal(got, want) {
+		t.Errorf("CountWords = %v, want %v", got, want)
+	}
+}
+
+func TestWideReverse(t *testing.T) {
+	got, err := WideReverse("héllo, 世界")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "界世 ,olléh"; got != want {
+		t.Errorf("WideReverse = %q, want %q", got, want)
+	}
+	if n, err := WideCount("世界"); err != nil || n != 2 {
+		t.Errorf("WideCount = %d, %v; want 2, nil", n, err)
+	}
+}
+
+func TestSession(t *testing.T) {
+	s, err := NewSession("suite", 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	if total, err := s.Add(7); err != nil || total != 7 {
+		t.Errorf("Add(7) = %d, %v; want 7, nil", total, err)
+	}
+	if _, err := s.Add(7); !errors.Is(err, ErrRange) {
+		t.Errorf("Add past the limit: err = %v, want ErrRange", err)
+	}
+	if total, calls, err := s.Stats(); err != nil || total != 7 || calls != 1 {
+		t.Errorf("Stats = %d, %d, %v; want 7, 1, nil", total, calls, err)
+	}
+	if err := s.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.Add(1); err != ErrClosed {
+		t.Errorf("Add after Close: err = %v, want ErrClosed", err)
+	}
+}
+
+func TestReducer(t *testing.T) {
+	values := []int32{3, -1, 4, 1, -5}
+	for name, want := range map[string]int64{"sum": 2, "min": -5, "max": 4} {
+		r, err := GetReducer(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := r.Reduce(values); got != want {
+			t.Errorf("%s reducer = %d, want %d", name, got, want)
+		}
+	}
+	if _, err := GetReducer("avg"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("GetReducer(%q): err = %v, want ErrNotFound", "avg", err)
+	}
+}
@@ -0,0 +1,103 @@
+//go:build !nocgo && !windows
+
+package mylib
+
+/*
+
+#include <stdint.h>
+#include "mylib.h"
+
+// Defined in threads_export.go.
+extern void goThreadTrampoline(void *userdata, int thread, int seq);
+
+static myThreads *startThreadsGateway(int n, int count, uintptr_t handle) {
+	return myThreadsStart(n, count, goThreadTrampoline, (void *)handle);
+}
+
+*/
+import "C"
+
+import (
+	"github.com/lxwagn/using-go-with-c-libraries/pkg/features"
+	"github.com/lxwagn/using-go-with-c-libraries/pkg/handles"
+)
+
+// A ThreadEvent is one callback made by a thread of the C library.
+type ThreadEvent struct {
+	Thread int // index of the C thread
+	Seq    int // number of callbacks that thread made before this one
+}
+
+// A ThreadGroup is a set of threads created by the C library itself,
+// with pthread_create, that call into Go.
+//
+// A thread Go did not create can still call an exported Go function: the
+// runtime attaches it on the first call and it runs Go code like any other
+// goroutine. What it cannot get is a Go pointer from C, so the callbacks
+// find the group through a handle, and hand their events to the rest of
+// the program over a channel.
+type ThreadGroup struct {
+	events chan ThreadEvent
+	done   chan struct{}
+}
+
+// StartThreads has the C library start n threads that each make count
+// callbacks, and returns as soon as they are running. The callbacks
+// arrive on Events.
+func StartThreads(n, count int) (*ThreadGroup, error) {
+	if err := require(features.Threads); err != nil {
+		return nil, err
+	}
+	cn, err := cint("myThreadsStart", n)
+	if err != nil {
+		return nil, err
+	}
+	ccount, err := cint("myThreadsStart", count)
+	if err != nil {
+		return nil, err
+	}
+	g := &ThreadGroup{
+		events: make(chan ThreadEvent, 64),
+		done:   make(chan struct{}),
+	}
+	h := handles.New(g)
+
+	var t *C.myThreads
+	err = callC(func() error {
+		var err error
+		if t, err = C.startThreadsGateway(C.int(cn), C.int(ccount), C.uintptr_t(h.Uintptr())); t == nil {
+			return lastError("myThreadsStart", err)
+		}
+		return nil
+	})
+	if err != nil {
+		h.Delete()
+		return nil, err
+	}
+
+	go func() {
+		// Joining only touches the group, and can take as long as the
+		// receiver wants, so it is not done under the library lock.
+		releaseCUnlocked(func() {
+			C.myThreadsJoin(t)
+		})
+		h.Delete()
+		close(g.events)
+		close(g.done)
+	}()
+	return g, nil
+}
+
+// Events returns the channel the callbacks are delivered on. It is closed
+// once every thread has finished.
+//
+// The C threads block when the channel is full, so Events must be
+// drained for the group to finish.
+func (g *ThreadGroup) Events() <-chan ThreadEvent {
+	return g.events
+}
+
+// Wait waits for every thread of the group to finish.
+func (g *ThreadGroup) Wait() {
+	<-g.done
+}
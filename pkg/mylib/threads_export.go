@@ -0,0 +1,24 @@
+//go:build !nocgo && !windows
+
+package mylib
+
+import "C"
+
+import (
+	"unsafe"
+
+	"github.com/lxwagn/using-go-with-c-libraries/pkg/handles"
+)
+
+// goThreadTrampoline runs on a C thread. The first call from each thread
+// makes the runtime attach it; from then on it is an ordinary goroutine
+// for the duration of the call.
+//
+//export goThreadTrampoline
+func goThreadTrampoline(userdata unsafe.Pointer, thread, seq C.int) {
+	g, err := handles.FromUintptr[*ThreadGroup](uintptr(userdata)).Get()
+	if err != nil {
+		return
+	}
+	g.events <- ThreadEvent{Thread: int(thread), Seq: int(seq)}
+}
@@ -0,0 +1,77 @@
+//go:build cgo && !nocgo && !windows
+
+package mylib
+
+import (
+	"errors"
+	"runtime"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/lxwagn/using-go-with-c-libraries/pkg/handles"
+)
+
+// runThreads starts n C threads making count callbacks each, and checks
+// that every callback arrives exactly once, in order per thread, and that
+// nothing leaks.
+func runThreads(t *testing.T, n, count int) {
+	t.Helper()
+	liveBefore := handles.Live()
+	goroutinesBefore := runtime.NumGoroutine()
+
+	g, err := StartThreads(n, count)
+	if err != nil {
+		t.Fatal(err)
+	}
+	next := make([]int, n)
+	total := 0
+	for ev := range g.Events() {
+		if ev.Thread < 0 || ev.Thread >= n {
+			t.Fatalf("event from unknown thread %d", ev.Thread)
+		}
+		if ev.Seq != next[ev.Thread] {
+			t.Fatalf("thread %d: got seq %d, want %d", ev.Thread, ev.Seq, next[ev.Thread])
+		}
+		next[ev.Thread]++
+		total++
+	}
+	g.Wait()
+	if total != n*count {
+		t.Fatalf("got %d callbacks, want %d", total, n*count)
+	}
+
+	if live := handles.Live(); live != liveBefore {
+		t.Errorf("%d handles outstanding, want %d", live, liveBefore)
+	}
+	// The joining goroutine exits just after closing the channel.
+	deadline := time.Now().Add(time.Second)
+	for runtime.NumGoroutine() > goroutinesBefore {
+		if time.Now().After(deadline) {
+			t.Fatalf("%d goroutines running, want %d", runtime.NumGoroutine(), goroutinesBefore)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestThreads(t *testing.T) {
+	runThreads(t, 1, 10)
+}
+
+// TestThreadsStress has many C threads entering Go at once, each needing
+// the runtime to attach it and all competing for one channel.
+func TestThreadsStress(t *testing.T) {
+	runThreads(t, 64, 2000)
+}
+
+func TestThreadsRepeated(t *testing.T) {
+	for range 50 {
+		runThreads(t, 8, 10)
+	}
+}
+
+func TestStartThreadsInvalid(t *testing.T) {
+	if _, err := StartThreads(-1, 10); !errors.Is(err, syscall.EINVAL) {
+		t.Errorf("StartThreads(-1, 10) = %v, want EINVAL", err)
+	}
+}
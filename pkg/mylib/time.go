@@ -0,0 +1,90 @@
+//go:build !nocgo && !windows
+
+package mylib
+
+/*
+
+#include "mylib.h"
+
+*/
+import "C"
+
+import (
+	"math"
+	"time"
+	"unsafe"
+
+	"github.com/lxwagn/using-go-with-c-libraries/pkg/ctime"
+	"github.com/lxwagn/using-go-with-c-libraries/pkg/features"
+)
+
+// FormatTime returns t, to the second, as the C library prints it:
+// "2006-01-02T15:04:05Z", in UTC. It fails with ErrRange for a year
+// before 0 or after 9999, and with an error matching ErrInvalid and
+// cnum.ErrRange if t does not fit in a time_t.
+func FormatTime(t time.Time) (string, error) {
+	if err := require(features.Time); err != nil {
+		return "", err
+	}
+	sec, err := ctime.ToTimeT(t)
+	if err != nil {
+		return "", argError("myTimeFormat", err)
+	}
+	var buf [len("2006-01-02T15:04:05Z") + 1]C.char
+	var s string
+	err = callC(func() error {
+		if rc := C.myTimeFormat(C.time_t(sec), &buf[0], C.size_t(len(buf))); rc != C.MYLIB_OK {
+			return codes.Error("myTimeFormat", int(rc))
+		}
+		s = C.GoString(&buf[0])
+		return nil
+	})
+	return s, err
+}
+
+// TimespecAdd returns t+d as the C library adds them, in a struct
+// timespec. It fails with ErrRange if the sum does not fit in a time_t.
+func TimespecAdd(t time.Time, d time.Duration) (time.Time, error) {
+	if err := require(features.Time); err != nil {
+		return time.Time{}, err
+	}
+	var ts C.struct_timespec
+	if err := ctime.SetTimespec(unsafe.Pointer(&ts), t); err != nil {
+		return time.Time{}, argError("myTimespecAdd", err)
+	}
+	err := callC(func() error {
+		return codes.Error("myTimespecAdd", int(C.myTimespecAdd(&ts, C.longlong(d))))
+	})
+	if err != nil {
+		return time.Time{}, err
+	}
+	return ctime.Timespec(unsafe.Pointer(&ts))
+}
+
+// TimevalSub returns end-start, to the microsecond, as the C library
+// subtracts two struct timevals. It fails with ErrRange if the difference
+// does not fit in a time.Duration.
+func TimevalSub(end, start time.Time) (time.Duration, error) {
+	if err := require(features.Time); err != nil {
+		return 0, err
+	}
+	var e, s C.struct_timeval
+	if err := ctime.SetTimeval(unsafe.Pointer(&e), end); err != nil {
+		return 0, argError("myTimevalSub", err)
+	}
+	if err := ctime.SetTimeval(unsafe.Pointer(&s), start); err != nil {
+		return 0, argError("myTimevalSub", err)
+	}
+	var micros C.longlong
+	err := callC(func() error {
+		rc := C.myTimevalSub(&e, &s, &micros)
+		if rc == C.MYLIB_OK && (micros > math.MaxInt64/1000 || micros < math.MinInt64/1000) {
+			rc = C.MYLIB_ERANGE
+		}
+		return codes.Error("myTimevalSub", int(rc))
+	})
+	if err != nil {
+		return 0, err
+	}
+	return time.Duration(micros) * time.Microsecond, nil
+}
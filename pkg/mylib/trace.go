@@ -0,0 +1,166 @@
+package mylib
+
+import (
+	"context"
+	"fmt"
+	"go/token"
+	"reflect"
+	"regexp"
+	"runtime"
+	"runtime/trace"
+	"strings"
+	"sync/atomic"
+)
+
+// A TraceMode says how calls into the C library are annotated in an
+// execution trace, as recorded by runtime/trace.Start or a test's -trace
+// flag and shown by go tool trace.
+//
+// A goroutine in C shows in a trace only as running, or as a thread lost
+// to a syscall, with nothing to say which C function it is in. With
+// annotations on, every call into C is a region named after the wrapper
+// making it, such as "mylib.Lookup", which spans the wait for the library
+// lock, if the call takes it, and the call. A call that fails logs its
+// error in the region. The "User-defined regions" view then gives the
+// time spent in each wrapper and the goroutines whose calls are slow,
+// which is where to look when slow C calls pile up threads.
+//
+// The annotations are made around the call rather than the lock, so
+// the calls made without it, such as Session.Sync's and
+// Subscription.Close's, and every call of a build with -tags
+// mylib_nolock, are annotated too.
+type TraceMode int32
+
+const (
+	// TraceOff annotates nothing. It is the default.
+	TraceOff TraceMode = iota
+
+	// TraceRegions makes every call a region.
+	TraceRegions
+
+	// TraceTasks also makes every call a task of the same name holding
+	// its region, so that the "User-defined tasks" view groups the calls
+	// by wrapper, with a latency histogram for each.
+	TraceTasks
+)
+
+func (m TraceMode) String() string {
+	switch m {
+	case TraceOff:
+		return "off"
+	case TraceRegions:
+		return "regions"
+	case TraceTasks:
+		return "tasks"
+	}
+	return fmt.Sprintf("TraceMode(%d)", int(m))
+}
+
+var traceMode atomic.Int32
+
+// SetTraceMode sets how calls into C are annotated from now on and
+// returns the previous mode. The annotations cost nothing while no trace
+// is being recorded, and a walk of the caller's stack per call while one
+// is.
+func SetTraceMode(m TraceMode) TraceMode {
+	return TraceMode(traceMode.Swap(int32(m)))
+}
+
+// A traceSpan is what startTrace began for one call.
+type traceSpan struct {
+	ctx    context.Context
+	task   *trace.Task
+	region *trace.Region
+}
+
+// tracing reports whether calls are to be annotated.
+func tracing() bool {
+	return TraceMode(traceMode.Load()) != TraceOff && trace.IsEnabled()
+}
+
+// startTrace begins the annotations for a call into C made by the wrapper
+// name, if a trace is being recorded.
+func startTrace(name string) traceSpan {
+	mode := TraceMode(traceMode.Load())
+	if mode == TraceOff || !trace.IsEnabled() {
+		return traceSpan{}
+	}
+	ctx := context.Background()
+	var s traceSpan
+	if mode == TraceTasks {
+		ctx, s.task = trace.NewTask(ctx, name)
+	}
+	s.ctx = ctx
+	s.region = trace.StartRegion(ctx, name)
+	return s
+}
+
+// fail logs err, if the call failed, in its region.
+func (s traceSpan) fail(err error) {
+	if s.region != nil && err != nil {
+		trace.Log(s.ctx, "error", err.Error())
+	}
+}
+
+// context returns the context of the span's task, if it has one.
+func (s traceSpan) context() context.Context {
+	if s.ctx == nil {
+		return context.Background()
+	}
+	return s.ctx
+}
+
+func (s traceSpan) end() {
+	if s.region != nil {
+		s.region.End()
+	}
+	if s.task != nil {
+		s.task.End()
+	}
+}
+
+// pkgPath is this package's import path.
+var pkgPath = reflect.TypeFor[TraceMode]().PkgPath()
+
+// closureSuffix ends the name of a closure, such as
+// "(*Subscription).Close.func1", or "(*Subscription).Close.1" in a -race
+// build.
+var closureSuffix = regexp.MustCompile(`(\.func\d+|\.\d+)+$`)
+
+// wrapperName returns the name of the exported function of this package
+// that the call into C is made for, without the package path, such as
+// "mylib.Lookup" or "mylib.(*Session).Add". Helpers and closures that
+// call callC are named after the exported function calling them, and a
+// closure run by another package, such as a sync.Once, after the function
+// it is declared in.
+func wrapperName() string {
+	var pcs [16]uintptr
+	// Skip runtime.Callers, wrapperName, doC and callC.
+	n := runtime.Callers(4, pcs[:])
+	frames := runtime.CallersFrames(pcs[:n])
+	name := ""
+	for {
+		f, more := frames.Next()
+		rest, ok := strings.CutPrefix(f.Function, pkgPath+".")
+		if !ok {
+			break
+		}
+		if name == "" {
+			name = rest
+		}
+		fn := rest
+		if i := strings.Index(fn, ")."); fn[0] == '(' && i >= 0 {
+			fn = fn[i+2:]
+		}
+		if !strings.Contains(fn, ".") && token.IsExported(fn) {
+			return "mylib." + rest
+		}
+		if !more {
+			break
+		}
+	}
+	if name == "" {
+		return "mylib"
+	}
+	return "mylib." + closureSuffix.ReplaceAllString(name, "")
+}
@@ -0,0 +1,97 @@
+// The fallback build makes no calls into C, and so has none to annotate.
+
+//go:build cgo || nocgo || windows
+
+package mylib
+
+import (
+	"bytes"
+	"errors"
+	"runtime/trace"
+	"testing"
+	"time"
+)
+
+// traceOf records an execution trace of f with calls into C annotated as
+// mode says.
+func traceOf(t *testing.T, mode TraceMode, f func()) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := trace.Start(&buf); err != nil {
+		// The test binary is already tracing, for -trace.
+		t.Skipf("cannot start a trace: %v", err)
+	}
+	prev := SetTraceMode(mode)
+	f()
+	SetTraceMode(prev)
+	trace.Stop()
+	return buf.Bytes()
+}
+
+// hasString reports whether the trace b has s in its string table, where
+// a string shorter than 128 bytes is preceded by its length. A trace has
+// no parser in the standard library, but region and task names are kept
+// there, which is enough to tell whether the annotations were made.
+// Stacks name the wrappers too, with their package paths, so hasString
+// looks for the whole string.
+func hasString(b []byte, s string) bool {
+	return bytes.Contains(b, append([]byte{byte(len(s))}, s...))
+}
+
+func TestTraceRegions(t *testing.T) {
+	calls := func() {
+		if _, err := Lookup("two"); err != nil {
+			t.Error(err)
+		}
+		if _, err := CountWords("a b a"); err != nil {
+			t.Error(err)
+		}
+	}
+	for _, mode := range []TraceMode{TraceRegions, TraceTasks} {
+		b := traceOf(t, mode, calls)
+		for _, name := range []string{"mylib.Lookup", "mylib.CountWords"} {
+			if !hasString(b, name) {
+				t.Errorf("%v: no %s region in the trace", mode, name)
+			}
+		}
+	}
+	if b := traceOf(t, TraceOff, calls); hasString(b, "mylib.Lookup") {
+		t.Errorf("%v: mylib.Lookup region in the trace", TraceOff)
+	}
+}
+
+// TestTraceError checks that a failed call logs its error in its region.
+func TestTraceError(t *testing.T) {
+	var err error
+	b := traceOf(t, TraceRegions, func() {
+		_, err = Lookup("four")
+	})
+	if err == nil {
+		t.Fatal("Lookup(\"four\") succeeded")
+	}
+	if !hasString(b, "error") || !bytes.Contains(b, []byte(err.Error())) {
+		t.Errorf("no %q logged in the trace", err)
+	}
+}
+
+// TestTraceUnlocked checks that a call made without the library lock, as
+// Sync's is, is annotated like the others.
+func TestTraceUnlocked(t *testing.T) {
+	s, err := NewSession("trace", 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	b := traceOf(t, TraceRegions, func() {
+		err = s.Sync(time.Millisecond)
+	})
+	if errors.Is(err, errors.ErrUnsupported) {
+		t.Skip(err)
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !hasString(b, "mylib.(*Session).Sync") {
+		t.Error("no mylib.(*Session).Sync region in the trace")
+	}
+}
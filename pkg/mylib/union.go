@@ -0,0 +1,46 @@
+//go:build !nocgo && !windows
+
+package mylib
+
+/*
+
+#include "mylib.h"
+
+*/
+import "C"
+
+import (
+	"unsafe"
+
+	"github.com/lxwagn/using-go-with-c-libraries/pkg/mylib/marshal"
+)
+
+// Value is the Go form of struct myValue, a tagged union.
+type Value = marshal.Value
+
+// Flags is the Go form of struct myFlags, a set of bitfields.
+type Flags = marshal.Flags
+
+// DoubleValue has the C library double v in place: numbers are
+// multiplied by two and text is repeated. It returns an error matching
+// ErrRange if the repeated text would not fit.
+func DoubleValue(v *Value) error {
+	return callC(func() error {
+		return codes.Error("myValueDouble", int(C.myValueDouble((*C.struct_myValue)(marshal.ValuePtr(v)))))
+	})
+}
+
+// UpgradeFlags passes f to C by value and returns the result of
+// myFlagsUpgrade: readable flags become writable and the level goes up
+// by one.
+func UpgradeFlags(f Flags) Flags {
+	var cf C.struct_myFlags
+	marshal.FlagsToC(unsafe.Pointer(&cf), f)
+
+	var r C.struct_myFlags
+	callC(func() error {
+		r = C.myFlagsUpgrade(cf)
+		return nil
+	})
+	return marshal.FlagsFromC(unsafe.Pointer(&r))
+}
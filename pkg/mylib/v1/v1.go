@@ -0,0 +1,158 @@
+//go:build linux && !nocgo
+
+// Package mylib binds version 1.x of libmylib through dlopen, for
+// programs that use it alongside version 2.0, bound by pkg/mylib/v2:
+//
+//	import (
+//		mylib1 "github.com/lxwagn/using-go-with-c-libraries/pkg/mylib/v1"
+//		mylib2 "github.com/lxwagn/using-go-with-c-libraries/pkg/mylib/v2"
+//	)
+//
+// Every Library is loaded with RTLD_LOCAL, so its symbols never replace
+// those of the other version. It covers the functions whose signatures
+// 2.0 changed, and myCounterAdd, which shows that each version keeps its
+// own state.
+//
+// dlopen returns a library that is already loaded rather than loading it
+// again, so in a program that also links 1.x, through pkg/mylib for
+// instance, Open returns that copy, state and all.
+package mylib
+
+/*
+
+#cgo CFLAGS: -I${SRCDIR}/../../../src
+#include "mylib.h"
+
+// Each shim casts a symbol resolved with dlsym back to its type in
+// mylib.h and calls it. cgo cannot call through a function pointer
+// itself.
+
+static int callVersion(void *fn) {
+	return ((int (*)(void))fn)();
+}
+
+static int callLookup(void *fn, const char *key, int *value) {
+	return ((int (*)(const char *, int *))fn)(key, value);
+}
+
+static unsigned int callChecksum(void *fn, const unsigned char *buf, size_t n) {
+	return ((unsigned int (*)(const unsigned char *, size_t))fn)(buf, n);
+}
+
+static int callCounterAdd(void *fn, int delta) {
+	return ((int (*)(int))fn)(delta);
+}
+
+*/
+import "C"
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"unsafe"
+
+	"github.com/lxwagn/using-go-with-c-libraries/internal/status"
+	"github.com/lxwagn/using-go-with-c-libraries/pkg/cmem"
+	"github.com/lxwagn/using-go-with-c-libraries/pkg/dynload"
+)
+
+// DefaultName is the file name of the 1.x library.
+const DefaultName = "libmylib.so"
+
+var codes = status.Codes
+
+// Errors returned by the library. They are pkg/mylib's, so errors.Is
+// matches either package's.
+var (
+	ErrNUL      = status.ErrNUL
+	ErrNotFound = status.ErrNotFound
+	ErrInvalid  = status.ErrInvalid
+)
+
+// A Library is a loaded copy of libmylib 1.x. It is safe for concurrent
+// use until Close.
+type Library struct {
+	so *dynload.Handle
+
+	// mu is held for every call, as pkg/mylib's lock is.
+	mu sync.Mutex
+
+	version, lookup, checksum, counterAdd unsafe.Pointer
+}
+
+// Open loads the library from path, DefaultName if it is empty, and
+// fails unless it is version 1.x.
+func Open(path string) (*Library, error) {
+	if path == "" {
+		path = DefaultName
+	}
+	so, err := dynload.OpenHandle(path, dynload.Now)
+	if err != nil {
+		return nil, fmt.Errorf("mylib: %w", err)
+	}
+	l := &Library{so: so}
+	for _, s := range []struct {
+		p    *unsafe.Pointer
+		name string
+	}{
+		{&l.version, "myVersion"},
+		{&l.lookup, "myLookup"},
+		{&l.checksum, "myChecksum"},
+		{&l.counterAdd, "myCounterAdd"},
+	} {
+		if *s.p, err = so.Sym(s.name); err != nil {
+			so.Close()
+			return nil, err
+		}
+	}
+	if v := l.Version(); v/10000 != 1 {
+		so.Close()
+		return nil, fmt.Errorf("mylib: %s is version %d.%d, not 1.x", path, v/10000, v/100%100)
+	}
+	return l, nil
+}
+
+// Close unloads the library. It must not be used afterwards.
+func (l *Library) Close() error {
+	return l.so.Close()
+}
+
+// Version returns the library's myVersion.
+func (l *Library) Version() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return int(C.callVersion(l.version))
+}
+
+// Lookup returns the value stored in the library's table under key.
+func (l *Library) Lookup(key string) (int, error) {
+	if strings.IndexByte(key, 0) >= 0 {
+		return 0, ErrNUL
+	}
+	ckey := (*C.char)(cmem.CString(key))
+	defer cmem.Free(unsafe.Pointer(ckey))
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	var v C.int
+	if err := codes.Error("myLookup", int(C.callLookup(l.lookup, ckey, &v))); err != nil {
+		return 0, err
+	}
+	return int(v), nil
+}
+
+// Checksum returns the Adler-32 checksum of b.
+func (l *Library) Checksum(b []byte) uint32 {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return uint32(C.callChecksum(l.checksum, (*C.uchar)(unsafe.SliceData(b)), C.size_t(len(b))))
+}
+
+// CounterAdd adds delta to the library's counter and returns the new
+// value.
+func (l *Library) CounterAdd(delta int) int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return int(C.callCounterAdd(l.counterAdd, C.int(delta)))
+}
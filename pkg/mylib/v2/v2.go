@@ -0,0 +1,157 @@
+//go:build linux && !nocgo
+
+// Package mylib binds version 2.0 of libmylib through dlopen, for
+// programs that use it alongside version 1.x, bound by pkg/mylib/v1 or
+// pkg/mylib. It covers the functions whose signatures changed in 2.0, and
+// myCounterAdd, which shows that each version keeps its own state.
+//
+// Every symbol is resolved with dlvsym, asking for the version
+// MYLIB_2.0. A 1.x library has no versioned symbols, so Open fails on one
+// instead of binding functions whose signatures do not match.
+package mylib
+
+/*
+
+#cgo CFLAGS: -I${SRCDIR}/../../../src
+#include "mylib2.h"
+
+// Each shim casts a symbol resolved with dlvsym back to its type in
+// mylib2.h and calls it. cgo cannot call through a function pointer
+// itself.
+
+static int callVersion(void *fn) {
+	return ((int (*)(void))fn)();
+}
+
+static int callLookup(void *fn, const char *key, long long *value) {
+	return ((int (*)(const char *, long long *))fn)(key, value);
+}
+
+static unsigned int callChecksum(void *fn, unsigned int adler, const unsigned char *buf, size_t n) {
+	return ((unsigned int (*)(unsigned int, const unsigned char *, size_t))fn)(adler, buf, n);
+}
+
+static int callCounterAdd(void *fn, int delta) {
+	return ((int (*)(int))fn)(delta);
+}
+
+*/
+import "C"
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"unsafe"
+
+	"github.com/lxwagn/using-go-with-c-libraries/internal/status"
+	"github.com/lxwagn/using-go-with-c-libraries/pkg/cmem"
+	"github.com/lxwagn/using-go-with-c-libraries/pkg/dynload"
+)
+
+// DefaultName is the file name of the 2.0 library.
+const DefaultName = "libmylib.so.2"
+
+// SymbolVersion is the version of every symbol of the 2.0 library.
+const SymbolVersion = C.MYLIB2_SYMVER
+
+var codes = status.Codes
+
+// Errors returned by the library. Its status codes are unchanged from
+// 1.x, and the errors are pkg/mylib's, so errors.Is matches either
+// package's.
+var (
+	ErrNUL      = status.ErrNUL
+	ErrNotFound = status.ErrNotFound
+	ErrInvalid  = status.ErrInvalid
+)
+
+// A Library is a loaded copy of libmylib 2.0. It is safe for concurrent
+// use until Close.
+type Library struct {
+	so *dynload.Handle
+
+	// mu is held for every call, as pkg/mylib's lock is.
+	mu sync.Mutex
+
+	version, lookup, checksum, counterAdd unsafe.Pointer
+}
+
+// Open loads the library from path, DefaultName if it is empty, and
+// fails unless it is version 2.x.
+func Open(path string) (*Library, error) {
+	if path == "" {
+		path = DefaultName
+	}
+	so, err := dynload.OpenHandle(path, dynload.Now)
+	if err != nil {
+		return nil, fmt.Errorf("mylib: %w", err)
+	}
+	l := &Library{so: so}
+	for _, s := range []struct {
+		p    *unsafe.Pointer
+		name string
+	}{
+		{&l.version, "myVersion"},
+		{&l.lookup, "myLookup"},
+		{&l.checksum, "myChecksum"},
+		{&l.counterAdd, "myCounterAdd"},
+	} {
+		if *s.p, err = so.VSym(s.name, SymbolVersion); err != nil {
+			so.Close()
+			return nil, err
+		}
+	}
+	if v := l.Version(); v/10000 != 2 {
+		so.Close()
+		return nil, fmt.Errorf("mylib: %s is version %d.%d, not 2.x", path, v/10000, v/100%100)
+	}
+	return l, nil
+}
+
+// Close unloads the library. It must not be used afterwards.
+func (l *Library) Close() error {
+	return l.so.Close()
+}
+
+// Version returns the library's myVersion.
+func (l *Library) Version() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return int(C.callVersion(l.version))
+}
+
+// Lookup returns the value stored in the library's table under key.
+// Unlike 1.x's, the values are 64-bit.
+func (l *Library) Lookup(key string) (int64, error) {
+	if strings.IndexByte(key, 0) >= 0 {
+		return 0, ErrNUL
+	}
+	ckey := (*C.char)(cmem.CString(key))
+	defer cmem.Free(unsafe.Pointer(ckey))
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	var v C.longlong
+	if err := codes.Error("myLookup", int(C.callLookup(l.lookup, ckey, &v))); err != nil {
+		return 0, err
+	}
+	return int64(v), nil
+}
+
+// Checksum continues the Adler-32 checksum adler over b, as
+// hash/adler32 does; a checksum starts at 1. Checksum(1, b) equals 1.x's
+// Checksum(b).
+func (l *Library) Checksum(adler uint32, b []byte) uint32 {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return uint32(C.callChecksum(l.checksum, C.uint(adler), (*C.uchar)(unsafe.SliceData(b)), C.size_t(len(b))))
+}
+
+// CounterAdd adds delta to the library's counter and returns the new
+// value.
+func (l *Library) CounterAdd(delta int) int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return int(C.callCounterAdd(l.counterAdd, C.int(delta)))
+}
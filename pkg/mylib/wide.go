@@ -0,0 +1,64 @@
+//go:build !nocgo && !windows
+
+package mylib
+
+/*
+
+#include "mylib.h"
+
+*/
+import "C"
+
+import (
+	"unsafe"
+
+	"github.com/lxwagn/using-go-with-c-libraries/pkg/features"
+	"github.com/lxwagn/using-go-with-c-libraries/pkg/wchar"
+)
+
+// WideCount returns the number of characters in s as counted by the C
+// library in its wchar_t form.
+func WideCount(s string) (int, error) {
+	w, err := wchar.Encode(s)
+	if err != nil {
+		return 0, ErrNUL
+	}
+	if err := require(features.WideStrings); err != nil {
+		return 0, err
+	}
+
+	var n C.int
+	err = callC(func() error {
+		var errno error
+		if n, errno = C.myWideCount((*C.wchar_t)(unsafe.Pointer(&w[0]))); n < 0 {
+			return lastError("myWideCount", errno)
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	return int(n), nil
+}
+
+// WideReverse returns s with its characters in reverse order, reversed by
+// the C library in its wchar_t form. Characters outside the Basic
+// Multilingual Plane stay intact on Windows, where they are surrogate
+// pairs.
+func WideReverse(s string) (string, error) {
+	w, err := wchar.Encode(s)
+	if err != nil {
+		return "", ErrNUL
+	}
+	if err := require(features.WideStrings); err != nil {
+		return "", err
+	}
+
+	err = callC(func() error {
+		return codes.Error("myWideReverse", int(C.myWideReverse((*C.wchar_t)(unsafe.Pointer(&w[0])))))
+	})
+	if err != nil {
+		return "", err
+	}
+	return wchar.Decode(w), nil
+}
@@ -0,0 +1,30 @@
+//go:build !cgo && !nocgo && !windows
+
+package mylib
+
+import (
+	"slices"
+	"strings"
+	"unicode/utf8"
+)
+
+// WideCount returns the number of characters in s, as the C library
+// counts them in its wchar_t form: one per code point.
+func WideCount(s string) (int, error) {
+	if strings.IndexByte(s, 0) >= 0 {
+		return 0, ErrNUL
+	}
+	return utf8.RuneCountInString(s), nil
+}
+
+// WideReverse returns s with its characters, that is its code points, in
+// reverse order. Invalid UTF-8 becomes U+FFFD, as it does on the way to
+// wchar_t.
+func WideReverse(s string) (string, error) {
+	if strings.IndexByte(s, 0) >= 0 {
+		return "", ErrNUL
+	}
+	r := []rune(s)
+	slices.Reverse(r)
+	return string(r), nil
+}
@@ -0,0 +1,63 @@
+//go:build !nocgo && !windows
+
+package mylib
+
+/*
+
+#include "mylib.h"
+
+*/
+import "C"
+
+import (
+	"unsafe"
+
+	"github.com/lxwagn/using-go-with-c-libraries/pkg/features"
+	"github.com/lxwagn/using-go-with-c-libraries/pkg/wire"
+)
+
+// EncodeWire returns the wire-format bytes of h as myWireEncode writes
+// them. pkg/wire encodes the same bytes in Go; this is the library's
+// codec, for checking one against the other.
+func EncodeWire(h wire.Header) ([]byte, error) {
+	if err := require(features.Wire); err != nil {
+		return nil, err
+	}
+	ch := C.struct_myWireHeader{
+		sequence:  C.uint(h.Sequence),
+		timestamp: C.longlong(h.Timestamp),
+		delta:     C.short(h.Delta),
+		length:    C.ushort(h.Length),
+	}
+	b := make([]byte, wire.Size)
+	err := callC(func() error {
+		return codes.Error("myWireEncode", int(C.myWireEncode(&ch, (*C.uchar)(unsafe.Pointer(&b[0])), C.size_t(len(b)))))
+	})
+	if err != nil {
+		return nil, err
+	}
+	return b, nil
+}
+
+// DecodeWire decodes the header at the start of b with myWireDecode. It
+// fails with ErrInvalid for a short header or a bad magic and with
+// ErrRange for an unsupported version, where pkg/wire has errors of its
+// own.
+func DecodeWire(b []byte) (wire.Header, error) {
+	if err := require(features.Wire); err != nil {
+		return wire.Header{}, err
+	}
+	var ch C.struct_myWireHeader
+	err := callC(func() error {
+		return codes.Error("myWireDecode", int(C.myWireDecode((*C.uchar)(unsafe.Pointer(unsafe.SliceData(b))), C.size_t(len(b)), &ch)))
+	})
+	if err != nil {
+		return wire.Header{}, err
+	}
+	return wire.Header{
+		Sequence:  uint32(ch.sequence),
+		Timestamp: int64(ch.timestamp),
+		Delta:     int16(ch.delta),
+		Length:    uint16(ch.length),
+	}, nil
+}
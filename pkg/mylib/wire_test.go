@@ -0,0 +1,70 @@
+//go:build cgo && !nocgo && !windows
+
+package mylib
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"testing"
+
+	"github.com/lxwagn/using-go-with-c-libraries/pkg/wire"
+)
+
+// TestWire checks the C codec against pkg/wire, whose own tests hold it
+// to hand-written vectors: each must decode the other's bytes, and both
+// must refuse the same bad headers.
+func TestWire(t *testing.T) {
+	if WireMagic != wire.Magic || WireVersion != wire.Version || WireSize != wire.Size {
+		t.Fatalf("mylib.h has magic %#04x, version %d and size %d; pkg/wire %#04x, %d and %d",
+			WireMagic, WireVersion, WireSize, wire.Magic, wire.Version, wire.Size)
+	}
+	for _, h := range []wire.Header{
+		{},
+		{Sequence: 0x01020304, Timestamp: -2, Delta: -300, Length: 0x0a0b},
+		{Sequence: 0xdeadbeef, Timestamp: 1700000000123456789, Delta: 32767},
+	} {
+		goB, err := h.MarshalBinary()
+		if err != nil {
+			t.Fatal(err)
+		}
+		cB, err := EncodeWire(h)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(goB, cB) {
+			t.Errorf("%+v encodes as %x in Go and %x in C", h, goB, cB)
+		}
+		var fromC wire.Header
+		if err := fromC.UnmarshalBinary(cB); err != nil {
+			t.Fatal(err)
+		}
+		fromGo, err := DecodeWire(goB)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if fromC != h || fromGo != h {
+			t.Errorf("%+v decodes as %+v in Go and %+v in C", h, fromC, fromGo)
+		}
+	}
+}
+
+func TestWireRefused(t *testing.T) {
+	h := wire.Header{Sequence: 0x01020304, Timestamp: -2, Delta: -300, Length: 0x0a0b}
+	b, err := h.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+	swapped := binary.LittleEndian.AppendUint16(nil, wire.Magic)
+	swapped = append(swapped, b[2:]...)
+	if _, err := DecodeWire(swapped); !errors.Is(err, ErrInvalid) {
+		t.Errorf("decoded a little-endian magic %x: %v, want ErrInvalid", swapped, err)
+	}
+	b[2] = wire.Version + 1
+	if _, err := DecodeWire(b); !errors.Is(err, ErrRange) {
+		t.Errorf("decoded version %d: %v, want ErrRange", b[2], err)
+	}
+	if _, err := DecodeWire(b[:wire.Size-1]); !errors.Is(err, ErrInvalid) {
+		t.Errorf("decoded %d bytes: %v, want ErrInvalid", wire.Size-1, err)
+	}
+}
@@ -0,0 +1,57 @@
+//go:build !nocgo && !windows
+
+package mylib
+
+/*
+
+#include <stdint.h>
+#include <stdlib.h>
+#include "mylib.h"
+
+// Defined in words_export.go.
+extern void goWordTrampoline(void *userdata, char *word, int len);
+
+static void eachWordGateway(const char *text, uintptr_t handle) {
+	myEachWord(text, (myWordCallback)goWordTrampoline, (void *)handle);
+}
+
+*/
+import "C"
+
+import (
+	"strings"
+	"unsafe"
+
+	"github.com/lxwagn/using-go-with-c-libraries/pkg/cmem"
+	"github.com/lxwagn/using-go-with-c-libraries/pkg/handles"
+)
+
+// wordCounter is the Go state handed to C as opaque userdata. C only ever
+// sees its handle, never a pointer to it.
+type wordCounter struct {
+	counts map[string]int
+}
+
+// CountWords splits text on spaces in C and returns how often each word
+// occurs.
+func CountWords(text string) (map[string]int, error) {
+	if strings.IndexByte(text, 0) >= 0 {
+		return nil, ErrNUL
+	}
+
+	wc := &wordCounter{counts: make(map[string]int)}
+	h := handles.New(wc)
+	defer h.Delete()
+
+	ctext := (*C.char)(cmem.CString(text))
+	defer cmem.Free(unsafe.Pointer(ctext))
+
+	err := callC(func() error {
+		C.eachWordGateway(ctext, C.uintptr_t(h.Uintptr()))
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return wc.counts, nil
+}
@@ -0,0 +1,23 @@
+//go:build !nocgo && !windows
+
+package mylib
+
+/*
+#include <stdint.h>
+*/
+import "C"
+
+import (
+	"unsafe"
+
+	"github.com/lxwagn/using-go-with-c-libraries/pkg/handles"
+)
+
+//export goWordTrampoline
+func goWordTrampoline(userdata unsafe.Pointer, word *C.char, n C.int) {
+	wc, err := handles.FromUintptr[*wordCounter](uintptr(userdata)).Get()
+	if err != nil {
+		return
+	}
+	wc.counts[C.GoStringN(word, n)]++
+}
@@ -0,0 +1,15 @@
+// Package shm maps POSIX shared memory objects, the ones shm_open creates,
+// into Go, so that a Go program can exchange data with a C program, or one
+// built on libmylib, without copying it through a pipe or a socket.
+//
+// A Region is the mapped object as a []byte; View and ViewSlice look at
+// parts of it as typed Go values laid out the way C lays them out. Ring is
+// a single-producer, single-consumer message queue over a Region, in the
+// same format as libmylib's myRing functions, so either end may be C.
+//
+// Memory in a Region is shared with other processes, which may change it
+// at any time. Anything they write concurrently must be read and written
+// with sync/atomic, as Ring's indices are; the rest needs such an index to
+// say when it is safe to touch. The package is Linux only, where shared
+// memory objects live in /dev/shm.
+package shm
@@ -0,0 +1,116 @@
+//go:build linux
+
+package shm
+
+import (
+	"errors"
+	"io/fs"
+	"strings"
+	"syscall"
+)
+
+// dir is where Linux keeps shared memory objects.
+const dir = "/dev/shm"
+
+var errName = errors.New("shm: name must be a slash followed by a file name")
+
+// A Region is a shared memory object mapped into this process.
+type Region struct {
+	name string
+	data []byte
+}
+
+// Create creates the shared memory object name, which, as for shm_open,
+// is a slash followed by a file name, and maps size zeroed bytes of it.
+// It fails if the object already exists.
+func Create(name string, size int) (*Region, error) {
+	if size <= 0 {
+		return nil, &fs.PathError{Op: "create", Path: name, Err: fs.ErrInvalid}
+	}
+	return open(name, syscall.O_RDWR|syscall.O_CREAT|syscall.O_EXCL, size)
+}
+
+// Open maps the whole of the existing shared memory object name.
+func Open(name string) (*Region, error) {
+	return open(name, syscall.O_RDWR, 0)
+}
+
+func open(name string, flag, size int) (*Region, error) {
+	path, err := objectPath(name)
+	if err != nil {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: err}
+	}
+	fd, err := syscall.Open(path, flag|syscall.O_CLOEXEC|syscall.O_NOFOLLOW, 0600)
+	if err != nil {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: err}
+	}
+	defer syscall.Close(fd)
+
+	r, err := mapFD(fd, name, size)
+	if err != nil && size > 0 {
+		syscall.Unlink(path)
+	}
+	return r, err
+}
+
+func mapFD(fd int, name string, size int) (*Region, error) {
+	if size > 0 {
+		if err := syscall.Ftruncate(fd, int64(size)); err != nil {
+			return nil, &fs.PathError{Op: "truncate", Path: name, Err: err}
+		}
+	} else {
+		var st syscall.Stat_t
+		if err := syscall.Fstat(fd, &st); err != nil {
+			return nil, &fs.PathError{Op: "stat", Path: name, Err: err}
+		}
+		if st.Size <= 0 {
+			return nil, &fs.PathError{Op: "open", Path: name, Err: errors.New("shm: object is empty")}
+		}
+		size = int(st.Size)
+	}
+	data, err := syscall.Mmap(fd, 0, size, syscall.PROT_READ|syscall.PROT_WRITE, syscall.MAP_SHARED)
+	if err != nil {
+		return nil, &fs.PathError{Op: "mmap", Path: name, Err: err}
+	}
+	return &Region{name: name, data: data}, nil
+}
+
+// Unlink removes the shared memory object name. Processes that have it
+// mapped keep their mappings.
+func Unlink(name string) error {
+	path, err := objectPath(name)
+	if err == nil {
+		err = syscall.Unlink(path)
+	}
+	if err != nil {
+		return &fs.PathError{Op: "unlink", Path: name, Err: err}
+	}
+	return nil
+}
+
+func objectPath(name string) (string, error) {
+	if len(name) < 2 || name[0] != '/' || strings.ContainsAny(name[1:], "/\x00") || name == "/." || name == "/.." {
+		return "", errName
+	}
+	return dir + name, nil
+}
+
+// Name returns the name the region was created or opened with.
+func (r *Region) Name() string { return r.name }
+
+// Len returns the size of the region in bytes.
+func (r *Region) Len() int { return len(r.data) }
+
+// Bytes returns the mapped memory. It must not be used after Close.
+func (r *Region) Bytes() []byte { return r.data }
+
+// Close unmaps the region, after which neither it nor any view into it
+// may be used. It does not remove the shared memory object; see Unlink.
+func (r *Region) Close() error {
+	if r.data == nil {
+		return fs.ErrClosed
+	}
+	err := syscall.Munmap(r.data)
+	r.data = nil
+	return err
+}
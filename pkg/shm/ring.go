@@ -0,0 +1,171 @@
+//go:build linux
+
+package shm
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"sync/atomic"
+)
+
+// The ring layout, shared with libmylib's myRing functions (see the
+// comment on them in mylib.h): a 64-byte header holding a magic number,
+// the capacity of the data area that follows, and the free-running byte
+// counts written by the producer (head) and consumed by the consumer
+// (tail). Messages are a little-endian uint32 length and then the bytes,
+// wrapping around the end of the data area.
+const (
+	ringMagic  = 0x4d59524e
+	ringHeader = 64
+)
+
+type ringHeaderLayout struct {
+	Magic    atomic.Uint32
+	Capacity uint32
+	Head     atomic.Uint64
+	Tail     atomic.Uint64
+}
+
+var (
+	// ErrFull is returned by Push when the ring has no room for the
+	// message yet.
+	ErrFull = errors.New("shm: ring is full")
+	// ErrEmpty is returned by Pop when there is no message.
+	ErrEmpty = errors.New("shm: ring is empty")
+	// ErrTooLarge is returned by Push for a message bigger than the ring.
+	ErrTooLarge = errors.New("shm: message does not fit in ring")
+)
+
+// A Ring is a single-producer, single-consumer message queue in a shared
+// memory object. One process, or goroutine, may Push while another Pops,
+// with no locking; two may not Push, or Pop, at the same time.
+type Ring struct {
+	r    *Region
+	h    *ringHeaderLayout
+	data []byte
+	mask uint64
+}
+
+// CreateRing creates the shared memory object name holding an empty ring
+// whose data area is capacity bytes, a power of two. Each message takes 4
+// bytes of it besides its own.
+func CreateRing(name string, capacity int) (*Ring, error) {
+	if capacity <= 0 || capacity&(capacity-1) != 0 || capacity > 1<<30 {
+		return nil, fmt.Errorf("shm: ring capacity %d is not a power of two", capacity)
+	}
+	r, err := Create(name, ringHeader+capacity)
+	if err != nil {
+		return nil, err
+	}
+	ring, _ := newRing(r)
+	ring.h.Capacity = uint32(capacity)
+	ring.data = r.data[ringHeader:]
+	ring.mask = uint64(capacity - 1)
+	// The magic goes last: whoever opens the ring checks it first.
+	ring.h.Magic.Store(ringMagic)
+	return ring, nil
+}
+
+// OpenRing opens the ring in the existing shared memory object name.
+func OpenRing(name string) (*Ring, error) {
+	r, err := Open(name)
+	if err != nil {
+		return nil, err
+	}
+	ring, err := newRing(r)
+	if err == nil {
+		capacity := uint64(ring.h.Capacity)
+		switch {
+		case ring.h.Magic.Load() != ringMagic:
+			err = fmt.Errorf("shm: %s is not a ring", name)
+		case capacity == 0 || capacity&(capacity-1) != 0 || ringHeader+capacity > uint64(r.Len()):
+			err = fmt.Errorf("shm: %s has a corrupt ring header", name)
+		default:
+			ring.data = r.data[ringHeader : ringHeader+capacity]
+			ring.mask = capacity - 1
+			return ring, nil
+		}
+	}
+	r.Close()
+	return nil, err
+}
+
+func newRing(r *Region) (*Ring, error) {
+	if r.Len() < ringHeader {
+		return nil, fmt.Errorf("shm: %s is too small for a ring", r.Name())
+	}
+	h, err := View[ringHeaderLayout](r, 0)
+	if err != nil {
+		return nil, err
+	}
+	return &Ring{r: r, h: h}, nil
+}
+
+// Cap returns the size of the ring's data area.
+func (q *Ring) Cap() int { return len(q.data) }
+
+// Push adds msg to the ring, or returns ErrFull if there is no room for it
+// yet.
+func (q *Ring) Push(msg []byte) error {
+	need := uint64(len(msg)) + 4
+	if need > uint64(len(q.data)) {
+		return ErrTooLarge
+	}
+	head := q.h.Head.Load()
+	tail := q.h.Tail.Load()
+	if uint64(len(q.data))-(head-tail) < need {
+		return ErrFull
+	}
+	var n [4]byte
+	binary.LittleEndian.PutUint32(n[:], uint32(len(msg)))
+	q.copyIn(head, n[:])
+	q.copyIn(head+4, msg)
+	q.h.Head.Store(head + need)
+	return nil
+}
+
+// Pop removes the oldest message from the ring, copying it into buf, and
+// returns its length. If the ring is empty it returns ErrEmpty. If buf is
+// too short it returns the message's length and io.ErrShortBuffer, and
+// leaves the message in the ring.
+func (q *Ring) Pop(buf []byte) (int, error) {
+	tail := q.h.Tail.Load()
+	head := q.h.Head.Load()
+	if head == tail {
+		return 0, ErrEmpty
+	}
+	var n [4]byte
+	q.copyOut(tail, n[:])
+	size := binary.LittleEndian.Uint32(n[:])
+	if uint64(size)+4 > head-tail {
+		return 0, fmt.Errorf("shm: ring %s is corrupt", q.r.Name())
+	}
+	if int(size) > len(buf) {
+		return int(size), io.ErrShortBuffer
+	}
+	q.copyOut(tail+4, buf[:size])
+	q.h.Tail.Store(tail + 4 + uint64(size))
+	return int(size), nil
+}
+
+// copyIn and copyOut copy between p and the data area at the free-running
+// offset off, wrapping around its end.
+func (q *Ring) copyIn(off uint64, p []byte) {
+	i := int(off & q.mask)
+	n := copy(q.data[i:], p)
+	copy(q.data, p[n:])
+}
+
+func (q *Ring) copyOut(off uint64, p []byte) {
+	i := int(off & q.mask)
+	n := copy(p, q.data[i:])
+	copy(p[n:], q.data)
+}
+
+// Close unmaps the ring. It does not remove the shared memory object; see
+// Unlink.
+func (q *Ring) Close() error {
+	return q.r.Close()
+}
@@ -0,0 +1,46 @@
+//go:build linux
+
+package shm
+
+import (
+	"fmt"
+	"io/fs"
+	"unsafe"
+)
+
+// View returns a pointer to the T at byte offset off in r, which must lie
+// inside the region and be aligned for T. T must hold no Go pointers, and
+// should only contain fixed-size types (int32 rather than int) so that its
+// layout matches the C struct it stands for.
+//
+// The pointer is valid until r is closed.
+func View[T any](r *Region, off int) (*T, error) {
+	p, err := view[T](r, off, 1)
+	return (*T)(p), err
+}
+
+// ViewSlice returns the n values of type T starting at byte offset off in
+// r, with the same restrictions as View.
+func ViewSlice[T any](r *Region, off, n int) ([]T, error) {
+	p, err := view[T](r, off, n)
+	if err != nil {
+		return nil, err
+	}
+	return unsafe.Slice((*T)(p), n), nil
+}
+
+func view[T any](r *Region, off, n int) (unsafe.Pointer, error) {
+	var zero T
+	size, align := int(unsafe.Sizeof(zero)), int(unsafe.Alignof(zero))
+	if r.data == nil {
+		return nil, fs.ErrClosed
+	}
+	if off < 0 || off > len(r.data) || n < 0 || (size > 0 && n > (len(r.data)-off)/size) {
+		return nil, fmt.Errorf("shm: %d values of %T at offset %d do not fit in %d bytes", n, zero, off, len(r.data))
+	}
+	// The mapping starts on a page boundary, so off alone decides.
+	if off%align != 0 {
+		return nil, fmt.Errorf("shm: offset %d is not aligned for %T", off, zero)
+	}
+	return unsafe.Add(unsafe.Pointer(unsafe.SliceData(r.data)), off), nil
+}
@@ -0,0 +1,57 @@
+// Package wchar converts between Go strings and C wchar_t strings.
+//
+// The width of wchar_t is up to the platform: on Linux, macOS and the
+// BSDs it is 4 bytes holding UTF-32 code points, on Windows 2 bytes
+// holding UTF-16, where code points above U+FFFF take a surrogate pair.
+// Char has the platform's width, and Encode and Decode use its encoding.
+//
+// Conversions never fail on bad input. Ill-formed UTF-8 in a Go string,
+// and unpaired surrogates or values beyond U+10FFFF in a wchar_t string,
+// become U+FFFD, as they do in the standard library's conversions.
+package wchar
+
+import (
+	"errors"
+	"strings"
+	"unsafe"
+)
+
+// ErrNUL is returned by Encode for a string with a NUL byte, which C
+// would take as its end.
+var ErrNUL = errors.New("wchar: string contains NUL byte")
+
+// Size is the size of a wchar_t in bytes.
+const Size = int(unsafe.Sizeof(Char(0)))
+
+// Encode returns s as a NUL-terminated wchar_t string.
+func Encode(s string) ([]Char, error) {
+	if strings.IndexByte(s, 0) >= 0 {
+		return nil, ErrNUL
+	}
+	return append(encode(s), 0), nil
+}
+
+// Decode returns the string in w, up to its first NUL, or all of w if it
+// has none.
+func Decode(w []Char) string {
+	for i, c := range w {
+		if c == 0 {
+			w = w[:i]
+			break
+		}
+	}
+	return decode(w)
+}
+
+// GoString returns a Go copy of the NUL-terminated wchar_t string at p,
+// like C.GoString does for char strings. A nil p gives "".
+func GoString(p unsafe.Pointer) string {
+	if p == nil {
+		return ""
+	}
+	n := 0
+	for *(*Char)(unsafe.Add(p, n*Size)) != 0 {
+		n++
+	}
+	return decode(unsafe.Slice((*Char)(p), n))
+}
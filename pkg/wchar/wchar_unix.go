@@ -0,0 +1,24 @@
+//go:build !windows
+
+package wchar
+
+import "strings"
+
+// Char is C's wchar_t: a UTF-32 code point.
+type Char = int32
+
+func encode(s string) []Char {
+	// Converting to []rune replaces ill-formed UTF-8 with U+FFFD.
+	return []rune(s)
+}
+
+func decode(w []Char) string {
+	var b strings.Builder
+	b.Grow(len(w))
+	for _, c := range w {
+		// WriteRune writes U+FFFD for surrogates and values that are
+		// not code points.
+		b.WriteRune(c)
+	}
+	return b.String()
+}
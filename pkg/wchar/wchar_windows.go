@@ -0,0 +1,15 @@
+package wchar
+
+import "unicode/utf16"
+
+// Char is C's wchar_t: a UTF-16 code unit.
+type Char = uint16
+
+func encode(s string) []Char {
+	return utf16.Encode([]rune(s))
+}
+
+func decode(w []Char) string {
+	// Decode replaces unpaired surrogates with U+FFFD.
+	return string(utf16.Decode(w))
+}
@@ -0,0 +1,104 @@
+// Package wire encodes and decodes the message header of the C library's
+// wire format, the packed, big-endian layout that mylib.h documents, in
+// Go alone.
+//
+// The bytes of a header cannot be read through a Go or C struct: most of
+// its fields are unaligned, and a struct would hold them in the host's
+// byte order, which is big-endian only on a few targets Go supports. The
+// codec reads and writes each field at its offset with
+// encoding/binary.BigEndian instead, so a Header decodes the same from
+// bytes written on any host, by this package or by myWireEncode:
+//
+//	var h wire.Header
+//	if err := h.UnmarshalBinary(packet[:wire.Size]); err != nil {
+//		return err
+//	}
+//	payload := packet[wire.Size:][:h.Length]
+//
+// The package does not use cgo, so builds without it share it, and
+// mylib.EncodeWire and mylib.DecodeWire are there to check it against
+// the C library's own codec.
+package wire
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+)
+
+// The constants of the format, as MYLIB_WIRE_MAGIC, MYLIB_WIRE_VERSION
+// and MYLIB_WIRE_SIZE define them.
+const (
+	Magic   = 0x4d57
+	Version = 1
+	Size    = 19
+)
+
+// The offset of each field in an encoded header.
+const (
+	offMagic     = 0
+	offVersion   = 2
+	offSequence  = 3
+	offTimestamp = 7
+	offDelta     = 15
+	offLength    = 17
+)
+
+var (
+	// ErrShort is returned for fewer than Size bytes.
+	ErrShort = errors.New("wire: short header")
+
+	// ErrMagic is returned for bytes that do not start with Magic.
+	ErrMagic = errors.New("wire: bad magic")
+
+	// ErrVersion is returned for a header of a version other than
+	// Version.
+	ErrVersion = errors.New("wire: unsupported version")
+)
+
+// A Header is a message header with its fields in Go's types. The magic
+// and version are not fields: encoding writes this package's, and
+// decoding checks for them.
+type Header struct {
+	Sequence  uint32
+	Timestamp int64 // nanoseconds since the Unix epoch, by convention
+	Delta     int16
+	Length    uint16 // of the payload that follows the header
+}
+
+// AppendBinary appends the encoded header to b.
+func (h Header) AppendBinary(b []byte) ([]byte, error) {
+	b = binary.BigEndian.AppendUint16(b, Magic)
+	b = append(b, Version)
+	b = binary.BigEndian.AppendUint32(b, h.Sequence)
+	b = binary.BigEndian.AppendUint64(b, uint64(h.Timestamp))
+	b = binary.BigEndian.AppendUint16(b, uint16(h.Delta))
+	b = binary.BigEndian.AppendUint16(b, h.Length)
+	return b, nil
+}
+
+// MarshalBinary returns the Size bytes of the encoded header.
+func (h Header) MarshalBinary() ([]byte, error) {
+	return h.AppendBinary(make([]byte, 0, Size))
+}
+
+// UnmarshalBinary decodes the header at the start of b, which may go on
+// with the payload.
+func (h *Header) UnmarshalBinary(b []byte) error {
+	if len(b) < Size {
+		return fmt.Errorf("%w: %d bytes, want %d", ErrShort, len(b), Size)
+	}
+	if m := binary.BigEndian.Uint16(b[offMagic:]); m != Magic {
+		return fmt.Errorf("%w %#04x", ErrMagic, m)
+	}
+	if v := b[offVersion]; v != Version {
+		return fmt.Errorf("%w %d", ErrVersion, v)
+	}
+	*h = Header{
+		Sequence:  binary.BigEndian.Uint32(b[offSequence:]),
+		Timestamp: int64(binary.BigEndian.Uint64(b[offTimestamp:])),
+		Delta:     int16(binary.BigEndian.Uint16(b[offDelta:])),
+		Length:    binary.BigEndian.Uint16(b[offLength:]),
+	}
+	return nil
+}
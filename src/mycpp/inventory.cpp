@@ -0,0 +1,39 @@
+#include "inventory.hpp"
+
+namespace mycpp {
+
+void Inventory::add(const std::string &name, int qty) {
+	if (qty <= 0)
+		throw std::invalid_argument("quantity must be positive");
+	items_[name] += qty;
+}
+
+void Inventory::remove(const std::string &name, int qty) {
+	if (qty <= 0)
+		throw std::invalid_argument("quantity must be positive");
+	auto it = items_.find(name);
+	if (it == items_.end())
+		throw NotFound(name);
+	if (it->second < qty)
+		throw std::out_of_range("only " + std::to_string(it->second) + " " + name + " in stock");
+	it->second -= qty;
+}
+
+int Inventory::count(const std::string &name) const {
+	auto it = items_.find(name);
+	if (it == items_.end())
+		throw NotFound(name);
+	return it->second;
+}
+
+std::string Inventory::describe() const {
+	std::string s;
+	for (const auto &item : items_) {
+		if (!s.empty())
+			s += ' ';
+		s += item.first + "=" + std::to_string(item.second);
+	}
+	return s;
+}
+
+} // namespace mycpp
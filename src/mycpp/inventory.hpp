@@ -0,0 +1,37 @@
+// An example C++ library: a stock count per item name. It uses classes,
+// std::string and exceptions, none of which Go can call directly; mycpp.h
+// is the C interface to it.
+#pragma once
+
+#include <map>
+#include <stdexcept>
+#include <string>
+
+namespace mycpp {
+
+// NotFound is thrown for an item the inventory has never seen.
+class NotFound : public std::runtime_error {
+public:
+	explicit NotFound(const std::string &name)
+		: std::runtime_error("no item named " + name) {}
+};
+
+class Inventory {
+public:
+	// add increases the stock of name. qty must be positive.
+	void add(const std::string &name, int qty);
+
+	// remove decreases the stock of name, throwing std::out_of_range if
+	// there is not enough of it.
+	void remove(const std::string &name, int qty);
+
+	int count(const std::string &name) const;
+
+	// describe lists the items as "name=qty" pairs in name order.
+	std::string describe() const;
+
+private:
+	std::map<std::string, int> items_;
+};
+
+} // namespace mycpp
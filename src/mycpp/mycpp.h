@@ -0,0 +1,36 @@
+/*
+ * C interface to the C++ Inventory class. Every function catches all
+ * exceptions and turns them into a status code: a C++ exception must never
+ * unwind through a C or Go caller. The exception's message is kept until
+ * the next call on the same inventory and can be read with
+ * myInventoryLastError.
+ */
+#ifdef __cplusplus
+extern "C" {
+#endif
+
+#define MYCPP_OK 0
+#define MYCPP_ENOTFOUND 1
+#define MYCPP_EINVAL 2
+#define MYCPP_ERANGE 3
+#define MYCPP_ENOMEM 4
+#define MYCPP_EUNKNOWN 5
+
+typedef struct myInventory myInventory;
+
+/* Returns NULL if out of memory. */
+myInventory *myInventoryNew(void);
+void myInventoryFree(myInventory *inv);
+
+int myInventoryAdd(myInventory *inv, const char *name, int qty);
+int myInventoryRemove(myInventory *inv, const char *name, int qty);
+int myInventoryCount(myInventory *inv, const char *name, int *count);
+
+/* Stores a malloc'd string in *out, to be released with free. */
+int myInventoryDescribe(myInventory *inv, char **out);
+
+const char *myInventoryLastError(const myInventory *inv);
+
+#ifdef __cplusplus
+}
+#endif
@@ -0,0 +1,79 @@
+#include <cstdlib>
+#include <cstring>
+#include <new>
+
+#include "inventory.hpp"
+#include "mycpp.h"
+
+struct myInventory {
+	mycpp::Inventory inv;
+	std::string lastError;
+};
+
+// guard runs f, translating any exception into a status code and
+// recording its message. It is the only place exceptions are caught, so
+// no entry point can let one escape.
+template <typename F>
+static int guard(myInventory *inv, F f) {
+	try {
+		inv->lastError.clear();
+		f();
+		return MYCPP_OK;
+	} catch (const mycpp::NotFound &e) {
+		inv->lastError = e.what();
+		return MYCPP_ENOTFOUND;
+	} catch (const std::invalid_argument &e) {
+		inv->lastError = e.what();
+		return MYCPP_EINVAL;
+	} catch (const std::out_of_range &e) {
+		inv->lastError = e.what();
+		return MYCPP_ERANGE;
+	} catch (const std::bad_alloc &) {
+		// Assigning the message could throw again.
+		return MYCPP_ENOMEM;
+	} catch (const std::exception &e) {
+		inv->lastError = e.what();
+		return MYCPP_EUNKNOWN;
+	} catch (...) {
+		return MYCPP_EUNKNOWN;
+	}
+}
+
+extern "C" {
+
+myInventory *myInventoryNew(void) {
+	return new (std::nothrow) myInventory;
+}
+
+void myInventoryFree(myInventory *inv) {
+	delete inv;
+}
+
+int myInventoryAdd(myInventory *inv, const char *name, int qty) {
+	return guard(inv, [&] { inv->inv.add(name, qty); });
+}
+
+int myInventoryRemove(myInventory *inv, const char *name, int qty) {
+	return guard(inv, [&] { inv->inv.remove(name, qty); });
+}
+
+int myInventoryCount(myInventory *inv, const char *name, int *count) {
+	return guard(inv, [&] { *count = inv->inv.count(name); });
+}
+
+int myInventoryDescribe(myInventory *inv, char **out) {
+	return guard(inv, [&] {
+		std::string s = inv->inv.describe();
+		char *p = static_cast<char *>(std::malloc(s.size() + 1));
+		if (p == nullptr)
+			throw std::bad_alloc();
+		std::memcpy(p, s.c_str(), s.size() + 1);
+		*out = p;
+	});
+}
+
+const char *myInventoryLastError(const myInventory *inv) {
+	return inv->lastError.c_str();
+}
+
+} // extern "C"
@@ -1,6 +1,2182 @@
+#include <assert.h>
+#include <errno.h>
+#include <limits.h>
+#include <stdarg.h>
+#include <stddef.h>
+#include <stdlib.h>
+#include <string.h>
+#include <sys/stat.h>
+#include <time.h>
+
 #include "mylib.h"
+#include "mylib_layout.h"
+
+#ifdef _WIN32
+#include <windows.h>
+
+#if defined(_MSC_VER) && !defined(__clang__)
+/*
+ * MSVC has none of GCC's __atomic builtins, which the rest of the file
+ * uses, nor S_ISREG. The stand-ins cover the 4- and 8-byte integers the
+ * builtins are applied to; the Interlocked functions are full barriers,
+ * stronger than any order asked for.
+ */
+#define __ATOMIC_RELAXED 0
+#define __ATOMIC_ACQUIRE 2
+#define __ATOMIC_RELEASE 3
+#define __atomic_load_n(p, order)                                                  \
+	(sizeof(*(p)) == 8 ? InterlockedCompareExchange64((volatile LONG64 *)(p), 0, 0) \
+			   : InterlockedCompareExchange((volatile LONG *)(p), 0, 0))
+#define __atomic_fetch_add(p, n, order)                                        \
+	(sizeof(*(p)) == 8 ? InterlockedExchangeAdd64((volatile LONG64 *)(p), (n)) \
+			   : InterlockedExchangeAdd((volatile LONG *)(p), (LONG)(n)))
+#define __atomic_fetch_sub(p, n, order) __atomic_fetch_add(p, -(n), order)
+#define S_ISREG(m) (((m) & _S_IFMT) == _S_IFREG)
+#endif
+
+static unsigned long threadSelf(void) {
+	return GetCurrentThreadId();
+}
+
+/*
+ * fail records why the current call failed: in errno and, since that is
+ * what the Windows binding can read reliably, in GetLastError.
+ */
+static void fail(int err) {
+	DWORD code;
+
+	errno = err;
+	switch (err) {
+	case EINVAL:
+		code = ERROR_INVALID_PARAMETER;
+		break;
+	case ENOMEM:
+	case EAGAIN:
+		code = ERROR_NOT_ENOUGH_MEMORY;
+		break;
+	case ENOENT:
+		code = ERROR_FILE_NOT_FOUND;
+		break;
+	default:
+		code = ERROR_GEN_FAILURE;
+	}
+	SetLastError(code);
+}
+#else
+#include <pthread.h>
+
+static unsigned long threadSelf(void) {
+	return (unsigned long)pthread_self();
+}
+
+/* fail records in errno why the current call failed. */
+static void fail(int err) {
+	errno = err;
+}
+#endif
+
+/*
+ * The allocator. The library allocates only through these wrappers,
+ * which count the allocations live so that mySetAllocator can refuse to
+ * swap the functions out from under them.
+ */
+static myMallocFunc allocMalloc = malloc;
+static myReallocFunc allocRealloc = realloc;
+static myFreeFunc allocFree = free;
+static long long allocLive;
+
+int mySetAllocator(myMallocFunc m, myReallocFunc r, myFreeFunc f) {
+	if ((m == NULL) != (r == NULL) || (m == NULL) != (f == NULL))
+		return MYLIB_EINVAL;
+	if (__atomic_load_n(&allocLive, __ATOMIC_ACQUIRE) != 0)
+		return MYLIB_EINVAL;
+	allocMalloc = m ? m : malloc;
+	allocRealloc = r ? r : realloc;
+	allocFree = f ? f : free;
+	return MYLIB_OK;
+}
+
+long long myAllocations(void) {
+	return __atomic_load_n(&allocLive, __ATOMIC_ACQUIRE);
+}
+
+static void *myMalloc(size_t n) {
+	void *p = allocMalloc(n);
+
+	if (p != NULL)
+		__atomic_fetch_add(&allocLive, 1, __ATOMIC_RELEASE);
+	return p;
+}
+
+static void *myCalloc(size_t count, size_t size) {
+	void *p;
+
+	if (size != 0 && count > (size_t)-1 / size)
+		return NULL;
+	p = myMalloc(count * size);
+	if (p != NULL)
+		memset(p, 0, count * size);
+	return p;
+}
+
+/* Never called with n 0, which some realloc implementations take as free. */
+static void *myRealloc(void *p, size_t n) {
+	void *q = allocRealloc(p, n);
+
+	if (p == NULL && q != NULL)
+		__atomic_fetch_add(&allocLive, 1, __ATOMIC_RELEASE);
+	return q;
+}
+
+void myFree(void *p) {
+	if (p == NULL)
+		return;
+	__atomic_fetch_sub(&allocLive, 1, __ATOMIC_RELEASE);
+	allocFree(p);
+}
+
+static char *myStrdup(const char *s) {
+	size_t n = strlen(s) + 1;
+	char *d = myMalloc(n);
+
+	if (d != NULL)
+		memcpy(d, s, n);
+	return d;
+}
+
+int myVersion(void) {
+	return MYLIB_VERSION;
+}
 
 void myPrintFunction(char *s) {
 	printf("%s\n", s);
+	fflush(stdout);
+}
+
+void myPrintStruct(const struct myStruct *s) {
+	printf("myStruct{a: %d, b: \"%s\"}\n", s->a, s->b ? s->b : "");
+	fflush(stdout);
+}
+
+struct myStruct myMakeStruct(int a, char *b) {
+	struct myStruct s;
+	s.a = a;
+	s.b = b;
+	return s;
+}
+
+struct myStruct myScaleStruct(struct myStruct s, int factor) {
+	s.a *= factor;
+	return s;
+}
+
+void myTranslatePoints(struct myPoint *pts, int n, int dx, int dy) {
+	int i;
+
+	for (i = 0; i < n; i++) {
+		pts[i].x += dx;
+		pts[i].y += dy;
+	}
+}
+
+int myCallN(myCallback cb, void *userdata, int n) {
+	int i, sum = 0;
+
+	for (i = 0; i < n; i++)
+		sum += cb(userdata, i);
+	return sum;
+}
+
+void myEachWord(const char *text, myWordCallback cb, void *userdata) {
+	const char *start;
+
+	while (*text) {
+		while (*text == ' ')
+			text++;
+		start = text;
+		while (*text && *text != ' ')
+			text++;
+		if (text > start)
+			cb(userdata, start, (int)(text - start));
+	}
+}
+
+int mySplitWords(const char *text, struct myWord **out) {
+	struct myWord *head = NULL, **tail = &head, *w;
+	const char *p = text, *start;
+
+	if (text == NULL || out == NULL)
+		return MYLIB_EINVAL;
+	while (*p) {
+		while (*p == ' ')
+			p++;
+		start = p;
+		while (*p && *p != ' ')
+			p++;
+		if (p == start)
+			continue;
+
+		w = myMalloc(sizeof(*w));
+		if (w == NULL || (w->text = myMalloc(p - start + 1)) == NULL) {
+			myFree(w);
+			myWordsFree(head);
+			return MYLIB_ENOMEM;
+		}
+		memcpy(w->text, start, p - start);
+		w->text[p - start] = '\0';
+		w->offset = (int)(start - text);
+		w->next = NULL;
+		*tail = w;
+		tail = &w->next;
+	}
+	*out = head;
+	return MYLIB_OK;
+}
+
+void myWordsFree(struct myWord *list) {
+	struct myWord *next;
+
+	for (; list != NULL; list = next) {
+		next = list->next;
+		myFree(list->text);
+		myFree(list);
+	}
+}
+
+static const struct {
+	const char *key;
+	int value;
+} myTable[] = {
+	{"one", 1},
+	{"two", 2},
+	{"three", 3},
+};
+
+int myLookup(const char *key, int *value) {
+	size_t i;
+
+	if (key == NULL || value == NULL)
+		return MYLIB_EINVAL;
+	for (i = 0; i < sizeof(myTable) / sizeof(myTable[0]); i++) {
+		if (strcmp(myTable[i].key, key) == 0) {
+			*value = myTable[i].value;
+			return MYLIB_OK;
+		}
+	}
+	return MYLIB_ENOTFOUND;
+}
+
+long myFileSize(const char *path) {
+	struct stat st;
+
+	if (stat(path, &st) != 0)
+		return -1;
+	if (!S_ISREG(st.st_mode)) {
+		errno = EINVAL;
+		return -1;
+	}
+	return (long)st.st_size;
+}
+
+static int myCounter;
+
+int myCounterAdd(int delta) {
+	int v;
+
+	/* Deliberately a separate read and write, so concurrent callers lose
+	 * updates unless they are serialized. */
+	v = myCounter;
+	v += delta;
+	myCounter = v;
+	return v;
+}
+
+int myCrunch(long long iterations, const int *cancel, long long *result) {
+	unsigned long long x = 88172645463325252ull;
+	long long i;
+
+	if (iterations < 0 || result == NULL)
+		return MYLIB_EINVAL;
+	for (i = 0; i < iterations; i++) {
+		if ((i & 0xfff) == 0 && cancel != NULL && __atomic_load_n(cancel, __ATOMIC_RELAXED))
+			return MYLIB_ECANCELED;
+		/* xorshift64 */
+		x ^= x << 13;
+		x ^= x >> 7;
+		x ^= x << 17;
+	}
+	*result = (long long)(x >> 1);
+	return MYLIB_OK;
+}
+
+int myCrunchProgress(long long iterations, myProgressCallback cb, void *userdata, long long *result) {
+	unsigned long long x = 88172645463325252ull;
+	long long i;
+
+	if (iterations < 0 || cb == NULL || result == NULL)
+		return MYLIB_EINVAL;
+	for (i = 0; i < iterations; i++) {
+		if ((i & 0x3ff) == 0 && i > 0 && cb(userdata, i, iterations) != 0)
+			return MYLIB_ECANCELED;
+		x ^= x << 13;
+		x ^= x >> 7;
+		x ^= x << 17;
+	}
+	if (cb(userdata, iterations, iterations) != 0)
+		return MYLIB_ECANCELED;
+	*result = (long long)(x >> 1);
+	return MYLIB_OK;
+}
+
+static long long mySum(const int *values, int n) {
+	long long s = 0;
+	int i;
+
+	for (i = 0; i < n; i++)
+		s += values[i];
+	return s;
+}
+
+static long long myMin(const int *values, int n) {
+	long long m = LLONG_MAX;
+	int i;
+
+	for (i = 0; i < n; i++)
+		if (values[i] < m)
+			m = values[i];
+	return m;
+}
+
+static long long myMax(const int *values, int n) {
+	long long m = LLONG_MIN;
+	int i;
+
+	for (i = 0; i < n; i++)
+		if (values[i] > m)
+			m = values[i];
+	return m;
+}
+
+myReducer myGetReducer(const char *name) {
+	static const struct {
+		const char *name;
+		myReducer fn;
+	} reducers[] = {
+		{"sum", mySum},
+		{"min", myMin},
+		{"max", myMax},
+	};
+	size_t i;
+
+	if (name == NULL)
+		return NULL;
+	for (i = 0; i < sizeof(reducers) / sizeof(reducers[0]); i++)
+		if (strcmp(reducers[i].name, name) == 0)
+			return reducers[i].fn;
+	return NULL;
+}
+
+int myBatch(struct myRequest *reqs, int n) {
+	int i, v, failed = 0;
+
+	for (i = 0; i < n; i++) {
+		struct myRequest *r = &reqs[i];
+
+		r->result = 0;
+		switch (r->op) {
+		case MYLIB_OP_COUNTER_ADD:
+			if (r->arg < INT_MIN || r->arg > INT_MAX) {
+				r->status = MYLIB_ERANGE;
+				break;
+			}
+			r->result = myCounterAdd((int)r->arg);
+			r->status = MYLIB_OK;
+			break;
+		case MYLIB_OP_LOOKUP:
+			r->status = myLookup(r->key, &v);
+			if (r->status == MYLIB_OK)
+				r->result = v;
+			break;
+		default:
+			r->status = MYLIB_EINVAL;
+		}
+		if (r->status != MYLIB_OK)
+			failed++;
+	}
+	return failed;
+}
+
+struct myBuffer {
+	char *data;
+	size_t len;
+	size_t cap;
+};
+
+static int myBuffersLive;
+
+static size_t initialBufferSize(void) {
+	size_t n;
+
+	myConfigLock();
+	n = myBufferSize;
+	myConfigUnlock();
+	return n > 0 ? n : 16;
+}
+
+myBuffer *myBufferNew(void) {
+	myBuffer *b;
+
+	b = myCalloc(1, sizeof(*b));
+	if (b == NULL) {
+		fail(ENOMEM);
+		return NULL;
+	}
+	myBuffersLive++;
+	return b;
+}
+
+void myBufferFree(myBuffer *b) {
+	if (b == NULL)
+		return;
+	myFree(b->data);
+	myFree(b);
+	myBuffersLive--;
+}
+
+int myBufferAppend(myBuffer *b, const char *s) {
+	size_t n, cap;
+	char *data;
+
+	if (b == NULL || s == NULL)
+		return MYLIB_EINVAL;
+	n = strlen(s);
+	if (b->len + n + 1 > b->cap) {
+		cap = b->cap ? b->cap : initialBufferSize();
+		while (cap < b->len + n + 1)
+			cap *= 2;
+		data = myRealloc(b->data, cap);
+		if (data == NULL)
+			return MYLIB_ENOMEM;
+		b->data = data;
+		b->cap = cap;
+	}
+	memcpy(b->data + b->len, s, n + 1);
+	b->len += n;
+	return MYLIB_OK;
+}
+
+int myBufferFrom(const char *s, myBuffer **out) {
+	myBuffer *b;
+	int rc;
+
+	if (s == NULL || out == NULL)
+		return MYLIB_EINVAL;
+	if ((b = myBufferNew()) == NULL)
+		return MYLIB_ENOMEM;
+	if ((rc = myBufferAppend(b, s)) != MYLIB_OK) {
+		myBufferFree(b);
+		return rc;
+	}
+	*out = b;
+	return MYLIB_OK;
+}
+
+const char *myBufferData(const myBuffer *b) {
+	return b->data ? b->data : "";
+}
+
+size_t myBufferLen(const myBuffer *b) {
+	return b->len;
+}
+
+int myBufferLive(void) {
+	return myBuffersLive;
+}
+
+struct mySession {
+	char *name;
+	long long limit;
+	long long total;
+	int calls;
+	unsigned long owner;
+};
+
+mySession *mySessionNew(const char *name, long long limit) {
+	mySession *s;
+
+	if (name == NULL || limit < 0) {
+		fail(EINVAL);
+		return NULL;
+	}
+	s = myCalloc(1, sizeof(*s));
+	if (s == NULL) {
+		fail(ENOMEM);
+		return NULL;
+	}
+	s->name = myStrdup(name);
+	if (s->name == NULL) {
+		myFree(s);
+		fail(ENOMEM);
+		return NULL;
+	}
+	s->limit = limit;
+	s->owner = threadSelf();
+	return s;
+}
+
+void mySessionFree(mySession *s) {
+	if (s == NULL)
+		return;
+	myFree(s->name);
+	myFree(s);
+}
+
+int mySessionAdd(mySession *s, long long delta, long long *total) {
+	long long t;
+
+	if (s == NULL)
+		return MYLIB_EINVAL;
+	if (delta > s->limit || delta < -s->limit)
+		return MYLIB_ERANGE;
+	t = s->total + delta;
+	if (t > s->limit || t < -s->limit)
+		return MYLIB_ERANGE;
+	s->total = t;
+	s->calls++;
+	if (total != NULL)
+		*total = t;
+	return MYLIB_OK;
+}
+
+int mySessionReset(mySession *s) {
+	if (s == NULL)
+		return MYLIB_EINVAL;
+	s->total = 0;
+	s->calls = 0;
+	return MYLIB_OK;
+}
+
+int mySessionStats(const mySession *s, long long *total, int *calls) {
+	if (s == NULL)
+		return MYLIB_EINVAL;
+	if (total != NULL)
+		*total = s->total;
+	if (calls != NULL)
+		*calls = s->calls;
+	return MYLIB_OK;
+}
+
+const char *mySessionName(const mySession *s) {
+	return s != NULL ? s->name : NULL;
+}
+
+int mySessionOwner(const mySession *s, int *owner) {
+	if (s == NULL || owner == NULL)
+		return MYLIB_EINVAL;
+	*owner = s->owner == threadSelf();
+	return MYLIB_OK;
+}
+
+int mySessionSync(mySession *s, int millis) {
+	if (s == NULL || millis < 0)
+		return MYLIB_EINVAL;
+#ifdef _WIN32
+	Sleep(millis);
+#else
+	{
+		struct timespec ts = {millis / 1000, (long)(millis % 1000) * 1000000};
+
+		while (nanosleep(&ts, &ts) != 0 && errno == EINTR)
+			;
+	}
+#endif
+	return MYLIB_OK;
+}
+
+/* In UTF-16, a high surrogate followed by a low one is one code point. */
+#if WCHAR_MAX <= 0xffff
+#define isHigh(c) ((c) >= 0xd800 && (c) <= 0xdbff)
+#define isLow(c) ((c) >= 0xdc00 && (c) <= 0xdfff)
+#else
+#define isHigh(c) 0
+#define isLow(c) 0
+#endif
+
+int myWideCount(const wchar_t *s) {
+	int n = 0;
+
+	if (s == NULL) {
+		fail(EINVAL);
+		return -1;
+	}
+	for (; *s; s++, n++) {
+		if (isHigh(s[0]) && isLow(s[1]))
+			s++;
+	}
+	return n;
+}
+
+int myWideReverse(wchar_t *s) {
+	size_t i, j, n;
+	wchar_t c;
+
+	if (s == NULL)
+		return MYLIB_EINVAL;
+	n = wcslen(s);
+	for (i = 0, j = n; i + 1 < j; i++, j--) {
+		c = s[i];
+		s[i] = s[j - 1];
+		s[j - 1] = c;
+	}
+	/* Reversing turned each surrogate pair around; put it back. */
+	for (i = 0; i + 1 < n; i++) {
+		if (isLow(s[i]) && isHigh(s[i + 1])) {
+			c = s[i];
+			s[i] = s[i + 1];
+			s[i + 1] = c;
+			i++;
+		}
+	}
+	return MYLIB_OK;
+}
+
+void myFill(unsigned char *buf, size_t n, unsigned char seed) {
+	size_t i;
+
+	for (i = 0; i < n; i++)
+		buf[i] = (unsigned char)(seed + i);
+}
+
+unsigned int myChecksum(const unsigned char *buf, size_t n) {
+	unsigned int a = 1, b = 0;
+	size_t i;
+
+	/* Adler-32 */
+	for (i = 0; i < n; i++) {
+		a = (a + buf[i]) % 65521;
+		b = (b + a) % 65521;
+	}
+	return (b << 16) | a;
+}
+
+#ifndef _MSC_VER
+double _Complex myComplexMul(double _Complex a, double _Complex b) {
+	return a * b;
+}
+
+void myComplexScale(double _Complex *z, size_t n, double _Complex factor) {
+	size_t i;
+
+	for (i = 0; i < n; i++)
+		z[i] *= factor;
+}
+
+double _Complex myComplexSum(const double _Complex *z, size_t n) {
+	double _Complex sum = 0;
+	size_t i;
+
+	for (i = 0; i < n; i++)
+		sum += z[i];
+	return sum;
+}
+#endif
+
+int myValueDouble(struct myValue *v) {
+	size_t n;
+
+	switch (v->kind) {
+	case MYLIB_VALUE_INT:
+		v->u.i *= 2;
+		return MYLIB_OK;
+	case MYLIB_VALUE_REAL:
+		v->u.d *= 2;
+		return MYLIB_OK;
+	case MYLIB_VALUE_TEXT:
+		n = strnlen(v->u.text, sizeof v->u.text);
+		if (n == sizeof v->u.text)
+			return MYLIB_EINVAL;
+		if (2 * n >= sizeof v->u.text)
+			return MYLIB_ERANGE;
+		memcpy(v->u.text + n, v->u.text, n);
+		v->u.text[2 * n] = '\0';
+		return MYLIB_OK;
+	}
+	return MYLIB_EINVAL;
+}
+
+struct myFlags myFlagsUpgrade(struct myFlags f) {
+	if (f.readable)
+		f.writable = 1;
+	if (f.level < 15)
+		f.level++;
+	return f;
+}
+
+struct myParser {
+	jmp_buf jmp;
+	char error[64];
+};
+
+myParser *myParserNew(void) {
+	return myCalloc(1, sizeof(myParser));
+}
+
+void myParserFree(myParser *p) {
+	myFree(p);
+}
+
+jmp_buf *myParserJmpbuf(myParser *p) {
+	return &p->jmp;
+}
+
+const char *myParserError(const myParser *p) {
+	return p->error;
+}
+
+/* parserFail does not return. */
+static void parserFail(myParser *p, const char *msg) {
+	snprintf(p->error, sizeof p->error, "%s", msg);
+	longjmp(p->jmp, 1);
+}
+
+long long myParseRecord(myParser *p, const char *record) {
+	const char *s;
+	long long v = 0;
+	int digit;
+
+	s = strchr(record, '=');
+	if (s == NULL)
+		parserFail(p, "missing '='");
+	if (s == record)
+		parserFail(p, "empty key");
+	if (*++s == '\0')
+		parserFail(p, "empty value");
+	for (; *s != '\0'; s++) {
+		if (*s < '0' || *s > '9')
+			parserFail(p, "value is not a number");
+		digit = *s - '0';
+		if (v > (LLONG_MAX - digit) / 10)
+			parserFail(p, "value out of range");
+		v = v * 10 + digit;
+	}
+	return v;
+}
+
+#ifndef _WIN32
+#include <pthread.h>
+#include <setjmp.h>
+#include <signal.h>
+
+static struct sigaction myOldInt, myOldSegv;
+static int myHandlersInstalled;
+/* Counted atomically: sig_atomic_t only covers a handler interrupting the
+ * thread that reads the count, and in a Go process the signal may arrive
+ * on any thread. */
+static int mySignals;
+
+static __thread sigjmp_buf myProbeJmp;
+static __thread volatile sig_atomic_t myProbing;
+
+/* forward passes a signal on to the handler that was installed before
+ * ours, the way that handler expects to be called. */
+static void forward(const struct sigaction *old, int sig, siginfo_t *info, void *ctx) {
+	if (old->sa_flags & SA_SIGINFO) {
+		old->sa_sigaction(sig, info, ctx);
+		return;
+	}
+	if (old->sa_handler == SIG_IGN)
+		return;
+	if (old->sa_handler == SIG_DFL) {
+		/* Let the default action happen when the handler returns. */
+		signal(sig, SIG_DFL);
+		raise(sig);
+		return;
+	}
+	old->sa_handler(sig);
+}
+
+static void onInterrupt(int sig, siginfo_t *info, void *ctx) {
+	int err = errno;
+
+	__atomic_fetch_add(&mySignals, 1, __ATOMIC_RELAXED);
+	forward(&myOldInt, sig, info, ctx);
+	errno = err;
+}
+
+static void onFault(int sig, siginfo_t *info, void *ctx) {
+	if (myProbing)
+		siglongjmp(myProbeJmp, 1);
+	/* Not ours: a Go runtime turns faults in Go code into panics. */
+	forward(&myOldSegv, sig, info, ctx);
+}
+
+int myInstallSignalHandlers(void) {
+	struct sigaction sa;
+
+	if (myHandlersInstalled)
+		return MYLIB_OK;
+
+	memset(&sa, 0, sizeof sa);
+	sigemptyset(&sa.sa_mask);
+	/* SA_ONSTACK is required in a Go process: the signal may arrive on a
+	 * goroutine's small stack, and Go aborts if a handler without it
+	 * receives a signal. */
+	sa.sa_flags = SA_SIGINFO | SA_ONSTACK | SA_RESTART;
+
+	sa.sa_sigaction = onInterrupt;
+	if (sigaction(SIGINT, &sa, &myOldInt) != 0)
+		return MYLIB_EINVAL;
+	sa.sa_sigaction = onFault;
+	if (sigaction(SIGSEGV, &sa, &myOldSegv) != 0) {
+		sigaction(SIGINT, &myOldInt, NULL);
+		return MYLIB_EINVAL;
+	}
+	myHandlersInstalled = 1;
+	return MYLIB_OK;
+}
+
+int myRestoreSignalHandlers(void) {
+	if (!myHandlersInstalled)
+		return MYLIB_OK;
+	sigaction(SIGINT, &myOldInt, NULL);
+	sigaction(SIGSEGV, &myOldSegv, NULL);
+	myHandlersInstalled = 0;
+	return MYLIB_OK;
+}
+
+int mySignalCount(void) {
+	return __atomic_load_n(&mySignals, __ATOMIC_RELAXED);
+}
+
+int myProbeRead(uintptr_t addr, unsigned char *out) {
+	if (!myHandlersInstalled || out == NULL)
+		return MYLIB_EINVAL;
+	if (sigsetjmp(myProbeJmp, 1) != 0) {
+		myProbing = 0;
+		return MYLIB_EINVAL;
+	}
+	myProbing = 1;
+	*out = *(volatile unsigned char *)addr;
+	myProbing = 0;
+	return MYLIB_OK;
+}
+
+struct myThread {
+	pthread_t tid;
+	struct myThreads *group;
+	int index;
+};
+
+struct myThreads {
+	int n, count;
+	myThreadCallback cb;
+	void *userdata;
+
+	/* Threads wait for started before making callbacks, so that none
+	 * are made if starting a later thread fails. */
+	pthread_mutex_t mu;
+	pthread_cond_t cond;
+	int started, aborted;
+
+	struct myThread threads[];
+};
+
+static void *threadMain(void *arg) {
+	struct myThread *t = arg;
+	myThreads *g = t->group;
+	int i, aborted;
+	char index[16];
+	const char *attrs[] = {"thread", index};
+
+	pthread_mutex_lock(&g->mu);
+	while (!g->started)
+		pthread_cond_wait(&g->cond, &g->mu);
+	aborted = g->aborted;
+	pthread_mutex_unlock(&g->mu);
+	if (aborted)
+		return NULL;
+
+	snprintf(index, sizeof index, "%d", t->index);
+	MYLIB_LOG(MYLIB_LOG_DEBUG, attrs, 1, "thread started");
+	for (i = 0; i < g->count; i++)
+		g->cb(g->userdata, t->index, i);
+	MYLIB_LOG(MYLIB_LOG_DEBUG, attrs, 1, "thread done");
+	return NULL;
+}
+
+static void release(myThreads *g, int aborted) {
+	pthread_mutex_lock(&g->mu);
+	g->started = 1;
+	g->aborted = aborted;
+	pthread_cond_broadcast(&g->cond);
+	pthread_mutex_unlock(&g->mu);
+}
+
+myThreads *myThreadsStart(int nthreads, int count, myThreadCallback cb, void *userdata) {
+	myThreads *g;
+	int i, rc = 0;
+
+	if (nthreads < 0 || count < 0 || cb == NULL) {
+		fail(EINVAL);
+		return NULL;
+	}
+	g = myCalloc(1, sizeof(*g) + nthreads * sizeof(g->threads[0]));
+	if (g == NULL) {
+		fail(ENOMEM);
+		return NULL;
+	}
+	g->count = count;
+	g->cb = cb;
+	g->userdata = userdata;
+	pthread_mutex_init(&g->mu, NULL);
+	pthread_cond_init(&g->cond, NULL);
+	for (i = 0; i < nthreads; i++) {
+		g->threads[i].group = g;
+		g->threads[i].index = i;
+		rc = pthread_create(&g->threads[i].tid, NULL, threadMain, &g->threads[i]);
+		if (rc != 0)
+			break;
+		g->n++;
+	}
+	if (g->n < nthreads) {
+		MYLIB_LOG(MYLIB_LOG_ERROR, NULL, 0, "myThreadsStart: thread %d of %d: %s", g->n, nthreads,
+			  strerror(rc));
+		release(g, 1);
+		myThreadsJoin(g);
+		fail(rc);
+		return NULL;
+	}
+	release(g, 0);
+	return g;
 }
 
+int myThreadsJoin(myThreads *g) {
+	int i;
+
+	if (g == NULL)
+		return MYLIB_EINVAL;
+	for (i = 0; i < g->n; i++)
+		pthread_join(g->threads[i].tid, NULL);
+	pthread_cond_destroy(&g->cond);
+	pthread_mutex_destroy(&g->mu);
+	myFree(g);
+	return MYLIB_OK;
+}
+
+struct myEmitter {
+	long long count;
+	int interval;
+	myEventCallback cb;
+	void *userdata;
+	pthread_t tid;
+
+	/* The thread waits on cond between events, so that stopping does
+	 * not have to wait out the interval. */
+	pthread_mutex_t mu;
+	pthread_cond_t cond;
+	int stopped;
+};
+
+/* emitterWait waits interval microseconds or until the emitter is
+ * stopped, and reports whether it was. */
+static int emitterWait(myEmitter *e) {
+	struct timespec deadline;
+	int stopped;
+
+	clock_gettime(CLOCK_REALTIME, &deadline);
+	deadline.tv_sec += e->interval / 1000000;
+	deadline.tv_nsec += (long)(e->interval % 1000000) * 1000;
+	if (deadline.tv_nsec >= 1000000000) {
+		deadline.tv_sec++;
+		deadline.tv_nsec -= 1000000000;
+	}
+	pthread_mutex_lock(&e->mu);
+	while (!e->stopped && e->interval > 0)
+		if (pthread_cond_timedwait(&e->cond, &e->mu, &deadline) == ETIMEDOUT)
+			break;
+	stopped = e->stopped;
+	pthread_mutex_unlock(&e->mu);
+	return stopped;
+}
+
+static void *emitterMain(void *arg) {
+	myEmitter *e = arg;
+	struct myEvent ev;
+
+	for (ev.seq = 0; e->count == 0 || ev.seq < e->count; ev.seq++) {
+		if (emitterWait(e))
+			break;
+		ev.value = (int)(ev.seq % 100);
+		e->cb(e->userdata, &ev);
+	}
+	e->cb(e->userdata, NULL);
+	return NULL;
+}
+
+myEmitter *myEmitterStart(long long count, int interval, myEventCallback cb, void *userdata) {
+	myEmitter *e;
+	int rc;
+
+	if (count < 0 || interval < 0 || cb == NULL) {
+		fail(EINVAL);
+		return NULL;
+	}
+	e = myCalloc(1, sizeof(*e));
+	if (e == NULL) {
+		fail(ENOMEM);
+		return NULL;
+	}
+	e->count = count;
+	e->interval = interval;
+	e->cb = cb;
+	e->userdata = userdata;
+	pthread_mutex_init(&e->mu, NULL);
+	pthread_cond_init(&e->cond, NULL);
+	rc = pthread_create(&e->tid, NULL, emitterMain, e);
+	if (rc != 0) {
+		pthread_cond_destroy(&e->cond);
+		pthread_mutex_destroy(&e->mu);
+		myFree(e);
+		fail(rc);
+		return NULL;
+	}
+	return e;
+}
+
+int myEmitterStop(myEmitter *e) {
+	if (e == NULL)
+		return MYLIB_EINVAL;
+	pthread_mutex_lock(&e->mu);
+	e->stopped = 1;
+	pthread_cond_signal(&e->cond);
+	pthread_mutex_unlock(&e->mu);
+	pthread_join(e->tid, NULL);
+	pthread_cond_destroy(&e->cond);
+	pthread_mutex_destroy(&e->mu);
+	myFree(e);
+	return MYLIB_OK;
+}
+
+#include <fcntl.h>
+#include <stdatomic.h>
+#include <stddef.h>
+#include <stdint.h>
+#include <sys/mman.h>
+#include <sys/socket.h>
+#include <unistd.h>
+
+struct ringHeader {
+	uint32_t magic;
+	uint32_t capacity;
+	_Atomic uint64_t head;
+	_Atomic uint64_t tail;
+};
+
+_Static_assert(offsetof(struct ringHeader, head) == 8 && offsetof(struct ringHeader, tail) == 16,
+	       "ring header layout differs from mylib.h");
+
+#ifdef __ANDROID__
+/* Bionic has no POSIX shared memory: Android processes share memory
+ * through ASharedMemory descriptors passed over binder, not by name. The
+ * rest of the library builds with the NDK; rings fail with ENOSYS. */
+static int shmOpen(const char *name, int flags, mode_t mode) {
+	(void)name;
+	(void)flags;
+	(void)mode;
+	errno = ENOSYS;
+	return -1;
+}
+
+static int shmUnlink(const char *name) {
+	(void)name;
+	errno = ENOSYS;
+	return -1;
+}
+#else
+#define shmOpen shm_open
+#define shmUnlink shm_unlink
+#endif
+
+struct myRing {
+	struct ringHeader *h;
+	unsigned char *data;
+	size_t size;
+};
+
+myRing *myRingOpen(const char *name, unsigned int capacity) {
+	struct stat st;
+	struct ringHeader *h;
+	myRing *r;
+	size_t size = MYLIB_RING_SIZE(capacity);
+	int fd, err;
+
+	if (name == NULL || (capacity & (capacity - 1)) != 0) {
+		fail(EINVAL);
+		return NULL;
+	}
+	fd = shmOpen(name, capacity ? O_RDWR | O_CREAT | O_EXCL : O_RDWR, 0600);
+	if (fd < 0)
+		return NULL;
+	if (capacity != 0) {
+		if (ftruncate(fd, (off_t)size) != 0)
+			goto bad;
+	} else {
+		if (fstat(fd, &st) != 0)
+			goto bad;
+		size = (size_t)st.st_size;
+		if (size < MYLIB_RING_HEADER) {
+			errno = EINVAL;
+			goto bad;
+		}
+	}
+	h = mmap(NULL, size, PROT_READ | PROT_WRITE, MAP_SHARED, fd, 0);
+	if (h == MAP_FAILED)
+		goto bad;
+	close(fd);
+
+	if (capacity != 0) {
+		h->capacity = capacity;
+		atomic_store(&h->head, 0);
+		atomic_store(&h->tail, 0);
+		h->magic = MYLIB_RING_MAGIC;
+	} else if (h->magic != MYLIB_RING_MAGIC || h->capacity == 0 ||
+		   (h->capacity & (h->capacity - 1)) != 0 ||
+		   MYLIB_RING_SIZE(h->capacity) > size) {
+		munmap(h, size);
+		fail(EINVAL);
+		return NULL;
+	}
+
+	r = myMalloc(sizeof(*r));
+	if (r == NULL) {
+		munmap(h, size);
+		fail(ENOMEM);
+		return NULL;
+	}
+	r->h = h;
+	r->data = (unsigned char *)h + MYLIB_RING_HEADER;
+	r->size = size;
+	return r;
+
+bad:
+	err = errno;
+	close(fd);
+	if (capacity != 0)
+		shmUnlink(name);
+	fail(err);
+	return NULL;
+}
+
+void myRingClose(myRing *r) {
+	if (r == NULL)
+		return;
+	munmap(r->h, r->size);
+	myFree(r);
+}
+
+int myRingUnlink(const char *name) {
+	if (name == NULL || shmUnlink(name) != 0)
+		return MYLIB_EINVAL;
+	return MYLIB_OK;
+}
+
+/* ringCopy copies n bytes between the data area at offset off, wrapping
+ * around its end, and buf. */
+static void ringCopy(myRing *r, uint64_t off, unsigned char *buf, size_t n, int toRing) {
+	uint32_t mask = r->h->capacity - 1;
+	size_t i;
+
+	for (i = 0; i < n; i++) {
+		if (toRing)
+			r->data[(off + i) & mask] = buf[i];
+		else
+			buf[i] = r->data[(off + i) & mask];
+	}
+}
+
+int myRingPush(myRing *r, const unsigned char *msg, unsigned int n) {
+	unsigned char len[4];
+	uint64_t head, tail;
+
+	if (r == NULL || (msg == NULL && n > 0) || MYLIB_RING_MESSAGE_SIZE(n) > r->h->capacity)
+		return MYLIB_EINVAL;
+	head = atomic_load_explicit(&r->h->head, memory_order_relaxed);
+	tail = atomic_load_explicit(&r->h->tail, memory_order_acquire);
+	if (r->h->capacity - (head - tail) < MYLIB_RING_MESSAGE_SIZE(n))
+		return MYLIB_ERANGE;
+
+	len[0] = n;
+	len[1] = n >> 8;
+	len[2] = n >> 16;
+	len[3] = n >> 24;
+	ringCopy(r, head, len, 4, 1);
+	ringCopy(r, head + 4, (unsigned char *)msg, n, 1);
+	atomic_store_explicit(&r->h->head, head + MYLIB_RING_MESSAGE_SIZE(n), memory_order_release);
+	return MYLIB_OK;
+}
+
+int myRingPop(myRing *r, unsigned char *buf, unsigned int cap, unsigned int *n) {
+	unsigned char len[4];
+	uint64_t head, tail;
+	uint32_t size;
+
+	if (r == NULL || n == NULL || (buf == NULL && cap > 0))
+		return MYLIB_EINVAL;
+	tail = atomic_load_explicit(&r->h->tail, memory_order_relaxed);
+	head = atomic_load_explicit(&r->h->head, memory_order_acquire);
+	if (head == tail)
+		return MYLIB_ENOTFOUND;
+
+	ringCopy(r, tail, len, 4, 0);
+	size = len[0] | len[1] << 8 | len[2] << 16 | (uint32_t)len[3] << 24;
+	*n = size;
+	if (size > cap)
+		return MYLIB_ERANGE;
+	ringCopy(r, tail + 4, buf, size, 0);
+	atomic_store_explicit(&r->h->tail, tail + MYLIB_RING_MESSAGE_SIZE(size), memory_order_release);
+	return MYLIB_OK;
+}
+
+static void *echoMain(void *arg) {
+	int fd = (int)(intptr_t)arg;
+	char buf[4096];
+	ssize_t n, off, w;
+
+	while ((n = read(fd, buf, sizeof(buf))) != 0) {
+		if (n < 0) {
+			if (errno == EINTR)
+				continue;
+			break;
+		}
+		for (off = 0; off < n; off += w) {
+			w = write(fd, buf + off, n - off);
+			if (w < 0 && errno == EINTR)
+				w = 0;
+			else if (w < 0)
+				goto out;
+		}
+	}
+out:
+	close(fd);
+	return NULL;
+}
+
+int myEchoOpen(void) {
+	pthread_t tid;
+	int sv[2], err;
+
+	if (socketpair(AF_UNIX, SOCK_STREAM | SOCK_CLOEXEC, 0, sv) != 0)
+		return -1;
+	err = pthread_create(&tid, NULL, echoMain, (void *)(intptr_t)sv[1]);
+	if (err != 0) {
+		close(sv[0]);
+		close(sv[1]);
+		fail(err);
+		return -1;
+	}
+	pthread_detach(tid);
+	return sv[0];
+}
+
+static int notifyPipe[2] = {-1, -1};
+
+int myNotifyFd(void) {
+	if (notifyPipe[0] < 0) {
+		if (pipe(notifyPipe) != 0)
+			return -1;
+		fcntl(notifyPipe[0], F_SETFD, FD_CLOEXEC);
+		fcntl(notifyPipe[1], F_SETFD, FD_CLOEXEC);
+		fcntl(notifyPipe[1], F_SETFL, fcntl(notifyPipe[1], F_GETFL) | O_NONBLOCK);
+	}
+	return notifyPipe[0];
+}
+
+int myNotify(const char *msg) {
+	size_t n;
+	ssize_t w;
+	char *line;
+
+	if (msg == NULL || myNotifyFd() < 0)
+		return MYLIB_EINVAL;
+	/* One write, so that a line is never split between readers' reads
+	 * or half written when the pipe fills. */
+	n = strlen(msg);
+	if (n + 1 > PIPE_BUF)
+		return MYLIB_EINVAL;
+	line = myMalloc(n + 1);
+	if (line == NULL)
+		return MYLIB_ENOMEM;
+	memcpy(line, msg, n);
+	line[n] = '\n';
+	w = write(notifyPipe[1], line, n + 1);
+	myFree(line);
+	if (w < 0)
+		return errno == EAGAIN ? MYLIB_ERANGE : MYLIB_EINVAL;
+	return MYLIB_OK;
+}
+
+void myNotifyClose(void) {
+	if (notifyPipe[0] < 0)
+		return;
+	close(notifyPipe[0]);
+	close(notifyPipe[1]);
+	notifyPipe[0] = notifyPipe[1] = -1;
+}
+
+#define REACTOR_BUF 4096
+
+/* A peer that has closed its end must fail the write with EPIPE rather
+ * than raise SIGPIPE in a host that has not ignored it. */
+#ifndef MSG_NOSIGNAL
+#define MSG_NOSIGNAL 0
+#endif
+
+struct reactorConn {
+	int fd; /* -1 for a free slot */
+	int events; /* as last passed to watch */
+	size_t len;
+	unsigned char buf[REACTOR_BUF];
+};
+
+struct myReactor {
+	myWatchFunc watch;
+	void *userdata;
+	long long bytes;
+	struct reactorConn conns[MYLIB_REACTOR_MAX];
+};
+
+myReactor *myReactorNew(myWatchFunc watch, void *userdata) {
+	myReactor *r;
+	int i;
+
+	if (watch == NULL) {
+		fail(EINVAL);
+		return NULL;
+	}
+	if ((r = myMalloc(sizeof(*r))) == NULL) {
+		fail(ENOMEM);
+		return NULL;
+	}
+	r->watch = watch;
+	r->userdata = userdata;
+	r->bytes = 0;
+	for (i = 0; i < MYLIB_REACTOR_MAX; i++)
+		r->conns[i].fd = -1;
+	return r;
+}
+
+void myReactorFree(myReactor *r) {
+	int i;
+
+	if (r == NULL)
+		return;
+	for (i = 0; i < MYLIB_REACTOR_MAX; i++)
+		if (r->conns[i].fd >= 0)
+			close(r->conns[i].fd);
+	myFree(r);
+}
+
+/* rewatch tells the caller what c now waits for: room to flush its
+ * buffer if it holds anything, and more to read unless it is full. */
+static void rewatch(myReactor *r, struct reactorConn *c) {
+	int events = 0;
+
+	if (c->len < REACTOR_BUF)
+		events |= MYLIB_POLL_IN;
+	if (c->len > 0)
+		events |= MYLIB_POLL_OUT;
+	if (events != c->events) {
+		c->events = events;
+		r->watch(r->userdata, c->fd, events);
+	}
+}
+
+static void reactorClose(myReactor *r, struct reactorConn *c) {
+	r->watch(r->userdata, c->fd, 0);
+	close(c->fd);
+	c->fd = -1;
+}
+
+int myReactorConnect(myReactor *r) {
+	struct reactorConn *c = NULL;
+	int sv[2], i;
+
+	if (r == NULL) {
+		fail(EINVAL);
+		return -1;
+	}
+	for (i = 0; i < MYLIB_REACTOR_MAX && c == NULL; i++)
+		if (r->conns[i].fd < 0)
+			c = &r->conns[i];
+	if (c == NULL) {
+		fail(EMFILE);
+		return -1;
+	}
+	if (socketpair(AF_UNIX, SOCK_STREAM | SOCK_CLOEXEC, 0, sv) != 0)
+		return -1;
+	fcntl(sv[1], F_SETFL, fcntl(sv[1], F_GETFL) | O_NONBLOCK);
+#ifdef SO_NOSIGPIPE
+	setsockopt(sv[1], SOL_SOCKET, SO_NOSIGPIPE, &(int){1}, sizeof(int));
+#endif
+	c->fd = sv[1];
+	c->events = 0;
+	c->len = 0;
+	rewatch(r, c);
+	return sv[0];
+}
+
+int myReactorReady(myReactor *r, int fd, int events) {
+	struct reactorConn *c = NULL;
+	ssize_t n;
+	int i;
+
+	if (r == NULL || fd < 0)
+		return MYLIB_EINVAL;
+	for (i = 0; i < MYLIB_REACTOR_MAX && c == NULL; i++)
+		if (r->conns[i].fd == fd)
+			c = &r->conns[i];
+	if (c == NULL)
+		return MYLIB_ENOTFOUND;
+	/* Readiness is a hint: a descriptor reported ready may have nothing
+	 * for us by now, which EAGAIN says. */
+	if ((events & MYLIB_POLL_IN) && c->len < REACTOR_BUF) {
+		n = read(fd, c->buf + c->len, REACTOR_BUF - c->len);
+		if (n == 0 || (n < 0 && errno != EAGAIN && errno != EINTR)) {
+			reactorClose(r, c);
+			return MYLIB_OK;
+		}
+		if (n > 0)
+			c->len += (size_t)n;
+	}
+	if (c->len > 0) {
+		n = send(fd, c->buf, c->len, MSG_NOSIGNAL);
+		if (n < 0 && errno != EAGAIN && errno != EINTR) {
+			reactorClose(r, c);
+			return MYLIB_OK;
+		}
+		if (n > 0) {
+			memmove(c->buf, c->buf + n, c->len - (size_t)n);
+			c->len -= (size_t)n;
+			r->bytes += n;
+		}
+	}
+	rewatch(r, c);
+	return MYLIB_OK;
+}
+
+long long myReactorBytes(myReactor *r) {
+	return r == NULL ? 0 : r->bytes;
+}
+
+struct fillJob {
+	unsigned char *buf;
+	size_t n;
+	unsigned char seed;
+	int delay;
+	myDoneFunc done;
+	void *userdata;
+};
+
+static void *fillMain(void *arg) {
+	struct fillJob job = *(struct fillJob *)arg;
+	struct timespec ts;
+
+	myFree(arg);
+	ts.tv_sec = job.delay / 1000000;
+	ts.tv_nsec = (long)(job.delay % 1000000) * 1000;
+	while (nanosleep(&ts, &ts) != 0 && errno == EINTR)
+		;
+	myFill(job.buf, job.n, job.seed);
+	job.done(job.userdata, MYLIB_OK, myChecksum(job.buf, job.n));
+	return NULL;
+}
+
+int myFillAsync(unsigned char *buf, size_t n, unsigned char seed, int delay, myDoneFunc done, void *userdata) {
+	struct fillJob *job;
+	pthread_attr_t attr;
+	pthread_t tid;
+	int rc;
+
+	if ((buf == NULL && n > 0) || delay < 0 || done == NULL)
+		return MYLIB_EINVAL;
+	if ((job = myMalloc(sizeof(*job))) == NULL)
+		return MYLIB_ENOMEM;
+	job->buf = buf;
+	job->n = n;
+	job->seed = seed;
+	job->delay = delay;
+	job->done = done;
+	job->userdata = userdata;
+	pthread_attr_init(&attr);
+	pthread_attr_setdetachstate(&attr, PTHREAD_CREATE_DETACHED);
+	rc = pthread_create(&tid, &attr, fillMain, job);
+	pthread_attr_destroy(&attr);
+	if (rc != 0) {
+		myFree(job);
+		return MYLIB_ENOMEM;
+	}
+	return MYLIB_OK;
+}
+#endif
+
+static int initialized;
+
+int myInit(void) {
+	if (initialized)
+		return MYLIB_EINVAL;
+	initialized = 1;
+	return MYLIB_OK;
+}
+
+void myShutdown(void) {
+	if (!initialized)
+		return;
+#ifndef _WIN32
+	myNotifyClose();
+#endif
+	initialized = 0;
+}
+
+int myInitialized(void) {
+	return initialized;
+}
+
+int myTimeFormat(time_t t, char *buf, size_t n) {
+	struct tm tm;
+	int len;
+
+	if (buf == NULL)
+		return MYLIB_EINVAL;
+#ifdef _WIN32
+	if (gmtime_s(&tm, &t) != 0)
+		return MYLIB_ERANGE;
+#else
+	if (gmtime_r(&t, &tm) == NULL)
+		return MYLIB_ERANGE;
+#endif
+	if (tm.tm_year < -1900 || tm.tm_year > 9999 - 1900)
+		return MYLIB_ERANGE;
+	len = snprintf(buf, n, "%04d-%02d-%02dT%02d:%02d:%02dZ", tm.tm_year + 1900, tm.tm_mon + 1,
+		       tm.tm_mday, tm.tm_hour, tm.tm_min, tm.tm_sec);
+	if (len < 0 || (size_t)len >= n)
+		return MYLIB_ERANGE;
+	return MYLIB_OK;
+}
+
+int myTimespecAdd(struct timespec *ts, long long nanos) {
+	long long sec, nsec, cur;
+	time_t t;
+
+	if (ts == NULL || ts->tv_nsec < 0 || ts->tv_nsec >= 1000000000)
+		return MYLIB_EINVAL;
+	sec = nanos / 1000000000;
+	nsec = ts->tv_nsec + nanos % 1000000000;
+	if (nsec < 0) {
+		nsec += 1000000000;
+		sec--;
+	} else if (nsec >= 1000000000) {
+		nsec -= 1000000000;
+		sec++;
+	}
+
+	/* time_t may be 32 or 64 bits wide; do the sum in long long and
+	 * check that it survives the trip back. */
+	cur = (long long)ts->tv_sec;
+	if ((sec > 0 && cur > LLONG_MAX - sec) || (sec < 0 && cur < LLONG_MIN - sec))
+		return MYLIB_ERANGE;
+	t = (time_t)(cur + sec);
+	if ((long long)t != cur + sec)
+		return MYLIB_ERANGE;
+	ts->tv_sec = t;
+	ts->tv_nsec = nsec;
+	return MYLIB_OK;
+}
+
+#ifndef _WIN32
+int myTimevalSub(const struct timeval *end, const struct timeval *start, long long *micros) {
+	long long a, b, sec;
+
+	if (end == NULL || start == NULL || micros == NULL)
+		return MYLIB_EINVAL;
+	a = (long long)end->tv_sec;
+	b = (long long)start->tv_sec;
+	if ((b < 0 && a > LLONG_MAX + b) || (b > 0 && a < LLONG_MIN + b))
+		return MYLIB_ERANGE;
+	sec = a - b;
+	if (sec > LLONG_MAX / 1000000 - 1 || sec < LLONG_MIN / 1000000 + 1)
+		return MYLIB_ERANGE;
+	*micros = sec * 1000000 + ((long long)end->tv_usec - (long long)start->tv_usec);
+	return MYLIB_OK;
+}
+#endif
+
+long myWriteReport(FILE *f, const char *title, const long long *values, size_t n) {
+	long long total = 0;
+	long written;
+	size_t i;
+	int len;
+
+	if (f == NULL || title == NULL || (values == NULL && n > 0)) {
+		errno = EINVAL;
+		return -1;
+	}
+	if ((len = fprintf(f, "%s\n", title)) < 0)
+		return -1;
+	written = len;
+	for (i = 0; i < n; i++) {
+		if ((len = fprintf(f, "%lu: %lld\n", (unsigned long)i, values[i])) < 0)
+			return -1;
+		written += len;
+		total += values[i];
+	}
+	if ((len = fprintf(f, "total: %lld\n", total)) < 0)
+		return -1;
+	return written + len;
+}
+
+char *myJoin(const char *const *parts, size_t n, const char *sep) {
+	size_t i, len, seplen, total = 1;
+	char *s, *d;
+
+	if ((parts == NULL && n > 0) || sep == NULL) {
+		errno = EINVAL;
+		return NULL;
+	}
+	seplen = strlen(sep);
+	for (i = 0; i < n; i++) {
+		if (parts[i] == NULL) {
+			errno = EINVAL;
+			return NULL;
+		}
+		len = strlen(parts[i]) + (i > 0 ? seplen : 0);
+		if (total > (size_t)-1 - len) {
+			errno = ERANGE;
+			return NULL;
+		}
+		total += len;
+	}
+	if ((s = myMalloc(total)) == NULL) {
+		errno = ENOMEM;
+		return NULL;
+	}
+	d = s;
+	for (i = 0; i < n; i++) {
+		if (i > 0) {
+			memcpy(d, sep, seplen);
+			d += seplen;
+		}
+		len = strlen(parts[i]);
+		memcpy(d, parts[i], len);
+		d += len;
+	}
+	*d = '\0';
+	return s;
+}
+
+void *myRepeat(const void *buf, size_t n, size_t times, size_t *len) {
+	unsigned char *p;
+	size_t i;
+
+	if ((buf == NULL && n > 0) || len == NULL) {
+		errno = EINVAL;
+		return NULL;
+	}
+	if (n != 0 && times > (size_t)-1 / n) {
+		errno = ERANGE;
+		return NULL;
+	}
+	/* A zero-length result still gets its own allocation, so that NULL
+	 * only ever means failure. */
+	if ((p = myMalloc(n * times > 0 ? n * times : 1)) == NULL) {
+		errno = ENOMEM;
+		return NULL;
+	}
+	for (i = 0; i < times; i++)
+		memcpy(p + i * n, buf, n);
+	*len = n * times;
+	return p;
+}
+
+/* Returns a new copy of the n bytes at s without the blanks at either end. */
+static char *dupTrimmed(const char *s, size_t n) {
+	char *d;
+
+	while (n > 0 && (*s == ' ' || *s == '\t')) {
+		s++;
+		n--;
+	}
+	while (n > 0 && (s[n - 1] == ' ' || s[n - 1] == '\t'))
+		n--;
+	if ((d = myMalloc(n + 1)) != NULL) {
+		memcpy(d, s, n);
+		d[n] = '\0';
+	}
+	return d;
+}
+
+int myParseKeyValue(const char *line, char **key, char **value) {
+	const char *eq;
+	char *k, *v;
+
+	if (line == NULL || key == NULL || value == NULL || (eq = strchr(line, '=')) == NULL)
+		return MYLIB_EINVAL;
+	if ((k = dupTrimmed(line, (size_t)(eq - line))) == NULL)
+		return MYLIB_ENOMEM;
+	if (*k == '\0') {
+		myFree(k);
+		return MYLIB_EINVAL;
+	}
+	if ((v = dupTrimmed(eq + 1, strlen(eq + 1))) == NULL) {
+		myFree(k);
+		return MYLIB_ENOMEM;
+	}
+	*key = k;
+	*value = v;
+	return MYLIB_OK;
+}
+
+int myParseArgs(int argc, char *const *argv, struct myArgs *out) {
+	struct myArgs a = {0, 0, NULL, 1};
+	char *end;
+	int i;
+
+	if (argc < 1 || argv == NULL || out == NULL || argv[argc] != NULL)
+		return MYLIB_EINVAL;
+	for (i = 1; i < argc; i++) {
+		const char *arg = argv[i];
+
+		if (strcmp(arg, "--") == 0) {
+			i++;
+			break;
+		}
+		if (arg[0] != '-' || arg[1] == '\0')
+			break;
+		if (strcmp(arg, "-v") == 0) {
+			a.verbose = 1;
+		} else if (strcmp(arg, "-n") == 0) {
+			if (++i == argc)
+				return MYLIB_EINVAL;
+			errno = 0;
+			a.count = strtoll(argv[i], &end, 10);
+			if (errno != 0 || end == argv[i] || *end != '\0')
+				return MYLIB_EINVAL;
+		} else if (strncmp(arg, "--name=", 7) == 0) {
+			a.name = arg + 7;
+		} else {
+			return MYLIB_EINVAL;
+		}
+	}
+	a.rest = i;
+	*out = a;
+	return MYLIB_OK;
+}
+
+/* Merge sorts the n elements at a, using tmp, of the same size, for the merge. */
+static void mergeSort(unsigned char *a, unsigned char *tmp, size_t n, size_t size, myCompareFunc cmp,
+		      void *userdata) {
+	size_t half = n / 2, i = 0, j = half, k = 0;
+
+	if (n < 2)
+		return;
+	mergeSort(a, tmp, half, size, cmp, userdata);
+	mergeSort(a + half * size, tmp, n - half, size, cmp, userdata);
+	/* Already in order: the common case for nearly sorted input. */
+	if (cmp(a + (half - 1) * size, a + half * size, userdata) <= 0)
+		return;
+	while (i < half && j < n) {
+		if (cmp(a + j * size, a + i * size, userdata) < 0)
+			memcpy(tmp + k++ * size, a + j++ * size, size);
+		else
+			memcpy(tmp + k++ * size, a + i++ * size, size);
+	}
+	memcpy(tmp + k * size, a + i * size, (half - i) * size);
+	k += half - i;
+	memcpy(a, tmp, k * size);
+}
+
+int mySort(void *base, size_t n, size_t size, myCompareFunc cmp, void *userdata) {
+	unsigned char *tmp;
+
+	if ((base == NULL && n > 0) || size == 0 || cmp == NULL)
+		return MYLIB_EINVAL;
+	if (n < 2)
+		return MYLIB_OK;
+	if (n > (size_t)-1 / size)
+		return MYLIB_ERANGE;
+	if ((tmp = myMalloc(n * size)) == NULL)
+		return MYLIB_ENOMEM;
+	mergeSort(base, tmp, n, size, cmp, userdata);
+	myFree(tmp);
+	return MYLIB_OK;
+}
+
+unsigned int myHash(const char *s, size_t n) {
+	unsigned int h = 2166136261u;
+	size_t i;
+
+	for (i = 0; i < n; i++) {
+		h ^= (unsigned char)s[i];
+		h *= 16777619u;
+	}
+	return h;
+}
+
+unsigned int myHashString(const char *s) {
+	return myHash(s, strlen(s));
+}
+
+double myMean(const int *v, size_t n) {
+	double sum = 0;
+	size_t i;
+
+	assert(v != NULL && n > 0);
+	for (i = 0; i < n; i++)
+		sum += v[i];
+	return sum / n;
+}
+
+void myGetABI(struct myABI *abi) {
+	abi->structSize = sizeof(struct myStruct);
+	abi->structB = offsetof(struct myStruct, b);
+	abi->pointSize = sizeof(struct myPoint);
+	abi->pointWeight = offsetof(struct myPoint, weight);
+	abi->wcharSize = sizeof(wchar_t);
+	abi->longSize = sizeof(long);
+	abi->pointerSize = sizeof(void *);
+}
+
+#define MY_STR1(x) #x
+#define MY_STR(x) MY_STR1(x)
+
+const char *myCompiler(void) {
+#if defined(__clang__) && defined(_MSC_VER)
+	return "clang-cl " MY_STR(__clang_major__) "." MY_STR(__clang_minor__);
+#elif defined(_MSC_VER)
+	return "MSVC " MY_STR(_MSC_VER);
+#elif defined(__clang__) && defined(__MINGW32__)
+	return "MinGW-w64 clang " MY_STR(__clang_major__) "." MY_STR(__clang_minor__);
+#elif defined(__MINGW32__)
+	return "MinGW-w64 GCC " __VERSION__;
+#elif defined(__clang__)
+	return "clang " MY_STR(__clang_major__) "." MY_STR(__clang_minor__);
+#elif defined(__GNUC__)
+	return "GCC " __VERSION__;
+#else
+	return "unknown";
+#endif
+}
+
+long long MYLIB_STDCALL myMulAdd(int a, int b, long long c) {
+	return (long long)a * b + c;
+}
+
+int myLogLevel = MYLIB_LOG_INFO;
+size_t myBufferSize = 16;
+
+#ifdef _WIN32
+static SRWLOCK myConfigMu = SRWLOCK_INIT;
+
+void myConfigLock(void) {
+	AcquireSRWLockExclusive(&myConfigMu);
+}
+
+void myConfigUnlock(void) {
+	ReleaseSRWLockExclusive(&myConfigMu);
+}
+#else
+static pthread_mutex_t myConfigMu = PTHREAD_MUTEX_INITIALIZER;
+
+void myConfigLock(void) {
+	pthread_mutex_lock(&myConfigMu);
+}
+
+void myConfigUnlock(void) {
+	pthread_mutex_unlock(&myConfigMu);
+}
+#endif
+
+/*
+ * The wire format is written and read a byte at a time with shifts, which
+ * mean the same on every host, rather than by converting with htonl and
+ * the like and storing through an unaligned pointer.
+ */
+static void putBE(unsigned char *p, unsigned long long v, int n) {
+	while (n-- > 0) {
+		p[n] = (unsigned char)v;
+		v >>= 8;
+	}
+}
+
+static unsigned long long getBE(const unsigned char *p, int n) {
+	unsigned long long v = 0;
+	int i;
+
+	for (i = 0; i < n; i++)
+		v = v << 8 | p[i];
+	return v;
+}
+
+int myWireEncode(const struct myWireHeader *h, unsigned char *buf, size_t n) {
+	if (h == NULL || buf == NULL || n < MYLIB_WIRE_SIZE)
+		return MYLIB_EINVAL;
+	putBE(buf, MYLIB_WIRE_MAGIC, 2);
+	buf[2] = MYLIB_WIRE_VERSION;
+	putBE(buf + 3, h->sequence, 4);
+	putBE(buf + 7, (unsigned long long)h->timestamp, 8);
+	putBE(buf + 15, (unsigned short)h->delta, 2);
+	putBE(buf + 17, h->length, 2);
+	return MYLIB_OK;
+}
+
+int myWireDecode(const unsigned char *buf, size_t n, struct myWireHeader *h) {
+	unsigned long long ts;
+	unsigned int delta;
+
+	if (buf == NULL || h == NULL || n < MYLIB_WIRE_SIZE || getBE(buf, 2) != MYLIB_WIRE_MAGIC)
+		return MYLIB_EINVAL;
+	if (buf[2] != MYLIB_WIRE_VERSION)
+		return MYLIB_ERANGE;
+	h->sequence = (unsigned int)getBE(buf + 3, 4);
+	/* Converting an out-of-range value to a signed type is
+	 * implementation-defined; going through the sign keeps it defined. */
+	ts = getBE(buf + 7, 8);
+	h->timestamp = ts > LLONG_MAX ? -(long long)(~ts) - 1 : (long long)ts;
+	delta = (unsigned int)getBE(buf + 15, 2);
+	h->delta = (short)(delta > 0x7fff ? (int)delta - 0x10000 : (int)delta);
+	h->length = (unsigned short)getBE(buf + 17, 2);
+	return MYLIB_OK;
+}
+
+#ifdef MYLIB_COVERAGE
+/* From the coverage runtime --coverage links in: GCC's libgcov, or the
+ * compatible one of clang's compiler-rt. */
+void __gcov_dump(void);
+void __gcov_reset(void);
+
+int myCoverageFlush(void) {
+	__gcov_dump();
+	__gcov_reset();
+	return 1;
+}
+#else
+int myCoverageFlush(void) {
+	return 0;
+}
+#endif
+
+struct myMessage *myMessageNew(unsigned short type, const void *data, size_t n) {
+	struct myMessage *m;
+
+	if ((data == NULL && n > 0) || n > UINT_MAX) {
+		errno = data == NULL ? EINVAL : ERANGE;
+		return NULL;
+	}
+	if ((m = myMalloc(offsetof(struct myMessage, data) + n)) == NULL) {
+		errno = ENOMEM;
+		return NULL;
+	}
+	m->length = (unsigned int)n;
+	m->type = type;
+	if (n > 0)
+		memcpy(m->data, data, n);
+	return m;
+}
+
+unsigned int myMessageChecksum(const struct myMessage *m) {
+	unsigned int h = 2166136261u;
+	unsigned int i;
+
+	for (i = 0; i < m->length; i++) {
+		h ^= m->data[i];
+		h *= 16777619u;
+	}
+	return h;
+}
+
+/*
+ * The handler is read under logMu, shared, for the whole of each call to
+ * it, so that mySetLogHandler, taking it exclusively, returns only once
+ * the old handler is done.
+ */
+static myLogHandler logHandler;
+static void *logUserdata;
+
+#ifdef _WIN32
+static SRWLOCK logMu = SRWLOCK_INIT;
+#define logReadLock() AcquireSRWLockShared(&logMu)
+#define logReadUnlock() ReleaseSRWLockShared(&logMu)
+#define logWriteLock() AcquireSRWLockExclusive(&logMu)
+#define logWriteUnlock() ReleaseSRWLockExclusive(&logMu)
+#else
+static pthread_rwlock_t logMu = PTHREAD_RWLOCK_INITIALIZER;
+#define logReadLock() pthread_rwlock_rdlock(&logMu)
+#define logReadUnlock() pthread_rwlock_unlock(&logMu)
+#define logWriteLock() pthread_rwlock_wrlock(&logMu)
+#define logWriteUnlock() pthread_rwlock_unlock(&logMu)
+#endif
+
+void mySetLogHandler(myLogHandler h, void *userdata) {
+	logWriteLock();
+	logHandler = h;
+	logUserdata = h != NULL ? userdata : NULL;
+	logWriteUnlock();
+}
+
+static int logToStdout(const char *message, int n, const char *const *attrs, int nattrs) {
+	int i;
+
+	if (printf("mylib: %s", message) < 0) {
+		fail(errno);
+		return -1;
+	}
+	for (i = 0; i < nattrs; i++) {
+		if (printf(" %s=%s", attrs[2 * i], attrs[2 * i + 1]) < 0) {
+			fail(errno);
+			return -1;
+		}
+	}
+	if (putchar('\n') == EOF) {
+		fail(errno);
+		return -1;
+	}
+	fflush(stdout);
+	return n;
+}
+
+static int logv(int level, const char *file, int line, const char *const *attrs, int nattrs,
+		const char *format, va_list ap) {
+	struct myLogRecord r;
+	char small[256], *message = small;
+	va_list again;
+	int n, threshold;
+
+	if (format == NULL || nattrs < 0 || (attrs == NULL && nattrs > 0)) {
+		fail(EINVAL);
+		return -1;
+	}
+	myConfigLock();
+	threshold = myLogLevel;
+	myConfigUnlock();
+	if (level <= MYLIB_LOG_OFF || level > threshold)
+		return 0;
+
+	va_copy(again, ap);
+	n = vsnprintf(small, sizeof small, format, ap);
+	if (n >= (int)sizeof small) {
+		if ((message = myMalloc((size_t)n + 1)) == NULL) {
+			va_end(again);
+			fail(ENOMEM);
+			return -1;
+		}
+		vsnprintf(message, (size_t)n + 1, format, again);
+	}
+	va_end(again);
+	if (n < 0) {
+		fail(EINVAL);
+		return -1;
+	}
+
+	logReadLock();
+	if (logHandler != NULL) {
+		r.level = level;
+		r.file = file;
+		r.line = line;
+		r.message = message;
+		r.attrs = attrs;
+		r.nattrs = nattrs;
+		logHandler(&r, logUserdata);
+		logReadUnlock();
+	} else {
+		logReadUnlock();
+		n = logToStdout(message, n, attrs, nattrs);
+	}
+	if (message != small)
+		myFree(message);
+	return n;
+}
+
+int myLogAt(int level, const char *file, int line, const char *const *attrs, int nattrs,
+	    const char *format, ...) {
+	va_list ap;
+	int n;
+
+	va_start(ap, format);
+	n = logv(level, file, line, attrs, nattrs, format, ap);
+	va_end(ap);
+	return n;
+}
+
+int myLogf(const char *format, ...) {
+	va_list ap;
+	int n;
+
+	va_start(ap, format);
+	n = logv(MYLIB_LOG_INFO, NULL, 0, NULL, 0, format, ap);
+	va_end(ap);
+	return n;
+}
+
+FILE *myOpenReport(const char *title, const long long *values, size_t n) {
+	FILE *f;
+	int saved;
+
+	if ((f = tmpfile()) == NULL)
+		return NULL;
+	if (myWriteReport(f, title, values, n) < 0 || fflush(f) != 0 || fseek(f, 0, SEEK_SET) != 0) {
+		saved = errno;
+		fclose(f);
+		errno = saved;
+		return NULL;
+	}
+	return f;
+}
+
+#ifdef _WIN32
+#include <windows.h>
+
+/*
+ * The layout pkg/mylib's Windows build assumes, whichever of MSVC and
+ * MinGW-w64 compiled the DLL: both follow the Windows ABI, where long is
+ * 4 bytes and wchar_t 2, and put a double at an 8-byte offset. A
+ * compiler or flag that disagrees (-mno-ms-bitfields, -fshort-wchar
+ * elsewhere, #pragma pack) fails here rather than at run time. The
+ * array trick stands in for static_assert, which older MSVC lacks in C.
+ */
+#define MY_ASSERT(name, cond) typedef char myAssert_##name[(cond) ? 1 : -1]
+MY_ASSERT(long, sizeof(long) == 4);
+MY_ASSERT(wchar, sizeof(wchar_t) == 2);
+MY_ASSERT(struct_b, offsetof(struct myStruct, b) == sizeof(void *));
+MY_ASSERT(struct_size, sizeof(struct myStruct) == 2 * sizeof(void *));
+MY_ASSERT(point_weight, offsetof(struct myPoint, weight) == 8);
+MY_ASSERT(point_size, sizeof(struct myPoint) == 16);
+
+void myPrintFunctionW(const wchar_t *s) {
+	char *buf;
+	int n;
+
+	/* The console may not use a UTF-8 code page, but pipes and files
+	 * written by Go programs expect UTF-8. */
+	n = WideCharToMultiByte(CP_UTF8, 0, s, -1, NULL, 0, NULL, NULL);
+	if (n <= 0)
+		return;
+	buf = myMalloc(n);
+	if (buf == NULL)
+		return;
+	WideCharToMultiByte(CP_UTF8, 0, s, -1, buf, n, NULL, NULL);
+	printf("%s\n", buf);
+	fflush(stdout);
+	myFree(buf);
+}
+
+long long myFileSizeW(const wchar_t *path) {
+	WIN32_FILE_ATTRIBUTE_DATA d;
+
+	if (!GetFileAttributesExW(path, GetFileExInfoStandard, &d))
+		return -1;
+	if (d.dwFileAttributes & FILE_ATTRIBUTE_DIRECTORY) {
+		SetLastError(ERROR_INVALID_PARAMETER);
+		return -1;
+	}
+	return ((long long)d.nFileSizeHigh << 32) | d.nFileSizeLow;
+}
+#endif
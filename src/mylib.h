@@ -1,9 +1,678 @@
+#include <setjmp.h>
 #include <stdio.h>
+#include <time.h>
+#include <wchar.h>
 
 struct myStruct {
 	int a;
 	char *b;
 };
 
+struct myPoint {
+	int x;
+	int y;
+	double weight;
+};
+
+/*
+ * Versions. myVersion returns the version of the library that is loaded,
+ * which need not be the one the program was compiled against, as
+ * MYLIB_VERSION_NUMBER(major, minor, patch). MYLIB_VERSION is the version
+ * of this header in the same form.
+ */
+#define MYLIB_VERSION_MAJOR 1
+#define MYLIB_VERSION_MINOR 0
+#define MYLIB_VERSION_PATCH 0
+#define MYLIB_VERSION_STRING "1.0.0"
+
+#define MYLIB_VERSION_NUMBER(major, minor, patch) ((major) * 10000 + (minor) * 100 + (patch))
+#define MYLIB_VERSION MYLIB_VERSION_NUMBER(MYLIB_VERSION_MAJOR, MYLIB_VERSION_MINOR, MYLIB_VERSION_PATCH)
+
+int myVersion(void);
+
 void myPrintFunction(char *s);
 
+/* Structs */
+void myPrintStruct(const struct myStruct *s);
+struct myStruct myMakeStruct(int a, char *b);
+struct myStruct myScaleStruct(struct myStruct s, int factor);
+void myTranslatePoints(struct myPoint *pts, int n, int dx, int dy);
+
+/* Callbacks */
+typedef int (*myCallback)(void *userdata, int value);
+int myCallN(myCallback cb, void *userdata, int n);
+
+/* Opaque userdata */
+typedef void (*myWordCallback)(void *userdata, const char *word, int len);
+void myEachWord(const char *text, myWordCallback cb, void *userdata);
+
+/*
+ * Linked lists. mySplitWords stores in *out the words of text as a list,
+ * NULL if there are none, and fails with MYLIB_ENOMEM, leaving *out alone,
+ * if memory runs out. The caller owns the list, including every node's
+ * text, and releases all of it with one call to myWordsFree.
+ */
+struct myWord {
+	char *text;
+	int offset;           /* where the word starts in text, in bytes */
+	struct myWord *next;
+};
+
+int mySplitWords(const char *text, struct myWord **out);
+void myWordsFree(struct myWord *list);
+
+/*
+ * Errors. Functions returning an int status use these codes; those that
+ * return NULL or -1 instead say why in errno and, on Windows, also through
+ * GetLastError. The comment after each code names its Go constant.
+ */
+enum myStatus {
+	MYLIB_OK = 0,        /* OK */
+	MYLIB_ENOTFOUND = 1, /* not found */
+	MYLIB_EINVAL = 2,    /* invalid */
+	MYLIB_ERANGE = 3,    /* range */
+	MYLIB_ECANCELED = 4, /* canceled */
+	MYLIB_ENOMEM = 5,    /* no memory */
+};
+
+int myLookup(const char *key, int *value);
+long myFileSize(const char *path);
+
+/* Not thread-safe */
+int myCounterAdd(int delta);
+
+/* Buffers: created with myBufferNew, released with myBufferFree */
+typedef struct myBuffer myBuffer;
+
+myBuffer *myBufferNew(void);
+void myBufferFree(myBuffer *b);
+int myBufferAppend(myBuffer *b, const char *s);
+const char *myBufferData(const myBuffer *b);
+size_t myBufferLen(const myBuffer *b);
+int myBufferLive(void);
+
+/*
+ * Sessions: independent stateful objects. Each session keeps its own
+ * running total, bounded by the limit it was created with. Every function
+ * rejects a NULL session with MYLIB_EINVAL.
+ */
+typedef struct mySession mySession;
+
+/* Returns NULL if name is NULL, limit is negative or memory runs out. */
+mySession *mySessionNew(const char *name, long long limit);
+void mySessionFree(mySession *s);
+/* Fails with MYLIB_ERANGE, leaving the total alone, if |total| would pass the limit. */
+int mySessionAdd(mySession *s, long long delta, long long *total);
+int mySessionReset(mySession *s);
+int mySessionStats(const mySession *s, long long *total, int *calls);
+const char *mySessionName(const mySession *s);
+/*
+ * Sets *owner to 1 if the calling thread is the one that created s, and to
+ * 0 otherwise, as a library keeping per-thread state would need to check.
+ */
+int mySessionOwner(const mySession *s, int *owner);
+/*
+ * Flushes s to its peer, which takes millis milliseconds: a stand-in for a
+ * call that can block without bound and cannot be interrupted. It touches
+ * nothing but s, so it may run alongside calls on other sessions.
+ */
+int mySessionSync(mySession *s, int millis);
+
+/* Byte buffers */
+void myFill(unsigned char *buf, size_t n, unsigned char seed);
+unsigned int myChecksum(const unsigned char *buf, size_t n);
+
+/*
+ * Complex numbers, as C99 double _Complex: two doubles, the real part
+ * first, aligned like a double. MSVC's C compiler has no _Complex, so a
+ * DLL built with it lacks these.
+ */
+#ifndef _MSC_VER
+double _Complex myComplexMul(double _Complex a, double _Complex b);
+/* Multiplies each of the n numbers at z by factor, in place. */
+void myComplexScale(double _Complex *z, size_t n, double _Complex factor);
+double _Complex myComplexSum(const double _Complex *z, size_t n);
+#endif
+
+/*
+ * Long-running work. myCrunch checks *cancel every few thousand
+ * iterations and stops with MYLIB_ECANCELED once another thread has set it
+ * to a non-zero value.
+ */
+int myCrunch(long long iterations, const int *cancel, long long *result);
+/*
+ * myCrunchProgress computes what myCrunch does, calling cb with the number
+ * of iterations done after every 1024 of them and at the end. It stops
+ * with MYLIB_ECANCELED if cb returns non-zero.
+ */
+typedef int (*myProgressCallback)(void *userdata, long long done, long long total);
+int myCrunchProgress(long long iterations, myProgressCallback cb, void *userdata, long long *result);
+
+/* Function pointers handed out at run time */
+typedef long long (*myReducer)(const int *values, int n);
+
+/* Returns the reducer called name ("sum", "min" or "max"), or NULL. */
+myReducer myGetReducer(const char *name);
+
+/* Batches: many operations in one call */
+enum myOp {
+	MYLIB_OP_COUNTER_ADD = 1, /* counter add */
+	MYLIB_OP_LOOKUP = 2,      /* lookup */
+};
+
+struct myRequest {
+	int op;               /* an enum myOp */
+	int status;           /* out: MYLIB_OK or an error code */
+	long long arg;        /* in: the delta for MYLIB_OP_COUNTER_ADD */
+	const char *key;      /* in: the key for MYLIB_OP_LOOKUP */
+	long long result;     /* out */
+};
+
+/* Runs reqs in order and returns the number that failed. */
+int myBatch(struct myRequest *reqs, int n);
+
+/* Unions and bitfields */
+#define MYLIB_VALUE_INT 0
+#define MYLIB_VALUE_REAL 1
+#define MYLIB_VALUE_TEXT 2
+
+/* Whether kind holds a number, either MYLIB_VALUE_INT or MYLIB_VALUE_REAL. */
+#define MYLIB_VALUE_IS_NUMBER(kind) ((kind) == MYLIB_VALUE_INT || (kind) == MYLIB_VALUE_REAL)
+
+/* A tagged value: kind says which member of u is set. */
+struct myValue {
+	int kind;
+	union {
+		long long i;
+		double d;
+		char text[16];
+	} u;
+};
+
+struct myFlags {
+	unsigned int readable : 1;
+	unsigned int writable : 1;
+	unsigned int mode : 3;
+	unsigned int level : 4;
+};
+
+/* Doubles a number, or repeats text as far as it fits. */
+int myValueDouble(struct myValue *v);
+
+/* Makes readable flags writable and raises the level by one, up to 15. */
+struct myFlags myFlagsUpgrade(struct myFlags f);
+
+/* Variadic: printf-style logging at MYLIB_LOG_INFO, to stdout, prefixed
+ * with "mylib: ", or to the handler mySetLogHandler installed. Prints
+ * nothing and returns 0 while myLogLevel is below MYLIB_LOG_INFO. */
+int myLogf(const char *format, ...);
+
+#ifndef _WIN32
+#include <stdint.h>
+
+/*
+ * Signals. myInstallSignalHandlers installs handlers for SIGINT, which it
+ * counts, and SIGSEGV, which lets myProbeRead survive reading a bad
+ * address. Both handlers pass every signal they do not consume on to the
+ * handler installed before them, so a host runtime keeps working.
+ */
+int myInstallSignalHandlers(void);
+int myRestoreSignalHandlers(void);
+int mySignalCount(void);
+/* Reads the byte at addr, or returns MYLIB_EINVAL if that faults. */
+int myProbeRead(uintptr_t addr, unsigned char *out);
+
+/*
+ * Threads. myThreadsStart starts nthreads threads of the library's own,
+ * each calling cb count times with its index and a sequence number, and
+ * returns at once. myThreadsJoin waits for them all and frees t.
+ */
+typedef void (*myThreadCallback)(void *userdata, int thread, int seq);
+typedef struct myThreads myThreads;
+
+/* Returns NULL if nthreads or count is negative or a thread cannot start. */
+myThreads *myThreadsStart(int nthreads, int count, myThreadCallback cb, void *userdata);
+int myThreadsJoin(myThreads *t);
+
+/*
+ * Events. myEmitterStart starts a thread of the library's own that calls
+ * cb with count events, or until stopped if count is 0, waiting interval
+ * microseconds before each one. Event seq carries value seq % 100. After
+ * the last event cb is called once more with ev NULL. myEmitterStop stops
+ * the thread early, waits for it, so that no callback is running or still
+ * to come once it returns, and frees e. A callback must not call
+ * myEmitterStop.
+ */
+struct myEvent {
+	long long seq; /* events emitted before this one */
+	int value;
+};
+
+typedef void (*myEventCallback)(void *userdata, const struct myEvent *ev);
+typedef struct myEmitter myEmitter;
+
+/* Returns NULL if count or interval is negative or the thread cannot start. */
+myEmitter *myEmitterStart(long long count, int interval, myEventCallback cb, void *userdata);
+int myEmitterStop(myEmitter *e);
+
+/*
+ * Shared-memory rings: a single-producer, single-consumer message queue in
+ * a POSIX shared memory object, for passing messages between processes.
+ * The layout is fixed, so a program can map the object without this
+ * library:
+ *
+ *	offset  0: uint32 magic, MYLIB_RING_MAGIC
+ *	offset  4: uint32 capacity of the data area in bytes, a power of two
+ *	offset  8: uint64 head, bytes ever written; only the producer moves it
+ *	offset 16: uint64 tail, bytes ever read; only the consumer moves it
+ *	offset 64: the data area
+ *
+ * A message is its length as a little-endian uint32 followed by its bytes,
+ * wrapping around the end of the data area. Each side publishes its
+ * counter with a release store after touching the data and reads the
+ * other's with an acquire load.
+ */
+#define MYLIB_RING_MAGIC 0x4d59524e
+#define MYLIB_RING_HEADER 64
+
+/*
+ * The size of the object holding a ring of capacity bytes, and the room a
+ * message of n bytes takes in the data area.
+ */
+#define MYLIB_RING_SIZE(capacity) (MYLIB_RING_HEADER + (size_t)(capacity))
+#define MYLIB_RING_MESSAGE_SIZE(n) (4 + (uint64_t)(n))
+
+typedef struct myRing myRing;
+
+/*
+ * Creates the object name ("/something") with room for capacity bytes of
+ * messages, failing if it exists, or opens an existing one if capacity is
+ * 0. Returns NULL on failure.
+ */
+myRing *myRingOpen(const char *name, unsigned int capacity);
+void myRingClose(myRing *r);
+int myRingUnlink(const char *name);
+/* Fails with MYLIB_ERANGE if the ring is too full, and MYLIB_EINVAL if the
+ * message could never fit. */
+int myRingPush(myRing *r, const unsigned char *msg, unsigned int n);
+/* Fails with MYLIB_ENOTFOUND if the ring is empty, and with MYLIB_ERANGE,
+ * setting *n to the message's length, if it is longer than cap. */
+int myRingPop(myRing *r, unsigned char *buf, unsigned int cap, unsigned int *n);
+
+/*
+ * File descriptors. myEchoOpen returns one end of a connected stream
+ * socket whose other end a thread of the library's own echoes back until
+ * it reads end of file. The caller owns the descriptor and must close it.
+ *
+ * myNotifyFd returns the read end of a pipe, created on first use, to
+ * which myNotify writes each message as a line. The library owns both
+ * ends: the caller must not close the descriptor, which stays valid until
+ * myNotifyClose. myNotify never blocks; it fails with MYLIB_ERANGE if the
+ * pipe is full. All three return -1 or a status code on failure.
+ */
+int myEchoOpen(void);
+int myNotifyFd(void);
+int myNotify(const char *msg);
+void myNotifyClose(void);
+
+/*
+ * Reactors. A reactor serves connections without a thread of its own:
+ * the caller polls the library's descriptors and reports readiness, as
+ * with c-ares or libcurl's multi interface. The reactor calls watch with
+ * a descriptor and the MYLIB_POLL_ events it wants to hear about, again
+ * whenever that set changes, and with 0 just before it closes the
+ * descriptor; the caller then calls myReactorReady with the events that
+ * became ready. watch is only called from within myReactorConnect and
+ * myReactorReady, on the caller's thread.
+ *
+ * myReactorConnect returns one end of a connected stream socket for the
+ * caller to own and close, whose other end the reactor keeps and writes
+ * back whatever it reads from, as myEchoOpen's thread does, until end of
+ * file. It buffers what the socket cannot take yet, and stops reading
+ * while the buffer is full. myReactorBytes returns the bytes written back
+ * so far. myReactorFree closes the reactor's descriptors without calling
+ * watch.
+ */
+#define MYLIB_POLL_IN 1
+#define MYLIB_POLL_OUT 2
+
+typedef void (*myWatchFunc)(void *userdata, int fd, int events);
+typedef struct myReactor myReactor;
+
+/* Returns NULL if watch is NULL or memory runs out. */
+myReactor *myReactorNew(myWatchFunc watch, void *userdata);
+void myReactorFree(myReactor *r);
+/* Returns -1 on failure, with errno set; EMFILE once MYLIB_REACTOR_MAX
+ * connections are open. */
+int myReactorConnect(myReactor *r);
+/* Fails with MYLIB_ENOTFOUND for a descriptor the reactor does not own. */
+int myReactorReady(myReactor *r, int fd, int events);
+long long myReactorBytes(myReactor *r);
+#define MYLIB_REACTOR_MAX 64
+
+/*
+ * Asynchronous fills. myFillAsync returns at once, having started a
+ * thread of the library's own that waits delay microseconds, fills the n
+ * bytes at buf as myFill does, and calls done with MYLIB_OK and their
+ * myChecksum. The library keeps using buf until done is called, which
+ * happens exactly once, on that thread. It fails with MYLIB_EINVAL for
+ * a negative delay or missing argument, and with MYLIB_ENOMEM if the
+ * thread cannot start, in which case done is never called.
+ */
+typedef void (*myDoneFunc)(void *userdata, int status, unsigned int checksum);
+int myFillAsync(unsigned char *buf, size_t n, unsigned char seed, int delay, myDoneFunc done, void *userdata);
+#endif
+
+/*
+ * Wide strings. wchar_t holds UTF-32 on Unix and UTF-16 on Windows, where
+ * a code point above U+FFFF takes a surrogate pair; both functions treat a
+ * pair as one character. myWideCount returns the number of code points in
+ * s, or -1 if s is NULL. myWideReverse reverses s in place by code point.
+ */
+int myWideCount(const wchar_t *s);
+int myWideReverse(wchar_t *s);
+
+/*
+ * Fatal errors, in the style of libpng. myParseRecord parses a record
+ * "key=value", where value is a decimal integer, and returns the value.
+ * On a malformed record it does not return: it saves a message for
+ * myParserError and longjmps to the jmp_buf from myParserJmpbuf, which
+ * the caller must have passed to setjmp first. myParserNew returns NULL
+ * if memory runs out.
+ */
+typedef struct myParser myParser;
+
+myParser *myParserNew(void);
+void myParserFree(myParser *p);
+jmp_buf *myParserJmpbuf(myParser *p);
+const char *myParserError(const myParser *p);
+long long myParseRecord(myParser *p, const char *record);
+
+/*
+ * Allocator. Every allocation the library makes for itself goes through
+ * the functions passed to mySetAllocator, which replace malloc, realloc
+ * and free. Passing NULL for all three restores the C library's. Memory
+ * must be freed by the allocator that gave it out, so mySetAllocator
+ * fails with MYLIB_EINVAL while myAllocations, the number of allocations
+ * not yet freed, is not zero, and when only some functions are NULL.
+ * It must not run alongside any other call into the library.
+ */
+typedef void *(*myMallocFunc)(size_t size);
+typedef void *(*myReallocFunc)(void *p, size_t size);
+typedef void (*myFreeFunc)(void *p);
+
+int mySetAllocator(myMallocFunc m, myReallocFunc r, myFreeFunc f);
+long long myAllocations(void);
+
+/*
+ * Lifetime, in the style of curl_global_init. myInit sets up the
+ * library's global state and myShutdown releases it, such as the notify
+ * pipe. Between the two, myInitialized returns 1. myInit fails with
+ * MYLIB_EINVAL if the library is initialized already, and myShutdown does
+ * nothing if it is not. Neither is thread-safe, and neither may run
+ * alongside any other call into the library.
+ */
+int myInit(void);
+void myShutdown(void);
+int myInitialized(void);
+
+/*
+ * Time. myTimeFormat writes t, in seconds since 1970 UTC, to buf as
+ * "2006-01-02T15:04:05Z", failing with MYLIB_ERANGE if buf has no room
+ * for it or the year is outside 0 to 9999. myTimespecAdd adds nanos to
+ * *ts, which must have 0 <= tv_nsec < 1000000000 and keeps it so. It
+ * fails with MYLIB_ERANGE, leaving *ts alone, if tv_sec would not fit in
+ * a time_t. myTimevalSub stores end - start in *micros.
+ */
+int myTimeFormat(time_t t, char *buf, size_t n);
+int myTimespecAdd(struct timespec *ts, long long nanos);
+#ifndef _WIN32
+#include <sys/time.h>
+int myTimevalSub(const struct timeval *end, const struct timeval *start, long long *micros);
+#endif
+
+/*
+ * Streams. myWriteReport prints a report of the n values to f, a line for
+ * the title, one per value and one for their total, and returns the number
+ * of bytes it wrote or -1 if a write failed, leaving f to the caller.
+ * myOpenReport writes the same report to a temporary file and returns it
+ * open for reading from the start; the caller closes it with fclose.
+ */
+long myWriteReport(FILE *f, const char *title, const long long *values, size_t n);
+FILE *myOpenReport(const char *title, const long long *values, size_t n);
+
+/*
+ * Ownership. myJoin and myRepeat return memory that passes to the caller,
+ * who must release it with myFree, which goes through the allocator of
+ * mySetAllocator, rather than with free. myJoin returns the n parts with
+ * sep between them as a new string. myRepeat returns times copies of the
+ * n bytes at buf, storing their length in *len. Both return NULL with
+ * errno set on failure: EINVAL for a NULL argument, ERANGE if the result
+ * would be too long, ENOMEM if it cannot be allocated.
+ */
+char *myJoin(const char *const *parts, size_t n, const char *sep);
+void *myRepeat(const void *buf, size_t n, size_t times, size_t *len);
+void myFree(void *p);
+
+/*
+ * Out-parameters. These return a status and store their results through
+ * pointers to pointers, which they leave alone on failure.
+ * myParseKeyValue splits line at its first '=' into a key and a value,
+ * each with surrounding blanks removed, and stores them as new strings to
+ * release with myFree; it fails with MYLIB_EINVAL if there is no '=' or
+ * the key is empty. myBufferFrom stores a new buffer holding s in *out.
+ */
+int myParseKeyValue(const char *line, char **key, char **value);
+int myBufferFrom(const char *s, myBuffer **out);
+
+/*
+ * Arguments. myParseArgs reads options from argv as main gets it: argc
+ * arguments, the program name first, and a NULL after the last. It takes
+ * -v, -n COUNT and --name=NAME, up to "--" or the first argument that is
+ * not an option, and stores in out->rest the index of the first argument
+ * left. out->name points into argv. It fails with MYLIB_EINVAL for an
+ * unknown option, a missing or malformed value, or a missing NULL.
+ */
+struct myArgs {
+	int verbose;
+	long long count;
+	const char *name;
+	int rest;
+};
+
+int myParseArgs(int argc, char *const *argv, struct myArgs *out);
+
+/*
+ * Sorting. mySort sorts the n elements of size bytes at base with cmp,
+ * which returns less than, equal to or greater than zero as a sorts
+ * before, with or after b. Unlike qsort it passes userdata on to cmp, and
+ * it is stable: equal elements keep their order. It needs n * size bytes
+ * of scratch space, failing with MYLIB_ERANGE if that overflows and with
+ * MYLIB_ENOMEM if it cannot be allocated.
+ */
+typedef int (*myCompareFunc)(const void *a, const void *b, void *userdata);
+int mySort(void *base, size_t n, size_t size, myCompareFunc cmp, void *userdata);
+
+/*
+ * Hashing. myHash returns the 32-bit FNV-1a hash of the n bytes at s,
+ * which need not be NUL-terminated and may contain NULs, and
+ * myHashString that of the NUL-terminated string s. Neither keeps s.
+ */
+unsigned int myHash(const char *s, size_t n);
+unsigned int myHashString(const char *s);
+
+/*
+ * Statistics. myMean returns the mean of the n values at v. It asserts
+ * that n is not 0, so an empty array aborts the process unless the
+ * library was built with NDEBUG.
+ */
+double myMean(const int *v, size_t n);
+
+/*
+ * ABI. myGetABI describes how the library was compiled: the sizes and
+ * offsets of the structs passed by address or by value, and of the types
+ * whose size differs between platforms. A caller that builds its own
+ * copies of the structs compares them with its own before calling
+ * anything else. myCompiler names the compiler, such as "MSVC 1939" or
+ * "MinGW-w64 GCC 13.2.0".
+ */
+struct myABI {
+	size_t structSize;
+	size_t structB; /* offset of myStruct.b */
+	size_t pointSize;
+	size_t pointWeight; /* offset of myPoint.weight */
+	size_t wcharSize;
+	size_t longSize;
+	size_t pointerSize;
+};
+
+void myGetABI(struct myABI *abi);
+const char *myCompiler(void);
+
+/*
+ * Calling conventions. On 32-bit Windows the Win32 API and many SDK DLLs
+ * use __stdcall, where the function pops its own arguments, rather than
+ * C's cdecl; elsewhere there is a single convention and MYLIB_STDCALL is
+ * empty. myMulAdd, which returns a * b + c, is exported that way as a
+ * sample. Compilers decorate such names with the size of the arguments,
+ * _myMulAdd@16 for MSVC and myMulAdd@16 for MinGW; mylib.def and MinGW's
+ * --kill-at export it as plain myMulAdd.
+ */
+#ifdef _WIN32
+#define MYLIB_STDCALL __stdcall
+#else
+#define MYLIB_STDCALL
+#endif
+
+long long MYLIB_STDCALL myMulAdd(int a, int b, long long c);
+
+/*
+ * Configuration, kept in global variables. myLogLevel, an enum
+ * myLogLevel, is MYLIB_LOG_INFO to begin with; below that myLogf prints
+ * nothing. myBufferSize is the capacity a buffer's first allocation gets,
+ * 16 to begin with; 0 means the default. The library's own threads read
+ * them, so they may only be read or written between myConfigLock and
+ * myConfigUnlock, called on the same thread; the lock also makes a change
+ * visible to every thread that takes it afterwards. From a DLL, C code
+ * importing them has to declare them __declspec(dllimport).
+ */
+enum myLogLevel {
+	MYLIB_LOG_OFF = 0,   /* off */
+	MYLIB_LOG_ERROR = 1, /* error */
+	MYLIB_LOG_INFO = 2,  /* info */
+	MYLIB_LOG_DEBUG = 3, /* debug */
+};
+
+extern int myLogLevel;
+extern size_t myBufferSize;
+
+void myConfigLock(void);
+void myConfigUnlock(void);
+
+/*
+ * Wire format: a message header as it travels between hosts, packed and
+ * big-endian whatever the byte order of either:
+ *
+ *	offset  0: uint16 magic, MYLIB_WIRE_MAGIC
+ *	offset  2: uint8 version, MYLIB_WIRE_VERSION
+ *	offset  3: uint32 sequence
+ *	offset  7: int64 timestamp
+ *	offset 15: int16 delta
+ *	offset 17: uint16 length of the payload that follows
+ *
+ * MYLIB_WIRE_SIZE bytes in all. Most fields are unaligned, so the bytes
+ * cannot be read as a C struct. struct myWireHeader holds the fields in
+ * host order instead; myWireEncode writes h to the MYLIB_WIRE_SIZE bytes
+ * at buf, and myWireDecode reads them back. Both fail with MYLIB_EINVAL
+ * if n is short of MYLIB_WIRE_SIZE; myWireDecode also fails with
+ * MYLIB_EINVAL for a wrong magic, and with MYLIB_ERANGE for a version
+ * other than MYLIB_WIRE_VERSION.
+ */
+#define MYLIB_WIRE_MAGIC 0x4d57
+#define MYLIB_WIRE_VERSION 1
+#define MYLIB_WIRE_SIZE 19
+
+struct myWireHeader {
+	unsigned int sequence;
+	long long timestamp;
+	short delta;
+	unsigned short length;
+};
+
+int myWireEncode(const struct myWireHeader *h, unsigned char *buf, size_t n);
+int myWireDecode(const unsigned char *buf, size_t n, struct myWireHeader *h);
+
+/*
+ * Coverage. Built with -DMYLIB_COVERAGE and --coverage, as make coverage
+ * does, the library counts the lines it runs, and writes the counts to
+ * the .gcda file next to its object when the process calls exit. A host
+ * that exits some other way, as Go programs do, calls myCoverageFlush
+ * first: it adds the counts so far to the file and starts them again from
+ * zero, so that it can be called more than once. It returns 1, or 0 from
+ * a build without coverage.
+ */
+int myCoverageFlush(void);
+
+/*
+ * Messages: a header with the payload after it in the same allocation,
+ * as a flexible array member. A message with n bytes of payload takes
+ * offsetof(struct myMessage, data) + n bytes, 6 + n, which is not
+ * sizeof(struct myMessage) + n: the size counts the padding that rounds
+ * the struct up to the alignment of length, and data starts inside it.
+ * length is the number of bytes in data.
+ *
+ * myMessageNew allocates a message of the given type holding a copy of
+ * the n bytes at data, which the caller releases with myFree; it returns
+ * NULL with errno EINVAL if data is NULL and n is not 0, ERANGE if n
+ * does not fit in length, or ENOMEM.
+ * myMessageChecksum returns the 32-bit FNV-1a hash of m's payload, the
+ * length bytes at data, whoever allocated m.
+ */
+struct myMessage {
+	unsigned int length;
+	unsigned short type;
+	unsigned char data[];
+};
+
+struct myMessage *myMessageNew(unsigned short type, const void *data, size_t n);
+unsigned int myMessageChecksum(const struct myMessage *m);
+
+/*
+ * Log handler. The library logs through myLogAt, with the level, the file
+ * and line of the call, as MYLIB_LOG fills them in, and attributes: nattrs
+ * key/value pairs of strings in attrs, 2*nattrs of them. Records above
+ * myLogLevel are dropped, as are those at MYLIB_LOG_OFF. Without a handler
+ * the rest go to stdout, as myLogf's always did, with the attributes after
+ * the message as key=value; mySetLogHandler(h, userdata) sends them to h
+ * instead, and mySetLogHandler(NULL, NULL) back to stdout. myLogAt returns
+ * the length of the message, or -1 with errno EINVAL or ENOMEM.
+ *
+ * The handler runs on the thread that logs, which may be one of the
+ * library's own, and on several at once; the record and its strings are
+ * valid until it returns. mySetLogHandler waits for the calls to the
+ * handler it replaces to return, so that the old userdata can be freed
+ * after it; a handler must not call it, nor log through the library.
+ */
+struct myLogRecord {
+	int level; /* enum myLogLevel */
+	const char *file;
+	int line;
+	const char *message;
+	const char *const *attrs;
+	int nattrs;
+};
+
+typedef void (*myLogHandler)(const struct myLogRecord *r, void *userdata);
+
+void mySetLogHandler(myLogHandler h, void *userdata);
+int myLogAt(int level, const char *file, int line, const char *const *attrs, int nattrs,
+	    const char *format, ...);
+
+#define MYLIB_LOG(level, attrs, nattrs, ...) \
+	myLogAt((level), __FILE__, __LINE__, (attrs), (nattrs), __VA_ARGS__)
+
+#ifdef _WIN32
+/* Windows: UTF-16 variants, which report errors through GetLastError */
+void myPrintFunctionW(const wchar_t *s);
+long long myFileSizeW(const wchar_t *path);
+#endif
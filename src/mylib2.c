@@ -0,0 +1,53 @@
+/*
+ * Version 2.0 of the library: mylib.c with the three functions whose
+ * signatures changed renamed out of the way, and their 2.0 versions. The
+ * version script mylib2.map hides the old ones, prefixed abi1_.
+ */
+#define myVersion abi1_myVersion
+#define myLookup abi1_myLookup
+#define myChecksum abi1_myChecksum
+#include "mylib.c"
+#undef myVersion
+#undef myLookup
+#undef myChecksum
+
+#include "mylib2.h"
+
+static const struct {
+	const char *key;
+	long long value;
+} myTable2[] = {
+	{"one", 1},
+	{"two", 2},
+	{"three", 3},
+	{"tera", 1000000000000LL},
+};
+
+int myVersion(void) {
+	return 20000;
+}
+
+int myLookup(const char *key, long long *value) {
+	size_t i;
+
+	if (key == NULL || value == NULL)
+		return MYLIB_EINVAL;
+	for (i = 0; i < sizeof(myTable2) / sizeof(myTable2[0]); i++) {
+		if (strcmp(myTable2[i].key, key) == 0) {
+			*value = myTable2[i].value;
+			return MYLIB_OK;
+		}
+	}
+	return MYLIB_ENOTFOUND;
+}
+
+unsigned int myChecksum(unsigned int adler, const unsigned char *buf, size_t n) {
+	unsigned int a = adler & 0xffff, b = adler >> 16;
+	size_t i;
+
+	for (i = 0; i < n; i++) {
+		a = (a + buf[i]) % 65521;
+		b = (b + a) % 65521;
+	}
+	return (b << 16) | a;
+}
@@ -0,0 +1,23 @@
+#ifndef MYLIB2_H
+#define MYLIB2_H
+
+#include <stddef.h>
+
+/*
+ * Version 2.0 of the library changes the signatures of three functions
+ * and keeps the rest of mylib.h. A program cannot include both headers,
+ * and a process that needs both versions loads each with dlopen and
+ * RTLD_LOCAL, so that neither's symbols replace the other's. The 2.0
+ * symbols carry the version MYLIB_2.0, which dlvsym asks for by name.
+ *
+ * myVersion returns 20000 and up. myLookup stores 64-bit values, and a
+ * few table entries need them. myChecksum continues a running Adler-32,
+ * as zlib's adler32 does: pass 1 to start one.
+ */
+#define MYLIB2_SYMVER "MYLIB_2.0"
+
+int myVersion(void);
+int myLookup(const char *key, long long *value);
+unsigned int myChecksum(unsigned int adler, const unsigned char *buf, size_t n);
+
+#endif
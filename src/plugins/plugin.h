@@ -0,0 +1,27 @@
+#include <stddef.h>
+
+/*
+ * The plugin ABI. A plugin is a shared library exporting the functions
+ * below; pkg/cplugin loads every one it finds in a directory.
+ *
+ * After dlopen, the host calls myPluginInit with the ABI version it was
+ * built for. A plugin returns 0 to accept it and anything else to refuse
+ * to load. myPluginShutdown, which is optional, is called before dlclose;
+ * a plugin may be loaded again afterwards and must start from a clean
+ * state.
+ */
+#define MYPLUGIN_ABI 1
+
+int myPluginInit(int abi);
+void myPluginShutdown(void);
+
+/* A short name for the plugin, valid until it is unloaded. */
+const char *myPluginName(void);
+
+/*
+ * Transforms the n bytes at in, writing up to cap bytes of the result to
+ * out, and returns the length of the whole result, or -1 on failure. A
+ * result longer than cap is truncated, and the host calls again with room
+ * for all of it, as with snprintf.
+ */
+long myPluginProcess(const unsigned char *in, size_t n, unsigned char *out, size_t cap);
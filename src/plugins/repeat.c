@@ -0,0 +1,40 @@
+#include <string.h>
+
+#include "plugin.h"
+
+/*
+ * Writes its input twice, separated by a space, so its result is longer
+ * than its input. It refuses to be initialized twice without a shutdown
+ * in between.
+ */
+
+static int initialized;
+
+int myPluginInit(int abi) {
+	if (abi != MYPLUGIN_ABI || initialized)
+		return -1;
+	initialized = 1;
+	return 0;
+}
+
+void myPluginShutdown(void) {
+	initialized = 0;
+}
+
+const char *myPluginName(void) {
+	return "repeat";
+}
+
+long myPluginProcess(const unsigned char *in, size_t n, unsigned char *out, size_t cap) {
+	size_t want = 2 * n + 1;
+
+	if (!initialized || (in == NULL && n > 0))
+		return -1;
+	if (want <= cap) {
+		memcpy(out, in, n);
+		out[n] = ' ';
+		memcpy(out + n + 1, in, n);
+	}
+	return (long)want;
+}
+
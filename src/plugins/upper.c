@@ -0,0 +1,43 @@
+#include "plugin.h"
+
+/* Upper-cases ASCII letters and leaves every other byte alone. */
+
+static int initialized;
+
+int myPluginInit(int abi) {
+	if (abi != MYPLUGIN_ABI)
+		return -1;
+	initialized = 1;
+	return 0;
+}
+
+void myPluginShutdown(void) {
+	initialized = 0;
+}
+
+const char *myPluginName(void) {
+	return "upper";
+}
+
+long myPluginProcess(const unsigned char *in, size_t n, unsigned char *out, size_t cap) {
+	size_t i;
+
+	if (!initialized || (in == NULL && n > 0))
+		return -1;
+	for (i = 0; i < n && i < cap; i++)
+		out[i] = in[i] >= 'a' && in[i] <= 'z' ? in[i] - 'a' + 'A' : in[i];
+	return (long)n;
+}
+
+/*
+ * Not part of the plugin ABI: the host can only call it by looking it up
+ * and knowing its signature, as the selfcheck does through pkg/ffi.
+ */
+int upperCount(const char *s, int limit) {
+	int n = 0;
+
+	for (; *s != '\0' && n < limit; s++)
+		if (*s >= 'a' && *s <= 'z')
+			n++;
+	return n;
+}
@@ -0,0 +1,157 @@
+//go:build swig
+
+// Command conformance runs the same checks against the hand-written cgo
+// bindings in pkg/mylib and the SWIG-generated ones in package mylibswig,
+// and reports any place where they disagree with the C library's
+// documented behavior.
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/lxwagn/using-go-with-c-libraries/pkg/mylib"
+	mylibswig "github.com/lxwagn/using-go-with-c-libraries/swig"
+)
+
+// An impl is one set of bindings, reduced to the C library's own status
+// codes so that both can be checked the same way.
+type impl struct {
+	name       string
+	lookup     func(key string) (value, status int)
+	fileSize   func(path string) int64
+	counterAdd func(delta int) int
+	buffer     func(parts []string) (data string, length int)
+}
+
+var cgoImpl = impl{
+	name: "cgo",
+	lookup: func(key string) (int, int) {
+		v, err := mylib.Lookup(key)
+		switch {
+		case errors.Is(err, mylib.ErrNotFound):
+			return 0, 1
+		case err != nil:
+			return 0, -1
+		}
+		return v, 0
+	},
+	fileSize: func(path string) int64 {
+		n, err := mylib.FileSize(path)
+		if err != nil {
+			return -1
+		}
+		return n
+	},
+	counterAdd: mylib.CounterAdd,
+	buffer: func(parts []string) (string, int) {
+		b, err := mylib.NewBuffer()
+		if err != nil {
+			return "", -1
+		}
+		defer b.Close()
+		for _, p := range parts {
+			if err := b.Append(p); err != nil {
+				return "", -1
+			}
+		}
+		return b.String(), b.Len()
+	},
+}
+
+var swigImpl = impl{
+	name: "swig",
+	lookup: func(key string) (int, int) {
+		out := []int{0}
+		status := mylibswig.MyLookup(key, out)
+		return int(out[0]), int(status)
+	},
+	fileSize: func(path string) int64 {
+		return int64(mylibswig.MyFileSize(path))
+	},
+	counterAdd: func(delta int) int {
+		return int(mylibswig.MyCounterAdd(delta))
+	},
+	buffer: func(parts []string) (string, int) {
+		b := mylibswig.MyBufferNew()
+		defer mylibswig.MyBufferFree(b)
+		for _, p := range parts {
+			if mylibswig.MyBufferAppend(b, p) != 0 {
+				return "", -1
+			}
+		}
+		return mylibswig.MyBufferData(b), int(mylibswig.MyBufferLen(b))
+	},
+}
+
+// A check returns a description of what went wrong, or "" if nothing did.
+type check struct {
+	name string
+	run  func(impl) string
+}
+
+var checks = []check{
+	{"lookup finds known keys", func(im impl) string {
+		for key, want := range map[string]int{"one": 1, "two": 2, "three": 3} {
+			if v, st := im.lookup(key); st != 0 || v != want {
+				return fmt.Sprintf("lookup(%q) = %d, status %d; want %d, status 0", key, v, st, want)
+			}
+		}
+		return ""
+	}},
+	{"lookup reports missing keys", func(im impl) string {
+		if _, st := im.lookup("four"); st != 1 {
+			return fmt.Sprintf("lookup(\"four\") status %d; want 1", st)
+		}
+		return ""
+	}},
+	{"file size", func(im impl) string {
+		fi, err := os.Stat("go.mod")
+		if err != nil {
+			return "run from the repository root: " + err.Error()
+		}
+		if n := im.fileSize("go.mod"); n != fi.Size() {
+			return fmt.Sprintf("fileSize(go.mod) = %d; want %d", n, fi.Size())
+		}
+		if n := im.fileSize("does/not/exist"); n != -1 {
+			return fmt.Sprintf("fileSize of a missing file = %d; want -1", n)
+		}
+		return ""
+	}},
+	{"counter", func(im impl) string {
+		// Both implementations share the library's one counter, so only
+		// the difference is meaningful.
+		before := im.counterAdd(0)
+		if after := im.counterAdd(5); after-before != 5 {
+			return fmt.Sprintf("counterAdd(5) moved the counter by %d", after-before)
+		}
+		return ""
+	}},
+	{"buffer round trip", func(im impl) string {
+		parts := []string{"hello", ", ", "world"}
+		want := strings.Join(parts, "")
+		if data, n := im.buffer(parts); data != want || n != len(want) {
+			return fmt.Sprintf("buffer = %q (len %d); want %q (len %d)", data, n, want, len(want))
+		}
+		return ""
+	}},
+}
+
+func main() {
+	failed := false
+	for _, im := range []impl{cgoImpl, swigImpl} {
+		for _, c := range checks {
+			if msg := c.run(im); msg != "" {
+				fmt.Printf("FAIL %s: %s: %s\n", im.name, c.name, msg)
+				failed = true
+				continue
+			}
+			fmt.Printf("ok   %s: %s\n", im.name, c.name)
+		}
+	}
+	if failed {
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,10 @@
+// Package mylibswig binds libmylib through SWIG instead of hand-written
+// cgo, for comparing the two approaches. SWIG generates the Go and C
+// wrappers from mylibswig.swig at build time, so building the package
+// needs swig on the PATH and the swig build tag:
+//
+//	go build -tags swig ./swig/...
+//
+// swig/conformance runs the same checks against this package and
+// pkg/mylib.
+package mylibswig
@@ -0,0 +1,7 @@
+//go:build swig
+
+package mylibswig
+
+// #cgo CFLAGS: -I${SRCDIR}/../src
+// #cgo pkg-config: mylib
+import "C"